@@ -18,6 +18,7 @@ package backends
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math/big"
@@ -30,6 +31,8 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
@@ -62,6 +65,10 @@ var (
 type SimulatedBackend struct {
 	database   ethdb.Database   // In memory database to store our testing data
 	blockchain *core.BlockChain // Ethereum blockchain to handle the consensus
+	engine     consensus.Engine // Consensus engine creating the blocks
+
+	signers map[common.Address]*ecdsa.PrivateKey // Keys of the clique signers, nil on ethash chains
+	sealer  common.Address                       // Clique signer picked to seal the pending block, zero if in-turn
 
 	mu           sync.Mutex
 	pendingBlock *types.Block   // Currently pending block that will be imported on request
@@ -77,12 +84,20 @@ type SimulatedBackend struct {
 // A simulated backend always uses chainID 1337.
 func NewSimulatedBackendWithDatabase(database ethdb.Database, alloc core.GenesisAlloc, gasLimit uint64) *SimulatedBackend {
 	genesis := core.Genesis{Config: params.AllEthashProtocolChanges, GasLimit: gasLimit, Alloc: alloc}
+	return newSimulatedBackend(database, &genesis, ethash.NewFaker(), nil)
+}
+
+// newSimulatedBackend creates a new binding backend on top of the given genesis
+// and consensus engine, sealing clique blocks with the given signer keys.
+func newSimulatedBackend(database ethdb.Database, genesis *core.Genesis, engine consensus.Engine, signers map[common.Address]*ecdsa.PrivateKey) *SimulatedBackend {
 	genesis.MustCommit(database)
-	blockchain, _ := core.NewBlockChain(database, nil, genesis.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	blockchain, _ := core.NewBlockChain(database, nil, genesis.Config, engine, vm.Config{}, nil, nil)
 
 	backend := &SimulatedBackend{
 		database:   database,
 		blockchain: blockchain,
+		engine:     engine,
+		signers:    signers,
 		config:     genesis.Config,
 		events:     filters.NewEventSystem(&filterBackend{database, blockchain}, false),
 	}
@@ -126,12 +141,52 @@ func (b *SimulatedBackend) Rollback() {
 }
 
 func (b *SimulatedBackend) rollback(parent *types.Block) {
-	blocks, _ := core.GenerateChain(b.config, parent, ethash.NewFaker(), b.database, 1, func(int, *core.BlockGen) {})
+	b.sealer = common.Address{}
 
-	b.pendingBlock = blocks[0]
+	block, err := b.generate(parent, nil)
+	if err != nil {
+		panic(err) // This cannot happen unless the simulator is wrong, fail in that case
+	}
+	b.pendingBlock = block
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), b.blockchain.StateCache(), nil)
 }
 
+// generate creates a block on top of the given parent. On clique chains, the
+// block is sealed by the signer picked with SetSealer, or the in-turn one.
+func (b *SimulatedBackend) generate(parent *types.Block, gen func(*core.BlockGen)) (*types.Block, error) {
+	engine, isClique := b.engine.(*clique.Clique)
+
+	var (
+		header = &types.Header{ParentHash: parent.Hash(), Number: new(big.Int).Add(parent.Number(), common.Big1)}
+		sealer common.Address
+		err    error
+	)
+	if isClique {
+		if sealer, err = engine.PrepareSealer(b.blockchain, header, b.sealer); err != nil {
+			return nil, err
+		}
+	}
+	blocks, _ := core.GenerateChain(b.config, parent, b.engine, b.database, 1, func(number int, block *core.BlockGen) {
+		if isClique {
+			// Imported blocks execute with the recovered sealer as coinbase,
+			// the pending state needs to see the same.
+			block.SetCoinbase(sealer)
+		}
+		if gen != nil {
+			gen(block)
+		}
+		if isClique {
+			block.SetDifficulty(header.Difficulty)
+			block.SetExtra(header.Extra)
+			block.SetNonce(header.Nonce)
+		}
+	})
+	if !isClique {
+		return blocks[0], nil
+	}
+	return b.seal(blocks[0], sealer)
+}
+
 // Fork creates a side-chain that can be used to simulate reorgs.
 //
 // This function should be called with the ancestor block where the new side
@@ -662,15 +717,18 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 		return fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce)
 	}
 	// Include tx in chain
-	blocks, _ := core.GenerateChain(b.config, block, ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
+	pending, err := b.generate(block, func(block *core.BlockGen) {
 		for _, tx := range b.pendingBlock.Transactions() {
 			block.AddTxWithChain(b.blockchain, tx)
 		}
 		block.AddTxWithChain(b.blockchain, tx)
 	})
+	if err != nil {
+		return err
+	}
 	stateDB, _ := b.blockchain.State()
 
-	b.pendingBlock = blocks[0]
+	b.pendingBlock = pending
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), stateDB.Database(), nil)
 	return nil
 }
@@ -780,12 +838,15 @@ func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
 		return errors.New("Could not adjust time on non-empty block")
 	}
 
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
+	pending, err := b.generate(b.blockchain.CurrentBlock(), func(block *core.BlockGen) {
 		block.OffsetTime(int64(adjustment.Seconds()))
 	})
+	if err != nil {
+		return err
+	}
 	stateDB, _ := b.blockchain.State()
 
-	b.pendingBlock = blocks[0]
+	b.pendingBlock = pending
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), stateDB.Database(), nil)
 
 	return nil
@@ -0,0 +1,185 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	errNotClique     = errors.New("simulatedBackend is not running clique")
+	errUnknownSigner = errors.New("unknown clique signer")
+)
+
+// NewSimulatedBackendWithClique creates a new binding backend sealing blocks
+// with clique, the given signers making up the initial validator set. Blocks
+// are sealed by the in-turn signer unless another one is picked with SetSealer,
+// and execute with the sealer as coinbase like on a live chain.
+// A simulated backend always uses chainID 1337.
+func NewSimulatedBackendWithClique(alloc core.GenesisAlloc, gasLimit uint64, signers ...*ecdsa.PrivateKey) *SimulatedBackend {
+	if len(signers) == 0 {
+		panic("simulated clique backend without signers")
+	}
+	keys := make(map[common.Address]*ecdsa.PrivateKey, len(signers))
+	validators := make([]common.Address, 0, len(signers))
+	for _, key := range signers {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		keys[addr] = key
+		validators = append(validators, addr)
+	}
+	config := *params.AllCliqueProtocolChanges
+	config.Clique = &params.CliqueConfig{InitialValidators: validators}
+
+	var (
+		database = rawdb.NewMemoryDatabase()
+		genesis  = core.Genesis{
+			Config:    &config,
+			GasLimit:  gasLimit,
+			Alloc:     alloc,
+			ExtraData: make([]byte, 32+crypto.SignatureLength),
+		}
+	)
+	return newSimulatedBackend(database, &genesis, clique.New(config.Clique, database), keys)
+}
+
+// seal signs a clique block with the key of the given signer.
+func (b *SimulatedBackend) seal(block *types.Block, signer common.Address) (*types.Block, error) {
+	key, ok := b.signers[signer]
+	if !ok {
+		return nil, errUnknownSigner
+	}
+	header := block.Header()
+	sig, err := crypto.Sign(clique.SealHash(header).Bytes(), key)
+	if err != nil {
+		return nil, err
+	}
+	copy(header.Extra[len(header.Extra)-crypto.SignatureLength:], sig)
+	return block.WithSeal(header), nil
+}
+
+// SetSealer picks the clique signer sealing the pending block, which is rebuilt
+// with its transactions to execute with the new coinbase. The choice is reset
+// once the block is committed or rolled back.
+func (b *SimulatedBackend) SetSealer(signer common.Address) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.signers == nil {
+		return errNotClique
+	}
+	if _, ok := b.signers[signer]; !ok {
+		return errUnknownSigner
+	}
+	prev := b.sealer
+	b.sealer = signer
+	if err := b.regeneratePending(); err != nil {
+		b.sealer = prev
+		return err
+	}
+	return nil
+}
+
+// AdvanceEpoch moves the clique validator registry to a new epoch, which the
+// pending block proposes once committed. Without signers the validator set is
+// carried over, otherwise the given signers make up the new one. The number of
+// the new epoch is returned.
+func (b *SimulatedBackend) AdvanceEpoch(signers ...*ecdsa.PrivateKey) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	engine, ok := b.engine.(*clique.Clique)
+	if !ok {
+		return 0, errNotClique
+	}
+	var (
+		epoch uint64
+		err   error
+	)
+	if len(signers) == 0 {
+		epoch, err = engine.AdvanceEpoch()
+	} else {
+		validators := make([]common.Address, 0, len(signers))
+		for _, key := range signers {
+			addr := crypto.PubkeyToAddress(key.PublicKey)
+			b.signers[addr] = key
+			validators = append(validators, addr)
+		}
+		epoch, err = engine.RotateValidators(validators)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return epoch, b.regeneratePending()
+}
+
+// regeneratePending rebuilds the pending block with its transactions, picking
+// up changes to its sealer or to the validator registry.
+func (b *SimulatedBackend) regeneratePending() error {
+	parent := b.blockchain.GetBlockByHash(b.pendingBlock.ParentHash())
+	if parent == nil {
+		return errBlockDoesNotExist
+	}
+	// Keep any time shift of the pending block, generated blocks being 10s apart
+	offset := int64(b.pendingBlock.Time()) - int64(parent.Time()) - 10
+
+	pending, err := b.generate(parent, func(block *core.BlockGen) {
+		if offset > 0 {
+			block.OffsetTime(offset)
+		}
+		for _, tx := range b.pendingBlock.Transactions() {
+			block.AddTxWithChain(b.blockchain, tx)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	stateDB, _ := b.blockchain.State()
+
+	b.pendingBlock = pending
+	b.pendingState, _ = state.New(b.pendingBlock.Root(), stateDB.Database(), nil)
+	return nil
+}
+
+// CliqueSnapshot retrieves the clique snapshot at the given block, the latest
+// if blockNumber is nil.
+func (b *SimulatedBackend) CliqueSnapshot(blockNumber *big.Int) (*clique.Snapshot, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	engine, ok := b.engine.(*clique.Clique)
+	if !ok {
+		return nil, errNotClique
+	}
+	number := rpc.LatestBlockNumber
+	if blockNumber != nil {
+		number = rpc.BlockNumber(blockNumber.Int64())
+	}
+	api := engine.APIs(b.blockchain)[0].Service.(*clique.API)
+	return api.GetSnapshot(&number)
+}
@@ -0,0 +1,132 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that the clique simulated backend seals blocks with the in-turn signer
+// or the picked one, executes them with the sealer as coinbase and rolls over
+// to new validator sets at epoch blocks.
+func TestSimulatedBackendClique(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		keyA, _  = crypto.GenerateKey()
+		keyB, _  = crypto.GenerateKey()
+		keyC, _  = crypto.GenerateKey()
+		addrC    = crypto.PubkeyToAddress(keyC.PublicKey)
+		testAddr = crypto.PubkeyToAddress(testKey.PublicKey)
+		contract = common.HexToAddress("0xc01bba5e")
+	)
+	// The contract stores the coinbase it executes with: COINBASE PUSH1 0 SSTORE
+	sim := NewSimulatedBackendWithClique(core.GenesisAlloc{
+		testAddr: {Balance: big.NewInt(1e18)},
+		contract: {Code: common.FromHex("0x41600055"), Balance: new(big.Int)},
+	}, 10000000, keyA, keyB)
+	defer sim.Close()
+
+	sealer := func(number uint64) common.Address {
+		header, err := sim.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			t.Fatalf("block %d: %v", number, err)
+		}
+		signer, err := sim.engine.Author(header)
+		if err != nil {
+			t.Fatalf("block %d: %v", number, err)
+		}
+		return signer
+	}
+	callContract := func(nonce uint64) {
+		head, _ := sim.HeaderByNumber(ctx, nil)
+		tx := types.NewTransaction(nonce, contract, new(big.Int), 100000, new(big.Int).Mul(head.BaseFee, big.NewInt(2)), nil)
+		tx, _ = types.SignTx(tx, types.HomesteadSigner{}, testKey)
+		if err := sim.SendTransaction(ctx, tx); err != nil {
+			t.Fatalf("failed to send transaction: %v", err)
+		}
+	}
+	// Blocks are sealed in turn, executing with the sealer as coinbase
+	callContract(0)
+	sim.Commit()
+	sim.Commit()
+
+	snap, err := sim.CliqueSnapshot(nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	for number := uint64(1); number <= 2; number++ {
+		if signer := sealer(number); !snap.Signers[signer] {
+			t.Fatalf("block %d: unauthorized sealer %x", number, signer)
+		}
+	}
+	if sealer(1) == sealer(2) {
+		t.Fatalf("blocks not sealed in turn: both sealed by %x", sealer(1))
+	}
+	stored, _ := sim.StorageAt(ctx, contract, common.Hash{}, nil)
+	if have, want := common.BytesToAddress(stored), sealer(1); have != want {
+		t.Fatalf("coinbase mismatch: have %x, want %x", have, want)
+	}
+	// Picking the sealer rebuilds the pending block out of turn
+	callContract(1)
+	outOfTurn := sealer(2)
+	if err := sim.SetSealer(outOfTurn); err != nil {
+		t.Fatalf("failed to pick sealer: %v", err)
+	}
+	sim.Commit()
+
+	header, _ := sim.HeaderByNumber(ctx, big.NewInt(3))
+	if clique.InTurn(header) || sealer(3) != outOfTurn {
+		t.Fatalf("block 3 not sealed out of turn by %x", outOfTurn)
+	}
+	stored, _ = sim.StorageAt(ctx, contract, common.Hash{}, nil)
+	if have := common.BytesToAddress(stored); have != outOfTurn {
+		t.Fatalf("coinbase mismatch: have %x, want %x", have, outOfTurn)
+	}
+	// Rotating the validators makes the next block an epoch block
+	epoch, err := sim.AdvanceEpoch(keyC)
+	if err != nil {
+		t.Fatalf("failed to advance epoch: %v", err)
+	}
+	sim.Commit()
+
+	header, _ = sim.HeaderByNumber(ctx, big.NewInt(4))
+	number, signers, ok := clique.EpochSigners(header)
+	if !ok || number != epoch || len(signers) != 1 || signers[0] != addrC {
+		t.Fatalf("epoch block mismatch: epoch %d, signers %x, ok %v", number, signers, ok)
+	}
+	if snap, err = sim.CliqueSnapshot(nil); err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	if snap.EpochNumber != epoch || snap.Number != 4 || len(snap.Signers) != 1 || !snap.Signers[addrC] {
+		t.Fatalf("snapshot mismatch: %+v", snap)
+	}
+	if snap.PreviousSnapNumber == nil || *snap.PreviousSnapNumber != 0 {
+		t.Fatalf("previous snapshot mismatch: %v", snap.PreviousSnapNumber)
+	}
+	sim.Commit()
+	if signer := sealer(5); signer != addrC {
+		t.Fatalf("block 5 sealer mismatch: have %x, want %x", signer, addrC)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"strconv"
@@ -110,6 +111,8 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 		Flags: append([]cli.Flag{
 			utils.CacheFlag,
 			utils.SyncModeFlag,
+			utils.ExportFormatFlag,
+			utils.ExportStreamFlag,
 		}, utils.DatabasePathFlags...),
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -117,7 +120,13 @@ Requires a first argument of the file to write to.
 Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing. If the file ends with .gz, the output will
-be gzipped.`,
+be gzipped.
+
+With --format proto, the blocks are written with their transactions
+and receipts as length prefixed protobuf messages, following the
+schema in cmd/utils/export.proto. With --stream, the first argument
+is a stream to write to instead: "-" for stdout, "unix:<path>" for a
+unix socket or "tcp:<host:port>" for a TCP connection.`,
 	}
 	importPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(importPreimages),
@@ -188,7 +197,12 @@ func initGenesis(ctx *cli.Context) error {
 	if err := json.NewDecoder(file).Decode(genesis); err != nil {
 		utils.Fatalf("invalid genesis file: %v", err)
 	}
-	// Open and initialise both full and light databases
+	writeGenesis(ctx, genesis)
+	return nil
+}
+
+// writeGenesis initialises both the full and light databases with the genesis.
+func writeGenesis(ctx *cli.Context, genesis *core.Genesis) {
 	stack, _ := makeConfigNode(ctx)
 	defer stack.Close()
 	for _, name := range []string{"chaindata", "lightchaindata"} {
@@ -203,7 +217,6 @@ func initGenesis(ctx *cli.Context) error {
 		chaindb.Close()
 		log.Info("Successfully wrote genesis state", "database", name, "hash", hash)
 	}
-	return nil
 }
 
 func dumpGenesis(ctx *cli.Context) error {
@@ -308,30 +321,58 @@ func exportChain(ctx *cli.Context) error {
 	chain, _ := utils.MakeChain(ctx, stack)
 	start := time.Now()
 
-	var err error
-	fp := ctx.Args().First()
-	if len(ctx.Args()) < 3 {
-		err = utils.ExportChain(chain, fp)
-	} else {
+	var (
+		err    error
+		fp     = ctx.Args().First()
+		format = ctx.String(utils.ExportFormatFlag.Name)
+		stream = ctx.Bool(utils.ExportStreamFlag.Name)
+	)
+	if format != utils.ExportFormatRLP && format != utils.ExportFormatProto {
+		utils.Fatalf("Export error: unknown format %q\n", format)
+	}
+	ranged := len(ctx.Args()) >= 3
+	first, last := uint64(0), chain.CurrentBlock().NumberU64()
+	if ranged {
 		// This can be improved to allow for numbers larger than 9223372036854775807
-		first, ferr := strconv.ParseInt(ctx.Args().Get(1), 10, 64)
-		last, lerr := strconv.ParseInt(ctx.Args().Get(2), 10, 64)
+		f, ferr := strconv.ParseInt(ctx.Args().Get(1), 10, 64)
+		l, lerr := strconv.ParseInt(ctx.Args().Get(2), 10, 64)
 		if ferr != nil || lerr != nil {
 			utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
 		}
-		if first < 0 || last < 0 {
+		if f < 0 || l < 0 {
 			utils.Fatalf("Export error: block number must be greater than 0\n")
 		}
-		if head := chain.CurrentFastBlock(); uint64(last) > head.NumberU64() {
-			utils.Fatalf("Export error: block number %d larger than head block %d\n", uint64(last), head.NumberU64())
+		if head := chain.CurrentFastBlock(); uint64(l) > head.NumberU64() {
+			utils.Fatalf("Export error: block number %d larger than head block %d\n", uint64(l), head.NumberU64())
 		}
-		err = utils.ExportAppendChain(chain, fp, uint64(first), uint64(last))
+		first, last = uint64(f), uint64(l)
+	}
+	switch {
+	case stream:
+		var w io.WriteCloser
+		if w, err = utils.OpenExportStream(fp); err != nil {
+			utils.Fatalf("Export error: %v\n", err)
+		}
+		if format == utils.ExportFormatProto {
+			err = utils.ExportChainProto(chain, w, first, last)
+		} else {
+			err = chain.ExportN(w, first, last)
+		}
+		if w != os.Stdout {
+			w.Close()
+		}
+	case format == utils.ExportFormatProto:
+		err = utils.ExportChainProtoFile(chain, fp, first, last, ranged)
+	case ranged:
+		err = utils.ExportAppendChain(chain, fp, first, last)
+	default:
+		err = utils.ExportChain(chain, fp)
 	}
-
 	if err != nil {
 		utils.Fatalf("Export error: %v\n", err)
 	}
-	fmt.Printf("Export done in %v\n", time.Since(start))
+	// Keep stdout clean for the stream consumer
+	fmt.Fprintf(os.Stderr, "Export done in %v\n", time.Since(start))
 	return nil
 }
 
@@ -0,0 +1,136 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/chainspec"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	chainspecNameFlag = cli.StringFlag{
+		Name:  "name",
+		Usage: "Name of the network in the exported chainspec",
+		Value: "aksara",
+	}
+	chainspecCommand = cli.Command{
+		Name:        "chainspec",
+		Usage:       "Convert the genesis to and from the chainspec format of other clients",
+		Category:    "BLOCKCHAIN COMMANDS",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:      "export",
+				Usage:     "Export the genesis and clique config as a chainspec",
+				ArgsUsage: "[<specPath>]",
+				Action:    utils.MigrateFlags(exportChainspec),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags: utils.GroupFlags([]cli.Flag{
+					chainspecNameFlag,
+				}, utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `
+geth chainspec export [<specPath>]
+exports the genesis of the selected network, or else of the chain in the data
+directory, along with its clique parameters as a chainspec. The fork blocks are
+expressed as the transitions of the EIPs they activate, the validator registry
+parameters are kept in the clique engine params. The chainspec is written to
+the given file, or to stdout if none.`,
+			},
+			{
+				Name:      "import",
+				Usage:     "Bootstrap and initialize a new genesis block from a chainspec",
+				ArgsUsage: "<specPath>",
+				Action:    utils.MigrateFlags(importChainspec),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags:     utils.DatabasePathFlags,
+				Description: `
+geth chainspec import <specPath>
+initializes the genesis block of a clique network from a chainspec, like the
+init command does from a genesis file. EIPs of a fork activating at different
+blocks are rejected, as they can't be expressed in the chain config.`,
+			},
+		},
+	}
+)
+
+func exportChainspec(ctx *cli.Context) error {
+	if len(ctx.Args()) > 1 {
+		utils.Fatalf("This command requires at most one argument.")
+	}
+	genesis := utils.MakeGenesis(ctx)
+	if genesis == nil {
+		stack, _ := makeConfigNode(ctx)
+		defer stack.Close()
+
+		db := utils.MakeChainDatabase(ctx, stack, true)
+		defer db.Close()
+
+		var err error
+		if genesis, err = core.ReadGenesis(db); err != nil {
+			utils.Fatalf("Failed to read genesis: %v", err)
+		}
+	}
+	spec, err := chainspec.New(ctx.String(chainspecNameFlag.Name), genesis)
+	if err != nil {
+		utils.Fatalf("Failed to convert genesis: %v", err)
+	}
+	out := os.Stdout
+	if path := ctx.Args().First(); path != "" {
+		if out, err = os.Create(path); err != nil {
+			utils.Fatalf("Failed to create chainspec file: %v", err)
+		}
+		defer out.Close()
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(spec); err != nil {
+		utils.Fatalf("Failed to write chainspec: %v", err)
+	}
+	if out != os.Stdout {
+		log.Info("Exported chainspec", "file", out.Name())
+	}
+	return nil
+}
+
+func importChainspec(ctx *cli.Context) error {
+	specPath := ctx.Args().First()
+	if len(specPath) == 0 {
+		utils.Fatalf("Must supply path to chainspec JSON file")
+	}
+	file, err := os.Open(specPath)
+	if err != nil {
+		utils.Fatalf("Failed to read chainspec file: %v", err)
+	}
+	defer file.Close()
+
+	spec := new(chainspec.Spec)
+	if err := json.NewDecoder(file).Decode(spec); err != nil {
+		utils.Fatalf("invalid chainspec file: %v", err)
+	}
+	genesis, err := spec.ToGenesis()
+	if err != nil {
+		utils.Fatalf("Failed to convert chainspec: %v", err)
+	}
+	writeGenesis(ctx, genesis)
+	return nil
+}
@@ -0,0 +1,258 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	bridgeABIFlag = cli.BoolFlag{
+		Name:  "abi",
+		Usage: "Print only the ABI encoding of the epoch transition",
+	}
+
+	signersOutFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "File to write the signer history to (default = stdout)",
+	}
+
+	cliqueCommand = cli.Command{
+		Name:        "clique",
+		Usage:       "A set of commands for the clique consensus engine",
+		Category:    "MISCELLANEOUS COMMANDS",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:      "rebuild-snapshots",
+				Usage:     "Re-derive all clique snapshots from the canonical headers",
+				ArgsUsage: "",
+				Action:    utils.MigrateFlags(rebuildCliqueSnapshots),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags:     utils.GroupFlags(utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `
+geth clique rebuild-snapshots
+re-derives all clique snapshots from the canonical headers, starting from the
+registry state of the genesis epoch and applying every epoch block on top, and
+replaces the persisted ones with them in a single write. This recovers from
+snapshots corrupted by earlier versions without a full resync. A failed rebuild
+leaves the persisted snapshots untouched.
+
+After the rebuild, the new snapshots are compared with the replaced ones and a
+JSON report listing the blocks whose snapshots changed is printed.
+`,
+			},
+			{
+				Name:      "export-bridge",
+				Usage:     "Export the data a bridge contract needs to verify an epoch transition",
+				ArgsUsage: "<epoch>",
+				Action:    utils.MigrateFlags(exportBridgeEpoch),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags:     utils.GroupFlags([]cli.Flag{bridgeABIFlag}, utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `
+geth clique export-bridge <epoch>
+prints the epoch block which transitioned the chain into the given epoch, its
+seal and the validator sets before and after, as JSON along with the ABI
+encoding of the
+    (uint64 epoch, uint64 number, bytes32 hash, bytes header, bytes sealData,
+     bytes32 sealHash, bytes signature, address signer, address[] signers,
+     address[] validators)
+tuple. Light verifier contracts on other chains follow the validator rotation
+by checking each transition against the previous validator set.
+`,
+			},
+			{
+				Name:      "export-signers",
+				Usage:     "Export the signer set of every epoch as a versioned JSON file",
+				ArgsUsage: "",
+				Action:    utils.MigrateFlags(exportCliqueSigners),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags:     utils.GroupFlags([]cli.Flag{signersOutFlag}, utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `
+geth clique export-signers [--out signers.json]
+writes the signer sets of all the epochs of the canonical chain as JSON, each
+with the epoch block opening it and the range of blocks sealed by its signers.
+The file is versioned and meant to be published as a static artifact, letting
+bridges and explorers attribute blocks to signer sets without running a node.
+It fails if the snapshot of a canonical epoch block is missing or stale, run
+rebuild-snapshots first then.
+`,
+			},
+		},
+	}
+)
+
+func rebuildCliqueSnapshots(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	config, err := readCliqueConfig(db)
+	if err != nil {
+		return err
+	}
+	var (
+		start    = time.Now()
+		lastDraw time.Time
+	)
+	report, err := clique.RebuildSnapshots(db, config, func(number, head uint64) {
+		if number != head && time.Since(lastDraw) < 100*time.Millisecond {
+			return
+		}
+		lastDraw = time.Now()
+		drawProgress(number, head, time.Since(start))
+	})
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	if !report.Consistent() {
+		log.Warn("Rebuilt snapshots differ from previous ones", "mismatched", len(report.Mismatched), "missing", len(report.Missing), "stale", len(report.Stale))
+	}
+	log.Info("Rebuilt clique snapshots", "count", report.Rebuilt, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func exportBridgeEpoch(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("expected the epoch number as argument")
+	}
+	epoch, err := strconv.ParseUint(ctx.Args().First(), 0, 64)
+	if err != nil {
+		return fmt.Errorf("invalid epoch number: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	config, err := readCliqueConfig(db)
+	if err != nil {
+		return err
+	}
+	// The registry isn't watched offline, the persisted snapshots suffice
+	engineConfig := *config
+	engineConfig.API = ""
+	engine := clique.New(&engineConfig, db)
+	defer engine.Close()
+
+	chain, err := core.NewHeaderChain(db, rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0)), engine, func() bool { return false })
+	if err != nil {
+		return err
+	}
+	export, err := engine.ExportBridgeEpoch(chain, epoch)
+	if err != nil {
+		return err
+	}
+	packed, err := export.Pack()
+	if err != nil {
+		return err
+	}
+	if ctx.Bool(bridgeABIFlag.Name) {
+		fmt.Println(hexutil.Encode(packed))
+		return nil
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		*clique.BridgeEpoch
+		ABI hexutil.Bytes `json:"abi"`
+	}{export, packed})
+}
+
+func exportCliqueSigners(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	config, err := readCliqueConfig(db)
+	if err != nil {
+		return err
+	}
+	history, err := clique.ExportSigners(db, config)
+	if err != nil {
+		return err
+	}
+	out := ctx.String(signersOutFlag.Name)
+	if out == "" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(history)
+	}
+	blob, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(out, append(blob, '\n'), 0644); err != nil {
+		return err
+	}
+	log.Info("Exported clique signer history", "file", out, "epochs", len(history.Epochs), "head", history.Head)
+	return nil
+}
+
+// readCliqueConfig retrieves the clique configuration of the chain stored in
+// the database.
+func readCliqueConfig(db ethdb.Database) (*params.CliqueConfig, error) {
+	genesis := rawdb.ReadCanonicalHash(db, 0)
+	if genesis == (common.Hash{}) {
+		return nil, errors.New("database contains no chain")
+	}
+	config := rawdb.ReadChainConfig(db, genesis)
+	if config == nil || config.Clique == nil {
+		return nil, errors.New("chain is not running clique consensus")
+	}
+	return config.Clique, nil
+}
+
+// drawProgress renders a single line progress bar to stderr.
+func drawProgress(done, total uint64, elapsed time.Duration) {
+	const width = 40
+
+	ratio := 1.0
+	if total > 0 {
+		ratio = float64(done) / float64(total)
+	}
+	filled := int(ratio * width)
+	fmt.Fprintf(os.Stderr, "\r[%s%s] %6.2f%% %d/%d %v", strings.Repeat("=", filled), strings.Repeat(" ", width-filled), ratio*100, done, total, common.PrettyDuration(elapsed))
+}
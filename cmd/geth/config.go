@@ -39,6 +39,7 @@ import (
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rosetta"
 	"github.com/naoina/toml"
 )
 
@@ -145,6 +146,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	}
 
 	utils.SetEthConfig(ctx, stack, &cfg.Eth)
+	cfg.Eth.Miner.GitCommit = gitCommit
 	if ctx.GlobalIsSet(utils.EthStatsURLFlag.Name) {
 		cfg.Ethstats.URL = ctx.GlobalString(utils.EthStatsURLFlag.Name)
 	}
@@ -184,6 +186,16 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 	if ctx.GlobalIsSet(utils.GraphQLEnabledFlag.Name) {
 		utils.RegisterGraphQLService(stack, backend, cfg.Node)
 	}
+	// Configure Rosetta if requested
+	if ctx.GlobalIsSet(utils.RosettaEnabledFlag.Name) {
+		if eth == nil {
+			utils.Fatalf("Rosetta is not supported in light client mode")
+		}
+		utils.RegisterRosettaService(stack, eth, cfg.Node, rosetta.Config{
+			Blockchain: ctx.GlobalString(utils.RosettaBlockchainFlag.Name),
+			Symbol:     ctx.GlobalString(utils.RosettaSymbolFlag.Name),
+		})
+	}
 	// Add the Ethereum Stats daemon if requested.
 	if cfg.Ethstats.URL != "" {
 		utils.RegisterEthStatsService(stack, backend, cfg.Ethstats.URL)
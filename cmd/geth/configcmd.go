@@ -0,0 +1,239 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// maxSealGasRate is the gas per second above which a clique chain is unlikely
+// to execute and propagate its blocks within the block period.
+const maxSealGasRate = 50_000_000
+
+var configCommand = cli.Command{
+	Name:     "config",
+	Usage:    "A set of commands for the node configuration",
+	Category: "MISCELLANEOUS COMMANDS",
+	Subcommands: []cli.Command{
+		{
+			Name:      "check",
+			Usage:     "Validate the configuration file and flags before starting the node",
+			ArgsUsage: "",
+			Action:    utils.MigrateFlags(checkConfig),
+			Category:  "MISCELLANEOUS COMMANDS",
+			Flags:     utils.GroupFlags(nodeFlags, rpcFlags),
+			Description: `
+geth config check
+loads the configuration file and applies the flags exactly as the node would,
+then validates the resulting setup: the chain configuration and, on clique
+chains, the registry settings, the signer policy, the block period against the
+gas limit and the signer account the node would seal with. Every problem found
+is printed with the setting to fix; the command fails if any of them would stop
+the node from sealing.`,
+		},
+	},
+}
+
+// configReport collects the problems found in a configuration.
+type configReport struct {
+	errors   []string
+	warnings []string
+}
+
+func (r *configReport) errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *configReport) warnf(format string, args ...interface{}) {
+	r.warnings = append(r.warnings, fmt.Sprintf(format, args...))
+}
+
+// checkConfig is the 'geth config check' command.
+func checkConfig(ctx *cli.Context) error {
+	// Loading the config and applying the flags already fails on malformed
+	// files and conflicting flags.
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+
+	report := new(configReport)
+	config, gasLimit := checkChainConfig(ctx, stack, &cfg, report)
+	if config != nil && config.Clique != nil {
+		checkCliqueConfig(ctx, stack, &cfg, config.Clique, gasLimit, report)
+	}
+	for _, warning := range report.warnings {
+		fmt.Println("WARN: ", warning)
+	}
+	for _, err := range report.errors {
+		fmt.Println("ERROR:", err)
+	}
+	if len(report.errors) > 0 {
+		return fmt.Errorf("configuration invalid: %d errors, %d warnings", len(report.errors), len(report.warnings))
+	}
+	fmt.Printf("Configuration valid, %d warnings\n", len(report.warnings))
+	return nil
+}
+
+// checkChainConfig validates the chain configuration the node would run with,
+// taken from the configured genesis or the existing chain database, returning
+// it along with the genesis gas limit.
+func checkChainConfig(ctx *cli.Context, stack *node.Node, cfg *gethConfig, report *configReport) (*params.ChainConfig, uint64) {
+	var (
+		config   *params.ChainConfig
+		gasLimit uint64
+	)
+	if genesis := cfg.Eth.Genesis; genesis != nil {
+		config, gasLimit = genesis.Config, genesis.GasLimit
+	} else if path := stack.ResolvePath("chaindata"); path != "" && common.FileExist(path) {
+		db := utils.MakeChainDatabase(ctx, stack, true)
+		if hash := rawdb.ReadCanonicalHash(db, 0); hash != (common.Hash{}) {
+			config = rawdb.ReadChainConfig(db, hash)
+			if header := rawdb.ReadHeader(db, hash, 0); header != nil {
+				gasLimit = header.GasLimit
+			}
+		}
+		db.Close()
+	}
+	if config == nil {
+		report.warnf("No genesis configured and no initialized chain database, chain checks skipped (run 'geth init' or pick a network)")
+		return nil, 0
+	}
+	if err := config.CheckConfigForkOrder(); err != nil {
+		report.errorf("Invalid fork order in the chain config: %v", err)
+	}
+	if config.Clique == nil && (cfg.Eth.CliqueMaxFutureDrift != 0 || cfg.Eth.CliqueTimestampJitter != 0) {
+		report.warnf("Clique timestamp policy set on a %v chain, it has no effect", config)
+	}
+	return config, gasLimit
+}
+
+// checkCliqueConfig runs the clique specific sanity checks.
+func checkCliqueConfig(ctx *cli.Context, stack *node.Node, cfg *gethConfig, config *params.CliqueConfig, gasLimit uint64, report *configReport) {
+	// The signer set is tracked from the registry, which has to be reachable
+	if config.DNR == (common.Address{}) {
+		report.errorf("Chain config has no clique.dnr registry address, the signer set can't be tracked")
+	}
+	if config.API == "" {
+		report.warnf("Chain config has no clique.api Ethereum endpoint, the validator set stays static until advanced by hand")
+	} else if !strings.HasPrefix(config.API, "http") && !strings.HasPrefix(config.API, "ws") && !strings.HasSuffix(config.API, ".ipc") {
+		report.errorf("Chain config clique.api %q is not an http, websocket or IPC endpoint", config.API)
+	}
+	if config.EpochBlock != 0 && len(config.InitialValidators) == 0 {
+		report.errorf("Chain config starts at registry epoch block %d without clique.initialValidators", config.EpochBlock)
+	}
+	if policy := config.SignerPolicy; policy != nil {
+		if policy.Block == nil {
+			report.errorf("Chain config clique.signerPolicy has no activation block")
+		}
+		if policy.MinSigners != 0 && policy.MaxSigners != 0 && policy.MinSigners > policy.MaxSigners {
+			report.errorf("Chain config clique.signerPolicy minSigners %d above maxSigners %d", policy.MinSigners, policy.MaxSigners)
+		}
+		if size := uint64(len(config.InitialValidators)); size > 0 && policy.MinSigners > size {
+			report.warnf("Chain config clique.signerPolicy minSigners %d above the %d initial validators", policy.MinSigners, size)
+		}
+	}
+	// The period has to leave enough time to execute full blocks
+	ceil := cfg.Eth.Miner.GasCeil
+	switch {
+	case ceil < params.TxGas:
+		report.errorf("Miner gas ceiling %d can't fit a single transaction, raise --miner.gaslimit", ceil)
+	case config.Period == 0:
+		report.warnf("Clique period is 0, blocks are only sealed while transactions are pending")
+	case ceil/config.Period > maxSealGasRate:
+		report.warnf("Miner gas ceiling %d over a %ds period is %d gas/s, above the %d gas/s blocks can be reliably executed and propagated at; lower --miner.gaslimit", ceil, config.Period, ceil/config.Period, maxSealGasRate)
+	}
+	if gasLimit != 0 && ceil > gasLimit && config.Period > 0 {
+		// The gas limit moves by at most 1/1024 per block towards the ceiling
+		var blocks uint64
+		for limit := gasLimit; limit < ceil && blocks < 1_000_000; blocks++ {
+			limit += limit/params.GasLimitBoundDivisor - 1
+		}
+		if eta := time.Duration(blocks*config.Period) * time.Second; eta > 24*time.Hour {
+			report.warnf("Gas limit takes %d blocks (%v) to rise from the genesis %d to the miner gas ceiling %d", blocks, eta, gasLimit, ceil)
+		}
+	}
+	if drift, jitter := cfg.Eth.CliqueMaxFutureDrift, cfg.Eth.CliqueTimestampJitter; drift > 0 && jitter > drift {
+		report.errorf("Clique timestamp jitter %v above the maximum future drift %v", jitter, drift)
+	}
+	if drift := cfg.Eth.CliqueMaxFutureDrift; drift > 0 && config.Period > 0 && drift >= time.Duration(config.Period)*time.Second {
+		report.warnf("Clique maximum future drift %v spans a whole %ds period, out of turn blocks may be accepted early", drift, config.Period)
+	}
+	checkCliqueSigner(ctx, stack, cfg, report)
+}
+
+// checkCliqueSigner verifies that the account clique would seal with is held by
+// one of the configured wallets, keystore or clef, and usable for sealing.
+func checkCliqueSigner(ctx *cli.Context, stack *node.Node, cfg *gethConfig, report *configReport) {
+	if !ctx.GlobalBool(utils.MiningEnabledFlag.Name) && cfg.Eth.Miner.Etherbase == (common.Address{}) {
+		report.warnf("Mining disabled and no --miner.etherbase set, signer checks skipped")
+		return
+	}
+	if ctx.GlobalIsSet(utils.DeveloperFlag.Name) {
+		return // The developer account is created on startup
+	}
+	var (
+		manager = stack.AccountManager()
+		signer  = cfg.Eth.Miner.Etherbase
+	)
+	if signer == (common.Address{}) {
+		if wallets := manager.Wallets(); len(wallets) > 0 {
+			if accs := wallets[0].Accounts(); len(accs) > 0 {
+				signer = accs[0].Address
+			}
+		}
+		if signer == (common.Address{}) {
+			report.errorf("No signer account: set --miner.etherbase to an account of the keystore or clef (--signer)")
+			return
+		}
+		report.warnf("No --miner.etherbase set, sealing with the first wallet account %s", signer)
+	}
+	wallet, err := manager.Find(accounts.Account{Address: signer})
+	if err != nil {
+		report.errorf("Signer %s not found in the keystore (%s) or clef: %v", signer, stack.KeyStoreDir(), err)
+		return
+	}
+	if wallet.URL().Scheme != keystore.KeyStoreScheme {
+		return
+	}
+	// Keystore accounts need to be unlocked up front to seal unattended
+	unlocked := false
+	for _, entry := range strings.Split(ctx.GlobalString(utils.UnlockedAccountFlag.Name), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		if common.IsHexAddress(entry) && common.HexToAddress(entry) == signer {
+			unlocked = true
+		} else if !common.IsHexAddress(entry) {
+			unlocked = true // Index into the keystore, can't tell without the password
+		}
+	}
+	if !unlocked {
+		report.errorf("Signer %s is a keystore account not unlocked with --unlock, sealing fails until it's unlocked", signer)
+	} else if ctx.GlobalString(utils.PasswordFileFlag.Name) == "" {
+		report.warnf("Signer %s is unlocked without --password, the node prompts for it on startup", signer)
+	}
+}
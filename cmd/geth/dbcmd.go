@@ -72,6 +72,7 @@ Remove blockchain and state databases`,
 			dbExportCmd,
 			dbMetadataCmd,
 			dbMigrateFreezerCmd,
+			dbCompressAncientCmd,
 			dbCheckStateContentCmd,
 		},
 	}
@@ -208,6 +209,21 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		Description: `The freezer-migrate command checks your database for receipts in a legacy format and updates those.
 WARNING: please back-up the receipt files in your ancients before running this command.`,
 	}
+	dbCompressAncientCmd = cli.Command{
+		Action:    utils.MigrateFlags(compressAncient),
+		Name:      "compress-ancient",
+		Usage:     "Recompress freezer tables with zstd and a dictionary trained on their data (WARNING: may take a long time)",
+		ArgsUsage: "<type (optional)> ...",
+		Flags: utils.GroupFlags([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `The compress-ancient command rewrites the given snappy compressed freezer tables, or all
+of them if none is given, with zstd. The dictionary of each table is trained on a sample of
+its own items, which suits the repetitive data of headers, bodies and receipts far better than
+snappy. Converted tables keep being written in zstd, the others are still read and written
+with snappy. An interrupted conversion is resumed by running the command again.
+WARNING: the node must be stopped, and please back-up your ancients before running this command.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -795,6 +811,43 @@ func freezerMigrate(ctx *cli.Context) error {
 	return nil
 }
 
+func compressAncient(ctx *cli.Context) error {
+	var kinds []string
+	for kind, noSnap := range rawdb.FreezerNoSnappy {
+		if !noSnap {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+	if ctx.NArg() > 0 {
+		for _, kind := range ctx.Args() {
+			if noSnap, ok := rawdb.FreezerNoSnappy[kind]; !ok || noSnap {
+				return fmt.Errorf("Could not compress freezer-type '%v'. Available options: %v", kind, kinds)
+			}
+		}
+		kinds = ctx.Args()
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	path := ctx.GlobalString(utils.AncientFlag.Name)
+	switch {
+	case path == "":
+		path = filepath.Join(stack.ResolvePath("chaindata"), "ancient")
+	case !filepath.IsAbs(path):
+		path = stack.ResolvePath(path)
+	}
+	for _, kind := range kinds {
+		log.Info("Compressing freezer table", "location", path, "name", kind)
+		start := time.Now()
+		if err := rawdb.CompressFreezerTable(path, kind); err != nil {
+			return err
+		}
+		log.Info("Compression finished", "name", kind, "duration", time.Since(start))
+	}
+	return nil
+}
+
 // dbHasLegacyReceipts checks freezer entries for legacy receipts. It stops at the first
 // non-empty receipt and checks its format. The index of this first non-empty element is
 // the second return parameter.
@@ -0,0 +1,152 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/gateway"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	gatewayUpstreamsFlag = cli.StringFlag{
+		Name:  "gateway.upstreams",
+		Usage: "Comma separated HTTP-RPC endpoints of the upstream nodes",
+	}
+	gatewayAPIFlag = cli.StringFlag{
+		Name:  "gateway.api",
+		Usage: "Comma separated API namespaces forwarded to the upstreams",
+		Value: strings.Join(gateway.DefaultConfig.Modules, ","),
+	}
+	gatewayMaxLagFlag = cli.Uint64Flag{
+		Name:  "gateway.maxlag",
+		Usage: "Blocks an upstream may trail the best one by while considered healthy",
+		Value: gateway.DefaultConfig.MaxLag,
+	}
+	gatewayCheckIntervalFlag = cli.DurationFlag{
+		Name:  "gateway.checkinterval",
+		Usage: "Interval between two health checks of the upstreams",
+		Value: gateway.DefaultConfig.CheckInterval,
+	}
+	gatewayHedgeFlag = cli.DurationFlag{
+		Name:  "gateway.hedge",
+		Usage: "Time after which a call is also sent to a second upstream (0 = disabled)",
+		Value: gateway.DefaultConfig.HedgeDelay,
+	}
+	gatewayTimeoutFlag = cli.DurationFlag{
+		Name:  "gateway.timeout",
+		Usage: "Maximum time to wait for the upstreams to answer a call",
+		Value: gateway.DefaultConfig.Timeout,
+	}
+
+	gatewayCommand = cli.Command{
+		Action:    utils.MigrateFlags(runGateway),
+		Name:      "gateway",
+		Usage:     "Serve the RPC API by proxying to a pool of upstream nodes",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			gatewayUpstreamsFlag,
+			gatewayAPIFlag,
+			gatewayMaxLagFlag,
+			gatewayCheckIntervalFlag,
+			gatewayHedgeFlag,
+			gatewayTimeoutFlag,
+			utils.HTTPListenAddrFlag,
+			utils.HTTPPortFlag,
+			utils.MetricsEnabledFlag,
+			utils.MetricsHTTPFlag,
+			utils.MetricsPortFlag,
+		},
+		Category: "MISCELLANEOUS COMMANDS",
+		Description: `
+geth gateway --gateway.upstreams http://node1:8545,http://node2:8545
+
+Runs a lightweight RPC gateway without a chain of its own, serving the
+JSON-RPC API over HTTP by proxying the calls to a pool of upstream nodes:
+
+  - The upstreams are health checked, the ones failing or trailing the best
+    head by more than --gateway.maxlag blocks are taken out of rotation.
+  - Every client is pinned to one upstream, so consecutive calls resolving
+    block tags like "latest" observe a consistent chain. Clients are told
+    apart by the X-Gateway-Session header, or by their IP address.
+  - Calls failing on an upstream are retried on the next one, and calls
+    answered slower than --gateway.hedge are also sent to the next upstream,
+    the first answer being returned. Transactions are never hedged.
+
+Calls are forwarded as they are, so the clique and aks namespaces are served
+like the standard ones. Only the namespaces listed in --gateway.api are
+forwarded. The health of the upstreams is served by gateway_upstreams.`,
+	}
+)
+
+func runGateway(ctx *cli.Context) error {
+	upstreams := utils.SplitAndTrim(ctx.String(gatewayUpstreamsFlag.Name))
+	if len(upstreams) == 0 {
+		return errors.New("no upstreams configured, set --" + gatewayUpstreamsFlag.Name)
+	}
+	gw, err := gateway.New(gateway.Config{
+		Upstreams:     upstreams,
+		Modules:       utils.SplitAndTrim(ctx.String(gatewayAPIFlag.Name)),
+		CheckInterval: ctx.Duration(gatewayCheckIntervalFlag.Name),
+		MaxLag:        ctx.Uint64(gatewayMaxLagFlag.Name),
+		HedgeDelay:    ctx.Duration(gatewayHedgeFlag.Name),
+		Timeout:       ctx.Duration(gatewayTimeoutFlag.Name),
+	})
+	if err != nil {
+		return err
+	}
+	utils.SetupMetrics(ctx)
+	go metrics.CollectProcessMetrics(3 * time.Second)
+
+	gw.Start()
+	defer gw.Stop()
+
+	endpoint := net.JoinHostPort(ctx.String(utils.HTTPListenAddrFlag.Name), fmt.Sprint(ctx.Int(utils.HTTPPortFlag.Name)))
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:      gw,
+		ReadTimeout:  rpc.DefaultHTTPTimeouts.ReadTimeout,
+		WriteTimeout: rpc.DefaultHTTPTimeouts.WriteTimeout,
+		IdleTimeout:  rpc.DefaultHTTPTimeouts.IdleTimeout,
+	}
+	go srv.Serve(listener)
+	log.Info("RPC gateway started", "endpoint", "http://"+listener.Addr().String(), "upstreams", len(upstreams))
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+	<-sigc
+
+	log.Info("Shutting down RPC gateway")
+	return srv.Close()
+}
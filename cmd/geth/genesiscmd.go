@@ -0,0 +1,292 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// systemContracts are the canonical addresses of the predeployed system
+// contracts, so that all networks place them at the same location.
+var systemContracts = map[string]common.Address{
+	"staking":  common.HexToAddress("0x0000000000000000000000000000000000001000"),
+	"slashing": common.HexToAddress("0x0000000000000000000000000000000000001001"),
+	"registry": common.HexToAddress("0x0000000000000000000000000000000000001002"),
+}
+
+var (
+	genesisCodeFlag = cli.StringFlag{
+		Name:  "code",
+		Usage: "File containing the runtime bytecode of the contract, hex encoded or binary",
+	}
+	genesisStorageFlag = cli.StringFlag{
+		Name:  "storage",
+		Usage: "JSON file mapping storage slots to their initial values",
+	}
+	genesisNameFlag = cli.StringFlag{
+		Name:  "name",
+		Usage: "System contract to predeploy at its canonical address (staking, slashing, registry)",
+	}
+	genesisAddressFlag = cli.StringFlag{
+		Name:  "address",
+		Usage: "Address to predeploy the contract at, instead of a canonical one",
+	}
+	genesisBalanceFlag = cli.StringFlag{
+		Name:  "balance",
+		Usage: "Initial balance of the contract in wei",
+		Value: "0",
+	}
+	genesisOutputFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "File to write the patched genesis spec to (default = overwrite the input)",
+	}
+	genesisOverwriteFlag = cli.BoolFlag{
+		Name:  "overwrite",
+		Usage: "Replace an account already allocated at the contract address",
+	}
+	genesisHashFlag = cli.StringFlag{
+		Name:  "hash",
+		Usage: "Genesis hash the spec is expected to produce",
+	}
+
+	genesisCommand = cli.Command{
+		Name:        "genesis",
+		Usage:       "A set of commands for preparing genesis specifications",
+		Category:    "BLOCKCHAIN COMMANDS",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:      "add-contract",
+				Usage:     "Predeploy a system contract in a genesis spec",
+				ArgsUsage: "<genesisPath>",
+				Action:    utils.MigrateFlags(addGenesisContract),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags: []cli.Flag{
+					genesisCodeFlag,
+					genesisStorageFlag,
+					genesisNameFlag,
+					genesisAddressFlag,
+					genesisBalanceFlag,
+					genesisOutputFlag,
+					genesisOverwriteFlag,
+				},
+				Description: `
+geth genesis add-contract --name staking --code staking.bin --storage staking.json genesis.json
+allocates the given runtime bytecode and storage in the genesis spec, either at
+the canonical address of a system contract or at an explicit --address, and
+prints the resulting genesis hash.
+
+The storage file is a JSON object mapping 32 byte slots to 32 byte values, as
+used by the alloc section of the spec. The code file may hold hex or binary.
+`,
+			},
+			{
+				Name:      "verify",
+				Usage:     "Compute the genesis hash of a spec and check it against the expected one",
+				ArgsUsage: "<genesisPath>",
+				Action:    utils.MigrateFlags(verifyGenesis),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags:     utils.GroupFlags([]cli.Flag{genesisHashFlag}, utils.DatabasePathFlags),
+				Description: `
+geth genesis verify [--hash <hash>] genesis.json
+prints the genesis hash, state root and predeployed system contracts of the spec.
+The hash is checked against the --hash flag, if given, and against the genesis
+block of the local database, if initialised. Validators use it to confirm they
+all launch the network from the same genesis before the first block is sealed.
+`,
+			},
+		},
+	}
+)
+
+// readGenesisSpec loads a genesis specification from a JSON file.
+func readGenesisSpec(path string) (*core.Genesis, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read genesis file: %v", err)
+	}
+	defer file.Close()
+
+	genesis := new(core.Genesis)
+	if err := json.NewDecoder(file).Decode(genesis); err != nil {
+		return nil, fmt.Errorf("invalid genesis file: %v", err)
+	}
+	return genesis, nil
+}
+
+// readContractCode loads contract bytecode from a file holding either its hex
+// encoding, optionally 0x prefixed, or the raw binary.
+func readContractCode(path string) ([]byte, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(strings.TrimSpace(string(blob)), "0x")
+	if code, err := hex.DecodeString(text); err == nil {
+		return code, nil
+	}
+	return blob, nil
+}
+
+func addGenesisContract(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("expected the genesis file as argument")
+	}
+	path := ctx.Args().First()
+	genesis, err := readGenesisSpec(path)
+	if err != nil {
+		return err
+	}
+	// Resolve the address the contract is deployed at
+	var addr common.Address
+	switch name, hexaddr := ctx.String(genesisNameFlag.Name), ctx.String(genesisAddressFlag.Name); {
+	case name != "" && hexaddr != "":
+		return errors.New("--name and --address are mutually exclusive")
+	case name != "":
+		canonical, ok := systemContracts[name]
+		if !ok {
+			return fmt.Errorf("unknown system contract %q", name)
+		}
+		addr = canonical
+	case hexaddr != "":
+		if !common.IsHexAddress(hexaddr) {
+			return fmt.Errorf("invalid contract address %q", hexaddr)
+		}
+		addr = common.HexToAddress(hexaddr)
+	default:
+		return errors.New("either --name or --address is required")
+	}
+	if !ctx.IsSet(genesisCodeFlag.Name) {
+		return errors.New("contract code file required (--code)")
+	}
+	code, err := readContractCode(ctx.String(genesisCodeFlag.Name))
+	if err != nil {
+		return fmt.Errorf("failed to read contract code: %v", err)
+	}
+	if len(code) == 0 {
+		return errors.New("empty contract code")
+	}
+	var storage map[common.Hash]common.Hash
+	if file := ctx.String(genesisStorageFlag.Name); file != "" {
+		blob, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read contract storage: %v", err)
+		}
+		if err := json.Unmarshal(blob, &storage); err != nil {
+			return fmt.Errorf("invalid contract storage: %v", err)
+		}
+	}
+	balance, ok := math.ParseBig256(ctx.String(genesisBalanceFlag.Name))
+	if !ok {
+		return fmt.Errorf("invalid contract balance %q", ctx.String(genesisBalanceFlag.Name))
+	}
+	if genesis.Alloc == nil {
+		genesis.Alloc = make(core.GenesisAlloc)
+	}
+	if _, ok := genesis.Alloc[addr]; ok && !ctx.Bool(genesisOverwriteFlag.Name) {
+		return fmt.Errorf("account %v already allocated, use --overwrite to replace it", addr)
+	}
+	genesis.Alloc[addr] = core.GenesisAccount{
+		Code:    code,
+		Storage: storage,
+		Balance: balance,
+		Nonce:   1,
+	}
+	// Write the patched spec and report the new genesis
+	out := ctx.String(genesisOutputFlag.Name)
+	if out == "" {
+		out = path
+	}
+	blob, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(out, append(blob, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write genesis file: %v", err)
+	}
+	block := genesis.ToBlock(nil)
+	log.Info("Added genesis contract", "address", addr, "code", len(code), "codehash", crypto.Keccak256Hash(code), "slots", len(storage))
+	fmt.Printf("Genesis hash: %v\n", block.Hash())
+	return nil
+}
+
+func verifyGenesis(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("expected the genesis file as argument")
+	}
+	genesis, err := readGenesisSpec(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	block := genesis.ToBlock(nil)
+	fmt.Printf("Genesis hash: %v\n", block.Hash())
+	fmt.Printf("State root:   %v\n", block.Root())
+
+	// List the system contracts so validators can compare their code too
+	names := make([]string, 0, len(systemContracts))
+	for name := range systemContracts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		addr := systemContracts[name]
+		if account, ok := genesis.Alloc[addr]; ok && len(account.Code) > 0 {
+			fmt.Printf("%-12s  %v codehash %v slots %d\n", name, addr, crypto.Keccak256Hash(account.Code), len(account.Storage))
+		}
+	}
+	if expect := ctx.String(genesisHashFlag.Name); expect != "" {
+		want := common.HexToHash(expect)
+		if want != block.Hash() {
+			return fmt.Errorf("genesis hash mismatch: have %v, want %v", block.Hash(), want)
+		}
+		log.Info("Genesis hash matches expected one", "hash", want)
+	}
+	// Compare against the local chain if the node was already initialised
+	if ctx.IsSet(utils.DataDirFlag.Name) {
+		stack, _ := makeConfigNode(ctx)
+		defer stack.Close()
+
+		db := utils.MakeChainDatabase(ctx, stack, true)
+		defer db.Close()
+
+		stored := rawdb.ReadCanonicalHash(db, 0)
+		switch {
+		case stored == (common.Hash{}):
+			log.Info("Local database not initialised, skipping comparison")
+		case stored != block.Hash():
+			return fmt.Errorf("genesis hash mismatch with local database: have %v, database %v", block.Hash(), stored)
+		default:
+			log.Info("Genesis hash matches local database", "hash", stored)
+		}
+	}
+	return nil
+}
@@ -64,6 +64,7 @@ var (
 		utils.PasswordFileFlag,
 		utils.BootnodesFlag,
 		utils.MinFreeDiskSpaceFlag,
+		utils.ShutdownBudgetFlag,
 		utils.KeyStoreDirFlag,
 		utils.ExternalSignerFlag,
 		utils.NoUSBFlag,
@@ -83,6 +84,8 @@ var (
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolJournalRemotesFlag,
+		utils.TxPoolRemoteJournalFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -90,6 +93,19 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolFeeExemptFlag,
+		utils.TxPoolReplayProtectedFlag,
+		utils.TxPoolUnprotectedFlag,
+		utils.TxPoolSponsoredFlag,
+		utils.TxPoolSponsorBudgetFlag,
+		utils.TxPoolSponsorMaxGasFlag,
+		utils.TxPoolSponsorJournalFlag,
+		utils.TxManagerEnabledFlag,
+		utils.TxManagerStuckBlocksFlag,
+		utils.TxManagerPolicyFlag,
+		utils.TxManagerBumpPercentFlag,
+		utils.TxManagerMaxBumpsFlag,
+		utils.TxManagerNonceLeaseFlag,
 		utils.SyncModeFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
@@ -106,6 +122,21 @@ var (
 		utils.UltraLightOnlyAnnounceFlag,
 		utils.LightNoSyncServeFlag,
 		utils.EthRequiredBlocksFlag,
+		utils.EthStrictForkIDFlag,
+		utils.EthHeadLagPeriodsFlag,
+		utils.EthAdvertiseValidatorFlag,
+		utils.CliqueMaxFutureDriftFlag,
+		utils.CliqueTimestampJitterFlag,
+		utils.CliqueFastVerifyFlag,
+		utils.CliqueNTPServersFlag,
+		utils.CliqueNTPIntervalFlag,
+		utils.CliqueNTPMaxDriftFlag,
+		utils.CliqueNTPEnforceFlag,
+		utils.CliqueNoPreflightFlag,
+		utils.CliquePreflightLagFlag,
+		utils.CliquePreflightDiskFlag,
+		utils.ContractAnalyticsFlag,
+		utils.EpochStatsFlag,
 		utils.LegacyWhitelistFlag,
 		utils.BloomFilterSizeFlag,
 		utils.CacheFlag,
@@ -114,6 +145,7 @@ var (
 		utils.CacheTrieJournalFlag,
 		utils.CacheTrieRejournalFlag,
 		utils.CacheGCFlag,
+		utils.CacheGCAutoTuneFlag,
 		utils.CacheSnapshotFlag,
 		utils.CacheNoPrefetchFlag,
 		utils.CachePreimagesFlag,
@@ -129,18 +161,31 @@ var (
 		utils.MinerGasPriceFlag,
 		utils.MinerEtherbaseFlag,
 		utils.MinerExtraDataFlag,
+		utils.MinerVanityFlag,
+		utils.MinerVanityTagFlag,
+		utils.MinerDeterministicFlag,
+		utils.MinerPrefetchFlag,
+		utils.MinerWiggleMinFlag,
+		utils.MinerWiggleMaxFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerifyFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
+		utils.NetRulesFlag,
+		utils.GeoIPFlag,
+		utils.MaxSessionAgeFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.DNSDiscoveryFlag,
+		utils.SentryNodesFlag,
+		utils.SentryValidatorsFlag,
 		utils.DeveloperFlag,
 		utils.DeveloperPeriodFlag,
 		utils.DeveloperGasLimitFlag,
+		utils.DeveloperForkFlag,
+		utils.DeveloperForkBlockFlag,
 		utils.VMEnableDebugFlag,
 		utils.NetworkIdFlag,
 		utils.EthStatsURLFlag,
@@ -150,6 +195,7 @@ var (
 		utils.GpoPercentileFlag,
 		utils.GpoMaxGasPriceFlag,
 		utils.GpoIgnoreGasPriceFlag,
+		utils.GpoFixedTipFlag,
 		utils.MinerNotifyFullFlag,
 		configFileFlag,
 	}, utils.NetworkFlags, utils.DatabasePathFlags)
@@ -167,20 +213,43 @@ var (
 		utils.GraphQLEnabledFlag,
 		utils.GraphQLCORSDomainFlag,
 		utils.GraphQLVirtualHostsFlag,
+		utils.RosettaEnabledFlag,
+		utils.RosettaBlockchainFlag,
+		utils.RosettaSymbolFlag,
 		utils.HTTPApiFlag,
 		utils.HTTPPathPrefixFlag,
+		utils.HTTPCompressionThresholdFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
 		utils.WSPathPrefixFlag,
+		utils.WSCompressionFlag,
+		utils.WSCompressionThresholdFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 		utils.InsecureUnlockAllowedFlag,
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalEVMTimeoutFlag,
+		utils.RPCTraceCacheFlag,
+		utils.ExExSocketFlag,
+		utils.ExExStateDiffsFlag,
+		utils.FollowerServeFlag,
+		utils.FollowerPrimaryFlag,
+		utils.FollowerJWTSecretFlag,
+		utils.ReplicaStandbyFlag,
+		utils.MaintenanceBandwidthFlag,
+		utils.MaintenanceIOPSFlag,
+		utils.MaintenanceInTurnWindowFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCGasPriceFloorFlag,
+		utils.RPCGasPriceCeilFlag,
+		utils.RPCCliqueFieldsFlag,
+		utils.RPCSignedMethodsFlag,
+		utils.RPCArchiveFlag,
+		utils.RPCArchiveMethodsFlag,
+		utils.RPCQuotasFlag,
 		utils.AllowUnprotectedTxs,
 	}
 
@@ -217,6 +286,19 @@ func init() {
 		removedbCommand,
 		dumpCommand,
 		dumpGenesisCommand,
+		chainspecCommand,
+		// See replaycmd.go:
+		replayCommand,
+		// See rollbackcmd.go:
+		rollbackCommand,
+		// See gatewaycmd.go:
+		gatewayCommand,
+		// See shadowforkcmd.go:
+		shadowForkCommand,
+		// See genesiscmd.go:
+		genesisCommand,
+		// See cliquecmd.go:
+		cliqueCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -232,6 +314,9 @@ func init() {
 		licenseCommand,
 		// See config.go
 		dumpConfigCommand,
+		configCommand,
+		// See multicmd.go
+		multiCommand,
 		// see dbcmd.go
 		dbCommand,
 		// See cmd/utils/flags_legacy.go
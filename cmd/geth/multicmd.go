@@ -0,0 +1,278 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/naoina/toml"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var multiCommand = cli.Command{
+	Action:    utils.MigrateFlags(runMulti),
+	Name:      "multi",
+	Usage:     "Run several chain instances in a single process",
+	ArgsUsage: "<config.toml>",
+	Flags: []cli.Flag{
+		utils.MetricsEnabledFlag,
+		utils.MetricsEnabledExpensiveFlag,
+		utils.MetricsHTTPFlag,
+		utils.MetricsPortFlag,
+	},
+	Category: "MISCELLANEOUS COMMANDS",
+	Description: `
+The multi command runs several chain instances, e.g. a mainnet and a testnet
+node, or the validators of several consortium chains, in a single process. The
+configuration file lists the instances, each of them pointing at a regular geth
+TOML configuration (as produced by dumpconfig) with its own data directory,
+genesis, clique settings and ports:
+
+    [[Instance]]
+    Name = "mainnet"
+    Config = "/etc/aksara/mainnet.toml"
+    Mine = true
+    Unlock = ["0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8"]
+    PasswordFile = "/etc/aksara/mainnet.pass"
+
+    [[Instance]]
+    Name = "testnet"
+    Config = "/etc/aksara/testnet.toml"
+
+Command line flags don't apply to the instances. Logging, metrics and profiling
+are process-wide and shared by all of them.`,
+}
+
+// multiConfig is the configuration of the multi command.
+type multiConfig struct {
+	Instance []multiInstance
+}
+
+// multiInstance is a chain instance run by the multi command.
+type multiInstance struct {
+	Name         string   // Name tagging the logs of the instance's node
+	Config       string   // Path to the geth TOML configuration of the instance
+	Mine         bool     `toml:",omitempty"` // Whether to seal blocks
+	Unlock       []string `toml:",omitempty"` // Accounts to unlock
+	PasswordFile string   `toml:",omitempty"` // Passwords of the accounts to unlock, one per line
+}
+
+// loadMultiConfig loads the multi command configuration along with the geth
+// configurations of its instances.
+func loadMultiConfig(file string) (*multiConfig, []gethConfig, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	config := new(multiConfig)
+	if err := tomlSettings.NewDecoder(bufio.NewReader(f)).Decode(config); err != nil {
+		if _, ok := err.(*toml.LineError); ok {
+			err = errors.New(file + ", " + err.Error())
+		}
+		return nil, nil, err
+	}
+	if len(config.Instance) == 0 {
+		return nil, nil, fmt.Errorf("%s: no instances configured", file)
+	}
+	configs := make([]gethConfig, len(config.Instance))
+	for i, inst := range config.Instance {
+		configs[i] = gethConfig{
+			Eth:     ethconfig.Defaults,
+			Node:    defaultNodeConfig(),
+			Metrics: metrics.DefaultConfig,
+		}
+		path := inst.Config
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+		if err := loadConfig(path, &configs[i]); err != nil {
+			return nil, nil, fmt.Errorf("instance %q: %v", inst.Name, err)
+		}
+	}
+	if err := checkMultiInstances(config.Instance, configs); err != nil {
+		return nil, nil, err
+	}
+	return config, configs, nil
+}
+
+// checkMultiInstances ensures the instances can run side by side, not sharing
+// data directories nor listening on the same endpoints.
+func checkMultiInstances(instances []multiInstance, configs []gethConfig) error {
+	var (
+		names     = make(map[string]bool)
+		datadirs  = make(map[string]string)
+		endpoints = make(map[string]string)
+	)
+	claim := func(name, kind, endpoint string) error {
+		if endpoint == "" {
+			return nil
+		}
+		if other, ok := endpoints[endpoint]; ok {
+			return fmt.Errorf("instance %q: %s endpoint %s already used by %s", name, kind, endpoint, other)
+		}
+		endpoints[endpoint] = fmt.Sprintf("instance %q", name)
+		return nil
+	}
+	for i, inst := range instances {
+		if inst.Name == "" {
+			return fmt.Errorf("instance %d: missing name", i)
+		}
+		if names[inst.Name] {
+			return fmt.Errorf("instance %q: duplicate name", inst.Name)
+		}
+		names[inst.Name] = true
+
+		cfg := &configs[i].Node
+		if cfg.DataDir == "" {
+			return fmt.Errorf("instance %q: missing data directory", inst.Name)
+		}
+		datadir, err := filepath.Abs(cfg.DataDir)
+		if err != nil {
+			return fmt.Errorf("instance %q: %v", inst.Name, err)
+		}
+		if other, ok := datadirs[datadir]; ok {
+			return fmt.Errorf("instance %q: data directory %s already used by instance %q", inst.Name, datadir, other)
+		}
+		datadirs[datadir] = inst.Name
+
+		if cfg.P2P.ListenAddr != "" {
+			if _, port, err := net.SplitHostPort(cfg.P2P.ListenAddr); err == nil && port != "0" {
+				if err := claim(inst.Name, "p2p", "p2p:"+port); err != nil {
+					return err
+				}
+			}
+		}
+		if cfg.HTTPHost != "" && cfg.HTTPPort != 0 {
+			if err := claim(inst.Name, "http", cfg.HTTPEndpoint()); err != nil {
+				return err
+			}
+		}
+		if cfg.WSHost != "" && cfg.WSPort != 0 && (cfg.WSHost != cfg.HTTPHost || cfg.WSPort != cfg.HTTPPort) {
+			if err := claim(inst.Name, "ws", cfg.WSEndpoint()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runMulti creates, starts and runs the configured chain instances until all
+// of them are shut down.
+func runMulti(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("expected the multi instance configuration file as the only argument")
+	}
+	config, configs, err := loadMultiConfig(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	// Start the process-wide metrics export and collection
+	utils.SetupMetrics(ctx)
+	go metrics.CollectProcessMetrics(3 * time.Second)
+
+	stacks := make([]*node.Node, len(configs))
+	backends := make([]*eth.Ethereum, len(configs))
+	for i := range configs {
+		cfg, inst := &configs[i], config.Instance[i]
+
+		cfg.Node.Logger = log.New("chain", inst.Name)
+		stack, err := node.New(&cfg.Node)
+		if err != nil {
+			return fmt.Errorf("instance %q: failed to create the protocol stack: %v", inst.Name, err)
+		}
+		defer stack.Close()
+
+		if err := setAccountManagerBackends(stack); err != nil {
+			return fmt.Errorf("instance %q: failed to set account manager backends: %v", inst.Name, err)
+		}
+		backend, ethereum := utils.RegisterEthService(stack, &cfg.Eth)
+		if cfg.Ethstats.URL != "" {
+			utils.RegisterEthStatsService(stack, backend, cfg.Ethstats.URL)
+		}
+		if inst.Mine && ethereum == nil {
+			return fmt.Errorf("instance %q: light clients do not support mining", inst.Name)
+		}
+		stacks[i], backends[i] = stack, ethereum
+	}
+	for i, stack := range stacks {
+		inst := config.Instance[i]
+
+		log.Info("Starting chain instance", "chain", inst.Name, "datadir", stack.DataDir())
+		utils.StartNode(ctx, stack, false)
+
+		if err := unlockInstanceAccounts(stack, inst); err != nil {
+			return fmt.Errorf("instance %q: %v", inst.Name, err)
+		}
+		if inst.Mine {
+			backends[i].TxPool().SetGasPrice(configs[i].Eth.Miner.GasPrice)
+			if err := backends[i].StartMining(0); err != nil {
+				return fmt.Errorf("instance %q: failed to start mining: %v", inst.Name, err)
+			}
+		}
+	}
+	for _, stack := range stacks {
+		stack.Wait()
+	}
+	return nil
+}
+
+// unlockInstanceAccounts unlocks the accounts requested by an instance.
+func unlockInstanceAccounts(stack *node.Node, inst multiInstance) error {
+	if len(inst.Unlock) == 0 {
+		return nil
+	}
+	// Insecure account unlocking is not allowed if the node's APIs are exposed
+	if !stack.Config().InsecureUnlockAllowed && stack.Config().ExtRPCEnabled() {
+		return errors.New("account unlock with HTTP access is forbidden")
+	}
+	var passwords []string
+	if inst.PasswordFile != "" {
+		text, err := os.ReadFile(inst.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("failed to read password file: %v", err)
+		}
+		passwords = strings.Split(string(text), "\n")
+		for i := range passwords {
+			passwords[i] = strings.TrimRight(passwords[i], "\r")
+		}
+	}
+	backends := stack.AccountManager().Backends(keystore.KeyStoreType)
+	if len(backends) == 0 {
+		return errors.New("keystore is not available")
+	}
+	ks := backends[0].(*keystore.KeyStore)
+	for i, account := range inst.Unlock {
+		unlockAccount(ks, strings.TrimSpace(account), i, passwords)
+	}
+	return nil
+}
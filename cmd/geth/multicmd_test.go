@@ -0,0 +1,64 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests that multi instance configurations are loaded with the instance configs
+// resolved relative to the top-level file, and that conflicting instances are
+// rejected.
+func TestLoadMultiConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("a.toml", "[Node]\nDataDir = \"a\"\nHTTPHost = \"127.0.0.1\"\nHTTPPort = 8545\n[Node.P2P]\nListenAddr = \":30303\"\n")
+	write("b.toml", "[Node]\nDataDir = \"b\"\nHTTPHost = \"127.0.0.1\"\nHTTPPort = 8555\n[Node.P2P]\nListenAddr = \":30313\"\n")
+	write("c.toml", "[Node]\nDataDir = \"c\"\nHTTPHost = \"127.0.0.1\"\nHTTPPort = 8545\n[Node.P2P]\nListenAddr = \":30323\"\n")
+
+	valid := write("valid.toml", "[[Instance]]\nName = \"a\"\nConfig = \"a.toml\"\n\n[[Instance]]\nName = \"b\"\nConfig = \"b.toml\"\nMine = true\n")
+	config, configs, err := loadMultiConfig(valid)
+	if err != nil {
+		t.Fatalf("failed to load valid config: %v", err)
+	}
+	if len(config.Instance) != 2 || !config.Instance[1].Mine {
+		t.Fatalf("instances mismatch: %+v", config.Instance)
+	}
+	if configs[1].Node.DataDir != "b" || configs[1].Node.HTTPPort != 8555 {
+		t.Fatalf("instance config mismatch: %+v", configs[1].Node)
+	}
+	tests := map[string]string{
+		"empty":     "",
+		"unnamed":   "[[Instance]]\nConfig = \"a.toml\"\n",
+		"duplicate": "[[Instance]]\nName = \"a\"\nConfig = \"a.toml\"\n\n[[Instance]]\nName = \"a\"\nConfig = \"b.toml\"\n",
+		"datadir":   "[[Instance]]\nName = \"a\"\nConfig = \"a.toml\"\n\n[[Instance]]\nName = \"b\"\nConfig = \"a.toml\"\n",
+		"http":      "[[Instance]]\nName = \"a\"\nConfig = \"a.toml\"\n\n[[Instance]]\nName = \"c\"\nConfig = \"c.toml\"\n",
+	}
+	for name, content := range tests {
+		if _, _, err := loadMultiConfig(write(name+".toml", content)); err == nil {
+			t.Errorf("%s: invalid config accepted", name)
+		}
+	}
+}
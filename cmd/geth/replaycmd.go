@@ -0,0 +1,282 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	replayBlockFlag = cli.Uint64Flag{
+		Name:  "block",
+		Usage: "Number of the block to re-execute",
+	}
+	replayTraceFlag = cli.StringFlag{
+		Name:  "trace",
+		Usage: "File to write the JSON opcode trace of the re-execution to",
+	}
+	replayTraceMemoryFlag = cli.BoolFlag{
+		Name:  "trace.memory",
+		Usage: "Include EVM memory in the opcode trace",
+	}
+	replayTraceStorageFlag = cli.BoolFlag{
+		Name:  "trace.nostorage",
+		Usage: "Exclude contract storage from the opcode trace",
+	}
+
+	replayCommand = cli.Command{
+		Action:    utils.MigrateFlags(replayBlock),
+		Name:      "replay",
+		Usage:     "Re-execute a block against its parent state and diff the results",
+		ArgsUsage: "",
+		Flags: utils.GroupFlags([]cli.Flag{
+			utils.CacheFlag,
+			replayBlockFlag,
+			replayTraceFlag,
+			replayTraceMemoryFlag,
+			replayTraceStorageFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+geth replay --block <number> [--trace <file>]
+
+Re-executes the transactions of the given canonical block on top of the state
+of its parent and compares the outcome with the locally stored block: the state
+root, the receipt root, the gas used and every individual receipt. The report is
+printed as JSON and the command fails if any field diverges. The parent state
+must be available locally, so historical blocks require an archive node.
+
+With --trace, the full opcode trace of the re-execution is written to the given
+file as JSON lines, which can be compared between two builds to pinpoint the
+first diverging instruction.`,
+	}
+)
+
+// replayReport is the outcome of re-executing a block.
+type replayReport struct {
+	Number       uint64            `json:"number"`
+	Hash         common.Hash       `json:"hash"`
+	Error        string            `json:"error,omitempty"`
+	StateRoot    *replayHashDiff   `json:"stateRoot,omitempty"`
+	ReceiptRoot  *replayHashDiff   `json:"receiptRoot,omitempty"`
+	Bloom        bool              `json:"bloomMismatch,omitempty"`
+	GasUsed      *replayUintDiff   `json:"gasUsed,omitempty"`
+	Receipts     []*replayTxReport `json:"receipts,omitempty"`
+	Transactions int               `json:"transactions"`
+	Diverged     bool              `json:"diverged"`
+}
+
+// replayTxReport lists the fields of a single receipt that diverge between the
+// stored and the re-executed version.
+type replayTxReport struct {
+	Index             int             `json:"index"`
+	Hash              common.Hash     `json:"hash"`
+	Status            *replayUintDiff `json:"status,omitempty"`
+	GasUsed           *replayUintDiff `json:"gasUsed,omitempty"`
+	CumulativeGasUsed *replayUintDiff `json:"cumulativeGasUsed,omitempty"`
+	Logs              *replayUintDiff `json:"logs,omitempty"`
+	LogMismatch       []int           `json:"logMismatch,omitempty"`
+	ContractAddress   *replayAddrDiff `json:"contractAddress,omitempty"`
+	Missing           bool            `json:"missing,omitempty"`
+}
+
+type replayHashDiff struct {
+	Stored   common.Hash `json:"stored"`
+	Replayed common.Hash `json:"replayed"`
+}
+
+type replayAddrDiff struct {
+	Stored   common.Address `json:"stored"`
+	Replayed common.Address `json:"replayed"`
+}
+
+type replayUintDiff struct {
+	Stored   uint64 `json:"stored"`
+	Replayed uint64 `json:"replayed"`
+}
+
+func replayBlock(ctx *cli.Context) error {
+	if !ctx.IsSet(replayBlockFlag.Name) {
+		utils.Fatalf("This command requires --%s.", replayBlockFlag.Name)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+	defer chain.Stop()
+
+	number := ctx.Uint64(replayBlockFlag.Name)
+
+	var vmConfig vm.Config
+	if path := ctx.String(replayTraceFlag.Name); path != "" {
+		out, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		vmConfig = vm.Config{
+			Debug: true,
+			Tracer: logger.NewJSONLogger(&logger.Config{
+				EnableMemory:   ctx.Bool(replayTraceMemoryFlag.Name),
+				DisableStorage: ctx.Bool(replayTraceStorageFlag.Name),
+			}, out),
+		}
+		log.Info("Tracing block re-execution", "file", path)
+	}
+	report, err := replay(chain, db, number, vmConfig)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	if report.Diverged {
+		return fmt.Errorf("block #%d diverges from its re-execution", number)
+	}
+	log.Info("Block re-execution matches stored data", "number", number, "hash", report.Hash)
+	return nil
+}
+
+// replay re-executes the canonical block with the given number on top of the
+// state of its parent and reports how the outcome compares to the stored one.
+func replay(chain *core.BlockChain, db ethdb.Reader, number uint64, vmConfig vm.Config) (*replayReport, error) {
+	if number == 0 {
+		return nil, errors.New("genesis block cannot be replayed")
+	}
+	block := chain.GetBlockByNumber(number)
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	parent := chain.GetBlock(block.ParentHash(), number-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent of block #%d not found", number)
+	}
+	statedb, err := chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, fmt.Errorf("parent state %x unavailable, replaying requires the parent state to be present: %v", parent.Root(), err)
+	}
+	log.Info("Replaying block", "number", number, "hash", block.Hash(), "txs", len(block.Transactions()))
+
+	report := &replayReport{
+		Number:       number,
+		Hash:         block.Hash(),
+		Transactions: len(block.Transactions()),
+	}
+	receipts, _, usedGas, err := core.NewStateProcessor(chain.Config(), chain, chain.Engine()).Process(block, statedb, vmConfig)
+	if err != nil {
+		report.Error, report.Diverged = err.Error(), true
+	} else {
+		root := statedb.IntermediateRoot(chain.Config().IsEIP158(block.Number()))
+		stored := rawdb.ReadReceipts(db, block.Hash(), number, chain.Config())
+		diffReplay(report, block, root, usedGas, receipts, stored)
+	}
+	return report, nil
+}
+
+// diffReplay compares the re-executed results of a block with the stored ones
+// and fills in the mismatching fields of the report.
+func diffReplay(report *replayReport, block *types.Block, root common.Hash, usedGas uint64, replayed, stored types.Receipts) {
+	if root != block.Root() {
+		report.StateRoot = &replayHashDiff{Stored: block.Root(), Replayed: root}
+	}
+	if usedGas != block.GasUsed() {
+		report.GasUsed = &replayUintDiff{Stored: block.GasUsed(), Replayed: usedGas}
+	}
+	if hash := types.DeriveSha(replayed, trie.NewStackTrie(nil)); hash != block.ReceiptHash() {
+		report.ReceiptRoot = &replayHashDiff{Stored: block.ReceiptHash(), Replayed: hash}
+	}
+	if types.CreateBloom(replayed) != block.Bloom() {
+		report.Bloom = true
+	}
+	for i, receipt := range replayed {
+		diff := &replayTxReport{Index: i, Hash: receipt.TxHash}
+		if i >= len(stored) {
+			diff.Missing = true
+			report.Receipts = append(report.Receipts, diff)
+			continue
+		}
+		if !diffReceipt(diff, stored[i], receipt) {
+			report.Receipts = append(report.Receipts, diff)
+		}
+	}
+	report.Diverged = report.StateRoot != nil || report.GasUsed != nil || report.ReceiptRoot != nil || report.Bloom || len(report.Receipts) > 0
+}
+
+// diffReceipt compares two receipts field by field, returning whether they
+// are identical.
+func diffReceipt(diff *replayTxReport, stored, replayed *types.Receipt) bool {
+	match := true
+	if stored.Status != replayed.Status {
+		diff.Status = &replayUintDiff{Stored: stored.Status, Replayed: replayed.Status}
+		match = false
+	}
+	if stored.GasUsed != replayed.GasUsed {
+		diff.GasUsed = &replayUintDiff{Stored: stored.GasUsed, Replayed: replayed.GasUsed}
+		match = false
+	}
+	if stored.CumulativeGasUsed != replayed.CumulativeGasUsed {
+		diff.CumulativeGasUsed = &replayUintDiff{Stored: stored.CumulativeGasUsed, Replayed: replayed.CumulativeGasUsed}
+		match = false
+	}
+	if stored.ContractAddress != replayed.ContractAddress {
+		diff.ContractAddress = &replayAddrDiff{Stored: stored.ContractAddress, Replayed: replayed.ContractAddress}
+		match = false
+	}
+	if len(stored.Logs) != len(replayed.Logs) {
+		diff.Logs = &replayUintDiff{Stored: uint64(len(stored.Logs)), Replayed: uint64(len(replayed.Logs))}
+		return false
+	}
+	for j := range stored.Logs {
+		if !equalLogs(stored.Logs[j], replayed.Logs[j]) {
+			diff.LogMismatch = append(diff.LogMismatch, j)
+			match = false
+		}
+	}
+	return match
+}
+
+func equalLogs(a, b *types.Log) bool {
+	if a.Address != b.Address || len(a.Topics) != len(b.Topics) || !bytes.Equal(a.Data, b.Data) {
+		return false
+	}
+	for i := range a.Topics {
+		if a.Topics[i] != b.Topics[i] {
+			return false
+		}
+	}
+	return true
+}
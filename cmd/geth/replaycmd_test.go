@@ -0,0 +1,91 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the blocks of a generated chain replay to their stored results,
+// and that tampered receipts are reported as diverging.
+func TestReplayBlock(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		signer  = types.LatestSigner(params.TestChainConfig)
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(params.Ether)}},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 3, func(i int, block *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), params.TxGas, block.BaseFee(), nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		block.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	for number := uint64(1); number <= 3; number++ {
+		report, err := replay(chain, db, number, vm.Config{})
+		if err != nil {
+			t.Fatalf("block %d: failed to replay: %v", number, err)
+		}
+		if report.Diverged || report.Transactions != 1 || report.Hash != blocks[number-1].Hash() {
+			t.Errorf("block %d: report mismatch: %+v", number, report)
+		}
+	}
+	// Tamper with the stored receipt of a block and ensure it's caught
+	block := blocks[1]
+	receipts := rawdb.ReadRawReceipts(db, block.Hash(), block.NumberU64())
+	receipts[0].CumulativeGasUsed++
+	rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts)
+
+	report, err := replay(chain, db, block.NumberU64(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to replay tampered block: %v", err)
+	}
+	if !report.Diverged || len(report.Receipts) != 1 || report.Receipts[0].CumulativeGasUsed == nil || report.StateRoot != nil {
+		t.Errorf("tampered receipt not reported: %+v", report)
+	}
+	if _, err := replay(chain, db, 0, vm.Config{}); err == nil {
+		t.Errorf("genesis block replayed")
+	}
+	if _, err := replay(chain, db, 4, vm.Config{}); err == nil {
+		t.Errorf("missing block replayed")
+	}
+}
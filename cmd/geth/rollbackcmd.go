@@ -0,0 +1,95 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	rollbackEpochFlag = cli.Uint64Flag{
+		Name:  "to-epoch",
+		Usage: "Epoch to roll the chain back to",
+	}
+
+	rollbackCommand = cli.Command{
+		Action:    utils.MigrateFlags(rollbackChain),
+		Name:      "rollback",
+		Usage:     "Rewind the chain head to the block opening a clique epoch",
+		ArgsUsage: "",
+		Flags: utils.GroupFlags([]cli.Flag{
+			utils.CacheFlag,
+			rollbackEpochFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+geth rollback --to-epoch <epoch>
+
+Rewinds the chain head to the epoch block which opened the given clique epoch,
+for recovering from bad imports. A checkpoint is recorded at every epoch block
+imported or sealed, and the rollback only proceeds if the checkpoint block is
+still canonical, below the head, its clique snapshot unchanged and its state
+available, so the chain lands exactly on the epoch boundary. The clique
+snapshots and checkpoints of the later epochs are deleted along with the blocks.`,
+	}
+)
+
+func rollbackChain(ctx *cli.Context) error {
+	if !ctx.IsSet(rollbackEpochFlag.Name) {
+		utils.Fatalf("This command requires --%s.", rollbackEpochFlag.Name)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+	defer chain.Stop()
+
+	config := chain.Config().Clique
+	if config == nil {
+		return errors.New("chain is not running clique consensus")
+	}
+	epoch := ctx.Uint64(rollbackEpochFlag.Name)
+	checkpoint, err := clique.CheckRollback(db, config, epoch)
+	if err != nil {
+		return err
+	}
+	if !chain.HasState(checkpoint.Root) {
+		return fmt.Errorf("state of checkpoint block #%d unavailable, the chain would be rewound past the epoch boundary", checkpoint.Number)
+	}
+	head := chain.CurrentBlock().NumberU64()
+	log.Info("Rolling back chain", "epoch", epoch, "number", checkpoint.Number, "hash", checkpoint.Hash, "head", head)
+
+	if err := chain.SetHead(checkpoint.Number); err != nil {
+		return err
+	}
+	if current := chain.CurrentBlock(); current.Hash() != checkpoint.Hash {
+		return fmt.Errorf("chain rewound to #%d [%x] instead of checkpoint block #%d", current.NumberU64(), current.Hash().Bytes()[:4], checkpoint.Number)
+	}
+	deleted, err := clique.RewindCheckpoints(db, config, checkpoint.Number)
+	if err != nil {
+		return err
+	}
+	log.Info("Rolled back chain", "epoch", epoch, "number", checkpoint.Number, "hash", checkpoint.Hash, "dropped", head-checkpoint.Number, "deleted", deleted)
+	return nil
+}
@@ -0,0 +1,270 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	shadowForkBlockFlag = cli.Uint64Flag{
+		Name:  "at-block",
+		Usage: "Number of the block whose state seeds the shadow fork",
+	}
+	shadowForkChainIDFlag = cli.Uint64Flag{
+		Name:  "new-chainid",
+		Usage: "Chain id of the shadow fork",
+	}
+	shadowForkTargetFlag = cli.StringFlag{
+		Name:  "target",
+		Usage: "Data directory to create the shadow fork in",
+	}
+	shadowForkSignersFlag = cli.StringFlag{
+		Name:  "signers",
+		Usage: "Comma separated list of signer addresses sealing the shadow fork",
+	}
+	shadowForkPeriodFlag = cli.Uint64Flag{
+		Name:  "period",
+		Usage: "Block period of the shadow fork in seconds (defaults to the source chain's)",
+	}
+
+	shadowForkCommand = cli.Command{
+		Action:    utils.MigrateFlags(shadowFork),
+		Name:      "shadowfork",
+		Usage:     "Copy the state at a block into a new chain with a local signer set",
+		ArgsUsage: "",
+		Flags: utils.GroupFlags([]cli.Flag{
+			utils.CacheFlag,
+			shadowForkBlockFlag,
+			shadowForkChainIDFlag,
+			shadowForkTargetFlag,
+			shadowForkSignersFlag,
+			shadowForkPeriodFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+geth shadowfork --at-block <number> --new-chainid <id> --target <datadir> --signers <addr,...>
+
+Creates a new data directory whose genesis block carries the complete state of
+the given block of the local chain. The chain configuration is copied from the
+source chain with the chain id replaced and the clique signer set replaced by
+the given local signers, so upgrades can be rehearsed against real state before
+activation on the live network. The validator registry is not followed by the
+shadow fork, its signer set stays the given one.
+
+The shadow fork starts at block number zero with the timestamp, gas limit and
+base fee of the source block. The source state must be available locally, which
+for historical blocks requires an archive node.`,
+	}
+)
+
+func shadowFork(ctx *cli.Context) error {
+	for _, flag := range []cli.Flag{shadowForkBlockFlag, shadowForkChainIDFlag, shadowForkTargetFlag, shadowForkSignersFlag} {
+		if !ctx.IsSet(flag.GetName()) {
+			utils.Fatalf("This command requires --%s.", flag.GetName())
+		}
+	}
+	var signers []common.Address
+	for _, signer := range utils.SplitAndTrim(ctx.String(shadowForkSignersFlag.Name)) {
+		if !common.IsHexAddress(signer) {
+			utils.Fatalf("Invalid signer address: %s", signer)
+		}
+		signers = append(signers, common.HexToAddress(signer))
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+	defer chain.Stop()
+
+	number := ctx.Uint64(shadowForkBlockFlag.Name)
+	source := chain.GetHeaderByNumber(number)
+	if source == nil {
+		return fmt.Errorf("block #%d not found", number)
+	}
+	if !chain.HasState(source.Root) {
+		return fmt.Errorf("state %x of block #%d unavailable", source.Root, number)
+	}
+	if chain.Config().Clique == nil {
+		return errors.New("shadow forks are only supported for clique chains")
+	}
+	genesis := shadowForkGenesis(chain.Config(), ctx.Uint64(shadowForkChainIDFlag.Name), signers, ctx.Uint64(shadowForkPeriodFlag.Name))
+
+	// Open the target database, refusing to overwrite an existing chain
+	target, err := node.New(&node.Config{DataDir: ctx.String(shadowForkTargetFlag.Name), Name: clientIdentifier})
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	targetDb, err := target.OpenDatabaseWithFreezer("chaindata", 0, 0, "", "", false)
+	if err != nil {
+		return err
+	}
+	defer targetDb.Close()
+
+	if rawdb.ReadCanonicalHash(targetDb, 0) != (common.Hash{}) {
+		return errors.New("target data directory already contains a chain")
+	}
+	log.Info("Copying state into shadow fork", "number", number, "root", source.Root)
+	if err := copyState(db, targetDb, source.Root); err != nil {
+		return err
+	}
+	block, err := genesis.CommitFork(targetDb, source)
+	if err != nil {
+		return err
+	}
+
+	// Seed the registry of the new chain with the local signer set
+	clique.NewDNR(genesis.Config.Clique, targetDb)
+
+	log.Info("Created shadow fork", "datadir", ctx.String(shadowForkTargetFlag.Name), "chainid", genesis.Config.ChainID, "genesis", block.Hash(), "root", block.Root(), "signers", len(signers))
+	return nil
+}
+
+// shadowForkGenesis derives the genesis of a shadow fork of a clique chain,
+// sealed by the given local signers. The registry the source chain follows is
+// dropped, so that the shadow fork keeps its signer set instead of picking up
+// the validators of the live network. A zero period keeps the source chain's.
+func shadowForkGenesis(source *params.ChainConfig, chainID uint64, signers []common.Address, period uint64) *core.Genesis {
+	config := *source
+	cliqueConfig := *source.Clique
+	cliqueConfig.InitialValidators = signers
+	cliqueConfig.API = ""
+	cliqueConfig.DNR = common.Address{}
+	if period != 0 {
+		cliqueConfig.Period = period
+	}
+	config.Clique = &cliqueConfig
+	config.ChainID = new(big.Int).SetUint64(chainID)
+
+	extra := make([]byte, 32, 32+len(signers)*common.AddressLength+crypto.SignatureLength)
+	for _, signer := range signers {
+		extra = append(extra, signer[:]...)
+	}
+	extra = append(extra, make([]byte, crypto.SignatureLength)...)
+
+	return &core.Genesis{
+		Config:     &config,
+		Nonce:      config.Clique.EpochBlock,
+		ExtraData:  extra,
+		Difficulty: big.NewInt(1),
+	}
+}
+
+// copyState copies all trie nodes and contract codes reachable from the given
+// state root from one database into another.
+func copyState(src ethdb.Database, dst ethdb.Database, root common.Hash) error {
+	var (
+		batch      = dst.NewBatch()
+		triedb     = trie.NewDatabase(src)
+		nodes      int
+		codes      int
+		lastReport time.Time
+		start      = time.Now()
+	)
+	flush := func(force bool) error {
+		if !force && batch.ValueSize() < ethdb.IdealBatchSize {
+			return nil
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+		return nil
+	}
+	copyTrie := func(root common.Hash, onLeaf func(blob []byte) error) error {
+		t, err := trie.NewSecure(root, triedb)
+		if err != nil {
+			return err
+		}
+		it := t.NodeIterator(nil)
+		for it.Next(true) {
+			// Embedded nodes don't have their own hash and are stored in their parent
+			if hash := it.Hash(); hash != (common.Hash{}) {
+				blob := rawdb.ReadTrieNode(src, hash)
+				if len(blob) == 0 {
+					return fmt.Errorf("missing trie node %x", hash)
+				}
+				rawdb.WriteTrieNode(batch, hash, blob)
+				nodes++
+			}
+			if it.Leaf() && onLeaf != nil {
+				if err := onLeaf(it.LeafBlob()); err != nil {
+					return err
+				}
+			}
+			if err := flush(false); err != nil {
+				return err
+			}
+			if time.Since(lastReport) > 8*time.Second {
+				log.Info("Copying state", "nodes", nodes, "codes", codes, "elapsed", common.PrettyDuration(time.Since(start)))
+				lastReport = time.Now()
+			}
+		}
+		return it.Error()
+	}
+	err := copyTrie(root, func(blob []byte) error {
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(blob, &acc); err != nil {
+			return err
+		}
+		if acc.Root != emptyRoot {
+			if err := copyTrie(acc.Root, nil); err != nil {
+				return err
+			}
+		}
+		if !bytes.Equal(acc.CodeHash, emptyCode) {
+			hash := common.BytesToHash(acc.CodeHash)
+			code := rawdb.ReadCode(src, hash)
+			if len(code) == 0 {
+				return fmt.Errorf("missing code %x", hash)
+			}
+			rawdb.WriteCode(batch, hash, code)
+			codes++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := flush(true); err != nil {
+		return err
+	}
+	log.Info("Copied state", "nodes", nodes, "codes", codes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
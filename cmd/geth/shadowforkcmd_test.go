@@ -0,0 +1,105 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the genesis of a shadow fork drops the registry of the source
+// chain and is sealed by the local signers only.
+func TestShadowForkGenesis(t *testing.T) {
+	var (
+		local, _  = crypto.GenerateKey()
+		remote, _ = crypto.GenerateKey()
+		signer    = crypto.PubkeyToAddress(local.PublicKey)
+	)
+	source := *params.AllCliqueProtocolChanges
+	source.Clique = &params.CliqueConfig{
+		Period:            5,
+		DNR:               common.Address{0xdd},
+		EpochBlock:        10,
+		API:               "http://registry.invalid",
+		InitialValidators: []common.Address{crypto.PubkeyToAddress(remote.PublicKey)},
+	}
+	genesis := shadowForkGenesis(&source, 1337, []common.Address{signer}, 0)
+
+	config := genesis.Config.Clique
+	if config.API != "" || config.DNR != (common.Address{}) {
+		t.Fatalf("registry of the source chain kept: api %q, dnr %x", config.API, config.DNR)
+	}
+	if config.Period != 5 || genesis.Config.ChainID.Uint64() != 1337 {
+		t.Fatalf("shadow fork parameters mismatch: period %d, chain id %v", config.Period, genesis.Config.ChainID)
+	}
+	if source.Clique.API == "" || len(source.Clique.InitialValidators) != 1 || source.Clique.InitialValidators[0] == signer {
+		t.Fatalf("source chain config modified: %+v", source.Clique)
+	}
+	// Seal the first block of the shadow fork and verify it
+	db := rawdb.NewMemoryDatabase()
+	block := genesis.MustCommit(db)
+	clique.NewDNR(config, db)
+
+	engine := clique.New(config, db)
+	defer engine.Close()
+
+	chain, err := core.NewBlockChain(db, nil, genesis.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create shadow fork chain: %v", err)
+	}
+	defer chain.Stop()
+
+	engine.Authorize(signer, func(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+		return crypto.Sign(crypto.Keccak256(data), local)
+	})
+	seal := func(key *ecdsa.PrivateKey) *types.Header {
+		header := &types.Header{
+			ParentHash: block.Hash(),
+			Number:     big.NewInt(1),
+			UncleHash:  types.EmptyUncleHash,
+			GasLimit:   block.GasLimit(),
+			BaseFee:    misc.CalcBaseFee(genesis.Config, block.Header()),
+		}
+		if err := engine.Prepare(chain, header); err != nil {
+			t.Fatalf("failed to prepare header: %v", err)
+		}
+		sig, err := crypto.Sign(clique.SealHash(header).Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to seal header: %v", err)
+		}
+		copy(header.Extra[len(header.Extra)-crypto.SignatureLength:], sig)
+		return header
+	}
+	if err := engine.VerifyHeader(chain, seal(local), true); err != nil {
+		t.Errorf("block sealed by local signer rejected: %v", err)
+	}
+	if err := engine.VerifyHeader(chain, seal(remote), true); err == nil {
+		t.Errorf("block sealed by source chain signer accepted")
+	}
+}
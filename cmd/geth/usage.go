@@ -35,6 +35,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 		Flags: utils.GroupFlags([]cli.Flag{
 			configFileFlag,
 			utils.MinFreeDiskSpaceFlag,
+			utils.ShutdownBudgetFlag,
 			utils.KeyStoreDirFlag,
 			utils.USBFlag,
 			utils.SmartCardDaemonPathFlag,
@@ -47,6 +48,21 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
 			utils.EthRequiredBlocksFlag,
+			utils.EthStrictForkIDFlag,
+			utils.EthHeadLagPeriodsFlag,
+			utils.EthAdvertiseValidatorFlag,
+			utils.CliqueMaxFutureDriftFlag,
+			utils.CliqueTimestampJitterFlag,
+			utils.CliqueFastVerifyFlag,
+			utils.CliqueNTPServersFlag,
+			utils.CliqueNTPIntervalFlag,
+			utils.CliqueNTPMaxDriftFlag,
+			utils.CliqueNTPEnforceFlag,
+			utils.CliqueNoPreflightFlag,
+			utils.CliquePreflightLagFlag,
+			utils.CliquePreflightDiskFlag,
+			utils.ContractAnalyticsFlag,
+			utils.EpochStatsFlag,
 		}, utils.NetworkFlags, utils.DatabasePathFlags),
 	},
 	{
@@ -69,6 +85,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.DeveloperFlag,
 			utils.DeveloperPeriodFlag,
 			utils.DeveloperGasLimitFlag,
+			utils.DeveloperForkFlag,
+			utils.DeveloperForkBlockFlag,
 		},
 	},
 	{
@@ -91,6 +109,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
+			utils.TxPoolJournalRemotesFlag,
+			utils.TxPoolRemoteJournalFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
@@ -98,6 +118,24 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolFeeExemptFlag,
+			utils.TxPoolReplayProtectedFlag,
+			utils.TxPoolUnprotectedFlag,
+			utils.TxPoolSponsoredFlag,
+			utils.TxPoolSponsorBudgetFlag,
+			utils.TxPoolSponsorMaxGasFlag,
+			utils.TxPoolSponsorJournalFlag,
+		},
+	},
+	{
+		Name: "TRANSACTION MANAGER",
+		Flags: []cli.Flag{
+			utils.TxManagerEnabledFlag,
+			utils.TxManagerStuckBlocksFlag,
+			utils.TxManagerPolicyFlag,
+			utils.TxManagerBumpPercentFlag,
+			utils.TxManagerMaxBumpsFlag,
+			utils.TxManagerNonceLeaseFlag,
 		},
 	},
 	{
@@ -109,10 +147,14 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.CacheTrieJournalFlag,
 			utils.CacheTrieRejournalFlag,
 			utils.CacheGCFlag,
+			utils.CacheGCAutoTuneFlag,
 			utils.CacheSnapshotFlag,
 			utils.CacheNoPrefetchFlag,
 			utils.CachePreimagesFlag,
 			utils.FDLimitFlag,
+			utils.MaintenanceBandwidthFlag,
+			utils.MaintenanceIOPSFlag,
+			utils.MaintenanceInTurnWindowFlag,
 		},
 	},
 	{
@@ -134,6 +176,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.HTTPPortFlag,
 			utils.HTTPApiFlag,
 			utils.HTTPPathPrefixFlag,
+			utils.HTTPCompressionThresholdFlag,
 			utils.HTTPCORSDomainFlag,
 			utils.HTTPVirtualHostsFlag,
 			utils.WSEnabledFlag,
@@ -141,6 +184,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSPathPrefixFlag,
+			utils.WSCompressionFlag,
+			utils.WSCompressionThresholdFlag,
 			utils.WSAllowedOriginsFlag,
 			utils.JWTSecretFlag,
 			utils.AuthListenFlag,
@@ -149,9 +194,26 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.GraphQLEnabledFlag,
 			utils.GraphQLCORSDomainFlag,
 			utils.GraphQLVirtualHostsFlag,
+			utils.RosettaEnabledFlag,
+			utils.RosettaBlockchainFlag,
+			utils.RosettaSymbolFlag,
 			utils.RPCGlobalGasCapFlag,
 			utils.RPCGlobalEVMTimeoutFlag,
+			utils.RPCTraceCacheFlag,
+			utils.ExExSocketFlag,
+			utils.ExExStateDiffsFlag,
+			utils.FollowerServeFlag,
+			utils.FollowerPrimaryFlag,
+			utils.FollowerJWTSecretFlag,
+			utils.ReplicaStandbyFlag,
 			utils.RPCGlobalTxFeeCapFlag,
+			utils.RPCGasPriceFloorFlag,
+			utils.RPCGasPriceCeilFlag,
+			utils.RPCCliqueFieldsFlag,
+			utils.RPCSignedMethodsFlag,
+			utils.RPCArchiveFlag,
+			utils.RPCArchiveMethodsFlag,
+			utils.RPCQuotasFlag,
 			utils.AllowUnprotectedTxs,
 			utils.JSpathFlag,
 			utils.ExecFlag,
@@ -170,8 +232,13 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
+			utils.NetRulesFlag,
+			utils.GeoIPFlag,
+			utils.MaxSessionAgeFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
+			utils.SentryNodesFlag,
+			utils.SentryValidatorsFlag,
 		},
 	},
 	{
@@ -185,6 +252,12 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.MinerGasLimitFlag,
 			utils.MinerEtherbaseFlag,
 			utils.MinerExtraDataFlag,
+			utils.MinerVanityFlag,
+			utils.MinerVanityTagFlag,
+			utils.MinerDeterministicFlag,
+			utils.MinerPrefetchFlag,
+			utils.MinerWiggleMinFlag,
+			utils.MinerWiggleMaxFlag,
 			utils.MinerRecommitIntervalFlag,
 			utils.MinerNoVerifyFlag,
 		},
@@ -196,6 +269,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.GpoPercentileFlag,
 			utils.GpoMaxGasPriceFlag,
 			utils.GpoIgnoreGasPriceFlag,
+			utils.GpoFixedTipFlag,
 		},
 	},
 	{
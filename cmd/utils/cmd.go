@@ -87,9 +87,18 @@ func StartNode(ctx *cli.Context, stack *node.Node, isConsole bool) {
 			go monitorFreeDiskSpace(sigc, stack.InstanceDir(), uint64(minFreeDiskSpace)*1024*1024)
 		}
 
+		// Flush the state that must survive a restart first, then close the node
+		// within the budget, exiting regardless once it's exhausted so that the
+		// final status is reported before the supervisor kills the process.
+		budget := ctx.GlobalDuration(ShutdownBudgetFlag.Name)
 		shutdown := func() {
-			log.Info("Got interrupt, shutting down...")
-			go stack.Close()
+			log.Info("Got interrupt, shutting down...", "budget", budget)
+			go func() {
+				if !stack.Shutdown(budget) {
+					debug.Exit()
+					os.Exit(1)
+				}
+			}()
 			for i := 10; i > 0; i-- {
 				<-sigc
 				if i > 1 {
@@ -0,0 +1,259 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Export formats supported by the export command.
+const (
+	ExportFormatRLP   = "rlp"
+	ExportFormatProto = "proto"
+)
+
+// OpenExportStream opens the stream target of an export: "-" for stdout,
+// "unix:<path>" for a unix socket or "tcp:<host:port>" for a TCP connection.
+func OpenExportStream(target string) (io.WriteCloser, error) {
+	if target == "-" {
+		return os.Stdout, nil
+	}
+	parts := strings.SplitN(target, ":", 2)
+	if len(parts) != 2 || (parts[0] != "unix" && parts[0] != "tcp") {
+		return nil, fmt.Errorf("invalid export stream %q, want \"-\", \"unix:<path>\" or \"tcp:<host:port>\"", target)
+	}
+	return net.Dial(parts[0], parts[1])
+}
+
+// ExportChainProtoFile exports the canonical blocks from first to last inclusive
+// into the specified file in the protobuf format, appending to the file if asked
+// and data already exists in it. If the file ends with .gz, the output will be
+// gzipped.
+func ExportChainProtoFile(blockchain *core.BlockChain, fn string, first, last uint64, appendFile bool) error {
+	log.Info("Exporting blockchain", "file", fn)
+
+	// Open the file handle and potentially wrap with a gzip stream
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendFile {
+		flags = os.O_CREATE | os.O_APPEND | os.O_WRONLY
+	}
+	fh, err := os.OpenFile(fn, flags, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	if err := ExportChainProto(blockchain, writer, first, last); err != nil {
+		return err
+	}
+	log.Info("Exported blockchain", "file", fn)
+	return nil
+}
+
+// ExportChainProto exports the canonical blocks from first to last inclusive
+// with their transactions and receipts into w, as length prefixed protobuf Block
+// messages following the schema in export.proto.
+func ExportChainProto(blockchain *core.BlockChain, w io.Writer, first, last uint64) error {
+	if first > last {
+		return fmt.Errorf("export failed: first (%d) is greater than last (%d)", first, last)
+	}
+	log.Info("Exporting batch of blocks", "count", last-first+1, "format", ExportFormatProto)
+
+	var (
+		out             = bufio.NewWriterSize(w, 1024*1024)
+		config          = blockchain.Config()
+		buf, msg        []byte
+		start, reported = time.Now(), time.Now()
+	)
+	for nr := first; nr <= last; nr++ {
+		block := blockchain.GetBlockByNumber(nr)
+		if block == nil {
+			return fmt.Errorf("export failed on #%d: not found", nr)
+		}
+		receipts := blockchain.GetReceiptsByHash(block.Hash())
+		if len(receipts) != len(block.Transactions()) {
+			return fmt.Errorf("export failed on #%d: %d receipts for %d transactions", nr, len(receipts), len(block.Transactions()))
+		}
+		msg = appendProtoBlock(msg[:0], block, receipts, blockchain.GetTd(block.Hash(), nr), types.MakeSigner(config, block.Number()))
+
+		buf = protowire.AppendVarint(buf[:0], uint64(len(msg)))
+		if _, err := out.Write(buf); err != nil {
+			return err
+		}
+		if _, err := out.Write(msg); err != nil {
+			return err
+		}
+		if time.Since(reported) >= 8*time.Second {
+			log.Info("Exporting blocks", "exported", nr-first, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+	}
+	return out.Flush()
+}
+
+// appendProtoBlock appends the protobuf Block message of a block to b.
+func appendProtoBlock(b []byte, block *types.Block, receipts types.Receipts, td *big.Int, signer types.Signer) []byte {
+	b = appendProtoMessage(b, 1, appendProtoHeader(nil, block.Header()))
+	for _, tx := range block.Transactions() {
+		b = appendProtoMessage(b, 2, appendProtoTransaction(nil, tx, signer))
+	}
+	for _, receipt := range receipts {
+		b = appendProtoMessage(b, 3, appendProtoReceipt(nil, receipt))
+	}
+	for _, uncle := range block.Uncles() {
+		b = appendProtoMessage(b, 4, appendProtoHeader(nil, uncle))
+	}
+	return appendProtoBig(b, 5, td)
+}
+
+// appendProtoHeader appends the fields of a protobuf Header message to b.
+func appendProtoHeader(b []byte, header *types.Header) []byte {
+	b = appendProtoBytes(b, 1, header.Hash().Bytes())
+	b = appendProtoBytes(b, 2, header.ParentHash.Bytes())
+	b = appendProtoBytes(b, 3, header.UncleHash.Bytes())
+	b = appendProtoBytes(b, 4, header.Coinbase.Bytes())
+	b = appendProtoBytes(b, 5, header.Root.Bytes())
+	b = appendProtoBytes(b, 6, header.TxHash.Bytes())
+	b = appendProtoBytes(b, 7, header.ReceiptHash.Bytes())
+	b = appendProtoBytes(b, 8, header.Bloom.Bytes())
+	b = appendProtoBig(b, 9, header.Difficulty)
+	b = appendProtoUint(b, 10, header.Number.Uint64())
+	b = appendProtoUint(b, 11, header.GasLimit)
+	b = appendProtoUint(b, 12, header.GasUsed)
+	b = appendProtoUint(b, 13, header.Time)
+	b = appendProtoBytes(b, 14, header.Extra)
+	b = appendProtoBytes(b, 15, header.MixDigest.Bytes())
+	if nonce := header.Nonce.Uint64(); nonce != 0 {
+		b = protowire.AppendTag(b, 16, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, nonce)
+	}
+	return appendProtoBig(b, 17, header.BaseFee)
+}
+
+// appendProtoTransaction appends the fields of a protobuf Transaction message
+// to b. The sender is left unset if it can't be recovered.
+func appendProtoTransaction(b []byte, tx *types.Transaction, signer types.Signer) []byte {
+	b = appendProtoBytes(b, 1, tx.Hash().Bytes())
+	b = appendProtoUint(b, 2, uint64(tx.Type()))
+	if from, err := types.Sender(signer, tx); err == nil {
+		b = appendProtoBytes(b, 3, from.Bytes())
+	}
+	if to := tx.To(); to != nil {
+		b = appendProtoBytes(b, 4, to.Bytes())
+	}
+	b = appendProtoUint(b, 5, tx.Nonce())
+	b = appendProtoBig(b, 6, tx.Value())
+	b = appendProtoUint(b, 7, tx.Gas())
+	b = appendProtoBig(b, 8, tx.GasPrice())
+	if tx.Type() == types.DynamicFeeTxType {
+		b = appendProtoBig(b, 9, tx.GasFeeCap())
+		b = appendProtoBig(b, 10, tx.GasTipCap())
+	}
+	b = appendProtoBytes(b, 11, tx.Data())
+	if tx.Protected() {
+		b = appendProtoBig(b, 12, tx.ChainId())
+	}
+	for _, tuple := range tx.AccessList() {
+		entry := appendProtoBytes(nil, 1, tuple.Address.Bytes())
+		for _, key := range tuple.StorageKeys {
+			entry = appendProtoMessage(entry, 2, key.Bytes())
+		}
+		b = appendProtoMessage(b, 13, entry)
+	}
+	v, r, s := tx.RawSignatureValues()
+	b = appendProtoBig(b, 14, v)
+	b = appendProtoBig(b, 15, r)
+	return appendProtoBig(b, 16, s)
+}
+
+// appendProtoReceipt appends the fields of a protobuf Receipt message to b.
+func appendProtoReceipt(b []byte, receipt *types.Receipt) []byte {
+	b = appendProtoUint(b, 1, uint64(receipt.Type))
+	if len(receipt.PostState) > 0 {
+		b = appendProtoBytes(b, 3, receipt.PostState)
+	} else {
+		b = appendProtoUint(b, 2, receipt.Status)
+	}
+	b = appendProtoUint(b, 4, receipt.CumulativeGasUsed)
+	b = appendProtoUint(b, 5, receipt.GasUsed)
+	if receipt.ContractAddress != (common.Address{}) {
+		b = appendProtoBytes(b, 6, receipt.ContractAddress.Bytes())
+	}
+	b = appendProtoBytes(b, 7, receipt.Bloom.Bytes())
+	for _, l := range receipt.Logs {
+		entry := appendProtoBytes(nil, 1, l.Address.Bytes())
+		for _, topic := range l.Topics {
+			entry = appendProtoMessage(entry, 2, topic.Bytes())
+		}
+		entry = appendProtoBytes(entry, 3, l.Data)
+		entry = appendProtoUint(entry, 4, uint64(l.Index))
+		b = appendProtoMessage(b, 8, entry)
+	}
+	return b
+}
+
+// appendProtoMessage appends a length delimited field to b, even if empty, as
+// needed for the elements of repeated fields.
+func appendProtoMessage(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// appendProtoBytes appends a bytes field to b, unless it's empty.
+func appendProtoBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	return appendProtoMessage(b, num, v)
+}
+
+// appendProtoUint appends a varint field to b, unless it's zero.
+func appendProtoUint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// appendProtoBig appends a big integer field as big endian bytes to b, unless
+// it's nil or zero.
+func appendProtoBig(b []byte, num protowire.Number, v *big.Int) []byte {
+	if v == nil {
+		return b
+	}
+	return appendProtoBytes(b, num, v.Bytes())
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeProtoFields splits a protobuf message into its fields by number, the
+// varints decoded and the length delimited ones as raw bytes.
+func decodeProtoFields(t *testing.T, b []byte) map[protowire.Number][]interface{} {
+	fields := make(map[protowire.Number][]interface{})
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("invalid tag: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				t.Fatalf("invalid varint: %v", protowire.ParseError(n))
+			}
+			fields[num] = append(fields[num], v)
+			b = b[n:]
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				t.Fatalf("invalid fixed64: %v", protowire.ParseError(n))
+			}
+			fields[num] = append(fields[num], v)
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				t.Fatalf("invalid bytes: %v", protowire.ParseError(n))
+			}
+			fields[num] = append(fields[num], v)
+			b = b[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+	return fields
+}
+
+func TestExportProtoBlock(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		from   = crypto.PubkeyToAddress(key.PublicKey)
+		to     = common.HexToAddress("0xdeadbeef")
+		signer = types.LatestSignerForChainID(big.NewInt(1))
+		tx     = types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			Nonce:     7,
+			To:        &to,
+			Value:     big.NewInt(1000),
+			Gas:       21000,
+			GasFeeCap: big.NewInt(30),
+			GasTipCap: big.NewInt(2),
+		})
+		header = &types.Header{
+			Number:     big.NewInt(42),
+			Difficulty: big.NewInt(2),
+			GasLimit:   8000000,
+			GasUsed:    21000,
+			Time:       1000,
+			Extra:      []byte("vanity"),
+			Nonce:      types.EncodeNonce(0xff),
+			BaseFee:    big.NewInt(7),
+		}
+		receipt = &types.Receipt{
+			Type:              types.DynamicFeeTxType,
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: 21000,
+			GasUsed:           21000,
+			Logs:              []*types.Log{{Address: to, Topics: []common.Hash{{0x01}}, Data: []byte{0x02}, Index: 3}},
+		}
+		block = types.NewBlockWithHeader(header).WithBody([]*types.Transaction{tx}, nil)
+	)
+	fields := decodeProtoFields(t, appendProtoBlock(nil, block, types.Receipts{receipt}, big.NewInt(84), signer))
+	if len(fields[1]) != 1 || len(fields[2]) != 1 || len(fields[3]) != 1 || len(fields[4]) != 0 {
+		t.Fatalf("block field counts mismatch: %d headers, %d txs, %d receipts, %d uncles", len(fields[1]), len(fields[2]), len(fields[3]), len(fields[4]))
+	}
+	if td := new(big.Int).SetBytes(fields[5][0].([]byte)); td.Uint64() != 84 {
+		t.Errorf("total difficulty mismatch: have %v, want 84", td)
+	}
+	// Check the header fields
+	h := decodeProtoFields(t, fields[1][0].([]byte))
+	if hash := h[1][0].([]byte); !bytes.Equal(hash, block.Hash().Bytes()) {
+		t.Errorf("header hash mismatch: have %x, want %x", hash, block.Hash())
+	}
+	if number := h[10][0].(uint64); number != 42 {
+		t.Errorf("number mismatch: have %d, want 42", number)
+	}
+	if nonce := h[16][0].(uint64); nonce != 0xff {
+		t.Errorf("nonce mismatch: have %x, want ff", nonce)
+	}
+	if baseFee := new(big.Int).SetBytes(h[17][0].([]byte)); baseFee.Uint64() != 7 {
+		t.Errorf("base fee mismatch: have %v, want 7", baseFee)
+	}
+	if _, ok := h[9]; !ok {
+		t.Errorf("difficulty missing")
+	}
+	// Check the transaction fields
+	x := decodeProtoFields(t, fields[2][0].([]byte))
+	if sender := x[3][0].([]byte); !bytes.Equal(sender, from.Bytes()) {
+		t.Errorf("sender mismatch: have %x, want %x", sender, from)
+	}
+	if typ := x[2][0].(uint64); typ != types.DynamicFeeTxType {
+		t.Errorf("type mismatch: have %d, want %d", typ, types.DynamicFeeTxType)
+	}
+	if nonce := x[5][0].(uint64); nonce != 7 {
+		t.Errorf("nonce mismatch: have %d, want 7", nonce)
+	}
+	if tip := new(big.Int).SetBytes(x[10][0].([]byte)); tip.Uint64() != 2 {
+		t.Errorf("tip cap mismatch: have %v, want 2", tip)
+	}
+	// Check the receipt and log fields
+	r := decodeProtoFields(t, fields[3][0].([]byte))
+	if status := r[2][0].(uint64); status != types.ReceiptStatusSuccessful {
+		t.Errorf("status mismatch: have %d, want %d", status, types.ReceiptStatusSuccessful)
+	}
+	l := decodeProtoFields(t, r[8][0].([]byte))
+	if topic := l[2][0].([]byte); !bytes.Equal(topic, common.Hash{0x01}.Bytes()) {
+		t.Errorf("topic mismatch: have %x", topic)
+	}
+	if index := l[4][0].(uint64); index != 3 {
+		t.Errorf("log index mismatch: have %d, want 3", index)
+	}
+}
@@ -37,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
@@ -48,6 +49,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/eth/txmgr"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/remotedb"
 	"github.com/ethereum/go-ethereum/ethstats"
@@ -67,6 +69,8 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rosetta"
+	"github.com/ethereum/go-ethereum/rpc"
 	pcsclite "github.com/gballet/go-libpcsclite"
 	gopsutil "github.com/shirou/gopsutil/mem"
 	"gopkg.in/urfave/cli.v1"
@@ -126,6 +130,10 @@ var (
 		Name:  "datadir.minfreedisk",
 		Usage: "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
 	}
+	ShutdownBudgetFlag = cli.DurationFlag{
+		Name:  "shutdown.budget",
+		Usage: "Time allowed for a graceful shutdown before exiting regardless, keep below the container stop grace period (0 = unbounded)",
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -181,6 +189,14 @@ var (
 		Usage: "Initial block gas limit",
 		Value: 11500000,
 	}
+	DeveloperForkFlag = cli.StringFlag{
+		Name:  "dev.fork",
+		Usage: "RPC endpoint of a live node whose state the developer chain is forked off (fetched on demand, requires debug_dbGet)",
+	}
+	DeveloperForkBlockFlag = cli.Uint64Flag{
+		Name:  "dev.fork.block",
+		Usage: "Block number of the remote chain to fork the developer chain off (default = latest)",
+	}
 	IdentityFlag = cli.StringFlag{
 		Name:  "identity",
 		Usage: "Custom node name",
@@ -206,6 +222,15 @@ var (
 		Name:  "incompletes",
 		Usage: "Include accounts for which we don't have the address (missing preimage)",
 	}
+	ExportFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: `Export format ("rlp" or "proto")`,
+		Value: ExportFormatRLP,
+	}
+	ExportStreamFlag = cli.BoolFlag{
+		Name:  "stream",
+		Usage: `Export to a stream instead of a file: stdout ("-"), a unix socket ("unix:<path>") or a TCP address ("tcp:<host:port>")`,
+	}
 	ExcludeCodeFlag = cli.BoolFlag{
 		Name:  "nocode",
 		Usage: "Exclude contract code (save db lookups)",
@@ -248,6 +273,71 @@ var (
 		Name:  "eth.requiredblocks",
 		Usage: "Comma separated block number-to-hash mappings to require for peering (<number>=<hash>)",
 	}
+	EthStrictForkIDFlag = cli.BoolFlag{
+		Name:  "eth.strictforkid",
+		Usage: "Disconnect peers whose upcoming fork differs from the local fork schedule",
+	}
+	EthHeadLagPeriodsFlag = cli.Uint64Flag{
+		Name:  "eth.headlagperiods",
+		Usage: "Number of block periods without a new head after which peers are rotated (0 = disabled)",
+	}
+	EthAdvertiseValidatorFlag = cli.BoolFlag{
+		Name:  "eth.advertisevalidator",
+		Usage: "Prove the clique signer running the node in its node record, letting peers prioritize it",
+	}
+	CliqueMaxFutureDriftFlag = cli.DurationFlag{
+		Name:  "clique.maxfuturedrift",
+		Usage: "Maximum time a clique block may be ahead of the local clock before being rejected (0 = schedule as future block)",
+	}
+	CliqueTimestampJitterFlag = cli.DurationFlag{
+		Name:  "clique.timestampjitter",
+		Usage: "Time a clique block may be ahead of the local clock while still being accepted right away",
+	}
+	CliqueFastVerifyFlag = cli.StringFlag{
+		Name:  "clique.fastverify",
+		Usage: "Trusted checkpoint (<number>=<hash>) below which the initial sync checks clique seals against cached signer keys instead of recovering them",
+	}
+	CliqueNTPServersFlag = cli.StringFlag{
+		Name:  "clique.ntpservers",
+		Usage: "Comma separated NTP servers to check the local clock against (empty = disabled)",
+		Value: strings.Join(ethconfig.Defaults.CliqueClock.Servers, ","),
+	}
+	CliqueNTPIntervalFlag = cli.DurationFlag{
+		Name:  "clique.ntpinterval",
+		Usage: "Interval between two checks of the local clock against the NTP servers",
+		Value: ethconfig.Defaults.CliqueClock.Interval,
+	}
+	CliqueNTPMaxDriftFlag = cli.DurationFlag{
+		Name:  "clique.ntpmaxdrift",
+		Usage: "Drift from the NTP servers beyond which the local clock is unhealthy",
+		Value: ethconfig.Defaults.CliqueClock.MaxDrift,
+	}
+	CliqueNTPEnforceFlag = cli.BoolFlag{
+		Name:  "clique.ntpenforce",
+		Usage: "Refuse to seal blocks while the local clock is drifted beyond the limit",
+	}
+	CliqueNoPreflightFlag = cli.BoolFlag{
+		Name:  "clique.nopreflight",
+		Usage: "Disable the startup checks holding back sealing until the validator is synced, authorized and healthy",
+	}
+	CliquePreflightLagFlag = cli.Uint64Flag{
+		Name:  "clique.preflightlag",
+		Usage: "Maximum number of blocks the node may trail the sync target or its trusted and static peers by before sealing",
+		Value: ethconfig.Defaults.PreflightMaxLag,
+	}
+	CliquePreflightDiskFlag = cli.Uint64Flag{
+		Name:  "clique.preflightdisk",
+		Usage: "Minimum free disk space of the datadir in megabytes before sealing",
+		Value: ethconfig.Defaults.PreflightMinFreeDisk,
+	}
+	ContractAnalyticsFlag = cli.BoolFlag{
+		Name:  "analytics.contracts",
+		Usage: "Index the daily gas usage, calls and unique senders of contracts (aks_contractStats)",
+	}
+	EpochStatsFlag = cli.BoolFlag{
+		Name:  "analytics.epochs",
+		Usage: "Roll up the gas usage, transactions, unique senders, block fullness and sealers of each epoch (aks_getEpochStats, clique only)",
+	}
 	LegacyWhitelistFlag = cli.StringFlag{
 		Name:  "whitelist",
 		Usage: "Comma separated block number-to-hash mappings to enforce (<number>=<hash>) (deprecated in favor of --eth.requiredblocks)",
@@ -365,6 +455,15 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolJournalRemotesFlag = cli.BoolFlag{
+		Name:  "txpool.journalremotes",
+		Usage: "Also journal remote transactions to survive node restarts",
+	}
+	TxPoolRemoteJournalFlag = cli.StringFlag{
+		Name:  "txpool.remotejournal",
+		Usage: "Disk journal for remote transactions, if journaled",
+		Value: core.DefaultTxPoolConfig.RemoteJournal,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -400,6 +499,65 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: ethconfig.Defaults.TxPool.Lifetime,
 	}
+	TxPoolFeeExemptFlag = cli.StringFlag{
+		Name:  "txpool.feeexempt",
+		Usage: "Comma separated accounts whose transactions are accepted regardless of the price limit (e.g. zero tip)",
+	}
+	TxPoolReplayProtectedFlag = cli.BoolFlag{
+		Name:  "txpool.replayprotected",
+		Usage: "Only accept transactions with EIP-155 replay protection",
+	}
+	TxPoolUnprotectedFlag = cli.StringFlag{
+		Name:  "txpool.unprotected",
+		Usage: "Comma separated senders allowed to submit transactions without replay protection regardless (e.g. legacy tooling)",
+	}
+	TxPoolSponsoredFlag = cli.StringFlag{
+		Name:  "txpool.sponsored",
+		Usage: "Comma separated senders and contracts whose zero priced transactions are sponsored (chains without base fee only)",
+	}
+	TxPoolSponsorBudgetFlag = BigFlag{
+		Name:  "txpool.sponsorbudget",
+		Usage: "Fees forgone for sponsored transactions before no longer accepting them, priced at the price limit (wei)",
+	}
+	TxPoolSponsorMaxGasFlag = cli.Uint64Flag{
+		Name:  "txpool.sponsormaxgas",
+		Usage: "Maximum gas of a sponsored transaction (0 = block gas limit)",
+	}
+	TxPoolSponsorJournalFlag = cli.StringFlag{
+		Name:  "txpool.sponsorjournal",
+		Usage: "Disk journal of the spent sponsor budget to survive node restarts",
+		Value: core.DefaultTxPoolConfig.SponsorJournal,
+	}
+	// Transaction manager settings
+	TxManagerEnabledFlag = cli.BoolFlag{
+		Name:  "txmgr",
+		Usage: "Enable tracking and resubmission of locally submitted transactions",
+	}
+	TxManagerStuckBlocksFlag = cli.Uint64Flag{
+		Name:  "txmgr.stuckblocks",
+		Usage: "Number of blocks a local transaction may stay pending before being resubmitted",
+		Value: ethconfig.Defaults.TxManager.StuckBlocks,
+	}
+	TxManagerPolicyFlag = cli.StringFlag{
+		Name:  "txmgr.policy",
+		Usage: `Action taken on stuck transactions ("none", "rebroadcast" or "bump")`,
+		Value: string(ethconfig.Defaults.TxManager.Policy),
+	}
+	TxManagerBumpPercentFlag = cli.Uint64Flag{
+		Name:  "txmgr.bumppercent",
+		Usage: "Fee increase applied to stuck transactions on every bump (%)",
+		Value: ethconfig.Defaults.TxManager.BumpPercent,
+	}
+	TxManagerMaxBumpsFlag = cli.IntFlag{
+		Name:  "txmgr.maxbumps",
+		Usage: "Maximum number of fee bumps applied to a single transaction",
+		Value: ethconfig.Defaults.TxManager.MaxBumps,
+	}
+	TxManagerNonceLeaseFlag = cli.DurationFlag{
+		Name:  "txmgr.noncelease",
+		Usage: "Lifetime of nonce ranges reserved via txmgr_reserveNonceRange",
+		Value: ethconfig.Defaults.TxManager.NonceLease,
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -431,6 +589,10 @@ var (
 		Usage: "Percentage of cache memory allowance to use for trie pruning (default = 25% full mode, 0% archive mode)",
 		Value: 25,
 	}
+	CacheGCAutoTuneFlag = cli.BoolFlag{
+		Name:  "cache.gc.autotune",
+		Usage: "Adapt the trie pruning cache to the block import time and available memory (trie clean and snapshot caches keep their startup size)",
+	}
 	CacheSnapshotFlag = cli.IntFlag{
 		Name:  "cache.snapshot",
 		Usage: "Percentage of cache memory allowance to use for snapshot caching (default = 10% full mode, 20% archive mode)",
@@ -485,6 +647,30 @@ var (
 		Name:  "miner.extradata",
 		Usage: "Block extra data set by the miner (default = client version)",
 	}
+	MinerVanityFlag = cli.BoolFlag{
+		Name:  "miner.vanity",
+		Usage: "Announce the client version and build in the structured header vanity of sealed clique blocks",
+	}
+	MinerDeterministicFlag = cli.BoolFlag{
+		Name:  "miner.deterministic",
+		Usage: "Order block transactions only by price, nonce and hash, announcing it in the header vanity (implies --miner.vanity)",
+	}
+	MinerPrefetchFlag = cli.BoolFlag{
+		Name:  "miner.prefetch",
+		Usage: "Warm the trie nodes of the pending transactions expected in the next block between sealing slots (clique only)",
+	}
+	MinerWiggleMinFlag = cli.DurationFlag{
+		Name:  "miner.wigglemin",
+		Usage: "Lower bound of the out-of-turn sealing delay per signer adapted to the observed block propagation latency (clique only)",
+	}
+	MinerWiggleMaxFlag = cli.DurationFlag{
+		Name:  "miner.wigglemax",
+		Usage: "Upper bound of the out-of-turn sealing delay per signer adapted to the observed block propagation latency (clique only)",
+	}
+	MinerVanityTagFlag = cli.StringFlag{
+		Name:  "miner.vanitytag",
+		Usage: "Operator tag announced in the structured header vanity (max 15 bytes, implies --miner.vanity)",
+	}
 	MinerRecommitIntervalFlag = cli.DurationFlag{
 		Name:  "miner.recommit",
 		Usage: "Time interval to recreate the block being mined",
@@ -528,11 +714,81 @@ var (
 		Usage: "Sets a timeout used for eth_call (0=infinite)",
 		Value: ethconfig.Defaults.RPCEVMTimeout,
 	}
+	ExExSocketFlag = cli.StringFlag{
+		Name:  "exex.socket",
+		Usage: "Unix socket streaming chain changes to execution extensions (relative paths are within the datadir)",
+	}
+	ExExStateDiffsFlag = cli.BoolFlag{
+		Name:  "exex.statediffs",
+		Usage: "Record the state diffs of committed blocks for execution extensions",
+	}
+	FollowerServeFlag = cli.BoolFlag{
+		Name:  "follower.serve",
+		Usage: "Stream the chain to followers over the authenticated RPC endpoint",
+	}
+	FollowerPrimaryFlag = cli.StringFlag{
+		Name:  "follower.primary",
+		Usage: "Run as a follower writing the blocks and state diffs of the primary at this authenticated websocket endpoint, disabling p2p",
+	}
+	FollowerJWTSecretFlag = cli.StringFlag{
+		Name:  "follower.jwtsecret",
+		Usage: "Path to the JWT secret of the primary (defaults to --authrpc.jwtsecret)",
+	}
+	ReplicaStandbyFlag = DirectoryFlag{
+		Name:  "replica.standby",
+		Usage: "Dual-write the chain to a warm standby datadir at this local or network path",
+	}
+	MaintenanceBandwidthFlag = cli.IntFlag{
+		Name:  "maintenance.bandwidth",
+		Usage: "Megabytes per second state healing and snapshot generation may write (0 = unlimited)",
+	}
+	MaintenanceIOPSFlag = cli.IntFlag{
+		Name:  "maintenance.iops",
+		Usage: "Database write batches per second state healing and snapshot generation may issue (0 = unlimited)",
+	}
+	MaintenanceInTurnWindowFlag = cli.DurationFlag{
+		Name:  "maintenance.inturnwindow",
+		Usage: "Pause state healing and snapshot generation this long before and after the local signer's in-turn slots (0 = never paused)",
+	}
+	RPCTraceCacheFlag = cli.IntFlag{
+		Name:  "rpc.tracecache",
+		Usage: "Megabytes of disk used to cache debug_traceTransaction results (0 = disabled)",
+	}
 	RPCGlobalTxFeeCapFlag = cli.Float64Flag{
 		Name:  "rpc.txfeecap",
 		Usage: "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
 		Value: ethconfig.Defaults.RPCTxFeeCap,
 	}
+	RPCGasPriceFloorFlag = BigFlag{
+		Name:  "rpc.gaspricefloor",
+		Usage: "Minimum gas price (in wei) suggested by eth_gasPrice",
+	}
+	RPCGasPriceCeilFlag = BigFlag{
+		Name:  "rpc.gaspriceceil",
+		Usage: "Maximum gas price (in wei) suggested by eth_gasPrice",
+	}
+	RPCCliqueFieldsFlag = cli.BoolFlag{
+		Name:  "rpc.cliquefields",
+		Usage: "Include the clique sealer and in-turn fields in block and header responses",
+	}
+	RPCSignedMethodsFlag = cli.StringFlag{
+		Name:  "rpc.signedmethods",
+		Usage: "Comma separated list of methods whose HTTP and WS responses are signed with the node key (e.g. eth_getBlockByNumber,clique_getSnapshot)",
+		Value: "",
+	}
+	RPCArchiveFlag = cli.StringFlag{
+		Name:  "rpc.archive",
+		Usage: "RPC endpoint of an archive node serving the historical state calls failing locally (e.g. on pruned nodes)",
+	}
+	RPCArchiveMethodsFlag = cli.StringFlag{
+		Name:  "rpc.archivemethods",
+		Usage: "Comma separated list of methods offloaded to the archive node",
+		Value: strings.Join(node.DefaultArchiveMethods, ","),
+	}
+	RPCQuotasFlag = cli.StringFlag{
+		Name:  "rpc.quotas",
+		Usage: "Comma separated list of per-namespace HTTP and WS call quotas as namespace:cpu, the share of the CPUs in percent the calls may keep busy (e.g. debug:20)",
+	}
 	// Authenticated RPC HTTP settings
 	AuthListenFlag = cli.StringFlag{
 		Name:  "authrpc.addr",
@@ -609,6 +865,11 @@ var (
 		Usage: "HTTP path path prefix on which JSON-RPC is served. Use '/' to serve on all paths.",
 		Value: "",
 	}
+	HTTPCompressionThresholdFlag = cli.IntFlag{
+		Name:  "http.compressthreshold",
+		Usage: "Minimum size in bytes of HTTP-RPC responses to gzip (negative disables compression)",
+		Value: node.DefaultConfig.HTTPCompressionThreshold,
+	}
 	GraphQLEnabledFlag = cli.BoolFlag{
 		Name:  "graphql",
 		Usage: "Enable GraphQL on the HTTP-RPC server. Note that GraphQL can only be started if an HTTP server is started as well.",
@@ -623,6 +884,20 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
 	}
+	RosettaEnabledFlag = cli.BoolFlag{
+		Name:  "rosetta",
+		Usage: "Enable the Rosetta API on the HTTP-RPC server under /rosetta. Note that Rosetta can only be started if an HTTP server is started as well.",
+	}
+	RosettaBlockchainFlag = cli.StringFlag{
+		Name:  "rosetta.blockchain",
+		Usage: "Blockchain name reported in the Rosetta network identifier",
+		Value: rosetta.DefaultConfig.Blockchain,
+	}
+	RosettaSymbolFlag = cli.StringFlag{
+		Name:  "rosetta.symbol",
+		Usage: "Symbol of the native currency reported over Rosetta",
+		Value: rosetta.DefaultConfig.Symbol,
+	}
 	WSEnabledFlag = cli.BoolFlag{
 		Name:  "ws",
 		Usage: "Enable the WS-RPC server",
@@ -652,6 +927,15 @@ var (
 		Usage: "HTTP path prefix on which JSON-RPC is served. Use '/' to serve on all paths.",
 		Value: "",
 	}
+	WSCompressionFlag = cli.BoolFlag{
+		Name:  "ws.compression",
+		Usage: "Enable permessage-deflate compression of WS-RPC messages",
+	}
+	WSCompressionThresholdFlag = cli.IntFlag{
+		Name:  "ws.compressthreshold",
+		Usage: "Minimum size in bytes of WS-RPC messages to compress",
+		Value: node.DefaultConfig.WSCompressionThreshold,
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -711,10 +995,30 @@ var (
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	NetRulesFlag = cli.StringFlag{
+		Name:  "netrules",
+		Usage: "JSON file of rules allowing or denying peers by CIDR, country, ASN and enode (replaceable with admin_setNetRules)",
+	}
+	GeoIPFlag = cli.StringFlag{
+		Name:  "geoip",
+		Usage: "Comma separated MaxMind databases (country, ASN) to look up the peers of the network rules in",
+	}
+	MaxSessionAgeFlag = cli.DurationFlag{
+		Name:  "p2p.maxsessionage",
+		Usage: "Maximum age of peer sessions before reconnecting to renegotiate the encryption keys (0 = never)",
+	}
 	DNSDiscoveryFlag = cli.StringFlag{
 		Name:  "discovery.dns",
 		Usage: "Sets DNS discovery entry points (use \"\" to disable DNS)",
 	}
+	SentryNodesFlag = cli.StringFlag{
+		Name:  "sentry.nodes",
+		Usage: "Comma separated enode URLs of the sentries to peer with exclusively, hiding the validator from the network (disables discovery)",
+	}
+	SentryValidatorsFlag = cli.StringFlag{
+		Name:  "sentry.validators",
+		Usage: "Comma separated enode URLs of the validators to relay for as their sentry",
+	}
 
 	// ATM the url is left to the user and deployment to
 	JSpathFlag = DirectoryFlag{
@@ -744,6 +1048,10 @@ var (
 		Usage: "Gas price below which gpo will ignore transactions",
 		Value: ethconfig.Defaults.GPO.IgnorePrice.Int64(),
 	}
+	GpoFixedTipFlag = BigFlag{
+		Name:  "gpo.fixedtip",
+		Usage: "Constant priority fee (in wei) recommended by gpo instead of sampling recent blocks",
+	}
 
 	// Metrics flags
 	MetricsEnabledFlag = cli.BoolFlag{
@@ -1049,9 +1357,42 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(HTTPPathPrefixFlag.Name) {
 		cfg.HTTPPathPrefix = ctx.GlobalString(HTTPPathPrefixFlag.Name)
 	}
+	if ctx.GlobalIsSet(HTTPCompressionThresholdFlag.Name) {
+		cfg.HTTPCompressionThreshold = ctx.GlobalInt(HTTPCompressionThresholdFlag.Name)
+	}
 	if ctx.GlobalIsSet(AllowUnprotectedTxs.Name) {
 		cfg.AllowUnprotectedTxs = ctx.GlobalBool(AllowUnprotectedTxs.Name)
 	}
+	if ctx.GlobalIsSet(RPCSignedMethodsFlag.Name) {
+		cfg.RPCSignedMethods = SplitAndTrim(ctx.GlobalString(RPCSignedMethodsFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCArchiveFlag.Name) {
+		cfg.ArchiveEndpoint = ctx.GlobalString(RPCArchiveFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCArchiveMethodsFlag.Name) {
+		cfg.ArchiveMethods = SplitAndTrim(ctx.GlobalString(RPCArchiveMethodsFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCQuotasFlag.Name) {
+		cfg.RPCQuotas = parseRPCQuotas(ctx.GlobalString(RPCQuotasFlag.Name))
+	}
+}
+
+// parseRPCQuotas parses the per-namespace call quotas given as namespace:cpu,
+// the CPU share in percent.
+func parseRPCQuotas(spec string) map[string]rpc.Quota {
+	quotas := make(map[string]rpc.Quota)
+	for _, entry := range SplitAndTrim(spec) {
+		fields := strings.Split(entry, ":")
+		if len(fields) != 2 || fields[0] == "" {
+			Fatalf("Invalid --%s entry %q, want namespace:cpu", RPCQuotasFlag.Name, entry)
+		}
+		cpu, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || cpu <= 0 || cpu > 100 {
+			Fatalf("Invalid CPU share in --%s entry %q, want a percentage", RPCQuotasFlag.Name, entry)
+		}
+		quotas[fields[0]] = rpc.Quota{CPU: cpu / 100}
+	}
+	return quotas
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -1089,6 +1430,12 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSPathPrefixFlag.Name) {
 		cfg.WSPathPrefix = ctx.GlobalString(WSPathPrefixFlag.Name)
 	}
+	if ctx.GlobalIsSet(WSCompressionFlag.Name) {
+		cfg.WSCompression = ctx.GlobalBool(WSCompressionFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSCompressionThresholdFlag.Name) {
+		cfg.WSCompressionThreshold = ctx.GlobalInt(WSCompressionThresholdFlag.Name)
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -1292,6 +1639,19 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NetRestrict = list
 	}
+	if ctx.GlobalIsSet(NetRulesFlag.Name) {
+		rules, err := p2p.LoadNetRules(ctx.GlobalString(NetRulesFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", NetRulesFlag.Name, err)
+		}
+		cfg.NetRules = rules
+	}
+	if ctx.GlobalIsSet(GeoIPFlag.Name) {
+		cfg.GeoIPDatabases = SplitAndTrim(ctx.GlobalString(GeoIPFlag.Name))
+	}
+	if ctx.GlobalIsSet(MaxSessionAgeFlag.Name) {
+		cfg.MaxSessionAge = ctx.GlobalDuration(MaxSessionAgeFlag.Name)
+	}
 
 	if ctx.GlobalBool(DeveloperFlag.Name) {
 		// --dev mode can't use p2p networking.
@@ -1301,6 +1661,20 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		cfg.NoDiscovery = true
 		cfg.DiscoveryV5 = false
 	}
+	if ctx.GlobalIsSet(SentryNodesFlag.Name) {
+		// Validators behind sentries stay invisible to the network.
+		cfg.NoDiscovery = true
+		cfg.DiscoveryV5 = false
+		cfg.TrustedOnly = true
+	}
+	if ctx.GlobalIsSet(FollowerPrimaryFlag.Name) {
+		// Followers import the chain of their primary instead of the p2p network.
+		cfg.MaxPeers = 0
+		cfg.ListenAddr = ""
+		cfg.NoDial = true
+		cfg.NoDiscovery = true
+		cfg.DiscoveryV5 = false
+	}
 }
 
 // SetNodeConfig applies node-related command line flags to the config.
@@ -1409,6 +1783,9 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config, light bool) {
 	if ctx.GlobalIsSet(GpoIgnoreGasPriceFlag.Name) {
 		cfg.IgnorePrice = big.NewInt(ctx.GlobalInt64(GpoIgnoreGasPriceFlag.Name))
 	}
+	if ctx.GlobalIsSet(GpoFixedTipFlag.Name) {
+		cfg.FixedTip = GlobalBig(ctx, GpoFixedTipFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
@@ -1431,6 +1808,12 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolJournalRemotesFlag.Name) {
+		cfg.JournalRemotes = ctx.GlobalBool(TxPoolJournalRemotesFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalFlag.Name) {
+		cfg.RemoteJournal = ctx.GlobalString(TxPoolRemoteJournalFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1452,6 +1835,66 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolFeeExemptFlag.Name) {
+		for _, account := range SplitAndTrim(ctx.GlobalString(TxPoolFeeExemptFlag.Name)) {
+			if !common.IsHexAddress(account) {
+				Fatalf("Invalid account in --txpool.feeexempt: %s", account)
+			} else {
+				cfg.FeeExempt = append(cfg.FeeExempt, common.HexToAddress(account))
+			}
+		}
+	}
+	if ctx.GlobalIsSet(TxPoolReplayProtectedFlag.Name) {
+		cfg.ReplayProtected = ctx.GlobalBool(TxPoolReplayProtectedFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolUnprotectedFlag.Name) {
+		for _, account := range SplitAndTrim(ctx.GlobalString(TxPoolUnprotectedFlag.Name)) {
+			if !common.IsHexAddress(account) {
+				Fatalf("Invalid account in --txpool.unprotected: %s", account)
+			} else {
+				cfg.Unprotected = append(cfg.Unprotected, common.HexToAddress(account))
+			}
+		}
+	}
+	if ctx.GlobalIsSet(TxPoolSponsoredFlag.Name) {
+		for _, account := range SplitAndTrim(ctx.GlobalString(TxPoolSponsoredFlag.Name)) {
+			if !common.IsHexAddress(account) {
+				Fatalf("Invalid account in --txpool.sponsored: %s", account)
+			} else {
+				cfg.Sponsored = append(cfg.Sponsored, common.HexToAddress(account))
+			}
+		}
+	}
+	if ctx.GlobalIsSet(TxPoolSponsorBudgetFlag.Name) {
+		cfg.SponsorBudget = GlobalBig(ctx, TxPoolSponsorBudgetFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolSponsorMaxGasFlag.Name) {
+		cfg.SponsorMaxGas = ctx.GlobalUint64(TxPoolSponsorMaxGasFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolSponsorJournalFlag.Name) {
+		cfg.SponsorJournal = ctx.GlobalString(TxPoolSponsorJournalFlag.Name)
+	}
+}
+
+func setTxManager(ctx *cli.Context, cfg *txmgr.Config) {
+	if ctx.GlobalIsSet(TxManagerEnabledFlag.Name) {
+		cfg.Enabled = ctx.GlobalBool(TxManagerEnabledFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxManagerStuckBlocksFlag.Name) {
+		cfg.StuckBlocks = ctx.GlobalUint64(TxManagerStuckBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxManagerPolicyFlag.Name) {
+		cfg.Policy = txmgr.Policy(ctx.GlobalString(TxManagerPolicyFlag.Name))
+	}
+	if ctx.GlobalIsSet(TxManagerBumpPercentFlag.Name) {
+		cfg.BumpPercent = ctx.GlobalUint64(TxManagerBumpPercentFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxManagerMaxBumpsFlag.Name) {
+		cfg.MaxBumps = ctx.GlobalInt(TxManagerMaxBumpsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxManagerNonceLeaseFlag.Name) {
+		cfg.NonceLease = ctx.GlobalDuration(TxManagerNonceLeaseFlag.Name)
+	}
 }
 
 func setEthash(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1481,6 +1924,21 @@ func setEthash(ctx *cli.Context, cfg *ethconfig.Config) {
 	}
 }
 
+func setClock(ctx *cli.Context, cfg *clique.ClockConfig) {
+	if ctx.GlobalIsSet(CliqueNTPServersFlag.Name) {
+		cfg.Servers = SplitAndTrim(ctx.GlobalString(CliqueNTPServersFlag.Name))
+	}
+	if ctx.GlobalIsSet(CliqueNTPIntervalFlag.Name) {
+		cfg.Interval = ctx.GlobalDuration(CliqueNTPIntervalFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueNTPMaxDriftFlag.Name) {
+		cfg.MaxDrift = ctx.GlobalDuration(CliqueNTPMaxDriftFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueNTPEnforceFlag.Name) {
+		cfg.Enforce = ctx.GlobalBool(CliqueNTPEnforceFlag.Name)
+	}
+}
+
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.GlobalIsSet(MinerNotifyFlag.Name) {
 		cfg.Notify = strings.Split(ctx.GlobalString(MinerNotifyFlag.Name), ",")
@@ -1489,6 +1947,25 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.GlobalIsSet(MinerExtraDataFlag.Name) {
 		cfg.ExtraData = []byte(ctx.GlobalString(MinerExtraDataFlag.Name))
 	}
+	if ctx.GlobalIsSet(MinerVanityFlag.Name) {
+		cfg.Vanity = ctx.GlobalBool(MinerVanityFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerVanityTagFlag.Name) {
+		cfg.Vanity = true
+		cfg.VanityTag = ctx.GlobalString(MinerVanityTagFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerDeterministicFlag.Name) {
+		cfg.DeterministicOrdering = ctx.GlobalBool(MinerDeterministicFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerPrefetchFlag.Name) {
+		cfg.Prefetch = ctx.GlobalBool(MinerPrefetchFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerWiggleMinFlag.Name) {
+		cfg.WiggleMin = ctx.GlobalDuration(MinerWiggleMinFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerWiggleMaxFlag.Name) {
+		cfg.WiggleMax = ctx.GlobalDuration(MinerWiggleMaxFlag.Name)
+	}
 	if ctx.GlobalIsSet(MinerGasLimitFlag.Name) {
 		cfg.GasCeil = ctx.GlobalUint64(MinerGasLimitFlag.Name)
 	}
@@ -1534,6 +2011,24 @@ func setRequiredBlocks(ctx *cli.Context, cfg *ethconfig.Config) {
 	}
 }
 
+// parseFastVerifyCheckpoint parses the trusted checkpoint of the fast clique
+// header verification, given as <number>=<hash>.
+func parseFastVerifyCheckpoint(checkpoint string) *clique.FastVerifyCheckpoint {
+	parts := strings.Split(checkpoint, "=")
+	if len(parts) != 2 {
+		Fatalf("Invalid fast verification checkpoint: %s", checkpoint)
+	}
+	number, err := strconv.ParseUint(parts[0], 0, 64)
+	if err != nil {
+		Fatalf("Invalid fast verification checkpoint number %s: %v", parts[0], err)
+	}
+	var hash common.Hash
+	if err = hash.UnmarshalText([]byte(parts[1])); err != nil {
+		Fatalf("Invalid fast verification checkpoint hash %s: %v", parts[1], err)
+	}
+	return &clique.FastVerifyCheckpoint{Number: number, Hash: hash}
+}
+
 // CheckExclusive verifies that only a single instance of the provided flags was
 // set by the user. Each flag might optionally be followed by a string type to
 // specialize it further.
@@ -1581,6 +2076,13 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	CheckExclusive(ctx, MainnetFlag, DeveloperFlag, RopstenFlag, RinkebyFlag, GoerliFlag, SepoliaFlag, KilnFlag)
 	CheckExclusive(ctx, LightServeFlag, SyncModeFlag, "light")
 	CheckExclusive(ctx, DeveloperFlag, ExternalSignerFlag) // Can't use both ephemeral unlocked and external signer
+	// Replicas only import the blocks of their primary
+	CheckExclusive(ctx, FollowerPrimaryFlag, MiningEnabledFlag)
+	CheckExclusive(ctx, FollowerPrimaryFlag, DeveloperFlag)
+	// The structured vanity replaces the free-form extra data
+	CheckExclusive(ctx, MinerExtraDataFlag, MinerVanityFlag)
+	CheckExclusive(ctx, MinerExtraDataFlag, MinerVanityTagFlag)
+	CheckExclusive(ctx, MinerExtraDataFlag, MinerDeterministicFlag)
 	if ctx.GlobalString(GCModeFlag.Name) == "archive" && ctx.GlobalUint64(TxLookupLimitFlag.Name) != 0 {
 		ctx.GlobalSet(TxLookupLimitFlag.Name, "0")
 		log.Warn("Disable transaction unindexing for archive node")
@@ -1595,11 +2097,80 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setEtherbase(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO, ctx.GlobalString(SyncModeFlag.Name) == "light")
 	setTxPool(ctx, &cfg.TxPool)
+	setTxManager(ctx, &cfg.TxManager)
 	setEthash(ctx, cfg)
 	setMiner(ctx, &cfg.Miner)
+	setClock(ctx, &cfg.CliqueClock)
 	setRequiredBlocks(ctx, cfg)
 	setLes(ctx, cfg)
 
+	if ctx.GlobalIsSet(EthStrictForkIDFlag.Name) {
+		cfg.StrictForkID = ctx.GlobalBool(EthStrictForkIDFlag.Name)
+	}
+	if ctx.GlobalIsSet(EthHeadLagPeriodsFlag.Name) {
+		cfg.HeadLagPeriods = ctx.GlobalUint64(EthHeadLagPeriodsFlag.Name)
+	}
+	if ctx.GlobalIsSet(EthAdvertiseValidatorFlag.Name) {
+		cfg.AdvertiseValidator = ctx.GlobalBool(EthAdvertiseValidatorFlag.Name)
+	}
+	if ctx.GlobalIsSet(SentryNodesFlag.Name) {
+		cfg.SentryNodes = SplitAndTrim(ctx.GlobalString(SentryNodesFlag.Name))
+	}
+	if ctx.GlobalIsSet(SentryValidatorsFlag.Name) {
+		cfg.SentryValidators = SplitAndTrim(ctx.GlobalString(SentryValidatorsFlag.Name))
+	}
+	if ctx.GlobalIsSet(CliqueMaxFutureDriftFlag.Name) {
+		cfg.CliqueMaxFutureDrift = ctx.GlobalDuration(CliqueMaxFutureDriftFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueTimestampJitterFlag.Name) {
+		cfg.CliqueTimestampJitter = ctx.GlobalDuration(CliqueTimestampJitterFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueFastVerifyFlag.Name) {
+		cfg.CliqueFastVerify = parseFastVerifyCheckpoint(ctx.GlobalString(CliqueFastVerifyFlag.Name))
+	}
+	if ctx.GlobalIsSet(CliqueNoPreflightFlag.Name) {
+		cfg.NoPreflight = ctx.GlobalBool(CliqueNoPreflightFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliquePreflightLagFlag.Name) {
+		cfg.PreflightMaxLag = ctx.GlobalUint64(CliquePreflightLagFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliquePreflightDiskFlag.Name) {
+		cfg.PreflightMinFreeDisk = ctx.GlobalUint64(CliquePreflightDiskFlag.Name)
+	}
+	if ctx.GlobalIsSet(ContractAnalyticsFlag.Name) {
+		cfg.ContractAnalytics = ctx.GlobalBool(ContractAnalyticsFlag.Name)
+	}
+	if ctx.GlobalIsSet(EpochStatsFlag.Name) {
+		cfg.EpochStats = ctx.GlobalBool(EpochStatsFlag.Name)
+	}
+	if ctx.GlobalIsSet(ExExSocketFlag.Name) {
+		cfg.ExExSocket = ctx.GlobalString(ExExSocketFlag.Name)
+	}
+	if ctx.GlobalIsSet(ExExStateDiffsFlag.Name) {
+		cfg.ExExStateDiffs = ctx.GlobalBool(ExExStateDiffsFlag.Name)
+	}
+	if ctx.GlobalIsSet(FollowerServeFlag.Name) {
+		cfg.Replica.Serve = ctx.GlobalBool(FollowerServeFlag.Name)
+	}
+	if ctx.GlobalIsSet(FollowerPrimaryFlag.Name) {
+		cfg.Replica.Primary = ctx.GlobalString(FollowerPrimaryFlag.Name)
+	}
+	if ctx.GlobalIsSet(FollowerJWTSecretFlag.Name) {
+		cfg.Replica.JWTSecret = ctx.GlobalString(FollowerJWTSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(ReplicaStandbyFlag.Name) {
+		cfg.Replica.Standby = ctx.GlobalString(ReplicaStandbyFlag.Name)
+	}
+	if ctx.GlobalIsSet(MaintenanceBandwidthFlag.Name) {
+		cfg.Maintenance.Bandwidth = ctx.GlobalInt(MaintenanceBandwidthFlag.Name) * 1024 * 1024
+	}
+	if ctx.GlobalIsSet(MaintenanceIOPSFlag.Name) {
+		cfg.Maintenance.IOPS = ctx.GlobalInt(MaintenanceIOPSFlag.Name)
+	}
+	if ctx.GlobalIsSet(MaintenanceInTurnWindowFlag.Name) {
+		cfg.Maintenance.InTurnWindow = ctx.GlobalDuration(MaintenanceInTurnWindowFlag.Name)
+	}
+
 	// Cap the cache allowance and tune the garbage collector
 	mem, err := gopsutil.VirtualMemory()
 	if err == nil {
@@ -1664,6 +2235,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieDirtyCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalIsSet(CacheGCAutoTuneFlag.Name) {
+		cfg.TrieDirtyAutoTune = ctx.GlobalBool(CacheGCAutoTuneFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheSnapshotFlag.Name) {
 		cfg.SnapshotCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheSnapshotFlag.Name) / 100
 	}
@@ -1695,9 +2269,24 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(RPCGlobalEVMTimeoutFlag.Name) {
 		cfg.RPCEVMTimeout = ctx.GlobalDuration(RPCGlobalEVMTimeoutFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCTraceCacheFlag.Name) {
+		cfg.RPCTraceCache = ctx.GlobalInt(RPCTraceCacheFlag.Name)
+	}
 	if ctx.GlobalIsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.GlobalFloat64(RPCGlobalTxFeeCapFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCGasPriceFloorFlag.Name) {
+		cfg.RPCGasPriceFloor = GlobalBig(ctx, RPCGasPriceFloorFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCGasPriceCeilFlag.Name) {
+		cfg.RPCGasPriceCeil = GlobalBig(ctx, RPCGasPriceCeilFlag.Name)
+	}
+	if cfg.RPCGasPriceFloor != nil && cfg.RPCGasPriceCeil != nil && cfg.RPCGasPriceFloor.Cmp(cfg.RPCGasPriceCeil) > 0 {
+		Fatalf("Gas price floor %v above ceiling %v", cfg.RPCGasPriceFloor, cfg.RPCGasPriceCeil)
+	}
+	if ctx.GlobalIsSet(RPCCliqueFieldsFlag.Name) {
+		cfg.RPCCliqueFields = ctx.GlobalBool(RPCCliqueFieldsFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) {
 		cfg.EthDiscoveryURLs, cfg.SnapDiscoveryURLs = []string{}, []string{}
 	} else if ctx.GlobalIsSet(DNSDiscoveryFlag.Name) {
@@ -1761,6 +2350,11 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 			cfg.NetworkId = 1337
 		}
 		cfg.SyncMode = downloader.FullSync
+		cfg.DevControls = true
+		// Developer chains travel in time, don't check their clock unless asked
+		if !ctx.GlobalIsSet(CliqueNTPServersFlag.Name) {
+			cfg.CliqueClock.Servers = nil
+		}
 		// Create new developer account or reuse existing one
 		var (
 			developer  accounts.Account
@@ -1791,6 +2385,20 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 
 		// Create a new developer genesis block or reuse existing one
 		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), ctx.GlobalUint64(DeveloperGasLimitFlag.Name), developer.Address)
+		if ctx.GlobalIsSet(DeveloperForkFlag.Name) {
+			// Only fund the developer on top of the remote state and inherit
+			// the gas limit of the remote chain unless explicitly requested.
+			cfg.StateOverlay = ctx.GlobalString(DeveloperForkFlag.Name)
+			if ctx.GlobalIsSet(DeveloperForkBlockFlag.Name) {
+				number := ctx.GlobalUint64(DeveloperForkBlockFlag.Name)
+				cfg.StateOverlayBlock = &number
+			}
+			cfg.Genesis.Alloc = core.GenesisAlloc{developer.Address: cfg.Genesis.Alloc[developer.Address]}
+			if !ctx.GlobalIsSet(DeveloperGasLimitFlag.Name) {
+				cfg.Genesis.GasLimit = 0
+			}
+			log.Info("Forking developer chain off remote state", "endpoint", cfg.StateOverlay)
+		}
 		if ctx.GlobalIsSet(DataDirFlag.Name) {
 			// If datadir doesn't exist we need to open db in write-mode
 			// so leveldb can create files.
@@ -1864,7 +2472,17 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 			Fatalf("Failed to register the catalyst service: %v", err)
 		}
 	}
-	stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
+	if cfg.RPCTraceCache > 0 {
+		db, err := stack.OpenDatabase("tracecache", 16, 16, "eth/db/tracecache/", false)
+		if err != nil {
+			Fatalf("Failed to open the trace cache: %v", err)
+		}
+		cache := tracers.NewTraceCache(db, backend.APIBackend, uint64(cfg.RPCTraceCache)*1024*1024)
+		stack.RegisterLifecycle(cache)
+		stack.RegisterAPIs(tracers.APIsWithCache(backend.APIBackend, cache))
+	} else {
+		stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
+	}
 	return backend.APIBackend, backend
 }
 
@@ -1876,6 +2494,19 @@ func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url strin
 	}
 }
 
+// RegisterRosettaService mounts the Rosetta API on the node's HTTP-RPC server.
+func RegisterRosettaService(stack *node.Node, backend *eth.Ethereum, cfg node.Config, config rosetta.Config) {
+	peers := func() []*rosetta.Peer {
+		var peers []*rosetta.Peer
+		for _, info := range stack.Server().PeersInfo() {
+			peers = append(peers, &rosetta.Peer{PeerID: info.ID})
+		}
+		return peers
+	}
+	handler := node.NewHTTPHandlerStack(rosetta.NewHandler(backend.APIBackend, config, peers), cfg.HTTPCors, cfg.HTTPVirtualHosts, nil)
+	stack.RegisterHandler("Rosetta", "/rosetta/", handler)
+}
+
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
 func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, cfg node.Config) {
 	if err := graphql.New(stack, backend, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
@@ -0,0 +1,175 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+)
+
+// Kinds of anomalies detected in the sealing record of an epoch.
+const (
+	AnomalySealShare    = "seal-share"      // Signer sealing far more than its fair share of the blocks
+	AnomalyTimestampGap = "timestamp-gap"   // Block timestamp far past the one of its parent
+	AnomalyOutOfTurnRun = "out-of-turn-run" // Long run of consecutive out-of-turn blocks
+)
+
+const (
+	anomalySealShareZ    = 4.0 // Standard deviations above the fair share a signer's count is anomalous beyond
+	anomalyMinRounds     = 4   // Full signer rounds needed before judging the seal shares
+	anomalyGapPeriods    = 3   // Block periods between two blocks considered a timestamp gap
+	anomalyOutOfTurnRun  = 4   // Consecutive out-of-turn blocks considered a cluster
+	maxAnomaliesPerEpoch = 256 // Maximum number of block bound anomalies reported for an epoch
+)
+
+// Anomaly is a statistical irregularity in the sealing record of an epoch.
+type Anomaly struct {
+	Kind      string          `json:"kind"`
+	Epoch     uint64          `json:"epoch"`
+	Validator *common.Address `json:"validator,omitempty"` // Validator the anomaly is attributed to, if any
+	Block     *uint64         `json:"block,omitempty"`     // First block of the anomaly, if bound to blocks
+	Value     float64         `json:"value"`               // Observed value, durations in seconds
+	Threshold float64         `json:"threshold"`           // Value the observation is anomalous beyond
+}
+
+// EpochAnomalies are the anomalies detected over the blocks of an epoch.
+type EpochAnomalies struct {
+	Epoch      uint64     `json:"epoch"`
+	EpochBlock uint64     `json:"epochBlock"` // Block which started the epoch
+	StartBlock uint64     `json:"startBlock"` // First block analyzed
+	EndBlock   uint64     `json:"endBlock"`   // Last block analyzed
+	Closed     bool       `json:"closed"`     // Whether the epoch was closed by the end block
+	Anomalies  []*Anomaly `json:"anomalies"`
+}
+
+// sealedBlock is the sealing record of a single block analyzed for anomalies.
+type sealedBlock struct {
+	number uint64
+	signer common.Address
+	inturn bool
+	time   uint64
+}
+
+// analyzeBlocks checks the sealing record of consecutive blocks of an epoch for
+// anomalies. The parent time is the timestamp of the block preceding the first
+// one, the period the expected block interval in seconds, zero if unknown.
+func analyzeBlocks(epoch uint64, signers []common.Address, period uint64, parentTime uint64, blocks []sealedBlock) []*Anomaly {
+	anomalies := []*Anomaly{}
+	if len(blocks) == 0 {
+		return anomalies
+	}
+	var (
+		counts  = make(map[common.Address]uint64)
+		bound   int      // Number of block bound anomalies reported
+		run     int      // Length of the current out-of-turn run
+		cluster *Anomaly // Anomaly reported for the current out-of-turn run, if any
+	)
+	report := func(kind string, validator *common.Address, block uint64, value, threshold float64) *Anomaly {
+		if bound >= maxAnomaliesPerEpoch {
+			return nil
+		}
+		bound++
+		anomaly := &Anomaly{Kind: kind, Epoch: epoch, Validator: validator, Block: &block, Value: value, Threshold: threshold}
+		anomalies = append(anomalies, anomaly)
+		return anomaly
+	}
+	for i, block := range blocks {
+		counts[block.signer]++
+
+		// Blocks can't be sealed ahead of the period, but stalls show as gaps
+		if period > 0 && block.time > parentTime+anomalyGapPeriods*period {
+			signer := block.signer
+			report(AnomalyTimestampGap, &signer, block.number, float64(block.time-parentTime), float64(anomalyGapPeriods*period))
+		}
+		parentTime = block.time
+
+		// Report out-of-turn runs once, as soon as they reach the threshold
+		if block.inturn {
+			run, cluster = 0, nil
+			continue
+		}
+		if run++; run == anomalyOutOfTurnRun {
+			cluster = report(AnomalyOutOfTurnRun, nil, blocks[i-run+1].number, float64(run), anomalyOutOfTurnRun)
+		} else if cluster != nil {
+			cluster.Value = float64(run)
+		}
+	}
+	// The sealers of each block are roughly a binomial draw over the signers,
+	// flag the ones far in the upper tail once enough rounds were sealed
+	if n := len(signers); n > 1 && len(blocks) >= anomalyMinRounds*n {
+		var (
+			p         = 1 / float64(n)
+			mean      = float64(len(blocks)) * p
+			threshold = mean + anomalySealShareZ*math.Sqrt(mean*(1-p))
+		)
+		for _, signer := range signers {
+			if count := float64(counts[signer]); count > threshold {
+				signer := signer
+				anomalies = append(anomalies, &Anomaly{Kind: AnomalySealShare, Epoch: epoch, Validator: &signer, Value: count, Threshold: threshold})
+			}
+		}
+	}
+	return anomalies
+}
+
+// Anomalies analyzes the sealing record of an epoch for anomalies, be it the
+// running one or a closed one. At most the last 50000 blocks of the epoch are
+// analyzed.
+func (c *Clique) Anomalies(chain consensus.ChainHeaderReader, epoch uint64) (*EpochAnomalies, error) {
+	snap, end, closed, err := c.epochSpan(chain, epoch)
+	if err != nil {
+		return nil, err
+	}
+	start := snap.Number + 1
+	if end >= start+maxEpochScan {
+		start = end - maxEpochScan + 1
+	}
+	result := &EpochAnomalies{
+		Epoch:      epoch,
+		EpochBlock: snap.Number,
+		StartBlock: start,
+		EndBlock:   end,
+		Closed:     closed,
+	}
+	parent := chain.GetHeaderByNumber(start - 1)
+	if parent == nil {
+		return nil, errMissingBlock(start - 1)
+	}
+	blocks := make([]sealedBlock, 0, end+1-start)
+	for n := start; n <= end; n++ {
+		entry, err := c.sealerAt(chain, n)
+		if err != nil {
+			return nil, err
+		}
+		header := chain.GetHeaderByNumber(n)
+		if header == nil {
+			return nil, errMissingBlock(n)
+		}
+		blocks = append(blocks, sealedBlock{number: n, signer: entry.Signer, inturn: entry.Inturn, time: header.Time})
+	}
+	result.Anomalies = analyzeBlocks(epoch, snap.signers(), c.config.Period, parent.Time, blocks)
+	return result, nil
+}
+
+// GetAnomalies returns the statistical anomalies detected in the sealing record
+// of an epoch: signers sealing far more than their fair share, timestamp gaps
+// and clusters of out-of-turn blocks.
+func (api *API) GetAnomalies(epoch uint64) (*EpochAnomalies, error) {
+	return api.clique.Anomalies(api.chain, epoch)
+}
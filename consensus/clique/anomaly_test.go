@@ -0,0 +1,74 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that the epoch analysis flags excessive seal shares, timestamp gaps and
+// out-of-turn runs, while leaving a regular rotation alone.
+func TestAnalyzeBlocks(t *testing.T) {
+	signers := []common.Address{{1}, {2}, {3}, {4}}
+
+	// rotation seals the given number of blocks in turn, every period seconds
+	rotation := func(count int) []sealedBlock {
+		blocks := make([]sealedBlock, count)
+		for i := range blocks {
+			n := uint64(i + 1)
+			blocks[i] = sealedBlock{number: n, signer: signers[n%4], inturn: true, time: 1000 + 5*n}
+		}
+		return blocks
+	}
+	if anomalies := analyzeBlocks(1, signers, 5, 1000, rotation(100)); len(anomalies) != 0 {
+		t.Fatalf("regular rotation flagged: %v", anomalies)
+	}
+	// Signer 1 takes over every out-of-turn slot from block 20, after a stall
+	blocks := rotation(100)
+	for i := 19; i < len(blocks); i++ {
+		if blocks[i].signer != signers[1] {
+			blocks[i].signer, blocks[i].inturn = signers[1], false
+		}
+		blocks[i].time += 60
+	}
+	anomalies := analyzeBlocks(1, signers, 5, 1000, blocks)
+
+	kinds := make(map[string][]*Anomaly)
+	for _, anomaly := range anomalies {
+		kinds[anomaly.Kind] = append(kinds[anomaly.Kind], anomaly)
+	}
+	if gaps := kinds[AnomalyTimestampGap]; len(gaps) != 1 || *gaps[0].Block != 20 || gaps[0].Value != 65 {
+		t.Errorf("timestamp gaps mismatch: have %v", gaps)
+	}
+	if shares := kinds[AnomalySealShare]; len(shares) != 1 || *shares[0].Validator != signers[1] || shares[0].Value != 86 {
+		t.Errorf("seal shares mismatch: have %v", shares)
+	}
+	if runs := kinds[AnomalyOutOfTurnRun]; len(runs) != 0 {
+		t.Errorf("out-of-turn runs shorter than a round flagged: %v", runs)
+	}
+	// Blocks 50 to 55 are all sealed out-of-turn
+	blocks = rotation(100)
+	for i := 49; i < 55; i++ {
+		blocks[i].signer, blocks[i].inturn = signers[(i+2)%4], false
+	}
+	anomalies = analyzeBlocks(1, signers, 5, 1000, blocks)
+	if len(anomalies) != 1 || anomalies[0].Kind != AnomalyOutOfTurnRun || *anomalies[0].Block != 50 || anomalies[0].Value != 6 {
+		t.Errorf("out-of-turn runs mismatch: have %v", anomalies)
+	}
+}
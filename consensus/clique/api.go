@@ -17,14 +17,16 @@
 package clique
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -47,7 +49,7 @@ func (api *API) GetSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
 	}
 	// Ensure we have an actually valid block and return its snapshot
 	if header == nil {
-		return nil, errUnknownBlock
+		return nil, errMissingBlock(uint64(number.Int64()))
 	}
 	return api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
 }
@@ -56,11 +58,39 @@ func (api *API) GetSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
 func (api *API) GetSnapshotAtHash(hash common.Hash) (*Snapshot, error) {
 	header := api.chain.GetHeaderByHash(hash)
 	if header == nil {
-		return nil, errUnknownBlock
+		return nil, errMissingBlockHash(hash)
 	}
 	return api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
 }
 
+// rawSnapshot is the canonical serialization of a snapshot and its hash.
+type rawSnapshot struct {
+	Number  uint64        `json:"number"`
+	Hash    common.Hash   `json:"hash"` // Hash of the snapshot block
+	RLP     hexutil.Bytes `json:"rlp"`
+	RLPHash common.Hash   `json:"rlpHash"`
+}
+
+// GetSnapshotRaw retrieves the canonical RLP encoding of the snapshot at a given
+// block along with its keccak256 hash, allowing other implementations to compare
+// their snapshot derivation byte by byte.
+func (api *API) GetSnapshotRaw(number *rpc.BlockNumber) (*rawSnapshot, error) {
+	snap, err := api.GetSnapshot(number)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := rlp.EncodeToBytes(snap)
+	if err != nil {
+		return nil, err
+	}
+	return &rawSnapshot{
+		Number:  snap.Number,
+		Hash:    snap.Hash,
+		RLP:     blob,
+		RLPHash: crypto.Keccak256Hash(blob),
+	}, nil
+}
+
 // GetSigners retrieves the list of authorized signers at the specified block.
 func (api *API) GetSigners(number *rpc.BlockNumber) ([]common.Address, error) {
 	// Retrieve the requested block number (or current if none requested)
@@ -72,7 +102,7 @@ func (api *API) GetSigners(number *rpc.BlockNumber) ([]common.Address, error) {
 	}
 	// Ensure we have an actually valid block and return the signers from its snapshot
 	if header == nil {
-		return nil, errUnknownBlock
+		return nil, errMissingBlock(uint64(number.Int64()))
 	}
 	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
 	if err != nil {
@@ -85,7 +115,7 @@ func (api *API) GetSigners(number *rpc.BlockNumber) ([]common.Address, error) {
 func (api *API) GetSignersAtHash(hash common.Hash) ([]common.Address, error) {
 	header := api.chain.GetHeaderByHash(hash)
 	if header == nil {
-		return nil, errUnknownBlock
+		return nil, errMissingBlockHash(hash)
 	}
 	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
 	if err != nil {
@@ -94,6 +124,80 @@ func (api *API) GetSignersAtHash(hash common.Hash) ([]common.Address, error) {
 	return snap.signers(), nil
 }
 
+// recentSeal is a block sealed within the recently-signed window.
+type recentSeal struct {
+	Block  uint64         `json:"block"`
+	Signer common.Address `json:"signer"`
+}
+
+// recentsWindow are the seals observed within the recently-signed window at a
+// block. The window isn't enforced by consensus, it's derived from the sealer
+// index for monitoring only.
+type recentsWindow struct {
+	Number  uint64           `json:"number"`
+	Limit   uint64           `json:"limit"`   // Half the signers plus one, the window spans limit-1 blocks
+	Recents []recentSeal     `json:"recents"` // Last block of each signer within the window, in ascending block order
+	Sealed  []common.Address `json:"sealed"`  // Current signers observed sealing within the window
+	Window  []recentSeal     `json:"window"`  // Sealers of the last limit-1 canonical blocks
+}
+
+// GetRecents retrieves the seals observed within the recently-signed window at
+// the specified block: the sealers of the canonical blocks the window spans,
+// taken from the sealer index, the last block of each signer within it and the
+// current signers among them. Consensus doesn't bar these signers from sealing
+// the next block, the window only shows how the sealing rotated.
+func (api *API) GetRecents(number *rpc.BlockNumber) (*recentsWindow, error) {
+	// Retrieve the requested block number (or current if none requested)
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return nil, errMissingBlock(uint64(number.Int64()))
+	}
+	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		head   = header.Number.Uint64()
+		limit  = uint64(len(snap.Signers)/2 + 1)
+		result = &recentsWindow{
+			Number:  head,
+			Limit:   limit,
+			Recents: make([]recentSeal, 0, len(snap.Recents)),
+			Sealed:  []common.Address{},
+			Window:  make([]recentSeal, 0, limit-1),
+		}
+	)
+	start := uint64(1)
+	if head+1 > limit {
+		start = head + 2 - limit
+	}
+	last := make(map[common.Address]uint64)
+	for n := start; n <= head; n++ {
+		entry, err := api.clique.sealerAt(api.chain, n)
+		if err != nil {
+			return nil, err
+		}
+		result.Window = append(result.Window, recentSeal{Block: n, Signer: entry.Signer})
+		last[entry.Signer] = n
+	}
+	for signer, block := range last {
+		result.Recents = append(result.Recents, recentSeal{Block: block, Signer: signer})
+	}
+	sort.Slice(result.Recents, func(i, j int) bool { return result.Recents[i].Block < result.Recents[j].Block })
+
+	for _, signer := range snap.signers() {
+		if _, ok := last[signer]; ok {
+			result.Sealed = append(result.Sealed, signer)
+		}
+	}
+	return result, nil
+}
+
 type status struct {
 	InturnPercent float64                `json:"inturnPercent"`
 	SigningStatus map[common.Address]int `json:"sealerActivity"`
@@ -106,8 +210,14 @@ type epochPerformance struct {
 	NumBlocks     uint64                 `json:"numBlocks"`
 	NextEpoch     uint64                 `json:"nextEpoch"`
 	StartBlock    uint64                 `json:"startBlock"`
+	EndBlock      uint64                 `json:"endBlock"`       // Last block scanned
+	Next          *uint64                `json:"next,omitempty"` // First block not yet scanned if the scan was cut short
 }
 
+// maxEpochScan is the maximum number of headers scanned by a single epoch
+// performance request.
+const maxEpochScan = 50000
+
 // Status returns the status of the last N blocks,
 // - the number of active signers,
 // - the number of signers,
@@ -116,7 +226,6 @@ func (api *API) Status() (*status, error) {
 	var (
 		numBlocks = uint64(64)
 		header    = api.chain.CurrentHeader()
-		diff      = uint64(0)
 		optimals  = 0
 	)
 	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
@@ -137,25 +246,23 @@ func (api *API) Status() (*status, error) {
 		signStatus[s] = 0
 	}
 	for n := start; n < end; n++ {
-		h := api.chain.GetHeaderByNumber(n)
-		if h == nil {
-			return nil, fmt.Errorf("missing block %d", n)
-		}
-		if h.Difficulty.Cmp(diffInTurn) == 0 {
-			optimals++
-		}
-		diff += h.Difficulty.Uint64()
-		sealer, err := api.clique.Author(h)
+		entry, err := api.clique.sealerAt(api.chain, n)
 		if err != nil {
 			return nil, err
 		}
-		signStatus[sealer]++
+		if entry.Inturn {
+			optimals++
+		}
+		signStatus[entry.Signer]++
 	}
-	return &status{
-		InturnPercent: float64(100*optimals) / float64(numBlocks),
+	res := &status{
 		SigningStatus: signStatus,
 		NumBlocks:     numBlocks,
-	}, nil
+	}
+	if numBlocks > 0 {
+		res.InturnPercent = float64(100*optimals) / float64(numBlocks)
+	}
+	return res, nil
 }
 
 // SlasherStat returns the performance of all the validators in the previous epoch only,
@@ -165,68 +272,28 @@ func (api *API) Status() (*status, error) {
 // - next epoch number if available else 0,
 // - the percentage of in-turn blocks
 func (api *API) SlasherStat(epochNumber uint64) (*epochPerformance, error) {
-	var (
-		numBlocks = uint64(0)
-		header    = api.chain.CurrentHeader()
-		optimals  = 0
-	)
+	header := api.chain.CurrentHeader()
+
 	// get the latest epoch which is running currently
-	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	current, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
 	if err != nil {
 		return nil, err
 	}
 	// should have at least another epoch before current epoch
-	if snap.PreviousSnapNumber == nil || snap.PreviousSnapHash == nil {
-		return nil, fmt.Errorf("requested epoch not found")
+	if current.PreviousSnapNumber == nil || current.PreviousSnapHash == nil {
+		return nil, errEpochNotFound(epochNumber)
 	}
 
 	// get the target epoch/the previous epoch where slashing is allowed
-	snap, err = api.clique.snapshot(api.chain, *snap.PreviousSnapNumber, *snap.PreviousSnapHash, nil)
+	snap, err := api.clique.snapshot(api.chain, *current.PreviousSnapNumber, *current.PreviousSnapHash, nil)
 	if err != nil {
 		return nil, err
 	}
 	if snap.EpochNumber != epochNumber {
-		return nil, fmt.Errorf("epoch number mismatch, expected=%v got=%v", epochNumber, snap.EpochNumber)
-	}
-	var (
-		signers = snap.signers()
-		end     = header.Number.Uint64()
-		start   = snap.Number + 1
-	)
-	signStatus := make(map[common.Address]int)
-	for _, s := range signers {
-		signStatus[s] = 0
-	}
-	nextEpoch := uint64(0)
-	n := start
-	for ; n <= end; n++ {
-		h := api.chain.GetHeaderByNumber(n)
-		if h == nil {
-			return nil, fmt.Errorf("missing block %d", n)
-		}
-		numBlocks++
-
-		if h.Difficulty.Cmp(diffInTurn) == 0 {
-			optimals++
-		}
-		sealer, err := api.clique.Author(h)
-		if err != nil {
-			return nil, err
-		}
-		signStatus[sealer]++
-
-		if !bytes.Equal(h.Nonce[:], nonceDropVote) {
-			nextEpoch = h.Nonce.Uint64()
-			break
-		}
+		return nil, errEpochMismatch(epochNumber, snap.EpochNumber)
 	}
-	return &epochPerformance{
-		InturnPercent: float64(100*optimals) / float64(numBlocks),
-		SigningStatus: signStatus,
-		NumBlocks:     numBlocks,
-		NextEpoch:     nextEpoch,
-		StartBlock:    start,
-	}, nil
+	// the previous epoch is closed by the block of the current one
+	return api.scanEpoch(snap, snap.Number+1, current.Number)
 }
 
 // EpochPerformance returns the performance of all the validators in an epoch,
@@ -235,62 +302,113 @@ func (api *API) SlasherStat(epochNumber uint64) (*epochPerformance, error) {
 // - start of epoch block,
 // - next epoch number if available else 0,
 // - the percentage of in-turn blocks
-func (api *API) EpochPerformance(epochNumber, epochBlockNumber uint64) (*epochPerformance, error) {
-	var (
-		numBlocks = uint64(0)
-		header    = api.chain.CurrentHeader()
-		optimals  = 0
-	)
+//
+// At most limit blocks (and never more than 50000) are scanned. If the epoch
+// extends beyond them, the result reports the next block to continue with via
+// EpochPerformanceRange.
+func (api *API) EpochPerformance(epochNumber, epochBlockNumber uint64, limit *uint64) (*epochPerformance, error) {
+	n := uint64(maxEpochScan)
+	if limit != nil {
+		n = *limit
+	}
+	if n == 0 || n > maxEpochScan {
+		return nil, errInvalidLimit(n, maxEpochScan, fmt.Sprintf("invalid limit %d, must be between 1 and %d", n, maxEpochScan))
+	}
+	snap, err := api.epochSnapshot(epochNumber, epochBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	start := snap.Number + 1
+	end := api.chain.CurrentHeader().Number.Uint64()
+	if end >= start+n {
+		end = start + n - 1
+	}
+	return api.scanEpoch(snap, start, end)
+}
+
+// EpochPerformanceRange returns the performance of the validators of an epoch
+// over at most limit blocks starting at from, which must lie after the epoch
+// block. It allows consuming the performance of long running epochs page by
+// page, continuing each request at the returned next block until it is unset.
+func (api *API) EpochPerformanceRange(epochNumber, epochBlockNumber, from, limit uint64) (*epochPerformance, error) {
+	if limit == 0 || limit > maxEpochScan {
+		return nil, errInvalidLimit(limit, maxEpochScan, fmt.Sprintf("invalid limit %d, must be between 1 and %d", limit, maxEpochScan))
+	}
+	snap, err := api.epochSnapshot(epochNumber, epochBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if from <= snap.Number {
+		return nil, errInvalidRange(from, snap.Number)
+	}
+	end := api.chain.CurrentHeader().Number.Uint64()
+	if end >= from+limit {
+		end = from + limit - 1
+	}
+	return api.scanEpoch(snap, from, end)
+}
+
+// epochSnapshot retrieves the snapshot created at the given epoch block,
+// ensuring it belongs to the expected epoch.
+func (api *API) epochSnapshot(epochNumber, epochBlockNumber uint64) (*Snapshot, error) {
 	epochBlock := api.chain.GetHeaderByNumber(epochBlockNumber)
 	if epochBlock == nil {
-		return nil, fmt.Errorf("missing epoch block %d", epochBlockNumber)
+		return nil, errMissingBlock(epochBlockNumber)
 	}
 	snap, err := api.clique.snapshot(api.chain, epochBlockNumber, epochBlock.Hash(), nil)
 	if err != nil {
 		return nil, err
 	}
 	if snap.EpochNumber != epochNumber {
-		return nil, fmt.Errorf("epoch number mismatch, expected=%v got=%v", epochNumber, snap.EpochNumber)
+		return nil, errEpochMismatch(epochNumber, snap.EpochNumber)
 	}
-	var (
-		signers = snap.signers()
-		end     = header.Number.Uint64()
-		start   = snap.Number + 1
-	)
-	signStatus := make(map[common.Address]int)
-	for _, s := range signers {
-		signStatus[s] = 0
+	return snap, nil
+}
+
+// scanEpoch tallies the sealers of the canonical blocks from start to end
+// (inclusive) against the signers of the given snapshot. The scan stops early
+// at the first block of the next epoch, which is counted as the closing block
+// of the scanned one. If the end is reached without closing the epoch and the
+// chain continues past it, the next block to scan is reported.
+func (api *API) scanEpoch(snap *Snapshot, start, end uint64) (*epochPerformance, error) {
+	perf := &epochPerformance{
+		SigningStatus: make(map[common.Address]int),
+		StartBlock:    start,
 	}
-	nextEpoch := uint64(0)
-	n := start
-	for ; n <= end; n++ {
-		h := api.chain.GetHeaderByNumber(n)
-		if h == nil {
-			return nil, fmt.Errorf("missing block %d", n)
+	for _, s := range snap.signers() {
+		perf.SigningStatus[s] = 0
+	}
+	optimals := 0
+	for n := start; n <= end; n++ {
+		entry, err := api.clique.sealerAt(api.chain, n)
+		if err != nil {
+			return nil, err
 		}
-		numBlocks++
+		perf.NumBlocks++
+		perf.EndBlock = n
 
-		if h.Difficulty.Cmp(diffInTurn) == 0 {
+		if entry.Inturn {
 			optimals++
 		}
-		sealer, err := api.clique.Author(h)
-		if err != nil {
-			return nil, err
-		}
-		signStatus[sealer]++
+		perf.SigningStatus[entry.Signer]++
 
-		if !bytes.Equal(h.Nonce[:], nonceDropVote) {
-			nextEpoch = h.Nonce.Uint64()
+		if entry.Epoch {
+			h := api.chain.GetHeaderByNumber(n)
+			if h == nil {
+				return nil, errMissingBlock(n)
+			}
+			perf.NextEpoch = h.Nonce.Uint64()
 			break
 		}
 	}
-	return &epochPerformance{
-		InturnPercent: float64(100*optimals) / float64(numBlocks),
-		SigningStatus: signStatus,
-		NumBlocks:     numBlocks,
-		NextEpoch:     nextEpoch,
-		StartBlock:    start,
-	}, nil
+	if perf.NumBlocks > 0 {
+		perf.InturnPercent = float64(100*optimals) / float64(perf.NumBlocks)
+	}
+	if perf.NextEpoch == 0 && end < api.chain.CurrentHeader().Number.Uint64() {
+		next := end + 1
+		perf.Next = &next
+	}
+	return perf, nil
 }
 
 type blockNumberOrHashOrRLP struct {
@@ -324,16 +442,20 @@ func (sb *blockNumberOrHashOrRLP) UnmarshalJSON(data []byte) error {
 func (api *API) GetSigner(rlpOrBlockNr *blockNumberOrHashOrRLP) (common.Address, error) {
 	if len(rlpOrBlockNr.RLP) == 0 {
 		blockNrOrHash := rlpOrBlockNr.BlockNumberOrHash
-		var header *types.Header
 		if blockNrOrHash == nil {
-			header = api.chain.CurrentHeader()
-		} else if hash, ok := blockNrOrHash.Hash(); ok {
-			header = api.chain.GetHeaderByHash(hash)
-		} else if number, ok := blockNrOrHash.Number(); ok {
-			header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+			return api.clique.Author(api.chain.CurrentHeader())
+		}
+		if hash, ok := blockNrOrHash.Hash(); ok {
+			header := api.chain.GetHeaderByHash(hash)
+			if header == nil {
+				return common.Address{}, errMissingBlockHash(hash)
+			}
+			return api.clique.Author(header)
 		}
+		number, _ := blockNrOrHash.Number()
+		header := api.chain.GetHeaderByNumber(uint64(number.Int64()))
 		if header == nil {
-			return common.Address{}, fmt.Errorf("missing block %v", blockNrOrHash.String())
+			return common.Address{}, errMissingBlock(uint64(number.Int64()))
 		}
 		return api.clique.Author(header)
 	}
@@ -347,3 +469,70 @@ func (api *API) GetSigner(rlpOrBlockNr *blockNumberOrHashOrRLP) (common.Address,
 	}
 	return api.clique.Author(header)
 }
+
+// sealVerdict is the outcome of verifying a header against the clique rules.
+type sealVerdict struct {
+	Valid  bool        `json:"valid"`
+	Error  string      `json:"error,omitempty"` // First consensus rule violated, if any
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+	Epoch  uint64      `json:"epoch"` // Epoch of the snapshot the header is checked against
+
+	Signer             *common.Address `json:"signer,omitempty"` // Recovered sealer, unset if the seal is malformed
+	Authorized         bool            `json:"authorized"`       // Whether the sealer is in the signer set
+	RecentlySigned     bool            `json:"recentlySigned"`   // Whether the sealer signed one of the recent blocks
+	Inturn             bool            `json:"inturn"`
+	Difficulty         *hexutil.Big    `json:"difficulty"`
+	ExpectedDifficulty *hexutil.Big    `json:"expectedDifficulty,omitempty"`
+}
+
+// VerifyHeaderSeal checks whether a header, given as the RLP encoding of either
+// a block or a header, would be accepted on top of its parent. Besides the first
+// rule violated, the verdict details the sealer checks against the snapshot at
+// the parent: signer authorization, recency and in-turn difficulty. The header
+// is not imported nor recorded.
+func (api *API) VerifyHeaderSeal(blob hexutil.Bytes) (*sealVerdict, error) {
+	header := new(types.Header)
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(blob, block); err == nil {
+		header = block.Header()
+	} else if err := rlp.DecodeBytes(blob, header); err != nil {
+		return nil, err
+	}
+	if header.Number == nil || header.Number.Sign() == 0 {
+		return nil, errors.New("genesis header can't be verified")
+	}
+	number := header.Number.Uint64()
+	if api.chain.GetHeader(header.ParentHash, number-1) == nil {
+		return nil, errMissingBlockHash(header.ParentHash)
+	}
+	snap, err := api.clique.snapshot(api.chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	verdict := &sealVerdict{
+		Number:     number,
+		Hash:       header.Hash(),
+		Epoch:      snap.EpochNumber,
+		Difficulty: (*hexutil.Big)(header.Difficulty),
+	}
+	if signer, err := ecrecover(header, api.clique.signatures); err == nil {
+		_, authorized := snap.Signers[signer]
+		inturn := snap.inturn(number, signer)
+
+		verdict.Signer = &signer
+		verdict.Authorized = authorized
+		verdict.RecentlySigned = snap.recentlySigned(number, signer)
+		verdict.Inturn = inturn
+		verdict.ExpectedDifficulty = (*hexutil.Big)(diffNoTurn)
+		if inturn {
+			verdict.ExpectedDifficulty = (*hexutil.Big)(diffInTurn)
+		}
+	}
+	if err := api.clique.checkHeader(api.chain, header, nil, false); err != nil {
+		verdict.Error = err.Error()
+	} else {
+		verdict.Valid = true
+	}
+	return verdict, nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the recently-signed window is derived from the sealer index of the
+// canonical blocks it spans.
+func TestGetRecents(t *testing.T) {
+	var (
+		db         = rawdb.NewMemoryDatabase()
+		validators = []common.Address{{0x01}, {0x02}, {0x03}, {0x04}, {0x05}}
+		config     = &params.CliqueConfig{InitialValidators: validators}
+	)
+	NewDNR(config, db)
+	writeTestChain(db, 8, nil)
+	if _, err := RebuildSnapshots(db, config, nil); err != nil {
+		t.Fatalf("failed to build snapshots: %v", err)
+	}
+	var (
+		chain   = &canonicalReader{db: db}
+		sealers = []common.Address{{0x01}, {0x02}, {0x03}, {0x04}, {0x02}, {0x04}, {0x02}}
+	)
+	for i, sealer := range sealers {
+		writeSealer(db, chain.GetHeaderByNumber(uint64(i+1)), sealer)
+	}
+	api := &API{chain: chain, clique: New(config, db)}
+
+	recents, err := api.GetRecents(nil)
+	if err != nil {
+		t.Fatalf("failed to get recents: %v", err)
+	}
+	want := &recentsWindow{
+		Number:  7,
+		Limit:   3,
+		Recents: []recentSeal{{Block: 6, Signer: common.Address{0x04}}, {Block: 7, Signer: common.Address{0x02}}},
+		Sealed:  []common.Address{{0x02}, {0x04}},
+		Window:  []recentSeal{{Block: 6, Signer: common.Address{0x04}}, {Block: 7, Signer: common.Address{0x02}}},
+	}
+	if !reflect.DeepEqual(recents, want) {
+		t.Fatalf("recents mismatch: have %+v, want %+v", recents, want)
+	}
+}
+
+// Tests that the performance of an epoch is scanned up to the block closing
+// it, that limited scans report the block to continue with, and that the scan
+// of a still running epoch ends at the head.
+func TestEpochPerformance(t *testing.T) {
+	var (
+		db         = rawdb.NewMemoryDatabase()
+		validators = []common.Address{{0x01}, {0x02}}
+		config     = &params.CliqueConfig{InitialValidators: validators}
+	)
+	NewDNR(config, db)
+	writeTestChain(db, 10, map[uint64][]common.Address{6: validators})
+	if _, err := RebuildSnapshots(db, config, nil); err != nil {
+		t.Fatalf("failed to build snapshots: %v", err)
+	}
+	var (
+		chain   = &canonicalReader{db: db}
+		sealers = []common.Address{{0x01}, {0x02}, {0x01}, {0x01}, {0x02}, {0x01}, {0x02}, {0x02}, {0x01}}
+	)
+	for i, sealer := range sealers {
+		writeSealer(db, chain.GetHeaderByNumber(uint64(i+1)), sealer)
+	}
+	api := &API{chain: chain, clique: New(config, db)}
+
+	ptr := func(n uint64) *uint64 { return &n }
+	tests := []struct {
+		name string
+		scan func() (*epochPerformance, error)
+		want *epochPerformance
+	}{
+		{
+			name: "closed epoch",
+			scan: func() (*epochPerformance, error) { return api.EpochPerformance(0, 0, nil) },
+			want: &epochPerformance{
+				SigningStatus: map[common.Address]int{{0x01}: 4, {0x02}: 2},
+				NumBlocks:     6, NextEpoch: 60, StartBlock: 1, EndBlock: 6,
+			},
+		},
+		{
+			name: "limited scan",
+			scan: func() (*epochPerformance, error) { return api.EpochPerformance(0, 0, ptr(2)) },
+			want: &epochPerformance{
+				SigningStatus: map[common.Address]int{{0x01}: 1, {0x02}: 1},
+				NumBlocks:     2, StartBlock: 1, EndBlock: 2, Next: ptr(3),
+			},
+		},
+		{
+			name: "continued scan",
+			scan: func() (*epochPerformance, error) { return api.EpochPerformanceRange(0, 0, 3, 10) },
+			want: &epochPerformance{
+				SigningStatus: map[common.Address]int{{0x01}: 3, {0x02}: 1},
+				NumBlocks:     4, NextEpoch: 60, StartBlock: 3, EndBlock: 6,
+			},
+		},
+		{
+			name: "unclosed epoch",
+			scan: func() (*epochPerformance, error) { return api.EpochPerformance(60, 6, nil) },
+			want: &epochPerformance{
+				SigningStatus: map[common.Address]int{{0x01}: 1, {0x02}: 2},
+				NumBlocks:     3, StartBlock: 7, EndBlock: 9,
+			},
+		},
+		{
+			name: "limited unclosed epoch",
+			scan: func() (*epochPerformance, error) { return api.EpochPerformance(60, 6, ptr(2)) },
+			want: &epochPerformance{
+				SigningStatus: map[common.Address]int{{0x01}: 0, {0x02}: 2},
+				NumBlocks:     2, StartBlock: 7, EndBlock: 8, Next: ptr(9),
+			},
+		},
+	}
+	for _, tt := range tests {
+		perf, err := tt.scan()
+		if err != nil {
+			t.Errorf("%s: scan failed: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(perf, tt.want) {
+			t.Errorf("%s: performance mismatch: have %+v, want %+v", tt.name, perf, tt.want)
+		}
+	}
+	// Ranges must start after the epoch block, which must belong to the epoch
+	var aerr *APIError
+	if _, err := api.EpochPerformanceRange(60, 6, 6, 10); !errors.As(err, &aerr) || aerr.ErrorCode() != CodeInvalidRange {
+		t.Errorf("range starting at the epoch block: have %v, want code %d", err, CodeInvalidRange)
+	}
+	if _, err := api.EpochPerformance(0, 6, nil); !errors.As(err, &aerr) || aerr.ErrorCode() != CodeEpochMismatch {
+		t.Errorf("mismatching epoch block: have %v, want code %d", err, CodeEpochMismatch)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// bridgeEpochType is the ABI tuple a bridge epoch is packed as:
+//
+//	(uint64 epoch, uint64 number, bytes32 hash, bytes header, bytes sealData,
+//	 bytes32 sealHash, bytes signature, address signer, address[] signers,
+//	 address[] validators)
+var bridgeEpochType, _ = abi.NewType("tuple", "", []abi.ArgumentMarshaling{
+	{Name: "epoch", Type: "uint64"},
+	{Name: "number", Type: "uint64"},
+	{Name: "hash", Type: "bytes32"},
+	{Name: "header", Type: "bytes"},
+	{Name: "sealData", Type: "bytes"},
+	{Name: "sealHash", Type: "bytes32"},
+	{Name: "signature", Type: "bytes"},
+	{Name: "signer", Type: "address"},
+	{Name: "signers", Type: "address[]"},
+	{Name: "validators", Type: "address[]"},
+})
+
+// BridgeEpoch is the data a light verifier on another chain needs to accept the
+// transition into an epoch: the epoch block proposing the new validator set and
+// its seal by one of the validators of the previous epoch. Chaining the epochs
+// from a trusted validator set lets the verifier follow the validator rotation
+// and, with the latest set, check the seals of arbitrary headers.
+//
+// A verifier checks that keccak256(Header) is Hash and keccak256(SealData) is
+// SealHash, recovers the signer of SealHash from Signature, and requires it to
+// be among Signers. The new validators are also embedded in the header's extra
+// data, following the 32 byte vanity.
+type BridgeEpoch struct {
+	Epoch      uint64           `json:"epoch"`
+	Number     uint64           `json:"number"` // Number of the epoch block
+	Hash       common.Hash      `json:"hash"`
+	Header     hexutil.Bytes    `json:"header"`     // RLP encoded epoch header
+	SealData   hexutil.Bytes    `json:"sealData"`   // RLP encoded epoch header without the seal
+	SealHash   common.Hash      `json:"sealHash"`   // Hash signed by the sealer
+	Signature  hexutil.Bytes    `json:"signature"`  // 65 byte [R || S || V] seal with V being 27 or 28
+	Signer     common.Address   `json:"signer"`     // Validator which sealed the epoch block
+	Signers    []common.Address `json:"signers"`    // Validators of the previous epoch, ascending
+	Validators []common.Address `json:"validators"` // Validators of the epoch, ascending
+}
+
+// Pack returns the ABI encoding of the bridge epoch, ready to be passed as a
+// tuple argument to a verifier contract.
+func (e *BridgeEpoch) Pack() ([]byte, error) {
+	// The ABI packer only accepts plain byte slices for dynamic bytes
+	return abi.Arguments{{Type: bridgeEpochType}}.Pack(struct {
+		Epoch      uint64
+		Number     uint64
+		Hash       common.Hash
+		Header     []byte
+		SealData   []byte
+		SealHash   common.Hash
+		Signature  []byte
+		Signer     common.Address
+		Signers    []common.Address
+		Validators []common.Address
+	}{e.Epoch, e.Number, e.Hash, e.Header, e.SealData, e.SealHash, e.Signature, e.Signer, e.Signers, e.Validators})
+}
+
+// ExportBridgeEpoch assembles the data a bridge contract needs to verify the
+// canonical transition into the given epoch.
+func (c *Clique) ExportBridgeEpoch(chain consensus.ChainHeaderReader, epoch uint64) (*BridgeEpoch, error) {
+	snap, err := c.canonicalEpochSnapshot(chain, epoch)
+	if err != nil {
+		return nil, err
+	}
+	if snap.PreviousSnapNumber == nil || snap.PreviousSnapHash == nil {
+		return nil, errGenesisEpoch(epoch)
+	}
+	prev, err := previousSnapshot(c.db, c.config, snap)
+	if err != nil {
+		return nil, err
+	}
+	header := chain.GetHeader(snap.Hash, snap.Number)
+	if header == nil {
+		return nil, errMissingBlock(snap.Number)
+	}
+	sigcache, _ := lru.NewARC(1)
+	signer, err := ecrecover(header, sigcache)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return nil, err
+	}
+	signature := common.CopyBytes(header.Extra[len(header.Extra)-extraSeal:])
+	signature[crypto.RecoveryIDOffset] += 27
+
+	return &BridgeEpoch{
+		Epoch:      epoch,
+		Number:     snap.Number,
+		Hash:       snap.Hash,
+		Header:     enc,
+		SealData:   CliqueRLP(header),
+		SealHash:   SealHash(header),
+		Signature:  signature,
+		Signer:     signer,
+		Signers:    prev.signers(),
+		Validators: snap.signers(),
+	}, nil
+}
+
+// bridgeEpochResult is a bridge epoch along with its ABI encoding.
+type bridgeEpochResult struct {
+	*BridgeEpoch
+	ABI hexutil.Bytes `json:"abi"`
+}
+
+// GetBridgeEpoch returns the data a bridge contract on another chain needs to
+// verify the transition into the given epoch, along with its ABI encoding.
+func (api *API) GetBridgeEpoch(epoch uint64) (*bridgeEpochResult, error) {
+	export, err := api.clique.ExportBridgeEpoch(api.chain, epoch)
+	if err != nil {
+		return nil, err
+	}
+	packed, err := export.Pack()
+	if err != nil {
+		return nil, err
+	}
+	return &bridgeEpochResult{BridgeEpoch: export, ABI: packed}, nil
+}
+
+// canonicalEpochSnapshot returns the snapshot created at the canonical block
+// of the given epoch. It starts from the snapshot the head of the chain builds
+// on, the one of the last epoch block, and walks the previous snapshot links
+// back to the epoch.
+func (c *Clique) canonicalEpochSnapshot(chain consensus.ChainHeaderReader, epoch uint64) (*Snapshot, error) {
+	head := chain.CurrentHeader()
+	if head == nil {
+		return nil, errors.New("head header missing")
+	}
+	snap, err := c.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Walk the snapshots back to the requested epoch
+	for snap.EpochNumber > epoch {
+		if snap.PreviousSnapNumber == nil || snap.PreviousSnapHash == nil {
+			return nil, errEpochNotFound(epoch)
+		}
+		if snap, err = previousSnapshot(c.db, c.config, snap); err != nil {
+			return nil, err
+		}
+	}
+	if snap.EpochNumber != epoch {
+		return nil, errEpochNotFound(epoch)
+	}
+	return snap, nil
+}
+
+// previousSnapshot loads the snapshot linked as the previous one of snap,
+// checking that it's the one snap was derived from.
+func previousSnapshot(db ethdb.Database, config *params.CliqueConfig, snap *Snapshot) (*Snapshot, error) {
+	number := *snap.PreviousSnapNumber
+	prev, err := loadSnapshot(config, nil, db, number)
+	if err != nil || prev.Hash != *snap.PreviousSnapHash {
+		return nil, errMissingSnapshot(number)
+	}
+	return prev, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestExportBridgeEpoch(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		signer = crypto.PubkeyToAddress(key.PublicKey)
+		next   = common.Address{0x02}
+		db     = rawdb.NewMemoryDatabase()
+		config = &params.CliqueConfig{InitialValidators: []common.Address{signer}}
+	)
+	NewDNR(config, db)
+
+	genesis := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1), Extra: make([]byte, extraVanity+extraSeal)}
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	extra := append(make([]byte, extraVanity), next[:]...)
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		Difficulty: diffInTurn,
+		Nonce:      types.EncodeNonce(7),
+		Extra:      append(extra, make([]byte, extraSeal)...),
+	}
+	sig, err := crypto.Sign(SealHash(header).Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to sign header: %v", err)
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+	rawdb.WriteHeader(db, header)
+	rawdb.WriteCanonicalHash(db, header.Hash(), 1)
+	rawdb.WriteHeadHeaderHash(db, header.Hash())
+
+	// Extend the chain past the epoch block, for the export to start from the head
+	parent := header
+	for i := int64(2); i <= 3; i++ {
+		child := &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(i), Difficulty: diffInTurn, Extra: make([]byte, extraVanity+extraSeal)}
+		rawdb.WriteHeader(db, child)
+		rawdb.WriteCanonicalHash(db, child.Hash(), uint64(i))
+		rawdb.WriteHeadHeaderHash(db, child.Hash())
+		parent = child
+	}
+	if _, err := RebuildSnapshots(db, config, nil); err != nil {
+		t.Fatalf("failed to build snapshots: %v", err)
+	}
+	var (
+		engine = New(config, db)
+		chain  = &canonicalReader{db: db}
+	)
+	export, err := engine.ExportBridgeEpoch(chain, 7)
+	if err != nil {
+		t.Fatalf("failed to export epoch: %v", err)
+	}
+	if export.Number != 1 || export.Hash != header.Hash() || export.Signer != signer {
+		t.Fatalf("epoch block mismatch: %+v", export)
+	}
+	if !reflect.DeepEqual(export.Signers, []common.Address{signer}) || !reflect.DeepEqual(export.Validators, []common.Address{next}) {
+		t.Fatalf("validator sets mismatch: signers %v, validators %v", export.Signers, export.Validators)
+	}
+	// Verify the export the way a bridge contract would
+	if crypto.Keccak256Hash(export.Header) != export.Hash || crypto.Keccak256Hash(export.SealData) != export.SealHash {
+		t.Fatalf("header encodings don't hash to the exported hashes")
+	}
+	sig = common.CopyBytes(export.Signature)
+	if sig[crypto.RecoveryIDOffset] != 27 && sig[crypto.RecoveryIDOffset] != 28 {
+		t.Fatalf("recovery id not in EVM form: %d", sig[crypto.RecoveryIDOffset])
+	}
+	sig[crypto.RecoveryIDOffset] -= 27
+	pub, err := crypto.SigToPub(export.SealHash[:], sig)
+	if err != nil || crypto.PubkeyToAddress(*pub) != signer {
+		t.Fatalf("seal doesn't recover to the signer: %v", err)
+	}
+	if _, err := export.Pack(); err != nil {
+		t.Fatalf("failed to abi encode export: %v", err)
+	}
+	if _, err := engine.ExportBridgeEpoch(chain, 0); err == nil {
+		t.Fatalf("genesis epoch exported")
+	}
+	if _, err := engine.ExportBridgeEpoch(chain, 5); err == nil {
+		t.Fatalf("unknown epoch exported")
+	}
+}
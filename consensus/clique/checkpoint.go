@@ -0,0 +1,177 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// checkpointPrefix + epoch (uint64 big endian) -> epoch checkpoint as JSON
+var checkpointPrefix = []byte("CliqueCheckpoint-")
+
+// EpochCheckpoint is the chain position recorded at an epoch block, which the
+// chain can be rolled back to for recovering from bad imports.
+type EpochCheckpoint struct {
+	Epoch    uint64      `json:"epoch"`
+	Number   uint64      `json:"number"`   // Epoch block the checkpoint was taken at
+	Hash     common.Hash `json:"hash"`     // Hash of the epoch block
+	Root     common.Hash `json:"root"`     // State root of the epoch block
+	Snapshot common.Hash `json:"snapshot"` // Consensus hash of the snapshot created at the epoch block
+	Time     time.Time   `json:"time"`     // Local time the checkpoint was taken
+}
+
+// checkpointKey = checkpointPrefix + epoch (uint64 big endian)
+func checkpointKey(epoch uint64) []byte {
+	key := make([]byte, len(checkpointPrefix)+8)
+	copy(key, checkpointPrefix)
+	binary.BigEndian.PutUint64(key[len(checkpointPrefix):], epoch)
+	return key
+}
+
+// writeEpochCheckpoint records the checkpoint of an epoch block along with the
+// snapshot it created.
+func writeEpochCheckpoint(db ethdb.KeyValueWriter, header *types.Header, snap *Snapshot) {
+	ref, err := snap.RLPHash()
+	if err != nil {
+		log.Warn("Failed to hash epoch snapshot", "number", header.Number, "err", err)
+		return
+	}
+	blob, err := json.Marshal(&EpochCheckpoint{
+		Epoch:    snap.EpochNumber,
+		Number:   header.Number.Uint64(),
+		Hash:     header.Hash(),
+		Root:     header.Root,
+		Snapshot: ref,
+		Time:     time.Now(),
+	})
+	if err != nil {
+		log.Warn("Failed to encode epoch checkpoint", "number", header.Number, "err", err)
+		return
+	}
+	if err := db.Put(checkpointKey(snap.EpochNumber), blob); err != nil {
+		log.Warn("Failed to store epoch checkpoint", "number", header.Number, "err", err)
+	}
+}
+
+// ReadEpochCheckpoint retrieves the checkpoint recorded at the block opening the
+// given epoch, nil if there's none.
+func ReadEpochCheckpoint(db ethdb.KeyValueReader, epoch uint64) *EpochCheckpoint {
+	blob, err := db.Get(checkpointKey(epoch))
+	if err != nil {
+		return nil
+	}
+	checkpoint := new(EpochCheckpoint)
+	if err := json.Unmarshal(blob, checkpoint); err != nil {
+		log.Warn("Undecodable epoch checkpoint", "epoch", epoch, "err", err)
+		return nil
+	}
+	return checkpoint
+}
+
+// ReadEpochCheckpoints retrieves all the recorded checkpoints in ascending epoch
+// order.
+func ReadEpochCheckpoints(db ethdb.Iteratee) []*EpochCheckpoint {
+	it := db.NewIterator(checkpointPrefix, nil)
+	defer it.Release()
+
+	var checkpoints []*EpochCheckpoint
+	for it.Next() {
+		checkpoint := new(EpochCheckpoint)
+		if err := json.Unmarshal(it.Value(), checkpoint); err != nil {
+			continue
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints
+}
+
+// LastEpochBlock returns the number of the epoch block whose snapshot the head
+// of the chain builds on, the last one persisted.
+func (c *Clique) LastEpochBlock(chain consensus.ChainHeaderReader) (uint64, error) {
+	head := chain.CurrentHeader()
+	snap, err := c.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return 0, err
+	}
+	return snap.Number, nil
+}
+
+// CheckRollback checks whether the chain can be rolled back to the checkpoint of
+// an epoch: the checkpoint must be of a canonical epoch block below the head and
+// the snapshot created there must still be the one recorded.
+func CheckRollback(db ethdb.Database, config *params.CliqueConfig, epoch uint64) (*EpochCheckpoint, error) {
+	checkpoint := ReadEpochCheckpoint(db, epoch)
+	if checkpoint == nil {
+		return nil, fmt.Errorf("no checkpoint for epoch %d", epoch)
+	}
+	if rawdb.ReadCanonicalHash(db, checkpoint.Number) != checkpoint.Hash {
+		return nil, fmt.Errorf("checkpoint block #%d of epoch %d is not canonical", checkpoint.Number, epoch)
+	}
+	head := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadHeaderHash(db))
+	if head == nil {
+		return nil, errors.New("head header missing")
+	}
+	if checkpoint.Number >= *head {
+		return nil, fmt.Errorf("checkpoint block #%d of epoch %d not below head #%d", checkpoint.Number, epoch, *head)
+	}
+	snap, err := loadSnapshot(config, nil, db, checkpoint.Number)
+	if err != nil {
+		return nil, errMissingSnapshot(checkpoint.Number)
+	}
+	if ref, err := snap.RLPHash(); err != nil || ref != checkpoint.Snapshot || snap.Hash != checkpoint.Hash {
+		return nil, fmt.Errorf("snapshot of epoch block #%d differs from the checkpoint", checkpoint.Number)
+	}
+	return checkpoint, nil
+}
+
+// RewindCheckpoints deletes the snapshots and checkpoints of the epoch blocks
+// above the given block after the chain was rolled back to it, so they aren't
+// mistaken for the ones of the blocks imported next. It returns the number of
+// entries deleted.
+func RewindCheckpoints(db ethdb.Database, config *params.CliqueConfig, number uint64) (int, error) {
+	snaps, err := loadSnapshots(db, config)
+	if err != nil {
+		return 0, err
+	}
+	batch := db.NewBatch()
+	deleted := 0
+	for n := range snaps {
+		if n > number {
+			batch.Delete(snapshotKey(n))
+			deleted++
+		}
+	}
+	for _, checkpoint := range ReadEpochCheckpoints(db) {
+		if checkpoint.Number > number {
+			batch.Delete(checkpointKey(checkpoint.Epoch))
+			deleted++
+		}
+	}
+	return deleted, batch.Write()
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that checkpoints are recorded at the epoch blocks, only rolled back to
+// while consistent, and rewound along with the chain.
+func TestEpochCheckpoints(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		signer = crypto.PubkeyToAddress(key.PublicKey)
+		db     = rawdb.NewMemoryDatabase()
+		config = &params.CliqueConfig{InitialValidators: []common.Address{signer}}
+	)
+	NewDNR(config, db)
+
+	genesis := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1), Extra: make([]byte, extraVanity+extraSeal)}
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	// Seal a chain opening epoch 7 at block 2 and epoch 8 at block 4
+	parent := genesis
+	for i := int64(1); i <= 5; i++ {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(i),
+			Difficulty: diffInTurn,
+			Root:       common.Hash{byte(i)},
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		if i == 2 || i == 4 {
+			header.Nonce = types.EncodeNonce(uint64(6 + i/2))
+			header.Extra = append(append(make([]byte, extraVanity), signer[:]...), make([]byte, extraSeal)...)
+		}
+		sig, err := crypto.Sign(SealHash(header).Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to sign header: %v", err)
+		}
+		copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), uint64(i))
+		rawdb.WriteHeadHeaderHash(db, header.Hash())
+		parent = header
+	}
+	if _, err := RebuildSnapshots(db, config, nil); err != nil {
+		t.Fatalf("failed to build snapshots: %v", err)
+	}
+	if checkpoints := ReadEpochCheckpoints(db); len(checkpoints) != 2 || checkpoints[0].Epoch != 7 || checkpoints[1].Epoch != 8 {
+		t.Fatalf("checkpoints mismatch: %v", checkpoints)
+	}
+	checkpoint, err := CheckRollback(db, config, 7)
+	if err != nil {
+		t.Fatalf("rollback to epoch 7 rejected: %v", err)
+	}
+	if checkpoint.Number != 2 || checkpoint.Hash != rawdb.ReadCanonicalHash(db, 2) || checkpoint.Root != (common.Hash{2}) {
+		t.Errorf("checkpoint mismatch: %+v", checkpoint)
+	}
+	if _, err := CheckRollback(db, config, 9); err == nil {
+		t.Errorf("rollback to unknown epoch accepted")
+	}
+	// A snapshot changed after the checkpoint must block the rollback
+	snap, err := loadSnapshot(config, nil, db, 4)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	snap.Signers = map[common.Address]bool{{0x01}: true}
+	if err := snap.store(db); err != nil {
+		t.Fatalf("failed to store snapshot: %v", err)
+	}
+	if _, err := CheckRollback(db, config, 8); err == nil {
+		t.Errorf("rollback to changed snapshot accepted")
+	}
+	// Rewinding drops the later epochs
+	deleted, err := RewindCheckpoints(db, config, 2)
+	if err != nil {
+		t.Fatalf("failed to rewind checkpoints: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted entries mismatch: have %d, want 2", deleted)
+	}
+	if ReadEpochCheckpoint(db, 8) != nil {
+		t.Errorf("checkpoint of epoch 8 retained")
+	}
+	if _, err := loadSnapshot(config, nil, db, 4); err == nil {
+		t.Errorf("snapshot of block 4 retained")
+	}
+	if ReadEpochCheckpoint(db, 7) == nil {
+		t.Errorf("checkpoint of epoch 7 deleted")
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"math/big"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
@@ -49,6 +50,7 @@ import (
 const (
 	inmemorySnapshots  = 128  // Number of recent vote snapshots to keep in memory
 	inmemorySignatures = 4096 // Number of recent block signatures to keep in memory
+	inmemoryDigests    = 128  // Number of epoch snapshot digests to keep in memory
 
 	wiggleTime = 500 * time.Millisecond // Random delay (per signer) to allow concurrent signers
 )
@@ -157,11 +159,43 @@ type Clique struct {
 	dnr        *DNR                 // dnr watcher
 	recents    *lru.ARCCache        // Snapshots for recent block to speed up reorgs
 	signatures *lru.ARCCache        // Signatures of recent blocks to speed up mining
+	keys       *signerKeys          // Public keys of the recent signers to speed up scanning the sealers
+	digests    *lru.ARCCache        // Digests of the epoch snapshots to speed up cross-checks
 
 	signer common.Address // Ethereum address of the signing key
 	signFn SignerFn       // Signer function to authorize hashes with
 	lock   sync.RWMutex   // Protects the signer and proposals fields
 
+	timeOffset time.Duration // Shift of the sealing clock on developer chains, protected by lock
+	instant    int32         // Number of in-flight instant seals (atomic, developer chains only)
+
+	wiggleLatency LatencyFn     // Observed propagation latency to adapt the wiggle to, protected by lock
+	wiggleMin     time.Duration // Lower bound of the adaptive wiggle per signer, protected by lock
+	wiggleMax     time.Duration // Upper bound of the adaptive wiggle per signer, protected by lock
+
+	timestamps        TimestampPolicy       // Tolerance for timestamps ahead of the local clock, protected by lock
+	fastVerify        *FastVerifyCheckpoint // Checkpoint below which the initial sync verifies seals with a shortcut, protected by lock
+	fastVerifySyncing func() bool           // Whether the initial sync is running, protected by lock
+	skews             *skewTracker          // Clock skews of the signers observed from their in-turn blocks
+	clock             *clockChecker         // Local clock check against NTP servers, nil if disabled, protected by lock
+
+	maintPending uint64                    // Blocks to skip announced in the next sealed block, protected by lock
+	maintUntil   uint64                    // Last block of the local signer's maintenance, protected by lock
+	maintenance  map[common.Address]uint64 // Last blocks of the maintenances announced by the signers
+	maintLock    sync.Mutex                // Protects the announced maintenances
+
+	sealingLog []*SealingPause  // Sealing pauses requested by the operator, the last one ongoing if unended, protected by lock
+	preflight  *PreflightStatus // Startup checks of the validator sealing is held back by, nil if not gated, protected by lock
+
+	votes *voteTracker // Latency of the signer changes applied by epoch blocks
+
+	sealStopped bool           // Whether sealing was stopped for shutdown, protected by lock
+	sealing     sync.WaitGroup // Blocks signed and not yet delivered or abandoned
+
+	indexerOnce sync.Once     // Ensures the sealer index backfill is only started once
+	closeCh     chan struct{} // Channel to signal the background jobs to terminate
+	closeOnce   sync.Once     // Ensures the close channel is only closed once
+
 	// The fields below are for testing only
 	fakeDiff bool // Skip difficulty verifications
 }
@@ -174,17 +208,32 @@ func New(config *params.CliqueConfig, db ethdb.Database) *Clique {
 	// Allocate the snapshot caches and create the engine
 	recents, _ := lru.NewARC(inmemorySnapshots)
 	signatures, _ := lru.NewARC(inmemorySignatures)
+	digests, _ := lru.NewARC(inmemoryDigests)
 
 	dnrInstance := NewDNR(&conf, db)
-	// start watch to monitor events
-	go dnrInstance.Watch(context.Background(), db)
+	if conf.API == "" {
+		// Without a registry endpoint (e.g. developer chains) the validator
+		// set stays the initial one until explicitly advanced.
+		log.Warn("No darknode registry endpoint configured, using static validator set")
+		dnrInstance.setSynced()
+	} else {
+		// start watch to monitor events
+		go dnrInstance.Watch(context.Background(), db)
+	}
 
 	return &Clique{
-		config:     &conf,
-		db:         db,
-		dnr:        dnrInstance,
-		recents:    recents,
-		signatures: signatures,
+		config:      &conf,
+		db:          db,
+		dnr:         dnrInstance,
+		recents:     recents,
+		signatures:  signatures,
+		digests:     digests,
+		keys:        new(signerKeys),
+		maintenance: make(map[common.Address]uint64),
+		votes:       newVoteTracker(),
+		skews:       newSkewTracker(),
+		sealingLog:  loadSealingLog(db),
+		closeCh:     make(chan struct{}),
 	}
 }
 
@@ -194,6 +243,28 @@ func (c *Clique) Author(header *types.Header) (common.Address, error) {
 	return ecrecover(header, c.signatures)
 }
 
+// EpochSigners returns the epoch number and the signer set announced by an epoch
+// block, or false if the header doesn't start a new epoch.
+func EpochSigners(header *types.Header) (uint64, []common.Address, bool) {
+	if bytes.Equal(header.Nonce[:], nonceDropVote) {
+		return 0, nil, false
+	}
+	list, _, err := splitEpochExtra(header)
+	if err != nil {
+		return 0, nil, false
+	}
+	signers := make([]common.Address, len(list)/common.AddressLength)
+	for i := range signers {
+		copy(signers[i][:], list[i*common.AddressLength:])
+	}
+	return header.Nonce.Uint64(), signers, true
+}
+
+// InTurn returns whether a header was sealed by the in-turn signer.
+func InTurn(header *types.Header) bool {
+	return header.Difficulty != nil && header.Difficulty.Cmp(diffInTurn) == 0
+}
+
 // VerifyHeader checks whether a header conforms to the consensus rules.
 func (c *Clique) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
 	c.dnr.WaitSynced()
@@ -227,14 +298,21 @@ func (c *Clique) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*typ
 // looking those up from the database. This is useful for concurrently verifying
 // a batch of new headers.
 func (c *Clique) verifyHeader(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header) error {
+	return c.checkHeader(chain, header, parents, true)
+}
+
+// checkHeader checks whether a header conforms to the consensus rules. If commit
+// is set, the sealer and the epoch snapshot of a valid header are recorded,
+// otherwise the header is only checked for whether it would be accepted.
+func (c *Clique) checkHeader(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header, commit bool) error {
 	if header.Number == nil {
 		return errUnknownBlock
 	}
 	number := header.Number.Uint64()
 
 	// Don't waste time checking blocks from the future
-	if header.Time > uint64(time.Now().Unix()) {
-		return consensus.ErrFutureBlock
+	if err := c.checkFutureTimestamp(header); err != nil {
+		return err
 	}
 	// epoch is called through nonce=epoch block no.
 	epoch := !bytes.Equal(header.Nonce[:], nonceDropVote)
@@ -250,8 +328,18 @@ func (c *Clique) verifyHeader(chain consensus.ChainHeaderReader, header *types.H
 	if !epoch && signersBytes != 0 {
 		return errExtraSigners
 	}
-	if epoch && signersBytes%common.AddressLength != 0 {
-		return errInvalidCheckpointSigners
+	if epoch {
+		// Past the fork, epoch blocks commit to the snapshot they produce
+		_, commit, err := splitEpochExtra(header)
+		if err != nil {
+			return err
+		}
+		switch committing := c.config.IsSnapshotCommit(header.Number); {
+		case committing && commit == nil:
+			return errMissingSnapshotCommit
+		case !committing && commit != nil:
+			return errInvalidCheckpointSigners
+		}
 	}
 	// Ensure that the mix digest is zero as we don't have fork protection currently
 	if header.MixDigest != (common.Hash{}) {
@@ -276,14 +364,15 @@ func (c *Clique) verifyHeader(chain consensus.ChainHeaderReader, header *types.H
 		return err
 	}
 	// All basic checks passed, verify cascading fields
-	return c.verifyCascadingFields(chain, header, parents, epoch)
+	return c.verifyCascadingFields(chain, header, parents, epoch, commit)
 }
 
 // verifyCascadingFields verifies all the header fields that are not standalone,
 // rather depend on a batch of previous headers. The caller may optionally pass
 // in a batch of parents (ascending order) to avoid looking those up from the
 // database. This is useful for concurrently verifying a batch of new headers.
-func (c *Clique) verifyCascadingFields(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header, epoch bool) error {
+// The sealer and epoch snapshot of the header are only recorded if commit is set.
+func (c *Clique) verifyCascadingFields(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header, epoch bool, commit bool) error {
 	// The genesis block is the always valid dead-end
 	number := header.Number.Uint64()
 	if number == 0 {
@@ -300,7 +389,8 @@ func (c *Clique) verifyCascadingFields(chain consensus.ChainHeaderReader, header
 		return consensus.ErrUnknownAncestor
 	}
 	if parent.Time+c.config.Period > header.Time {
-		return errInvalidTimestamp
+		signer, _ := ecrecover(header, c.signatures)
+		return fmt.Errorf("%w: block %d sealed by %s at %d, parent at %d, period %d", errInvalidTimestamp, number, signer, header.Time, parent.Time, c.config.Period)
 	}
 	// Verify that the gasUsed is <= gasLimit
 	if header.GasUsed > header.GasLimit {
@@ -332,20 +422,64 @@ func (c *Clique) verifyCascadingFields(chain consensus.ChainHeaderReader, header
 	)
 	if epoch {
 		epochNum = header.Nonce.Uint64()
-		extraSuffix := len(header.Extra) - extraSeal
-		ok, validators = snap.validEpoch(epochNum, header.Extra[extraVanity:extraSuffix], c.db)
+		list, _, err := splitEpochExtra(header)
+		if err != nil {
+			return err
+		}
+		ok, validators = snap.validEpoch(epochNum, list, c.db)
 		if !ok {
 			return errMismatchingCheckpointSigners
 		}
+		if err := verifySnapshotCommit(header, epochNum, validators); err != nil {
+			return err
+		}
+		if err := checkSignerPolicy(c.config, snap, number, validators); err != nil {
+			return err
+		}
+		// Past the fork, the vanity commits to the closing epoch's performance
+		if c.config.IsPerformanceCommit(header.Number) {
+			root, err := c.epochPerformanceRoot(chain, snap, number-1, header.ParentHash, parents)
+			if err != nil {
+				return err
+			}
+			if common.BytesToHash(header.Extra[:extraVanity]) != root {
+				return errInvalidPerformanceRoot
+			}
+		}
+	}
+	// All basic checks passed, verify the seal and return. Ancestors of the fast
+	// verification checkpoint may take a shortcut during the initial sync.
+	if err := c.checkFastVerifyCheckpoint(header); err != nil {
+		return err
 	}
-	// All basic checks passed, verify the seal and return
-	if err = c.verifySeal(snap, header, parents); err != nil {
+	trusted := !epoch && c.trustedSeal(chain, header, parents)
+	if trusted {
+		if err := c.verifyTrustedSeal(snap, header); err != nil {
+			return err
+		}
+		fastVerifyMeter.Mark(1)
+	} else if err = c.verifySeal(snap, header, parents); err != nil {
 		return err
 	}
+	if !commit {
+		return nil
+	}
+	// The signer is cached by now, record it in the sealer index. Clock skews are
+	// only sampled off the live chain.
+	if signer, err := c.sealerOf(header); err == nil {
+		c.indexSealer(header, signer)
+		if !trusted && header.Difficulty.Cmp(diffInTurn) == 0 {
+			c.skews.observe(signer, number, header.Time, c.now())
+		}
+	}
+	c.trackVotes(header, snap, epoch, epochNum, validators)
 
 	if epoch {
 		snap.updateEpoch(header, epochNum, validators)
-		return snap.store(c.db)
+		if err := snap.store(c.db); err != nil {
+			return err
+		}
+		writeEpochCheckpoint(c.db, header, snap)
 	}
 
 	return nil
@@ -400,7 +534,19 @@ func (c *Clique) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 				if err != nil {
 					return nil, fmt.Errorf("failed to get dnr from db, error=%w", err)
 				}
+				// Validate the registry state against the header's commitment, if any
+				if number > 0 {
+					if err := verifySnapshotCommit(checkpoint, dnrInstance.LastEpochBlock, dnrInstance.Validators); err != nil {
+						return nil, fmt.Errorf("trusted checkpoint #%d: %w", number, err)
+					}
+				}
 				snap = newSnapshot(c.config, c.signatures, number, dnrInstance.LastEpochBlock, nil, hash, nil, dnrInstance.Validators)
+				if last, ok := c.deriveLastAuthorization(chain, checkpoint); ok {
+					snap.LastAuthorization = last
+				} else {
+					snap.AuthorizationsFrom = number
+					log.Warn("Signer policy cooldown unknown at trusted checkpoint", "number", number)
+				}
 				if err := snap.store(c.db); err != nil {
 					return nil, err
 				}
@@ -458,13 +604,8 @@ func (c *Clique) verifySeal(snap *Snapshot, header *types.Header, parents []*typ
 	if _, ok := snap.Signers[signer]; !ok {
 		return errUnauthorizedSigner
 	}
-	for seen, recent := range snap.Recents {
-		if recent == signer {
-			// Signer is among recents, only fail if the current block doesn't shift it out
-			if limit := uint64(len(snap.Signers)/2 + 1); seen > number-limit {
-				return errRecentlySigned
-			}
-		}
+	if snap.recentlySigned(number, signer) {
+		return errRecentlySigned
 	}
 	// Ensure that the difficulty corresponds to the turn-ness of the signer
 	log.Info("turn_check", "block", header.Number.Uint64(), "signers", snap.signers(), "block-signer", signer, "difficulty", header.Difficulty.Uint64())
@@ -477,6 +618,9 @@ func (c *Clique) verifySeal(snap *Snapshot, header *types.Header, parents []*typ
 			return errWrongDifficulty
 		}
 	}
+	if until, ok := maintenanceHint(header); ok {
+		c.noteMaintenance(number, until, signer)
+	}
 	return nil
 }
 
@@ -484,9 +628,6 @@ func (c *Clique) verifySeal(snap *Snapshot, header *types.Header, parents []*typ
 // header for running the transactions on top.
 func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
 	c.dnr.WaitSynced()
-	// If the block isn't a checkpoint, cast a random vote (good enough for now)
-	header.Coinbase = common.Address{}
-	header.Nonce = types.BlockNonce{}
 
 	number := header.Number.Uint64()
 	// Assemble the voting snapshot to check which votes make sense
@@ -494,13 +635,39 @@ func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 	if err != nil {
 		return err
 	}
-
-	// Set the correct difficulty
 	c.lock.RLock()
 	// Copy signer protected by mutex to avoid race condition
 	signer := c.signer
 	c.lock.RUnlock()
-	header.Difficulty = calcDifficulty(snap, number, signer)
+
+	if err := c.prepareFields(chain, header, snap, signer); err != nil {
+		return err
+	}
+	c.prepareMaintenance(header)
+	// Ensure the timestamp has the correct delay
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	header.Time = parent.Time + c.config.Period
+	if now := uint64(c.now().Unix()); header.Time < now {
+		header.Time = now
+	}
+	return nil
+}
+
+// prepareFields sets the coinbase, nonce, difficulty, extra-data and mix digest
+// of a header to be sealed by the given signer on top of the snapshot of its
+// parent, proposing the latest registry epoch if the snapshot lags behind.
+func (c *Clique) prepareFields(chain consensus.ChainHeaderReader, header *types.Header, snap *Snapshot, signer common.Address) error {
+	// If the block isn't a checkpoint, cast a random vote (good enough for now)
+	header.Coinbase = common.Address{}
+	header.Nonce = types.BlockNonce{}
+
+	number := header.Number.Uint64()
+
+	// Set the correct difficulty
+	header.Difficulty = calcDifficulty(snap, number, candidateSigner(snap, number, signer))
 
 	// Ensure the extra data has all its components
 	if len(header.Extra) < extraVanity {
@@ -513,12 +680,33 @@ func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 		return err
 	}
 
-	if snap.EpochNumber < dnrInstance.LastEpochBlock {
+	// Withhold registry epochs violating the signer policy, they'd be rejected
+	proposable := snap.EpochNumber < dnrInstance.LastEpochBlock
+	if proposable {
+		if err := checkSignerPolicy(c.config, snap, number, dnrInstance.Validators); err != nil {
+			log.Warn("Withholding epoch violating signer policy", "last_epoch", snap.EpochNumber, "dnr_epoch", dnrInstance.LastEpochBlock, "err", err)
+			proposable = false
+		}
+	}
+	if proposable {
 		log.Info("proposing epoch...", "last_epoch", snap.EpochNumber, "dnr_epoch", dnrInstance.LastEpochBlock)
 		for signer := range dnrInstance.Validators {
 			header.Extra = append(header.Extra, signer[:]...)
 		}
 		header.Nonce = types.EncodeNonce(dnrInstance.LastEpochBlock)
+
+		if c.config.IsSnapshotCommit(header.Number) {
+			commit := snapshotCommitment(dnrInstance.LastEpochBlock, dnrInstance.Validators)
+			header.Extra = append(header.Extra, commit[:]...)
+		}
+
+		if c.config.IsPerformanceCommit(header.Number) {
+			root, err := c.epochPerformanceRoot(chain, snap, number-1, header.ParentHash, nil)
+			if err != nil {
+				return err
+			}
+			header.Extra = append(root.Bytes(), header.Extra[extraVanity:]...)
+		}
 	} else {
 		log.Info("no epoch...", "last_epoch", snap.EpochNumber, "dnr_epoch", dnrInstance.LastEpochBlock)
 	}
@@ -526,16 +714,6 @@ func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 
 	// Mix digest is reserved for now, set to empty
 	header.MixDigest = common.Hash{}
-
-	// Ensure the timestamp has the correct delay
-	parent := chain.GetHeader(header.ParentHash, number-1)
-	if parent == nil {
-		return consensus.ErrUnknownAncestor
-	}
-	header.Time = parent.Time + c.config.Period
-	if header.Time < uint64(time.Now().Unix()) {
-		header.Time = uint64(time.Now().Unix())
-	}
 	return nil
 }
 
@@ -567,6 +745,53 @@ func (c *Clique) Authorize(signer common.Address, signFn SignerFn) {
 	c.signFn = signFn
 }
 
+// Signer returns the address the engine is authorized to seal blocks with, or
+// the zero address if it isn't.
+func (c *Clique) Signer() common.Address {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.signer
+}
+
+// IsSigner reports whether an address is authorized to seal blocks on top of
+// the head of the chain.
+func (c *Clique) IsSigner(chain consensus.ChainHeaderReader, signer common.Address) (bool, error) {
+	head := chain.CurrentHeader()
+	snap, err := c.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return false, err
+	}
+	_, ok := snap.Signers[signer]
+	return ok, nil
+}
+
+// Signers returns the signers authorized to seal blocks on top of the head of
+// the chain, in ascending order.
+func (c *Clique) Signers(chain consensus.ChainHeaderReader) ([]common.Address, error) {
+	head := chain.CurrentHeader()
+	snap, err := c.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return snap.signers(), nil
+}
+
+// ExpectedSealer returns the signer in-turn to seal the given header on top of
+// its parent, and whether that's the locally authorized signer.
+func (c *Clique) ExpectedSealer(chain consensus.ChainHeaderReader, header *types.Header) (common.Address, bool, error) {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return common.Address{}, false, errUnknownBlock
+	}
+	snap, err := c.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return common.Address{}, false, err
+	}
+	sealer := snap.inturnSigner(number)
+	return sealer, sealer != (common.Address{}) && sealer == c.Signer(), nil
+}
+
 // Seal implements consensus.Engine, attempting to create a sealed block using
 // the local signing credentials.
 func (c *Clique) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
@@ -580,14 +805,28 @@ func (c *Clique) Seal(chain consensus.ChainHeaderReader, block *types.Block, res
 		return errUnknownBlock
 	}
 	// For 0-period chains, refuse to seal empty blocks (no reward but would spin sealing)
-	if c.config.Period == 0 && len(block.Transactions()) == 0 {
+	instant := atomic.LoadInt32(&c.instant) > 0
+	if c.config.Period == 0 && len(block.Transactions()) == 0 && !instant {
 		return errors.New("sealing paused while waiting for transactions")
 	}
 	// Don't hold the signer fields for the entire sealing procedure
 	c.lock.RLock()
-	signer, signFn := c.signer, c.signFn
+	signer, signFn, maintUntil := c.signer, c.signFn, c.maintUntil
 	c.lock.RUnlock()
 
+	if number <= maintUntil {
+		return errInMaintenance
+	}
+	if err := c.sealingPaused(number); err != nil {
+		return err
+	}
+	if err := c.clockSealable(); err != nil {
+		return err
+	}
+	if err := c.preflightSealable(); err != nil {
+		return err
+	}
+
 	// Bail out if we're unauthorized to sign a block
 	snap, err := c.snapshot(chain, number-1, header.ParentHash, nil)
 	if err != nil {
@@ -606,23 +845,32 @@ func (c *Clique) Seal(chain consensus.ChainHeaderReader, block *types.Block, res
 		}
 	}
 	// Sweet, the protocol permits us to sign the block, wait for our time
-	delay := time.Unix(int64(header.Time), 0).Sub(time.Now()) // nolint: gosimple
-	if header.Difficulty.Cmp(diffNoTurn) == 0 {
+	delay := time.Unix(int64(header.Time), 0).Sub(c.now())
+	if instant {
+		delay = 0
+	} else if header.Difficulty.Cmp(diffNoTurn) == 0 {
 		// It's not our turn explicitly to sign, delay it a bit
-		wiggle := time.Duration(len(snap.Signers)/2+1) * wiggleTime
+		wiggle := c.outOfTurnWiggle(snap, number)
 		delay += time.Duration(rand.Int63n(int64(wiggle)))
 
 		log.Trace("Out-of-turn signing requested", "wiggle", common.PrettyDuration(wiggle))
 	}
 	// Sign all the things!
+	if !c.beginSeal() {
+		return errSealingStopped
+	}
 	sighash, err := signFn(accounts.Account{Address: signer}, accounts.MimetypeClique, CliqueRLP(header))
 	if err != nil {
+		c.sealing.Done()
 		return err
 	}
 	copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
+	writeLastSealed(c.db, header, signer)
 	// Wait until sealing is terminated or delay timeout.
 	log.Trace("Waiting for slot to sign and propagate", "delay", common.PrettyDuration(delay))
 	go func() {
+		defer c.sealing.Done()
+
 		select {
 		case <-stop:
 			return
@@ -631,17 +879,23 @@ func (c *Clique) Seal(chain consensus.ChainHeaderReader, block *types.Block, res
 
 		select {
 		case results <- block.WithSeal(header):
-			if !bytes.Equal(header.Nonce[:], nonceDropVote) {
+			c.indexSealer(header, signer)
+			c.sealedMaintenance(header, signer)
+			if bytes.Equal(header.Nonce[:], nonceDropVote) {
+				c.trackVotes(header, snap, false, 0, nil)
+			} else {
 				dnrInstance, err := GetDNR(c.db, header.Nonce.Uint64())
 				if err = snap.store(c.db); err != nil {
 					log.Warn("failed to get dnr from db (sealing)", "error", err)
 					return
 				}
+				c.trackVotes(header, snap, true, dnrInstance.LastEpochBlock, dnrInstance.Validators)
 				snap.updateEpoch(header, dnrInstance.LastEpochBlock, dnrInstance.Validators)
 				if err = snap.store(c.db); err != nil {
 					log.Warn("failed to update snapshot", "error", err)
 					return
 				}
+				writeEpochCheckpoint(c.db, header, snap)
 				log.Info("epoch stored", "header_no,", header.Number.Uint64(), "hash", header.Hash())
 			}
 		default:
@@ -665,7 +919,19 @@ func (c *Clique) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64,
 	signer := c.signer
 	c.lock.RUnlock()
 
-	return calcDifficulty(snap, parent.Number.Uint64()+1, signer)
+	number := parent.Number.Uint64() + 1
+	return calcDifficulty(snap, number, candidateSigner(snap, number, signer))
+}
+
+// candidateSigner returns the signer a block is assembled for: the local one if
+// it's authorized, the in-turn one otherwise. Nodes unable to seal thus assemble
+// the candidate the network expects next instead of an out-of-turn block nobody
+// would produce.
+func candidateSigner(snap *Snapshot, number uint64, signer common.Address) common.Address {
+	if _, ok := snap.Signers[signer]; !ok {
+		return snap.inturnSigner(number)
+	}
+	return signer
 }
 
 func calcDifficulty(snap *Snapshot, currentBlockNumber uint64, signer common.Address) *big.Int {
@@ -680,8 +946,12 @@ func (c *Clique) SealHash(header *types.Header) common.Hash {
 	return SealHash(header)
 }
 
-// Close implements consensus.Engine. It's a noop for clique as there are no background threads.
+// Close implements consensus.Engine, terminating the background sealer index
+// backfill if running.
 func (c *Clique) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+	})
 	return nil
 }
 
@@ -693,6 +963,11 @@ func (c *Clique) APIs(chain consensus.ChainHeaderReader) []rpc.API {
 		Version:   "1.0",
 		Service:   &API{chain: chain, clique: c},
 		Public:    false,
+	}, {
+		Namespace: "clique",
+		Version:   "1.0",
+		Service:   &PauseAPI{chain: chain, clique: c},
+		Public:    false,
 	}}
 }
 
@@ -0,0 +1,290 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	ntpMeasurements = 3               // Number of measurements done against every NTP server per check
+	ntpTimeout      = 5 * time.Second // Time allowance for an NTP server to answer a measurement
+)
+
+// errClockDrift is returned when sealing a block while the local clock drifted
+// beyond the configured limit and the clock check is enforced.
+var errClockDrift = errors.New("local clock drifted beyond limit, refusing to seal")
+
+var clockDriftGauge = metrics.NewRegisteredGauge("clique/clock/drift", nil) // Local clock drift from the NTP servers in milliseconds
+
+// ClockConfig are the configuration parameters of the local clock check against
+// NTP servers.
+type ClockConfig struct {
+	Servers  []string      `toml:",omitempty"` // NTP servers (host[:port]) the local clock is checked against, none disables the check
+	Interval time.Duration // Interval between two clock checks
+	MaxDrift time.Duration // Drift from the NTP servers beyond which the local clock is unhealthy
+	Enforce  bool          `toml:",omitempty"` // Whether to refuse sealing while the local clock is unhealthy
+}
+
+// DefaultClockConfig contains the default settings for the local clock check.
+var DefaultClockConfig = ClockConfig{
+	Servers:  []string{"pool.ntp.org", "time.google.com", "time.cloudflare.com"},
+	Interval: 10 * time.Minute,
+	MaxDrift: time.Second,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *ClockConfig) sanitize() ClockConfig {
+	conf := *config
+	if conf.Interval <= 0 {
+		log.Warn("Sanitizing invalid clock check interval", "provided", conf.Interval, "updated", DefaultClockConfig.Interval)
+		conf.Interval = DefaultClockConfig.Interval
+	}
+	if conf.MaxDrift <= 0 {
+		log.Warn("Sanitizing invalid clock drift limit", "provided", conf.MaxDrift, "updated", DefaultClockConfig.MaxDrift)
+		conf.MaxDrift = DefaultClockConfig.MaxDrift
+	}
+	return conf
+}
+
+// NTPServerStatus is the outcome of the last measurement against an NTP server.
+type NTPServerStatus struct {
+	Server string   `json:"server"`
+	Drift  *float64 `json:"drift,omitempty"` // Seconds the local clock is ahead of the server, negative if behind
+	Error  string   `json:"error,omitempty"`
+}
+
+// ClockStatus is the outcome of the last check of the local clock.
+type ClockStatus struct {
+	Checked  time.Time          `json:"checked"`
+	Drift    *float64           `json:"drift,omitempty"` // Median seconds the local clock is ahead of the servers, unset if none answered
+	MaxDrift float64            `json:"maxDrift"`        // Seconds of drift beyond which the clock is unhealthy
+	Healthy  bool               `json:"healthy"`         // Whether the drift is within the limit, or unknown
+	Enforced bool               `json:"enforced"`        // Whether sealing is refused while the clock is unhealthy
+	Servers  []*NTPServerStatus `json:"servers"`
+}
+
+// clockChecker periodically compares the local clock against NTP servers.
+type clockChecker struct {
+	config ClockConfig
+	query  func(server string) (time.Duration, error)
+
+	status *ClockStatus // Outcome of the last check, nil until the first one finished
+	lock   sync.RWMutex
+}
+
+// check measures the drift of the local clock against every server, taking the
+// median across the servers answering. The clock is only deemed unhealthy if a
+// drift could be measured, unreachable servers alone don't stop sealing.
+func (cc *clockChecker) check() *ClockStatus {
+	status := &ClockStatus{
+		Checked:  time.Now(),
+		MaxDrift: cc.config.MaxDrift.Seconds(),
+		Healthy:  true,
+		Enforced: cc.config.Enforce,
+	}
+	var drifts []time.Duration
+	for _, server := range cc.config.Servers {
+		result := &NTPServerStatus{Server: server}
+		if drift, err := cc.query(server); err != nil {
+			result.Error = err.Error()
+		} else {
+			seconds := drift.Seconds()
+			result.Drift = &seconds
+			drifts = append(drifts, drift)
+		}
+		status.Servers = append(status.Servers, result)
+	}
+	if len(drifts) == 0 {
+		log.Warn("Failed to check local clock, no NTP server answered", "servers", len(cc.config.Servers))
+	} else {
+		sort.Slice(drifts, func(i, j int) bool { return drifts[i] < drifts[j] })
+		drift := drifts[len(drifts)/2]
+
+		seconds := drift.Seconds()
+		status.Drift = &seconds
+		status.Healthy = drift <= cc.config.MaxDrift && drift >= -cc.config.MaxDrift
+		clockDriftGauge.Update(drift.Milliseconds())
+
+		if status.Healthy {
+			log.Debug("Checked local clock against NTP", "drift", common.PrettyDuration(drift), "servers", len(drifts))
+		} else {
+			log.Error("Local clock drifted from NTP servers", "drift", common.PrettyDuration(drift), "max", cc.config.MaxDrift, "enforced", cc.config.Enforce)
+			log.Error("Please enable network time synchronisation in system settings")
+		}
+	}
+	cc.lock.Lock()
+	cc.status = status
+	cc.lock.Unlock()
+
+	return status
+}
+
+// current returns the outcome of the last check, nil if none finished yet.
+func (cc *clockChecker) current() *ClockStatus {
+	cc.lock.RLock()
+	defer cc.lock.RUnlock()
+
+	return cc.status
+}
+
+// sealable returns an error if the clock check is enforced and the last check
+// found the local clock drifted beyond the limit.
+func (cc *clockChecker) sealable() error {
+	if !cc.config.Enforce {
+		return nil
+	}
+	if status := cc.current(); status != nil && !status.Healthy {
+		return fmt.Errorf("%w: drift %.3fs, max %v", errClockDrift, *status.Drift, cc.config.MaxDrift)
+	}
+	return nil
+}
+
+// StartClockCheck launches a background job checking the local clock against
+// the configured NTP servers at startup and periodically afterwards. If the
+// check is enforced, sealing is refused while the clock is drifted.
+func (c *Clique) StartClockCheck(config ClockConfig) {
+	if len(config.Servers) == 0 {
+		return
+	}
+	checker := &clockChecker{config: config.sanitize(), query: sntpQuery}
+
+	c.lock.Lock()
+	if c.clock != nil {
+		c.lock.Unlock()
+		return
+	}
+	c.clock = checker
+	c.lock.Unlock()
+
+	log.Info("Started local clock check", "servers", len(checker.config.Servers), "interval", checker.config.Interval, "maxdrift", checker.config.MaxDrift, "enforced", checker.config.Enforce)
+	go func() {
+		checker.check()
+
+		ticker := time.NewTicker(checker.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				checker.check()
+			case <-c.closeCh:
+				return
+			}
+		}
+	}()
+}
+
+// clockStatus returns the outcome of the last local clock check, nil if the
+// check is disabled or didn't finish yet.
+func (c *Clique) clockStatus() *ClockStatus {
+	c.lock.RLock()
+	checker := c.clock
+	c.lock.RUnlock()
+
+	if checker == nil {
+		return nil
+	}
+	return checker.current()
+}
+
+// ClockStatus returns the outcome of the last local clock check, along with
+// whether the check is enabled at all.
+func (c *Clique) ClockStatus() (*ClockStatus, bool) {
+	c.lock.RLock()
+	enabled := c.clock != nil
+	c.lock.RUnlock()
+
+	return c.clockStatus(), enabled
+}
+
+// clockSealable returns an error if sealing is refused due to the local clock.
+func (c *Clique) clockSealable() error {
+	c.lock.RLock()
+	checker := c.clock
+	c.lock.RUnlock()
+
+	if checker == nil {
+		return nil
+	}
+	return checker.sealable()
+}
+
+// sntpQuery measures the drift of the local clock against an NTP server using
+// the simple network time protocol (RFC 4330), taking the median of a few
+// measurements. The drift is positive if the local clock is ahead.
+func sntpQuery(server string) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+	addr, err := net.ResolveUDPAddr("udp", server)
+	if err != nil {
+		return 0, err
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	// Construct the time request (empty package with only 2 fields set):
+	//   Bits 3-5: Protocol version, 3
+	//   Bits 6-8: Mode of operation, client, 3
+	request := make([]byte, 48)
+	request[0] = 3<<3 | 3
+
+	drifts := make([]time.Duration, 0, ntpMeasurements)
+	for i := 0; i < ntpMeasurements; i++ {
+		sent := time.Now()
+		if _, err := conn.Write(request); err != nil {
+			return 0, err
+		}
+		conn.SetDeadline(sent.Add(ntpTimeout))
+
+		reply := make([]byte, 48)
+		if _, err := conn.Read(reply); err != nil {
+			return 0, err
+		}
+		elapsed := time.Since(sent)
+
+		// Reconstruct the server time from the transmit timestamp of the reply
+		var (
+			sec     = uint64(binary.BigEndian.Uint32(reply[40:44]))
+			frac    = uint64(binary.BigEndian.Uint32(reply[44:48]))
+			nanosec = sec*1e9 + (frac*1e9)>>32
+			stamp   = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(nanosec))
+		)
+		if sec == 0 {
+			return 0, errors.New("invalid NTP reply")
+		}
+		// Calculate the drift based on an assumed answer time of RTT/2
+		drifts = append(drifts, sent.Add(elapsed/2).Sub(stamp))
+	}
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i] < drifts[j] })
+	return drifts[len(drifts)/2], nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// Tests that the drift against an SNTP server is measured from its replies.
+func TestSNTPQuery(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	// Serve the time 3 seconds ahead of the local clock
+	go func() {
+		buf := make([]byte, 48)
+		for {
+			_, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			var (
+				now   = time.Now().Add(3 * time.Second)
+				since = now.Sub(time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC))
+				reply = make([]byte, 48)
+			)
+			binary.BigEndian.PutUint32(reply[40:], uint32(since/time.Second))
+			binary.BigEndian.PutUint32(reply[44:], uint32((uint64(since%time.Second)<<32)/1e9))
+			conn.WriteToUDP(reply, addr)
+		}
+	}()
+	drift, err := sntpQuery(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	if drift > -2900*time.Millisecond || drift < -3100*time.Millisecond {
+		t.Errorf("drift mismatch: have %v, want -3s", drift)
+	}
+}
+
+// Tests that the clock is only deemed unhealthy by the median drift of the
+// answering servers, and that sealing is only refused if enforced.
+func TestClockChecker(t *testing.T) {
+	drifts := map[string]time.Duration{"a": 2 * time.Second, "b": 3 * time.Second, "c": 100 * time.Millisecond}
+	query := func(server string) (time.Duration, error) {
+		if drift, ok := drifts[server]; ok {
+			return drift, nil
+		}
+		return 0, errors.New("unreachable")
+	}
+	checker := &clockChecker{config: ClockConfig{Servers: []string{"a", "b", "c", "d"}, MaxDrift: time.Second}, query: query}
+	if err := checker.sealable(); err != nil {
+		t.Fatalf("unchecked clock refused sealing: %v", err)
+	}
+	status := checker.check()
+	if status.Healthy || *status.Drift != 2 || len(status.Servers) != 4 || status.Servers[3].Error == "" {
+		t.Fatalf("drifted clock status mismatch: %+v", status)
+	}
+	if err := checker.sealable(); err != nil {
+		t.Errorf("unenforced check refused sealing: %v", err)
+	}
+	checker.config.Enforce = true
+	if err := checker.sealable(); !errors.Is(err, errClockDrift) {
+		t.Errorf("enforced check error mismatch: have %v, want %v", err, errClockDrift)
+	}
+	// Unreachable servers alone don't stop sealing
+	checker.config.Servers = []string{"d", "e"}
+	if status := checker.check(); !status.Healthy || status.Drift != nil {
+		t.Errorf("unknown clock status mismatch: %+v", status)
+	}
+	if err := checker.sealable(); err != nil {
+		t.Errorf("unknown clock refused sealing: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// errMissingSnapshotCommit is returned if an epoch block past the snapshot
+	// commitment fork doesn't commit to the snapshot it produces.
+	errMissingSnapshotCommit = errors.New("missing snapshot commitment on epoch block")
+
+	// errInvalidSnapshotCommit is returned if the snapshot commitment of an epoch
+	// block doesn't match the snapshot it produces.
+	errInvalidSnapshotCommit = errors.New("invalid snapshot commitment on epoch block")
+)
+
+// snapshotCommitment is the commitment of an epoch block to the snapshot it
+// produces: the keccak256 hash of the RLP list of the epoch number and the
+// signers in ascending order.
+func snapshotCommitment(epoch uint64, signers map[common.Address]bool) common.Hash {
+	list := make([]common.Address, 0, len(signers))
+	for signer := range signers {
+		list = append(list, signer)
+	}
+	sort.Sort(signersAscending(list))
+
+	blob, _ := rlp.EncodeToBytes([]interface{}{epoch, list})
+	return crypto.Keccak256Hash(blob)
+}
+
+// splitEpochExtra splits the extra-data of an epoch header between the vanity
+// and the seal into the signer list and the trailing snapshot commitment, if
+// any. The commitment isn't a multiple of the address length, so its presence
+// is unambiguous without knowing the chain configuration.
+func splitEpochExtra(header *types.Header) ([]byte, *common.Hash, error) {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, nil, errMissingSignature
+	}
+	body := header.Extra[extraVanity : len(header.Extra)-extraSeal]
+	if len(body)%common.AddressLength == 0 {
+		return body, nil, nil
+	}
+	if len(body) < common.HashLength || (len(body)-common.HashLength)%common.AddressLength != 0 {
+		return nil, nil, errInvalidCheckpointSigners
+	}
+	commit := common.BytesToHash(body[len(body)-common.HashLength:])
+	return body[:len(body)-common.HashLength], &commit, nil
+}
+
+// verifySnapshotCommit checks that the epoch header commits to the snapshot
+// with the given epoch and signers, if it commits to any.
+func verifySnapshotCommit(header *types.Header, epoch uint64, signers map[common.Address]bool) error {
+	_, commit, err := splitEpochExtra(header)
+	if err != nil {
+		return err
+	}
+	if commit != nil && *commit != snapshotCommitment(epoch, signers) {
+		return errInvalidSnapshotCommit
+	}
+	return nil
+}
+
+// snapshotCommitStatus is the snapshot commitment of an epoch block compared to
+// the locally derived snapshot.
+type snapshotCommitStatus struct {
+	Number    uint64       `json:"number"`
+	Epoch     uint64       `json:"epoch"`
+	Committed *common.Hash `json:"committed,omitempty"` // Commitment in the header, unset if none
+	Derived   common.Hash  `json:"derived"`             // Commitment of the local snapshot
+	Valid     bool         `json:"valid"`               // Whether the local snapshot matches the commitment
+}
+
+// VerifySnapshotCommitment compares the local snapshot at an epoch block with
+// the commitment in its header, validating the snapshot against the chain data.
+func (api *API) VerifySnapshotCommitment(number *rpc.BlockNumber) (*snapshotCommitStatus, error) {
+	snap, err := api.GetSnapshot(number)
+	if err != nil {
+		return nil, err
+	}
+	header := api.chain.GetHeader(snap.Hash, snap.Number)
+	if header == nil {
+		return nil, errMissingBlock(snap.Number)
+	}
+	status := &snapshotCommitStatus{
+		Number:  snap.Number,
+		Epoch:   snap.EpochNumber,
+		Derived: snapshotCommitment(snap.EpochNumber, snap.Signers),
+	}
+	if !bytes.Equal(header.Nonce[:], nonceDropVote) {
+		if _, commit, err := splitEpochExtra(header); err == nil {
+			status.Committed = commit
+		}
+	}
+	status.Valid = status.Committed != nil && *status.Committed == status.Derived
+	return status, nil
+}
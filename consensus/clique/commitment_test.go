@@ -0,0 +1,76 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests that the snapshot commitment is split off the signer list of epoch
+// headers, and verified against the snapshot the epoch block produces.
+func TestSnapshotCommitment(t *testing.T) {
+	var (
+		signers = map[common.Address]bool{{0x01}: true, {0x02}: true, {0x03}: true}
+		commit  = snapshotCommitment(7, signers)
+	)
+	epochHeader := func(list []common.Address, suffix []byte) *types.Header {
+		extra := make([]byte, extraVanity)
+		for _, signer := range list {
+			extra = append(extra, signer[:]...)
+		}
+		extra = append(append(extra, suffix...), make([]byte, extraSeal)...)
+		return &types.Header{Nonce: types.EncodeNonce(7), Extra: extra}
+	}
+	// The commitment doesn't depend on the order the signers are listed in
+	list := []common.Address{{0x03}, {0x01}, {0x02}}
+	header := epochHeader(list, commit[:])
+
+	body, have, err := splitEpochExtra(header)
+	if err != nil {
+		t.Fatalf("failed to split committing header: %v", err)
+	}
+	if len(body) != 3*common.AddressLength || have == nil || *have != commit {
+		t.Fatalf("split mismatch: signers %x, commitment %v", body, have)
+	}
+	if epoch, parsed, ok := EpochSigners(header); !ok || epoch != 7 || len(parsed) != 3 || parsed[0] != list[0] {
+		t.Fatalf("epoch signers mismatch: %d %v %v", epoch, parsed, ok)
+	}
+	if err := verifySnapshotCommit(header, 7, signers); err != nil {
+		t.Errorf("valid commitment rejected: %v", err)
+	}
+	if err := verifySnapshotCommit(header, 8, signers); err != errInvalidSnapshotCommit {
+		t.Errorf("commitment to another epoch error mismatch: have %v, want %v", err, errInvalidSnapshotCommit)
+	}
+	if err := verifySnapshotCommit(header, 7, map[common.Address]bool{{0x01}: true, {0x02}: true}); err != errInvalidSnapshotCommit {
+		t.Errorf("commitment to other signers error mismatch: have %v, want %v", err, errInvalidSnapshotCommit)
+	}
+	// Headers without a commitment are left alone, malformed ones rejected
+	plain := epochHeader(list, nil)
+	if body, have, err := splitEpochExtra(plain); err != nil || have != nil || !bytes.Equal(body, plain.Extra[extraVanity:len(plain.Extra)-extraSeal]) {
+		t.Errorf("plain header split mismatch: %x %v %v", body, have, err)
+	}
+	if err := verifySnapshotCommit(plain, 8, signers); err != nil {
+		t.Errorf("uncommitted header rejected: %v", err)
+	}
+	if _, _, err := splitEpochExtra(epochHeader(list, commit[:31])); err != errInvalidCheckpointSigners {
+		t.Errorf("truncated commitment error mismatch: have %v, want %v", err, errInvalidCheckpointSigners)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+// EpochSnapshotDigest identifies the snapshot derived at the canonical block of
+// an epoch by the hash of its canonical encoding, which nodes compare to detect
+// diverging snapshot derivations.
+type EpochSnapshotDigest struct {
+	Epoch   uint64      `json:"epoch"`
+	Number  uint64      `json:"number"`  // Number of the epoch block
+	Hash    common.Hash `json:"hash"`    // Hash of the epoch block
+	RLPHash common.Hash `json:"rlpHash"` // Keccak256 hash of the canonical snapshot encoding
+}
+
+// EpochSnapshotDigest returns the digest of the locally derived snapshot of the
+// given epoch on the canonical chain, walking back the epochs from the head. The
+// digests are cached, a cached one being dropped once its epoch block is no
+// longer canonical.
+func (c *Clique) EpochSnapshotDigest(chain consensus.ChainHeaderReader, epoch uint64) (*EpochSnapshotDigest, error) {
+	if cached, ok := c.digests.Get(epoch); ok {
+		digest := cached.(EpochSnapshotDigest)
+		if rawdb.ReadCanonicalHash(c.db, digest.Number) == digest.Hash {
+			return &digest, nil
+		}
+		c.digests.Remove(epoch)
+	}
+	snap, _, _, err := c.epochSpan(chain, epoch)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := snap.RLPHash()
+	if err != nil {
+		return nil, err
+	}
+	digest := EpochSnapshotDigest{Epoch: epoch, Number: snap.Number, Hash: snap.Hash, RLPHash: hash}
+	c.digests.Add(epoch, digest)
+	return &digest, nil
+}
+
+// CurrentEpoch returns the epoch of the snapshot at the head of the chain.
+func (c *Clique) CurrentEpoch(chain consensus.ChainHeaderReader) (uint64, error) {
+	head := chain.CurrentHeader()
+	snap, err := c.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return 0, err
+	}
+	return snap.EpochNumber, nil
+}
+
+// EpochSpan returns the blocks sealed in an epoch on the canonical chain: the
+// ones following its epoch block, up to and including the epoch block closing
+// it or the head if it's still running.
+func (c *Clique) EpochSpan(chain consensus.ChainHeaderReader, epoch uint64) (first uint64, last uint64, closed bool, err error) {
+	snap, end, closed, err := c.epochSpan(chain, epoch)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return snap.Number + 1, end, closed, nil
+}
+
+// epochSpan walks back the epochs from the head until the requested one,
+// returning its snapshot at the epoch block along with the last block sealed in
+// it and whether that block closed it.
+func (c *Clique) epochSpan(chain consensus.ChainHeaderReader, epoch uint64) (*Snapshot, uint64, bool, error) {
+	head := chain.CurrentHeader()
+	snap, err := c.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	var (
+		end    = head.Number.Uint64()
+		closed bool
+	)
+	for snap.EpochNumber > epoch {
+		if snap.PreviousSnapNumber == nil || snap.PreviousSnapHash == nil {
+			return nil, 0, false, errEpochNotFound(epoch)
+		}
+		end, closed = snap.Number, true
+		if snap, err = c.snapshot(chain, *snap.PreviousSnapNumber, *snap.PreviousSnapHash, nil); err != nil {
+			return nil, 0, false, err
+		}
+	}
+	if snap.EpochNumber != epoch {
+		return nil, 0, false, errEpochNotFound(epoch)
+	}
+	return snap, end, closed, nil
+}
+
+// EpochAt returns the epoch of the snapshot at the given canonical block, which
+// the blocks following it are sealed in until the next epoch block.
+func (c *Clique) EpochAt(chain consensus.ChainHeaderReader, number uint64) (uint64, error) {
+	header := chain.GetHeaderByNumber(number)
+	if header == nil {
+		return 0, errMissingBlock(number)
+	}
+	snap, err := c.snapshot(chain, number, header.Hash(), nil)
+	if err != nil {
+		return 0, err
+	}
+	return snap.EpochNumber, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the epoch snapshot digests are derived by walking back the epochs
+// from the head, and that cached digests of epoch blocks which are no longer
+// canonical are not served.
+func TestEpochSnapshotDigest(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		signer = crypto.PubkeyToAddress(key.PublicKey)
+		next   = common.Address{0x02}
+		db     = rawdb.NewMemoryDatabase()
+		config = &params.CliqueConfig{InitialValidators: []common.Address{signer}}
+	)
+	NewDNR(config, db)
+
+	genesis := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1), Extra: make([]byte, extraVanity+extraSeal)}
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	parent := genesis
+	for i := int64(1); i <= 3; i++ {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(i),
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		if i == 2 {
+			header.Nonce = types.EncodeNonce(7)
+			header.Extra = append(append(make([]byte, extraVanity), next[:]...), make([]byte, extraSeal)...)
+		}
+		sig, err := crypto.Sign(SealHash(header).Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to sign header: %v", err)
+		}
+		copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), uint64(i))
+		rawdb.WriteHeadHeaderHash(db, header.Hash())
+		parent = header
+	}
+	if _, err := RebuildSnapshots(db, config, nil); err != nil {
+		t.Fatalf("failed to build snapshots: %v", err)
+	}
+	var (
+		engine = New(config, db)
+		chain  = &canonicalReader{db: db}
+	)
+	// Cache a digest of an epoch block which is no longer canonical
+	engine.digests.Add(uint64(7), EpochSnapshotDigest{Epoch: 7, Number: 2, Hash: common.Hash{0xff}})
+
+	stored, err := loadSnapshot(config, nil, db, 2)
+	if err != nil {
+		t.Fatalf("failed to load epoch snapshot: %v", err)
+	}
+	want, _ := stored.RLPHash()
+	for i := 0; i < 2; i++ {
+		digest, err := engine.EpochSnapshotDigest(chain, 7)
+		if err != nil {
+			t.Fatalf("attempt %d: failed to get digest: %v", i, err)
+		}
+		if digest.Number != 2 || digest.Hash != stored.Hash || digest.RLPHash != want {
+			t.Errorf("attempt %d: digest mismatch: have %+v, want number 2, hash %x, rlp hash %x", i, digest, stored.Hash, want)
+		}
+	}
+	if digest, err := engine.EpochSnapshotDigest(chain, 0); err != nil || digest.Number != 0 {
+		t.Errorf("genesis epoch digest mismatch: %+v, %v", digest, err)
+	}
+	if _, err := engine.EpochSnapshotDigest(chain, 8); err == nil {
+		t.Errorf("digest of future epoch returned")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// instantSealTimeout is the time allowance for an instant seal to complete.
+const instantSealTimeout = 5 * time.Second
+
+var (
+	// errRegistryDriven is returned when trying to manually advance the epoch
+	// of a chain following a darknode registry.
+	errRegistryDriven = errors.New("epochs are driven by the darknode registry")
+
+	// errNoSigner is returned when sealing without an authorized signer.
+	errNoSigner = errors.New("no signer authorized")
+
+	// errEmptyValidators is returned when rotating to an empty validator set.
+	errEmptyValidators = errors.New("empty validator set")
+)
+
+// now returns the current time of the sealing clock, which is the wall clock
+// shifted by the time offset set on developer chains.
+func (c *Clique) now() time.Time {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return time.Now().Add(c.timeOffset)
+}
+
+// Now returns the current time of the sealing clock.
+func (c *Clique) Now() time.Time {
+	return c.now()
+}
+
+// TimeOffset returns the shift of the sealing clock relative to the wall clock.
+func (c *Clique) TimeOffset() time.Duration {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.timeOffset
+}
+
+// SetTimeOffset shifts the sealing clock relative to the wall clock. Blocks
+// are timestamped and validated against the shifted clock, which allows
+// developer chains to travel forward in time without waiting.
+func (c *Clique) SetTimeOffset(offset time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	log.Info("Shifted clique sealing clock", "offset", offset)
+	c.timeOffset = offset
+}
+
+// SealNow seals the given block synchronously, without waiting for its slot
+// and regardless of it being empty. It is meant for on-demand block production
+// on developer chains.
+func (c *Clique) SealNow(chain consensus.ChainHeaderReader, block *types.Block) (*types.Block, error) {
+	c.lock.RLock()
+	authorized := c.signFn != nil
+	c.lock.RUnlock()
+	if !authorized {
+		return nil, errNoSigner
+	}
+	atomic.AddInt32(&c.instant, 1)
+	defer atomic.AddInt32(&c.instant, -1)
+
+	var (
+		results = make(chan *types.Block, 1)
+		stop    = make(chan struct{})
+	)
+	defer close(stop)
+
+	if err := c.Seal(chain, block, results, stop); err != nil {
+		return nil, err
+	}
+	select {
+	case sealed := <-results:
+		return sealed, nil
+	case <-time.After(instantSealTimeout):
+		return nil, errors.New("sealing timed out")
+	}
+}
+
+// AdvanceEpoch moves the static validator registry of a developer chain to a
+// new epoch, which the next sealed block proposes. The validator set itself
+// is carried over unchanged.
+func (c *Clique) AdvanceEpoch() (uint64, error) {
+	return c.advanceEpoch(nil)
+}
+
+// RotateValidators moves the static validator registry of a developer chain to
+// a new epoch with the given validator set, which the next sealed block
+// proposes.
+func (c *Clique) RotateValidators(validators []common.Address) (uint64, error) {
+	if len(validators) == 0 {
+		return 0, errEmptyValidators
+	}
+	set := make(map[common.Address]bool, len(validators))
+	for _, validator := range validators {
+		set[validator] = true
+	}
+	return c.advanceEpoch(set)
+}
+
+// advanceEpoch moves the static validator registry to the next epoch, switching
+// to the given validator set if not nil.
+func (c *Clique) advanceEpoch(validators map[common.Address]bool) (uint64, error) {
+	if c.config.API != "" {
+		return 0, errRegistryDriven
+	}
+	dnr, err := GetLatestDNR(c.db)
+	if err != nil {
+		return 0, err
+	}
+	dnr.LastEpochBlock++
+	if validators != nil {
+		dnr.Validators = validators
+	}
+	if err := dnr.store(c.db); err != nil {
+		return 0, err
+	}
+	c.dnr.LastEpochBlock = dnr.LastEpochBlock
+	c.dnr.Validators = dnr.Validators
+	log.Info("Advanced static validator registry", "epoch", dnr.LastEpochBlock, "validators", len(dnr.Validators))
+	return dnr.LastEpochBlock, nil
+}
+
+// PrepareSealer prepares the consensus fields of a header like Prepare, but for
+// the given signer instead of the authorized one and leaving the timestamp as
+// is. If signer is the zero address, the in-turn signer of the block is picked.
+// The signer the header is prepared for is returned. It is meant for simulated
+// chains sealing blocks with several signers.
+func (c *Clique) PrepareSealer(chain consensus.ChainHeaderReader, header *types.Header, signer common.Address) (common.Address, error) {
+	number := header.Number.Uint64()
+	snap, err := c.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if signer == (common.Address{}) {
+		signer = snap.inturnSigner(number)
+	}
+	if _, ok := snap.Signers[signer]; !ok {
+		return common.Address{}, errUnauthorizedSigner
+	}
+	if err := c.prepareFields(chain, header, snap, signer); err != nil {
+		return common.Address{}, err
+	}
+	return signer, nil
+}
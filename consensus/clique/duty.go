@@ -0,0 +1,125 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxDutyHorizon is the maximum number of future blocks a duty schedule covers.
+const maxDutyHorizon = 100000
+
+// dutySlot is an upcoming block a signer is expected to seal in-turn.
+type dutySlot struct {
+	Block uint64 `json:"block"`
+	Time  uint64 `json:"time"` // Estimated unix timestamp
+}
+
+// dutySchedule lists the in-turn slots of a signer over the coming blocks.
+type dutySchedule struct {
+	Signer    common.Address `json:"signer"`
+	Epoch     uint64         `json:"epoch"` // Epoch whose signer set the rotation is computed from
+	Head      uint64         `json:"head"`
+	BlockTime float64        `json:"blockTime"` // Block interval in seconds used for time estimates
+	Slots     []dutySlot     `json:"slots"`
+	ICal      string         `json:"ical,omitempty"` // iCalendar feed of the slots, if requested
+}
+
+// GetDutySchedule returns the in-turn slots of a signer within the next horizon
+// blocks, optionally rendered as an iCalendar feed too. Slots are computed from
+// the signer set of the current snapshot, so they only hold until the epoch
+// ends. Times are based on the configured block period, or the block interval
+// observed over the current epoch on chains without a fixed period.
+func (api *API) GetDutySchedule(signer common.Address, horizon uint64, ical *bool) (*dutySchedule, error) {
+	if horizon == 0 || horizon > maxDutyHorizon {
+		return nil, errInvalidLimit(horizon, maxDutyHorizon, fmt.Sprintf("invalid horizon %d, must be between 1 and %d", horizon, maxDutyHorizon))
+	}
+	header := api.chain.CurrentHeader()
+	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := snap.Signers[signer]; !ok {
+		return nil, errNotValidator(snap.EpochNumber, signer)
+	}
+	head := header.Number.Uint64()
+
+	blockTime := float64(api.clique.config.Period)
+	if blockTime == 0 && head > snap.Number {
+		first := api.chain.GetHeaderByNumber(snap.Number)
+		if first == nil {
+			return nil, errMissingBlock(snap.Number)
+		}
+		blockTime = float64(header.Time-first.Time) / float64(head-snap.Number)
+	}
+	schedule := &dutySchedule{
+		Signer:    signer,
+		Epoch:     snap.EpochNumber,
+		Head:      head,
+		BlockTime: blockTime,
+		Slots:     []dutySlot{},
+	}
+	for n := head + 1; n <= head+horizon; n++ {
+		if snap.inturn(n, signer) {
+			schedule.Slots = append(schedule.Slots, dutySlot{
+				Block: n,
+				Time:  header.Time + uint64(float64(n-head)*blockTime),
+			})
+		}
+	}
+	if ical != nil && *ical {
+		schedule.ICal = schedule.iCalendar(header.Time)
+	}
+	return schedule, nil
+}
+
+// iCalendar renders the slots of the schedule as an RFC 5545 calendar, with an
+// event per slot lasting one block interval. The stamp is the time the schedule
+// was computed at.
+func (s *dutySchedule) iCalendar(stamp uint64) string {
+	const layout = "20060102T150405Z"
+
+	duration := time.Duration(s.BlockTime * float64(time.Second))
+	if duration < time.Second {
+		duration = time.Second
+	}
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format+"\r\n", args...)
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//go-ethereum//clique duty schedule//EN")
+	line("CALSCALE:GREGORIAN")
+	for _, slot := range s.Slots {
+		start := time.Unix(int64(slot.Time), 0).UTC()
+		line("BEGIN:VEVENT")
+		line("UID:%d-%s@clique", slot.Block, strings.ToLower(s.Signer.Hex()))
+		line("DTSTAMP:%s", time.Unix(int64(stamp), 0).UTC().Format(layout))
+		line("DTSTART:%s", start.Format(layout))
+		line("DTEND:%s", start.Add(duration).Format(layout))
+		line("SUMMARY:In-turn block %d", slot.Block)
+		line("DESCRIPTION:Signer %s is in-turn to seal block %d of epoch %d", s.Signer.Hex(), slot.Block, s.Epoch)
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
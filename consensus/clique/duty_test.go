@@ -0,0 +1,54 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that duty schedules render into a well formed iCalendar feed.
+func TestDutyScheduleICal(t *testing.T) {
+	schedule := &dutySchedule{
+		Signer:    common.HexToAddress("0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8"),
+		Epoch:     3,
+		Head:      100,
+		BlockTime: 5,
+		Slots:     []dutySlot{{Block: 103, Time: 1650000015}, {Block: 106, Time: 1650000030}},
+	}
+	feed := schedule.iCalendar(1650000000)
+
+	if !strings.HasPrefix(feed, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(feed, "END:VCALENDAR\r\n") {
+		t.Fatalf("calendar not delimited:\n%s", feed)
+	}
+	if have := strings.Count(feed, "BEGIN:VEVENT\r\n"); have != len(schedule.Slots) {
+		t.Fatalf("event count mismatch: have %d, want %d", have, len(schedule.Slots))
+	}
+	for _, want := range []string{
+		"UID:103-0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8@clique\r\n",
+		"DTSTAMP:20220415T052000Z\r\n",
+		"DTSTART:20220415T052015Z\r\n",
+		"DTEND:20220415T052020Z\r\n",
+		"SUMMARY:In-turn block 106\r\n",
+	} {
+		if !strings.Contains(feed, want) {
+			t.Errorf("missing line %q in calendar:\n%s", want, feed)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// JSON-RPC error codes returned by the clique API. They are stable, clients
+// should branch on them rather than on the error messages.
+const (
+	// CodeMissingBlock is returned if a block is not available. The data holds
+	// its number or hash.
+	CodeMissingBlock = -39000
+
+	// CodeEpochNotFound is returned if an epoch is not part of the canonical
+	// chain. The data holds the epoch number.
+	CodeEpochNotFound = -39001
+
+	// CodeEpochMismatch is returned if the epoch block given belongs to another
+	// epoch than the one requested. The data holds the expected and actual epoch.
+	CodeEpochMismatch = -39002
+
+	// CodeEpochNotClosed is returned if an epoch is still running. The data
+	// holds the epoch number.
+	CodeEpochNotClosed = -39003
+
+	// CodeGenesisEpoch is returned if the genesis epoch is requested where a
+	// preceding one is needed. The data holds the epoch number.
+	CodeGenesisEpoch = -39004
+
+	// CodeMissingSnapshot is returned if the snapshot of an epoch block is not
+	// available. The data holds the epoch block number.
+	CodeMissingSnapshot = -39005
+
+	// CodeNotValidator is returned if an address did not validate an epoch. The
+	// data holds the epoch number and the address.
+	CodeNotValidator = -39006
+
+	// CodeInvalidLimit is returned if a request exceeds the allowed number of
+	// items. The data holds the limit requested and the maximum.
+	CodeInvalidLimit = -39007
+
+	// CodeInvalidRange is returned if a block range starts before the epoch it
+	// is scanned for. The data holds the range start and the epoch block number.
+	CodeInvalidRange = -39008
+
+	// CodeNoEpochHistory is returned if no epoch has been completed yet.
+	CodeNoEpochHistory = -39009
+)
+
+// APIError is an error of the clique API, carrying a stable JSON-RPC error code
+// and machine-readable details about the failure.
+type APIError struct {
+	code int
+	msg  string
+	data interface{}
+}
+
+func (e *APIError) ErrorCode() int         { return e.code }
+func (e *APIError) Error() string          { return e.msg }
+func (e *APIError) ErrorData() interface{} { return e.data }
+
+var (
+	_ rpc.Error     = new(APIError)
+	_ rpc.DataError = new(APIError)
+)
+
+// errMissingBlock creates the error of a missing block number.
+func errMissingBlock(number uint64) *APIError {
+	return &APIError{
+		code: CodeMissingBlock,
+		msg:  fmt.Sprintf("missing block %d", number),
+		data: struct {
+			Number uint64 `json:"number"`
+		}{number},
+	}
+}
+
+// errMissingBlockHash creates the error of a missing block hash.
+func errMissingBlockHash(hash common.Hash) *APIError {
+	return &APIError{
+		code: CodeMissingBlock,
+		msg:  fmt.Sprintf("missing block %v", hash),
+		data: struct {
+			Hash common.Hash `json:"hash"`
+		}{hash},
+	}
+}
+
+// errEpochNotFound creates the error of an unknown epoch.
+func errEpochNotFound(epoch uint64) *APIError {
+	return &APIError{
+		code: CodeEpochNotFound,
+		msg:  fmt.Sprintf("epoch %d not found", epoch),
+		data: epochErrorData{epoch},
+	}
+}
+
+// errEpochMismatch creates the error of an epoch block of the wrong epoch.
+func errEpochMismatch(expected, got uint64) *APIError {
+	return &APIError{
+		code: CodeEpochMismatch,
+		msg:  fmt.Sprintf("epoch number mismatch, expected=%v got=%v", expected, got),
+		data: struct {
+			Expected uint64 `json:"expected"`
+			Got      uint64 `json:"got"`
+		}{expected, got},
+	}
+}
+
+// errEpochNotClosed creates the error of an epoch still running.
+func errEpochNotClosed(epoch uint64) *APIError {
+	return &APIError{
+		code: CodeEpochNotClosed,
+		msg:  fmt.Sprintf("epoch %d not closed yet", epoch),
+		data: epochErrorData{epoch},
+	}
+}
+
+// errGenesisEpoch creates the error of a request for the epoch preceding the
+// genesis one.
+func errGenesisEpoch(epoch uint64) *APIError {
+	return &APIError{
+		code: CodeGenesisEpoch,
+		msg:  fmt.Sprintf("epoch %d is the genesis epoch", epoch),
+		data: epochErrorData{epoch},
+	}
+}
+
+// errMissingSnapshot creates the error of a missing epoch snapshot.
+func errMissingSnapshot(number uint64) *APIError {
+	return &APIError{
+		code: CodeMissingSnapshot,
+		msg:  fmt.Sprintf("snapshot of epoch block %d missing", number),
+		data: struct {
+			Number uint64 `json:"number"`
+		}{number},
+	}
+}
+
+// errNotValidator creates the error of an address not validating an epoch.
+func errNotValidator(epoch uint64, validator common.Address) *APIError {
+	return &APIError{
+		code: CodeNotValidator,
+		msg:  fmt.Sprintf("%v not a validator in epoch %d", validator, epoch),
+		data: struct {
+			Epoch     uint64         `json:"epoch"`
+			Validator common.Address `json:"validator"`
+		}{epoch, validator},
+	}
+}
+
+// errInvalidLimit creates the error of a limit out of the allowed range.
+func errInvalidLimit(limit, max uint64, msg string) *APIError {
+	return &APIError{
+		code: CodeInvalidLimit,
+		msg:  msg,
+		data: struct {
+			Limit uint64 `json:"limit"`
+			Max   uint64 `json:"max"`
+		}{limit, max},
+	}
+}
+
+// errInvalidRange creates the error of a range starting before the epoch block.
+func errInvalidRange(from, epochBlock uint64) *APIError {
+	return &APIError{
+		code: CodeInvalidRange,
+		msg:  fmt.Sprintf("range start %d not after epoch block %d", from, epochBlock),
+		data: struct {
+			From       uint64 `json:"from"`
+			EpochBlock uint64 `json:"epochBlock"`
+		}{from, epochBlock},
+	}
+}
+
+// epochErrorData is the data of errors about a single epoch.
+type epochErrorData struct {
+	Epoch uint64 `json:"epoch"`
+}
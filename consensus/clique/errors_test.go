@@ -0,0 +1,65 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that clique API errors reach RPC clients with their code and data.
+func TestAPIErrors(t *testing.T) {
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("clique", &API{}); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	tests := []struct {
+		method string
+		args   []interface{}
+		code   int
+		data   string
+	}{
+		{"clique_epochPerformance", []interface{}{1, 1, 0}, CodeInvalidLimit, `{"limit":0,"max":50000}`},
+		{"clique_epochPerformanceRange", []interface{}{1, 1, 2, maxEpochScan + 1}, CodeInvalidLimit, `{"limit":50001,"max":50000}`},
+		{"clique_epochSchedule", []interface{}{maxEpochSchedule + 1}, CodeInvalidLimit, `{"limit":129,"max":128}`},
+	}
+	for i, tt := range tests {
+		err := client.Call(nil, tt.method, tt.args...)
+
+		var rerr rpc.Error
+		if !errors.As(err, &rerr) || rerr.ErrorCode() != tt.code {
+			t.Errorf("test %d: error mismatch: have %v, want code %d", i, err, tt.code)
+			continue
+		}
+		var derr rpc.DataError
+		if !errors.As(err, &derr) {
+			t.Errorf("test %d: error carries no data", i)
+			continue
+		}
+		data, _ := json.Marshal(derr.ErrorData())
+		if string(data) != tt.data {
+			t.Errorf("test %d: data mismatch: have %s, want %s", i, data, tt.data)
+		}
+	}
+}
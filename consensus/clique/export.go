@@ -0,0 +1,127 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// SignerHistoryVersion is the version of the signer history format, bumped on
+// incompatible changes so consumers of the static files can detect them.
+const SignerHistoryVersion = 1
+
+// SignerEpoch is an epoch of the canonical chain along with its signer set and
+// the blocks sealed by it.
+type SignerEpoch struct {
+	Epoch      uint64           `json:"epoch"`
+	Block      uint64           `json:"block"`               // Epoch block which opened the epoch
+	Hash       common.Hash      `json:"hash"`                // Hash of the epoch block
+	FirstBlock uint64           `json:"firstBlock"`          // First block sealed by the signer set
+	LastBlock  *uint64          `json:"lastBlock,omitempty"` // Last block sealed by the signer set, unset if still running
+	Signers    []common.Address `json:"signers"`             // Signer set in ascending order
+}
+
+// SignerHistory maps every epoch of the canonical chain to its signer set.
+type SignerHistory struct {
+	Version  uint64         `json:"version"`
+	Genesis  common.Hash    `json:"genesis"`
+	Head     uint64         `json:"head"` // Head block the history was exported at
+	HeadHash common.Hash    `json:"headHash"`
+	Epochs   []*SignerEpoch `json:"epochs"` // Epochs in ascending order
+}
+
+// ExportSigners collects the signer sets of all the epochs of the canonical
+// chain from the persisted snapshots. It fails if the snapshot of a canonical
+// epoch block is missing or was left behind by a dropped fork, the snapshots
+// need to be rebuilt then.
+func ExportSigners(db ethdb.Database, config *params.CliqueConfig) (*SignerHistory, error) {
+	headHash := rawdb.ReadHeadHeaderHash(db)
+	head := rawdb.ReadHeaderNumber(db, headHash)
+	if head == nil {
+		return nil, errors.New("head header missing")
+	}
+	snaps, err := loadSnapshots(db, config)
+	if err != nil {
+		return nil, err
+	}
+	var canonical []*Snapshot
+	for number, snap := range snaps {
+		if snap == nil {
+			return nil, errMissingSnapshot(number)
+		}
+		if number > *head {
+			continue
+		}
+		if hash := rawdb.ReadCanonicalHash(db, number); hash != snap.Hash {
+			// Snapshots of dropped forks only matter if they shadow a canonical epoch
+			header := rawdb.ReadHeader(db, hash, number)
+			if header == nil {
+				return nil, errMissingBlock(number)
+			}
+			if number == 0 || !bytes.Equal(header.Nonce[:], nonceDropVote) {
+				return nil, errMissingSnapshot(number)
+			}
+			continue
+		}
+		canonical = append(canonical, snap)
+	}
+	sort.Slice(canonical, func(i, j int) bool { return canonical[i].Number < canonical[j].Number })
+	if len(canonical) == 0 || canonical[0].Number != 0 {
+		return nil, errMissingSnapshot(0)
+	}
+	// Every snapshot must be derived from the one preceding it, lest an epoch
+	// whose snapshot is missing be skipped
+	for i := 1; i < len(canonical); i++ {
+		snap := canonical[i]
+		if snap.PreviousSnapNumber == nil || snap.PreviousSnapHash == nil {
+			return nil, errMissingSnapshot(canonical[i-1].Number)
+		}
+		if *snap.PreviousSnapHash != canonical[i-1].Hash {
+			return nil, errMissingSnapshot(*snap.PreviousSnapNumber)
+		}
+	}
+	history := &SignerHistory{
+		Version:  SignerHistoryVersion,
+		Genesis:  canonical[0].Hash,
+		Head:     *head,
+		HeadHash: headHash,
+		Epochs:   make([]*SignerEpoch, 0, len(canonical)),
+	}
+	for i, snap := range canonical {
+		epoch := &SignerEpoch{
+			Epoch:      snap.EpochNumber,
+			Block:      snap.Number,
+			Hash:       snap.Hash,
+			FirstBlock: snap.Number + 1,
+			Signers:    snap.signers(),
+		}
+		// The epoch block of the next epoch is still sealed by this signer set
+		if i+1 < len(canonical) {
+			last := canonical[i+1].Number
+			epoch.LastBlock = &last
+		}
+		history.Epochs = append(history.Epochs, epoch)
+	}
+	return history, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestExportSigners(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		signer = crypto.PubkeyToAddress(key.PublicKey)
+		next   = common.Address{0x02}
+		db     = rawdb.NewMemoryDatabase()
+		config = &params.CliqueConfig{InitialValidators: []common.Address{signer}}
+	)
+	NewDNR(config, db)
+
+	genesis := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1), Extra: make([]byte, extraVanity+extraSeal)}
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	parent := genesis
+	for i := int64(1); i <= 3; i++ {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(i),
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		if i == 2 {
+			header.Nonce = types.EncodeNonce(7)
+			header.Extra = append(append(make([]byte, extraVanity), next[:]...), make([]byte, extraSeal)...)
+		}
+		sig, err := crypto.Sign(SealHash(header).Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to sign header: %v", err)
+		}
+		copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), uint64(i))
+		rawdb.WriteHeadHeaderHash(db, header.Hash())
+		parent = header
+	}
+	if _, err := RebuildSnapshots(db, config, nil); err != nil {
+		t.Fatalf("failed to build snapshots: %v", err)
+	}
+	history, err := ExportSigners(db, config)
+	if err != nil {
+		t.Fatalf("failed to export signers: %v", err)
+	}
+	if history.Version != SignerHistoryVersion || history.Genesis != genesis.Hash() || history.Head != 3 || history.HeadHash != parent.Hash() {
+		t.Fatalf("history metadata mismatch: %+v", history)
+	}
+	if len(history.Epochs) != 2 {
+		t.Fatalf("epoch count mismatch: have %d, want 2", len(history.Epochs))
+	}
+	first, second := history.Epochs[0], history.Epochs[1]
+	if first.Block != 0 || first.FirstBlock != 1 || first.LastBlock == nil || *first.LastBlock != 2 || !reflect.DeepEqual(first.Signers, []common.Address{signer}) {
+		t.Errorf("genesis epoch mismatch: %+v", first)
+	}
+	if second.Epoch != 7 || second.Block != 2 || second.FirstBlock != 3 || second.LastBlock != nil || !reflect.DeepEqual(second.Signers, []common.Address{next}) {
+		t.Errorf("running epoch mismatch: %+v", second)
+	}
+	// Reorg the epoch block to a fork one whose snapshot isn't persisted
+	fork := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		Difficulty: diffNoTurn,
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	rawdb.WriteHeader(db, fork)
+	rawdb.WriteCanonicalHash(db, fork.Hash(), 1)
+
+	fork = &types.Header{
+		ParentHash: fork.Hash(),
+		Number:     big.NewInt(2),
+		Difficulty: diffNoTurn,
+		Nonce:      types.EncodeNonce(7),
+		Extra:      append(append(make([]byte, extraVanity), signer[:]...), make([]byte, extraSeal)...),
+	}
+	rawdb.WriteHeader(db, fork)
+	rawdb.WriteCanonicalHash(db, fork.Hash(), 2)
+	if _, err := ExportSigners(db, config); err == nil {
+		t.Fatalf("signers exported with the snapshot of a dropped epoch block")
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// errFastVerifyCheckpoint is returned when the header at the number of the fast
+// verification checkpoint isn't the trusted one.
+var errFastVerifyCheckpoint = errors.New("header mismatches the fast verification checkpoint")
+
+// fastVerifyMeter counts the headers verified by the fast verification shortcut.
+var fastVerifyMeter = metrics.NewRegisteredMeter("clique/fastverify", nil)
+
+// FastVerifyCheckpoint is a canonical block trusted by the operator, below which
+// the initial sync may take shortcuts verifying the ancestors of the block.
+type FastVerifyCheckpoint struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// SetFastVerify enables verifying the headers below a trusted checkpoint with a
+// shortcut during the initial sync, as reported by syncing. The shortcut checks
+// the seal against the cached keys of the authorized signers and the in-turn
+// difficulty, but skips the recent signer rules. Only headers extending the
+// canonical chain are eligible, and the header at the number of the checkpoint
+// must be the trusted one, so the shortcut headers can't be built upon past the
+// checkpoint unless they are its ancestors.
+func (c *Clique) SetFastVerify(checkpoint *FastVerifyCheckpoint, syncing func() bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.fastVerify, c.fastVerifySyncing = checkpoint, syncing
+}
+
+// checkFastVerifyCheckpoint verifies that the header at the number of the fast
+// verification checkpoint, if any, is the trusted one.
+func (c *Clique) checkFastVerifyCheckpoint(header *types.Header) error {
+	c.lock.RLock()
+	checkpoint := c.fastVerify
+	c.lock.RUnlock()
+
+	if checkpoint == nil || header.Number.Uint64() != checkpoint.Number {
+		return nil
+	}
+	if header.Hash() != checkpoint.Hash {
+		return errFastVerifyCheckpoint
+	}
+	return nil
+}
+
+// trustedSeal reports whether the seal of a non-epoch header may be verified
+// with the fast verification shortcut: the initial sync is running, the header
+// is below the checkpoint and it extends the canonical chain.
+func (c *Clique) trustedSeal(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header) bool {
+	c.lock.RLock()
+	checkpoint, syncing := c.fastVerify, c.fastVerifySyncing
+	c.lock.RUnlock()
+
+	if checkpoint == nil || syncing == nil || header.Number.Uint64() >= checkpoint.Number || !syncing() {
+		return false
+	}
+	// The first header of the batch must build on the canonical chain, the rest
+	// are linked to it by hash
+	first := header
+	if len(parents) > 0 {
+		first = parents[0]
+	}
+	parent := chain.GetHeaderByNumber(first.Number.Uint64() - 1)
+	return parent != nil && parent.Hash() == first.ParentHash
+}
+
+// verifyTrustedSeal verifies the seal of a header with the fast verification
+// shortcut, checking the signature against the cached keys of the signers, the
+// authorization of the signer and its in-turn difficulty.
+func (c *Clique) verifyTrustedSeal(snap *Snapshot, header *types.Header) error {
+	signer, err := c.sealerOf(header)
+	if err != nil {
+		return err
+	}
+	if _, ok := snap.Signers[signer]; !ok {
+		return errUnauthorizedSigner
+	}
+	if !c.fakeDiff {
+		inturn := snap.inturn(header.Number.Uint64(), signer)
+		if inturn && header.Difficulty.Cmp(diffInTurn) != 0 {
+			return errWrongDifficulty
+		}
+		if !inturn && header.Difficulty.Cmp(diffNoTurn) != 0 {
+			return errWrongDifficulty
+		}
+	}
+	return nil
+}
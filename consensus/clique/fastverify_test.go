@@ -0,0 +1,143 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// canonicalReader serves the canonical headers of a database.
+type canonicalReader struct {
+	consensus.ChainHeaderReader
+	db ethdb.Database
+}
+
+func (r *canonicalReader) GetHeaderByNumber(number uint64) *types.Header {
+	hash := rawdb.ReadCanonicalHash(r.db, number)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	return rawdb.ReadHeader(r.db, hash, number)
+}
+
+func (r *canonicalReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return rawdb.ReadHeader(r.db, hash, number)
+}
+
+func (r *canonicalReader) CurrentHeader() *types.Header {
+	hash := rawdb.ReadHeadHeaderHash(r.db)
+	number := rawdb.ReadHeaderNumber(r.db, hash)
+	if number == nil {
+		return nil
+	}
+	return rawdb.ReadHeader(r.db, hash, *number)
+}
+
+// Tests that the fast verification shortcut is only taken during the initial
+// sync, below the checkpoint and on the canonical chain, and that it still
+// checks the signer and its difficulty.
+func TestTrustedSeal(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		other, _ = crypto.GenerateKey()
+		signer   = crypto.PubkeyToAddress(key.PublicKey)
+		db       = rawdb.NewMemoryDatabase()
+		chain    = &canonicalReader{db: db}
+		engine   = New(&params.CliqueConfig{Period: 2}, db)
+		snap     = &Snapshot{Signers: map[common.Address]bool{signer: true}}
+	)
+	seal := func(parent *types.Header, key *ecdsa.PrivateKey, difficulty *big.Int, extra byte) *types.Header {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Difficulty: difficulty,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		header.Extra[0] = extra
+		sig, err := crypto.Sign(SealHash(header).Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to sign header: %v", err)
+		}
+		copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		return header
+	}
+	// Build a canonical chain of 3 blocks
+	genesis := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1), Extra: make([]byte, extraVanity+extraSeal)}
+	headers := []*types.Header{genesis}
+	for i := 1; i <= 3; i++ {
+		headers = append(headers, seal(headers[i-1], key, diffInTurn, 0))
+	}
+	for i, header := range headers {
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), uint64(i))
+	}
+	var (
+		next    = seal(headers[3], key, diffInTurn, 0)
+		side    = seal(seal(headers[1], key, diffInTurn, 1), key, diffInTurn, 1)
+		syncing = true
+	)
+	if engine.trustedSeal(chain, next, nil) {
+		t.Errorf("shortcut taken with fast verification disabled")
+	}
+	engine.SetFastVerify(&FastVerifyCheckpoint{Number: 10, Hash: common.Hash{0x10}}, func() bool { return syncing })
+	if !engine.trustedSeal(chain, next, nil) {
+		t.Errorf("shortcut not taken for canonical extension below the checkpoint")
+	}
+	if !engine.trustedSeal(chain, seal(next, key, diffInTurn, 0), []*types.Header{next}) {
+		t.Errorf("shortcut not taken for batch extending the canonical chain")
+	}
+	if engine.trustedSeal(chain, side, nil) {
+		t.Errorf("shortcut taken for side chain header")
+	}
+	syncing = false
+	if engine.trustedSeal(chain, next, nil) {
+		t.Errorf("shortcut taken after the initial sync")
+	}
+	syncing = true
+	engine.SetFastVerify(&FastVerifyCheckpoint{Number: 4, Hash: common.Hash{0x4}}, func() bool { return syncing })
+	if engine.trustedSeal(chain, next, nil) {
+		t.Errorf("shortcut taken at the checkpoint")
+	}
+	// The header at the checkpoint must be the trusted one
+	if err := engine.checkFastVerifyCheckpoint(next); err != errFastVerifyCheckpoint {
+		t.Errorf("mismatching checkpoint header accepted: %v", err)
+	}
+	engine.SetFastVerify(&FastVerifyCheckpoint{Number: 4, Hash: next.Hash()}, func() bool { return syncing })
+	if err := engine.checkFastVerifyCheckpoint(next); err != nil {
+		t.Errorf("checkpoint header rejected: %v", err)
+	}
+	// The shortcut still verifies the signer and the difficulty
+	if err := engine.verifyTrustedSeal(snap, next); err != nil {
+		t.Errorf("valid seal rejected: %v", err)
+	}
+	if err := engine.verifyTrustedSeal(snap, seal(headers[3], other, diffInTurn, 0)); err != errUnauthorizedSigner {
+		t.Errorf("seal of unauthorized signer accepted: %v", err)
+	}
+	if err := engine.verifyTrustedSeal(snap, seal(headers[3], key, diffNoTurn, 0)); err != errWrongDifficulty {
+		t.Errorf("wrong difficulty accepted: %v", err)
+	}
+}
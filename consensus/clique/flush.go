@@ -0,0 +1,83 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// errSealingStopped is returned when sealing a block after sealing was stopped
+// for shutdown.
+var errSealingStopped = errors.New("sealing stopped for shutdown")
+
+// beginSeal registers a block about to be signed, reporting false if sealing
+// was stopped.
+func (c *Clique) beginSeal() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.sealStopped {
+		return false
+	}
+	c.sealing.Add(1)
+	return true
+}
+
+// StopSealing refuses to sign any further block and waits for the signed ones to
+// be delivered or abandoned. Every block signed from this datadir is then covered
+// by its slash protection record and by the snapshots on disk, so the node can be
+// killed without signing blocks it has no record of.
+func (c *Clique) StopSealing() error {
+	c.lock.Lock()
+	c.sealStopped = true
+	c.lock.Unlock()
+
+	c.sealing.Wait()
+	if sealed := ReadLastSealed(c.db); sealed != nil {
+		log.Info("Stopped sealing", "signer", sealed.Signer, "last", sealed.Number)
+	}
+	return nil
+}
+
+// FlushSnapshot persists the snapshot of the epoch the chain head is in, along
+// with its checkpoint, if they aren't on disk yet, sparing the restarted node
+// from rebuilding them from the headers.
+func (c *Clique) FlushSnapshot(chain consensus.ChainHeaderReader) error {
+	head := chain.CurrentHeader()
+	snap, err := c.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return err
+	}
+	if ok, _ := c.db.Has(snapshotKey(snap.Number)); !ok {
+		if err := snap.store(c.db); err != nil {
+			return err
+		}
+		log.Info("Flushed clique snapshot", "number", snap.Number, "epoch", snap.EpochNumber)
+	}
+	if snap.Number > 0 && ReadEpochCheckpoint(c.db, snap.EpochNumber) == nil {
+		header := chain.GetHeader(snap.Hash, snap.Number)
+		if header == nil {
+			return fmt.Errorf("epoch block #%d missing", snap.Number)
+		}
+		writeEpochCheckpoint(c.db, header, snap)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that stopping the sealing waits for the signed blocks to be delivered,
+// and refuses to sign further ones.
+func TestStopSealing(t *testing.T) {
+	engine := New(&params.CliqueConfig{Period: 2}, rawdb.NewMemoryDatabase())
+	if !engine.beginSeal() {
+		t.Fatalf("sealing refused before stopping")
+	}
+	stopped := make(chan struct{})
+	go func() {
+		engine.StopSealing()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatalf("sealing stopped with a block in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	engine.sealing.Done()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("sealing not stopped after the block was delivered")
+	}
+	if engine.beginSeal() {
+		t.Fatalf("sealing allowed after stopping")
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+)
+
+// ValidatorHealth is the sealing record of a validator over the current epoch.
+type ValidatorHealth struct {
+	Validator      common.Address `json:"validator"`
+	Epoch          uint64         `json:"epoch"`
+	EpochBlock     uint64         `json:"epochBlock"`     // Block which started the epoch
+	EpochTime      uint64         `json:"epochTime"`      // Timestamp of the epoch block
+	Head           uint64         `json:"head"`           // Last block considered
+	Sealed         uint64         `json:"sealed"`         // Blocks sealed since the epoch block
+	Uptime         float64        `json:"uptime"`         // Sealed blocks in percent of the fair share, at most 100
+	MissedStreak   uint64         `json:"missedStreak"`   // Consecutive in-turn slots missed up to the head
+	LastSealed     *uint64        `json:"lastSealed"`     // Last block sealed in the epoch, if any
+	LastSealedTime *uint64        `json:"lastSealedTime"` // Timestamp of the last block sealed in the epoch, if any
+	Maintenance    bool           `json:"maintenance"`    // Whether the validator announced a maintenance covering the head
+}
+
+// ValidatorHealth returns the sealing record of every validator of the current
+// epoch, scanning the sealers of the canonical blocks since the epoch block.
+func (c *Clique) ValidatorHealth(chain consensus.ChainHeaderReader) ([]*ValidatorHealth, error) {
+	head := chain.CurrentHeader()
+	snap, err := c.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	epochHeader := chain.GetHeaderByNumber(snap.Number)
+	if epochHeader == nil {
+		return nil, errMissingBlock(snap.Number)
+	}
+	if len(snap.Signers) == 0 {
+		return nil, nil
+	}
+	var (
+		number  = head.Number.Uint64()
+		signers = snap.signers()
+		health  = make(map[common.Address]*ValidatorHealth, len(signers))
+		missed  = make(map[common.Address]bool) // Whether the streak of a signer is still running
+	)
+	for _, signer := range signers {
+		health[signer] = &ValidatorHealth{
+			Validator:   signer,
+			Epoch:       snap.EpochNumber,
+			EpochBlock:  snap.Number,
+			EpochTime:   epochHeader.Time,
+			Head:        number,
+			Maintenance: c.inMaintenance(signer, number),
+		}
+		missed[signer] = true
+	}
+	// Walk the epoch backwards, so the first block sealed by a signer is its last
+	for n := number; n > snap.Number; n-- {
+		entry, err := c.sealerAt(chain, n)
+		if err != nil {
+			return nil, err
+		}
+		if h := health[entry.Signer]; h != nil {
+			h.Sealed++
+			if h.LastSealed == nil {
+				header := chain.GetHeaderByNumber(n)
+				if header == nil {
+					return nil, errMissingBlock(n)
+				}
+				sealed, stamp := n, header.Time
+				h.LastSealed, h.LastSealedTime = &sealed, &stamp
+			}
+		}
+		// Extend the streak of the in-turn signer unless it sealed its slot
+		if signer := signers[n%uint64(len(signers))]; missed[signer] {
+			if entry.Signer == signer {
+				missed[signer] = false
+			} else {
+				health[signer].MissedStreak++
+			}
+		}
+	}
+	result := make([]*ValidatorHealth, 0, len(signers))
+	for _, signer := range signers {
+		h := health[signer]
+		h.Uptime = 100
+		if blocks := number - snap.Number; blocks > 0 {
+			if share := float64(blocks) / float64(len(signers)); float64(h.Sealed) < share {
+				h.Uptime = 100 * float64(h.Sealed) / share
+			}
+		}
+		result = append(result, h)
+	}
+	return result, nil
+}
+
+// nodeHealth is the sealing health of the local node.
+type nodeHealth struct {
+	Signer      common.Address    `json:"signer"`
+	Sealing     bool              `json:"sealing"`          // Whether the health checks allow sealing
+	Reason      string            `json:"reason,omitempty"` // Reason sealing is refused, if it is
+	Maintenance MaintenanceStatus `json:"maintenance"`
+	Pause       *SealingPause     `json:"pause,omitempty"` // Ongoing sealing pause, if any
+	Clock       *ClockStatus      `json:"clock"`           // Last local clock check, unset if disabled or not yet done
+	Preflight   *PreflightStatus  `json:"preflight"`       // Last run of the startup checks, unset if not gated by them
+}
+
+// Health returns the sealing health of the local node, along with the outcome
+// of the last check of the local clock against the NTP servers.
+func (api *API) Health() *nodeHealth {
+	c := api.clique
+
+	c.lock.RLock()
+	signer, maintUntil := c.signer, c.maintUntil
+	var pause *SealingPause
+	if ongoing := c.pausedLocked(); ongoing != nil {
+		cpy := *ongoing
+		pause = &cpy
+	}
+	c.lock.RUnlock()
+
+	health := &nodeHealth{
+		Signer:      signer,
+		Sealing:     true,
+		Maintenance: c.Maintenance(),
+		Pause:       pause,
+		Clock:       c.clockStatus(),
+		Preflight:   c.Preflight(),
+	}
+	fail := func(err error) {
+		health.Sealing, health.Reason = false, err.Error()
+	}
+	switch head := api.chain.CurrentHeader().Number.Uint64(); {
+	case signer == (common.Address{}):
+		fail(errNotSigner)
+	case head+1 <= maintUntil:
+		fail(errInMaintenance)
+	case pause != nil:
+		fail(fmt.Errorf("%w: %s", errSealingPaused, pause.Reason))
+	default:
+		if err := c.clockSealable(); err != nil {
+			fail(err)
+		} else if err := c.preflightSealable(); err != nil {
+			fail(err)
+		}
+	}
+	return health
+}
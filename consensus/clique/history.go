@@ -0,0 +1,138 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// authorizationChange is an epoch block adding or removing an address from the
+// signer set.
+type authorizationChange struct {
+	Block      uint64 `json:"block"` // Epoch block applying the change
+	Epoch      uint64 `json:"epoch"`
+	Authorized bool   `json:"authorized"` // Whether the address was added or removed
+}
+
+// authorizationSpan is an epoch the address was a signer in, along with its
+// sealing record over the blocks of the epoch.
+type authorizationSpan struct {
+	Epoch      uint64  `json:"epoch"`
+	StartBlock uint64  `json:"startBlock"`         // Epoch block which opened the epoch
+	EndBlock   *uint64 `json:"endBlock,omitempty"` // Epoch block which closed the epoch, unset if still running
+	Signers    int     `json:"signers"`            // Size of the signer set in the epoch
+	Blocks     uint64  `json:"blocks"`             // Number of blocks sealed in the epoch so far
+	Scanned    uint64  `json:"scanned"`            // Number of blocks the sealing record covers
+	Sealed     uint64  `json:"sealed"`             // Number of scanned blocks sealed by the address
+	Uptime     float64 `json:"uptime"`             // Sealed blocks in percent of the fair share of the scanned ones
+}
+
+// authorizationHistory is the complete authorization timeline of an address.
+type authorizationHistory struct {
+	Address    common.Address         `json:"address"`
+	Authorized bool                   `json:"authorized"` // Whether the address is currently a signer
+	Changes    []*authorizationChange `json:"changes"`
+	Spans      []*authorizationSpan   `json:"spans"`
+}
+
+// epochTallyFn counts the blocks sealed by an address among the given blocks of
+// the epoch opened by a snapshot, returning the count and the number of blocks
+// scanned.
+type epochTallyFn func(snap *Snapshot, start, end uint64, address common.Address) (uint64, uint64, error)
+
+// buildAuthorizationHistory derives the authorization timeline of an address
+// from the snapshots of all the epochs in ascending order, the last one being
+// the running epoch at the given head.
+func buildAuthorizationHistory(address common.Address, snaps []*Snapshot, head uint64, tally epochTallyFn) (*authorizationHistory, error) {
+	history := &authorizationHistory{
+		Address: address,
+		Changes: []*authorizationChange{},
+		Spans:   []*authorizationSpan{},
+	}
+	for i, snap := range snaps {
+		authorized := snap.Signers[address]
+		if authorized != history.Authorized {
+			history.Changes = append(history.Changes, &authorizationChange{
+				Block:      snap.Number,
+				Epoch:      snap.EpochNumber,
+				Authorized: authorized,
+			})
+			history.Authorized = authorized
+		}
+		if !authorized {
+			continue
+		}
+		// The epoch runs up to and including the block opening the next one
+		span := &authorizationSpan{
+			Epoch:      snap.EpochNumber,
+			StartBlock: snap.Number,
+			Signers:    len(snap.Signers),
+		}
+		end := head
+		if i+1 < len(snaps) {
+			end = snaps[i+1].Number
+			span.EndBlock = &end
+		}
+		span.Blocks = end - snap.Number
+		if span.Blocks > 0 {
+			sealed, scanned, err := tally(snap, snap.Number+1, end, address)
+			if err != nil {
+				return nil, err
+			}
+			span.Sealed, span.Scanned = sealed, scanned
+			if scanned > 0 {
+				span.Uptime = float64(100*sealed) * float64(span.Signers) / float64(scanned)
+			}
+		}
+		history.Spans = append(history.Spans, span)
+	}
+	return history, nil
+}
+
+// GetAuthorizationHistory returns the authorization timeline of an address: the
+// epoch blocks adding it to or removing it from the signer set, and the epochs
+// it was a signer in along with its share of the sealed blocks. The sealing
+// record covers at most the first 50000 blocks of each epoch.
+func (api *API) GetAuthorizationHistory(address common.Address) (*authorizationHistory, error) {
+	head := api.chain.CurrentHeader()
+	snap, err := api.clique.snapshot(api.chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Walk the snapshot chain back to the genesis one
+	snaps := []*Snapshot{snap}
+	for snap.PreviousSnapNumber != nil && snap.PreviousSnapHash != nil {
+		if snap, err = api.clique.snapshot(api.chain, *snap.PreviousSnapNumber, *snap.PreviousSnapHash, nil); err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, snap)
+	}
+	for i, j := 0, len(snaps)-1; i < j; i, j = i+1, j-1 {
+		snaps[i], snaps[j] = snaps[j], snaps[i]
+	}
+	tally := func(snap *Snapshot, start, end uint64, address common.Address) (uint64, uint64, error) {
+		if end >= start+maxEpochScan {
+			end = start + maxEpochScan - 1
+		}
+		perf, err := api.scanEpoch(snap, start, end)
+		if err != nil {
+			return 0, 0, err
+		}
+		return uint64(perf.SigningStatus[address]), perf.NumBlocks, nil
+	}
+	return buildAuthorizationHistory(address, snaps, head.Number.Uint64(), tally)
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that the authorization timeline of an address is derived from the epoch
+// snapshots, with the uptime measured against the fair share of each epoch.
+func TestAuthorizationHistory(t *testing.T) {
+	var (
+		a, b  = common.Address{0x0a}, common.Address{0x0b}
+		snaps = []*Snapshot{
+			{Number: 0, EpochNumber: 1, Signers: map[common.Address]bool{a: true, b: true}},
+			{Number: 10, EpochNumber: 2, Signers: map[common.Address]bool{b: true}},
+			{Number: 20, EpochNumber: 3, Signers: map[common.Address]bool{a: true, b: true}},
+		}
+	)
+	// The address sealed half of the blocks of each epoch it was a signer in
+	tally := func(snap *Snapshot, start, end uint64, address common.Address) (uint64, uint64, error) {
+		if address != a || !snap.Signers[a] {
+			t.Fatalf("tally of unrelated epoch %d", snap.EpochNumber)
+		}
+		return (end - start + 1) / 2, end - start + 1, nil
+	}
+	history, err := buildAuthorizationHistory(a, snaps, 25, tally)
+	if err != nil {
+		t.Fatalf("failed to build history: %v", err)
+	}
+	if !history.Authorized || len(history.Changes) != 3 || len(history.Spans) != 2 {
+		t.Fatalf("history mismatch: %+v", history)
+	}
+	for i, want := range []authorizationChange{{0, 1, true}, {10, 2, false}, {20, 3, true}} {
+		if *history.Changes[i] != want {
+			t.Errorf("change %d mismatch: have %+v, want %+v", i, *history.Changes[i], want)
+		}
+	}
+	first, last := history.Spans[0], history.Spans[1]
+	if first.EndBlock == nil || *first.EndBlock != 10 || first.Blocks != 10 || first.Sealed != 5 || first.Uptime != 100 {
+		t.Errorf("closed span mismatch: %+v", first)
+	}
+	if last.EndBlock != nil || last.Blocks != 5 || last.Sealed != 2 || last.Uptime != 80 {
+		t.Errorf("running span mismatch: %+v", last)
+	}
+	// Addresses which never were signers have an empty history
+	if history, err = buildAuthorizationHistory(common.Address{0x0c}, snaps, 25, nil); err != nil || len(history.Changes) != 0 || len(history.Spans) != 0 || history.Authorized {
+		t.Fatalf("history of unknown address: %+v, %v", history, err)
+	}
+}
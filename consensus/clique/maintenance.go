@@ -0,0 +1,172 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// maxMaintenanceBlocks is the maximum number of blocks a signer can announce
+// to skip its slots for at once.
+const maxMaintenanceBlocks = 100000
+
+// maintenanceMagic prefixes the extra-data vanity of the blocks by which their
+// signer announces a maintenance, followed by the number of the last block of
+// the maintenance (uint64 big endian). The hint is advisory, it's not part of
+// the consensus rules.
+var maintenanceMagic = []byte("aksmaint")
+
+var (
+	// errInMaintenance is returned when sealing a block while the local signer
+	// skips its slots for an announced maintenance.
+	errInMaintenance = errors.New("signer in maintenance, skipping slots")
+
+	// errNotSigner is returned when entering maintenance without being
+	// authorized to seal blocks.
+	errNotSigner = errors.New("not authorized to seal blocks")
+)
+
+// MaintenanceStatus is the maintenance state of the local signer.
+type MaintenanceStatus struct {
+	Pending uint64 `json:"pending"` // Number of blocks to skip, announced in the next sealed block
+	Until   uint64 `json:"until"`   // Last block of the announced maintenance, zero if none
+}
+
+// maintenanceHint returns the last block of the maintenance announced by the
+// header's signer, if any. Announcements longer than a signer can make are
+// ignored, so that a forged hint can't reduce the wiggle of its slots forever.
+func maintenanceHint(header *types.Header) (uint64, bool) {
+	if len(header.Extra) < extraVanity || !bytes.HasPrefix(header.Extra, maintenanceMagic) {
+		return 0, false
+	}
+	until, number := binary.BigEndian.Uint64(header.Extra[len(maintenanceMagic):]), header.Number.Uint64()
+	return until, until > number && until-number <= maxMaintenanceBlocks
+}
+
+// EnterMaintenance makes the local signer announce in the next block it seals
+// that it skips its slots for the given number of blocks after it, and stop
+// sealing once that block is sealed. Meanwhile the other signers take its turns
+// with a reduced wiggle.
+func (c *Clique) EnterMaintenance(blocks uint64) error {
+	if blocks == 0 || blocks > maxMaintenanceBlocks {
+		return fmt.Errorf("maintenance must last between 1 and %d blocks", maxMaintenanceBlocks)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.signer == (common.Address{}) {
+		return errNotSigner
+	}
+	c.maintPending = blocks
+	return nil
+}
+
+// ExitMaintenance cancels the pending or ongoing maintenance of the local
+// signer, resuming sealing right away.
+func (c *Clique) ExitMaintenance() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.maintPending, c.maintUntil = 0, 0
+}
+
+// Maintenance returns the maintenance state of the local signer.
+func (c *Clique) Maintenance() MaintenanceStatus {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return MaintenanceStatus{Pending: c.maintPending, Until: c.maintUntil}
+}
+
+// prepareMaintenance adds the announcement of the pending maintenance of the
+// local signer to a header. Epoch blocks are skipped, their vanity may be taken
+// by the performance root.
+func (c *Clique) prepareMaintenance(header *types.Header) {
+	c.lock.RLock()
+	pending := c.maintPending
+	c.lock.RUnlock()
+
+	if pending == 0 || len(header.Extra) != extraVanity+extraSeal {
+		return
+	}
+	copy(header.Extra, maintenanceMagic)
+	binary.BigEndian.PutUint64(header.Extra[len(maintenanceMagic):], header.Number.Uint64()+pending)
+}
+
+// sealedMaintenance starts the maintenance of the local signer if the block it
+// just sealed announces it.
+func (c *Clique) sealedMaintenance(header *types.Header, signer common.Address) {
+	until, ok := maintenanceHint(header)
+	if !ok {
+		return
+	}
+	c.noteMaintenance(header.Number.Uint64(), until, signer)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.maintPending != 0 {
+		c.maintPending, c.maintUntil = 0, until
+		log.Info("Entered maintenance, skipping slots", "from", header.Number.Uint64()+1, "until", until)
+	}
+}
+
+// noteMaintenance records the maintenance announced by a signer in the given
+// block, dropping the expired ones.
+func (c *Clique) noteMaintenance(number, until uint64, signer common.Address) {
+	c.maintLock.Lock()
+	defer c.maintLock.Unlock()
+
+	for s, last := range c.maintenance {
+		if last < number {
+			delete(c.maintenance, s)
+		}
+	}
+	if until > c.maintenance[signer] {
+		c.maintenance[signer] = until
+		log.Debug("Signer announced maintenance", "signer", signer, "number", number, "until", until)
+	}
+}
+
+// inMaintenance returns whether a signer announced to skip the slot of the
+// given block.
+func (c *Clique) inMaintenance(signer common.Address, number uint64) bool {
+	c.maintLock.Lock()
+	defer c.maintLock.Unlock()
+
+	return number <= c.maintenance[signer]
+}
+
+// outOfTurnWiggle returns the maximum random delay of an out-of-turn block. It
+// is reduced to a single wiggle if the in-turn signer announced a maintenance,
+// as it won't seal the block anyway.
+func (c *Clique) outOfTurnWiggle(snap *Snapshot, number uint64) time.Duration {
+	wiggle := c.wiggle()
+	signers := snap.signers()
+	if len(signers) > 0 && c.inMaintenance(signers[number%uint64(len(signers))], number) {
+		return wiggle
+	}
+	return time.Duration(len(snap.Signers)/2+1) * wiggle
+}
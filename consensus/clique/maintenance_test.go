@@ -0,0 +1,92 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a pending maintenance is announced in the header vanity, picked up
+// by the other signers to reduce the wiggle of the skipped slots.
+func TestMaintenanceAnnouncement(t *testing.T) {
+	var (
+		signers = []common.Address{{0x01}, {0x02}, {0x03}, {0x04}}
+		engine  = New(&params.CliqueConfig{Period: 5}, rawdb.NewMemoryDatabase())
+	)
+	if err := engine.EnterMaintenance(10); err != errNotSigner {
+		t.Fatalf("maintenance without signer: have %v, want %v", err, errNotSigner)
+	}
+	engine.Authorize(signers[1], nil)
+	if err := engine.EnterMaintenance(0); err == nil {
+		t.Fatalf("empty maintenance accepted")
+	}
+	if err := engine.EnterMaintenance(10); err != nil {
+		t.Fatalf("failed to enter maintenance: %v", err)
+	}
+	// Epoch proposals are left alone, plain blocks carry the announcement
+	epoch := &types.Header{Number: big.NewInt(20), Extra: make([]byte, extraVanity+common.AddressLength+extraSeal)}
+	engine.prepareMaintenance(epoch)
+	if _, ok := maintenanceHint(epoch); ok {
+		t.Fatalf("maintenance announced in epoch block")
+	}
+	header := &types.Header{Number: big.NewInt(20), Extra: make([]byte, extraVanity+extraSeal)}
+	engine.prepareMaintenance(header)
+	if until, ok := maintenanceHint(header); !ok || until != 30 {
+		t.Fatalf("announcement mismatch: have %d (%v), want 30", until, ok)
+	}
+	// Announcements beyond the longest maintenance are ignored
+	forged := &types.Header{Number: big.NewInt(20), Extra: make([]byte, extraVanity+extraSeal)}
+	copy(forged.Extra, maintenanceMagic)
+	binary.BigEndian.PutUint64(forged.Extra[len(maintenanceMagic):], 21+maxMaintenanceBlocks)
+	if _, ok := maintenanceHint(forged); ok {
+		t.Fatalf("overlong maintenance announcement accepted")
+	}
+	binary.BigEndian.PutUint64(forged.Extra[len(maintenanceMagic):], 20+maxMaintenanceBlocks)
+	if _, ok := maintenanceHint(forged); !ok {
+		t.Fatalf("longest maintenance announcement rejected")
+	}
+	// Sealing the announcement starts the maintenance
+	engine.sealedMaintenance(header, signers[1])
+	if status := engine.Maintenance(); status.Pending != 0 || status.Until != 30 {
+		t.Fatalf("maintenance status mismatch: %+v", status)
+	}
+	// Slots of the signer in maintenance get a reduced wiggle, others the full one
+	snap := &Snapshot{Signers: make(map[common.Address]bool)}
+	for _, signer := range signers {
+		snap.Signers[signer] = true
+	}
+	if wiggle := engine.outOfTurnWiggle(snap, 21); wiggle != wiggleTime {
+		t.Errorf("skipped slot wiggle mismatch: have %v, want %v", wiggle, wiggleTime)
+	}
+	if wiggle := engine.outOfTurnWiggle(snap, 22); wiggle != 3*wiggleTime {
+		t.Errorf("regular slot wiggle mismatch: have %v, want %v", wiggle, 3*wiggleTime)
+	}
+	if wiggle := engine.outOfTurnWiggle(snap, 33); wiggle != 3*wiggleTime {
+		t.Errorf("slot after maintenance wiggle mismatch: have %v, want %v", wiggle, 3*wiggleTime)
+	}
+	engine.ExitMaintenance()
+	if status := engine.Maintenance(); status.Until != 0 {
+		t.Fatalf("maintenance not exited: %+v", status)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxOutageBlocks is the maximum number of blocks an outage simulation covers.
+const maxOutageBlocks = 100000
+
+// outageSimulation is the expected sealing behaviour of the chain with a set of
+// signers offline.
+type outageSimulation struct {
+	Epoch          uint64                 `json:"epoch"` // Epoch whose signer set the rotation is computed from
+	Head           uint64                 `json:"head"`
+	Signers        int                    `json:"signers"`
+	Offline        []common.Address       `json:"offline"`
+	Blocks         uint64                 `json:"blocks"`              // Number of blocks sealed in the simulation
+	Halted         bool                   `json:"halted"`              // Whether the online signers can't keep the chain going
+	HaltBlock      *uint64                `json:"haltBlock,omitempty"` // First block no online signer may seal
+	InturnPercent  float64                `json:"inturnPercent"`
+	BaseBlockTime  float64                `json:"baseBlockTime"`  // Block interval in seconds with every signer online
+	BlockTime      float64                `json:"blockTime"`      // Expected average block interval in seconds
+	Degradation    float64                `json:"degradation"`    // Block interval increase in percent
+	OutOfTurnDelay float64                `json:"outOfTurnDelay"` // Expected average delay of out-of-turn blocks in seconds
+	SigningStatus  map[common.Address]int `json:"sealerActivity"`
+}
+
+// SimulateOutage computes the expected block time degradation and in-turn
+// percentage over the next blocks if the given signers were offline. The
+// rotation and recently-signed rules are applied to the signer set and recent
+// signers of the current snapshot, so the result only holds until the epoch
+// ends. Out-of-turn blocks are assumed to be sealed by the eligible signer that
+// signed the longest ago, after the expected fastest random wiggle among all
+// eligible signers.
+func (api *API) SimulateOutage(offline []common.Address, blocks uint64) (*outageSimulation, error) {
+	if blocks == 0 || blocks > maxOutageBlocks {
+		return nil, errInvalidLimit(blocks, maxOutageBlocks, fmt.Sprintf("invalid block count %d, must be between 1 and %d", blocks, maxOutageBlocks))
+	}
+	header := api.chain.CurrentHeader()
+	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	down := make(map[common.Address]bool, len(offline))
+	for _, signer := range offline {
+		if _, ok := snap.Signers[signer]; !ok {
+			return nil, errNotValidator(snap.EpochNumber, signer)
+		}
+		down[signer] = true
+	}
+	head := header.Number.Uint64()
+
+	period := float64(api.clique.config.Period)
+	if period == 0 && head > snap.Number {
+		first := api.chain.GetHeaderByNumber(snap.Number)
+		if first == nil {
+			return nil, errMissingBlock(snap.Number)
+		}
+		period = float64(header.Time-first.Time) / float64(head-snap.Number)
+	}
+	return snap.simulateOutage(head, down, blocks, period, api.clique.wiggle()), nil
+}
+
+// simulateOutage seals up to count blocks on top of head with the signers of
+// the snapshot, skipping the offline ones. The period is the interval between
+// in-turn blocks in seconds, the wiggle the random delay per signer of
+// out-of-turn blocks.
+func (s *Snapshot) simulateOutage(head uint64, offline map[common.Address]bool, count uint64, period float64, wiggle time.Duration) *outageSimulation {
+	var (
+		signers = s.signers()
+		limit   = uint64(len(signers)/2 + 1)
+		spread  = float64(limit) * wiggle.Seconds()
+		last    = make(map[common.Address]uint64) // Last block sealed by each signer
+		sim     = &outageSimulation{
+			Epoch:         s.EpochNumber,
+			Head:          head,
+			Signers:       len(signers),
+			Offline:       []common.Address{},
+			BaseBlockTime: period,
+			SigningStatus: make(map[common.Address]int),
+		}
+	)
+	for _, signer := range signers {
+		sim.SigningStatus[signer] = 0
+		if offline[signer] {
+			sim.Offline = append(sim.Offline, signer)
+		}
+	}
+	for number, signer := range s.Recents {
+		if number > last[signer] {
+			last[signer] = number
+		}
+	}
+	eligible := func(signer common.Address, number uint64) bool {
+		seen, ok := last[signer]
+		return !offline[signer] && (!ok || seen+limit <= number)
+	}
+	var (
+		inturns  uint64
+		elapsed  float64
+		delays   float64
+		outturns uint64
+	)
+	for number := head + 1; number <= head+count && len(signers) > 0; number++ {
+		sealer := signers[number%uint64(len(signers))]
+		if eligible(sealer, number) {
+			inturns++
+			elapsed += period
+		} else {
+			// Pick the eligible signer which sealed the longest ago, the others
+			// racing it only shorten the expected wiggle
+			var (
+				racers int
+				found  bool
+			)
+			for _, signer := range signers {
+				if !eligible(signer, number) {
+					continue
+				}
+				racers++
+				if !found || last[signer] < last[sealer] {
+					sealer, found = signer, true
+				}
+			}
+			if !found {
+				halt := number
+				sim.Halted, sim.HaltBlock = true, &halt
+				break
+			}
+			delay := spread / float64(racers+1)
+			elapsed += period + delay
+			delays += delay
+			outturns++
+		}
+		last[sealer] = number
+		sim.SigningStatus[sealer]++
+		sim.Blocks++
+	}
+	if sim.Blocks > 0 {
+		sim.InturnPercent = float64(100*inturns) / float64(sim.Blocks)
+		sim.BlockTime = elapsed / float64(sim.Blocks)
+	}
+	if outturns > 0 {
+		sim.OutOfTurnDelay = delays / float64(outturns)
+	}
+	if period > 0 {
+		sim.Degradation = 100 * (sim.BlockTime - period) / period
+	}
+	return sim
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that outage simulations follow the rotation and recently-signed rules.
+func TestSimulateOutage(t *testing.T) {
+	snap := &Snapshot{
+		Signers: make(map[common.Address]bool),
+		Recents: make(map[uint64]common.Address),
+	}
+	signers := make([]common.Address, 5)
+	for i := range signers {
+		signers[i] = common.BytesToAddress([]byte{byte(i + 1)})
+		snap.Signers[signers[i]] = true
+	}
+	// Every signer online seals every block in-turn
+	sim := snap.simulateOutage(100, nil, 50, 5, wiggleTime)
+	if sim.Halted || sim.Blocks != 50 || sim.InturnPercent != 100 || sim.BlockTime != 5 || sim.Degradation != 0 {
+		t.Errorf("all online: halted %v, blocks %d, inturn %v, block time %v, degradation %v", sim.Halted, sim.Blocks, sim.InturnPercent, sim.BlockTime, sim.Degradation)
+	}
+	// A single signer offline keeps the chain going, but slower
+	sim = snap.simulateOutage(100, map[common.Address]bool{signers[0]: true}, 50, 5, wiggleTime)
+	if sim.Halted || sim.Blocks != 50 {
+		t.Fatalf("one offline: halted %v, blocks %d", sim.Halted, sim.Blocks)
+	}
+	if sim.InturnPercent >= 100 || sim.BlockTime <= 5 || sim.Degradation <= 0 || sim.OutOfTurnDelay <= 0 {
+		t.Errorf("one offline: inturn %v, block time %v, degradation %v, delay %v", sim.InturnPercent, sim.BlockTime, sim.Degradation, sim.OutOfTurnDelay)
+	}
+	if have := sim.SigningStatus[signers[0]]; have != 0 {
+		t.Errorf("offline signer sealed %d blocks", have)
+	}
+	if len(sim.Offline) != 1 || sim.Offline[0] != signers[0] {
+		t.Errorf("offline signers mismatch: have %v", sim.Offline)
+	}
+	// Less online signers than the recently-signed window halts the chain
+	sim = snap.simulateOutage(100, map[common.Address]bool{signers[0]: true, signers[1]: true, signers[2]: true}, 50, 5, wiggleTime)
+	if !sim.Halted || sim.HaltBlock == nil || sim.Blocks >= 50 {
+		t.Fatalf("majority offline: halted %v, blocks %d", sim.Halted, sim.Blocks)
+	}
+	if *sim.HaltBlock != 101+sim.Blocks {
+		t.Errorf("halt block mismatch: have %d, want %d", *sim.HaltBlock, 101+sim.Blocks)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// maxSealingLog is the number of sealing pauses retained for audit.
+const maxSealingLog = 256
+
+// sealingLogKey tracks the recent sealing pauses of the local signer as JSON.
+var sealingLogKey = []byte("CliqueSealingLog")
+
+var (
+	// errSealingPaused is returned when sealing a block while the operator paused
+	// the sealing.
+	errSealingPaused = errors.New("sealing paused")
+
+	// errNotPaused is returned when resuming the sealing while it isn't paused.
+	errNotPaused = errors.New("sealing not paused")
+
+	// errPauseReason is returned when pausing the sealing without a reason.
+	errPauseReason = errors.New("pause reason required")
+)
+
+// SealingPause is a window during which the operator paused the sealing of the
+// local signer.
+type SealingPause struct {
+	Reason     string     `json:"reason"`
+	Start      time.Time  `json:"start"`
+	StartBlock uint64     `json:"startBlock"`         // Head when the sealing was paused
+	End        *time.Time `json:"end,omitempty"`      // Unset while the pause is ongoing
+	EndBlock   *uint64    `json:"endBlock,omitempty"` // Head when the sealing was resumed
+	Skipped    uint64     `json:"skipped"`            // Number of blocks the signer refused to seal meanwhile
+	lastSkip   uint64     // Last block refused, counting every block once
+}
+
+// loadSealingLog retrieves the recorded sealing pauses from the database.
+func loadSealingLog(db ethdb.KeyValueReader) []*SealingPause {
+	blob, err := db.Get(sealingLogKey)
+	if err != nil {
+		return nil
+	}
+	var pauses []*SealingPause
+	if err := json.Unmarshal(blob, &pauses); err != nil {
+		log.Warn("Failed to decode sealing log", "err", err)
+		return nil
+	}
+	return pauses
+}
+
+// storeSealingLog persists the sealing pauses into the database.
+func storeSealingLog(db ethdb.KeyValueWriter, pauses []*SealingPause) {
+	blob, err := json.Marshal(pauses)
+	if err != nil {
+		log.Crit("Failed to encode sealing log", "err", err)
+	}
+	if err := db.Put(sealingLogKey, blob); err != nil {
+		log.Crit("Failed to store sealing log", "err", err)
+	}
+}
+
+// pausedLocked returns the ongoing sealing pause, if any. The caller must hold
+// the lock.
+func (c *Clique) pausedLocked() *SealingPause {
+	if n := len(c.sealingLog); n > 0 && c.sealingLog[n-1].End == nil {
+		return c.sealingLog[n-1]
+	}
+	return nil
+}
+
+// PauseSealing stops the local signer from taking its slots, while blocks keep
+// being imported, until the sealing is resumed. The pause and its reason are
+// recorded for audit and survive restarts.
+func (c *Clique) PauseSealing(head uint64, reason string) (*SealingPause, error) {
+	if reason == "" {
+		return nil, errPauseReason
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if pause := c.pausedLocked(); pause != nil {
+		return nil, fmt.Errorf("%w since block %d: %s", errSealingPaused, pause.StartBlock, pause.Reason)
+	}
+	pause := &SealingPause{Reason: reason, Start: time.Now(), StartBlock: head}
+	c.sealingLog = append(c.sealingLog, pause)
+	if len(c.sealingLog) > maxSealingLog {
+		c.sealingLog = c.sealingLog[len(c.sealingLog)-maxSealingLog:]
+	}
+	storeSealingLog(c.db, c.sealingLog)
+
+	log.Warn("Paused sealing", "head", head, "reason", reason)
+	cpy := *pause
+	return &cpy, nil
+}
+
+// ResumeSealing ends the ongoing sealing pause, taking the slots of the local
+// signer again from the next block on.
+func (c *Clique) ResumeSealing(head uint64) (*SealingPause, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	pause := c.pausedLocked()
+	if pause == nil {
+		return nil, errNotPaused
+	}
+	end := time.Now()
+	pause.End, pause.EndBlock = &end, &head
+	storeSealingLog(c.db, c.sealingLog)
+
+	log.Info("Resumed sealing", "head", head, "paused", common.PrettyDuration(end.Sub(pause.Start)), "skipped", pause.Skipped)
+	cpy := *pause
+	return &cpy, nil
+}
+
+// SealingLog returns the recorded sealing pauses, oldest first.
+func (c *Clique) SealingLog() []*SealingPause {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	pauses := make([]*SealingPause, len(c.sealingLog))
+	for i, pause := range c.sealingLog {
+		cpy := *pause
+		pauses[i] = &cpy
+	}
+	return pauses
+}
+
+// SealingPaused reports whether the sealing of the local signer is paused.
+func (c *Clique) SealingPaused() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.pausedLocked() != nil
+}
+
+// sealingPaused returns an error if the sealing is paused, counting the block
+// as skipped.
+func (c *Clique) sealingPaused(number uint64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	pause := c.pausedLocked()
+	if pause == nil {
+		return nil
+	}
+	if number > pause.lastSkip {
+		pause.Skipped, pause.lastSkip = pause.Skipped+1, number
+	}
+	return fmt.Errorf("%w: %s", errSealingPaused, pause.Reason)
+}
+
+// PauseAPI is the private API to pause the sealing of the local signer, served
+// over IPC and only over HTTP or websocket if the clique namespace is enabled.
+type PauseAPI struct {
+	chain  consensus.ChainHeaderReader
+	clique *Clique
+}
+
+// PauseSealing stops the local signer from taking its slots until resumed,
+// recording the reason for audit. Blocks keep being imported meanwhile.
+func (api *PauseAPI) PauseSealing(reason string) (*SealingPause, error) {
+	return api.clique.PauseSealing(api.chain.CurrentHeader().Number.Uint64(), reason)
+}
+
+// ResumeSealing ends the ongoing sealing pause.
+func (api *PauseAPI) ResumeSealing() (*SealingPause, error) {
+	return api.clique.ResumeSealing(api.chain.CurrentHeader().Number.Uint64())
+}
+
+// GetSealingLog returns the recorded sealing pauses with their reasons, oldest
+// first.
+func (api *API) GetSealingLog() []*SealingPause {
+	return api.clique.SealingLog()
+}
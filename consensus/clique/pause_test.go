@@ -0,0 +1,74 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that sealing pauses refuse the slots of the local signer until resumed,
+// and that they're recorded with their reasons across restarts.
+func TestSealingPause(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = New(&params.CliqueConfig{Period: 5}, db)
+	)
+	if _, err := engine.PauseSealing(10, ""); err != errPauseReason {
+		t.Fatalf("pause without reason error mismatch: have %v, want %v", err, errPauseReason)
+	}
+	if _, err := engine.ResumeSealing(10); err != errNotPaused {
+		t.Fatalf("resume without pause error mismatch: have %v, want %v", err, errNotPaused)
+	}
+	if _, err := engine.PauseSealing(10, "key rotation"); err != nil {
+		t.Fatalf("failed to pause sealing: %v", err)
+	}
+	if _, err := engine.PauseSealing(11, "again"); !errors.Is(err, errSealingPaused) {
+		t.Fatalf("double pause error mismatch: have %v, want %v", err, errSealingPaused)
+	}
+	// Every refused block is counted once, however often it's retried
+	for _, number := range []uint64{11, 11, 12, 13} {
+		if err := engine.sealingPaused(number); !errors.Is(err, errSealingPaused) {
+			t.Fatalf("block %d sealing error mismatch: have %v, want %v", number, err, errSealingPaused)
+		}
+	}
+	// The ongoing pause survives a restart
+	engine = New(&params.CliqueConfig{Period: 5}, db)
+	if err := engine.sealingPaused(14); !errors.Is(err, errSealingPaused) {
+		t.Fatalf("pause lost across restart: %v", err)
+	}
+	pause, err := engine.ResumeSealing(14)
+	if err != nil {
+		t.Fatalf("failed to resume sealing: %v", err)
+	}
+	if pause.Reason != "key rotation" || pause.StartBlock != 10 || pause.EndBlock == nil || *pause.EndBlock != 14 || pause.End == nil {
+		t.Fatalf("resumed pause mismatch: %+v", pause)
+	}
+	if err := engine.sealingPaused(15); err != nil {
+		t.Fatalf("resumed sealing refused: %v", err)
+	}
+	if _, err := engine.PauseSealing(20, "upgrade"); err != nil {
+		t.Fatalf("failed to pause sealing again: %v", err)
+	}
+	pauses := engine.SealingLog()
+	if len(pauses) != 2 || pauses[0].Reason != "key rotation" || pauses[1].Reason != "upgrade" || pauses[1].End != nil {
+		t.Fatalf("sealing log mismatch: %+v", pauses)
+	}
+}
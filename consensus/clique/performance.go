@@ -0,0 +1,246 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// errInvalidPerformanceRoot is returned if an epoch block past the performance
+// commitment fork doesn't carry the validator performance root of the closing
+// epoch in its vanity.
+var errInvalidPerformanceRoot = errors.New("invalid validator performance root on epoch block")
+
+// performanceLeaf hashes the sealed block count of a validator in an epoch. The
+// leaf is the keccak256 of the tightly packed (uint64 epoch, address validator,
+// uint64 count) tuple, which contracts can rebuild with abi.encodePacked.
+func performanceLeaf(epoch uint64, validator common.Address, count uint64) common.Hash {
+	var blob [8 + common.AddressLength + 8]byte
+	binary.BigEndian.PutUint64(blob[:8], epoch)
+	copy(blob[8:], validator[:])
+	binary.BigEndian.PutUint64(blob[8+common.AddressLength:], count)
+	return crypto.Keccak256Hash(blob[:])
+}
+
+// hashPair hashes two sibling nodes in ascending order, so proofs can be checked
+// without knowing the position of the leaf.
+func hashPair(a, b common.Hash) common.Hash {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return crypto.Keccak256Hash(a[:], b[:])
+}
+
+// performanceTree builds the merkle tree over the given leaves, returning all its
+// levels from the leaves up to the root. The last node of an odd level is carried
+// up unhashed.
+func performanceTree(leaves []common.Hash) [][]common.Hash {
+	levels := [][]common.Hash{leaves}
+	for level := leaves; len(level) > 1; {
+		next := make([]common.Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, hashPair(level[i], level[i+1]))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+// performanceLeaves computes the leaves of an epoch's performance tree, one per
+// validator in ascending address order.
+func performanceLeaves(epoch uint64, validators []common.Address, counts map[common.Address]uint64) []common.Hash {
+	leaves := make([]common.Hash, len(validators))
+	for i, validator := range validators {
+		leaves[i] = performanceLeaf(epoch, validator, counts[validator])
+	}
+	return leaves
+}
+
+// performanceRoot returns the merkle root committing to the sealed block counts
+// of the given validators in an epoch. An empty validator set commits to the
+// zero hash.
+func performanceRoot(epoch uint64, validators []common.Address, counts map[common.Address]uint64) common.Hash {
+	if len(validators) == 0 {
+		return common.Hash{}
+	}
+	levels := performanceTree(performanceLeaves(epoch, validators, counts))
+	return levels[len(levels)-1][0]
+}
+
+// performanceProof returns the sibling hashes proving the leaf at the given
+// index, from the bottom of the tree up.
+func performanceProof(levels [][]common.Hash, index int) []common.Hash {
+	var proof []common.Hash
+	for _, level := range levels[:len(levels)-1] {
+		if sibling := index ^ 1; sibling < len(level) {
+			proof = append(proof, level[sibling])
+		}
+		index /= 2
+	}
+	return proof
+}
+
+// verifyPerformanceProof checks a proof of a leaf against a performance root.
+func verifyPerformanceProof(root, leaf common.Hash, proof []common.Hash) bool {
+	for _, sibling := range proof {
+		leaf = hashPair(leaf, sibling)
+	}
+	return leaf == root
+}
+
+// epochTally counts the blocks sealed by each validator of the epoch opened by
+// the given snapshot, from its epoch block up to and including the block with
+// the given number and hash. The headers are walked back by parent hash, so the
+// tally is also correct for side chains and batches of not yet imported parents.
+func (c *Clique) epochTally(chain consensus.ChainHeaderReader, snap *Snapshot, number uint64, hash common.Hash, parents []*types.Header) (map[common.Address]uint64, error) {
+	counts := make(map[common.Address]uint64)
+	for number >= snap.Number {
+		var header *types.Header
+		if len(parents) > 0 && parents[len(parents)-1].Number.Uint64() == number {
+			header, parents = parents[len(parents)-1], parents[:len(parents)-1]
+		} else {
+			header = chain.GetHeader(hash, number)
+		}
+		if header == nil || header.Hash() != hash {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		// The genesis block is not sealed, nobody gets credited for it
+		if number == 0 {
+			break
+		}
+		if entry := readSealer(c.db, number, hash); entry != nil {
+			counts[entry.Signer]++
+		} else {
+			signer, err := ecrecover(header, c.signatures)
+			if err != nil {
+				return nil, err
+			}
+			counts[signer]++
+		}
+		if number == snap.Number {
+			break
+		}
+		number, hash = number-1, header.ParentHash
+	}
+	return counts, nil
+}
+
+// epochPerformanceRoot computes the performance root an epoch block with the
+// given parent must carry, committing to the epoch opened by the snapshot.
+func (c *Clique) epochPerformanceRoot(chain consensus.ChainHeaderReader, snap *Snapshot, parent uint64, parentHash common.Hash, parents []*types.Header) (common.Hash, error) {
+	counts, err := c.epochTally(chain, snap, parent, parentHash, parents)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return performanceRoot(snap.EpochNumber, snap.signers(), counts), nil
+}
+
+// performanceProofResult is the merkle proof of a validator's performance in a
+// closed epoch.
+type performanceProofResult struct {
+	Epoch       uint64         `json:"epoch"`       // Epoch the performance is proven for
+	EpochBlock  uint64         `json:"epochBlock"`  // Block which opened the epoch
+	CommitBlock uint64         `json:"commitBlock"` // Epoch block which closed the epoch and carries the root
+	Committed   bool           `json:"committed"`   // Whether the root is embedded in the commit block
+	Root        common.Hash    `json:"root"`
+	Validator   common.Address `json:"validator"`
+	Count       uint64         `json:"count"` // Number of blocks sealed by the validator in the epoch
+	Leaf        common.Hash    `json:"leaf"`
+	Index       int            `json:"index"` // Position of the leaf among the validators in ascending order
+	Proof       []common.Hash  `json:"proof"` // Sibling hashes from the leaf up to the root
+}
+
+// GetPerformanceProof returns the merkle proof of the number of blocks sealed by
+// a validator in a closed epoch, against the root committed to by the epoch block
+// which closed it.
+func (api *API) GetPerformanceProof(epoch uint64, validator common.Address) (*performanceProofResult, error) {
+	header := api.chain.CurrentHeader()
+	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if snap.EpochNumber <= epoch {
+		return nil, errEpochNotClosed(epoch)
+	}
+	// Walk back the epochs until the one requested, tracking the block closing it
+	closing := snap
+	for {
+		if closing.PreviousSnapNumber == nil || closing.PreviousSnapHash == nil {
+			return nil, errEpochNotFound(epoch)
+		}
+		prev, err := api.clique.snapshot(api.chain, *closing.PreviousSnapNumber, *closing.PreviousSnapHash, nil)
+		if err != nil {
+			return nil, err
+		}
+		if prev.EpochNumber == epoch {
+			snap = prev
+			break
+		}
+		if prev.EpochNumber < epoch {
+			return nil, errEpochNotFound(epoch)
+		}
+		closing = prev
+	}
+	commit := api.chain.GetHeader(closing.Hash, closing.Number)
+	if commit == nil {
+		return nil, errMissingBlock(closing.Number)
+	}
+	counts, err := api.clique.epochTally(api.chain, snap, commit.Number.Uint64()-1, commit.ParentHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		validators = snap.signers()
+		index      = -1
+	)
+	for i, v := range validators {
+		if v == validator {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, errNotValidator(epoch, validator)
+	}
+	levels := performanceTree(performanceLeaves(snap.EpochNumber, validators, counts))
+	root := levels[len(levels)-1][0]
+
+	return &performanceProofResult{
+		Epoch:       snap.EpochNumber,
+		EpochBlock:  snap.Number,
+		CommitBlock: closing.Number,
+		Committed:   api.clique.config.IsPerformanceCommit(commit.Number) && common.BytesToHash(commit.Extra[:extraVanity]) == root,
+		Root:        root,
+		Validator:   validator,
+		Count:       counts[validator],
+		Leaf:        levels[0][index],
+		Index:       index,
+		Proof:       performanceProof(levels, index),
+	}, nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that performance proofs verify against the root for every validator
+// and every tree shape, and that tampered counts are rejected.
+func TestPerformanceProof(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		var (
+			validators = make([]common.Address, n)
+			counts     = make(map[common.Address]uint64)
+		)
+		for i := range validators {
+			validators[i] = common.BytesToAddress([]byte{byte(i + 1)})
+			counts[validators[i]] = uint64(i * 7)
+		}
+		levels := performanceTree(performanceLeaves(3, validators, counts))
+		root := performanceRoot(3, validators, counts)
+		if levels[len(levels)-1][0] != root {
+			t.Fatalf("validators %d: tree root mismatch", n)
+		}
+		for i, validator := range validators {
+			proof := performanceProof(levels, i)
+			if !verifyPerformanceProof(root, performanceLeaf(3, validator, counts[validator]), proof) {
+				t.Errorf("validators %d, index %d: valid proof rejected", n, i)
+			}
+			if verifyPerformanceProof(root, performanceLeaf(3, validator, counts[validator]+1), proof) {
+				t.Errorf("validators %d, index %d: tampered count accepted", n, i)
+			}
+			if verifyPerformanceProof(root, performanceLeaf(4, validator, counts[validator]), proof) {
+				t.Errorf("validators %d, index %d: wrong epoch accepted", n, i)
+			}
+		}
+	}
+	if root := performanceRoot(1, nil, nil); root != (common.Hash{}) {
+		t.Errorf("empty validator set root mismatch: have %x, want zero", root)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// errSignerPolicy is returned if an epoch block applies a signer set change
+// violating the signer policy of the chain.
+var errSignerPolicy = errors.New("signer set change violates policy")
+
+// authorizes returns whether the signer set authorizes any signer not in the
+// snapshot yet.
+func (s *Snapshot) authorizes(signers map[common.Address]bool) bool {
+	for signer := range signers {
+		if !s.Signers[signer] {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSignerPolicy returns an error if applying the signer set by the epoch
+// block on top of the snapshot of its parent violates the signer policy.
+func checkSignerPolicy(config *params.CliqueConfig, snap *Snapshot, number uint64, signers map[common.Address]bool) error {
+	if !config.IsSignerPolicy(new(big.Int).SetUint64(number)) {
+		return nil
+	}
+	policy := config.SignerPolicy
+	if size := uint64(len(signers)); policy.MinSigners != 0 && size < policy.MinSigners {
+		return fmt.Errorf("%w: %d signers, minimum %d", errSignerPolicy, size, policy.MinSigners)
+	}
+	if size := uint64(len(signers)); policy.MaxSigners != 0 && size > policy.MaxSigners {
+		return fmt.Errorf("%w: %d signers, maximum %d", errSignerPolicy, size, policy.MaxSigners)
+	}
+	if policy.Cooldown == 0 || !snap.authorizes(signers) {
+		return nil
+	}
+	// Authorizations before the policy was enforced don't count towards it
+	last := snap.LastAuthorization
+	if last < policy.Block.Uint64() {
+		last = 0
+	}
+	if last == 0 && cooldownUnknown(config, snap, number) {
+		log.Warn("Signer policy cooldown unknown, not enforced", "number", number, "checkpoint", snap.AuthorizationsFrom)
+		return nil
+	}
+	if last != 0 && number < last+policy.Cooldown {
+		return fmt.Errorf("%w: authorization %d blocks after the previous one at block %d, cooldown %d", errSignerPolicy, number-last, last, policy.Cooldown)
+	}
+	return nil
+}
+
+// cooldownUnknown returns whether the snapshot was bootstrapped from a trusted
+// checkpoint too recently to tell if an authorization before it still holds the
+// cooldown at the given block.
+func cooldownUnknown(config *params.CliqueConfig, snap *Snapshot, number uint64) bool {
+	return snap.AuthorizationsFrom != 0 && number < snap.AuthorizationsFrom+config.SignerPolicy.Cooldown
+}
+
+// deriveLastAuthorization derives the last authorization of a snapshot created
+// at a trusted checkpoint from the epoch headers of the cooldown preceding it,
+// reporting false if they aren't available.
+func (c *Clique) deriveLastAuthorization(chain consensus.ChainHeaderReader, checkpoint *types.Header) (uint64, bool) {
+	number := checkpoint.Number.Uint64()
+	if number == 0 || !c.config.IsSignerPolicy(checkpoint.Number) {
+		return 0, true
+	}
+	// Authorizations up to the start of the window have run out of cooldown
+	policy := c.config.SignerPolicy
+	start := policy.Block.Uint64()
+	if number+2 > policy.Cooldown && number+2-policy.Cooldown > start {
+		start = number + 2 - policy.Cooldown
+	}
+	header := checkpoint
+	for {
+		// Find the epoch block preceding the header and its signer set
+		parent := header
+		for {
+			if parent = chain.GetHeader(parent.ParentHash, parent.Number.Uint64()-1); parent == nil {
+				return 0, false
+			}
+			if parent.Number.Uint64() == 0 || !bytes.Equal(parent.Nonce[:], nonceDropVote) {
+				break
+			}
+		}
+		var prev map[common.Address]bool
+		if parent.Number.Uint64() == 0 {
+			dnr, err := GetDNR(c.db, parent.Nonce.Uint64())
+			if err != nil {
+				return 0, false
+			}
+			prev = dnr.Validators
+		} else {
+			_, list, _ := EpochSigners(parent)
+			prev = make(map[common.Address]bool, len(list))
+			for _, signer := range list {
+				prev[signer] = true
+			}
+		}
+		_, list, _ := EpochSigners(header)
+		for _, signer := range list {
+			if !prev[signer] {
+				return header.Number.Uint64(), true
+			}
+		}
+		if parent.Number.Uint64() == 0 || parent.Number.Uint64() < start {
+			return 0, true
+		}
+		header = parent
+	}
+}
+
+// signerPolicyStatus is the signer policy of the chain along with the verdict on
+// the registry epoch pending to be applied.
+type signerPolicyStatus struct {
+	Policy            *params.CliqueSignerPolicy `json:"policy"`            // Signer policy of the chain, unset if none
+	Enforced          bool                       `json:"enforced"`          // Whether the policy is enforced for the next block
+	Signers           int                        `json:"signers"`           // Size of the signer set at the head
+	LastAuthorization uint64                     `json:"lastAuthorization"` // Last epoch block authorizing new signers whose cooldown still runs, zero if none
+	CooldownUnknown   bool                       `json:"cooldownUnknown"`   // Whether the cooldown isn't enforced, as the node was bootstrapped too recently to know it
+	Pending           *uint64                    `json:"pending,omitempty"` // Registry epoch pending to be applied, if any
+	Violation         string                     `json:"violation,omitempty"`
+}
+
+// GetSignerPolicy returns the signer policy of the chain and whether the
+// registry epoch pending to be applied violates it. Violating epochs are not
+// proposed by the local signer and rejected if proposed by others.
+func (api *API) GetSignerPolicy() (*signerPolicyStatus, error) {
+	header := api.chain.CurrentHeader()
+	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	next := header.Number.Uint64() + 1
+	status := &signerPolicyStatus{
+		Policy:            api.clique.config.SignerPolicy,
+		Enforced:          api.clique.config.IsSignerPolicy(new(big.Int).SetUint64(next)),
+		Signers:           len(snap.Signers),
+		LastAuthorization: snap.LastAuthorization,
+	}
+	if status.Policy != nil {
+		status.CooldownUnknown = snap.LastAuthorization == 0 && cooldownUnknown(api.clique.config, snap, next)
+	}
+	dnr, err := GetLatestDNR(api.clique.db)
+	if err != nil {
+		return nil, err
+	}
+	if snap.EpochNumber < dnr.LastEpochBlock {
+		epoch := dnr.LastEpochBlock
+		status.Pending = &epoch
+		if err := checkSignerPolicy(api.clique.config, snap, next, dnr.Validators); err != nil {
+			status.Violation = err.Error()
+		}
+	}
+	return status, nil
+}
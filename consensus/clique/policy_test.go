@@ -0,0 +1,205 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that signer set changes are checked against the size limits and the
+// cooldown between authorizations once the policy is enforced.
+func TestSignerPolicy(t *testing.T) {
+	config := &params.CliqueConfig{
+		Period: 5,
+		SignerPolicy: &params.CliqueSignerPolicy{
+			Block:      big.NewInt(100),
+			MinSigners: 3,
+			MaxSigners: 5,
+			Cooldown:   50,
+		},
+	}
+	set := func(ids ...byte) map[common.Address]bool {
+		signers := make(map[common.Address]bool)
+		for _, id := range ids {
+			signers[common.Address{id}] = true
+		}
+		return signers
+	}
+	snap := newSnapshot(config, nil, 0, 1, nil, common.Hash{}, nil, set(1, 2, 3, 4))
+
+	// Before the policy block anything goes
+	if err := checkSignerPolicy(config, snap, 99, set(1, 2)); err != nil {
+		t.Fatalf("change before policy block rejected: %v", err)
+	}
+	tests := []struct {
+		signers map[common.Address]bool
+		fail    bool
+	}{
+		{set(1, 2), true},             // Below minimum
+		{set(1, 2, 3), false},         // At minimum
+		{set(1, 2, 3, 4, 5), false},   // At maximum
+		{set(1, 2, 3, 4, 5, 6), true}, // Above maximum
+		{set(1, 2, 3, 5), false},      // Swap without previous authorization
+	}
+	for i, tt := range tests {
+		err := checkSignerPolicy(config, snap, 100, tt.signers)
+		if tt.fail != errors.Is(err, errSignerPolicy) {
+			t.Errorf("test %d: verdict mismatch: have %v, want failure %v", i, err, tt.fail)
+		}
+	}
+	// Authorizations before the policy block are not tracked
+	snap.updateEpoch(&types.Header{Number: big.NewInt(90)}, 2, set(1, 2, 3, 4, 7))
+	if snap.LastAuthorization != 0 {
+		t.Fatalf("authorization before policy block tracked at block %d", snap.LastAuthorization)
+	}
+	snap.updateEpoch(&types.Header{Number: big.NewInt(95)}, 2, set(1, 2, 3, 4))
+
+	// Authorizing new signers starts the cooldown, removals are unaffected
+	snap.updateEpoch(&types.Header{Number: big.NewInt(120)}, 2, set(1, 2, 3, 4, 5))
+	if snap.LastAuthorization != 120 {
+		t.Fatalf("last authorization mismatch: have %d, want 120", snap.LastAuthorization)
+	}
+	if err := checkSignerPolicy(config, snap, 150, set(1, 2, 3, 4, 6)); !errors.Is(err, errSignerPolicy) {
+		t.Errorf("authorization within cooldown accepted: %v", err)
+	}
+	if err := checkSignerPolicy(config, snap, 150, set(1, 2, 3, 4)); err != nil {
+		t.Errorf("removal within cooldown rejected: %v", err)
+	}
+	if err := checkSignerPolicy(config, snap, 170, set(1, 2, 3, 4, 6)); err != nil {
+		t.Errorf("authorization after cooldown rejected: %v", err)
+	}
+	snap.updateEpoch(&types.Header{Number: big.NewInt(150)}, 3, set(1, 2, 3, 4))
+	if snap.LastAuthorization != 120 {
+		t.Errorf("removal moved last authorization: have %d, want 120", snap.LastAuthorization)
+	}
+	// Authorizations are dropped once their cooldown ran out
+	snap.updateEpoch(&types.Header{Number: big.NewInt(200)}, 4, set(1, 2, 3, 4))
+	if snap.LastAuthorization != 0 {
+		t.Errorf("last authorization kept past its cooldown: %d", snap.LastAuthorization)
+	}
+	// Untracked authorizations recorded before the policy block was known
+	// don't count towards the cooldown
+	snap.LastAuthorization = 90
+	if err := checkSignerPolicy(config, snap, 110, set(1, 2, 3, 4, 6)); err != nil {
+		t.Errorf("authorization before policy block enforced: %v", err)
+	}
+	// Snapshots bootstrapped from a trusted checkpoint don't enforce the cooldown
+	// until any authorization before the checkpoint would have run out of it
+	snap.LastAuthorization, snap.AuthorizationsFrom = 0, 300
+	if err := checkSignerPolicy(config, snap, 320, set(1, 2, 3, 4, 6)); err != nil {
+		t.Errorf("unknown cooldown enforced: %v", err)
+	}
+	snap.updateEpoch(&types.Header{Number: big.NewInt(320)}, 5, set(1, 2, 3))
+	if snap.AuthorizationsFrom != 300 {
+		t.Errorf("unknown cooldown forgotten within it")
+	}
+	snap.updateEpoch(&types.Header{Number: big.NewInt(350)}, 6, set(1, 2, 3))
+	if snap.AuthorizationsFrom != 0 {
+		t.Errorf("unknown cooldown kept past it")
+	}
+}
+
+// Tests that the last authorization, deciding the signer policy cooldown, is
+// part of the canonical snapshot encoding, but not where it's known from.
+func TestSignerPolicyRLPHash(t *testing.T) {
+	snap := newSnapshot(&params.CliqueConfig{}, nil, 10, 1, nil, common.Hash{0x01}, nil, map[common.Address]bool{{0x01}: true})
+	want, err := snap.RLPHash()
+	if err != nil {
+		t.Fatalf("failed to hash snapshot: %v", err)
+	}
+	snap.AuthorizationsFrom = 10
+	if have, _ := snap.RLPHash(); have != want {
+		t.Errorf("authorization checkpoint changed snapshot digest: have %x, want %x", have, want)
+	}
+	snap.LastAuthorization = 10
+	if have, _ := snap.RLPHash(); have == want {
+		t.Errorf("last authorization left out of snapshot digest")
+	}
+	blob, _ := rlp.EncodeToBytes(snap)
+	dec := new(Snapshot)
+	if err := rlp.DecodeBytes(blob, dec); err != nil {
+		t.Fatalf("failed to decode snapshot: %v", err)
+	}
+	if dec.LastAuthorization != 10 {
+		t.Errorf("last authorization mismatch: have %d, want 10", dec.LastAuthorization)
+	}
+}
+
+// Tests that nodes bootstrapped from a trusted checkpoint derive the last
+// authorization from the epoch headers of the cooldown preceding it, and agree
+// with the nodes that followed the chain.
+func TestDeriveLastAuthorization(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		config = &params.CliqueConfig{
+			InitialValidators: []common.Address{{0x01}},
+			SignerPolicy:      &params.CliqueSignerPolicy{Block: big.NewInt(0), Cooldown: 5},
+		}
+		epochs = map[uint64][]common.Address{
+			3: {{0x01}, {0x02}},
+			5: {{0x01}},
+			9: {{0x01}},
+		}
+	)
+	NewDNR(config, db)
+	writeTestChain(db, 12, epochs)
+
+	var (
+		engine = New(config, db)
+		chain  = &canonicalReader{db: db}
+	)
+	tests := []struct {
+		number uint64
+		want   uint64
+	}{
+		{3, 3}, // Authorizing checkpoint
+		{5, 3}, // Authorization within the cooldown
+		{9, 0}, // Authorization ran out of cooldown
+	}
+	for _, tt := range tests {
+		last, ok := engine.deriveLastAuthorization(chain, chain.GetHeaderByNumber(tt.number))
+		if !ok || last != tt.want {
+			t.Errorf("checkpoint %d: have %d (%t), want %d", tt.number, last, ok, tt.want)
+		}
+	}
+	// The derived values match the ones tracked from genesis
+	if _, err := RebuildSnapshots(db, config, nil); err != nil {
+		t.Fatalf("failed to build snapshots: %v", err)
+	}
+	for _, tt := range tests {
+		snap, err := loadSnapshot(config, nil, db, tt.number)
+		if err != nil {
+			t.Fatalf("failed to load snapshot %d: %v", tt.number, err)
+		}
+		if snap.LastAuthorization != tt.want {
+			t.Errorf("snapshot %d: last authorization %d, want %d", tt.number, snap.LastAuthorization, tt.want)
+		}
+	}
+	// Without the preceding headers the last authorization is unknown
+	rawdb.DeleteHeader(db, chain.GetHeaderByNumber(4).Hash(), 4)
+	if _, ok := engine.deriveLastAuthorization(chain, chain.GetHeaderByNumber(5)); ok {
+		t.Errorf("last authorization derived without the preceding headers")
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// lastSealedKey tracks the last block signed by the local signer as JSON, the
+// slash protection record of the datadir.
+var lastSealedKey = []byte("CliqueLastSealed")
+
+// errPreflight is returned when sealing a block before the startup preflight
+// of the validator passed.
+var errPreflight = errors.New("preflight checks pending")
+
+// LastSealed is the last block signed by the local signer.
+type LastSealed struct {
+	Signer common.Address `json:"signer"`
+	Number uint64         `json:"number"`
+	Hash   common.Hash    `json:"hash"` // Seal hash of the block signed
+}
+
+// ReadLastSealed retrieves the last block signed by the local signer, nil if
+// none was signed from this datadir.
+func ReadLastSealed(db ethdb.KeyValueReader) *LastSealed {
+	blob, err := db.Get(lastSealedKey)
+	if err != nil || len(blob) == 0 {
+		return nil
+	}
+	sealed := new(LastSealed)
+	if err := json.Unmarshal(blob, sealed); err != nil {
+		log.Warn("Failed to decode last sealed block", "err", err)
+		return nil
+	}
+	return sealed
+}
+
+// writeLastSealed records a block as signed by the local signer.
+func writeLastSealed(db ethdb.KeyValueWriter, header *types.Header, signer common.Address) {
+	blob, err := json.Marshal(&LastSealed{Signer: signer, Number: header.Number.Uint64(), Hash: SealHash(header)})
+	if err != nil {
+		log.Crit("Failed to encode last sealed block", "err", err)
+	}
+	if err := db.Put(lastSealedKey, blob); err != nil {
+		log.Crit("Failed to store last sealed block", "err", err)
+	}
+}
+
+// PreflightCheck is the outcome of one of the startup checks of a validator.
+type PreflightCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// PreflightStatus is the outcome of the last run of the startup checks sealing
+// is held back by.
+type PreflightStatus struct {
+	Passed  bool             `json:"passed"`
+	Checked time.Time        `json:"checked"`
+	Checks  []PreflightCheck `json:"checks"`
+}
+
+// failures returns the failed checks in a single line.
+func (s *PreflightStatus) failures() string {
+	var failed []string
+	for _, check := range s.Checks {
+		if !check.Passed {
+			failed = append(failed, fmt.Sprintf("%s: %s", check.Name, check.Detail))
+		}
+	}
+	return strings.Join(failed, "; ")
+}
+
+// SetPreflight records the outcome of the startup checks of the validator.
+// Sealing is refused while the last outcome didn't pass; nil lifts the gate.
+func (c *Clique) SetPreflight(status *PreflightStatus) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.preflight = status
+}
+
+// Preflight returns the outcome of the last run of the startup checks, nil if
+// sealing isn't gated by them.
+func (c *Clique) Preflight() *PreflightStatus {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.preflight == nil {
+		return nil
+	}
+	cpy := *c.preflight
+	cpy.Checks = append([]PreflightCheck(nil), c.preflight.Checks...)
+	return &cpy
+}
+
+// preflightSealable returns an error if sealing is held back by the startup
+// checks.
+func (c *Clique) preflightSealable() error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.preflight == nil || c.preflight.Passed {
+		return nil
+	}
+	if failures := c.preflight.failures(); failures != "" {
+		return fmt.Errorf("%w: %s", errPreflight, failures)
+	}
+	return errPreflight
+}
+
+// SignerCount returns the number of signers authorized at the head of the chain.
+func (c *Clique) SignerCount(chain consensus.ChainHeaderReader) (int, error) {
+	head := chain.CurrentHeader()
+	snap, err := c.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return 0, err
+	}
+	return len(snap.Signers), nil
+}
+
+// CheckSlashProtection verifies that the slash protection record of the datadir
+// covers the recent blocks of the signer, taken from the sealer index, i.e. that
+// no other datadir sealed with the same key since this one did. Sealing on top of those risks signing
+// conflicting blocks, so the check fails until they leave the window of recent
+// signers, after which the signer couldn't seal conflicting blocks anymore.
+func (c *Clique) CheckSlashProtection(chain consensus.ChainHeaderReader, signer common.Address) error {
+	head := chain.CurrentHeader()
+	snap, err := c.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return err
+	}
+	// Find the last block of the signer within the recently-signed window
+	var (
+		number = head.Number.Uint64()
+		limit  = uint64(len(snap.Signers)/2 + 1)
+		last   uint64
+	)
+	for n := number; n > 0 && n+limit > number+1; n-- {
+		entry, err := c.sealerAt(chain, n)
+		if err != nil {
+			return err
+		}
+		if entry.Signer == signer {
+			last = n
+			break
+		}
+	}
+	if last == 0 {
+		return nil // Not sealed recently, nothing to conflict with
+	}
+	sealed := ReadLastSealed(c.db)
+	switch {
+	case sealed == nil:
+		return fmt.Errorf("signer sealed block %d, but this datadir has no record of sealing, waiting for it to leave the recent signers", last)
+	case sealed.Signer != signer:
+		return fmt.Errorf("signer sealed block %d, but this datadir last sealed as %s, waiting for it to leave the recent signers", last, sealed.Signer)
+	case sealed.Number < last:
+		return fmt.Errorf("signer sealed block %d, but this datadir last sealed block %d, waiting for it to leave the recent signers", last, sealed.Number)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that sealing is held back until the preflight passes, with the failed
+// checks reported.
+func TestPreflightGate(t *testing.T) {
+	engine := New(&params.CliqueConfig{Period: 5}, rawdb.NewMemoryDatabase())
+	if err := engine.preflightSealable(); err != nil {
+		t.Fatalf("ungated engine refused sealing: %v", err)
+	}
+	engine.SetPreflight(&PreflightStatus{})
+	if err := engine.preflightSealable(); err != errPreflight {
+		t.Fatalf("pending preflight error mismatch: have %v, want %v", err, errPreflight)
+	}
+	engine.SetPreflight(&PreflightStatus{Checks: []PreflightCheck{
+		{Name: "sync", Passed: true},
+		{Name: "disk", Detail: "1 GiB free, 2 GiB required"},
+	}})
+	err := engine.preflightSealable()
+	if !errors.Is(err, errPreflight) {
+		t.Fatalf("failed preflight error mismatch: have %v, want %v", err, errPreflight)
+	}
+	if want := "preflight checks pending: disk: 1 GiB free, 2 GiB required"; err.Error() != want {
+		t.Fatalf("failed preflight reason mismatch: have %q, want %q", err, want)
+	}
+	// The reported status can't be modified from the outside
+	engine.Preflight().Checks[1].Passed = true
+	if err := engine.preflightSealable(); err == nil {
+		t.Fatalf("preflight passed by modifying its report")
+	}
+	engine.SetPreflight(&PreflightStatus{Passed: true})
+	if err := engine.preflightSealable(); err != nil {
+		t.Fatalf("passed preflight refused sealing: %v", err)
+	}
+}
+
+// Tests that the last sealed block is recorded for the slash protection.
+func TestLastSealed(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	if sealed := ReadLastSealed(db); sealed != nil {
+		t.Fatalf("empty database has a sealed block: %v", sealed)
+	}
+	var (
+		signer = common.HexToAddress("0x01")
+		header = &types.Header{Number: big.NewInt(42), Extra: make([]byte, extraVanity+extraSeal)}
+	)
+	writeLastSealed(db, header, signer)
+
+	sealed := ReadLastSealed(db)
+	if sealed == nil {
+		t.Fatalf("sealed block not recorded")
+	}
+	if sealed.Signer != signer || sealed.Number != 42 || sealed.Hash != SealHash(header) {
+		t.Fatalf("sealed block mismatch: have %+v", sealed)
+	}
+}
+
+// Tests that the slash protection fails while the signer sealed a block within
+// the recently-signed window the datadir has no record of.
+func TestCheckSlashProtection(t *testing.T) {
+	var (
+		db         = rawdb.NewMemoryDatabase()
+		validators = []common.Address{{0x01}, {0x02}, {0x03}, {0x04}, {0x05}}
+		config     = &params.CliqueConfig{InitialValidators: validators}
+	)
+	NewDNR(config, db)
+	writeTestChain(db, 8, nil)
+	if _, err := RebuildSnapshots(db, config, nil); err != nil {
+		t.Fatalf("failed to build snapshots: %v", err)
+	}
+	var (
+		chain   = &canonicalReader{db: db}
+		engine  = New(config, db)
+		sealers = []common.Address{{0x01}, {0x02}, {0x03}, {0x04}, {0x02}, {0x04}, {0x02}}
+	)
+	for i, sealer := range sealers {
+		writeSealer(db, chain.GetHeaderByNumber(uint64(i+1)), sealer)
+	}
+	// Blocks sealed before the window don't conflict
+	if err := engine.CheckSlashProtection(chain, common.Address{0x01}); err != nil {
+		t.Fatalf("seal before the window refused: %v", err)
+	}
+	// A block sealed within the window without a record does
+	if err := engine.CheckSlashProtection(chain, common.Address{0x02}); err == nil {
+		t.Fatalf("unrecorded seal within the window accepted")
+	}
+	writeLastSealed(db, chain.GetHeaderByNumber(5), common.Address{0x02})
+	if err := engine.CheckSlashProtection(chain, common.Address{0x02}); err == nil {
+		t.Fatalf("outdated seal record accepted")
+	}
+	writeLastSealed(db, chain.GetHeaderByNumber(7), common.Address{0x02})
+	if err := engine.CheckSlashProtection(chain, common.Address{0x02}); err != nil {
+		t.Fatalf("recorded seal refused: %v", err)
+	}
+}
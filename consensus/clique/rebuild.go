@@ -0,0 +1,226 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// snapshotPrefix is the database key prefix of persisted snapshots.
+var snapshotPrefix = []byte("clique-")
+
+// RebuildReport summarizes a snapshot rebuild and how the re-derived snapshots
+// compare to the previously persisted ones.
+type RebuildReport struct {
+	Head       uint64   `json:"head"`       // Number of the last header processed
+	Previous   int      `json:"previous"`   // Number of snapshots persisted before the rebuild
+	Rebuilt    int      `json:"rebuilt"`    // Number of snapshots derived from the headers
+	Matching   int      `json:"matching"`   // Number of rebuilt snapshots identical to the previous ones
+	Mismatched []uint64 `json:"mismatched"` // Blocks whose previous snapshot differed from the rebuilt one
+	Missing    []uint64 `json:"missing"`    // Blocks whose snapshot was previously absent
+	Stale      []uint64 `json:"stale"`      // Blocks with a previous snapshot that was not re-derived
+}
+
+// Consistent returns whether the rebuilt snapshots are identical to the ones
+// persisted before.
+func (r *RebuildReport) Consistent() bool {
+	return len(r.Mismatched) == 0 && len(r.Missing) == 0 && len(r.Stale) == 0
+}
+
+// RebuildSnapshots re-derives all snapshots from the canonical headers, starting
+// with the registry state of the genesis block and applying every epoch block on
+// top. The rebuilt snapshots are collected in memory and swapped in for the
+// persisted ones in a single batch, so that a failed rebuild leaves them as they
+// were. The rebuilt snapshots are compared to the previous ones by their
+// canonical encoding. The optional progress callback is invoked for every
+// processed header.
+func RebuildSnapshots(db ethdb.Database, config *params.CliqueConfig, progress func(number, head uint64)) (*RebuildReport, error) {
+	headHash := rawdb.ReadHeadHeaderHash(db)
+	headNumber := rawdb.ReadHeaderNumber(db, headHash)
+	if headNumber == nil {
+		return nil, errors.New("head header missing")
+	}
+	previous, err := loadSnapshots(db, config)
+	if err != nil {
+		return nil, err
+	}
+	// Re-derive all snapshots from the canonical headers into a batch
+	genesis := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, 0), 0)
+	if genesis == nil {
+		return nil, errors.New("genesis header missing")
+	}
+	snap, err := genesisSnapshot(db, config, genesis)
+	if err != nil {
+		return nil, err
+	}
+	batch := db.NewBatch()
+	if err := snap.store(batch); err != nil {
+		return nil, err
+	}
+	rebuilt := map[uint64]*Snapshot{0: snap.copy()}
+
+	for number := uint64(1); number <= *headNumber; number++ {
+		header := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, number), number)
+		if header == nil {
+			return nil, fmt.Errorf("canonical header #%d missing", number)
+		}
+		if progress != nil {
+			progress(number, *headNumber)
+		}
+		if bytes.Equal(header.Nonce[:], nonceDropVote) {
+			continue
+		}
+		validators, err := headerValidators(header)
+		if err != nil {
+			return nil, fmt.Errorf("epoch block #%d: %v", number, err)
+		}
+		if err := verifySnapshotCommit(header, header.Nonce.Uint64(), validators); err != nil {
+			return nil, fmt.Errorf("epoch block #%d: %v", number, err)
+		}
+		snap.updateEpoch(header, header.Nonce.Uint64(), validators)
+		if err := snap.store(batch); err != nil {
+			return nil, err
+		}
+		writeEpochCheckpoint(batch, header, snap)
+		cpy := snap.copy()
+		cpy.PreviousSnapNumber, cpy.PreviousSnapHash = snap.PreviousSnapNumber, snap.PreviousSnapHash
+		rebuilt[number] = cpy
+	}
+	// Swap the rebuilt snapshots in, dropping the stale ones
+	for number := range previous {
+		if _, ok := rebuilt[number]; !ok {
+			if err := batch.Delete(snapshotKey(number)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	log.Info("Replaced persisted clique snapshots", "previous", len(previous), "rebuilt", len(rebuilt))
+
+	return compareSnapshots(*headNumber, previous, rebuilt)
+}
+
+// genesisSnapshot creates the initial snapshot of the chain the same way the
+// engine does, from the registry state recorded for the genesis epoch. If the
+// registry state is unavailable, the genesis signer list is used.
+func genesisSnapshot(db ethdb.Database, config *params.CliqueConfig, genesis *types.Header) (*Snapshot, error) {
+	if dnr, err := GetDNR(db, genesis.Nonce.Uint64()); err == nil {
+		return newSnapshot(config, nil, 0, dnr.LastEpochBlock, nil, genesis.Hash(), nil, dnr.Validators), nil
+	}
+	validators, err := headerValidators(genesis)
+	if err != nil {
+		return nil, fmt.Errorf("genesis: %v", err)
+	}
+	return newSnapshot(config, nil, 0, genesis.Nonce.Uint64(), nil, genesis.Hash(), nil, validators), nil
+}
+
+// headerValidators extracts the validator set from the extra-data of an epoch
+// header.
+func headerValidators(header *types.Header) (map[common.Address]bool, error) {
+	list, _, err := splitEpochExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	validators := make(map[common.Address]bool)
+	for i := 0; i < len(list); i += common.AddressLength {
+		validators[common.BytesToAddress(list[i:i+common.AddressLength])] = true
+	}
+	return validators, nil
+}
+
+// loadSnapshots retrieves all persisted snapshots indexed by block number.
+func loadSnapshots(db ethdb.Database, config *params.CliqueConfig) (map[uint64]*Snapshot, error) {
+	it := db.NewIterator(snapshotPrefix, nil)
+	defer it.Release()
+
+	snaps := make(map[uint64]*Snapshot)
+	for it.Next() {
+		number, err := strconv.ParseUint(string(it.Key()[len(snapshotPrefix):]), 10, 64)
+		if err != nil {
+			continue // Not a snapshot key
+		}
+		snap := new(Snapshot)
+		if err := json.Unmarshal(it.Value(), snap); err != nil {
+			log.Warn("Undecodable clique snapshot", "number", number, "err", err)
+			snap = nil // Corrupted, will be reported as mismatching
+		} else {
+			snap.config = config
+		}
+		snaps[number] = snap
+	}
+	return snaps, it.Error()
+}
+
+// compareSnapshots compares the previous and the rebuilt snapshot sets.
+func compareSnapshots(head uint64, previous, rebuilt map[uint64]*Snapshot) (*RebuildReport, error) {
+	report := &RebuildReport{
+		Head:     head,
+		Previous: len(previous),
+		Rebuilt:  len(rebuilt),
+	}
+	for number, snap := range rebuilt {
+		old, ok := previous[number]
+		if !ok {
+			report.Missing = append(report.Missing, number)
+			continue
+		}
+		if old == nil {
+			report.Mismatched = append(report.Mismatched, number)
+			continue
+		}
+		want, err := snap.RLPHash()
+		if err != nil {
+			return nil, err
+		}
+		have, err := old.RLPHash()
+		if err != nil {
+			return nil, err
+		}
+		if have != want {
+			report.Mismatched = append(report.Mismatched, number)
+			continue
+		}
+		report.Matching++
+	}
+	for number := range previous {
+		if _, ok := rebuilt[number]; !ok {
+			report.Stale = append(report.Stale, number)
+		}
+	}
+	for _, list := range [][]uint64{report.Mismatched, report.Missing, report.Stale} {
+		sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	}
+	return report, nil
+}
+
+func snapshotKey(number uint64) []byte {
+	return []byte(fmt.Sprintf("clique-%v", number))
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// writeTestChain writes a canonical header chain where the given blocks are
+// epoch blocks switching to the given validators.
+func writeTestChain(db ethdb.Database, length uint64, epochs map[uint64][]common.Address) {
+	var parent common.Hash
+	for number := uint64(0); number < length; number++ {
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     new(big.Int).SetUint64(number),
+			Difficulty: big.NewInt(1),
+			Extra:      make([]byte, extraVanity),
+		}
+		if validators, ok := epochs[number]; ok {
+			header.Nonce = types.EncodeNonce(number * 10)
+			for _, validator := range validators {
+				header.Extra = append(header.Extra, validator[:]...)
+			}
+		}
+		header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), number)
+		rawdb.WriteHeadHeaderHash(db, header.Hash())
+		parent = header.Hash()
+	}
+}
+
+func TestRebuildSnapshots(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		config = &params.CliqueConfig{InitialValidators: []common.Address{{0x01}}}
+		epochs = map[uint64][]common.Address{
+			4: {{0x01}, {0x02}},
+			9: {{0x02}},
+		}
+	)
+	NewDNR(config, db)
+	writeTestChain(db, 12, epochs)
+
+	// Derive the expected snapshots and damage the persisted set
+	report, err := RebuildSnapshots(db, config, nil)
+	if err != nil {
+		t.Fatalf("failed to build snapshots: %v", err)
+	}
+	if report.Rebuilt != 3 || len(report.Missing) != 3 {
+		t.Fatalf("initial build mismatch: %+v", report)
+	}
+	good, err := loadSnapshot(config, nil, db, 9)
+	if err != nil {
+		t.Fatalf("failed to load rebuilt snapshot: %v", err)
+	}
+	if want := map[common.Address]bool{{0x02}: true}; !reflect.DeepEqual(good.Signers, want) || good.EpochNumber != 90 || *good.PreviousSnapNumber != 4 {
+		t.Fatalf("rebuilt snapshot mismatch: %+v", good)
+	}
+	db.Put(snapshotKey(4), []byte("corrupted"))
+	db.Put(snapshotKey(7), []byte("{}"))
+
+	report, err = RebuildSnapshots(db, config, nil)
+	if err != nil {
+		t.Fatalf("failed to rebuild snapshots: %v", err)
+	}
+	if report.Consistent() || report.Matching != 2 || !reflect.DeepEqual(report.Mismatched, []uint64{4}) || !reflect.DeepEqual(report.Stale, []uint64{7}) {
+		t.Fatalf("rebuild report mismatch: %+v", report)
+	}
+	if has, _ := db.Has(snapshotKey(7)); has {
+		t.Fatalf("stale snapshot not deleted")
+	}
+	// Rebuilding again must be a noop
+	if report, err = RebuildSnapshots(db, config, nil); err != nil || !report.Consistent() || report.Matching != 3 {
+		t.Fatalf("repeated rebuild inconsistent: %+v, %v", report, err)
+	}
+	// A failed rebuild leaves the persisted snapshots untouched
+	db.Put(snapshotKey(7), []byte("{}"))
+	rawdb.DeleteCanonicalHash(db, 10)
+	if _, err := RebuildSnapshots(db, config, nil); err == nil {
+		t.Fatalf("rebuild with missing header succeeded")
+	}
+	snaps, err := loadSnapshots(db, config)
+	if err != nil {
+		t.Fatalf("failed to load snapshots: %v", err)
+	}
+	if len(snaps) != 4 {
+		t.Fatalf("persisted snapshots changed by failed rebuild: have %d, want 4", len(snaps))
+	}
+	if snap, err := loadSnapshot(config, nil, db, 9); err != nil || !reflect.DeepEqual(snap.Signers, good.Signers) {
+		t.Fatalf("persisted snapshot changed by failed rebuild: %+v, %v", snap, err)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// maxSignerKeys is the number of signer public keys the seals are checked against
+// before falling back to recovering the signer.
+const maxSignerKeys = 32
+
+var (
+	keyHitMeter  = metrics.NewRegisteredMeter("clique/recover/keyhit", nil)  // Seals matched against a known signer key
+	keyMissMeter = metrics.NewRegisteredMeter("clique/recover/keymiss", nil) // Seals needing a full signer recovery
+)
+
+// signerKey is the public key of a signer.
+type signerKey struct {
+	signer common.Address
+	pubkey []byte
+}
+
+// signerKeys caches the public keys of the signers seen recently. The keys are
+// ordered by their last match, the least recent first: with the signers sealing
+// in turn, the one sealing the next block is the one which sealed the longest
+// time ago, so ascending scans mostly match on the first key tried.
+type signerKeys struct {
+	keys []signerKey
+	lock sync.Mutex
+}
+
+// add records the public key of a signer as the most recently matched one.
+func (k *signerKeys) add(signer common.Address, pubkey []byte) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.touch(signer)
+	if len(k.keys) > 0 && k.keys[len(k.keys)-1].signer == signer {
+		return
+	}
+	if len(k.keys) >= maxSignerKeys {
+		k.keys = k.keys[1:]
+	}
+	k.keys = append(k.keys, signerKey{signer: signer, pubkey: pubkey})
+}
+
+// touch moves the key of a signer to the end of the list, if known. The lock
+// must be held by the caller.
+func (k *signerKeys) touch(signer common.Address) {
+	for i, key := range k.keys {
+		if key.signer == signer {
+			copy(k.keys[i:], k.keys[i+1:])
+			k.keys[len(k.keys)-1] = key
+			return
+		}
+	}
+}
+
+// match checks a signature against the known public keys, returning the signer
+// whose key it verifies against. The recovery id is ignored.
+func (k *signerKeys) match(hash []byte, signature []byte) (common.Address, bool) {
+	k.lock.Lock()
+	keys := append([]signerKey(nil), k.keys...)
+	k.lock.Unlock()
+
+	for _, key := range keys {
+		if crypto.VerifySignature(key.pubkey, hash, signature[:crypto.RecoveryIDOffset]) {
+			k.lock.Lock()
+			k.touch(key.signer)
+			k.lock.Unlock()
+			return key.signer, true
+		}
+	}
+	return common.Address{}, false
+}
+
+// sealerOf returns the signer of a header like ecrecover, but first checks the
+// seal against the public keys of the known signers, which is much cheaper than
+// a full recovery on chains with a small signer set. The recovery id of the seal
+// isn't checked by the fast path, so it may only be used for headers which were
+// already verified, such as the ones scanned for the sealer index.
+func (c *Clique) sealerOf(header *types.Header) (common.Address, error) {
+	hash := header.Hash()
+	if address, known := c.signatures.Get(hash); known {
+		return address.(common.Address), nil
+	}
+	if len(header.Extra) < extraSeal {
+		return common.Address{}, errMissingSignature
+	}
+	var (
+		signature = header.Extra[len(header.Extra)-extraSeal:]
+		sealHash  = SealHash(header).Bytes()
+	)
+	if signer, ok := c.keys.match(sealHash, signature); ok {
+		keyHitMeter.Mark(1)
+		c.signatures.Add(hash, signer)
+		return signer, nil
+	}
+	keyMissMeter.Mark(1)
+
+	pubkey, err := crypto.Ecrecover(sealHash, signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+
+	c.keys.add(signer, pubkey)
+	c.signatures.Add(hash, signer)
+	return signer, nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// sealedHeaders creates headers sealed in turn by the given keys.
+func sealedHeaders(t testing.TB, keys []*ecdsa.PrivateKey, n int) []*types.Header {
+	headers := make([]*types.Header, n)
+	for i := range headers {
+		header := &types.Header{
+			Number:     big.NewInt(int64(i + 1)),
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		sig, err := crypto.Sign(SealHash(header).Bytes(), keys[i%len(keys)])
+		if err != nil {
+			t.Fatalf("failed to seal header: %v", err)
+		}
+		copy(header.Extra[extraVanity:], sig)
+		headers[i] = header
+	}
+	return headers
+}
+
+// Tests that the signers found through the known public keys match the ones
+// recovered from the seals.
+func TestSealerOf(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 5)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	var (
+		engine      = New(&params.CliqueConfig{Period: 2}, rawdb.NewMemoryDatabase())
+		headers     = sealedHeaders(t, keys, 50)
+		sigcache, _ = lru.NewARC(inmemorySignatures)
+	)
+	misses := keyMissMeter.Count()
+	for i, header := range headers {
+		have, err := engine.sealerOf(header)
+		if err != nil {
+			t.Fatalf("header %d: failed to find sealer: %v", i, err)
+		}
+		want, _ := ecrecover(header, sigcache)
+		if have != want {
+			t.Errorf("header %d: sealer mismatch: have %x, want %x", i, have, want)
+		}
+	}
+	if misses := keyMissMeter.Count() - misses; metrics.Enabled && misses != int64(len(keys)) {
+		t.Errorf("full recoveries mismatch: have %d, want %d", misses, len(keys))
+	}
+	if n := len(engine.keys.keys); n != len(keys) {
+		t.Errorf("known keys mismatch: have %d, want %d", n, len(keys))
+	}
+}
+
+func BenchmarkSealerRecovery(b *testing.B) {
+	keys := make([]*ecdsa.PrivateKey, 5)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	headers := sealedHeaders(b, keys, 1000)
+
+	b.Run("ecrecover", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sigcache, _ := lru.NewARC(inmemorySignatures)
+			for _, header := range headers {
+				ecrecover(header, sigcache)
+			}
+		}
+	})
+	b.Run("keys", func(b *testing.B) {
+		engine := New(&params.CliqueConfig{Period: 2}, rawdb.NewMemoryDatabase())
+		for i := 0; i < b.N; i++ {
+			engine.signatures, _ = lru.NewARC(inmemorySignatures)
+			for _, header := range headers {
+				engine.sealerOf(header)
+			}
+		}
+	})
+}
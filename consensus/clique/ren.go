@@ -159,6 +159,12 @@ func (d *DNR) store(db ethdb.Database) error {
 	return db.Put([]byte("dnr-latest"), blob)
 }
 
+func (d *DNR) setSynced() {
+	d.syncLock.Lock()
+	d.synced = true
+	d.syncLock.Unlock()
+}
+
 func (d *DNR) WaitSynced() {
 	for {
 		d.syncLock.RLock()
@@ -0,0 +1,134 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"fmt"
+)
+
+const (
+	epochLengthSamples = 8   // Number of past epochs averaged to estimate the epoch length
+	maxEpochSchedule   = 128 // Maximum number of future epoch boundaries estimated
+)
+
+// errNoEpochHistory is returned if the epoch length can't be estimated as no
+// epoch has been completed yet.
+var errNoEpochHistory = &APIError{
+	code: CodeNoEpochHistory,
+	msg:  "no completed epoch to estimate the epoch length from",
+}
+
+// epochBoundary is the estimated position of a future epoch block.
+type epochBoundary struct {
+	Block uint64 `json:"block"`
+	Time  uint64 `json:"time"` // Estimated unix timestamp
+}
+
+// epochSchedule is the estimated end of the current epoch and the following
+// epoch boundaries.
+type epochSchedule struct {
+	Epoch      uint64          `json:"epoch"`      // Current epoch number
+	EpochBlock uint64          `json:"epochBlock"` // Block which started the current epoch
+	Head       uint64          `json:"head"`
+	Length     uint64          `json:"length"`     // Estimated epoch length in blocks
+	Samples    int             `json:"samples"`    // Number of past epochs the length is averaged over
+	BlockTime  float64         `json:"blockTime"`  // Block interval in seconds used for time estimates
+	Overdue    bool            `json:"overdue"`    // Whether the current epoch already exceeded the estimated length
+	End        epochBoundary   `json:"end"`        // Estimated end of the current epoch
+	Boundaries []epochBoundary `json:"boundaries"` // Estimated subsequent epoch boundaries
+}
+
+// EstimateEpochEnd returns the estimated block number and time at which the
+// current epoch ends.
+func (api *API) EstimateEpochEnd() (*epochSchedule, error) {
+	return api.epochSchedule(0)
+}
+
+// EpochSchedule returns the estimated end of the current epoch along with the
+// next count epoch boundaries after it.
+func (api *API) EpochSchedule(count uint64) (*epochSchedule, error) {
+	if count > maxEpochSchedule {
+		return nil, errInvalidLimit(count, maxEpochSchedule, fmt.Sprintf("too many epochs requested, max %d", maxEpochSchedule))
+	}
+	return api.epochSchedule(count)
+}
+
+// epochSchedule estimates the upcoming epoch boundaries. Epochs are triggered
+// by the darknode registry rather than after a fixed number of blocks, so the
+// epoch length is averaged over the recently completed epochs. Times are based
+// on the configured block period, or the observed block interval on chains
+// without a fixed period.
+func (api *API) epochSchedule(count uint64) (*epochSchedule, error) {
+	header := api.chain.CurrentHeader()
+	current, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Walk back the recent epochs to average their lengths
+	var (
+		snap    = current
+		samples int
+	)
+	for samples < epochLengthSamples && snap.PreviousSnapNumber != nil && snap.PreviousSnapHash != nil {
+		prev, err := api.clique.snapshot(api.chain, *snap.PreviousSnapNumber, *snap.PreviousSnapHash, nil)
+		if err != nil {
+			return nil, err
+		}
+		snap = prev
+		samples++
+	}
+	if samples == 0 || current.Number <= snap.Number {
+		return nil, errNoEpochHistory
+	}
+	length := (current.Number - snap.Number) / uint64(samples)
+	if length == 0 {
+		length = 1
+	}
+	blockTime := float64(api.clique.config.Period)
+	if blockTime == 0 {
+		first := api.chain.GetHeaderByNumber(snap.Number)
+		if first == nil {
+			return nil, errMissingBlock(snap.Number)
+		}
+		blockTime = float64(header.Time-first.Time) / float64(header.Number.Uint64()-snap.Number)
+	}
+	var (
+		head     = header.Number.Uint64()
+		schedule = &epochSchedule{
+			Epoch:      current.EpochNumber,
+			EpochBlock: current.Number,
+			Head:       head,
+			Length:     length,
+			Samples:    samples,
+			BlockTime:  blockTime,
+			Boundaries: make([]epochBoundary, 0, count),
+		}
+		estimate = func(number uint64) epochBoundary {
+			return epochBoundary{Block: number, Time: header.Time + uint64(float64(number-head)*blockTime)}
+		}
+	)
+	// An overdue epoch is expected to end with the next block
+	end := current.Number + length
+	if end <= head {
+		end, schedule.Overdue = head+1, true
+	}
+	schedule.End = estimate(end)
+	for i := uint64(1); i <= count; i++ {
+		schedule.Boundaries = append(schedule.Boundaries, estimate(end+i*length))
+	}
+	return schedule, nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newScheduleTestAPI creates an API over a chain of the given length with
+// epoch blocks at the given numbers, mined every interval seconds.
+func newScheduleTestAPI(t *testing.T, period, interval, length uint64, epochs []uint64) *API {
+	var (
+		db         = rawdb.NewMemoryDatabase()
+		validators = []common.Address{{0x01}}
+		config     = &params.CliqueConfig{Period: period, InitialValidators: validators}
+		isEpoch    = make(map[uint64]bool)
+		parent     common.Hash
+	)
+	for _, number := range epochs {
+		isEpoch[number] = true
+	}
+	NewDNR(config, db)
+	for number := uint64(0); number < length; number++ {
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     new(big.Int).SetUint64(number),
+			Time:       1000 + number*interval,
+			Difficulty: big.NewInt(1),
+			Extra:      make([]byte, extraVanity),
+		}
+		if isEpoch[number] {
+			header.Nonce = types.EncodeNonce(number * 10)
+			header.Extra = append(header.Extra, validators[0][:]...)
+		}
+		header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, header.Hash(), number)
+		rawdb.WriteHeadHeaderHash(db, header.Hash())
+		parent = header.Hash()
+	}
+	if _, err := RebuildSnapshots(db, config, nil); err != nil {
+		t.Fatalf("failed to build snapshots: %v", err)
+	}
+	return &API{chain: &canonicalReader{db: db}, clique: New(config, db)}
+}
+
+// Tests that the epoch length is averaged over the last eight epochs only, and
+// that the following boundaries are spaced by it.
+func TestEpochScheduleAveraging(t *testing.T) {
+	// A long first epoch followed by nine epochs of ten blocks each
+	api := newScheduleTestAPI(t, 2, 2, 146, []uint64{50, 60, 70, 80, 90, 100, 110, 120, 130, 140})
+
+	schedule, err := api.EpochSchedule(2)
+	if err != nil {
+		t.Fatalf("failed to estimate schedule: %v", err)
+	}
+	want := &epochSchedule{
+		Epoch:      1400,
+		EpochBlock: 140,
+		Head:       145,
+		Length:     10,
+		Samples:    epochLengthSamples,
+		BlockTime:  2,
+		End:        epochBoundary{Block: 150, Time: 1300},
+		Boundaries: []epochBoundary{{Block: 160, Time: 1320}, {Block: 170, Time: 1340}},
+	}
+	if !reflect.DeepEqual(schedule, want) {
+		t.Fatalf("schedule mismatch: have %+v, want %+v", schedule, want)
+	}
+	end, err := api.EstimateEpochEnd()
+	if err != nil {
+		t.Fatalf("failed to estimate epoch end: %v", err)
+	}
+	if end.End != want.End || len(end.Boundaries) != 0 {
+		t.Fatalf("epoch end mismatch: have %+v, want %+v", end, want.End)
+	}
+}
+
+// Tests that an epoch running past its estimated length is expected to end
+// with the next block.
+func TestEpochScheduleOverdue(t *testing.T) {
+	api := newScheduleTestAPI(t, 2, 2, 25, []uint64{5, 10})
+
+	schedule, err := api.EpochSchedule(1)
+	if err != nil {
+		t.Fatalf("failed to estimate schedule: %v", err)
+	}
+	if !schedule.Overdue || schedule.Length != 5 || schedule.Samples != 2 {
+		t.Fatalf("schedule mismatch: have %+v, want overdue with length 5 over 2 epochs", schedule)
+	}
+	if want := (epochBoundary{Block: 25, Time: 1050}); schedule.End != want {
+		t.Errorf("end mismatch: have %+v, want %+v", schedule.End, want)
+	}
+	if want := []epochBoundary{{Block: 30, Time: 1060}}; !reflect.DeepEqual(schedule.Boundaries, want) {
+		t.Errorf("boundaries mismatch: have %+v, want %+v", schedule.Boundaries, want)
+	}
+}
+
+// Tests that chains without a fixed block period estimate times from the block
+// interval observed over the sampled epochs.
+func TestEpochScheduleNoPeriod(t *testing.T) {
+	api := newScheduleTestAPI(t, 0, 3, 12, []uint64{4, 8})
+
+	schedule, err := api.EstimateEpochEnd()
+	if err != nil {
+		t.Fatalf("failed to estimate epoch end: %v", err)
+	}
+	if schedule.BlockTime != 3 {
+		t.Errorf("block time mismatch: have %v, want 3", schedule.BlockTime)
+	}
+	if want := (epochBoundary{Block: 12, Time: 1036}); schedule.End != want {
+		t.Errorf("end mismatch: have %+v, want %+v", schedule.End, want)
+	}
+}
+
+// Tests that no schedule is estimated before the first epoch is completed.
+func TestEpochScheduleNoHistory(t *testing.T) {
+	api := newScheduleTestAPI(t, 2, 2, 5, nil)
+
+	if _, err := api.EstimateEpochEnd(); err != errNoEpochHistory {
+		t.Fatalf("error mismatch: have %v, want %v", err, errNoEpochHistory)
+	}
+}
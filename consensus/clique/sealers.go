@@ -0,0 +1,218 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	sealerPrefix      = []byte("sealer-")             // sealerPrefix + num (uint64 big endian) + hash -> signer + flags
+	sealerBackfillKey = []byte("SealerIndexBackfill") // Number of the last block backfilled into the sealer index
+)
+
+const (
+	sealerInturn = 1 << iota // Block was sealed in-turn
+	sealerEpoch              // Block is an epoch block
+)
+
+// sealerPruneDepth is the number of blocks below a newly indexed one at which
+// the sealer index entries of side chain blocks are dropped.
+const sealerPruneDepth = 128
+
+// sealerEntry is the sealer index record of a single block.
+type sealerEntry struct {
+	Signer common.Address
+	Inturn bool
+	Epoch  bool
+}
+
+// sealerKey = sealerPrefix + num (uint64 big endian) + hash
+func sealerKey(number uint64, hash common.Hash) []byte {
+	key := make([]byte, len(sealerPrefix)+8+common.HashLength)
+	copy(key, sealerPrefix)
+	binary.BigEndian.PutUint64(key[len(sealerPrefix):], number)
+	copy(key[len(sealerPrefix)+8:], hash[:])
+	return key
+}
+
+// writeSealer records the signer of a header in the sealer index. Blocks are
+// keyed by hash too, so entries of side chains never shadow canonical ones.
+func writeSealer(db ethdb.KeyValueWriter, header *types.Header, signer common.Address) {
+	var flags byte
+	if header.Difficulty != nil && header.Difficulty.Cmp(diffInTurn) == 0 {
+		flags |= sealerInturn
+	}
+	if !bytes.Equal(header.Nonce[:], nonceDropVote) {
+		flags |= sealerEpoch
+	}
+	if err := db.Put(sealerKey(header.Number.Uint64(), header.Hash()), append(signer.Bytes(), flags)); err != nil {
+		log.Warn("Failed to index block sealer", "number", header.Number, "err", err)
+	}
+}
+
+// readSealer retrieves the sealer index record of a block, if present.
+func readSealer(db ethdb.KeyValueReader, number uint64, hash common.Hash) *sealerEntry {
+	blob, err := db.Get(sealerKey(number, hash))
+	if err != nil || len(blob) != common.AddressLength+1 {
+		return nil
+	}
+	return &sealerEntry{
+		Signer: common.BytesToAddress(blob[:common.AddressLength]),
+		Inturn: blob[common.AddressLength]&sealerInturn != 0,
+		Epoch:  blob[common.AddressLength]&sealerEpoch != 0,
+	}
+}
+
+// pruneSealers deletes the sealer index entries of the blocks with the given
+// number other than the canonical one, returning the number of entries dropped.
+func pruneSealers(db ethdb.Iteratee, w ethdb.KeyValueWriter, number uint64, canonical common.Hash) int {
+	prefix := sealerKey(number, common.Hash{})[:len(sealerPrefix)+8]
+
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	pruned := 0
+	for it.Next() {
+		if key := it.Key(); len(key) == len(prefix)+common.HashLength && common.BytesToHash(key[len(prefix):]) != canonical {
+			if err := w.Delete(common.CopyBytes(key)); err != nil {
+				log.Warn("Failed to prune block sealer", "number", number, "err", err)
+				continue
+			}
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// indexSealer records the signer of a verified or locally sealed header and
+// prunes the side chain entries sealerPruneDepth blocks below it, which can't
+// become canonical short of a deep reorg. Should one happen anyway, the pruned
+// signers are recovered again on demand.
+func (c *Clique) indexSealer(header *types.Header, signer common.Address) {
+	writeSealer(c.db, header, signer)
+
+	number := header.Number.Uint64()
+	if number <= sealerPruneDepth {
+		return
+	}
+	number -= sealerPruneDepth
+	if hash := rawdb.ReadCanonicalHash(c.db, number); hash != (common.Hash{}) {
+		pruneSealers(c.db, c.db, number, hash)
+	}
+}
+
+// sealerAt returns the sealer index record of the canonical block with the
+// given number, recovering and indexing the signer if it's not yet known.
+func (c *Clique) sealerAt(chain consensus.ChainHeaderReader, number uint64) (*sealerEntry, error) {
+	if hash := rawdb.ReadCanonicalHash(c.db, number); hash != (common.Hash{}) {
+		if entry := readSealer(c.db, number, hash); entry != nil {
+			return entry, nil
+		}
+	}
+	header := chain.GetHeaderByNumber(number)
+	if header == nil {
+		return nil, errMissingBlock(number)
+	}
+	signer, err := c.sealerOf(header)
+	if err != nil {
+		return nil, err
+	}
+	writeSealer(c.db, header, signer)
+	return readSealer(c.db, number, header.Hash()), nil
+}
+
+// StartSealerIndexer launches a background job indexing the sealers of the
+// canonical blocks imported before the index existed. Blocks imported or
+// sealed afterwards are indexed as they arrive.
+func (c *Clique) StartSealerIndexer(chain consensus.ChainHeaderReader) {
+	c.indexerOnce.Do(func() {
+		go c.backfillSealers(chain)
+	})
+}
+
+// backfillSealers indexes the canonical blocks from the last backfilled one up
+// to the head at startup, persisting its progress to resume after restarts.
+// The entries of the side chain blocks met along the way are pruned, catching
+// up on those left behind by a shutdown before they got deep enough.
+func (c *Clique) backfillSealers(chain consensus.ChainHeaderReader) {
+	var (
+		head  = chain.CurrentHeader().Number.Uint64()
+		next  = uint64(1)
+		batch = c.db.NewBatch()
+		start = time.Now()
+		last  = start
+	)
+	if blob, err := c.db.Get(sealerBackfillKey); err == nil && len(blob) == 8 {
+		next = binary.BigEndian.Uint64(blob) + 1
+	}
+	if next > head {
+		return
+	}
+	log.Info("Backfilling clique sealer index", "from", next, "to", head)
+
+	flush := func(number uint64) {
+		enc := make([]byte, 8)
+		binary.BigEndian.PutUint64(enc, number)
+		batch.Put(sealerBackfillKey, enc)
+		if err := batch.Write(); err != nil {
+			log.Error("Failed to write sealer index", "err", err)
+		}
+		batch.Reset()
+	}
+	for number := next; number <= head; number++ {
+		select {
+		case <-c.closeCh:
+			flush(number - 1)
+			return
+		default:
+		}
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			log.Warn("Sealer index backfill missing header", "number", number)
+			flush(number - 1)
+			return
+		}
+		if readSealer(c.db, number, header.Hash()) == nil {
+			signer, err := c.sealerOf(header)
+			if err != nil {
+				log.Warn("Failed to recover block sealer", "number", number, "err", err)
+				continue
+			}
+			writeSealer(batch, header, signer)
+		}
+		pruneSealers(c.db, batch, number, header.Hash())
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			flush(number)
+		}
+		if time.Since(last) > 8*time.Second {
+			log.Info("Backfilling clique sealer index", "number", number, "head", head, "elapsed", common.PrettyDuration(time.Since(start)))
+			last = time.Now()
+		}
+	}
+	flush(head)
+	log.Info("Backfilled clique sealer index", "blocks", head-next+1, "elapsed", common.PrettyDuration(time.Since(start)))
+}
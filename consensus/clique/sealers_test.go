@@ -0,0 +1,190 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// writeSealedChain writes n headers on top of parent, sealed in turn by the
+// given keys, and makes them canonical if requested. The fork byte is put in
+// the vanity to tell apart the headers of competing chains.
+func writeSealedChain(t *testing.T, db ethdb.Database, parent *types.Header, keys []*ecdsa.PrivateKey, n int, fork byte, canonical bool) []*types.Header {
+	headers := make([]*types.Header, n)
+	for i := range headers {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		header.Extra[0] = fork
+		sig, err := crypto.Sign(SealHash(header).Bytes(), keys[i%len(keys)])
+		if err != nil {
+			t.Fatalf("failed to seal header: %v", err)
+		}
+		copy(header.Extra[extraVanity:], sig)
+
+		rawdb.WriteHeader(db, header)
+		if canonical {
+			rawdb.WriteCanonicalHash(db, header.Hash(), header.Number.Uint64())
+			rawdb.WriteHeadHeaderHash(db, header.Hash())
+		}
+		headers[i], parent = header, header
+	}
+	return headers
+}
+
+// Tests that sealer index entries round-trip with their flags, and that the
+// entries of competing blocks don't shadow each other.
+func TestSealerIndexRoundTrip(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+
+	epoch := &types.Header{Number: big.NewInt(5), Difficulty: diffInTurn, Nonce: types.EncodeNonce(7)}
+	plain := &types.Header{Number: big.NewInt(5), Difficulty: diffNoTurn}
+
+	writeSealer(db, epoch, common.Address{0x01})
+	writeSealer(db, plain, common.Address{0x02})
+
+	if have, want := readSealer(db, 5, epoch.Hash()), (&sealerEntry{Signer: common.Address{0x01}, Inturn: true, Epoch: true}); *have != *want {
+		t.Errorf("epoch entry mismatch: have %+v, want %+v", have, want)
+	}
+	if have, want := readSealer(db, 5, plain.Hash()), (&sealerEntry{Signer: common.Address{0x02}}); *have != *want {
+		t.Errorf("plain entry mismatch: have %+v, want %+v", have, want)
+	}
+	if entry := readSealer(db, 6, epoch.Hash()); entry != nil {
+		t.Errorf("entry found under another number: %+v", entry)
+	}
+	if entry := readSealer(db, 5, common.Hash{0xff}); entry != nil {
+		t.Errorf("entry found for unknown block: %+v", entry)
+	}
+}
+
+// Tests that the backfill indexes the canonical blocks, resumes from its
+// progress marker and prunes the entries of side chain blocks.
+func TestSealerIndexBackfill(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		engine  = New(&params.CliqueConfig{Period: 1}, db)
+		chain   = &canonicalReader{db: db}
+		genesis = &types.Header{Number: big.NewInt(0), Difficulty: common.Big1}
+	)
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	canon := writeSealedChain(t, db, genesis, keys, 10, 0, true)
+	side := writeSealedChain(t, db, canon[3], keys[1:], 2, 1, false)
+	writeSealer(db, side[0], crypto.PubkeyToAddress(keys[1].PublicKey))
+
+	progress := func() uint64 {
+		blob, _ := db.Get(sealerBackfillKey)
+		return binary.BigEndian.Uint64(blob)
+	}
+	engine.backfillSealers(chain)
+	if progress() != 10 {
+		t.Fatalf("backfill progress mismatch: have %d, want 10", progress())
+	}
+	for i, header := range canon {
+		entry := readSealer(db, header.Number.Uint64(), header.Hash())
+		if want := crypto.PubkeyToAddress(keys[i%len(keys)].PublicKey); entry == nil || entry.Signer != want || !entry.Inturn {
+			t.Errorf("block %d: entry mismatch: have %+v, want in-turn %x", i+1, entry, want)
+		}
+	}
+	if entry := readSealer(db, side[0].Number.Uint64(), side[0].Hash()); entry != nil {
+		t.Errorf("side chain entry not pruned: %+v", entry)
+	}
+	// Extend the chain and ensure only the new blocks are backfilled
+	db.Delete(sealerKey(canon[0].Number.Uint64(), canon[0].Hash()))
+	more := writeSealedChain(t, db, canon[len(canon)-1], keys, 5, 0, true)
+
+	engine.backfillSealers(chain)
+	if progress() != 15 {
+		t.Fatalf("resumed backfill progress mismatch: have %d, want 15", progress())
+	}
+	if entry := readSealer(db, 1, canon[0].Hash()); entry != nil {
+		t.Errorf("block below the progress marker backfilled again: %+v", entry)
+	}
+	for _, header := range more {
+		if readSealer(db, header.Number.Uint64(), header.Hash()) == nil {
+			t.Errorf("block %d not backfilled", header.Number)
+		}
+	}
+}
+
+// Tests that lookups follow the canonical chain across a reorg, and that the
+// entries of the abandoned blocks are pruned once they are deep enough.
+func TestSealerIndexReorg(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		engine  = New(&params.CliqueConfig{Period: 1}, db)
+		chain   = &canonicalReader{db: db}
+		genesis = &types.Header{Number: big.NewInt(0), Difficulty: common.Big1}
+		signers = []common.Address{crypto.PubkeyToAddress(keys[0].PublicKey), crypto.PubkeyToAddress(keys[1].PublicKey)}
+	)
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	// Index an old chain, then reorg onto a new one sealed in the other order
+	old := writeSealedChain(t, db, genesis, keys, 3, 0, true)
+	for i, header := range old {
+		engine.indexSealer(header, signers[i%2])
+	}
+	reorged := writeSealedChain(t, db, genesis, []*ecdsa.PrivateKey{keys[1], keys[0]}, 3, 1, true)
+	for i := range reorged {
+		entry, err := engine.sealerAt(chain, uint64(i+1))
+		if err != nil {
+			t.Fatalf("block %d: failed to look up sealer: %v", i+1, err)
+		}
+		if want := signers[(i+1)%2]; entry.Signer != want {
+			t.Errorf("block %d: sealer mismatch after reorg: have %x, want %x", i+1, entry.Signer, want)
+		}
+	}
+	// Entries of the old chain survive until the new chain outgrows them
+	if readSealer(db, 1, old[0].Hash()) == nil {
+		t.Fatalf("abandoned entry pruned before getting deep enough")
+	}
+	more := writeSealedChain(t, db, reorged[len(reorged)-1], keys, sealerPruneDepth, 1, true)
+	for i, header := range more {
+		engine.indexSealer(header, signers[i%2])
+	}
+	for i, header := range old {
+		if entry := readSealer(db, header.Number.Uint64(), header.Hash()); entry != nil {
+			t.Errorf("block %d: abandoned entry not pruned: %+v", i+1, entry)
+		}
+		if readSealer(db, reorged[i].Number.Uint64(), reorged[i].Hash()) == nil {
+			t.Errorf("block %d: canonical entry pruned", i+1)
+		}
+	}
+}
@@ -19,14 +19,17 @@ package clique
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
+	"io"
+	"sort"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	lru "github.com/hashicorp/golang-lru"
-	"sort"
 )
 
 // Vote represents a single vote that an authorized signer made to modify the
@@ -57,6 +60,8 @@ type Snapshot struct {
 	Hash               common.Hash               `json:"hash"`                         // Block hash where the snapshot was created
 	Signers            map[common.Address]bool   `json:"signers"`                      // Set of authorized signers at this moment
 	Recents            map[uint64]common.Address `json:"recents"`                      // Set of recent signers for spam protections
+	LastAuthorization  uint64                    `json:"lastAuthorization,omitempty"`  // Last epoch block authorizing new signers whose cooldown still runs, zero if none
+	AuthorizationsFrom uint64                    `json:"authorizationsFrom,omitempty"` // Trusted checkpoint the authorizations are only known from, zero if derived from the chain
 }
 
 // signersAscending implements the sort interface to allow sorting a list of addresses
@@ -86,7 +91,7 @@ func newSnapshot(config *params.CliqueConfig, sigcache *lru.ARCCache, number, ep
 
 // loadSnapshot loads an existing snapshot from the database.
 func loadSnapshot(config *params.CliqueConfig, sigcache *lru.ARCCache, db ethdb.Database, number uint64) (*Snapshot, error) {
-	blob, err := db.Get([]byte(fmt.Sprintf("clique-%v", number)))
+	blob, err := db.Get(snapshotKey(number))
 	if err != nil {
 		return nil, err
 	}
@@ -101,12 +106,12 @@ func loadSnapshot(config *params.CliqueConfig, sigcache *lru.ARCCache, db ethdb.
 }
 
 // store inserts the snapshot into the database.
-func (s *Snapshot) store(db ethdb.Database) error {
+func (s *Snapshot) store(db ethdb.KeyValueWriter) error {
 	blob, err := json.Marshal(s)
 	if err != nil {
 		return err
 	}
-	return db.Put([]byte(fmt.Sprintf("clique-%v", s.Number)), blob)
+	return db.Put(snapshotKey(s.Number), blob)
 }
 
 // copy creates a deep copy of the snapshot, though not the individual votes.
@@ -119,6 +124,9 @@ func (s *Snapshot) copy() *Snapshot {
 		Signers:     make(map[common.Address]bool),
 		Recents:     make(map[uint64]common.Address),
 		EpochNumber: s.EpochNumber,
+
+		LastAuthorization:  s.LastAuthorization,
+		AuthorizationsFrom: s.AuthorizationsFrom,
 	}
 	for signer := range s.Signers {
 		cpy.Signers[signer] = true
@@ -170,15 +178,41 @@ func (s *Snapshot) signers() []common.Address {
 	return sigs
 }
 
+// inturnSigner returns the signer in-turn at a given block height, or the zero
+// address if there are no signers.
+func (s *Snapshot) inturnSigner(number uint64) common.Address {
+	signers := s.signers()
+	if len(signers) == 0 {
+		return common.Address{}
+	}
+	return signers[number%uint64(len(signers))]
+}
+
 // inturn returns if a signer at a given block height is in-turn or not.
 func (s *Snapshot) inturn(number uint64, signer common.Address) bool {
 	signers, offset := s.signers(), 0
+	if len(signers) == 0 {
+		return false
+	}
 	for offset < len(signers) && signers[offset] != signer {
 		offset++
 	}
 	return (number % uint64(len(signers))) == uint64(offset)
 }
 
+// recentlySigned returns whether a signer is among the recent ones and thus not
+// allowed to seal the block with the given number, which may shift it out.
+func (s *Snapshot) recentlySigned(number uint64, signer common.Address) bool {
+	for seen, recent := range s.Recents {
+		if recent == signer {
+			if limit := uint64(len(s.Signers)/2 + 1); seen > number-limit {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // signers retrieves the list of authorized signers in ascending order.
 func (s *Snapshot) updateEpoch(header *types.Header, epoch uint64, signers map[common.Address]bool) {
 	x, y := s.Number, s.Hash
@@ -186,6 +220,102 @@ func (s *Snapshot) updateEpoch(header *types.Header, epoch uint64, signers map[c
 	s.PreviousSnapHash = &y
 	s.Number = header.Number.Uint64()
 	s.Hash = header.Hash()
+	// Authorizations are only tracked once the signer policy is enforced, so
+	// that nodes agree on them whenever they started following the chain, and
+	// only until their cooldown runs out, so that nodes bootstrapped from a
+	// trusted checkpoint can derive them from the headers of the last cooldown
+	if s.config != nil && s.config.IsSignerPolicy(header.Number) {
+		cooldown := s.config.SignerPolicy.Cooldown
+		switch {
+		case s.authorizes(signers):
+			s.LastAuthorization, s.AuthorizationsFrom = s.Number, 0
+		case s.LastAuthorization != 0 && s.Number+1 >= s.LastAuthorization+cooldown:
+			s.LastAuthorization = 0
+		}
+		if s.AuthorizationsFrom != 0 && s.Number+1 >= s.AuthorizationsFrom+cooldown {
+			s.AuthorizationsFrom = 0
+		}
+	}
 	s.Signers = signers
 	s.EpochNumber = epoch
 }
+
+// snapshotRLP is the canonical consensus serialization of a snapshot. Sets are
+// flattened into sorted lists so the encoding is deterministic, and a missing
+// previous snapshot is encoded as a zero number and hash. The last authorization
+// decides the signer policy cooldown and is part of it, whereas the checkpoint
+// the authorizations are known from only says how the node got there.
+type snapshotRLP struct {
+	Number             uint64
+	Hash               common.Hash
+	EpochNumber        uint64
+	PreviousSnapNumber uint64
+	PreviousSnapHash   common.Hash
+	Signers            []common.Address // Ascending order
+	Recents            []recentRLP      // Ascending block number order
+	LastAuthorization  uint64
+}
+
+type recentRLP struct {
+	Number uint64
+	Signer common.Address
+}
+
+// EncodeRLP implements rlp.Encoder, serializing the snapshot into its canonical
+// form for cross-client comparison.
+func (s *Snapshot) EncodeRLP(w io.Writer) error {
+	enc := snapshotRLP{
+		Number:      s.Number,
+		Hash:        s.Hash,
+		EpochNumber: s.EpochNumber,
+		Signers:     s.signers(),
+		Recents:     make([]recentRLP, 0, len(s.Recents)),
+
+		LastAuthorization: s.LastAuthorization,
+	}
+	if s.PreviousSnapNumber != nil {
+		enc.PreviousSnapNumber = *s.PreviousSnapNumber
+	}
+	if s.PreviousSnapHash != nil {
+		enc.PreviousSnapHash = *s.PreviousSnapHash
+	}
+	for number, signer := range s.Recents {
+		enc.Recents = append(enc.Recents, recentRLP{Number: number, Signer: signer})
+	}
+	sort.Slice(enc.Recents, func(i, j int) bool { return enc.Recents[i].Number < enc.Recents[j].Number })
+	return rlp.Encode(w, &enc)
+}
+
+// DecodeRLP implements rlp.Decoder, loading a snapshot from its canonical form.
+// The consensus parameters and signature cache are not part of the encoding.
+func (s *Snapshot) DecodeRLP(stream *rlp.Stream) error {
+	var dec snapshotRLP
+	if err := stream.Decode(&dec); err != nil {
+		return err
+	}
+	s.Number, s.Hash, s.EpochNumber = dec.Number, dec.Hash, dec.EpochNumber
+	s.LastAuthorization = dec.LastAuthorization
+	s.PreviousSnapNumber, s.PreviousSnapHash = nil, nil
+	if dec.PreviousSnapHash != (common.Hash{}) {
+		number, hash := dec.PreviousSnapNumber, dec.PreviousSnapHash
+		s.PreviousSnapNumber, s.PreviousSnapHash = &number, &hash
+	}
+	s.Signers = make(map[common.Address]bool, len(dec.Signers))
+	for _, signer := range dec.Signers {
+		s.Signers[signer] = true
+	}
+	s.Recents = make(map[uint64]common.Address, len(dec.Recents))
+	for _, recent := range dec.Recents {
+		s.Recents[recent.Number] = recent.Signer
+	}
+	return nil
+}
+
+// RLPHash returns the keccak256 hash of the canonical snapshot encoding.
+func (s *Snapshot) RLPHash() (common.Hash, error) {
+	blob, err := rlp.EncodeToBytes(s)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(blob), nil
+}
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"math/big"
+	"reflect"
 	"sort"
 	"testing"
 
@@ -30,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 // testerAccountPool is a pool to maintain currently active tester accounts,
@@ -503,3 +505,95 @@ func TestClique(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the canonical snapshot encoding is deterministic and round trips.
+func TestSnapshotRLP(t *testing.T) {
+	var (
+		prevNumber = uint64(10)
+		prevHash   = common.Hash{0x01}
+		snap       = newSnapshot(nil, nil, 20, 3, &prevNumber, common.Hash{0x02}, &prevHash, map[common.Address]bool{
+			{0x03}: true,
+			{0x01}: true,
+			{0x02}: true,
+		})
+	)
+	snap.Recents[19] = common.Address{0x01}
+	snap.Recents[18] = common.Address{0x02}
+
+	blob, err := rlp.EncodeToBytes(snap)
+	if err != nil {
+		t.Fatalf("failed to encode snapshot: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if again, _ := rlp.EncodeToBytes(snap); !bytes.Equal(blob, again) {
+			t.Fatalf("non-deterministic encoding: %x != %x", blob, again)
+		}
+	}
+	dec := new(Snapshot)
+	if err := rlp.DecodeBytes(blob, dec); err != nil {
+		t.Fatalf("failed to decode snapshot: %v", err)
+	}
+	if !reflect.DeepEqual(dec, snap) {
+		t.Fatalf("round trip mismatch: have %+v, want %+v", dec, snap)
+	}
+	// A snapshot without predecessor must round trip too
+	genesis := newSnapshot(nil, nil, 0, 0, nil, common.Hash{0x02}, nil, map[common.Address]bool{{0x01}: true})
+	blob, _ = rlp.EncodeToBytes(genesis)
+	dec = new(Snapshot)
+	if err := rlp.DecodeBytes(blob, dec); err != nil {
+		t.Fatalf("failed to decode genesis snapshot: %v", err)
+	}
+	if dec.PreviousSnapNumber != nil || dec.PreviousSnapHash != nil {
+		t.Fatalf("phantom predecessor: %d %x", *dec.PreviousSnapNumber, *dec.PreviousSnapHash)
+	}
+}
+
+// Tests that signers are only barred from sealing until enough blocks passed
+// since their last one for the recency rule.
+func TestSnapshotRecentlySigned(t *testing.T) {
+	signers := map[common.Address]bool{{0x01}: true, {0x02}: true, {0x03}: true, {0x04}: true}
+	snap := newSnapshot(nil, nil, 0, 0, nil, common.Hash{}, nil, signers)
+	snap.Recents[10] = common.Address{0x01}
+	snap.Recents[11] = common.Address{0x02}
+
+	// With 4 signers, a signer may seal again 3 blocks after its last one
+	tests := []struct {
+		number uint64
+		signer common.Address
+		recent bool
+	}{
+		{12, common.Address{0x01}, true},
+		{13, common.Address{0x01}, false},
+		{13, common.Address{0x02}, true},
+		{14, common.Address{0x02}, false},
+		{12, common.Address{0x03}, false},
+	}
+	for i, tt := range tests {
+		if have := snap.recentlySigned(tt.number, tt.signer); have != tt.recent {
+			t.Errorf("test %d: recency mismatch for block %d: have %v, want %v", i, tt.number, have, tt.recent)
+		}
+	}
+}
+
+// Tests that blocks are assembled for the in-turn signer if the local one isn't
+// authorized, so that non-sealing nodes agree on the pending difficulty.
+func TestCandidateSigner(t *testing.T) {
+	signers := map[common.Address]bool{{0x01}: true, {0x02}: true, {0x03}: true}
+	snap := newSnapshot(nil, nil, 0, 0, nil, common.Hash{}, nil, signers)
+
+	if have := snap.inturnSigner(7); have != (common.Address{0x02}) {
+		t.Errorf("in-turn signer mismatch: have %x, want %x", have, common.Address{0x02})
+	}
+	if have := candidateSigner(snap, 7, common.Address{0x03}); have != (common.Address{0x03}) {
+		t.Errorf("authorized candidate replaced: have %x", have)
+	}
+	for _, local := range []common.Address{{}, {0x04}} {
+		if have := calcDifficulty(snap, 7, candidateSigner(snap, 7, local)); have.Cmp(diffInTurn) != 0 {
+			t.Errorf("unauthorized %x: difficulty mismatch: have %v, want %v", local, have, diffInTurn)
+		}
+	}
+	empty := newSnapshot(nil, nil, 0, 0, nil, common.Hash{}, nil, nil)
+	if have := calcDifficulty(empty, 7, candidateSigner(empty, 7, common.Address{})); have.Cmp(diffNoTurn) != 0 {
+		t.Errorf("empty signer set difficulty mismatch: have %v, want %v", have, diffNoTurn)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	skewSamples    = 32          // Number of recent in-turn blocks the clock skew of a signer is estimated over
+	skewMinSamples = 4           // Number of in-turn blocks needed before judging the clock of a signer
+	skewWindow     = time.Minute // Maximum age of the blocks measured, older ones are being synced
+	skewThreshold  = time.Second // Default clock skew a signer is reported as skewed beyond
+)
+
+// errFutureDrift is returned if a block's timestamp is further ahead of the local
+// clock than the configured maximum drift.
+var errFutureDrift = errors.New("timestamp too far in the future")
+
+var (
+	futureDriftMeter = metrics.NewRegisteredMeter("clique/timestamp/drift", nil) // Blocks rejected for their future drift
+	skewHistogram    = metrics.NewRegisteredHistogram("clique/skew", nil, metrics.NewExpDecaySample(1028, 0.015))
+)
+
+// TimestampPolicy is the local tolerance for block timestamps ahead of the local
+// clock. Blocks within the jitter are accepted right away, blocks beyond it are
+// scheduled as future blocks until the maximum drift, and rejected past it.
+type TimestampPolicy struct {
+	MaxFutureDrift time.Duration // Drift ahead of the local clock blocks are rejected beyond, zero to always schedule them
+	Jitter         time.Duration // Drift ahead of the local clock absorbed as clock jitter between the signers
+}
+
+// SetTimestampPolicy configures the tolerance for block timestamps ahead of the
+// local clock.
+func (c *Clique) SetTimestampPolicy(policy TimestampPolicy) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.timestamps = policy
+}
+
+// checkFutureTimestamp checks a block's timestamp against the local clock and
+// the configured timestamp policy.
+func (c *Clique) checkFutureTimestamp(header *types.Header) error {
+	c.lock.RLock()
+	policy := c.timestamps
+	c.lock.RUnlock()
+
+	now := c.now()
+	if header.Time <= uint64(now.Add(policy.Jitter).Unix()) {
+		return nil
+	}
+	if drift := time.Unix(int64(header.Time), 0).Sub(now); policy.MaxFutureDrift > 0 && drift > policy.MaxFutureDrift {
+		futureDriftMeter.Mark(1)
+		signer, _ := ecrecover(header, c.signatures)
+		return fmt.Errorf("%w: block %d sealed by %s is %v ahead of the local clock, max %v", errFutureDrift, header.Number, signer, common.PrettyDuration(drift), policy.MaxFutureDrift)
+	}
+	return consensus.ErrFutureBlock
+}
+
+// signerClock is the record of the recent clock skews observed from a signer.
+type signerClock struct {
+	samples []time.Duration // Skews of the recent in-turn blocks, oldest first
+	block   uint64          // Last block measured
+	gauge   metrics.Gauge   // Median skew of the signer in milliseconds
+}
+
+// skewTracker estimates the clock skew of the signers from the delay between
+// the timestamps of their in-turn blocks and their arrival. Signers seal their
+// in-turn blocks as soon as their clock reaches the timestamp, so the blocks of
+// a signer with a clock ahead arrive early, and the ones of a signer with a
+// clock behind arrive late. The estimate includes the propagation delay.
+type skewTracker struct {
+	signers map[common.Address]*signerClock
+	lock    sync.Mutex
+}
+
+// newSkewTracker creates an empty clock skew tracker.
+func newSkewTracker() *skewTracker {
+	return &skewTracker{
+		signers: make(map[common.Address]*signerClock),
+	}
+}
+
+// observe records the arrival of an in-turn block of a signer.
+func (t *skewTracker) observe(signer common.Address, number uint64, timestamp uint64, now time.Time) {
+	skew := time.Unix(int64(timestamp), 0).Sub(now)
+	if skew < -skewWindow {
+		return // Block sealed long ago, most probably being synced
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	clock := t.signers[signer]
+	if clock == nil {
+		clock = &signerClock{gauge: metrics.GetOrRegisterGauge("clique/skew/"+strings.ToLower(signer.Hex()), nil)}
+		t.signers[signer] = clock
+	}
+	if number <= clock.block {
+		return // Block reimported or reorged in, the first arrival counted
+	}
+	if len(clock.samples) >= skewSamples {
+		clock.samples = clock.samples[1:]
+	}
+	clock.samples, clock.block = append(clock.samples, skew), number
+	clock.gauge.Update(clock.median().Milliseconds())
+	skewHistogram.Update(skew.Milliseconds())
+
+	if skew > skewThreshold || skew < -skewThreshold {
+		log.Debug("Observed skewed signer clock", "signer", signer, "number", number, "skew", common.PrettyDuration(skew))
+	}
+}
+
+// median returns the median of the recent skews of a signer.
+func (clock *signerClock) median() time.Duration {
+	samples := make([]time.Duration, len(clock.samples))
+	copy(samples, clock.samples)
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return samples[len(samples)/2]
+}
+
+// SignerSkew is the estimated clock skew of a signer.
+type SignerSkew struct {
+	Signer    common.Address `json:"signer"`
+	Skew      float64        `json:"skew"`      // Median seconds the signer's blocks arrived ahead of their timestamp, negative if late
+	Samples   int            `json:"samples"`   // Number of recent in-turn blocks the skew is estimated over
+	LastBlock uint64         `json:"lastBlock"` // Last in-turn block of the signer measured
+}
+
+// skewed returns the signers whose median clock skew exceeds the threshold in
+// either direction, most skewed first.
+func (t *skewTracker) skewed(threshold time.Duration) []*SignerSkew {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	result := []*SignerSkew{}
+	for signer, clock := range t.signers {
+		if len(clock.samples) < skewMinSamples {
+			continue
+		}
+		if skew := clock.median(); skew > threshold || skew < -threshold {
+			result = append(result, &SignerSkew{
+				Signer:    signer,
+				Skew:      skew.Seconds(),
+				Samples:   len(clock.samples),
+				LastBlock: clock.block,
+			})
+		}
+	}
+	abs := func(x float64) float64 {
+		if x < 0 {
+			return -x
+		}
+		return x
+	}
+	sort.Slice(result, func(i, j int) bool { return abs(result[i].Skew) > abs(result[j].Skew) })
+	return result
+}
+
+// SkewedSigners returns the signers whose clocks appear skewed by more than the
+// threshold in seconds, one second by default, judged by the arrival of their
+// recent in-turn blocks relative to their timestamps.
+func (api *API) SkewedSigners(threshold *float64) ([]*SignerSkew, error) {
+	limit := skewThreshold
+	if threshold != nil {
+		if *threshold <= 0 {
+			return nil, fmt.Errorf("invalid skew threshold %v, must be positive", *threshold)
+		}
+		limit = time.Duration(*threshold * float64(time.Second))
+	}
+	return api.clique.skews.skewed(limit), nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that timestamps ahead of the local clock are accepted within the jitter,
+// scheduled within the maximum drift and rejected beyond it.
+func TestFutureTimestampPolicy(t *testing.T) {
+	engine := New(&params.CliqueConfig{Period: 5}, rawdb.NewMemoryDatabase())
+
+	now := uint64(engine.Now().Unix())
+	check := func(ahead uint64) error {
+		return engine.checkFutureTimestamp(&types.Header{Number: big.NewInt(1), Time: now + ahead, Extra: make([]byte, extraVanity+extraSeal)})
+	}
+	if err := check(0); err != nil {
+		t.Errorf("current timestamp rejected: %v", err)
+	}
+	if err := check(2); err != consensus.ErrFutureBlock {
+		t.Errorf("future timestamp error mismatch: have %v, want %v", err, consensus.ErrFutureBlock)
+	}
+	if err := check(3600); err != consensus.ErrFutureBlock {
+		t.Errorf("unbounded future timestamp error mismatch: have %v, want %v", err, consensus.ErrFutureBlock)
+	}
+	engine.SetTimestampPolicy(TimestampPolicy{MaxFutureDrift: 10 * time.Second, Jitter: 3 * time.Second})
+
+	if err := check(2); err != nil {
+		t.Errorf("timestamp within jitter rejected: %v", err)
+	}
+	if err := check(8); err != consensus.ErrFutureBlock {
+		t.Errorf("timestamp within drift error mismatch: have %v, want %v", err, consensus.ErrFutureBlock)
+	}
+	if err := check(30); !errors.Is(err, errFutureDrift) {
+		t.Errorf("timestamp beyond drift error mismatch: have %v, want %v", err, errFutureDrift)
+	}
+}
+
+// Tests that signers are reported as skewed once enough of their in-turn blocks
+// consistently arrived early or late.
+func TestSkewTracker(t *testing.T) {
+	var (
+		tracker = newSkewTracker()
+		ahead   = common.Address{1}
+		behind  = common.Address{2}
+		synced  = common.Address{3}
+		base    = time.Unix(1650000000, 0)
+	)
+	for i := uint64(1); i <= skewMinSamples; i++ {
+		stamp := uint64(base.Unix()) + 5*i
+		arrival := time.Unix(int64(stamp), 0)
+
+		tracker.observe(ahead, 3*i, stamp, arrival.Add(-3*time.Second))
+		tracker.observe(behind, 3*i+1, stamp, arrival.Add(2*time.Second))
+		tracker.observe(synced, 3*i+2, stamp, arrival.Add(200*time.Millisecond))
+
+		// Reimports of a block and blocks being synced are ignored
+		tracker.observe(ahead, 3*i, stamp, arrival.Add(time.Minute))
+		tracker.observe(synced, 1000+i, stamp, arrival.Add(time.Hour))
+
+		if skewed := tracker.skewed(skewThreshold); i < skewMinSamples && len(skewed) != 0 {
+			t.Fatalf("signers judged after %d blocks: %v", i, skewed)
+		}
+	}
+	skewed := tracker.skewed(skewThreshold)
+	if len(skewed) != 2 {
+		t.Fatalf("skewed signer count mismatch: have %d, want 2", len(skewed))
+	}
+	if skewed[0].Signer != ahead || skewed[0].Skew != 3 || skewed[0].Samples != skewMinSamples {
+		t.Errorf("signer ahead mismatch: have %+v", skewed[0])
+	}
+	if skewed[1].Signer != behind || skewed[1].Skew != -2 {
+		t.Errorf("signer behind mismatch: have %+v", skewed[1])
+	}
+	if skewed := tracker.skewed(100 * time.Millisecond); len(skewed) != 3 {
+		t.Errorf("skewed signer count below lower threshold mismatch: have %d, want 3", len(skewed))
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// VanitySchema is the version of the structured extra-data vanity layout. The
+// free-form vanity of earlier blocks is considered the first version.
+const VanitySchema = 3
+
+// vanitySchemaNoOrdering is the structured vanity layout predating the ordering
+// byte, with a 16 byte tag. It's still decoded for the blocks sealed with it.
+const vanitySchemaNoOrdering = 2
+
+const (
+	vanityBuildLength = 8  // Length of the build hash, the prefix of the git commit
+	vanityTagLength   = 15 // Maximum length of the operator tag
+)
+
+// vanityMagic prefixes a structured vanity, followed by the schema version.
+var vanityMagic = []byte("akv")
+
+// errInvalidVanityTag is returned when encoding an operator tag which doesn't
+// fit into the vanity.
+var errInvalidVanityTag = fmt.Errorf("operator tag longer than %d bytes", vanityTagLength)
+
+// Vanity is the structured content of the 32 byte extra-data vanity, announcing
+// the client a sealer runs. Its layout is:
+//
+//	magic (3) | schema (1) | major, minor, patch, stable (4) | build (8) | ordering (1) | tag (15)
+//
+// The tag is zero padded. Vanities of the second schema lack the ordering byte,
+// their tag being 16 bytes long instead. The vanity is advisory, it's not part
+// of the consensus rules, and is left out of epoch blocks and maintenance
+// announcements.
+type Vanity struct {
+	Major    uint8         `json:"major"`
+	Minor    uint8         `json:"minor"`
+	Patch    uint8         `json:"patch"`
+	Stable   bool          `json:"stable"`
+	Build    hexutil.Bytes `json:"build"`    // Prefix of the git commit of the build, empty if unknown
+	Ordering uint8         `json:"ordering"` // Version of the deterministic transaction ordering, zero if none
+	Tag      string        `json:"tag"`      // Operator chosen tag
+}
+
+// NewVanity creates the vanity of the running client with the given git commit
+// and operator tag.
+func NewVanity(gitCommit string, tag string) (*Vanity, error) {
+	if len(tag) > vanityTagLength {
+		return nil, errInvalidVanityTag
+	}
+	v := &Vanity{
+		Major:  params.VersionMajor,
+		Minor:  params.VersionMinor,
+		Patch:  params.VersionPatch,
+		Stable: params.VersionMeta == "stable",
+		Tag:    tag,
+	}
+	if build := common.FromHex(gitCommit); len(build) > vanityBuildLength {
+		v.Build = build[:vanityBuildLength]
+	} else if len(build) > 0 {
+		v.Build = build
+	}
+	return v, nil
+}
+
+// Version returns the textual client version, e.g. "1.10.17-stable".
+func (v *Vanity) Version() string {
+	meta := "unstable"
+	if v.Stable {
+		meta = "stable"
+	}
+	return fmt.Sprintf("%d.%d.%d-%s", v.Major, v.Minor, v.Patch, meta)
+}
+
+// EncodeVanity packs the vanity into the 32 bytes prefixing the extra-data.
+func EncodeVanity(v *Vanity) ([]byte, error) {
+	if len(v.Tag) > vanityTagLength {
+		return nil, errInvalidVanityTag
+	}
+	if len(v.Build) > vanityBuildLength {
+		return nil, fmt.Errorf("build hash longer than %d bytes", vanityBuildLength)
+	}
+	enc := make([]byte, extraVanity)
+	copy(enc, vanityMagic)
+	enc[3] = VanitySchema
+	enc[4], enc[5], enc[6] = v.Major, v.Minor, v.Patch
+	if v.Stable {
+		enc[7] = 1
+	}
+	copy(enc[8:16], v.Build)
+	enc[16] = v.Ordering
+	copy(enc[17:], v.Tag)
+	return enc, nil
+}
+
+// DecodeVanity unpacks the structured vanity prefixing the extra-data.
+func DecodeVanity(extra []byte) (*Vanity, error) {
+	if len(extra) < extraVanity || !bytes.HasPrefix(extra, vanityMagic) {
+		return nil, errors.New("no structured vanity")
+	}
+	v := &Vanity{
+		Major:  extra[4],
+		Minor:  extra[5],
+		Patch:  extra[6],
+		Stable: extra[7] == 1,
+	}
+	switch schema := extra[3]; schema {
+	case vanitySchemaNoOrdering:
+		v.Tag = string(bytes.TrimRight(extra[16:extraVanity], "\x00"))
+	case VanitySchema:
+		v.Ordering = extra[16]
+		v.Tag = string(bytes.TrimRight(extra[17:extraVanity], "\x00"))
+	default:
+		return nil, fmt.Errorf("unsupported vanity schema %d", schema)
+	}
+	if build := extra[8:16]; !bytes.Equal(build, make([]byte, vanityBuildLength)) {
+		v.Build = common.CopyBytes(build)
+	}
+	return v, nil
+}
+
+// vanityResult is the decoded vanity of a block along with its sealer.
+type vanityResult struct {
+	Number  hexutil.Uint64 `json:"number"`
+	Hash    common.Hash    `json:"hash"`
+	Signer  common.Address `json:"signer"`
+	Raw     hexutil.Bytes  `json:"raw"`
+	Schema  uint8          `json:"schema"`            // Vanity schema version, 1 for free-form
+	Version string         `json:"version,omitempty"` // Textual client version, if structured
+	Vanity  *Vanity        `json:"vanity,omitempty"`
+}
+
+// GetVanity decodes the extra-data vanity of a block (or the current head if
+// none requested) to report the client its sealer runs.
+func (api *API) GetVanity(number *rpc.BlockNumber) (*vanityResult, error) {
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return nil, errMissingBlock(uint64(number.Int64()))
+	}
+	if len(header.Extra) < extraVanity {
+		return nil, errMissingVanity
+	}
+	signer, err := api.clique.Author(header)
+	if err != nil {
+		return nil, err
+	}
+	res := &vanityResult{
+		Number: hexutil.Uint64(header.Number.Uint64()),
+		Hash:   header.Hash(),
+		Signer: signer,
+		Raw:    common.CopyBytes(header.Extra[:extraVanity]),
+		Schema: 1,
+	}
+	if v, err := DecodeVanity(header.Extra); err == nil {
+		res.Schema, res.Version, res.Vanity = header.Extra[3], v.Version(), v
+	}
+	return res, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that the structured vanity round trips through its encoding and that
+// free-form vanities are not mistaken for it.
+func TestVanityEncoding(t *testing.T) {
+	vanity, err := NewVanity("0x0123456789abcdef0123456789abcdef01234567", "operator-one")
+	if err != nil {
+		t.Fatalf("failed to create vanity: %v", err)
+	}
+	if want := common.FromHex("0x0123456789abcdef"); !bytes.Equal(vanity.Build, want) {
+		t.Fatalf("build hash mismatch: have %x, want %x", vanity.Build, want)
+	}
+	vanity.Ordering = 1
+
+	enc, err := EncodeVanity(vanity)
+	if err != nil {
+		t.Fatalf("failed to encode vanity: %v", err)
+	}
+	if len(enc) != extraVanity {
+		t.Fatalf("encoded vanity length mismatch: have %d, want %d", len(enc), extraVanity)
+	}
+	dec, err := DecodeVanity(append(enc, make([]byte, extraSeal)...))
+	if err != nil {
+		t.Fatalf("failed to decode vanity: %v", err)
+	}
+	if !reflect.DeepEqual(dec, vanity) {
+		t.Fatalf("decoded vanity mismatch: have %+v, want %+v", dec, vanity)
+	}
+	// Unknown builds are left empty
+	if vanity, _ = NewVanity("", ""); vanity.Build != nil {
+		t.Fatalf("unknown build hash: %x", vanity.Build)
+	}
+	if _, err := NewVanity("", "an-overly-long-operator-tag"); err != errInvalidVanityTag {
+		t.Fatalf("long tag error mismatch: have %v, want %v", err, errInvalidVanityTag)
+	}
+	// Free-form vanities and maintenance announcements are not decoded
+	for _, extra := range [][]byte{make([]byte, extraVanity), maintenanceMagic, append([]byte("akv\x04"), make([]byte, extraVanity)...)} {
+		padded := append(common.CopyBytes(extra), make([]byte, extraVanity)...)
+		if v, err := DecodeVanity(padded); err == nil {
+			t.Errorf("vanity %x decoded: %+v", extra, v)
+		}
+	}
+}
+
+// Tests that vanities of the schema predating the ordering byte still decode.
+func TestVanityDecodingNoOrdering(t *testing.T) {
+	extra := make([]byte, extraVanity+extraSeal)
+	copy(extra, "akv\x02\x01\x0a\x11\x01")
+	copy(extra[8:16], common.FromHex("0x0123456789abcdef"))
+	copy(extra[16:], "sixteen-byte-tag")
+
+	v, err := DecodeVanity(extra)
+	if err != nil {
+		t.Fatalf("failed to decode vanity: %v", err)
+	}
+	want := &Vanity{Major: 1, Minor: 10, Patch: 17, Stable: true, Build: common.FromHex("0x0123456789abcdef"), Tag: "sixteen-byte-tag"}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("decoded vanity mismatch: have %+v, want %+v", v, want)
+	}
+}
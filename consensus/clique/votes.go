@@ -0,0 +1,217 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	maxVoteHistory = 128             // Number of recent signer changes kept for the latency stats
+	maxVoteAge     = 5 * time.Minute // Age past which headers are considered synced rather than live
+)
+
+var (
+	voteLatencyHistogram = metrics.NewRegisteredHistogram("clique/votes/latency", nil, metrics.NewExpDecaySample(1028, 0.015))
+	voteOpenGauge        = metrics.NewRegisteredGauge("clique/votes/open", nil)
+	voteOpenAgeGauge     = metrics.NewRegisteredGauge("clique/votes/open/age", nil)
+)
+
+// signerChange is a change of the signer set, from the first block the new
+// registry epoch was pending for to the epoch block applying it.
+type signerChange struct {
+	Epoch   uint64           `json:"epoch"`
+	Opened  uint64           `json:"opened"`  // First block the change was pending for
+	Passed  uint64           `json:"passed"`  // Epoch block applying the change
+	Latency uint64           `json:"latency"` // Number of blocks elapsed in between
+	Added   []common.Address `json:"added"`
+	Removed []common.Address `json:"removed"`
+}
+
+// openProposal is a registry epoch pending to be applied by an epoch block.
+type openProposal struct {
+	Epoch  uint64 `json:"epoch"`
+	Opened uint64 `json:"opened"` // First block the change was pending for
+	Age    uint64 `json:"age"`    // Number of blocks the change is pending for
+}
+
+// voteLatencyStats is the responsiveness of the signer set to signer changes.
+type voteLatencyStats struct {
+	Head           uint64         `json:"head"` // Last live block tracked
+	Changes        []signerChange `json:"changes"`
+	Open           []openProposal `json:"open"`
+	AverageLatency float64        `json:"averageLatency"`
+	MaxLatency     uint64         `json:"maxLatency"`
+}
+
+// voteTracker measures the number of blocks elapsing between a signer change
+// being announced by the registry and an epoch block applying it. Only live
+// blocks are tracked, as the registry state during sync is unrelated to the
+// blocks being imported.
+type voteTracker struct {
+	head    uint64
+	open    map[uint64]uint64 // First block each pending epoch was seen for
+	passed  uint64            // Last epoch applied
+	changes []signerChange    // Recently applied changes, oldest first
+	lock    sync.Mutex
+}
+
+// newVoteTracker creates an empty signer change tracker.
+func newVoteTracker() *voteTracker {
+	return &voteTracker{open: make(map[uint64]uint64)}
+}
+
+// observe records the block number as a block with the given epoch pending in
+// the registry on top of the current epoch of the chain.
+func (t *voteTracker) observe(number, pending, current uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if number > t.head {
+		t.head = number
+	}
+	if pending > current && pending > t.passed {
+		if _, ok := t.open[pending]; !ok {
+			t.open[pending] = number
+		}
+	}
+	t.updateGauges()
+}
+
+// pass records the application of an epoch by the block with the given number,
+// changing the signer set from prev to next. A change the node didn't see as
+// pending beforehand is counted as passed right away.
+func (t *voteTracker) pass(number, epoch uint64, prev, next map[common.Address]bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if epoch <= t.passed {
+		return
+	}
+	opened, ok := t.open[epoch]
+	if !ok || opened > number {
+		opened = number
+	}
+	change := signerChange{
+		Epoch:   epoch,
+		Opened:  opened,
+		Passed:  number,
+		Latency: number - opened,
+		Added:   signerDiff(next, prev),
+		Removed: signerDiff(prev, next),
+	}
+	t.changes = append(t.changes, change)
+	if len(t.changes) > maxVoteHistory {
+		t.changes = t.changes[len(t.changes)-maxVoteHistory:]
+	}
+	t.passed = epoch
+	for pending := range t.open {
+		if pending <= epoch {
+			delete(t.open, pending)
+		}
+	}
+	voteLatencyHistogram.Update(int64(change.Latency))
+	t.updateGauges()
+}
+
+// updateGauges reports the number of open proposals and the age of the oldest
+// one. The lock must be held.
+func (t *voteTracker) updateGauges() {
+	var age uint64
+	for _, opened := range t.open {
+		if t.head > opened && t.head-opened > age {
+			age = t.head - opened
+		}
+	}
+	voteOpenGauge.Update(int64(len(t.open)))
+	voteOpenAgeGauge.Update(int64(age))
+}
+
+// stats returns the recently applied signer changes and the open proposals.
+func (t *voteTracker) stats() *voteLatencyStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	stats := &voteLatencyStats{
+		Head:    t.head,
+		Changes: append([]signerChange{}, t.changes...),
+		Open:    make([]openProposal, 0, len(t.open)),
+	}
+	var total uint64
+	for _, change := range t.changes {
+		total += change.Latency
+		if change.Latency > stats.MaxLatency {
+			stats.MaxLatency = change.Latency
+		}
+	}
+	if len(t.changes) > 0 {
+		stats.AverageLatency = float64(total) / float64(len(t.changes))
+	}
+	for epoch, opened := range t.open {
+		proposal := openProposal{Epoch: epoch, Opened: opened}
+		if t.head > opened {
+			proposal.Age = t.head - opened
+		}
+		stats.Open = append(stats.Open, proposal)
+	}
+	sort.Slice(stats.Open, func(i, j int) bool { return stats.Open[i].Epoch < stats.Open[j].Epoch })
+	return stats
+}
+
+// signerDiff returns the signers of a missing from b in ascending order.
+func signerDiff(a, b map[common.Address]bool) []common.Address {
+	diff := []common.Address{}
+	for signer := range a {
+		if !b[signer] {
+			diff = append(diff, signer)
+		}
+	}
+	sort.Sort(signersAscending(diff))
+	return diff
+}
+
+// trackVotes records the signer change state at a committed header, on top of
+// the snapshot of its parent. If the header is an epoch block, epoch and
+// validators are the registry epoch it applies.
+func (c *Clique) trackVotes(header *types.Header, snap *Snapshot, applied bool, epoch uint64, validators map[common.Address]bool) {
+	if c.now().Sub(time.Unix(int64(header.Time), 0)) > maxVoteAge {
+		return
+	}
+	number, current := header.Number.Uint64(), snap.EpochNumber
+	if applied {
+		c.votes.pass(number, epoch, snap.Signers, validators)
+		current = epoch
+	}
+	dnr, err := GetLatestDNR(c.db)
+	if err != nil {
+		return
+	}
+	c.votes.observe(number, dnr.LastEpochBlock, current)
+}
+
+// GetVoteLatencyStats returns the number of blocks elapsed between the registry
+// announcing each recent signer change and the epoch block applying it, along
+// with the age of the changes still pending, as observed by this node.
+func (api *API) GetVoteLatencyStats() *voteLatencyStats {
+	return api.clique.votes.stats()
+}
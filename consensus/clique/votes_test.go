@@ -0,0 +1,74 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that the vote tracker measures the blocks elapsed between a registry
+// epoch becoming pending and its application.
+func TestVoteTracker(t *testing.T) {
+	var (
+		a       = common.HexToAddress("0x01")
+		b       = common.HexToAddress("0x02")
+		c       = common.HexToAddress("0x03")
+		tracker = newVoteTracker()
+	)
+	// Blocks without a pending change don't open proposals
+	tracker.observe(10, 5, 5)
+	if stats := tracker.stats(); len(stats.Open) != 0 || stats.Head != 10 {
+		t.Fatalf("no change: open %v, head %d", stats.Open, stats.Head)
+	}
+	// A pending epoch is opened by the first block seeing it
+	tracker.observe(11, 7, 5)
+	tracker.observe(12, 7, 5)
+	tracker.observe(14, 7, 5)
+	stats := tracker.stats()
+	if len(stats.Open) != 1 || stats.Open[0].Epoch != 7 || stats.Open[0].Opened != 11 || stats.Open[0].Age != 3 {
+		t.Fatalf("open proposals mismatch: have %+v", stats.Open)
+	}
+	// Applying the epoch closes the proposal and records the change
+	tracker.pass(15, 7, map[common.Address]bool{a: true, b: true}, map[common.Address]bool{b: true, c: true})
+	tracker.observe(15, 7, 7)
+
+	stats = tracker.stats()
+	if len(stats.Open) != 0 {
+		t.Fatalf("proposal left open: %+v", stats.Open)
+	}
+	if len(stats.Changes) != 1 {
+		t.Fatalf("change count mismatch: have %d, want 1", len(stats.Changes))
+	}
+	change := stats.Changes[0]
+	if change.Opened != 11 || change.Passed != 15 || change.Latency != 4 {
+		t.Errorf("change mismatch: have %+v", change)
+	}
+	if len(change.Added) != 1 || change.Added[0] != c || len(change.Removed) != 1 || change.Removed[0] != a {
+		t.Errorf("signer diff mismatch: added %v, removed %v", change.Added, change.Removed)
+	}
+	if stats.AverageLatency != 4 || stats.MaxLatency != 4 {
+		t.Errorf("latency stats mismatch: average %v, max %d", stats.AverageLatency, stats.MaxLatency)
+	}
+	// Epochs applied without being seen pending pass right away, stale ones are ignored
+	tracker.pass(20, 9, nil, nil)
+	tracker.pass(21, 8, nil, nil)
+	if stats = tracker.stats(); len(stats.Changes) != 2 || stats.Changes[1].Latency != 0 {
+		t.Errorf("unseen change mismatch: have %+v", stats.Changes)
+	}
+}
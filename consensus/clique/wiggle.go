@@ -0,0 +1,75 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// wiggleLatencyFactor is the multiple of the observed propagation latency the
+// adaptive wiggle grants per signer, so that a racing out-of-turn block reaches
+// the other signers before their own delay expires.
+const wiggleLatencyFactor = 2
+
+var wiggleGauge = metrics.NewRegisteredGauge("clique/wiggle", nil) // Out-of-turn wiggle per signer in milliseconds
+
+// LatencyFn reports the recently observed block propagation latency, or false
+// if too few blocks were observed to tell.
+type LatencyFn func() (time.Duration, bool)
+
+// SetAdaptiveWiggle scales the out-of-turn wiggle per signer to the observed
+// block propagation latency, bounded by min and max. Well connected networks
+// fail over to out-of-turn signers faster, while lossy ones spread the racing
+// signers further apart to avoid forks. A nil latency function restores the
+// fixed wiggle.
+func (c *Clique) SetAdaptiveWiggle(latency LatencyFn, min, max time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.wiggleLatency, c.wiggleMin, c.wiggleMax = latency, min, max
+}
+
+// wiggle returns the maximum random delay per signer of out-of-turn blocks,
+// either the fixed one or the one adapted to the observed latency.
+func (c *Clique) wiggle() time.Duration {
+	c.lock.RLock()
+	latency, min, max := c.wiggleLatency, c.wiggleMin, c.wiggleMax
+	c.lock.RUnlock()
+
+	if latency == nil {
+		return wiggleTime
+	}
+	wiggle := wiggleTime
+	if observed, ok := latency(); ok {
+		wiggle = wiggleLatencyFactor * observed
+	}
+	if wiggle < min {
+		wiggle = min
+	}
+	if max > 0 && wiggle > max {
+		wiggle = max
+	}
+	if wiggle <= 0 {
+		wiggle = time.Millisecond // Random delays need a positive range
+	}
+	wiggleGauge.Update(wiggle.Milliseconds())
+	log.Trace("Adapted out-of-turn wiggle", "wiggle", wiggle)
+	return wiggle
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that the adaptive wiggle follows the observed latency within its bounds.
+func TestAdaptiveWiggle(t *testing.T) {
+	c := new(Clique)
+	if wiggle := c.wiggle(); wiggle != wiggleTime {
+		t.Fatalf("fixed wiggle mismatch: have %v, want %v", wiggle, wiggleTime)
+	}
+	tests := []struct {
+		latency  time.Duration
+		observed bool
+		min, max time.Duration
+		want     time.Duration
+	}{
+		{100 * time.Millisecond, true, 0, 0, 200 * time.Millisecond},                   // Scaled latency
+		{10 * time.Millisecond, true, 50 * time.Millisecond, 0, 50 * time.Millisecond}, // Lower bound
+		{2 * time.Second, true, 0, time.Second, time.Second},                           // Upper bound
+		{0, false, 0, 0, wiggleTime},                                                   // Unknown latency
+		{0, false, 0, 100 * time.Millisecond, 100 * time.Millisecond},                  // Unknown latency, bounded
+		{0, true, 0, 0, time.Millisecond},                                              // Zero latency
+	}
+	for i, tt := range tests {
+		latency, observed := tt.latency, tt.observed
+		c.SetAdaptiveWiggle(func() (time.Duration, bool) { return latency, observed }, tt.min, tt.max)
+		if wiggle := c.wiggle(); wiggle != tt.want {
+			t.Errorf("test %d: wiggle mismatch: have %v, want %v", i, wiggle, tt.want)
+		}
+	}
+	c.SetAdaptiveWiggle(nil, 0, 0)
+	if wiggle := c.wiggle(); wiggle != wiggleTime {
+		t.Errorf("restored wiggle mismatch: have %v, want %v", wiggle, wiggleTime)
+	}
+}
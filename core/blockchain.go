@@ -79,6 +79,8 @@ var (
 	blockPrefetchExecuteTimer   = metrics.NewRegisteredTimer("chain/prefetch/executes", nil)
 	blockPrefetchInterruptMeter = metrics.NewRegisteredMeter("chain/prefetch/interrupts", nil)
 
+	headerQuarantineMeter = metrics.NewRegisteredMeter("chain/quarantine", nil)
+
 	errInsertionInterrupted = errors.New("insertion is interrupted")
 	errChainStopped         = errors.New("blockchain is stopped")
 )
@@ -167,6 +169,8 @@ type BlockChain struct {
 	triegc *prque.Prque   // Priority queue mapping block numbers to tries to gc
 	gcproc time.Duration  // Accumulates canonical block processing for trie dumping
 
+	importTime int64 // Moving average of the canonical block processing time in nanoseconds (atomic)
+
 	// txLookupLimit is the maximum number of blocks from head whose tx indices
 	// are reserved:
 	//  * 0:   means no limit and regenerate any missing indexes
@@ -188,6 +192,8 @@ type BlockChain struct {
 	// Readers don't need to take it, they can just read the database.
 	chainmu *syncx.ClosableMutex
 
+	quarantineLock sync.Mutex // Serializes updates of the rejected header list
+
 	currentBlock          atomic.Value // Current head of the block chain
 	currentFastBlock      atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 	currentFinalizedBlock atomic.Value // Current finalized head
@@ -1528,6 +1534,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals, setHead bool)
 	case err != nil && !errors.Is(err, ErrKnownBlock):
 		bc.futureBlocks.Remove(block.Hash())
 		stats.ignored += len(it.chain)
+		if herr := it.headerError(); herr != nil {
+			bc.QuarantineHeader(block.Header(), "", herr)
+		}
 		bc.reportBlock(block, nil, err)
 		return it.index, err
 	}
@@ -1705,6 +1714,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals, setHead bool)
 
 			// Only count canonical blocks for GC processing time
 			bc.gcproc += proctime
+			bc.updateImportTime(proctime)
 
 		case SideStatTy:
 			log.Debug("Inserted forked block", "number", block.Number(), "hash", block.Hash(),
@@ -1736,6 +1746,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals, setHead bool)
 			stats.queued++
 		}
 	}
+	if herr := it.headerError(); block != nil && herr != nil {
+		bc.QuarantineHeader(block.Header(), "", herr)
+	}
 	stats.ignored += it.remaining()
 
 	return it.index, err
@@ -2173,6 +2186,40 @@ func (bc *BlockChain) updateFutureBlocks() {
 	}
 }
 
+// PrefetchTransactions speculatively runs the transactions expected in a block
+// with the given header on top of its parent's state, warming up the caches of
+// the trie nodes they touch, and returns the access lists of the transactions
+// run. The access lists are only tracked from Berlin on and otherwise empty.
+func (bc *BlockChain) PrefetchTransactions(header *types.Header, txs []*types.Transaction, interrupt *uint32) (map[common.Hash]types.AccessList, error) {
+	statedb, err := bc.prefetchState(header)
+	if err != nil {
+		return nil, err
+	}
+	return bc.prefetcher.PrefetchTransactions(header, txs, statedb, bc.vmConfig, interrupt), nil
+}
+
+// PrefetchAccessLists reads the state in the given access lists on top of the
+// parent's state of the given header, warming up the caches of the trie nodes of
+// transactions prefetched on top of an earlier head. It returns the number of
+// access lists read.
+func (bc *BlockChain) PrefetchAccessLists(header *types.Header, lists []types.AccessList, interrupt *uint32) (int, error) {
+	statedb, err := bc.prefetchState(header)
+	if err != nil {
+		return 0, err
+	}
+	return bc.prefetcher.PrefetchAccessLists(lists, statedb, interrupt), nil
+}
+
+// prefetchState returns the state of the parent of the given header to prefetch
+// on top of.
+func (bc *BlockChain) prefetchState(header *types.Header) (*state.StateDB, error) {
+	parent := bc.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	return state.New(parent.Root, bc.stateCache, bc.snaps)
+}
+
 // skipBlock returns 'true', if the block being imported can be skipped over, meaning
 // that the block does not need to be processed but can be considered already fully 'done'.
 func (bc *BlockChain) skipBlock(err error, it *insertIterator) bool {
@@ -2332,6 +2379,29 @@ Error: %v
 `, bc.chainConfig, block.Number(), block.Hash(), receiptString, err))
 }
 
+// QuarantineHeader records a header which failed verification along with the
+// reason, and the peer it was received from if known. Errors which don't make
+// the header itself invalid, like a missing parent, are ignored.
+func (bc *BlockChain) QuarantineHeader(header *types.Header, peer string, err error) {
+	switch {
+	case errors.Is(err, consensus.ErrUnknownAncestor), errors.Is(err, consensus.ErrPrunedAncestor),
+		errors.Is(err, consensus.ErrFutureBlock), errors.Is(err, ErrKnownBlock),
+		errors.Is(err, errInsertionInterrupted), errors.Is(err, errChainStopped):
+		return
+	}
+	bc.quarantineLock.Lock()
+	defer bc.quarantineLock.Unlock()
+
+	rawdb.WriteRejectedHeader(bc.db, &rawdb.RejectedHeader{
+		Header: header,
+		Reason: err.Error(),
+		Peer:   peer,
+		Time:   uint64(time.Now().Unix()),
+	})
+	headerQuarantineMeter.Mark(1)
+	log.Debug("Quarantined rejected header", "number", header.Number, "hash", header.Hash(), "peer", peer, "err", err)
+}
+
 // InsertHeaderChain attempts to insert the given header chain in to the local
 // chain, possibly creating a reorg. If an error is returned, it will return the
 // index number of the failing header as well an error describing what went wrong.
@@ -2346,6 +2416,9 @@ func (bc *BlockChain) InsertHeaderChain(chain []*types.Header, checkFreq int) (i
 	}
 	start := time.Now()
 	if i, err := bc.hc.ValidateHeaderChain(chain, checkFreq); err != nil {
+		if i >= 0 && i < len(chain) {
+			bc.QuarantineHeader(chain[i], "", err)
+		}
 		return i, err
 	}
 
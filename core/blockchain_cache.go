@@ -0,0 +1,111 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	trieDirtyLimitGauge = metrics.NewRegisteredGauge("chain/cache/dirtylimit", nil) // In MB
+	trieTimeLimitGauge  = metrics.NewRegisteredGauge("chain/cache/timelimit", nil)  // In seconds
+	importTimeGauge     = metrics.NewRegisteredGauge("chain/cache/importtime", nil) // In microseconds
+)
+
+// errTrieDirtyDisabled is returned when adjusting the dirty trie cache of an
+// archive node, which doesn't have one.
+var errTrieDirtyDisabled = errors.New("dirty trie cache disabled (archive node)")
+
+// CacheStatus is the state of the in-memory caches of the chain. The clean trie
+// and snapshot caches are allocated at startup, only the dirty trie cache can be
+// adjusted at runtime.
+type CacheStatus struct {
+	TrieCleanLimit int                // Memory allowance (MB) of the clean trie cache
+	SnapshotLimit  int                // Memory allowance (MB) of the snapshot cache
+	TrieDirtyLimit int                // Memory limit (MB) at which dirty trie nodes are flushed
+	TrieTimeLimit  time.Duration      // Processing time after which the in-memory trie is flushed
+	TrieDirtySize  common.StorageSize // Size of the dirty trie nodes held in memory
+	ImportTime     time.Duration      // Moving average of the canonical block processing time
+}
+
+// updateImportTime folds the processing time of a canonical block into the moving
+// average of the import time.
+func (bc *BlockChain) updateImportTime(proctime time.Duration) {
+	avg := atomic.LoadInt64(&bc.importTime)
+	if avg == 0 {
+		avg = int64(proctime)
+	} else {
+		avg += (int64(proctime) - avg) / 16
+	}
+	atomic.StoreInt64(&bc.importTime, avg)
+	importTimeGauge.Update(avg / int64(time.Microsecond))
+}
+
+// CacheStatus returns the state of the in-memory caches of the chain.
+func (bc *BlockChain) CacheStatus() (CacheStatus, error) {
+	if !bc.chainmu.TryLock() {
+		return CacheStatus{}, errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	nodes, _ := bc.stateCache.TrieDB().Size()
+	return CacheStatus{
+		TrieCleanLimit: bc.cacheConfig.TrieCleanLimit,
+		SnapshotLimit:  bc.cacheConfig.SnapshotLimit,
+		TrieDirtyLimit: bc.cacheConfig.TrieDirtyLimit,
+		TrieTimeLimit:  bc.cacheConfig.TrieTimeLimit,
+		TrieDirtySize:  nodes,
+		ImportTime:     time.Duration(atomic.LoadInt64(&bc.importTime)),
+	}, nil
+}
+
+// SetCacheLimits adjusts the memory limit (MB) at which dirty trie nodes are
+// flushed and the processing time after which the in-memory trie is flushed.
+// Zero values leave the respective limit unchanged.
+func (bc *BlockChain) SetCacheLimits(dirtyLimit int, timeLimit time.Duration) error {
+	if dirtyLimit < 0 || timeLimit < 0 {
+		return errors.New("negative cache limit")
+	}
+	if !bc.chainmu.TryLock() {
+		return errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	if bc.cacheConfig.TrieDirtyDisabled {
+		return errTrieDirtyDisabled
+	}
+	// The cache config may be shared with other chains, adjust a copy
+	config := *bc.cacheConfig
+	if dirtyLimit > 0 {
+		config.TrieDirtyLimit = dirtyLimit
+	}
+	if timeLimit > 0 {
+		config.TrieTimeLimit = timeLimit
+	}
+	bc.cacheConfig = &config
+	trieDirtyLimitGauge.Update(int64(config.TrieDirtyLimit))
+	trieTimeLimitGauge.Update(int64(config.TrieTimeLimit / time.Second))
+
+	log.Info("Updated trie cache limits", "dirty", config.TrieDirtyLimit, "time", config.TrieTimeLimit)
+	return nil
+}
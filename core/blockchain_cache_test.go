@@ -0,0 +1,56 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+)
+
+// Tests that the dirty trie cache limits are adjusted at runtime without
+// affecting the configuration shared with other chains.
+func TestSetCacheLimits(t *testing.T) {
+	_, chain, err := newCanonical(ethash.NewFaker(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if err := chain.SetCacheLimits(-1, 0); err == nil {
+		t.Fatalf("negative limit accepted")
+	}
+	if err := chain.SetCacheLimits(512, 0); err != nil {
+		t.Fatalf("failed to set limits: %v", err)
+	}
+	status, err := chain.CacheStatus()
+	if err != nil {
+		t.Fatalf("failed to retrieve status: %v", err)
+	}
+	if status.TrieDirtyLimit != 512 || status.TrieTimeLimit != defaultCacheConfig.TrieTimeLimit {
+		t.Fatalf("limits mismatch: have %d/%v, want 512/%v", status.TrieDirtyLimit, status.TrieTimeLimit, defaultCacheConfig.TrieTimeLimit)
+	}
+	if defaultCacheConfig.TrieDirtyLimit == 512 {
+		t.Fatalf("shared cache config modified")
+	}
+	chain.updateImportTime(160 * time.Millisecond)
+	chain.updateImportTime(0)
+	if status, _ = chain.CacheStatus(); status.ImportTime != 150*time.Millisecond {
+		t.Fatalf("import time mismatch: have %v, want %v", status.ImportTime, 150*time.Millisecond)
+	}
+}
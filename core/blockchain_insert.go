@@ -124,6 +124,15 @@ func (it *insertIterator) next() (*types.Block, error) {
 	return it.chain[it.index], it.validator.ValidateBody(it.chain[it.index])
 }
 
+// headerError returns the header verification error of the current block, nil
+// if the header is valid, even if the body isn't.
+func (it *insertIterator) headerError() error {
+	if it.index < 0 || it.index >= len(it.errors) {
+		return nil
+	}
+	return it.errors[it.index]
+}
+
 // peek returns the next block in the iterator, along with any potential validation
 // error for that block, but does **not** advance the iterator.
 //
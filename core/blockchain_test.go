@@ -3758,3 +3758,30 @@ func TestSetCanonical(t *testing.T) {
 	chain.SetCanonical(canon[TriesInMemory-1])
 	verify(canon[TriesInMemory-1])
 }
+
+// Tests that headers failing verification are quarantined, while the ones only
+// missing their parent are not.
+func TestQuarantineRejectedHeaders(t *testing.T) {
+	db, chain, err := newCanonical(ethash.NewFakeFailer(3), 0, false)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer chain.Stop()
+
+	headers := makeHeaderChain(chain.CurrentHeader(), 4, ethash.NewFaker(), db, 0)
+	if _, err := chain.InsertHeaderChain(headers, 1); err == nil {
+		t.Fatalf("invalid header chain inserted")
+	}
+	rejected := rawdb.ReadRejectedHeaders(db)
+	if len(rejected) != 1 {
+		t.Fatalf("rejected header count mismatch: have %d, want 1", len(rejected))
+	}
+	if rejected[0].Header.Hash() != headers[2].Hash() || rejected[0].Reason == "" {
+		t.Fatalf("rejected header mismatch: number %d, reason %q", rejected[0].Header.Number, rejected[0].Reason)
+	}
+	// Headers with unknown ancestors aren't invalid by themselves
+	chain.QuarantineHeader(headers[3], "peer", consensus.ErrUnknownAncestor)
+	if rejected := rawdb.ReadRejectedHeaders(db); len(rejected) != 1 {
+		t.Fatalf("header with unknown ancestor quarantined")
+	}
+}
@@ -0,0 +1,392 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package chainspec converts clique genesis specifications to and from the
+// chainspec format of other Ethereum clients, extended with the parameters of
+// the validator registry epochs.
+package chainspec
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var errNotClique = errors.New("only clique chains are supported")
+
+// Spec is a chain specification in the format used by other clients, where the
+// forks are expressed as the transitions of their individual EIPs.
+type Spec struct {
+	Name   string `json:"name"`
+	Engine struct {
+		Clique *struct {
+			Params CliqueParams `json:"params"`
+		} `json:"clique,omitempty"`
+	} `json:"engine"`
+	Params   Params                                `json:"params"`
+	Genesis  Genesis                               `json:"genesis"`
+	Accounts map[common.UnprefixedAddress]*Account `json:"accounts"`
+}
+
+// CliqueParams are the parameters of the clique engine. Epochs follow the
+// validator registry instead of a fixed checkpoint interval.
+type CliqueParams struct {
+	Period                      hexutil.Uint64   `json:"period"`
+	DNR                         common.Address   `json:"dnr"`                                   // Validator registry contract on Ethereum
+	EpochBlock                  hexutil.Uint64   `json:"epochBlock"`                            // First registry epoch followed
+	API                         string           `json:"api,omitempty"`                         // Ethereum RPC URL the registry is read from
+	InitialValidators           []common.Address `json:"initialValidators,omitempty"`           // Validators of the first epoch
+	PerformanceCommitTransition *hexutil.Uint64  `json:"performanceCommitTransition,omitempty"` // Epoch blocks commit to the validator performance
+	SnapshotCommitTransition    *hexutil.Uint64  `json:"snapshotCommitTransition,omitempty"`    // Epoch blocks commit to the snapshot they produce
+	SignerPolicy                *SignerPolicy    `json:"signerPolicy,omitempty"`                // Guardrails on the signer set changes of epoch blocks
+}
+
+// SignerPolicy are the guardrails on the signer set changes applied by the
+// epoch blocks, enforced from their transition on.
+type SignerPolicy struct {
+	Transition hexutil.Uint64 `json:"transition"`
+	MinSigners hexutil.Uint64 `json:"minSigners,omitempty"`
+	MaxSigners hexutil.Uint64 `json:"maxSigners,omitempty"`
+	Cooldown   hexutil.Uint64 `json:"cooldown,omitempty"`
+}
+
+// Params are the chain parameters and the activation blocks of the EIPs. Unset
+// transitions are never activated.
+type Params struct {
+	ChainID              hexutil.Uint64      `json:"chainID"`
+	NetworkID            hexutil.Uint64      `json:"networkID"`
+	AccountStartNonce    hexutil.Uint64      `json:"accountStartNonce"`
+	MaximumExtraDataSize hexutil.Uint64      `json:"maximumExtraDataSize"`
+	MinGasLimit          hexutil.Uint64      `json:"minGasLimit"`
+	GasLimitBoundDivisor math.HexOrDecimal64 `json:"gasLimitBoundDivisor"`
+	MaxCodeSize          hexutil.Uint64      `json:"maxCodeSize"`
+
+	HomesteadTransition   *hexutil.Uint64 `json:"homesteadTransition,omitempty"`
+	EIP150Transition      *hexutil.Uint64 `json:"eip150Transition,omitempty"`
+	EIP155Transition      *hexutil.Uint64 `json:"eip155Transition,omitempty"`
+	EIP160Transition      *hexutil.Uint64 `json:"eip160Transition,omitempty"`
+	EIP161abcTransition   *hexutil.Uint64 `json:"eip161abcTransition,omitempty"`
+	EIP161dTransition     *hexutil.Uint64 `json:"eip161dTransition,omitempty"`
+	MaxCodeSizeTransition *hexutil.Uint64 `json:"maxCodeSizeTransition,omitempty"`
+
+	EIP140Transition *hexutil.Uint64 `json:"eip140Transition,omitempty"`
+	EIP211Transition *hexutil.Uint64 `json:"eip211Transition,omitempty"`
+	EIP214Transition *hexutil.Uint64 `json:"eip214Transition,omitempty"`
+	EIP658Transition *hexutil.Uint64 `json:"eip658Transition,omitempty"`
+
+	EIP145Transition         *hexutil.Uint64 `json:"eip145Transition,omitempty"`
+	EIP1014Transition        *hexutil.Uint64 `json:"eip1014Transition,omitempty"`
+	EIP1052Transition        *hexutil.Uint64 `json:"eip1052Transition,omitempty"`
+	EIP1283Transition        *hexutil.Uint64 `json:"eip1283Transition,omitempty"`
+	EIP1283DisableTransition *hexutil.Uint64 `json:"eip1283DisableTransition,omitempty"`
+
+	EIP1344Transition *hexutil.Uint64 `json:"eip1344Transition,omitempty"`
+	EIP1884Transition *hexutil.Uint64 `json:"eip1884Transition,omitempty"`
+	EIP2028Transition *hexutil.Uint64 `json:"eip2028Transition,omitempty"`
+	EIP2200Transition *hexutil.Uint64 `json:"eip2200Transition,omitempty"`
+
+	EIP2565Transition *hexutil.Uint64 `json:"eip2565Transition,omitempty"`
+	EIP2718Transition *hexutil.Uint64 `json:"eip2718Transition,omitempty"`
+	EIP2929Transition *hexutil.Uint64 `json:"eip2929Transition,omitempty"`
+	EIP2930Transition *hexutil.Uint64 `json:"eip2930Transition,omitempty"`
+
+	EIP1559Transition *hexutil.Uint64 `json:"eip1559Transition,omitempty"`
+	EIP3198Transition *hexutil.Uint64 `json:"eip3198Transition,omitempty"`
+	EIP3529Transition *hexutil.Uint64 `json:"eip3529Transition,omitempty"`
+	EIP3541Transition *hexutil.Uint64 `json:"eip3541Transition,omitempty"`
+
+	// Forks without EIP transitions of their own
+	MuirGlacierTransition   *hexutil.Uint64 `json:"muirGlacierTransition,omitempty"`
+	ArrowGlacierTransition  *hexutil.Uint64 `json:"arrowGlacierTransition,omitempty"`
+	MergeForkTransition     *hexutil.Uint64 `json:"mergeForkTransition,omitempty"`
+	TerminalTotalDifficulty *hexutil.Big    `json:"terminalTotalDifficulty,omitempty"`
+
+	// PrecompileExtensions are the activation blocks of the precompiles of
+	// the EVM extension registry
+	PrecompileExtensions map[string]hexutil.Uint64 `json:"precompileExtensions,omitempty"`
+}
+
+// Genesis is the header of the genesis block.
+type Genesis struct {
+	Seal struct {
+		Ethereum struct {
+			Nonce   types.BlockNonce `json:"nonce"`
+			MixHash common.Hash      `json:"mixHash"`
+		} `json:"ethereum"`
+	} `json:"seal"`
+
+	Difficulty    *hexutil.Big   `json:"difficulty"`
+	Author        common.Address `json:"author"`
+	Timestamp     hexutil.Uint64 `json:"timestamp"`
+	ParentHash    common.Hash    `json:"parentHash"`
+	ExtraData     hexutil.Bytes  `json:"extraData"`
+	GasLimit      hexutil.Uint64 `json:"gasLimit"`
+	BaseFeePerGas *hexutil.Big   `json:"baseFeePerGas,omitempty"`
+}
+
+// Account is a prefunded genesis account.
+type Account struct {
+	Balance *math.HexOrDecimal256       `json:"balance"`
+	Nonce   math.HexOrDecimal64         `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// transition converts a fork block into an EIP transition.
+func transition(num *big.Int) *hexutil.Uint64 {
+	if num == nil {
+		return nil
+	}
+	n := hexutil.Uint64(num.Uint64())
+	return &n
+}
+
+// New converts a clique genesis specification into a chainspec.
+func New(name string, genesis *core.Genesis) (*Spec, error) {
+	config := genesis.Config
+	if config == nil || config.Clique == nil {
+		return nil, errNotClique
+	}
+	if config.DAOForkBlock != nil {
+		return nil, errors.New("the DAO fork has no chainspec transition")
+	}
+	spec := &Spec{Name: name}
+
+	spec.Engine.Clique = new(struct {
+		Params CliqueParams `json:"params"`
+	})
+	spec.Engine.Clique.Params = CliqueParams{
+		Period:                      hexutil.Uint64(config.Clique.Period),
+		DNR:                         config.Clique.DNR,
+		EpochBlock:                  hexutil.Uint64(config.Clique.EpochBlock),
+		API:                         config.Clique.API,
+		InitialValidators:           config.Clique.InitialValidators,
+		PerformanceCommitTransition: transition(config.Clique.PerformanceCommitBlock),
+		SnapshotCommitTransition:    transition(config.Clique.SnapshotCommitBlock),
+	}
+	if policy := config.Clique.SignerPolicy; policy != nil {
+		if policy.Block == nil {
+			return nil, errors.New("the signer policy has no activation block")
+		}
+		spec.Engine.Clique.Params.SignerPolicy = &SignerPolicy{
+			Transition: hexutil.Uint64(policy.Block.Uint64()),
+			MinSigners: hexutil.Uint64(policy.MinSigners),
+			MaxSigners: hexutil.Uint64(policy.MaxSigners),
+			Cooldown:   hexutil.Uint64(policy.Cooldown),
+		}
+	}
+	p := &spec.Params
+	if config.ChainID != nil {
+		p.ChainID = hexutil.Uint64(config.ChainID.Uint64())
+		p.NetworkID = p.ChainID
+	}
+	p.MaximumExtraDataSize = hexutil.Uint64(params.MaximumExtraDataSize)
+	p.MinGasLimit = hexutil.Uint64(params.MinGasLimit)
+	p.GasLimitBoundDivisor = math.HexOrDecimal64(params.GasLimitBoundDivisor)
+	p.MaxCodeSize = hexutil.Uint64(params.MaxCodeSize)
+
+	p.HomesteadTransition = transition(config.HomesteadBlock)
+	p.EIP150Transition = transition(config.EIP150Block)
+	p.EIP155Transition = transition(config.EIP155Block)
+	p.EIP160Transition = transition(config.EIP158Block)
+	p.EIP161abcTransition = transition(config.EIP158Block)
+	p.EIP161dTransition = transition(config.EIP158Block)
+	p.MaxCodeSizeTransition = transition(config.EIP158Block)
+
+	p.EIP140Transition = transition(config.ByzantiumBlock)
+	p.EIP211Transition = transition(config.ByzantiumBlock)
+	p.EIP214Transition = transition(config.ByzantiumBlock)
+	p.EIP658Transition = transition(config.ByzantiumBlock)
+
+	p.EIP145Transition = transition(config.ConstantinopleBlock)
+	p.EIP1014Transition = transition(config.ConstantinopleBlock)
+	p.EIP1052Transition = transition(config.ConstantinopleBlock)
+	p.EIP1283Transition = transition(config.ConstantinopleBlock)
+	p.EIP1283DisableTransition = transition(config.PetersburgBlock)
+
+	p.EIP1344Transition = transition(config.IstanbulBlock)
+	p.EIP1884Transition = transition(config.IstanbulBlock)
+	p.EIP2028Transition = transition(config.IstanbulBlock)
+	p.EIP2200Transition = transition(config.IstanbulBlock)
+
+	p.EIP2565Transition = transition(config.BerlinBlock)
+	p.EIP2718Transition = transition(config.BerlinBlock)
+	p.EIP2929Transition = transition(config.BerlinBlock)
+	p.EIP2930Transition = transition(config.BerlinBlock)
+
+	p.EIP1559Transition = transition(config.LondonBlock)
+	p.EIP3198Transition = transition(config.LondonBlock)
+	p.EIP3529Transition = transition(config.LondonBlock)
+	p.EIP3541Transition = transition(config.LondonBlock)
+
+	p.MuirGlacierTransition = transition(config.MuirGlacierBlock)
+	p.ArrowGlacierTransition = transition(config.ArrowGlacierBlock)
+	p.MergeForkTransition = transition(config.MergeForkBlock)
+	p.TerminalTotalDifficulty = (*hexutil.Big)(config.TerminalTotalDifficulty)
+
+	if len(config.PrecompileExtensions) > 0 {
+		p.PrecompileExtensions = make(map[string]hexutil.Uint64)
+		for name, num := range config.PrecompileExtensions {
+			if num != nil {
+				p.PrecompileExtensions[name] = hexutil.Uint64(num.Uint64())
+			}
+		}
+	}
+	g := &spec.Genesis
+	g.Seal.Ethereum.Nonce = types.EncodeNonce(genesis.Nonce)
+	g.Seal.Ethereum.MixHash = genesis.Mixhash
+	g.Difficulty = (*hexutil.Big)(genesis.Difficulty)
+	g.Author = genesis.Coinbase
+	g.Timestamp = hexutil.Uint64(genesis.Timestamp)
+	g.ParentHash = genesis.ParentHash
+	g.ExtraData = genesis.ExtraData
+	g.GasLimit = hexutil.Uint64(genesis.GasLimit)
+	g.BaseFeePerGas = (*hexutil.Big)(genesis.BaseFee)
+
+	spec.Accounts = make(map[common.UnprefixedAddress]*Account, len(genesis.Alloc))
+	for addr, account := range genesis.Alloc {
+		balance := new(big.Int)
+		if account.Balance != nil {
+			balance.Set(account.Balance)
+		}
+		spec.Accounts[common.UnprefixedAddress(addr)] = &Account{
+			Balance: (*math.HexOrDecimal256)(balance),
+			Nonce:   math.HexOrDecimal64(account.Nonce),
+			Code:    account.Code,
+			Storage: account.Storage,
+		}
+	}
+	return spec, nil
+}
+
+// fork converts the transitions of the EIPs activated by a fork back into its
+// block, failing if they don't all activate at once.
+func fork(name string, transitions ...*hexutil.Uint64) (*big.Int, error) {
+	first := transitions[0]
+	for _, t := range transitions[1:] {
+		if (t == nil) != (first == nil) || (t != nil && *t != *first) {
+			return nil, fmt.Errorf("%s EIPs activate at different blocks", name)
+		}
+	}
+	if first == nil {
+		return nil, nil
+	}
+	return new(big.Int).SetUint64(uint64(*first)), nil
+}
+
+// ToGenesis converts the chainspec into a clique genesis specification.
+func (spec *Spec) ToGenesis() (*core.Genesis, error) {
+	if spec.Engine.Clique == nil {
+		return nil, errNotClique
+	}
+	clique := spec.Engine.Clique.Params
+	p := spec.Params
+
+	config := &params.ChainConfig{
+		ChainID: new(big.Int).SetUint64(uint64(p.ChainID)),
+		Clique: &params.CliqueConfig{
+			Period:            uint64(clique.Period),
+			DNR:               clique.DNR,
+			EpochBlock:        uint64(clique.EpochBlock),
+			API:               clique.API,
+			InitialValidators: clique.InitialValidators,
+		},
+		TerminalTotalDifficulty: (*big.Int)(p.TerminalTotalDifficulty),
+	}
+	if t := clique.PerformanceCommitTransition; t != nil {
+		config.Clique.PerformanceCommitBlock = new(big.Int).SetUint64(uint64(*t))
+	}
+	if t := clique.SnapshotCommitTransition; t != nil {
+		config.Clique.SnapshotCommitBlock = new(big.Int).SetUint64(uint64(*t))
+	}
+	if policy := clique.SignerPolicy; policy != nil {
+		config.Clique.SignerPolicy = &params.CliqueSignerPolicy{
+			Block:      new(big.Int).SetUint64(uint64(policy.Transition)),
+			MinSigners: uint64(policy.MinSigners),
+			MaxSigners: uint64(policy.MaxSigners),
+			Cooldown:   uint64(policy.Cooldown),
+		}
+	}
+	forks := []struct {
+		name        string
+		block       **big.Int
+		transitions []*hexutil.Uint64
+	}{
+		{"Homestead", &config.HomesteadBlock, []*hexutil.Uint64{p.HomesteadTransition}},
+		{"Tangerine Whistle", &config.EIP150Block, []*hexutil.Uint64{p.EIP150Transition}},
+		{"EIP-155", &config.EIP155Block, []*hexutil.Uint64{p.EIP155Transition}},
+		{"Spurious Dragon", &config.EIP158Block, []*hexutil.Uint64{p.EIP161abcTransition, p.EIP160Transition, p.EIP161dTransition, p.MaxCodeSizeTransition}},
+		{"Byzantium", &config.ByzantiumBlock, []*hexutil.Uint64{p.EIP140Transition, p.EIP211Transition, p.EIP214Transition, p.EIP658Transition}},
+		{"Constantinople", &config.ConstantinopleBlock, []*hexutil.Uint64{p.EIP145Transition, p.EIP1014Transition, p.EIP1052Transition, p.EIP1283Transition}},
+		{"Petersburg", &config.PetersburgBlock, []*hexutil.Uint64{p.EIP1283DisableTransition}},
+		{"Istanbul", &config.IstanbulBlock, []*hexutil.Uint64{p.EIP1344Transition, p.EIP1884Transition, p.EIP2028Transition, p.EIP2200Transition}},
+		{"Muir Glacier", &config.MuirGlacierBlock, []*hexutil.Uint64{p.MuirGlacierTransition}},
+		{"Berlin", &config.BerlinBlock, []*hexutil.Uint64{p.EIP2929Transition, p.EIP2565Transition, p.EIP2718Transition, p.EIP2930Transition}},
+		{"London", &config.LondonBlock, []*hexutil.Uint64{p.EIP1559Transition, p.EIP3198Transition, p.EIP3529Transition, p.EIP3541Transition}},
+		{"Arrow Glacier", &config.ArrowGlacierBlock, []*hexutil.Uint64{p.ArrowGlacierTransition}},
+		{"Merge", &config.MergeForkBlock, []*hexutil.Uint64{p.MergeForkTransition}},
+	}
+	for _, f := range forks {
+		block, err := fork(f.name, f.transitions...)
+		if err != nil {
+			return nil, err
+		}
+		*f.block = block
+	}
+	if len(p.PrecompileExtensions) > 0 {
+		config.PrecompileExtensions = make(map[string]*big.Int)
+		for name, num := range p.PrecompileExtensions {
+			config.PrecompileExtensions[name] = new(big.Int).SetUint64(uint64(num))
+		}
+	}
+	if err := config.CheckConfigForkOrder(); err != nil {
+		return nil, err
+	}
+	g := spec.Genesis
+	genesis := &core.Genesis{
+		Config:     config,
+		Nonce:      g.Seal.Ethereum.Nonce.Uint64(),
+		Timestamp:  uint64(g.Timestamp),
+		ExtraData:  g.ExtraData,
+		GasLimit:   uint64(g.GasLimit),
+		Difficulty: (*big.Int)(g.Difficulty),
+		Mixhash:    g.Seal.Ethereum.MixHash,
+		Coinbase:   g.Author,
+		ParentHash: g.ParentHash,
+		BaseFee:    (*big.Int)(g.BaseFeePerGas),
+		Alloc:      make(core.GenesisAlloc, len(spec.Accounts)),
+	}
+	for addr, account := range spec.Accounts {
+		balance := new(big.Int)
+		if account.Balance != nil {
+			balance.Set((*big.Int)(account.Balance))
+		}
+		genesis.Alloc[common.Address(addr)] = core.GenesisAccount{
+			Balance: balance,
+			Nonce:   uint64(account.Nonce),
+			Code:    account.Code,
+			Storage: account.Storage,
+		}
+	}
+	return genesis, nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package chainspec
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func testGenesis() *core.Genesis {
+	return &core.Genesis{
+		Config: &params.ChainConfig{
+			ChainID:             big.NewInt(1337),
+			HomesteadBlock:      big.NewInt(0),
+			EIP150Block:         big.NewInt(0),
+			EIP155Block:         big.NewInt(0),
+			EIP158Block:         big.NewInt(0),
+			ByzantiumBlock:      big.NewInt(0),
+			ConstantinopleBlock: big.NewInt(0),
+			PetersburgBlock:     big.NewInt(0),
+			IstanbulBlock:       big.NewInt(0),
+			BerlinBlock:         big.NewInt(10),
+			LondonBlock:         big.NewInt(20),
+			PrecompileExtensions: map[string]*big.Int{
+				"blsVerify": big.NewInt(30),
+			},
+			Clique: &params.CliqueConfig{
+				Period:                 5,
+				DNR:                    common.Address{0xdd},
+				EpochBlock:             100,
+				InitialValidators:      []common.Address{{0x01}, {0x02}},
+				PerformanceCommitBlock: big.NewInt(40),
+				SnapshotCommitBlock:    big.NewInt(60),
+				SignerPolicy: &params.CliqueSignerPolicy{
+					Block:      big.NewInt(70),
+					MinSigners: 2,
+					MaxSigners: 21,
+					Cooldown:   1000,
+				},
+			},
+		},
+		Timestamp:  1650000000,
+		ExtraData:  make([]byte, 97),
+		GasLimit:   30000000,
+		Difficulty: big.NewInt(1),
+		Alloc: core.GenesisAlloc{
+			common.Address{0xaa}: {Balance: big.NewInt(1000000000000000000)},
+			common.Address{0xbb}: {
+				Balance: big.NewInt(0),
+				Nonce:   1,
+				Code:    []byte{0x60, 0x00},
+				Storage: map[common.Hash]common.Hash{{0x01}: {0x02}},
+			},
+		},
+	}
+}
+
+// Tests that a genesis survives the conversion to a chainspec and back.
+func TestRoundTrip(t *testing.T) {
+	genesis := testGenesis()
+
+	spec, err := New("aksara", genesis)
+	if err != nil {
+		t.Fatalf("failed to create chainspec: %v", err)
+	}
+	blob, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatalf("failed to encode chainspec: %v", err)
+	}
+	dec := new(Spec)
+	if err := json.Unmarshal(blob, dec); err != nil {
+		t.Fatalf("failed to decode chainspec: %v", err)
+	}
+	imported, err := dec.ToGenesis()
+	if err != nil {
+		t.Fatalf("failed to import chainspec: %v", err)
+	}
+	if !reflect.DeepEqual(imported.Config, genesis.Config) {
+		t.Errorf("config mismatch:\nhave %+v\nwant %+v", imported.Config, genesis.Config)
+	}
+	have := imported.ToBlock(rawdb.NewMemoryDatabase()).Hash()
+	want := genesis.ToBlock(rawdb.NewMemoryDatabase()).Hash()
+	if have != want {
+		t.Errorf("genesis hash mismatch: have %x, want %x", have, want)
+	}
+}
+
+// Tests that chainspecs activating the EIPs of a fork separately, which can't
+// be expressed in a chain config, are rejected.
+func TestPartialForkRejected(t *testing.T) {
+	spec, err := New("aksara", testGenesis())
+	if err != nil {
+		t.Fatalf("failed to create chainspec: %v", err)
+	}
+	spec.Params.EIP3529Transition = nil
+	if _, err := spec.ToGenesis(); err == nil {
+		t.Fatalf("partial london fork accepted")
+	}
+}
+
+// Tests that only clique chains are converted.
+func TestEthashRejected(t *testing.T) {
+	if _, err := New("mainnet", core.DefaultGenesisBlock()); err != errNotClique {
+		t.Fatalf("error mismatch: have %v, want %v", err, errNotClique)
+	}
+}
@@ -42,6 +42,10 @@ var (
 	// checksum does not match any local checksum variation, signalling that the
 	// two chains have diverged in the past at some point (possibly at genesis).
 	ErrLocalIncompatibleOrStale = errors.New("local incompatible or needs update")
+
+	// ErrScheduleMismatch is returned by the strict validator if the remote fork
+	// state is compatible, but its upcoming fork differs from the local schedule.
+	ErrScheduleMismatch = errors.New("fork schedule mismatch")
 )
 
 // Blockchain defines all necessary method to build a forkID.
@@ -105,6 +109,20 @@ func NewFilter(chain Blockchain) Filter {
 	)
 }
 
+// NewStrictFilter creates a filter that additionally rejects remote fork IDs
+// whose upcoming fork differs from the local fork schedule, disconnecting nodes
+// running incompatible builds before the fork is reached.
+func NewStrictFilter(chain Blockchain) Filter {
+	return newStrictFilter(
+		chain.Config(),
+		chain.Genesis().Hash(),
+		func() uint64 {
+			return chain.CurrentHeader().Number.Uint64()
+		},
+		true,
+	)
+}
+
 // NewStaticFilter creates a filter at block zero.
 func NewStaticFilter(config *params.ChainConfig, genesis common.Hash) Filter {
 	head := func() uint64 { return 0 }
@@ -115,6 +133,12 @@ func NewStaticFilter(config *params.ChainConfig, genesis common.Hash) Filter {
 // instead of a chain. The reason is to allow testing it without having to simulate
 // an entire blockchain.
 func newFilter(config *params.ChainConfig, genesis common.Hash, headfn func() uint64) Filter {
+	return newStrictFilter(config, genesis, headfn, false)
+}
+
+// newStrictFilter creates a fork ID filter, which in strict mode also requires
+// the remote's announced next fork to match the local fork schedule.
+func newStrictFilter(config *params.ChainConfig, genesis common.Hash, headfn func() uint64, strict bool) Filter {
 	// Calculate the all the valid fork hash and fork next combos
 	var (
 		forks = gatherForks(config)
@@ -166,6 +190,10 @@ func newFilter(config *params.ChainConfig, genesis common.Hash, headfn func() ui
 				if id.Next > 0 && head >= id.Next {
 					return ErrLocalIncompatibleOrStale
 				}
+				// In strict mode, the upcoming forks need to match too.
+				if strict && id.Next != nextFork(forks[i]) {
+					return ErrScheduleMismatch
+				}
 				// Haven't passed locally a remote-only fork, accept the connection (rule #1b).
 				return nil
 			}
@@ -185,6 +213,10 @@ func newFilter(config *params.ChainConfig, genesis common.Hash, headfn func() ui
 			for j := i + 1; j < len(sums); j++ {
 				if sums[j] == id.Hash {
 					// Yay, remote checksum is a superset, ignore upcoming forks
+					// unless strict, requiring the remote to know our next fork.
+					if strict && id.Next != nextFork(forks[j]) {
+						return ErrScheduleMismatch
+					}
 					return nil
 				}
 			}
@@ -196,6 +228,15 @@ func newFilter(config *params.ChainConfig, genesis common.Hash, headfn func() ui
 	}
 }
 
+// nextFork converts a fork block into its FORK_NEXT announcement, mapping the
+// sentry of the last fork to no upcoming fork.
+func nextFork(fork uint64) uint64 {
+	if fork == math.MaxUint64 {
+		return 0
+	}
+	return fork
+}
+
 // checksumUpdate calculates the next IEEE CRC32 checksum based on the previous
 // one and a fork block number (equivalent to CRC32(original-blob || fork)).
 func checksumUpdate(hash uint32, fork uint64) uint32 {
@@ -211,28 +252,30 @@ func checksumToBytes(hash uint32) [4]byte {
 	return blob
 }
 
+// Forks returns the activation blocks included in the fork ID of a chain, in
+// ascending order.
+func Forks(config *params.ChainConfig) []uint64 {
+	return gatherForks(config)
+}
+
 // gatherForks gathers all the known forks and creates a sorted list out of them.
+// Besides the upstream forks, the activation blocks of the precompile extensions
+// and the clique features of this fork are included, so nodes running builds
+// with diverging fork schedules are told apart.
 func gatherForks(config *params.ChainConfig) []uint64 {
 	// Gather all the fork block numbers via reflection
-	kind := reflect.TypeOf(params.ChainConfig{})
-	conf := reflect.ValueOf(config).Elem()
-
-	var forks []uint64
-	for i := 0; i < kind.NumField(); i++ {
-		// Fetch the next field and skip non-fork rules
-		field := kind.Field(i)
-		if !strings.HasSuffix(field.Name, "Block") {
-			continue
-		}
-		if field.Type != reflect.TypeOf(new(big.Int)) {
-			continue
-		}
-		// Extract the fork rule block number and aggregate it
-		rule := conf.Field(i).Interface().(*big.Int)
+	forks := forkBlocks(reflect.ValueOf(config).Elem())
+	for _, rule := range config.PrecompileExtensions {
 		if rule != nil {
 			forks = append(forks, rule.Uint64())
 		}
 	}
+	if config.Clique != nil {
+		forks = append(forks, forkBlocks(reflect.ValueOf(config.Clique).Elem())...)
+		if policy := config.Clique.SignerPolicy; policy != nil && policy.Block != nil {
+			forks = append(forks, policy.Block.Uint64())
+		}
+	}
 	// Sort the fork block numbers to permit chronological XOR
 	for i := 0; i < len(forks); i++ {
 		for j := i + 1; j < len(forks); j++ {
@@ -254,3 +297,27 @@ func gatherForks(config *params.ChainConfig) []uint64 {
 	}
 	return forks
 }
+
+// forkBlocks gathers the fork block numbers of a config struct via reflection,
+// taking every *big.Int field named with a Block suffix.
+func forkBlocks(conf reflect.Value) []uint64 {
+	kind := conf.Type()
+
+	var forks []uint64
+	for i := 0; i < kind.NumField(); i++ {
+		// Fetch the next field and skip non-fork rules
+		field := kind.Field(i)
+		if !strings.HasSuffix(field.Name, "Block") {
+			continue
+		}
+		if field.Type != reflect.TypeOf(new(big.Int)) {
+			continue
+		}
+		// Extract the fork rule block number and aggregate it
+		rule := conf.Field(i).Interface().(*big.Int)
+		if rule != nil {
+			forks = append(forks, rule.Uint64())
+		}
+	}
+	return forks
+}
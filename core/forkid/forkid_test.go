@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"math"
 	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -260,6 +261,67 @@ func TestValidation(t *testing.T) {
 	}
 }
 
+// Tests that the strict filter additionally rejects remotes whose upcoming fork
+// differs from the local fork schedule.
+func TestStrictValidation(t *testing.T) {
+	tests := []struct {
+		head uint64
+		id   ID
+		err  error
+	}{
+		// Local is mainnet Byzantium, remote announces the same and knows about Petersburg.
+		{7279999, ID{Hash: checksumToBytes(0xa00bc324), Next: 7280000}, nil},
+
+		// Local is mainnet Byzantium, remote announces the same, but is not aware of Petersburg.
+		{7279999, ID{Hash: checksumToBytes(0xa00bc324), Next: 0}, ErrScheduleMismatch},
+
+		// Local is mainnet Byzantium, remote announces the same with a fork unknown locally.
+		{7279999, ID{Hash: checksumToBytes(0xa00bc324), Next: math.MaxUint64}, ErrScheduleMismatch},
+
+		// Local is mainnet Spurious, remote announces Byzantium and knows about Petersburg.
+		{4369999, ID{Hash: checksumToBytes(0xa00bc324), Next: 7280000}, nil},
+
+		// Local is mainnet Spurious, remote announces Byzantium, but is not aware of Petersburg.
+		{4369999, ID{Hash: checksumToBytes(0xa00bc324), Next: 0}, ErrScheduleMismatch},
+
+		// Local is mainnet Petersburg, remote is syncing and aware of the upcoming forks.
+		{7987396, ID{Hash: checksumToBytes(0xa00bc324), Next: 7280000}, nil},
+
+		// Local is mainnet Arrow Glacier, remote announces the same without further forks.
+		{88888888, ID{Hash: checksumToBytes(0x20c327fc), Next: 0}, nil},
+	}
+	for i, tt := range tests {
+		filter := newStrictFilter(params.MainnetChainConfig, params.MainnetGenesisHash, func() uint64 { return tt.head }, true)
+		if err := filter(tt.id); err != tt.err {
+			t.Errorf("test %d: validation error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that the activation blocks of the precompile extensions and the clique
+// features are part of the fork ID.
+func TestCustomForks(t *testing.T) {
+	config := &params.ChainConfig{
+		ChainID:              big.NewInt(1),
+		HomesteadBlock:       big.NewInt(0),
+		PrecompileExtensions: map[string]*big.Int{"ext": big.NewInt(30)},
+		Clique: &params.CliqueConfig{
+			Period:                 5,
+			EpochBlock:             100,
+			PerformanceCommitBlock: big.NewInt(10),
+			SnapshotCommitBlock:    big.NewInt(20),
+			SignerPolicy:           &params.CliqueSignerPolicy{Block: big.NewInt(20)},
+		},
+	}
+	if forks := Forks(config); !reflect.DeepEqual(forks, []uint64{10, 20, 30}) {
+		t.Fatalf("fork list mismatch: have %v, want [10 20 30]", forks)
+	}
+	plain := &params.ChainConfig{ChainID: big.NewInt(1), HomesteadBlock: big.NewInt(0), Clique: &params.CliqueConfig{Period: 5}}
+	if have, want := NewID(config, common.Hash{}, 25), NewID(plain, common.Hash{}, 25); have == want || have.Next != 30 {
+		t.Errorf("custom forks not reflected in fork ID: have %v, plain %v", have, want)
+	}
+}
+
 // Tests that IDs are properly RLP encoded (specifically important because we
 // use uint32 to store the hash, but we need to encode it as [4]byte).
 func TestEncoding(t *testing.T) {
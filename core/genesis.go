@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
@@ -155,6 +156,44 @@ func CommitGenesisState(db ethdb.Database, hash common.Hash) error {
 	return err
 }
 
+// ReadGenesis reconstructs the genesis specification of the chain stored in the
+// database from its genesis block, chain config and persisted allocation.
+func ReadGenesis(db ethdb.Database) (*Genesis, error) {
+	stored := rawdb.ReadCanonicalHash(db, 0)
+	if stored == (common.Hash{}) {
+		return nil, errors.New("genesis block missing from database")
+	}
+	block := rawdb.ReadBlock(db, stored, 0)
+	if block == nil {
+		return nil, errors.New("genesis block missing from database")
+	}
+	config := rawdb.ReadChainConfig(db, stored)
+	if config == nil {
+		return nil, errors.New("genesis config missing from database")
+	}
+	blob := rawdb.ReadGenesisState(db, stored)
+	if len(blob) == 0 {
+		return nil, errors.New("genesis state missing from database")
+	}
+	var alloc GenesisAlloc
+	if err := alloc.UnmarshalJSON(blob); err != nil {
+		return nil, err
+	}
+	header := block.Header()
+	return &Genesis{
+		Config:     config,
+		Nonce:      header.Nonce.Uint64(),
+		Timestamp:  header.Time,
+		ExtraData:  header.Extra,
+		GasLimit:   header.GasLimit,
+		Difficulty: header.Difficulty,
+		Mixhash:    header.MixDigest,
+		Coinbase:   header.Coinbase,
+		Alloc:      alloc,
+		BaseFee:    header.BaseFee,
+	}, nil
+}
+
 // GenesisAccount is an account in the state of the genesis block.
 type GenesisAccount struct {
 	Code       []byte                      `json:"code,omitempty"`
@@ -288,6 +327,9 @@ func SetupGenesisBlockWithOverride(db ethdb.Database, genesis *Genesis, override
 	if err := newcfg.CheckConfigForkOrder(); err != nil {
 		return newcfg, common.Hash{}, err
 	}
+	if err := vm.ValidatePrecompileExtensions(newcfg); err != nil {
+		return newcfg, common.Hash{}, err
+	}
 	storedcfg := rawdb.ReadChainConfig(db, stored)
 	if storedcfg == nil {
 		log.Warn("Found genesis block without chain config")
@@ -397,6 +439,9 @@ func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
 	if err := config.CheckConfigForkOrder(); err != nil {
 		return nil, err
 	}
+	if err := vm.ValidatePrecompileExtensions(config); err != nil {
+		return nil, err
+	}
 	if config.Clique != nil && len(block.Extra()) < 32+crypto.SignatureLength {
 		return nil, errors.New("can't start clique chain without signers")
 	}
@@ -424,6 +469,84 @@ func (g *Genesis) MustCommit(db ethdb.Database) *types.Block {
 	return block
 }
 
+// CommitFork writes a genesis block forked off the state of an existing chain
+// to the database. Instead of starting from an empty state, the allocation of
+// the genesis specification is applied on top of the state of the given parent
+// header, which must be reachable through db. The timestamp and base fee are
+// inherited from the parent, as is the gas limit unless the spec sets one.
+func (g *Genesis) CommitFork(db ethdb.Database, parent *types.Header) (*types.Block, error) {
+	if g.Config == nil {
+		return nil, errors.New("can't fork genesis without chain config")
+	}
+	if err := g.Config.CheckConfigForkOrder(); err != nil {
+		return nil, err
+	}
+	if err := vm.ValidatePrecompileExtensions(g.Config); err != nil {
+		return nil, err
+	}
+	if g.Config.Clique != nil && len(g.ExtraData) < 32+crypto.SignatureLength {
+		return nil, errors.New("can't start clique chain without signers")
+	}
+	statedb, err := state.New(parent.Root, state.NewDatabase(db), nil)
+	if err != nil {
+		return nil, err
+	}
+	for addr, account := range g.Alloc {
+		if account.Balance != nil {
+			statedb.SetBalance(addr, account.Balance)
+		}
+		if account.Code != nil {
+			statedb.SetCode(addr, account.Code)
+		}
+		if account.Nonce != 0 {
+			statedb.SetNonce(addr, account.Nonce)
+		}
+		for key, value := range account.Storage {
+			statedb.SetState(addr, key, value)
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		return nil, err
+	}
+	if err := statedb.Database().TrieDB().Commit(root, true, nil); err != nil {
+		return nil, err
+	}
+	head := &types.Header{
+		Number:     new(big.Int),
+		Nonce:      types.EncodeNonce(g.Nonce),
+		Time:       parent.Time,
+		Extra:      g.ExtraData,
+		GasLimit:   g.GasLimit,
+		Difficulty: g.Difficulty,
+		Coinbase:   g.Coinbase,
+		Root:       root,
+	}
+	if g.GasLimit == 0 {
+		head.GasLimit = parent.GasLimit
+	}
+	if g.Difficulty == nil {
+		head.Difficulty = params.GenesisDifficulty
+	}
+	if g.Config.IsLondon(common.Big0) {
+		head.BaseFee = parent.BaseFee
+		if head.BaseFee == nil {
+			head.BaseFee = new(big.Int).SetUint64(params.InitialBaseFee)
+		}
+	}
+	block := types.NewBlock(head, nil, nil, nil, trie.NewStackTrie(nil))
+
+	rawdb.WriteTd(db, block.Hash(), block.NumberU64(), block.Difficulty())
+	rawdb.WriteBlock(db, block)
+	rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), nil)
+	rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	rawdb.WriteHeadBlockHash(db, block.Hash())
+	rawdb.WriteHeadFastBlockHash(db, block.Hash())
+	rawdb.WriteHeadHeaderHash(db, block.Hash())
+	rawdb.WriteChainConfig(db, block.Hash(), g.Config)
+	return block, nil
+}
+
 // GenesisBlockForTesting creates and writes a block in which addr has the given wei balance.
 func GenesisBlockForTesting(db ethdb.Database, addr common.Address, balance *big.Int) *types.Block {
 	g := Genesis{
@@ -508,7 +631,8 @@ func DeveloperGenesisBlock(period uint64, gasLimit uint64, faucet common.Address
 	// Override the default period to the user requested one
 	config := *params.AllCliqueProtocolChanges
 	config.Clique = &params.CliqueConfig{
-		Period: period,
+		Period:            period,
+		InitialValidators: []common.Address{faucet},
 	}
 
 	// Assemble and return the genesis with the precompiles and faucet pre-funded
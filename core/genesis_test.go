@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
@@ -243,3 +244,48 @@ func TestReadWriteGenesisAlloc(t *testing.T) {
 		}
 	}
 }
+
+func TestGenesis_CommitFork(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		source = &Genesis{
+			Config:    params.TestChainConfig,
+			Timestamp: 1234,
+			GasLimit:  30_000_000,
+			Alloc: GenesisAlloc{
+				{1}: {Balance: big.NewInt(1), Storage: map[common.Hash]common.Hash{{1}: {1}}},
+				{2}: {Balance: big.NewInt(2)},
+			},
+		}
+		parent = source.MustCommit(rawdb.NewMemoryDatabase())
+	)
+	// Fork off the source state in a fresh database sharing its state
+	source.MustCommit(db)
+	fork := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  GenesisAlloc{{2}: {Balance: big.NewInt(20)}},
+	}
+	block, err := fork.CommitFork(db, parent.Header())
+	if err != nil {
+		t.Fatalf("failed to fork genesis: %v", err)
+	}
+	if block.Time() != parent.Time() || block.GasLimit() != parent.GasLimit() {
+		t.Errorf("inherited fields mismatch: time %d, gas limit %d", block.Time(), block.GasLimit())
+	}
+	if rawdb.ReadCanonicalHash(db, 0) != block.Hash() {
+		t.Errorf("forked genesis not canonical")
+	}
+	statedb, err := state.New(block.Root(), state.NewDatabase(db), nil)
+	if err != nil {
+		t.Fatalf("forked state missing: %v", err)
+	}
+	if balance := statedb.GetBalance(common.Address{1}); balance.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("inherited balance mismatch: have %v, want 1", balance)
+	}
+	if value := statedb.GetState(common.Address{1}, common.Hash{1}); value != (common.Hash{1}) {
+		t.Errorf("inherited storage mismatch: have %x", value)
+	}
+	if balance := statedb.GetBalance(common.Address{2}); balance.Cmp(big.NewInt(20)) != 0 {
+		t.Errorf("overridden balance mismatch: have %v, want 20", balance)
+	}
+}
@@ -345,6 +345,46 @@ func ReadHeaderRange(db ethdb.Reader, number uint64, count uint64) []rlp.RawValu
 	return rlpHeaders
 }
 
+// ReadCanonicalHeaderRange returns the rlp-encoded canonical headers, starting at
+// 'from' and going forward. The frozen headers are read from the ancients in
+// sequential chunks, the rest one by one from the key-value store. It stops at
+// the first missing header. This method assumes that the caller already has
+// placed a cap on count, to prevent DoS issues.
+func ReadCanonicalHeaderRange(db ethdb.Reader, from uint64, count uint64) []rlp.RawValue {
+	var (
+		rlpHeaders []rlp.RawValue
+		number     = from
+	)
+	frozen, _ := db.Ancients()
+	for number < frozen && uint64(len(rlpHeaders)) < count {
+		limit := count - uint64(len(rlpHeaders))
+		if number+limit > frozen {
+			limit = frozen - number
+		}
+		data, err := db.AncientRange(freezerHeaderTable, number, limit, limit*700)
+		if err != nil || len(data) == 0 {
+			break
+		}
+		for _, header := range data {
+			rlpHeaders = append(rlpHeaders, header)
+		}
+		number += uint64(len(data))
+	}
+	// Read the remaining live headers, which might get frozen in the meantime
+	for ; uint64(len(rlpHeaders)) < count; number++ {
+		hash := ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			break
+		}
+		data := ReadHeaderRLP(db, hash, number)
+		if len(data) == 0 {
+			break
+		}
+		rlpHeaders = append(rlpHeaders, data)
+	}
+	return rlpHeaders
+}
+
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
 	var data []byte
@@ -950,6 +990,66 @@ func DeleteBadBlocks(db ethdb.KeyValueWriter) {
 	}
 }
 
+const rejectedHeadersToKeep = 128
+
+// RejectedHeader is a header which failed verification, kept for diagnosing
+// the peers propagating it.
+type RejectedHeader struct {
+	Header *types.Header
+	Reason string // Verification error
+	Peer   string // Peer the header was received from, empty if unknown
+	Time   uint64 // Unix time the header was rejected at
+}
+
+// ReadRejectedHeaders retrieves the quarantined headers, most recently rejected
+// first.
+func ReadRejectedHeaders(db ethdb.KeyValueReader) []*RejectedHeader {
+	blob, err := db.Get(rejectedHeaderKey)
+	if err != nil {
+		return nil
+	}
+	var rejected []*RejectedHeader
+	if err := rlp.DecodeBytes(blob, &rejected); err != nil {
+		log.Warn("Failed to decode rejected headers", "err", err)
+		return nil
+	}
+	return rejected
+}
+
+// WriteRejectedHeader quarantines a header which failed verification. A header
+// already quarantined is only refreshed, and once over the limit the records
+// rejected the longest ago are dropped.
+func WriteRejectedHeader(db ethdb.KeyValueStore, rejected *RejectedHeader) {
+	var (
+		hash = rejected.Header.Hash()
+		list = []*RejectedHeader{rejected}
+	)
+	for _, old := range ReadRejectedHeaders(db) {
+		if old.Header.Hash() != hash {
+			list = append(list, old)
+		} else if rejected.Peer == "" {
+			rejected.Peer = old.Peer
+		}
+	}
+	if len(list) > rejectedHeadersToKeep {
+		list = list[:rejectedHeadersToKeep]
+	}
+	data, err := rlp.EncodeToBytes(list)
+	if err != nil {
+		log.Crit("Failed to encode rejected headers", "err", err)
+	}
+	if err := db.Put(rejectedHeaderKey, data); err != nil {
+		log.Crit("Failed to write rejected headers", "err", err)
+	}
+}
+
+// DeleteRejectedHeaders deletes all the quarantined headers from the database.
+func DeleteRejectedHeaders(db ethdb.KeyValueWriter) {
+	if err := db.Delete(rejectedHeaderKey); err != nil {
+		log.Crit("Failed to delete rejected headers", "err", err)
+	}
+}
+
 // FindCommonAncestor returns the last common ancestor of two block headers
 func FindCommonAncestor(db ethdb.Reader, a, b *types.Header) *types.Header {
 	for bn := b.Number.Uint64(); a.Number.Uint64() > bn; {
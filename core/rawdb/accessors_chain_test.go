@@ -258,6 +258,68 @@ func TestBadBlockStorage(t *testing.T) {
 	}
 }
 
+// Tests that rejected headers are quarantined most recent first, refreshed when
+// rejected again and truncated beyond the limit.
+func TestRejectedHeaderStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	if rejected := ReadRejectedHeaders(db); len(rejected) != 0 {
+		t.Fatalf("Non existent rejected headers returned: %v", rejected)
+	}
+	first := &types.Header{Number: big.NewInt(1), Extra: []byte("rejected")}
+	WriteRejectedHeader(db, &RejectedHeader{Header: first, Reason: "unauthorized signer", Peer: "peer-1", Time: 1})
+	WriteRejectedHeader(db, &RejectedHeader{Header: &types.Header{Number: big.NewInt(2)}, Reason: "invalid mix digest", Time: 2})
+
+	// Rejecting the first header again moves it to the front, keeping its peer
+	WriteRejectedHeader(db, &RejectedHeader{Header: first, Reason: "unauthorized signer", Time: 3})
+	rejected := ReadRejectedHeaders(db)
+	if len(rejected) != 2 {
+		t.Fatalf("Rejected header count mismatch: have %d, want 2", len(rejected))
+	}
+	if rejected[0].Header.Hash() != first.Hash() || rejected[0].Peer != "peer-1" || rejected[0].Time != 3 {
+		t.Fatalf("Refreshed header mismatch: %+v", rejected[0])
+	}
+	// Write a bunch of headers, the oldest ones should be dropped
+	for i := 0; i < 2*rejectedHeadersToKeep; i++ {
+		WriteRejectedHeader(db, &RejectedHeader{Header: &types.Header{Number: big.NewInt(int64(i + 10))}, Reason: "bad seal"})
+	}
+	rejected = ReadRejectedHeaders(db)
+	if len(rejected) != rejectedHeadersToKeep {
+		t.Fatalf("Rejected headers not truncated: have %d, want %d", len(rejected), rejectedHeadersToKeep)
+	}
+	if number := rejected[0].Header.Number.Uint64(); number != 2*rejectedHeadersToKeep+9 {
+		t.Fatalf("Most recent rejected header mismatch: have %d", number)
+	}
+	DeleteRejectedHeaders(db)
+	if rejected := ReadRejectedHeaders(db); len(rejected) != 0 {
+		t.Fatalf("Rejected headers not deleted: %d", len(rejected))
+	}
+}
+
+// Tests operator audit log storage and retrieval operations.
+func TestAuditLogStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	if entries := ReadAuditLog(db); len(entries) != 0 {
+		t.Fatalf("Non existent audit entries returned: %d", len(entries))
+	}
+	for i := 0; i < auditEntriesToKeep+2; i++ {
+		WriteAuditEntry(db, &AuditEntry{Time: uint64(i), Operation: "debug_setHead"})
+	}
+	WriteAuditEntry(db, &AuditEntry{Time: 1000, Operation: "debug_setHead", Error: "refused"})
+
+	entries := ReadAuditLog(db)
+	if len(entries) != auditEntriesToKeep {
+		t.Fatalf("Audit entries count mismatch: have %d, want %d", len(entries), auditEntriesToKeep)
+	}
+	if entries[0].Time != 1000 || entries[0].Error != "refused" {
+		t.Fatalf("Most recent audit entry mismatch: %+v", entries[0])
+	}
+	if last := entries[len(entries)-1]; last.Time != 3 {
+		t.Fatalf("Oldest retained audit entry mismatch: have %d, want 3", last.Time)
+	}
+}
+
 // Tests block total difficulty storage and retrieval operations.
 func TestTdStorage(t *testing.T) {
 	db := NewMemoryDatabase()
@@ -935,3 +997,56 @@ func TestHeadersRLPStorage(t *testing.T) {
 	checkSequence(1, 1)    // Only block 1
 	checkSequence(1, 2)    // Genesis + block 1
 }
+
+func TestCanonicalHeaderRange(t *testing.T) {
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend")
+	}
+	defer db.Close()
+
+	// Freeze half of the chain, with headers too large to fit the initial read
+	var (
+		chain []*types.Block
+		pHash common.Hash
+	)
+	for i := 0; i < 100; i++ {
+		block := types.NewBlockWithHeader(&types.Header{
+			Number:      big.NewInt(int64(i)),
+			Extra:       make([]byte, 1024),
+			UncleHash:   types.EmptyUncleHash,
+			TxHash:      types.EmptyRootHash,
+			ReceiptHash: types.EmptyRootHash,
+			ParentHash:  pHash,
+		})
+		chain = append(chain, block)
+		pHash = block.Hash()
+	}
+	WriteAncientBlocks(db, chain[:50], make([]types.Receipts, 50), big.NewInt(100))
+	for i := 50; i < 100; i++ {
+		WriteCanonicalHash(db, chain[i].Hash(), chain[i].NumberU64())
+		WriteBlock(db, chain[i])
+	}
+	checkSequence := func(from, amount, want int) {
+		headersRlp := ReadCanonicalHeaderRange(db, uint64(from), uint64(amount))
+		if have := len(headersRlp); have != want {
+			t.Fatalf("from %d: have %d headers, want %d", from, have, want)
+		}
+		for i, headerRlp := range headersRlp {
+			var header types.Header
+			if err := rlp.DecodeBytes(headerRlp, &header); err != nil {
+				t.Fatal(err)
+			}
+			if have, want := header.Hash(), chain[from+i].Hash(); have != want {
+				t.Fatalf("from %d: header %d mismatch, have %x want %x", from, i, have, want)
+			}
+		}
+	}
+	checkSequence(0, 1, 1)     // Only genesis
+	checkSequence(0, 50, 50)   // All ancient ones
+	checkSequence(40, 20, 20)  // Across the ancients and the db
+	checkSequence(60, 10, 10)  // Only db blocks
+	checkSequence(0, 100, 100) // All blocks
+	checkSequence(90, 20, 10)  // Stops at the head
+	checkSequence(100, 10, 0)  // Beyond the head
+}
@@ -186,3 +186,44 @@ func WriteTransitionStatus(db ethdb.KeyValueWriter, data []byte) {
 		log.Crit("Failed to store the eth2 transition status", "err", err)
 	}
 }
+
+// AuditEntry is an operator action on the chain, recorded whether it was carried
+// out or refused.
+type AuditEntry struct {
+	Time      uint64 // Unix time of the action
+	Operation string // Name of the action, e.g. the RPC method
+	Details   string // Parameters and outcome of the action
+	Error     string // Reason the action was refused or failed, empty if it succeeded
+}
+
+const auditEntriesToKeep = 256
+
+// ReadAuditLog retrieves the recorded operator actions, most recent first.
+func ReadAuditLog(db ethdb.KeyValueReader) []*AuditEntry {
+	blob, err := db.Get(operatorAuditKey)
+	if err != nil {
+		return nil
+	}
+	var entries []*AuditEntry
+	if err := rlp.DecodeBytes(blob, &entries); err != nil {
+		log.Warn("Failed to decode operator audit log", "err", err)
+		return nil
+	}
+	return entries
+}
+
+// WriteAuditEntry records an operator action, dropping the oldest ones once over
+// the limit.
+func WriteAuditEntry(db ethdb.KeyValueStore, entry *AuditEntry) {
+	entries := append([]*AuditEntry{entry}, ReadAuditLog(db)...)
+	if len(entries) > auditEntriesToKeep {
+		entries = entries[:auditEntriesToKeep]
+	}
+	data, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		log.Crit("Failed to encode operator audit log", "err", err)
+	}
+	if err := db.Put(operatorAuditKey, data); err != nil {
+		log.Crit("Failed to write operator audit log", "err", err)
+	}
+}
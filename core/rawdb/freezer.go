@@ -429,6 +429,15 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	// Set up new dir for the migrated table, the content of which
 	// we'll at the end move over to the ancients dir.
 	migrationPath := filepath.Join(ancientsPath, "migration")
+	if table.zstdDict != nil {
+		// Zstd compressed tables are migrated keeping their dictionary
+		if err := os.MkdirAll(migrationPath, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(migrationPath, fmt.Sprintf("%s.zdict", kind)), table.zstdDict, 0644); err != nil {
+			return err
+		}
+	}
 	newTable, err := NewFreezerTable(migrationPath, kind, table.noCompression, false)
 	if err != nil {
 		return err
@@ -23,6 +23,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 )
 
 // This is the maximum amount of data that will be buffered in memory
@@ -86,6 +87,7 @@ type freezerTableBatch struct {
 	t *freezerTable
 
 	sb          *snappyBuffer
+	zb          *zstdBuffer
 	encBuffer   writeBuffer
 	dataBuffer  []byte
 	indexBuffer []byte
@@ -96,7 +98,10 @@ type freezerTableBatch struct {
 // newBatch creates a new batch for the freezer table.
 func (t *freezerTable) newBatch() *freezerTableBatch {
 	batch := &freezerTableBatch{t: t}
-	if !t.noCompression {
+	switch {
+	case t.zstdEnc != nil:
+		batch.zb = &zstdBuffer{enc: t.zstdEnc}
+	case !t.noCompression:
 		batch.sb = new(snappyBuffer)
 	}
 	batch.reset()
@@ -124,11 +129,7 @@ func (batch *freezerTableBatch) Append(item uint64, data interface{}) error {
 	if err := rlp.Encode(&batch.encBuffer, data); err != nil {
 		return err
 	}
-	encItem := batch.encBuffer.data
-	if batch.sb != nil {
-		encItem = batch.sb.compress(encItem)
-	}
-	return batch.appendItem(encItem)
+	return batch.appendItem(batch.compress(batch.encBuffer.data))
 }
 
 // AppendRaw injects a binary blob at the end of the freezer table. The item number is a
@@ -139,11 +140,18 @@ func (batch *freezerTableBatch) AppendRaw(item uint64, blob []byte) error {
 		return fmt.Errorf("%w: have %d want %d", errOutOrderInsertion, item, batch.curItem)
 	}
 
-	encItem := blob
-	if batch.sb != nil {
-		encItem = batch.sb.compress(blob)
+	return batch.appendItem(batch.compress(blob))
+}
+
+// compress encodes an item in the compression format of the table.
+func (batch *freezerTableBatch) compress(item []byte) []byte {
+	switch {
+	case batch.zb != nil:
+		return batch.zb.compress(item)
+	case batch.sb != nil:
+		return batch.sb.compress(item)
 	}
-	return batch.appendItem(encItem)
+	return item
 }
 
 func (batch *freezerTableBatch) appendItem(data []byte) error {
@@ -233,6 +241,19 @@ func (s *snappyBuffer) compress(data []byte) []byte {
 	return s.dst
 }
 
+// zstdBuffer writes zstd frames using the dictionary of the table, and can be
+// reused.
+type zstdBuffer struct {
+	enc *zstd.Encoder
+	dst []byte
+}
+
+// compress zstd-compresses the data.
+func (z *zstdBuffer) compress(data []byte) []byte {
+	z.dst = z.enc.EncodeAll(data, z.dst[:0])
+	return z.dst
+}
+
 // writeBuffer implements io.Writer for a byte slice.
 type writeBuffer struct {
 	data []byte
@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 )
 
 var (
@@ -86,7 +87,9 @@ func (i *indexEntry) bounds(end *indexEntry) (startOffset, endOffset, fileId uin
 
 // freezerTable represents a single chained data table within the freezer (e.g. blocks).
 // It consists of a data file (snappy encoded arbitrary data blobs) and an indexEntry
-// file (uncompressed 64 bit indices into the data file).
+// file (uncompressed 64 bit indices into the data file). Tables converted by
+// CompressFreezerTable are zstd encoded with a dictionary trained on their data
+// instead, which is kept in a separate file next to the index.
 type freezerTable struct {
 	// WARNING: The `items` field is accessed atomically. On 32 bit platforms, only
 	// 64-bit aligned fields can be atomic. The struct is guaranteed to be so aligned,
@@ -107,6 +110,10 @@ type freezerTable struct {
 	name          string
 	path          string
 
+	zstdDict []byte        // Dictionary of zstd compressed tables, nil for snappy or uncompressed ones
+	zstdEnc  *zstd.Encoder // Encoder of zstd compressed tables, using the dictionary
+	zstdDec  *zstd.Decoder // Decoder of zstd compressed tables, using the dictionary
+
 	head   *os.File            // File descriptor for the data head of the table
 	index  *os.File            // File descriptor for the indexEntry file of the table
 	meta   *os.File            // File descriptor for metadata of the table
@@ -136,10 +143,25 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
 	}
+	// Compressed tables holding a dictionary were converted to zstd, the others
+	// keep using snappy for backward compatibility.
+	var dict []byte
+	if !noCompression {
+		blob, err := os.ReadFile(filepath.Join(path, fmt.Sprintf("%s.zdict", name)))
+		switch {
+		case err == nil:
+			dict = blob
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+	}
 	var idxName string
-	if noCompression {
+	switch {
+	case noCompression:
 		idxName = fmt.Sprintf("%s.ridx", name) // raw index file
-	} else {
+	case dict != nil:
+		idxName = fmt.Sprintf("%s.zidx", name) // zstd compressed index file
+	default:
 		idxName = fmt.Sprintf("%s.cidx", name) // compressed index file
 	}
 	var (
@@ -190,6 +212,13 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 		readonly:      readonly,
 		maxFileSize:   maxFilesize,
 	}
+	if dict != nil {
+		if tab.zstdEnc, tab.zstdDec, err = newZstdCodec(dict); err != nil {
+			tab.Close()
+			return nil, err
+		}
+		tab.zstdDict = dict
+	}
 	if err := tab.repair(); err != nil {
 		tab.Close()
 		return nil, err
@@ -578,6 +607,14 @@ func (t *freezerTable) Close() error {
 	}
 	t.head = nil
 
+	if t.zstdEnc != nil {
+		if err := t.zstdEnc.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		t.zstdDec.Close()
+		t.zstdEnc, t.zstdDec = nil, nil
+	}
+
 	if errs != nil {
 		return fmt.Errorf("%v", errs)
 	}
@@ -589,9 +626,12 @@ func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error
 	var exist bool
 	if f, exist = t.files[num]; !exist {
 		var name string
-		if t.noCompression {
+		switch {
+		case t.noCompression:
 			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
+		case t.zstdDict != nil:
+			name = fmt.Sprintf("%s.%04d.zdat", t.name, num)
+		default:
 			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
 		}
 		f, err = opener(filepath.Join(t.path, name))
@@ -703,6 +743,21 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 	for i, diskSize := range sizes {
 		item := diskData[offset : offset+diskSize]
 		offset += diskSize
+
+		// The decompressed size of zstd items isn't known upfront, decode them
+		// before checking the limit.
+		if t.zstdDec != nil {
+			data, err := t.zstdDec.DecodeAll(item, nil)
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 && uint64(outputSize+len(data)) > maxBytes {
+				break
+			}
+			output = append(output, data)
+			outputSize += len(data)
+			continue
+		}
 		decompressedSize := diskSize
 		if !t.noCompression {
 			decompressedSize, _ = snappy.DecodedLen(item)
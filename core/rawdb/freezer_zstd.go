@@ -0,0 +1,370 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// freezerDictSize is the size of the zstd dictionaries trained for the
+	// freezer tables.
+	freezerDictSize = 64 * 1024
+
+	// freezerDictSamples is the amount of item data sampled from a freezer table
+	// to train its dictionary on.
+	freezerDictSamples = 8 * 1024 * 1024
+
+	// freezerDictMinSample is the size below which items aren't worth sampling.
+	freezerDictMinSample = 16
+
+	dictKmer    = 8  // Length of the substrings counted across the samples
+	dictSegment = 64 // Length of the sample segments the dictionary is built from
+)
+
+// newZstdCodec creates the zstd encoder and decoder of a table using the given
+// raw content dictionary.
+func newZstdCodec(dict []byte) (*zstd.Encoder, *zstd.Decoder, error) {
+	// The dictionary id is recorded in every frame, derive it from the content
+	// so that frames can't be decoded with another dictionary by accident.
+	id := crc32.ChecksumIEEE(dict)
+	if id == 0 {
+		id = 1
+	}
+	enc, err := zstd.NewWriter(nil,
+		zstd.WithEncoderLevel(zstd.SpeedBetterCompression),
+		zstd.WithEncoderConcurrency(1),
+		zstd.WithEncoderDictRaw(id, dict),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderDictRaw(id, dict))
+	if err != nil {
+		enc.Close()
+		return nil, nil, err
+	}
+	return enc, dec, nil
+}
+
+// dictCandidate is a segment of a sample which might be added to a dictionary.
+type dictCandidate struct {
+	sample []byte // Sample the segment is in
+	pos    int    // Position of the segment within the sample
+	score  uint64 // Occurrences of the segment's substrings in other samples
+}
+
+// dictCandidates is a max-heap of dictionary candidates by score.
+type dictCandidates []*dictCandidate
+
+func (c dictCandidates) Len() int            { return len(c) }
+func (c dictCandidates) Less(i, j int) bool  { return c[i].score > c[j].score }
+func (c dictCandidates) Swap(i, j int)       { c[i], c[j] = c[j], c[i] }
+func (c *dictCandidates) Push(x interface{}) { *c = append(*c, x.(*dictCandidate)) }
+func (c *dictCandidates) Pop() interface{} {
+	old := *c
+	n := len(old)
+	item := old[n-1]
+	*c = old[:n-1]
+	return item
+}
+
+// trainZstdDict builds a raw content dictionary of at most the given size from
+// the samples. Like the cover algorithm of the zstd reference implementation,
+// it greedily picks the sample segments whose substrings occur in the most
+// other samples, discounting the substrings already covered by the dictionary.
+// The best segments are placed at the end, where matches are cheapest to encode.
+func trainZstdDict(samples [][]byte, size int) []byte {
+	// Count the number of samples each substring occurs in
+	var (
+		freq = make(map[uint64]uint64)
+		seen = make(map[uint64]int)
+	)
+	for i, sample := range samples {
+		for pos := 0; pos+dictKmer <= len(sample); pos++ {
+			kmer := binary.BigEndian.Uint64(sample[pos:])
+			if last, ok := seen[kmer]; ok && last == i+1 {
+				continue
+			}
+			seen[kmer] = i + 1
+			freq[kmer]++
+		}
+	}
+	// score sums the occurrences of the substrings of a segment in other samples
+	score := func(sample []byte, pos int) uint64 {
+		var total uint64
+		for i := pos; i+dictKmer <= pos+dictSegment; i++ {
+			if n := freq[binary.BigEndian.Uint64(sample[i:])]; n > 1 {
+				total += n - 1
+			}
+		}
+		return total
+	}
+	cover := func(sample []byte, pos int) {
+		for i := pos; i+dictKmer <= pos+dictSegment; i++ {
+			delete(freq, binary.BigEndian.Uint64(sample[i:]))
+		}
+	}
+	// Collect the segments sharing anything with other samples
+	var candidates dictCandidates
+	for _, sample := range samples {
+		for pos := 0; pos+dictSegment <= len(sample); pos += dictSegment / 2 {
+			if s := score(sample, pos); s > 0 {
+				candidates = append(candidates, &dictCandidate{sample: sample, pos: pos, score: s})
+			}
+		}
+	}
+	heap.Init(&candidates)
+
+	// Pick the best segments, rescoring them lazily as the dictionary covers more
+	var picked [][]byte
+	for filled := 0; candidates.Len() > 0 && filled+dictSegment <= size; {
+		best := heap.Pop(&candidates).(*dictCandidate)
+		s := score(best.sample, best.pos)
+		if s == 0 {
+			continue
+		}
+		if s < best.score && candidates.Len() > 0 && s < candidates[0].score {
+			best.score = s
+			heap.Push(&candidates, best)
+			continue
+		}
+		picked = append(picked, best.sample[best.pos:best.pos+dictSegment])
+		filled += dictSegment
+		cover(best.sample, best.pos)
+	}
+	dict := make([]byte, 0, len(picked)*dictSegment)
+	for i := len(picked) - 1; i >= 0; i-- {
+		dict = append(dict, picked[i]...)
+	}
+	return dict
+}
+
+// sampleFreezerItems reads items spread evenly over a freezer table, up to the
+// given total size, skipping the ones too small to be worth sampling.
+func sampleFreezerItems(t *freezerTable, limit int) ([][]byte, error) {
+	var (
+		samples [][]byte
+		total   int
+		tail    = atomic.LoadUint64(&t.itemHidden)
+		items   = atomic.LoadUint64(&t.items)
+		stride  = uint64(1)
+	)
+	for i := tail; i < items; i += stride {
+		item, err := t.Retrieve(i)
+		if err != nil {
+			return nil, err
+		}
+		if len(item) < freezerDictMinSample {
+			continue
+		}
+		samples = append(samples, item)
+		total += len(item)
+
+		// Over the limit, drop every other sample and skip twice as many items
+		for total > limit && len(samples) > 1 {
+			thinned := samples[:0]
+			total = 0
+			for j := 0; j < len(samples); j += 2 {
+				thinned = append(thinned, samples[j])
+				total += len(samples[j])
+			}
+			samples = thinned
+			stride *= 2
+		}
+	}
+	return samples, nil
+}
+
+// CompressFreezerTable converts a snappy compressed freezer table in the given
+// ancient directory to zstd, with a dictionary trained on the table's own data.
+// Tables converted once keep being written in zstd. The database must not be
+// open while converting. An interrupted conversion is resumed by the next one.
+func CompressFreezerTable(path, kind string) error {
+	noCompression, ok := FreezerNoSnappy[kind]
+	if !ok {
+		return errUnknownTable
+	}
+	if noCompression {
+		return fmt.Errorf("freezer table %s is not compressed", kind)
+	}
+	table, err := newTable(path, kind, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, false, false)
+	if err != nil {
+		return err
+	}
+	if table.zstdDict != nil {
+		table.Close()
+		log.Info("Freezer table already compressed", "table", kind)
+
+		// The snappy files are only left over if a conversion was interrupted
+		return removeSnappyTable(path, kind)
+	}
+	if table.itemOffset > 0 || table.itemHidden > 0 {
+		table.Close()
+		return fmt.Errorf("migration not supported for tail-deleted freezers")
+	}
+	// Train the dictionary, unless an interrupted conversion already did. The items
+	// converted with it would be unreadable with another one.
+	var (
+		migrationPath = filepath.Join(path, "migration")
+		dictPath      = filepath.Join(migrationPath, fmt.Sprintf("%s.zdict", kind))
+		start         = time.Now()
+	)
+	dict, err := os.ReadFile(dictPath)
+	if os.IsNotExist(err) {
+		samples, err := sampleFreezerItems(table, freezerDictSamples)
+		if err != nil {
+			table.Close()
+			return err
+		}
+		if dict = trainZstdDict(samples, freezerDictSize); len(dict) == 0 {
+			table.Close()
+			log.Info("Freezer table too small to train a dictionary", "table", kind, "samples", len(samples))
+			return nil
+		}
+		log.Info("Trained freezer table dictionary", "table", kind, "samples", len(samples), "size", common.StorageSize(len(dict)), "elapsed", common.PrettyDuration(time.Since(start)))
+
+		if err := os.MkdirAll(migrationPath, 0755); err != nil {
+			table.Close()
+			return err
+		}
+		// Write the dictionary atomically, a partial one would be picked up on resume
+		if err := os.WriteFile(dictPath+".tmp", dict, 0644); err != nil {
+			table.Close()
+			return err
+		}
+		if err := os.Rename(dictPath+".tmp", dictPath); err != nil {
+			table.Close()
+			return err
+		}
+	} else if err != nil {
+		table.Close()
+		return err
+	}
+	newTable, err := newTable(migrationPath, kind, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, false, false)
+	if err != nil {
+		table.Close()
+		return err
+	}
+	if err := compressFreezerItems(table, newTable); err != nil {
+		table.Close()
+		newTable.Close()
+		return err
+	}
+	oldSize, _ := table.size()
+	newSize, _ := newTable.size()
+	log.Info("Compressed freezer table", "table", kind, "items", atomic.LoadUint64(&newTable.items),
+		"before", common.StorageSize(oldSize), "after", common.StorageSize(newSize+uint64(len(dict))),
+		"elapsed", common.PrettyDuration(time.Since(start)))
+
+	if err := newTable.Sync(); err != nil {
+		table.Close()
+		newTable.Close()
+		return err
+	}
+	table.Close()
+	if err := newTable.Close(); err != nil {
+		return err
+	}
+	// Move the converted files over, the dictionary last: the table is opened as
+	// zstd only once it's present, so it switches formats in a single step.
+	files, err := filepath.Glob(filepath.Join(migrationPath, fmt.Sprintf("%s.*", kind)))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file == dictPath {
+			continue
+		}
+		if err := os.Rename(file, filepath.Join(path, filepath.Base(file))); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(dictPath, filepath.Join(path, filepath.Base(dictPath))); err != nil {
+		return err
+	}
+	if err := removeSnappyTable(path, kind); err != nil {
+		return err
+	}
+	// Other tables might be halfway converted too, the directory is only
+	// removed if it's empty.
+	os.Remove(migrationPath)
+	return nil
+}
+
+// compressFreezerItems appends the items of a table missing from the converted
+// one, recompressing them.
+func compressFreezerItems(table, newTable *freezerTable) error {
+	var (
+		batch     = newTable.newBatch()
+		items     = atomic.LoadUint64(&table.items)
+		batchSize = uint64(1024)
+		maxBytes  = uint64(1024 * 1024)
+		start     = time.Now()
+		logged    = time.Now()
+	)
+	if offset := atomic.LoadUint64(&newTable.items); offset > 0 {
+		log.Info("Found previous compression attempt", "table", table.name, "compressed", offset)
+	}
+	for i := atomic.LoadUint64(&newTable.items); i < items; {
+		if i+batchSize > items {
+			batchSize = items - i
+		}
+		data, err := table.RetrieveItems(i, batchSize, maxBytes)
+		if err != nil {
+			return err
+		}
+		for _, item := range data {
+			if err := batch.AppendRaw(i, item); err != nil {
+				return err
+			}
+			i++
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Compressing freezer table", "table", table.name, "count", i, "total", items, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return batch.commit()
+}
+
+// removeSnappyTable deletes the snappy index and data files of a freezer table.
+func removeSnappyTable(path, kind string) error {
+	files, err := filepath.Glob(filepath.Join(path, fmt.Sprintf("%s.*.cdat", kind)))
+	if err != nil {
+		return err
+	}
+	files = append(files, filepath.Join(path, fmt.Sprintf("%s.cidx", kind)))
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// repetitiveItem creates an item resembling a receipt list: a few fixed
+// structures with a little varying data in between.
+func repetitiveItem(n int) []byte {
+	var item []byte
+	for i := 0; i < 1+n%4; i++ {
+		item = append(item, []byte("receipt:status=1;logs=[topic:ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef;")...)
+		item = append(item, []byte(fmt.Sprintf("from=%040x;to=%040x;value=%d]", n, n*7+i, rand.Int63()))...)
+	}
+	return item
+}
+
+func TestTrainZstdDict(t *testing.T) {
+	var samples [][]byte
+	for i := 0; i < 200; i++ {
+		samples = append(samples, repetitiveItem(i))
+	}
+	dict := trainZstdDict(samples, 1024)
+	if len(dict) == 0 || len(dict) > 1024 {
+		t.Fatalf("dictionary size %d out of bounds", len(dict))
+	}
+	if !bytes.Contains(dict, []byte("ddf252ad1be2c89b")) {
+		t.Fatalf("dictionary misses the common substrings: %q", dict)
+	}
+	// Samples sharing nothing don't make a dictionary
+	if dict := trainZstdDict([][]byte{bytes.Repeat([]byte{1}, 100)}, 1024); len(dict) != 0 {
+		t.Fatalf("dictionary trained on a single sample: %x", dict)
+	}
+}
+
+func TestCompressFreezerTable(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		kind  = freezerReceiptTable
+		items = 500
+	)
+	// Write a snappy table spanning multiple files
+	f, err := newTable(dir, kind, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 8*1024, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want [][]byte
+	batch := f.newBatch()
+	for i := 0; i < items; i++ {
+		want = append(want, repetitiveItem(i))
+		if err := batch.AppendRaw(uint64(i), want[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := batch.commit(); err != nil {
+		t.Fatal(err)
+	}
+	snappySize, _ := f.size()
+	f.Close()
+
+	if err := CompressFreezerTable(dir, kind); err != nil {
+		t.Fatalf("failed to compress table: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, kind+".cidx")); !os.IsNotExist(err) {
+		t.Fatalf("snappy index left over: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "migration")); !os.IsNotExist(err) {
+		t.Fatalf("migration directory left over: %v", err)
+	}
+	// Reopen the table and check it's zstd compressed, smaller and intact
+	f, err = newTable(dir, kind, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if f.zstdDict == nil {
+		t.Fatal("table not opened as zstd")
+	}
+	if zstdSize, _ := f.size(); zstdSize >= snappySize {
+		t.Errorf("table not shrunk: snappy %d, zstd %d", snappySize, zstdSize)
+	}
+	got, err := f.RetrieveItems(0, uint64(items), 1<<30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != items {
+		t.Fatalf("item count mismatch: have %d, want %d", len(got), items)
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Fatalf("item %d mismatch: have %x, want %x", i, got[i], want[i])
+		}
+	}
+	// New items are appended in zstd too
+	extra := repetitiveItem(items)
+	batch = f.newBatch()
+	if err := batch.AppendRaw(uint64(items), extra); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.commit(); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := f.Retrieve(uint64(items)); err != nil || !bytes.Equal(item, extra) {
+		t.Fatalf("appended item mismatch: have %x, want %x, err %v", item, extra, err)
+	}
+	// Compressing again leaves the table as it is
+	if err := CompressFreezerTable(dir, kind); err != nil {
+		t.Fatalf("failed to compress table twice: %v", err)
+	}
+}
@@ -78,6 +78,12 @@ var (
 	// badBlockKey tracks the list of bad blocks seen by local
 	badBlockKey = []byte("InvalidBlock")
 
+	// rejectedHeaderKey tracks the list of headers which failed verification
+	rejectedHeaderKey = []byte("RejectedHeaders")
+
+	// operatorAuditKey tracks the list of recent operator actions on the chain
+	operatorAuditKey = []byte("OperatorAudit")
+
 	// uncleanShutdownKey tracks the list of local crashes
 	uncleanShutdownKey = []byte("unclean-shutdown") // config prefix for the db
 
@@ -18,6 +18,7 @@ package snapshot
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
@@ -30,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/internal/maintenance"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
@@ -465,6 +467,28 @@ func (dl *diskLayer) generateRange(ctx *generatorContext, root common.Hash, pref
 	return !trieMore && !result.diskMore, last, nil
 }
 
+// waitMaintenance waits for the maintenance scheduler to let a batch of the
+// given size be written, returning early with the abort request if one is
+// received meanwhile.
+func (dl *diskLayer) waitMaintenance(size int) chan *generatorStats {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		maintenance.Wait(ctx, size)
+	}()
+	select {
+	case <-done:
+		return nil
+	case abort := <-dl.genAbort:
+		cancel()
+		<-done
+		return abort
+	}
+}
+
 // checkAndFlush checks if an interruption signal is received or the
 // batch size has exceeded the allowance.
 func (dl *diskLayer) checkAndFlush(ctx *generatorContext, current []byte) error {
@@ -482,6 +506,11 @@ func (dl *diskLayer) checkAndFlush(ctx *generatorContext, current []byte) error
 		// generation indeed makes progress.
 		journalProgress(ctx.batch, current, ctx.stats)
 
+		// Yield to the node's duties unless asked to abort, cutting the wait
+		// short if asked to abort meanwhile
+		if abort == nil {
+			abort = dl.waitMaintenance(ctx.batch.ValueSize())
+		}
 		if err := ctx.batch.Write(); err != nil {
 			return err
 		}
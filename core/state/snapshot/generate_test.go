@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/internal/maintenance"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
@@ -955,3 +956,31 @@ func TestGenerateBrokenSnapshotWithDanglingStorage(t *testing.T) {
 	snap.genAbort <- stop
 	<-stop
 }
+
+// Tests that an abort request received while the generator waits for the
+// maintenance scheduler cuts the wait short.
+func TestGenerateAbortDuringMaintenance(t *testing.T) {
+	scheduler := maintenance.NewScheduler(maintenance.Config{})
+	scheduler.SetPause(func() time.Duration { return time.Hour })
+	maintenance.Install(scheduler)
+	defer maintenance.Install(nil)
+
+	dl := &diskLayer{genAbort: make(chan chan *generatorStats)}
+	result := make(chan chan *generatorStats)
+	go func() { result <- dl.waitMaintenance(1) }()
+
+	stop := make(chan *generatorStats)
+	select {
+	case dl.genAbort <- stop:
+	case <-time.After(time.Second):
+		t.Fatalf("abort request not received while waiting")
+	}
+	select {
+	case abort := <-result:
+		if abort != stop {
+			t.Fatalf("abort request not returned")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("wait not cut short by abort request")
+	}
+}
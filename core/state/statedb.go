@@ -1037,6 +1037,12 @@ func (s *StateDB) AddSlotToAccessList(addr common.Address, slot common.Hash) {
 	}
 }
 
+// AccessList returns the accounts and storage slots in the access list, which
+// post-Berlin are the ones touched by the transaction being executed so far.
+func (s *StateDB) AccessList() types.AccessList {
+	return s.accessList.Export()
+}
+
 // AddressInAccessList returns true if the given address is in the access list.
 func (s *StateDB) AddressInAccessList(addr common.Address) bool {
 	return s.accessList.ContainsAddress(addr)
@@ -19,6 +19,7 @@ package core
 import (
 	"sync/atomic"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -82,6 +83,67 @@ func (p *statePrefetcher) Prefetch(block *types.Block, statedb *state.StateDB, c
 	}
 }
 
+// PrefetchTransactions speculatively runs the transactions expected in a block
+// with the given header on top of an arbitrary state, but any changes are
+// discarded. Contrary to Prefetch, the transactions don't have to make up a valid
+// block: they run without nonce checks, and the ones failing are skipped. The
+// only goal is to warm up the trie nodes they touch before the block is built or
+// imported. It returns the state accessed by each transaction run, which is only
+// tracked from Berlin on and otherwise empty.
+func (p *statePrefetcher) PrefetchTransactions(header *types.Header, txs []*types.Transaction, statedb *state.StateDB, cfg vm.Config, interrupt *uint32) map[common.Hash]types.AccessList {
+	var (
+		gaspool      = new(GasPool).AddGas(header.GasLimit)
+		blockContext = NewEVMBlockContext(header, p.bc, &header.Coinbase)
+		evm          = vm.NewEVM(blockContext, vm.TxContext{}, statedb, p.config, cfg)
+		signer       = types.MakeSigner(p.config, header.Number)
+		lists        = make(map[common.Hash]types.AccessList)
+	)
+	for i, tx := range txs {
+		// If transaction precaching was interrupted, abort
+		if interrupt != nil && atomic.LoadUint32(interrupt) == 1 {
+			break
+		}
+		msg, err := tx.AsMessage(signer, header.BaseFee)
+		if err != nil {
+			continue
+		}
+		// Skip the nonce checks, the preceding transactions of the sender might
+		// have been run by an earlier prefetch
+		msg = types.NewMessage(msg.From(), msg.To(), msg.Nonce(), msg.Value(), msg.Gas(), msg.GasPrice(), msg.GasFeeCap(), msg.GasTipCap(), msg.Data(), msg.AccessList(), true)
+
+		statedb.Prepare(tx.Hash(), i)
+		if err := precacheTransaction(msg, p.config, gaspool, statedb, header, evm); err != nil {
+			if gaspool.Gas() < params.TxGas {
+				break
+			}
+			continue
+		}
+		lists[tx.Hash()] = statedb.AccessList()
+	}
+	// Pre-load the trie nodes along the paths of the modified state
+	statedb.IntermediateRoot(true)
+	return lists
+}
+
+// PrefetchAccessLists reads the accounts and storage slots of the given access
+// lists from an arbitrary state. It warms up the trie nodes of transactions run
+// on top of an earlier state without running them again. It returns the number
+// of access lists read.
+func (p *statePrefetcher) PrefetchAccessLists(lists []types.AccessList, statedb *state.StateDB, interrupt *uint32) int {
+	for i, list := range lists {
+		if interrupt != nil && atomic.LoadUint32(interrupt) == 1 {
+			return i
+		}
+		for _, tuple := range list {
+			statedb.GetCodeHash(tuple.Address)
+			for _, slot := range tuple.StorageKeys {
+				statedb.GetState(tuple.Address, slot)
+			}
+		}
+	}
+	return len(lists)
+}
+
 // precacheTransaction attempts to apply a transaction to the given state database
 // and uses the input parameters for its environment. The goal is not to execute
 // the transaction successfully, rather to warm up touched data slots.
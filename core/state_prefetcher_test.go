@@ -0,0 +1,107 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the pending transactions expected in the next block are run on top
+// of the head without nonce checks, skipping the failing ones, and without
+// touching the chain state.
+func TestPrefetchTransactions(t *testing.T) {
+	var (
+		key, _    = crypto.GenerateKey()
+		addr      = crypto.PubkeyToAddress(key.PublicKey)
+		poor, _   = crypto.GenerateKey()
+		recipient = common.HexToAddress("0xdeadbeef")
+		db        = rawdb.NewMemoryDatabase()
+		gspec     = &Genesis{
+			Config:  params.TestChainConfig,
+			BaseFee: big.NewInt(params.InitialBaseFee),
+			Alloc:   GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.LatestSigner(params.TestChainConfig)
+	)
+	chain, err := NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit(),
+		Time:       genesis.Time() + 1,
+		Difficulty: genesis.Difficulty(),
+		BaseFee:    misc.CalcBaseFee(params.TestChainConfig, genesis.Header()),
+	}
+	price := new(big.Int).Mul(header.BaseFee, big.NewInt(2))
+	txs := []*types.Transaction{
+		// Nonce ahead of the state, as if its predecessor ran in an earlier prefetch
+		types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 1, To: &recipient, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: price}),
+		// Sender can't pay for the gas
+		types.MustSignNewTx(poor, signer, &types.LegacyTx{Nonce: 0, To: &recipient, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: price}),
+	}
+	lists, err := chain.PrefetchTransactions(header, txs, nil)
+	if err != nil {
+		t.Fatalf("failed to prefetch: %v", err)
+	}
+	if len(lists) != 1 {
+		t.Fatalf("transactions run mismatch: have %d, want 1", len(lists))
+	}
+	// The state touched by the transaction run is returned as its access list
+	touched := make(map[common.Address]bool)
+	for _, tuple := range lists[txs[0].Hash()] {
+		touched[tuple.Address] = true
+	}
+	if !touched[addr] || !touched[recipient] {
+		t.Fatalf("access list misses the touched accounts: %v", lists[txs[0].Hash()])
+	}
+	if read, err := chain.PrefetchAccessLists(header, []types.AccessList{lists[txs[0].Hash()]}, nil); err != nil || read != 1 {
+		t.Fatalf("failed to prefetch access lists: read %d, err %v", read, err)
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	if balance := statedb.GetBalance(recipient); balance.Sign() != 0 {
+		t.Fatalf("prefetch modified the chain state: recipient balance %v", balance)
+	}
+	// An interrupted prefetch runs nothing
+	interrupt := uint32(1)
+	if lists, _ := chain.PrefetchTransactions(header, txs, &interrupt); len(lists) != 0 {
+		t.Fatalf("interrupted prefetch ran %d transactions", len(lists))
+	}
+	// Unknown parents are rejected
+	header.ParentHash = common.Hash{0x01}
+	if _, err := chain.PrefetchTransactions(header, txs, nil); err == nil {
+		t.Fatalf("prefetch on unknown parent succeeded")
+	}
+}
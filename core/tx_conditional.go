@@ -0,0 +1,46 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// CheckConditionalState returns an error if the known accounts of a transaction
+// conditional don't match the given state.
+func CheckConditionalState(cond *types.TransactionConditional, statedb *state.StateDB) error {
+	for addr, account := range cond.KnownAccounts {
+		if account.StorageRoot != nil {
+			root := types.EmptyRootHash
+			if trie := statedb.StorageTrie(addr); trie != nil {
+				root = trie.Hash()
+			}
+			if root != *account.StorageRoot {
+				return fmt.Errorf("storage root of %v mismatch: have %v, want %v", addr, root, *account.StorageRoot)
+			}
+		}
+		for slot, want := range account.StorageSlots {
+			if have := statedb.GetState(addr, slot); have != want {
+				return fmt.Errorf("storage slot %v of %v mismatch: have %v, want %v", slot, addr, have, want)
+			}
+		}
+	}
+	return nil
+}
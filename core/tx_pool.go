@@ -20,6 +20,7 @@ import (
 	"errors"
 	"math"
 	"math/big"
+	"runtime"
 	"sort"
 	"sync"
 	"sync/atomic"
@@ -85,6 +86,14 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrWrongChainID is returned if a transaction is signed for a different chain
+	// id than the local one, e.g. when replayed from another chain.
+	ErrWrongChainID = errors.New("transaction signed for a different chain id")
+
+	// ErrUnprotectedTx is returned if a transaction without EIP-155 replay protection
+	// is submitted while the pool only accepts protected ones.
+	ErrUnprotectedTx = errors.New("transaction not replay-protected (EIP-155)")
 )
 
 var (
@@ -111,7 +120,10 @@ var (
 	validTxMeter       = metrics.NewRegisteredMeter("txpool/valid", nil)
 	invalidTxMeter     = metrics.NewRegisteredMeter("txpool/invalid", nil)
 	underpricedTxMeter = metrics.NewRegisteredMeter("txpool/underpriced", nil)
+	wrongChainTxMeter  = metrics.NewRegisteredMeter("txpool/wrongchain", nil)
+	unprotectedTxMeter = metrics.NewRegisteredMeter("txpool/unprotected", nil)
 	overflowedTxMeter  = metrics.NewRegisteredMeter("txpool/overflowed", nil)
+	journalStaleMeter  = metrics.NewRegisteredMeter("txpool/journal/stale", nil) // Journaled transactions invalidated while the node was down
 	// throttleTxMeter counts how many transactions are rejected due to too-many-changes between
 	// txpool reorgs.
 	throttleTxMeter = metrics.NewRegisteredMeter("txpool/throttle", nil)
@@ -156,9 +168,22 @@ type TxPoolConfig struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	JournalRemotes bool   // Whether to also journal remote transactions to survive node restarts
+	RemoteJournal  string // Journal of remote transactions, if journaled
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
+	FeeExempt []common.Address // Senders whose transactions are accepted regardless of the price limit
+
+	ReplayProtected bool             // Whether to only accept transactions with EIP-155 replay protection
+	Unprotected     []common.Address // Senders allowed to submit unprotected transactions regardless (e.g. legacy tooling)
+
+	Sponsored      []common.Address // Senders and contracts whose zero priced transactions are sponsored (chains without base fee only)
+	SponsorBudget  *big.Int         // Fees forgone for sponsored transactions, priced at the price limit
+	SponsorMaxGas  uint64           // Maximum gas of a sponsored transaction (0 = block gas limit)
+	SponsorJournal string           // Journal of the spent sponsor budget to survive node restarts
+
 	AccountSlots uint64 // Number of executable transaction slots guaranteed per account
 	GlobalSlots  uint64 // Maximum number of executable transaction slots for all accounts
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
@@ -173,6 +198,10 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	RemoteJournal: "remote-transactions.rlp",
+
+	SponsorJournal: "sponsor.rlp",
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -244,12 +273,17 @@ type TxPool struct {
 	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
 	eip1559  bool // Fork indicator whether we are using EIP-1559 type transactions.
 
+	currentHead   *types.Header  // Current head of the blockchain
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
 	currentMaxGas uint64         // Current gas limit for transaction caps
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	locals      *accountSet // Set of local transaction to exempt from eviction rules
+	feeExempt   *accountSet // Set of senders exempt from the price limit
+	unprotected *accountSet // Set of senders allowed to submit unprotected transactions
+	sponsor     *txSponsor  // Sponsorship of zero priced transactions, nil if disabled
+	journal     *txJournal  // Journal of local transaction to back up to disk
+	remotes     *txJournal  // Journal of remote transactions to back up to disk, nil if disabled
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
@@ -304,6 +338,15 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		log.Info("Setting new local account", "address", addr)
 		pool.locals.add(addr)
 	}
+	pool.feeExempt = newAccountSet(pool.signer, config.FeeExempt...)
+	for _, addr := range config.FeeExempt {
+		log.Info("Exempting account from txpool price limit", "address", addr)
+	}
+	pool.unprotected = newAccountSet(pool.signer, config.Unprotected...)
+	if config.ReplayProtected {
+		log.Info("Accepting only replay-protected transactions", "chainid", chainconfig.ChainID, "allowed", len(config.Unprotected))
+	}
+	pool.sponsor = newTxSponsor(config.Sponsored, config.SponsorBudget, config.SponsorMaxGas, config.SponsorJournal)
 	pool.priced = newTxPricedList(pool.all)
 	pool.reset(nil, chain.CurrentBlock().Header())
 
@@ -313,15 +356,26 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 
 	// If local transactions and journaling is enabled, load from disk
 	if !config.NoLocals && config.Journal != "" {
-		pool.journal = newTxJournal(config.Journal)
+		pool.journal = newTxJournal(config.Journal, "local")
 
-		if err := pool.journal.load(pool.AddLocals); err != nil {
+		if err := pool.journal.load(pool.revalidateJournaled(pool.AddLocals)); err != nil {
 			log.Warn("Failed to load transaction journal", "err", err)
 		}
 		if err := pool.journal.rotate(pool.local()); err != nil {
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote transaction journaling is enabled, load them from disk too
+	if config.JournalRemotes && config.RemoteJournal != "" {
+		pool.remotes = newTxJournal(config.RemoteJournal, "remote")
+
+		if err := pool.remotes.load(pool.revalidateJournaled(pool.AddRemotesSync)); err != nil {
+			log.Warn("Failed to load remote transaction journal", "err", err)
+		}
+		if err := pool.remotes.rotate(pool.remote()); err != nil {
+			log.Warn("Failed to rotate remote transaction journal", "err", err)
+		}
+	}
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
@@ -406,6 +460,13 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+			if pool.remotes != nil {
+				pool.mu.Lock()
+				if err := pool.remotes.rotate(pool.remote()); err != nil {
+					log.Warn("Failed to rotate remote tx journal", "err", err)
+				}
+				pool.mu.Unlock()
+			}
 		}
 	}
 }
@@ -422,9 +483,39 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.remotes != nil {
+		pool.remotes.close()
+	}
 	log.Info("Transaction pool stopped")
 }
 
+// FlushJournal regenerates the transaction journals from the current pool and
+// closes them, so that the journaled transactions survive the process being
+// killed during the rest of the shutdown. Transactions added afterwards aren't
+// journaled.
+func (pool *TxPool) FlushJournal() error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.journal != nil {
+		if err := pool.journal.rotate(pool.local()); err != nil {
+			return err
+		}
+		if err := pool.journal.close(); err != nil {
+			return err
+		}
+	}
+	if pool.remotes != nil {
+		if err := pool.remotes.rotate(pool.remote()); err != nil {
+			return err
+		}
+		if err := pool.remotes.close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SubscribeNewTxsEvent registers a subscription of NewTxsEvent and
 // starts sending event to the given channel.
 func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- NewTxsEvent) event.Subscription {
@@ -537,14 +628,26 @@ func (pool *TxPool) Pending(enforceTips bool) map[common.Address]types.Transacti
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
-	pending := make(map[common.Address]types.Transactions)
+	var (
+		pending  = make(map[common.Address]types.Transactions)
+		reserved = new(big.Int) // Sponsor budget reserved for the returned transactions
+	)
 	for addr, list := range pool.pending {
 		txs := list.Flatten()
 
-		// If the miner requests tip enforcement, cap the lists now
-		if enforceTips && !pool.locals.contains(addr) {
+		// If the miner requests tip enforcement, cap the lists now. Sponsored
+		// transactions are capped once the sponsor budget is used up instead.
+		if enforceTips {
+			exempt := pool.locals.contains(addr) || pool.feeExempt.contains(addr)
 			for i, tx := range txs {
-				if tx.EffectiveGasTipIntCmp(pool.gasPrice, pool.priced.urgent.baseFee) < 0 {
+				if pool.sponsor.sponsored(addr, tx) {
+					if !pool.sponsor.reserve(tx, pool.gasPrice, reserved) {
+						txs = txs[:i]
+						break
+					}
+					continue
+				}
+				if !exempt && tx.EffectiveGasTipIntCmp(pool.gasPrice, pool.priced.urgent.baseFee) < 0 {
 					txs = txs[:i]
 					break
 				}
@@ -557,6 +660,31 @@ func (pool *TxPool) Pending(enforceTips bool) map[common.Address]types.Transacti
 	return pending
 }
 
+// SponsorStatus returns the state of the sponsorship of zero priced transactions.
+func (pool *TxPool) SponsorStatus() SponsorStatus {
+	if pool.sponsor == nil {
+		return SponsorStatus{}
+	}
+	return pool.sponsor.status()
+}
+
+// ChargeSponsored accounts the sponsored transactions of a block sealed by the
+// local validator against the sponsor budget, priced at the current price limit.
+func (pool *TxPool) ChargeSponsored(block *types.Block, receipts types.Receipts) {
+	if pool.sponsor == nil {
+		return
+	}
+	price := pool.GasPrice()
+	signer := types.MakeSigner(pool.chainconfig, block.Number())
+	for i, tx := range block.Transactions() {
+		from, err := types.Sender(signer, tx)
+		if err != nil || !pool.sponsor.sponsored(from, tx) {
+			continue
+		}
+		pool.sponsor.charge(receipts[i].GasUsed, price)
+	}
+}
+
 // Locals retrieves the accounts currently considered local by the pool.
 func (pool *TxPool) Locals() []common.Address {
 	pool.mu.Lock()
@@ -581,6 +709,24 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// remote retrieves all currently known remote transactions, grouped by origin
+// account and sorted by nonce. The returned transaction set is a copy and can be
+// freely modified by calling code.
+func (pool *TxPool) remote() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for addr, pending := range pool.pending {
+		if !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], pending.Flatten()...)
+		}
+	}
+	for addr, queued := range pool.queue {
+		if !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], queued.Flatten()...)
+		}
+	}
+	return txs
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
@@ -616,13 +762,36 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if tx.GasFeeCapIntCmp(tx.GasTipCap()) < 0 {
 		return ErrTipAboveFeeCap
 	}
+	// Reject transactions signed for other chains explicitly, they are most likely
+	// replayed from them rather than just badly signed.
+	if err := pool.validateChainID(tx); err != nil {
+		return err
+	}
 	// Make sure the transaction is signed properly.
 	from, err := types.Sender(pool.signer, tx)
 	if err != nil {
 		return ErrInvalidSender
 	}
-	// Drop non-local transactions under our own minimal accepted gas price or tip
-	if !local && tx.GasTipCapIntCmp(pool.gasPrice) < 0 {
+	// Drop transactions without replay protection if only protected ones are
+	// accepted, unless the sender is allowed to submit them
+	if pool.config.ReplayProtected && !tx.Protected() && !pool.unprotected.contains(from) {
+		unprotectedTxMeter.Mark(1)
+		return ErrUnprotectedTx
+	}
+	// Zero priced transactions of sponsored accounts are accepted as long as the
+	// sponsor budget covers them, on chains without a base fee to pay
+	sponsored := pool.sponsor.sponsored(from, tx)
+	if sponsored {
+		if pool.eip1559 {
+			return ErrSponsorBaseFee
+		}
+		if err := pool.sponsor.admit(tx, pool.gasPrice); err != nil {
+			return err
+		}
+	}
+	// Drop non-local transactions under our own minimal accepted gas price or tip,
+	// unless the sender is exempt from fees
+	if !local && !sponsored && !pool.feeExempt.contains(from) && tx.GasTipCapIntCmp(pool.gasPrice) < 0 {
 		return ErrUnderpriced
 	}
 	// Ensure the transaction adheres to nonce ordering
@@ -645,6 +814,16 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	return nil
 }
 
+// validateChainID checks whether a replay protected transaction is signed for
+// the local chain id.
+func (pool *TxPool) validateChainID(tx *types.Transaction) error {
+	if tx.Protected() && pool.chainconfig.ChainID != nil && tx.ChainId().Cmp(pool.chainconfig.ChainID) != 0 {
+		wrongChainTxMeter.Mark(1)
+		return ErrWrongChainID
+	}
+	return nil
+}
+
 // add validates a transaction and inserts it into the non-executable queue for later
 // pending promotion and execution. If the transaction is a replacement for an already
 // pending or queued one, it overwrites the previous transaction if its price is higher.
@@ -793,10 +972,22 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 }
 
 // journalTx adds the specified transaction to the local disk journal if it is
-// deemed to have been sent from a local account.
+// deemed to have been sent from a local account, or to the remote journal if
+// remote transactions are journaled too.
 func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
-	// Only journal if it's enabled and the transaction is local
-	if pool.journal == nil || !pool.locals.contains(from) {
+	// Conditional transactions are not journaled as the preconditions would be lost
+	if tx.Conditional() != nil {
+		return
+	}
+	if !pool.locals.contains(from) {
+		if pool.remotes != nil {
+			if err := pool.remotes.insert(tx); err != nil {
+				log.Warn("Failed to journal remote transaction", "err", err)
+			}
+		}
+		return
+	}
+	if pool.journal == nil {
 		return
 	}
 	if err := pool.journal.insert(tx); err != nil {
@@ -804,6 +995,67 @@ func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
 	}
 }
 
+// revalidateJournaled wraps the function adding journaled transactions to the
+// pool, checking them against the current state in parallel first. Senders are
+// recovered and transactions with stale nonces or unfunded ones are dropped
+// before the pool lock is taken for the remaining ones.
+func (pool *TxPool) revalidateJournaled(add func([]*types.Transaction) []error) func([]*types.Transaction) []error {
+	return func(txs []*types.Transaction) []error {
+		workers := runtime.NumCPU()
+		if workers > len(txs) {
+			workers = len(txs)
+		}
+		// Every worker reads its own copy of the state, it's not thread safe
+		states := make([]*state.StateDB, workers)
+		pool.mu.RLock()
+		for i := range states {
+			states[i] = pool.currentState.Copy()
+		}
+		pool.mu.RUnlock()
+
+		var (
+			errs = make([]error, len(txs))
+			wg   sync.WaitGroup
+		)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := w; i < len(txs); i += workers {
+					from, err := types.Sender(pool.signer, txs[i])
+					switch {
+					case err != nil:
+						errs[i] = ErrInvalidSender
+					case states[w].GetNonce(from) > txs[i].Nonce():
+						errs[i] = ErrNonceTooLow
+					case states[w].GetBalance(from).Cmp(txs[i].Cost()) < 0:
+						errs[i] = ErrInsufficientFunds
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		// Hand the still valid transactions to the pool
+		var (
+			valid   = make([]*types.Transaction, 0, len(txs))
+			indices = make([]int, 0, len(txs))
+		)
+		for i, tx := range txs {
+			if errs[i] != nil {
+				journalStaleMeter.Mark(1)
+				continue
+			}
+			valid = append(valid, tx)
+			indices = append(indices, i)
+		}
+		for i, err := range add(valid) {
+			errs[indices[i]] = err
+		}
+		return errs
+	}
+}
+
 // promoteTx adds a transaction to the pending (processable) list of transactions
 // and returns whether it was inserted or an older was better.
 //
@@ -899,9 +1151,14 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 			knownTxMeter.Mark(1)
 			continue
 		}
-		// Exclude transactions with invalid signatures as soon as
-		// possible and cache senders in transactions before
-		// obtaining lock
+		// Exclude transactions signed for other chains or with invalid
+		// signatures as soon as possible and cache senders in
+		// transactions before obtaining lock
+		if err := pool.validateChainID(tx); err != nil {
+			errs[i] = err
+			invalidTxMeter.Mark(1)
+			continue
+		}
 		_, err := types.Sender(pool.signer, tx)
 		if err != nil {
 			errs[i] = ErrInvalidSender
@@ -1290,6 +1547,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 		log.Error("Failed to reset txpool state", "err", err)
 		return
 	}
+	pool.currentHead = newHead
 	pool.currentState = statedb
 	pool.pendingNonces = newTxNoncer(statedb)
 	pool.currentMaxGas = newHead.GasLimit
@@ -1532,6 +1790,16 @@ func (pool *TxPool) demoteUnexecutables() {
 		}
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
+		// Drop all conditional transactions that can't be included anymore
+		expired, unlocked := pool.filterExpired(list)
+		for _, tx := range expired {
+			hash := tx.Hash()
+			log.Trace("Removed expired conditional transaction", "hash", hash)
+			pool.all.Remove(hash)
+		}
+		drops = append(drops, expired...)
+		invalids = append(invalids, unlocked...)
+
 		for _, tx := range invalids {
 			hash := tx.Hash()
 			log.Trace("Demoting pending transaction", "hash", hash)
@@ -1564,6 +1832,31 @@ func (pool *TxPool) demoteUnexecutables() {
 	}
 }
 
+// filterExpired removes the transactions of a pending list whose conditionals
+// can't be satisfied by any future block, along with any transactions after
+// them which got invalidated by the resulting nonce gap.
+func (pool *TxPool) filterExpired(list *txList) (types.Transactions, types.Transactions) {
+	if pool.currentHead == nil {
+		return nil, nil
+	}
+	head := pool.currentHead
+	expired := list.txs.Filter(func(tx *types.Transaction) bool {
+		cond := tx.Conditional()
+		return cond != nil && cond.Expired(head.Number, head.Time)
+	})
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	lowest := expired[0].Nonce()
+	for _, tx := range expired[1:] {
+		if tx.Nonce() < lowest {
+			lowest = tx.Nonce()
+		}
+	}
+	invalids := list.txs.Filter(func(tx *types.Transaction) bool { return tx.Nonce() > lowest })
+	return expired, invalids
+}
+
 // addressByHeartbeat is an account address tagged with its last activity timestamp.
 type addressByHeartbeat struct {
 	address   common.Address
@@ -23,11 +23,13 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -49,6 +51,7 @@ var (
 func init() {
 	testTxPoolConfig = DefaultTxPoolConfig
 	testTxPoolConfig.Journal = ""
+	testTxPoolConfig.SponsorJournal = ""
 
 	cpy := *params.TestChainConfig
 	eip1559Config = &cpy
@@ -2346,6 +2349,63 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that remote transactions survive restarts if journaled, dropping the
+// ones invalidated by the state in the meantime.
+func TestTransactionJournalingRemotes(t *testing.T) {
+	t.Parallel()
+
+	journal := filepath.Join(t.TempDir(), "remotes.rlp")
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.NoLocals = true
+	config.JournalRemotes = true
+	config.RemoteJournal = journal
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	stale, _ := crypto.GenerateKey()
+	broke, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(stale.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(broke.PublicKey), big.NewInt(1000000000))
+
+	for i, err := range pool.AddRemotesSync([]*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(1), stale),
+		pricedTransaction(1, 100000, big.NewInt(1), stale),
+		pricedTransaction(2, 100000, big.NewInt(1), stale),
+		pricedTransaction(0, 100000, big.NewInt(1), broke),
+	}) {
+		if err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", i, err)
+		}
+	}
+	if pending, _ := pool.Stats(); pending != 4 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 4)
+	}
+	pool.Stop()
+
+	// Include the first transaction and drain the other account meanwhile
+	statedb.SetNonce(crypto.PubkeyToAddress(stale.PublicKey), 1)
+	statedb.SetBalance(crypto.PubkeyToAddress(broke.PublicKey), new(big.Int))
+	blockchain = &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	pending, queued := pool.Stats()
+	if pending != 2 || queued != 0 {
+		t.Fatalf("transactions mismatched: have %d pending, %d queued, want 2 pending", pending, queued)
+	}
+	if pool.locals.contains(crypto.PubkeyToAddress(stale.PublicKey)) {
+		t.Fatalf("journaled remote account turned local")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
@@ -2560,3 +2620,185 @@ func BenchmarkPoolMultiAccountBatchInsert(b *testing.B) {
 		pool.AddRemotesSync([]*types.Transaction{tx})
 	}
 }
+
+// Tests that fee exempt senders get their transactions accepted below the price
+// limit, while others are still rejected.
+func TestTransactionFeeExempt(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{10000000, statedb, new(event.Feed)}
+
+	exempt, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	config := testTxPoolConfig
+	config.FeeExempt = []common.Address{crypto.PubkeyToAddress(exempt.PublicKey)}
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(exempt.PublicKey), big.NewInt(1000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(other.PublicKey), big.NewInt(1000000))
+
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(0), exempt)); err != nil {
+		t.Fatalf("zero-fee transaction from exempt sender rejected: %v", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(0), other)); err != ErrUnderpriced {
+		t.Fatalf("zero-fee transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	<-pool.requestPromoteExecutables(newAccountSet(pool.signer, crypto.PubkeyToAddress(exempt.PublicKey)))
+	if pending := pool.Pending(true); len(pending) != 1 {
+		t.Fatalf("pending accounts mismatch: have %d, want 1", len(pending))
+	}
+}
+
+// Tests that conditional transactions are dropped once no future block can
+// satisfy their bounds, with the transactions after them demoted.
+func TestTransactionConditionalExpiry(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	account := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, account, big.NewInt(1000000))
+
+	max := hexutil.Big(*big.NewInt(5))
+	conditional := transaction(1, 100000, key)
+	conditional.SetConditional(&types.TransactionConditional{BlockNumberMax: &max})
+
+	pool.AddLocals([]*types.Transaction{transaction(0, 100000, key), conditional, transaction(2, 100000, key)})
+	<-pool.requestPromoteExecutables(newAccountSet(pool.signer, account))
+	if pending, _ := pool.Stats(); pending != 3 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 3)
+	}
+	// Advance the head to the maximum block of the conditional
+	<-pool.requestReset(nil, &types.Header{Number: big.NewInt(5), GasLimit: 10000000, BaseFee: big.NewInt(params.InitialBaseFee)})
+
+	pending, queued := pool.Stats()
+	if pending != 1 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+	if queued != 1 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	}
+	if pool.Get(conditional.Hash()) != nil {
+		t.Fatalf("expired conditional transaction not dropped")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that zero priced transactions of sponsored senders and contracts are
+// accepted within the sponsorship limits, and refused once the budget is spent,
+// including after a restart.
+func TestTransactionSponsorship(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{10000000, statedb, new(event.Feed)}
+
+	sponsored, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	contract := common.Address{0xc0}
+
+	chainconfig := *params.TestChainConfig
+	chainconfig.LondonBlock = nil
+
+	config := testTxPoolConfig
+	config.Sponsored = []common.Address{crypto.PubkeyToAddress(sponsored.PublicKey), contract}
+	config.SponsorBudget = big.NewInt(150000)
+	config.SponsorMaxGas = 100000
+	config.SponsorJournal = filepath.Join(t.TempDir(), "sponsor.rlp")
+	pool := NewTxPool(config, &chainconfig, blockchain)
+	defer pool.Stop()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(sponsored.PublicKey), big.NewInt(1000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(other.PublicKey), big.NewInt(1000000))
+
+	call := func(nonce uint64, gas uint64, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, contract, big.NewInt(0), gas, big.NewInt(0), nil), types.HomesteadSigner{}, key)
+		return tx
+	}
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(0), sponsored)); err != nil {
+		t.Fatalf("zero priced transaction from sponsored sender rejected: %v", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(0), other)); err != ErrUnderpriced {
+		t.Fatalf("zero priced transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if err := pool.AddRemote(call(0, 200000, other)); err != ErrSponsorGasLimit {
+		t.Fatalf("oversized sponsored transaction error mismatch: have %v, want %v", err, ErrSponsorGasLimit)
+	}
+	if err := pool.AddRemote(call(0, 100000, other)); err != nil {
+		t.Fatalf("zero priced transaction to sponsored contract rejected: %v", err)
+	}
+	<-pool.requestPromoteExecutables(newAccountSet(pool.signer, crypto.PubkeyToAddress(sponsored.PublicKey), crypto.PubkeyToAddress(other.PublicKey)))
+
+	// The budget only covers one of the sponsored transactions at a time
+	if pending := pool.Pending(true); len(pending) != 1 {
+		t.Fatalf("pending accounts mismatch: have %d, want 1", len(pending))
+	}
+	// Charging an included transaction leaves less than another one's worth
+	tx := call(1, 100000, other)
+	pool.ChargeSponsored(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)}).WithBody([]*types.Transaction{tx}, nil),
+		types.Receipts{{GasUsed: 100000}})
+
+	status := pool.SponsorStatus()
+	if status.Spent.ToInt().Cmp(big.NewInt(100000)) != 0 || status.Remaining.ToInt().Cmp(big.NewInt(50000)) != 0 || status.Transactions != 1 {
+		t.Fatalf("sponsor status mismatch: %+v", status)
+	}
+	if err := pool.AddRemote(tx); err != ErrSponsorExhausted {
+		t.Fatalf("sponsored transaction error mismatch: have %v, want %v", err, ErrSponsorExhausted)
+	}
+	if pending := pool.Pending(true); len(pending) != 0 {
+		t.Fatalf("pending accounts mismatch: have %d, want 0", len(pending))
+	}
+	// A restart resumes with the spent budget instead of the full one
+	pool.Stop()
+	restarted := NewTxPool(config, &chainconfig, blockchain)
+	defer restarted.Stop()
+
+	if resumed := restarted.SponsorStatus(); resumed.Spent.ToInt().Cmp(status.Spent.ToInt()) != 0 || resumed.Transactions != 1 || resumed.Gas != 100000 {
+		t.Fatalf("resumed sponsor status mismatch: have %+v, want %+v", resumed, status)
+	}
+	if err := restarted.AddRemote(tx); err != ErrSponsorExhausted {
+		t.Fatalf("sponsored transaction error mismatch after restart: have %v, want %v", err, ErrSponsorExhausted)
+	}
+}
+
+// Tests that transactions signed for other chains are rejected, as well as the
+// unprotected ones in replay protected mode, unless their sender is allowed to.
+func TestTransactionReplayProtection(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{10000000, statedb, new(event.Feed)}
+
+	legacy, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	config := testTxPoolConfig
+	config.ReplayProtected = true
+	config.Unprotected = []common.Address{crypto.PubkeyToAddress(legacy.PublicKey)}
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(legacy.PublicKey), big.NewInt(1000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(other.PublicKey), big.NewInt(1000000))
+
+	foreign, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 100000, big.NewInt(1), nil), types.NewEIP155Signer(big.NewInt(1337)), other)
+	if err := pool.AddRemote(foreign); err != ErrWrongChainID {
+		t.Fatalf("foreign transaction error mismatch: have %v, want %v", err, ErrWrongChainID)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), other)); err != ErrUnprotectedTx {
+		t.Fatalf("unprotected transaction error mismatch: have %v, want %v", err, ErrUnprotectedTx)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), legacy)); err != nil {
+		t.Fatalf("unprotected transaction from allowed sender rejected: %v", err)
+	}
+	protected, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 100000, big.NewInt(1), nil), types.NewEIP155Signer(params.TestChainConfig.ChainID), other)
+	if err := pool.AddRemote(protected); err != nil {
+		t.Fatalf("protected transaction rejected: %v", err)
+	}
+}
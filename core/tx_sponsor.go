@@ -0,0 +1,266 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	// ErrSponsorExhausted is returned if a sponsored transaction would cost more
+	// than what's left of the sponsor budget.
+	ErrSponsorExhausted = errors.New("sponsor budget exhausted")
+
+	// ErrSponsorGasLimit is returned if a sponsored transaction requests more gas
+	// than sponsored for a single transaction.
+	ErrSponsorGasLimit = errors.New("sponsored transaction exceeds gas limit")
+
+	// ErrSponsorBaseFee is returned if a sponsored transaction is submitted on a
+	// chain enforcing a base fee, which zero priced transactions can't pay.
+	ErrSponsorBaseFee = errors.New("sponsored transaction below base fee")
+)
+
+var (
+	sponsoredTxMeter     = metrics.NewRegisteredMeter("txpool/sponsor/accepted", nil)
+	sponsorRejectedMeter = metrics.NewRegisteredMeter("txpool/sponsor/rejected", nil)
+	sponsoredGasMeter    = metrics.NewRegisteredMeter("txpool/sponsor/gas", nil)
+	sponsorSpentGauge    = metrics.NewRegisteredGauge("txpool/sponsor/spent", nil)     // In gwei
+	sponsorRemainGauge   = metrics.NewRegisteredGauge("txpool/sponsor/remaining", nil) // In gwei
+)
+
+// SponsorStatus is the state of the transaction sponsorship of the local node.
+type SponsorStatus struct {
+	Enabled      bool             `json:"enabled"`
+	Accounts     []common.Address `json:"accounts"`
+	Budget       *hexutil.Big     `json:"budget"`
+	Spent        *hexutil.Big     `json:"spent"`
+	Remaining    *hexutil.Big     `json:"remaining"`
+	MaxGas       hexutil.Uint64   `json:"maxGas"`
+	Transactions hexutil.Uint64   `json:"transactions"`
+	Gas          hexutil.Uint64   `json:"gas"`
+}
+
+// txSponsor accounts the fees forgone by the local validator for including the
+// zero priced transactions of sponsored senders and contracts, priced at the
+// pool's price limit, against the sponsor budget. Once the budget is spent, no
+// further sponsored transactions are accepted. The spent budget is journaled to
+// disk after each charge, so a restart doesn't refill the budget.
+//
+// The sponsor only forgoes the fees paid to the validator. A base fee has to be
+// paid by every transaction in a block and can't be covered, so sponsorship is
+// limited to chains without one.
+type txSponsor struct {
+	accounts map[common.Address]struct{} // Senders and contracts whose transactions are sponsored
+	budget   *big.Int                    // Fees the sponsor covers in total
+	maxGas   uint64                      // Maximum gas sponsored for a single transaction
+	journal  string                      // Path of the journal of the spent budget, empty if kept in memory only
+
+	spent *big.Int // Fees covered for the included sponsored transactions
+	txs   uint64   // Number of sponsored transactions included
+	gas   uint64   // Gas used by the sponsored transactions included
+	lock  sync.Mutex
+}
+
+// sponsorJournal is the accounting of the sponsorship journaled to disk.
+type sponsorJournal struct {
+	Spent *big.Int
+	Txs   uint64
+	Gas   uint64
+}
+
+// newTxSponsor creates a sponsor for the transactions of the given accounts, or
+// nil if there are none, resuming the accounting from the journal if any.
+func newTxSponsor(accounts []common.Address, budget *big.Int, maxGas uint64, journal string) *txSponsor {
+	if len(accounts) == 0 {
+		return nil
+	}
+	s := &txSponsor{
+		accounts: make(map[common.Address]struct{}),
+		budget:   new(big.Int),
+		maxGas:   maxGas,
+		journal:  journal,
+		spent:    new(big.Int),
+	}
+	if budget != nil {
+		s.budget.Set(budget)
+	}
+	for _, addr := range accounts {
+		s.accounts[addr] = struct{}{}
+		log.Info("Sponsoring zero priced transactions", "account", addr)
+	}
+	if err := s.load(); err != nil {
+		log.Warn("Failed to load sponsor journal", "err", err)
+	}
+	s.updateGauges()
+	return s
+}
+
+// load resumes the accounting from the journal, if it exists.
+func (s *txSponsor) load() error {
+	if s.journal == "" {
+		return nil
+	}
+	blob, err := os.ReadFile(s.journal)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var journal sponsorJournal
+	if err := rlp.DecodeBytes(blob, &journal); err != nil {
+		return err
+	}
+	s.spent, s.txs, s.gas = journal.Spent, journal.Txs, journal.Gas
+	log.Info("Loaded sponsor journal", "spent", s.spent, "transactions", s.txs, "gas", s.gas)
+	return nil
+}
+
+// save journals the accounting to disk, replacing the previous journal only
+// once fully written. The caller must hold the lock.
+func (s *txSponsor) save() error {
+	if s.journal == "" {
+		return nil
+	}
+	blob, err := rlp.EncodeToBytes(&sponsorJournal{Spent: s.spent, Txs: s.txs, Gas: s.gas})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.journal+".new", blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(s.journal+".new", s.journal)
+}
+
+// sponsored returns whether a transaction is a zero priced one sent by or to a
+// sponsored account.
+func (s *txSponsor) sponsored(from common.Address, tx *types.Transaction) bool {
+	if s == nil || tx.GasFeeCap().Sign() != 0 {
+		return false
+	}
+	if _, ok := s.accounts[from]; ok {
+		return true
+	}
+	if to := tx.To(); to != nil {
+		_, ok := s.accounts[*to]
+		return ok
+	}
+	return false
+}
+
+// admit checks whether the sponsorship limits allow a transaction if it used
+// all its gas at the given price.
+func (s *txSponsor) admit(tx *types.Transaction, price *big.Int) error {
+	if s.maxGas != 0 && tx.Gas() > s.maxGas {
+		sponsorRejectedMeter.Mark(1)
+		return ErrSponsorGasLimit
+	}
+	if !s.reserve(tx, price, new(big.Int)) {
+		sponsorRejectedMeter.Mark(1)
+		return ErrSponsorExhausted
+	}
+	return nil
+}
+
+// reserve adds the cost of a transaction using all its gas at the given price to
+// the already reserved budget, if what's left of the budget covers it.
+func (s *txSponsor) reserve(tx *types.Transaction, price *big.Int, reserved *big.Int) bool {
+	cost := new(big.Int).Mul(new(big.Int).SetUint64(tx.Gas()), price)
+	cost.Add(cost, reserved)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if cost.Cmp(s.remaining()) > 0 {
+		return false
+	}
+	reserved.Set(cost)
+	return true
+}
+
+// charge accounts the gas used by an included sponsored transaction at the given
+// price against the budget.
+func (s *txSponsor) charge(gasUsed uint64, price *big.Int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.spent.Add(s.spent, new(big.Int).Mul(new(big.Int).SetUint64(gasUsed), price))
+	s.txs++
+	s.gas += gasUsed
+
+	sponsoredTxMeter.Mark(1)
+	sponsoredGasMeter.Mark(int64(gasUsed))
+	s.updateGauges()
+
+	if err := s.save(); err != nil {
+		log.Warn("Failed to write sponsor journal", "err", err)
+	}
+}
+
+// remaining returns the part of the budget left, zero if overspent. The caller
+// must hold the lock.
+func (s *txSponsor) remaining() *big.Int {
+	remaining := new(big.Int).Sub(s.budget, s.spent)
+	if remaining.Sign() < 0 {
+		remaining.SetUint64(0)
+	}
+	return remaining
+}
+
+// updateGauges reports the spent and remaining budget to the metrics system. The
+// caller must hold the lock.
+func (s *txSponsor) updateGauges() {
+	gwei := big.NewInt(params.GWei)
+	sponsorSpentGauge.Update(new(big.Int).Div(s.spent, gwei).Int64())
+	sponsorRemainGauge.Update(new(big.Int).Div(s.remaining(), gwei).Int64())
+}
+
+// status returns the state of the sponsorship.
+func (s *txSponsor) status() SponsorStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	status := SponsorStatus{
+		Enabled:      true,
+		Budget:       (*hexutil.Big)(new(big.Int).Set(s.budget)),
+		Spent:        (*hexutil.Big)(new(big.Int).Set(s.spent)),
+		Remaining:    (*hexutil.Big)(s.remaining()),
+		MaxGas:       hexutil.Uint64(s.maxGas),
+		Transactions: hexutil.Uint64(s.txs),
+		Gas:          hexutil.Uint64(s.gas),
+	}
+	for addr := range s.accounts {
+		status.Accounts = append(status.Accounts, addr)
+	}
+	sort.Slice(status.Accounts, func(i, j int) bool {
+		return bytes.Compare(status.Accounts[i][:], status.Accounts[j][:]) < 0
+	})
+	return status
+}
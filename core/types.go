@@ -17,6 +17,7 @@
 package core
 
 import (
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -40,6 +41,16 @@ type Prefetcher interface {
 	// the transaction messages using the statedb, but any changes are discarded. The
 	// only goal is to pre-cache transaction signatures and state trie nodes.
 	Prefetch(block *types.Block, statedb *state.StateDB, cfg vm.Config, interrupt *uint32)
+
+	// PrefetchTransactions speculatively runs the transactions expected in a block
+	// with the given header using the statedb, but any changes are discarded. The
+	// only goal is to warm up the state trie nodes they touch, which are returned
+	// as the access lists of the transactions run.
+	PrefetchTransactions(header *types.Header, txs []*types.Transaction, statedb *state.StateDB, cfg vm.Config, interrupt *uint32) map[common.Hash]types.AccessList
+
+	// PrefetchAccessLists reads the state in the given access lists from the
+	// statedb, warming up the state trie nodes of transactions run earlier.
+	PrefetchAccessLists(lists []types.AccessList, statedb *state.StateDB, interrupt *uint32) int
 }
 
 // Processor is an interface for processing blocks using a given initial state.
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"container/heap"
 	"errors"
+	"fmt"
 	"io"
 	"math/big"
 	"sync/atomic"
@@ -52,6 +53,8 @@ type Transaction struct {
 	inner TxData    // Consensus contents of a transaction
 	time  time.Time // Time first seen locally (spam avoidance)
 
+	conditional *TransactionConditional // Inclusion preconditions, local only
+
 	// caches
 	hash atomic.Value
 	size atomic.Value
@@ -396,6 +399,19 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	return &Transaction{inner: cpy, time: tx.time}, nil
 }
 
+// Conditional returns the inclusion preconditions of the transaction, or nil if
+// it may be included unconditionally. Preconditions are local to this node and
+// not propagated to peers.
+func (tx *Transaction) Conditional() *TransactionConditional {
+	return tx.conditional
+}
+
+// SetConditional attaches inclusion preconditions to the transaction. It must
+// be called before the transaction is shared, e.g. submitted to the pool.
+func (tx *Transaction) SetConditional(cond *TransactionConditional) {
+	tx.conditional = cond
+}
+
 // Transactions implements DerivableList for transactions.
 type Transactions []*Transaction
 
@@ -489,12 +505,44 @@ func (s *TxByPriceAndTime) Pop() interface{} {
 	return x
 }
 
+// TxByPriceNonceAndHash implements the sort interface, ordering transactions
+// by price, then nonce, then hash. Unlike TxByPriceAndTime, the order doesn't
+// depend on when the transactions were seen, only on their content.
+type TxByPriceNonceAndHash []*TxWithMinerFee
+
+func (s TxByPriceNonceAndHash) Len() int { return len(s) }
+func (s TxByPriceNonceAndHash) Less(i, j int) bool {
+	if cmp := s[i].minerFee.Cmp(s[j].minerFee); cmp != 0 {
+		return cmp > 0
+	}
+	if ni, nj := s[i].tx.Nonce(), s[j].tx.Nonce(); ni != nj {
+		return ni < nj
+	}
+	hi, hj := s[i].tx.Hash(), s[j].tx.Hash()
+	return bytes.Compare(hi[:], hj[:]) < 0
+}
+func (s TxByPriceNonceAndHash) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// txHeads is the price heap of the next transactions of each account, breaking
+// price ties either by arrival time or deterministically by nonce and hash.
+type txHeads struct {
+	TxByPriceAndTime
+	deterministic bool
+}
+
+func (h txHeads) Less(i, j int) bool {
+	if h.deterministic {
+		return TxByPriceNonceAndHash(h.TxByPriceAndTime).Less(i, j)
+	}
+	return h.TxByPriceAndTime.Less(i, j)
+}
+
 // TransactionsByPriceAndNonce represents a set of transactions that can return
 // transactions in a profit-maximizing sorted order, while supporting removing
 // entire batches of transactions for non-executable accounts.
 type TransactionsByPriceAndNonce struct {
 	txs     map[common.Address]Transactions // Per account nonce-sorted list of transactions
-	heads   TxByPriceAndTime                // Next transaction for each unique account (price heap)
+	heads   txHeads                         // Next transaction for each unique account (price heap)
 	signer  Signer                          // Signer for the set of transactions
 	baseFee *big.Int                        // Current base fee
 }
@@ -505,8 +553,25 @@ type TransactionsByPriceAndNonce struct {
 // Note, the input map is reowned so the caller should not interact any more with
 // if after providing it to the constructor.
 func NewTransactionsByPriceAndNonce(signer Signer, txs map[common.Address]Transactions, baseFee *big.Int) *TransactionsByPriceAndNonce {
+	return newTransactionsByPriceAndNonce(signer, txs, baseFee, false)
+}
+
+// NewTransactionsByPriceNonceAndHash creates a transaction set that can retrieve
+// price sorted transactions in a nonce-honouring way, breaking price ties by
+// nonce and hash. The order is fully determined by the given transactions.
+//
+// Note, the input map is reowned so the caller should not interact any more with
+// if after providing it to the constructor.
+func NewTransactionsByPriceNonceAndHash(signer Signer, txs map[common.Address]Transactions, baseFee *big.Int) *TransactionsByPriceAndNonce {
+	return newTransactionsByPriceAndNonce(signer, txs, baseFee, true)
+}
+
+func newTransactionsByPriceAndNonce(signer Signer, txs map[common.Address]Transactions, baseFee *big.Int, deterministic bool) *TransactionsByPriceAndNonce {
 	// Initialize a price and received time based heap with the head transactions
-	heads := make(TxByPriceAndTime, 0, len(txs))
+	heads := txHeads{
+		TxByPriceAndTime: make(TxByPriceAndTime, 0, len(txs)),
+		deterministic:    deterministic,
+	}
 	for from, accTxs := range txs {
 		acc, _ := Sender(signer, accTxs[0])
 		wrapped, err := NewTxWithMinerFee(accTxs[0], baseFee)
@@ -515,7 +580,7 @@ func NewTransactionsByPriceAndNonce(signer Signer, txs map[common.Address]Transa
 			delete(txs, from)
 			continue
 		}
-		heads = append(heads, wrapped)
+		heads.TxByPriceAndTime = append(heads.TxByPriceAndTime, wrapped)
 		txs[from] = accTxs[1:]
 	}
 	heap.Init(&heads)
@@ -531,18 +596,18 @@ func NewTransactionsByPriceAndNonce(signer Signer, txs map[common.Address]Transa
 
 // Peek returns the next transaction by price.
 func (t *TransactionsByPriceAndNonce) Peek() *Transaction {
-	if len(t.heads) == 0 {
+	if t.heads.Len() == 0 {
 		return nil
 	}
-	return t.heads[0].tx
+	return t.heads.TxByPriceAndTime[0].tx
 }
 
 // Shift replaces the current best head with the next one from the same account.
 func (t *TransactionsByPriceAndNonce) Shift() {
-	acc, _ := Sender(t.signer, t.heads[0].tx)
+	acc, _ := Sender(t.signer, t.heads.TxByPriceAndTime[0].tx)
 	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
 		if wrapped, err := NewTxWithMinerFee(txs[0], t.baseFee); err == nil {
-			t.heads[0], t.txs[acc] = wrapped, txs[1:]
+			t.heads.TxByPriceAndTime[0], t.txs[acc] = wrapped, txs[1:]
 			heap.Fix(&t.heads, 0)
 			return
 		}
@@ -557,6 +622,41 @@ func (t *TransactionsByPriceAndNonce) Pop() {
 	heap.Pop(&t.heads)
 }
 
+// VerifyPriceNonceAndHashOrder checks that the transactions of a block could have
+// been picked from some pending set in the order of a set created by
+// NewTransactionsByPriceNonceAndHash: no transaction may follow another one which
+// it outranks while it was already the next transaction of its account. The
+// index of the first out of order transaction is returned along with the error.
+func VerifyPriceNonceAndHashOrder(signer Signer, txs Transactions, baseFee *big.Int) (int, error) {
+	var (
+		wrapped = make(TxByPriceNonceAndHash, len(txs))
+		prev    = make([]int, len(txs)) // Index of the previous transaction of the same account
+		last    = make(map[common.Address]int)
+	)
+	for i, tx := range txs {
+		from, err := Sender(signer, tx)
+		if err != nil {
+			return i, err
+		}
+		if wrapped[i], err = NewTxWithMinerFee(tx, baseFee); err != nil {
+			return i, err
+		}
+		prev[i] = -1
+		if p, ok := last[from]; ok {
+			prev[i] = p
+		}
+		last[from] = i
+	}
+	for j := range txs {
+		for i := prev[j] + 1; i < j; i++ {
+			if wrapped.Less(j, i) {
+				return i, fmt.Errorf("transaction %d (%x) outranks preceding transaction %d (%x)", j, txs[j].Hash(), i, txs[i].Hash())
+			}
+		}
+	}
+	return len(txs), nil
+}
+
 // Message is a fully derived transaction and implements core.Message
 //
 // NOTE: In a future PR this will be removed.
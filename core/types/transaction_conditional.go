@@ -0,0 +1,125 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// MaxConditionalCost is the maximum number of storage roots and slots a
+// transaction conditional may check.
+const MaxConditionalCost = 1000
+
+// KnownAccount is the expected storage of an account, either its whole storage
+// root or the values of individual slots.
+type KnownAccount struct {
+	StorageRoot  *common.Hash
+	StorageSlots map[common.Hash]common.Hash
+}
+
+// UnmarshalJSON decodes either a storage root hash or a slot to value mapping.
+func (ka *KnownAccount) UnmarshalJSON(input []byte) error {
+	var root common.Hash
+	if err := json.Unmarshal(input, &root); err == nil {
+		ka.StorageRoot, ka.StorageSlots = &root, nil
+		return nil
+	}
+	var slots map[common.Hash]common.Hash
+	if err := json.Unmarshal(input, &slots); err != nil {
+		return errors.New("known account must be a storage root or a slot mapping")
+	}
+	ka.StorageRoot, ka.StorageSlots = nil, slots
+	return nil
+}
+
+// MarshalJSON encodes the known account as its storage root if set, or as the
+// slot to value mapping otherwise.
+func (ka KnownAccount) MarshalJSON() ([]byte, error) {
+	if ka.StorageRoot != nil {
+		return json.Marshal(ka.StorageRoot)
+	}
+	return json.Marshal(ka.StorageSlots)
+}
+
+// TransactionConditional holds the preconditions a transaction submitted with
+// eth_sendRawTransactionConditional must satisfy to be included in a block.
+type TransactionConditional struct {
+	KnownAccounts  map[common.Address]KnownAccount `json:"knownAccounts"`
+	BlockNumberMin *hexutil.Big                    `json:"blockNumberMin,omitempty"`
+	BlockNumberMax *hexutil.Big                    `json:"blockNumberMax,omitempty"`
+	TimestampMin   *hexutil.Uint64                 `json:"timestampMin,omitempty"`
+	TimestampMax   *hexutil.Uint64                 `json:"timestampMax,omitempty"`
+}
+
+// Cost returns the number of storage roots and slots checked by the conditional.
+func (c *TransactionConditional) Cost() int {
+	cost := 0
+	for _, account := range c.KnownAccounts {
+		if account.StorageRoot != nil {
+			cost++
+		}
+		cost += len(account.StorageSlots)
+	}
+	return cost
+}
+
+// Validate checks the conditional for internal consistency.
+func (c *TransactionConditional) Validate() error {
+	if cost := c.Cost(); cost > MaxConditionalCost {
+		return fmt.Errorf("conditional cost %d exceeds maximum %d", cost, MaxConditionalCost)
+	}
+	if c.BlockNumberMin != nil && c.BlockNumberMax != nil && c.BlockNumberMin.ToInt().Cmp(c.BlockNumberMax.ToInt()) > 0 {
+		return errors.New("block number range is empty")
+	}
+	if c.TimestampMin != nil && c.TimestampMax != nil && *c.TimestampMin > *c.TimestampMax {
+		return errors.New("timestamp range is empty")
+	}
+	return nil
+}
+
+// CheckBlock returns an error if a block with the given number and timestamp
+// is outside the bounds of the conditional.
+func (c *TransactionConditional) CheckBlock(number *big.Int, time uint64) error {
+	if c.BlockNumberMin != nil && number.Cmp(c.BlockNumberMin.ToInt()) < 0 {
+		return fmt.Errorf("block number %v before minimum %v", number, c.BlockNumberMin.ToInt())
+	}
+	if c.BlockNumberMax != nil && number.Cmp(c.BlockNumberMax.ToInt()) > 0 {
+		return fmt.Errorf("block number %v after maximum %v", number, c.BlockNumberMax.ToInt())
+	}
+	if c.TimestampMin != nil && time < uint64(*c.TimestampMin) {
+		return fmt.Errorf("timestamp %d before minimum %d", time, *c.TimestampMin)
+	}
+	if c.TimestampMax != nil && time > uint64(*c.TimestampMax) {
+		return fmt.Errorf("timestamp %d after maximum %d", time, *c.TimestampMax)
+	}
+	return nil
+}
+
+// Expired returns whether no block after the given one can satisfy the block
+// number and timestamp bounds of the conditional anymore.
+func (c *TransactionConditional) Expired(number *big.Int, time uint64) bool {
+	if c.BlockNumberMax != nil && number.Cmp(c.BlockNumberMax.ToInt()) >= 0 {
+		return true
+	}
+	return c.TimestampMax != nil && time >= uint64(*c.TimestampMax)
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestTransactionConditionalJSON(t *testing.T) {
+	input := `{
+		"knownAccounts": {
+			"0x0000000000000000000000000000000000000001": "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421",
+			"0x0000000000000000000000000000000000000002": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000002"
+			}
+		},
+		"blockNumberMin": "0x10",
+		"blockNumberMax": "0x14",
+		"timestampMax": "0x64"
+	}`
+	var cond TransactionConditional
+	if err := json.Unmarshal([]byte(input), &cond); err != nil {
+		t.Fatalf("failed to decode conditional: %v", err)
+	}
+	if root := cond.KnownAccounts[common.Address{19: 1}].StorageRoot; root == nil || *root != EmptyRootHash {
+		t.Fatalf("storage root mismatch: %v", root)
+	}
+	if slots := cond.KnownAccounts[common.Address{19: 2}].StorageSlots; slots[common.Hash{31: 1}] != (common.Hash{31: 2}) {
+		t.Fatalf("storage slots mismatch: %v", slots)
+	}
+	if cost := cond.Cost(); cost != 2 {
+		t.Fatalf("cost mismatch: have %d, want 2", cost)
+	}
+	if err := cond.Validate(); err != nil {
+		t.Fatalf("valid conditional rejected: %v", err)
+	}
+	// Round trip through the encoder
+	blob, err := json.Marshal(&cond)
+	if err != nil {
+		t.Fatalf("failed to encode conditional: %v", err)
+	}
+	var dec TransactionConditional
+	if err := json.Unmarshal(blob, &dec); err != nil || dec.Cost() != 2 {
+		t.Fatalf("round trip mismatch: %s, %v", blob, err)
+	}
+	// Check the block bounds
+	for _, tt := range []struct {
+		number  int64
+		time    uint64
+		ok      bool
+		expired bool
+	}{
+		{15, 50, false, false},
+		{16, 50, true, false},
+		{20, 100, true, true},
+		{21, 50, false, true},
+		{18, 101, false, true},
+	} {
+		if err := cond.CheckBlock(big.NewInt(tt.number), tt.time); (err == nil) != tt.ok {
+			t.Errorf("block %d at %d: check mismatch: %v", tt.number, tt.time, err)
+		}
+		if expired := cond.Expired(big.NewInt(tt.number), tt.time); expired != tt.expired {
+			t.Errorf("block %d at %d: expiry mismatch: have %v, want %v", tt.number, tt.time, expired, tt.expired)
+		}
+	}
+	// Empty ranges are rejected
+	cond.BlockNumberMin, cond.BlockNumberMax = cond.BlockNumberMax, cond.BlockNumberMin
+	if err := cond.Validate(); err == nil {
+		t.Fatalf("empty block range accepted")
+	}
+}
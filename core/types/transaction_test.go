@@ -405,6 +405,74 @@ func TestTransactionTimeSort(t *testing.T) {
 	}
 }
 
+// Tests that the deterministic ordering ignores when transactions were seen, and
+// that the orders it produces verify while reorderings don't.
+func TestTransactionDeterministicSort(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 10)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	signer := HomesteadSigner{}
+
+	// Generate transactions with few distinct prices, seen in random order
+	sorted := func() Transactions {
+		groups := map[common.Address]Transactions{}
+		for i, key := range keys {
+			addr := crypto.PubkeyToAddress(key.PublicKey)
+			for nonce := uint64(0); nonce < 3; nonce++ {
+				tx, _ := SignTx(NewTransaction(nonce, common.Address{}, big.NewInt(100), 100, big.NewInt(int64(1+(i+int(nonce))%3)), nil), signer, key)
+				tx.time = time.Unix(0, rand.Int63())
+				groups[addr] = append(groups[addr], tx)
+			}
+		}
+		txset := NewTransactionsByPriceNonceAndHash(signer, groups, nil)
+
+		txs := Transactions{}
+		for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
+			txs = append(txs, tx)
+			txset.Shift()
+		}
+		return txs
+	}
+	txs := sorted()
+	if len(txs) != 3*len(keys) {
+		t.Fatalf("expected %d transactions, found %d", 3*len(keys), len(txs))
+	}
+	for i := 0; i < 5; i++ {
+		if other := sorted(); !reflect.DeepEqual(hashes(other), hashes(txs)) {
+			t.Fatalf("ordering depends on arrival time")
+		}
+	}
+	if n, err := VerifyPriceNonceAndHashOrder(signer, txs, nil); err != nil {
+		t.Fatalf("deterministic order rejected at %d: %v", n, err)
+	}
+	// Skipping transactions keeps the order valid, swapping accounts doesn't
+	if n, err := VerifyPriceNonceAndHashOrder(signer, txs[3:], nil); err != nil {
+		t.Fatalf("suffix rejected at %d: %v", n, err)
+	}
+	for i := 0; i+1 < len(txs); i++ {
+		from, _ := Sender(signer, txs[i])
+		next, _ := Sender(signer, txs[i+1])
+		if from == next {
+			continue
+		}
+		swapped := append(Transactions{}, txs...)
+		swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+		if n, err := VerifyPriceNonceAndHashOrder(signer, swapped, nil); err == nil || n != i {
+			t.Fatalf("swap at %d: have violation at %d (%v)", i, n, err)
+		}
+		break
+	}
+}
+
+func hashes(txs Transactions) []common.Hash {
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	return hashes
+}
+
 // TestTransactionCoding tests serializing/de-serializing to/from rlp and JSON.
 func TestTransactionCoding(t *testing.T) {
 	key, err := crypto.GenerateKey()
@@ -130,16 +130,28 @@ func init() {
 
 // ActivePrecompiles returns the precompiles enabled with the current configuration.
 func ActivePrecompiles(rules params.Rules) []common.Address {
+	var addrs []common.Address
 	switch {
 	case rules.IsBerlin:
-		return PrecompiledAddressesBerlin
+		addrs = PrecompiledAddressesBerlin
 	case rules.IsIstanbul:
-		return PrecompiledAddressesIstanbul
+		addrs = PrecompiledAddressesIstanbul
 	case rules.IsByzantium:
-		return PrecompiledAddressesByzantium
+		addrs = PrecompiledAddressesByzantium
 	default:
-		return PrecompiledAddressesHomestead
+		addrs = PrecompiledAddressesHomestead
 	}
+	if len(rules.PrecompileExtensions) == 0 {
+		return addrs
+	}
+	extended := make([]common.Address, len(addrs), len(addrs)+len(rules.PrecompileExtensions))
+	copy(extended, addrs)
+	for _, name := range rules.PrecompileExtensions {
+		if ext, ok := LookupPrecompileExtension(name); ok {
+			extended = append(extended, ext.Address)
+		}
+	}
+	return extended
 }
 
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
@@ -53,7 +53,10 @@ func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
 	default:
 		precompiles = PrecompiledContractsHomestead
 	}
-	p, ok := precompiles[addr]
+	if p, ok := precompiles[addr]; ok {
+		return p, true
+	}
+	p, ok := evm.extensions[addr]
 	return p, ok
 }
 
@@ -108,6 +111,8 @@ type EVM struct {
 	chainConfig *params.ChainConfig
 	// chain rules contains the chain rules for the current epoch
 	chainRules params.Rules
+	// extensions holds the precompile extensions activated by the chain rules
+	extensions map[common.Address]PrecompiledContract
 	// virtual machine configuration options used to initialise the
 	// evm.
 	Config Config
@@ -134,6 +139,7 @@ func NewEVM(blockCtx BlockContext, txCtx TxContext, statedb StateDB, chainConfig
 		chainConfig: chainConfig,
 		chainRules:  chainConfig.Rules(blockCtx.BlockNumber, blockCtx.Random != nil),
 	}
+	evm.extensions = activeExtensions(evm.chainRules)
 	evm.interpreter = NewEVMInterpreter(evm, config)
 	return evm
 }
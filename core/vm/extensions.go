@@ -0,0 +1,173 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// PrecompileExtension is an additional precompiled contract which chains can
+// activate at a fork block of their choosing via the PrecompileExtensions field
+// of the chain config.
+type PrecompileExtension struct {
+	Name     string
+	Address  common.Address
+	Contract PrecompiledContract
+}
+
+var (
+	extensions     = make(map[string]PrecompileExtension)
+	extensionsLock sync.RWMutex
+)
+
+func init() {
+	mustRegister := func(name string, addr common.Address, contract PrecompiledContract) {
+		if err := RegisterPrecompileExtension(name, addr, contract); err != nil {
+			panic(err)
+		}
+	}
+	// EIP-2537 BLS12-381 curve operations
+	mustRegister("bls12381G1Add", common.BytesToAddress([]byte{10}), &bls12381G1Add{})
+	mustRegister("bls12381G1Mul", common.BytesToAddress([]byte{11}), &bls12381G1Mul{})
+	mustRegister("bls12381G1MultiExp", common.BytesToAddress([]byte{12}), &bls12381G1MultiExp{})
+	mustRegister("bls12381G2Add", common.BytesToAddress([]byte{13}), &bls12381G2Add{})
+	mustRegister("bls12381G2Mul", common.BytesToAddress([]byte{14}), &bls12381G2Mul{})
+	mustRegister("bls12381G2MultiExp", common.BytesToAddress([]byte{15}), &bls12381G2MultiExp{})
+	mustRegister("bls12381Pairing", common.BytesToAddress([]byte{16}), &bls12381Pairing{})
+	mustRegister("bls12381MapG1", common.BytesToAddress([]byte{17}), &bls12381MapG1{})
+	mustRegister("bls12381MapG2", common.BytesToAddress([]byte{18}), &bls12381MapG2{})
+
+	// RIP-7212 secp256r1 signature verification
+	mustRegister("p256Verify", common.BytesToAddress([]byte{0x01, 0x00}), &p256Verify{})
+}
+
+// RegisterPrecompileExtension adds a precompiled contract to the extension
+// registry under the given name. Embedders must register their extensions
+// before any chain activating them is loaded. The name and the address must
+// not be used by another extension, nor the address by a built-in precompile.
+func RegisterPrecompileExtension(name string, addr common.Address, contract PrecompiledContract) error {
+	extensionsLock.Lock()
+	defer extensionsLock.Unlock()
+
+	if _, ok := extensions[name]; ok {
+		return fmt.Errorf("precompile extension %q already registered", name)
+	}
+	if _, ok := PrecompiledContractsBerlin[addr]; ok {
+		return fmt.Errorf("precompile extension %q address %v taken by built-in precompile", name, addr)
+	}
+	for _, ext := range extensions {
+		if ext.Address == addr {
+			return fmt.Errorf("precompile extension %q address %v taken by %q", name, addr, ext.Name)
+		}
+	}
+	extensions[name] = PrecompileExtension{Name: name, Address: addr, Contract: contract}
+	return nil
+}
+
+// PrecompileExtensions returns all registered precompile extensions, ordered by
+// name.
+func PrecompileExtensions() []PrecompileExtension {
+	extensionsLock.RLock()
+	defer extensionsLock.RUnlock()
+
+	list := make([]PrecompileExtension, 0, len(extensions))
+	for _, ext := range extensions {
+		list = append(list, ext)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// LookupPrecompileExtension retrieves a registered precompile extension.
+func LookupPrecompileExtension(name string) (PrecompileExtension, bool) {
+	extensionsLock.RLock()
+	defer extensionsLock.RUnlock()
+
+	ext, ok := extensions[name]
+	return ext, ok
+}
+
+// ValidatePrecompileExtensions checks that all precompile extensions activated
+// by the chain config are registered.
+func ValidatePrecompileExtensions(config *params.ChainConfig) error {
+	for name := range config.PrecompileExtensions {
+		if _, ok := LookupPrecompileExtension(name); !ok {
+			return fmt.Errorf("unknown precompile extension %q", name)
+		}
+	}
+	return nil
+}
+
+// activeExtensions returns the precompile extensions enabled by the rules, keyed
+// by address, or nil if there are none.
+func activeExtensions(rules params.Rules) map[common.Address]PrecompiledContract {
+	if len(rules.PrecompileExtensions) == 0 {
+		return nil
+	}
+	active := make(map[common.Address]PrecompiledContract, len(rules.PrecompileExtensions))
+	for _, name := range rules.PrecompileExtensions {
+		if ext, ok := LookupPrecompileExtension(name); ok {
+			active[ext.Address] = ext.Contract
+		}
+	}
+	return active
+}
+
+// p256Verify implements secp256r1 signature verification as specified in
+// RIP-7212. The input is the 32 byte message hash, the r and s signature values
+// and the x and y public key coordinates, 160 bytes in total. A valid signature
+// returns 1 as a 32 byte word, anything else returns empty output.
+type p256Verify struct{}
+
+// p256VerifyGas is the gas cost of a secp256r1 signature verification.
+const p256VerifyGas uint64 = 3450
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *p256Verify) RequiredGas(input []byte) uint64 {
+	return p256VerifyGas
+}
+
+func (c *p256Verify) Run(input []byte) ([]byte, error) {
+	const p256VerifyInputLength = 160
+	if len(input) != p256VerifyInputLength {
+		return nil, nil
+	}
+	var (
+		hash  = input[:32]
+		r     = new(big.Int).SetBytes(input[32:64])
+		s     = new(big.Int).SetBytes(input[64:96])
+		x     = new(big.Int).SetBytes(input[96:128])
+		y     = new(big.Int).SetBytes(input[128:160])
+		curve = elliptic.P256()
+	)
+	if !curve.IsOnCurve(x, y) {
+		return nil, nil
+	}
+	pub := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	if !ecdsa.Verify(pub, hash, r, s) {
+		return nil, nil
+	}
+	return common.LeftPadBytes([]byte{1}, 32), nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestP256Verify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	hash := sha256.Sum256([]byte("passkey"))
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	input := append(hash[:], common.LeftPadBytes(r.Bytes(), 32)...)
+	input = append(input, common.LeftPadBytes(s.Bytes(), 32)...)
+	input = append(input, common.LeftPadBytes(key.X.Bytes(), 32)...)
+	input = append(input, common.LeftPadBytes(key.Y.Bytes(), 32)...)
+
+	ext, ok := LookupPrecompileExtension("p256Verify")
+	if !ok {
+		t.Fatalf("p256Verify extension not registered")
+	}
+	out, _, err := RunPrecompiledContract(ext.Contract, input, p256VerifyGas)
+	if err != nil || !bytes.Equal(out, common.LeftPadBytes([]byte{1}, 32)) {
+		t.Fatalf("valid signature rejected: %x, %v", out, err)
+	}
+	// Tamper with the hash and truncate the input
+	input[0] ^= 0xff
+	if out, _, err := RunPrecompiledContract(ext.Contract, input, p256VerifyGas); err != nil || len(out) != 0 {
+		t.Fatalf("invalid signature accepted: %x, %v", out, err)
+	}
+	if out, _, err := RunPrecompiledContract(ext.Contract, input[:159], p256VerifyGas); err != nil || len(out) != 0 {
+		t.Fatalf("short input accepted: %x, %v", out, err)
+	}
+}
+
+func TestPrecompileExtensionRegistry(t *testing.T) {
+	if err := RegisterPrecompileExtension("p256Verify", common.Address{0xff}, &p256Verify{}); err == nil {
+		t.Errorf("duplicate name registered")
+	}
+	if err := RegisterPrecompileExtension("testDuplicateAddr", common.BytesToAddress([]byte{0x01, 0x00}), &p256Verify{}); err == nil {
+		t.Errorf("duplicate address registered")
+	}
+	if err := RegisterPrecompileExtension("testBuiltinAddr", common.BytesToAddress([]byte{1}), &p256Verify{}); err == nil {
+		t.Errorf("built-in address registered")
+	}
+	config := *params.TestChainConfig
+	config.PrecompileExtensions = map[string]*big.Int{"p256Verify": big.NewInt(10)}
+	if err := ValidatePrecompileExtensions(&config); err != nil {
+		t.Fatalf("valid extensions rejected: %v", err)
+	}
+	addr := common.BytesToAddress([]byte{0x01, 0x00})
+	for _, tt := range []struct {
+		number int64
+		active bool
+	}{{9, false}, {10, true}} {
+		rules := config.Rules(big.NewInt(tt.number), false)
+		found := false
+		for _, a := range ActivePrecompiles(rules) {
+			found = found || a == addr
+		}
+		if found != tt.active {
+			t.Errorf("block %d: active precompile mismatch: have %v, want %v", tt.number, found, tt.active)
+		}
+		evm := NewEVM(BlockContext{BlockNumber: big.NewInt(tt.number)}, TxContext{}, nil, &config, Config{})
+		if _, ok := evm.precompile(addr); ok != tt.active {
+			t.Errorf("block %d: evm precompile mismatch: have %v, want %v", tt.number, ok, tt.active)
+		}
+	}
+	config.PrecompileExtensions["unknown"] = big.NewInt(0)
+	if err := ValidatePrecompileExtensions(&config); err == nil {
+		t.Fatalf("unknown extension accepted")
+	}
+}
@@ -0,0 +1,374 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alerts evaluates per-validator alert rules against the sealing record
+// of the current clique epoch and analyzes it for statistical anomalies,
+// reporting both to the log, the metrics and an optional webhook.
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Conditions an alert can be raised for.
+const (
+	ConditionMissedStreak = "missed-streak" // Consecutive in-turn slots missed
+	ConditionUptime       = "uptime"        // Share of the epoch's blocks below the minimum
+	ConditionSealAge      = "seal-age"      // Last sealed block too old
+)
+
+// Statuses of an alert notification.
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+var (
+	firedMeter      = metrics.NewRegisteredMeter("alerts/fired", nil)
+	resolvedMeter   = metrics.NewRegisteredMeter("alerts/resolved", nil)
+	firingGauge     = metrics.NewRegisteredGauge("alerts/firing", nil)
+	anomalyMeter    = metrics.NewRegisteredMeter("alerts/anomalies", nil)
+	webhookErrMeter = metrics.NewRegisteredMeter("alerts/webhook/errors", nil)
+)
+
+// Alert is a notification about a validator breaching an alert rule.
+type Alert struct {
+	Status    string         `json:"status"`
+	Rule      string         `json:"rule"`
+	Validator common.Address `json:"validator"`
+	Condition string         `json:"condition"`
+	Value     float64        `json:"value"`     // Value breaching the threshold, durations in seconds
+	Threshold float64        `json:"threshold"` // Threshold of the rule, durations in seconds
+	Epoch     uint64         `json:"epoch"`
+	Head      uint64         `json:"head"`
+	Since     time.Time      `json:"since"` // Time the alert started firing
+}
+
+// alertKey identifies an alert across evaluations.
+type alertKey struct {
+	rule      string
+	validator common.Address
+	condition string
+}
+
+// anomalyKey identifies an anomaly across analyses.
+type anomalyKey struct {
+	epoch     uint64
+	kind      string
+	validator common.Address
+	block     uint64
+}
+
+// firingAlert is an alert currently firing.
+type firingAlert struct {
+	alert    Alert
+	notified time.Time // Time the alert was last notified
+}
+
+// Monitor periodically evaluates the alert rules against the validators of the
+// current epoch.
+type Monitor struct {
+	config Config
+	health func() ([]*clique.ValidatorHealth, error)
+	client *http.Client
+
+	epoch     func() (uint64, error)                       // Current epoch, set if anomalies are analyzed
+	anomalies func(uint64) (*clique.EpochAnomalies, error) // Anomalies of an epoch, set if analyzed
+
+	firing   map[alertKey]*firingAlert // Alerts firing as of the last evaluation
+	reported map[anomalyKey]bool       // Anomalies of the current epoch already reported
+	analyzed *uint64                   // Epoch current as of the last analysis, if any
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a monitor evaluating the configured rules against the sealing
+// record of the chain's clique validators.
+func New(config Config, chain consensus.ChainHeaderReader, engine *clique.Clique) (*Monitor, error) {
+	if engine == nil {
+		return nil, errors.New("validator alerts require clique")
+	}
+	m, err := newMonitor(config, func() ([]*clique.ValidatorHealth, error) {
+		return engine.ValidatorHealth(chain)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if m.config.Anomalies {
+		m.epoch = func() (uint64, error) {
+			return engine.CurrentEpoch(chain)
+		}
+		m.anomalies = func(epoch uint64) (*clique.EpochAnomalies, error) {
+			return engine.Anomalies(chain, epoch)
+		}
+	}
+	return m, nil
+}
+
+func newMonitor(config Config, health func() ([]*clique.ValidatorHealth, error)) (*Monitor, error) {
+	conf, err := config.sanitize()
+	if err != nil {
+		return nil, err
+	}
+	return &Monitor{
+		config:   conf,
+		health:   health,
+		client:   &http.Client{Timeout: conf.Timeout},
+		firing:   make(map[alertKey]*firingAlert),
+		reported: make(map[anomalyKey]bool),
+		quit:     make(chan struct{}),
+	}, nil
+}
+
+// Start launches the evaluation loop.
+func (m *Monitor) Start() {
+	log.Info("Started validator alerting", "rules", len(m.config.Rules), "anomalies", m.anomalies != nil, "webhook", m.config.Webhook != "", "interval", m.config.Interval)
+
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Stop terminates the evaluation loop.
+func (m *Monitor) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+func (m *Monitor) loop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.evaluate(time.Now()); err != nil {
+				log.Warn("Failed to evaluate validator alerts", "err", err)
+			}
+			if m.anomalies != nil {
+				if err := m.analyze(); err != nil {
+					log.Warn("Failed to analyze epoch anomalies", "err", err)
+				}
+			}
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// evaluate checks the rules against the current sealing record, notifying the
+// alerts starting or repeating to fire and the ones resolved since the last
+// evaluation.
+func (m *Monitor) evaluate(now time.Time) error {
+	health, err := m.health()
+	if err != nil {
+		return err
+	}
+	var (
+		active = make(map[alertKey]bool)
+		notify []Alert
+	)
+	for _, rule := range m.config.Rules {
+		for _, h := range health {
+			if h.Maintenance || !rule.covers(h.Validator) {
+				continue
+			}
+			for _, alert := range rule.check(h, len(health), now) {
+				key := alertKey{rule: alert.Rule, validator: alert.Validator, condition: alert.Condition}
+				active[key] = true
+
+				firing := m.firing[key]
+				if firing == nil {
+					alert.Since = now
+					firing = &firingAlert{alert: alert, notified: now}
+					m.firing[key] = firing
+					firedMeter.Mark(1)
+				} else {
+					alert.Since = firing.alert.Since
+					firing.alert = alert
+					if m.config.Repeat == 0 || now.Sub(firing.notified) < m.config.Repeat {
+						continue
+					}
+					firing.notified = now
+				}
+				notify = append(notify, alert)
+			}
+		}
+	}
+	for key, firing := range m.firing {
+		if active[key] {
+			continue
+		}
+		delete(m.firing, key)
+		resolvedMeter.Mark(1)
+
+		alert := firing.alert
+		alert.Status = StatusResolved
+		notify = append(notify, alert)
+	}
+	firingGauge.Update(int64(len(m.firing)))
+
+	for _, alert := range notify {
+		if alert.Status == StatusFiring {
+			log.Warn("Validator alert firing", "rule", alert.Rule, "validator", alert.Validator, "condition", alert.Condition, "value", alert.Value, "threshold", alert.Threshold, "since", alert.Since)
+		} else {
+			log.Info("Validator alert resolved", "rule", alert.Rule, "validator", alert.Validator, "condition", alert.Condition)
+		}
+	}
+	if len(notify) > 0 && m.config.Webhook != "" {
+		if err := m.post(map[string]interface{}{"alerts": notify}); err != nil {
+			webhookErrMeter.Mark(1)
+			log.Warn("Failed to deliver validator alerts", "webhook", m.config.Webhook, "alerts", len(notify), "err", err)
+		}
+	}
+	return nil
+}
+
+// analyze checks the current epoch for anomalies, notifying the ones not yet
+// reported. The epoch closed since the last analysis is checked once more, to
+// cover the blocks sealed after the previous run.
+func (m *Monitor) analyze() error {
+	current, err := m.epoch()
+	if err != nil {
+		return err
+	}
+	epochs := []uint64{current}
+	if m.analyzed != nil && *m.analyzed < current {
+		epochs = []uint64{*m.analyzed, current}
+	}
+	var notify []*clique.Anomaly
+	for _, epoch := range epochs {
+		result, err := m.anomalies(epoch)
+		if err != nil {
+			return err
+		}
+		for _, anomaly := range result.Anomalies {
+			key := anomalyKey{epoch: anomaly.Epoch, kind: anomaly.Kind}
+			if anomaly.Validator != nil {
+				key.validator = *anomaly.Validator
+			}
+			if anomaly.Block != nil {
+				key.block = *anomaly.Block
+			}
+			if m.reported[key] {
+				continue
+			}
+			m.reported[key] = true
+			notify = append(notify, anomaly)
+		}
+	}
+	// Forget the anomalies of the closed epochs, they won't be analyzed again
+	for key := range m.reported {
+		if key.epoch < current {
+			delete(m.reported, key)
+		}
+	}
+	m.analyzed = &current
+
+	anomalyMeter.Mark(int64(len(notify)))
+	for _, anomaly := range notify {
+		log.Warn("Epoch anomaly detected", "epoch", anomaly.Epoch, "kind", anomaly.Kind, "validator", anomaly.Validator, "block", anomaly.Block, "value", anomaly.Value, "threshold", anomaly.Threshold)
+	}
+	if len(notify) > 0 && m.config.Webhook != "" {
+		if err := m.post(map[string]interface{}{"anomalies": notify}); err != nil {
+			webhookErrMeter.Mark(1)
+			log.Warn("Failed to deliver epoch anomalies", "webhook", m.config.Webhook, "anomalies", len(notify), "err", err)
+		}
+	}
+	return nil
+}
+
+// post delivers a batch of alerts or anomalies to the webhook.
+func (m *Monitor) post(payload map[string]interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	res, err := m.client.Post(m.config.Webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		blob, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("webhook returned %s: %s", res.Status, strings.TrimSpace(string(blob)))
+	}
+	return nil
+}
+
+// covers returns whether the rule applies to a validator.
+func (rule *Rule) covers(validator common.Address) bool {
+	if len(rule.Validators) == 0 {
+		return true
+	}
+	for _, v := range rule.Validators {
+		if v == validator {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns the firing alerts of a validator against the rule. The uptime
+// is only checked once every validator had a slot in the epoch.
+func (rule *Rule) check(h *clique.ValidatorHealth, validators int, now time.Time) []Alert {
+	var alerts []Alert
+	fire := func(condition string, value, threshold float64) {
+		alerts = append(alerts, Alert{
+			Status:    StatusFiring,
+			Rule:      rule.Name,
+			Validator: h.Validator,
+			Condition: condition,
+			Value:     value,
+			Threshold: threshold,
+			Epoch:     h.Epoch,
+			Head:      h.Head,
+		})
+	}
+	if rule.MissedStreak > 0 && h.MissedStreak >= rule.MissedStreak {
+		fire(ConditionMissedStreak, float64(h.MissedStreak), float64(rule.MissedStreak))
+	}
+	if rule.MinUptime > 0 && h.Head-h.EpochBlock >= uint64(validators) && h.Uptime < rule.MinUptime {
+		fire(ConditionUptime, h.Uptime, rule.MinUptime)
+	}
+	if rule.MaxSealAge > 0 {
+		last := h.EpochTime
+		if h.LastSealedTime != nil {
+			last = *h.LastSealedTime
+		}
+		if age := now.Sub(time.Unix(int64(last), 0)); age > rule.MaxSealAge {
+			fire(ConditionSealAge, age.Seconds(), rule.MaxSealAge.Seconds())
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,198 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+)
+
+// Tests that alerts fire once breaching a rule, repeat after the configured
+// interval and resolve once back within the thresholds.
+func TestMonitorAlerts(t *testing.T) {
+	var (
+		good = common.HexToAddress("0x01")
+		bad  = common.HexToAddress("0x02")
+		base = time.Unix(1650000000, 0)
+
+		health   []*clique.ValidatorHealth
+		received [][]Alert
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch struct {
+			Alerts []Alert `json:"alerts"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("invalid webhook payload: %v", err)
+		}
+		received = append(received, batch.Alerts)
+	}))
+	defer server.Close()
+
+	monitor, err := newMonitor(Config{
+		Rules: []Rule{{
+			Name:         "core",
+			MissedStreak: 3,
+			MinUptime:    80,
+			MaxSealAge:   time.Minute,
+		}},
+		Webhook: server.URL,
+		Repeat:  time.Hour,
+	}, func() ([]*clique.ValidatorHealth, error) { return health, nil })
+	if err != nil {
+		t.Fatalf("failed to create monitor: %v", err)
+	}
+	sealed := uint64(base.Unix())
+	health = []*clique.ValidatorHealth{
+		{Validator: good, EpochBlock: 100, Head: 120, Uptime: 100, LastSealed: new(uint64), LastSealedTime: &sealed},
+		{Validator: bad, EpochBlock: 100, Head: 120, Uptime: 40, MissedStreak: 4, EpochTime: sealed - 600},
+	}
+	// The bad validator breaches every condition of the rule
+	if err := monitor.evaluate(base); err != nil {
+		t.Fatalf("failed to evaluate: %v", err)
+	}
+	if len(received) != 1 || len(received[0]) != 3 {
+		t.Fatalf("firing notifications mismatch: have %v", received)
+	}
+	for _, alert := range received[0] {
+		if alert.Validator != bad || alert.Status != StatusFiring || alert.Rule != "core" {
+			t.Errorf("unexpected alert: %+v", alert)
+		}
+	}
+	// Alerts still firing are only notified again after the repeat interval
+	if err := monitor.evaluate(base.Add(time.Minute)); err != nil {
+		t.Fatalf("failed to evaluate: %v", err)
+	}
+	if len(received) != 1 {
+		t.Fatalf("alerts repeated early: have %v", received[1:])
+	}
+	health[1].Uptime, health[1].MissedStreak = 90, 0
+	health[1].LastSealedTime = &sealed
+
+	// Resolved conditions are notified once, the rest keeps firing. The good
+	// validator's seal is too old by now too.
+	if err := monitor.evaluate(base.Add(2 * time.Hour)); err != nil {
+		t.Fatalf("failed to evaluate: %v", err)
+	}
+	if len(received) != 2 {
+		t.Fatalf("notification count mismatch: have %d, want 2", len(received))
+	}
+	statuses := make(map[string]int)
+	for _, alert := range received[1] {
+		statuses[alert.Status+"/"+alert.Condition]++
+	}
+	want := map[string]int{
+		StatusResolved + "/" + ConditionMissedStreak: 1,
+		StatusResolved + "/" + ConditionUptime:       1,
+		StatusFiring + "/" + ConditionSealAge:        2,
+	}
+	for key, count := range want {
+		if statuses[key] != count {
+			t.Errorf("notification %s count mismatch: have %d, want %d", key, statuses[key], count)
+		}
+	}
+}
+
+// Tests that validators in maintenance or not covered by a rule are skipped.
+func TestMonitorSkips(t *testing.T) {
+	var (
+		listed   = common.HexToAddress("0x01")
+		unlisted = common.HexToAddress("0x02")
+	)
+	health := []*clique.ValidatorHealth{
+		{Validator: listed, EpochBlock: 100, Head: 120, MissedStreak: 5, Maintenance: true},
+		{Validator: unlisted, EpochBlock: 100, Head: 120, MissedStreak: 5},
+	}
+	monitor, err := newMonitor(Config{
+		Rules: []Rule{{Validators: []common.Address{listed}, MissedStreak: 1}},
+	}, func() ([]*clique.ValidatorHealth, error) { return health, nil })
+	if err != nil {
+		t.Fatalf("failed to create monitor: %v", err)
+	}
+	if err := monitor.evaluate(time.Now()); err != nil {
+		t.Fatalf("failed to evaluate: %v", err)
+	}
+	if len(monitor.firing) != 0 {
+		t.Errorf("skipped validators fired alerts: %v", monitor.firing)
+	}
+	if _, err := newMonitor(Config{Rules: []Rule{{Name: "empty"}}}, nil); err == nil {
+		t.Errorf("rule without thresholds accepted")
+	}
+}
+
+// Tests that epoch anomalies are reported once, including the ones found in the
+// last blocks of an epoch closed since the previous analysis.
+func TestMonitorAnomalies(t *testing.T) {
+	var (
+		validator = common.HexToAddress("0x01")
+		block     = uint64(150)
+
+		current  = uint64(3)
+		results  = make(map[uint64][]*clique.Anomaly)
+		received [][]*clique.Anomaly
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch struct {
+			Anomalies []*clique.Anomaly `json:"anomalies"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("invalid webhook payload: %v", err)
+		}
+		received = append(received, batch.Anomalies)
+	}))
+	defer server.Close()
+
+	monitor, err := newMonitor(Config{Anomalies: true, Webhook: server.URL}, nil)
+	if err != nil {
+		t.Fatalf("failed to create monitor: %v", err)
+	}
+	monitor.epoch = func() (uint64, error) { return current, nil }
+	monitor.anomalies = func(epoch uint64) (*clique.EpochAnomalies, error) {
+		return &clique.EpochAnomalies{Epoch: epoch, Anomalies: results[epoch]}, nil
+	}
+	share := &clique.Anomaly{Kind: clique.AnomalySealShare, Epoch: 3, Validator: &validator, Value: 60, Threshold: 40}
+	results[3] = []*clique.Anomaly{share}
+
+	// The first analysis reports the anomaly, the second one stays silent
+	for i := 0; i < 2; i++ {
+		if err := monitor.analyze(); err != nil {
+			t.Fatalf("failed to analyze: %v", err)
+		}
+	}
+	if len(received) != 1 || len(received[0]) != 1 || received[0][0].Kind != clique.AnomalySealShare {
+		t.Fatalf("anomaly notifications mismatch: have %v", received)
+	}
+	// A gap sealed right before the epoch closed is still reported
+	results[3] = append(results[3], &clique.Anomaly{Kind: clique.AnomalyTimestampGap, Epoch: 3, Validator: &validator, Block: &block, Value: 30, Threshold: 15})
+	current = 4
+
+	if err := monitor.analyze(); err != nil {
+		t.Fatalf("failed to analyze: %v", err)
+	}
+	if len(received) != 2 || len(received[1]) != 1 || received[1][0].Kind != clique.AnomalyTimestampGap {
+		t.Fatalf("closing epoch anomalies mismatch: have %v", received)
+	}
+	if len(monitor.reported) != 0 {
+		t.Errorf("anomalies of closed epochs retained: %v", monitor.reported)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package alerts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Rule is a set of thresholds the validators it applies to are checked against.
+// Every threshold set is a separate alert condition, unset ones are disabled.
+type Rule struct {
+	Name         string           // Name identifying the rule in the alerts
+	Validators   []common.Address `toml:",omitempty"` // Validators the rule applies to, all of them if empty
+	MissedStreak uint64           `toml:",omitempty"` // Consecutive in-turn slots missed to alert at
+	MinUptime    float64          `toml:",omitempty"` // Share of the epoch's blocks in percent of the fair share to alert below
+	MaxSealAge   time.Duration    `toml:",omitempty"` // Time since the last sealed block to alert beyond
+}
+
+// Config are the configuration parameters of the validator alerting.
+type Config struct {
+	Rules     []Rule        `toml:",omitempty"` // Alert rules, none disables alerting
+	Anomalies bool          `toml:",omitempty"` // Whether to analyze the epochs for statistical anomalies
+	Webhook   string        `toml:",omitempty"` // URL the alerts are posted to as JSON, if any
+	Interval  time.Duration // Interval between two evaluations of the rules
+	Timeout   time.Duration // Time allowance for the webhook to accept the alerts
+	Repeat    time.Duration // Interval to notify alerts still firing again, zero to only notify once
+}
+
+// DefaultConfig contains the default settings for the validator alerting.
+var DefaultConfig = Config{
+	Interval: 30 * time.Second,
+	Timeout:  10 * time.Second,
+	Repeat:   time.Hour,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *Config) sanitize() (Config, error) {
+	conf := *config
+	conf.Rules = make([]Rule, len(config.Rules))
+	for i, rule := range config.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i)
+		}
+		if rule.MissedStreak == 0 && rule.MinUptime == 0 && rule.MaxSealAge == 0 {
+			return conf, fmt.Errorf("alert rule %q has no threshold", rule.Name)
+		}
+		if rule.MinUptime < 0 || rule.MinUptime > 100 {
+			return conf, fmt.Errorf("alert rule %q has invalid minimum uptime %v, must be between 0 and 100", rule.Name, rule.MinUptime)
+		}
+		if rule.MaxSealAge < 0 {
+			return conf, fmt.Errorf("alert rule %q has negative maximum seal age %v", rule.Name, rule.MaxSealAge)
+		}
+		conf.Rules[i] = rule
+	}
+	if conf.Interval <= 0 {
+		log.Warn("Sanitizing invalid alert interval", "provided", conf.Interval, "updated", DefaultConfig.Interval)
+		conf.Interval = DefaultConfig.Interval
+	}
+	if conf.Timeout <= 0 {
+		log.Warn("Sanitizing invalid alert webhook timeout", "provided", conf.Timeout, "updated", DefaultConfig.Timeout)
+		conf.Timeout = DefaultConfig.Timeout
+	}
+	if conf.Repeat < 0 {
+		log.Warn("Sanitizing invalid alert repeat interval", "provided", conf.Repeat, "updated", 0)
+		conf.Repeat = 0
+	}
+	return conf, nil
+}
@@ -0,0 +1,272 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package analytics implements an indexer aggregating the gas consumption, call
+// counts and unique senders of contracts per day.
+package analytics
+
+import (
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// Confirmations is the number of blocks a block must be buried under before
+	// it is indexed. Deeper reorgs leave the stats of the dropped blocks behind.
+	Confirmations = 16
+
+	// secondsPerDay is the length of a stats bucket.
+	secondsPerDay = 24 * 60 * 60
+)
+
+var (
+	statsPrefix  = []byte("aks-stats-")   // statsPrefix + address + day (uint64 big endian) -> RLP(dayStats)
+	senderPrefix = []byte("aks-sender-")  // senderPrefix + address + day (uint64 big endian) + sender -> nil
+	headKey      = []byte("AksStatsHead") // Number of the last block indexed
+)
+
+// dayStats are the aggregated calls into a contract on a single day.
+type dayStats struct {
+	GasUsed uint64
+	Calls   uint64
+	Senders uint64
+}
+
+// statsKey = statsPrefix + address + day (uint64 big endian)
+func statsKey(addr common.Address, day uint64) []byte {
+	key := make([]byte, len(statsPrefix)+common.AddressLength+8)
+	copy(key, statsPrefix)
+	copy(key[len(statsPrefix):], addr[:])
+	binary.BigEndian.PutUint64(key[len(statsPrefix)+common.AddressLength:], day)
+	return key
+}
+
+// senderDayPrefix = senderPrefix + address + day (uint64 big endian)
+func senderDayPrefix(addr common.Address, day uint64) []byte {
+	key := make([]byte, len(senderPrefix)+common.AddressLength+8)
+	copy(key, senderPrefix)
+	copy(key[len(senderPrefix):], addr[:])
+	binary.BigEndian.PutUint64(key[len(senderPrefix)+common.AddressLength:], day)
+	return key
+}
+
+// senderKey = senderPrefix + address + day (uint64 big endian) + sender
+func senderKey(addr common.Address, day uint64, sender common.Address) []byte {
+	return append(senderDayPrefix(addr, day), sender[:]...)
+}
+
+// readDayStats retrieves the stats of a contract on a day, or nil if it wasn't
+// called that day.
+func readDayStats(db ethdb.KeyValueReader, addr common.Address, day uint64) *dayStats {
+	blob, err := db.Get(statsKey(addr, day))
+	if err != nil || len(blob) == 0 {
+		return nil
+	}
+	stats := new(dayStats)
+	if err := rlp.DecodeBytes(blob, stats); err != nil {
+		log.Error("Invalid contract stats", "address", addr, "day", day, "err", err)
+		return nil
+	}
+	return stats
+}
+
+// readHead retrieves the number of the last indexed block.
+func readHead(db ethdb.KeyValueReader) (uint64, bool) {
+	blob, err := db.Get(headKey)
+	if err != nil || len(blob) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(blob), true
+}
+
+// Chain is the subset of the blockchain the indexer follows.
+type Chain interface {
+	Config() *params.ChainConfig
+	CurrentHeader() *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+	GetBlockByNumber(number uint64) *types.Block
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	State() (*state.StateDB, error)
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Indexer aggregates the top level calls into contracts per contract and day
+// as blocks get confirmed. Only blocks imported after the indexer was enabled
+// are indexed, there is no backfill of the existing chain.
+type Indexer struct {
+	db    ethdb.Database
+	chain Chain
+	head  uint64 // Number of the last indexed block, atomically accessed
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a contract analytics indexer, resuming from the last indexed block
+// or, on first use, starting at the confirmed part of the current chain.
+func New(db ethdb.Database, chain Chain) *Indexer {
+	head, ok := readHead(db)
+	if !ok {
+		if number := chain.CurrentHeader().Number.Uint64(); number > Confirmations {
+			head = number - Confirmations
+		}
+		var blob [8]byte
+		binary.BigEndian.PutUint64(blob[:], head)
+		if err := db.Put(headKey, blob[:]); err != nil {
+			log.Error("Failed to store contract analytics head", "err", err)
+		}
+		log.Info("Started contract analytics index", "from", head+1)
+	}
+	return &Indexer{
+		db:    db,
+		chain: chain,
+		head:  head,
+		quit:  make(chan struct{}),
+	}
+}
+
+// Start launches the indexing loop.
+func (idx *Indexer) Start() {
+	idx.wg.Add(1)
+	go idx.loop()
+}
+
+// Stop terminates the indexing loop.
+func (idx *Indexer) Stop() {
+	close(idx.quit)
+	idx.wg.Wait()
+}
+
+// Head returns the number of the last indexed block.
+func (idx *Indexer) Head() uint64 {
+	return atomic.LoadUint64(&idx.head)
+}
+
+func (idx *Indexer) loop() {
+	defer idx.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := idx.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	idx.catchUp(idx.chain.CurrentHeader().Number.Uint64())
+	for {
+		select {
+		case ev := <-heads:
+			idx.catchUp(ev.Block.NumberU64())
+		case <-sub.Err():
+			return
+		case <-idx.quit:
+			return
+		}
+	}
+}
+
+// catchUp indexes all blocks confirmed by the given head.
+func (idx *Indexer) catchUp(head uint64) {
+	for head > Confirmations && idx.Head() < head-Confirmations {
+		select {
+		case <-idx.quit:
+			return
+		default:
+		}
+		number := idx.Head() + 1
+		block := idx.chain.GetBlockByNumber(number)
+		if block == nil {
+			return
+		}
+		if err := idx.index(block); err != nil {
+			log.Error("Failed to index contract analytics", "number", number, "err", err)
+			return
+		}
+	}
+}
+
+// index aggregates the calls of a single block into the daily stats.
+func (idx *Indexer) index(block *types.Block) error {
+	receipts := idx.chain.GetReceiptsByHash(block.Hash())
+	if len(receipts) != len(block.Transactions()) {
+		return errMissingReceipts
+	}
+	// Contracts are recognized by having code in the current state, which is
+	// available even when catching up with blocks whose state got pruned.
+	statedb, err := idx.chain.State()
+	if err != nil {
+		return err
+	}
+	var (
+		signer  = types.MakeSigner(idx.chain.Config(), block.Number())
+		day     = block.Time() / secondsPerDay
+		updates = make(map[common.Address]*dayStats)
+		senders = make(map[common.Address]map[common.Address]struct{})
+		batch   = idx.db.NewBatch()
+	)
+	for i, tx := range block.Transactions() {
+		to := tx.To()
+		if to == nil || statedb.GetCodeSize(*to) == 0 {
+			continue
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+		stats := updates[*to]
+		if stats == nil {
+			if stats = readDayStats(idx.db, *to, day); stats == nil {
+				stats = new(dayStats)
+			}
+			updates[*to] = stats
+			senders[*to] = make(map[common.Address]struct{})
+		}
+		stats.GasUsed += receipts[i].GasUsed
+		stats.Calls++
+
+		if _, ok := senders[*to][from]; ok {
+			continue
+		}
+		senders[*to][from] = struct{}{}
+		if ok, _ := idx.db.Has(senderKey(*to, day, from)); !ok {
+			stats.Senders++
+			batch.Put(senderKey(*to, day, from), nil)
+		}
+	}
+	for addr, stats := range updates {
+		blob, err := rlp.EncodeToBytes(stats)
+		if err != nil {
+			return err
+		}
+		batch.Put(statsKey(addr, day), blob)
+	}
+	var head [8]byte
+	binary.BigEndian.PutUint64(head[:], block.NumberU64())
+	batch.Put(headKey, head[:])
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&idx.head, block.NumberU64())
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package analytics
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that calls into contracts get aggregated per day once confirmed, and
+// that plain transfers are ignored.
+func TestContractStats(t *testing.T) {
+	var (
+		key1, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		key2, _  = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		addr1    = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2    = crypto.PubkeyToAddress(key2.PublicKey)
+		contract = common.HexToAddress("0xc0ffee")
+		eoa      = common.HexToAddress("0xdead")
+		funds    = big.NewInt(params.Ether)
+		config   = params.TestChainConfig
+		signer   = types.LatestSigner(config)
+		db       = rawdb.NewMemoryDatabase()
+		gspec    = &core.Genesis{
+			Config: config,
+			Alloc: core.GenesisAlloc{
+				addr1:    {Balance: funds},
+				addr2:    {Balance: funds},
+				contract: {Code: []byte{byte(vm.STOP)}, Balance: new(big.Int)},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(config, genesis, ethash.NewFaker(), db, 30, func(i int, b *core.BlockGen) {
+		if i == 10 {
+			b.OffsetTime(secondsPerDay)
+		}
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr1), contract, nil, params.TxGas, b.BaseFee(), nil), signer, key1)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(addr1), eoa, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key1)
+		b.AddTx(tx)
+		if i >= 10 {
+			tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(addr2), contract, nil, params.TxGas, b.BaseFee(), nil), signer, key2)
+			b.AddTx(tx)
+		}
+	})
+	chain, err := core.NewBlockChain(db, nil, config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	idx := New(db, chain)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	idx.catchUp(chain.CurrentHeader().Number.Uint64())
+	if head := idx.Head(); head != 30-Confirmations {
+		t.Fatalf("indexed head mismatch: have %d, want %d", head, 30-Confirmations)
+	}
+	// Blocks 1-10 fall on the first day with a single sender, blocks 11-14 on the
+	// second one with two senders
+	api := NewAPI(idx)
+	stats, err := api.ContractStats(contract, 0, rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to query stats: %v", err)
+	}
+	if stats.Calls != 18 || uint64(stats.GasUsed) != 18*params.TxGas || stats.Senders != 2 {
+		t.Errorf("total stats mismatch: have %d calls, %d gas, %d senders", stats.Calls, stats.GasUsed, stats.Senders)
+	}
+	if len(stats.Days) != 2 {
+		t.Fatalf("day count mismatch: have %d, want 2", len(stats.Days))
+	}
+	if day := stats.Days[0]; day.Calls != 10 || day.Senders != 1 {
+		t.Errorf("first day mismatch: have %d calls, %d senders", day.Calls, day.Senders)
+	}
+	if day := stats.Days[1]; day.Calls != 8 || day.Senders != 2 || uint64(day.Day) != secondsPerDay {
+		t.Errorf("second day mismatch: have day %d, %d calls, %d senders", day.Day, day.Calls, day.Senders)
+	}
+	stats, err = api.ContractStats(contract, 0, 5)
+	if err != nil {
+		t.Fatalf("failed to query stats: %v", err)
+	}
+	if stats.Calls != 10 || stats.Senders != 1 || len(stats.Days) != 1 {
+		t.Errorf("first day stats mismatch: have %d calls, %d senders, %d days", stats.Calls, stats.Senders, len(stats.Days))
+	}
+	// Transfers to accounts without code are not tracked
+	if stats, _ := api.ContractStats(eoa, 0, rpc.LatestBlockNumber); stats.Calls != 0 {
+		t.Errorf("transfers tracked: have %d calls", stats.Calls)
+	}
+}
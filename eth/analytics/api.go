@@ -0,0 +1,125 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package analytics
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxStatsDays is the maximum number of days a single stats query may span.
+const maxStatsDays = 366
+
+var errMissingReceipts = errors.New("missing block receipts")
+
+// DayStats are the calls into a contract on a single day.
+type DayStats struct {
+	Day     hexutil.Uint64 `json:"day"` // Unix timestamp of the start of the day (UTC)
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Calls   hexutil.Uint64 `json:"calls"`
+	Senders hexutil.Uint64 `json:"uniqueSenders"`
+}
+
+// ContractStats are the calls into a contract over a range of days.
+type ContractStats struct {
+	Address common.Address `json:"address"`
+	From    hexutil.Uint64 `json:"from"`    // Start of the first day covered
+	To      hexutil.Uint64 `json:"to"`      // Start of the last day covered
+	Indexed hexutil.Uint64 `json:"indexed"` // Number of the last indexed block
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Calls   hexutil.Uint64 `json:"calls"`
+	Senders hexutil.Uint64 `json:"uniqueSenders"` // Unique senders over the whole range
+	Days    []DayStats     `json:"days"`          // Days the contract was called on
+}
+
+// API exposes the contract analytics in the aks namespace.
+type API struct {
+	idx *Indexer
+}
+
+// NewAPI creates the contract analytics API.
+func NewAPI(idx *Indexer) *API {
+	return &API{idx: idx}
+}
+
+// ContractStats returns the daily gas consumption, call counts and unique
+// senders of top level calls into a contract, over the days spanned by the
+// given blocks. Days are aggregated as a whole, so the first and last day may
+// include calls outside of the block range.
+func (api *API) ContractStats(address common.Address, fromBlock rpc.BlockNumber, toBlock rpc.BlockNumber) (*ContractStats, error) {
+	from, err := api.blockTime(fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.blockTime(toBlock)
+	if err != nil {
+		return nil, err
+	}
+	first, last := from/secondsPerDay, to/secondsPerDay
+	if first > last {
+		return nil, errors.New("from block after to block")
+	}
+	if last-first >= maxStatsDays {
+		return nil, fmt.Errorf("range spans too many days, max %d", maxStatsDays)
+	}
+	result := &ContractStats{
+		Address: address,
+		From:    hexutil.Uint64(first * secondsPerDay),
+		To:      hexutil.Uint64(last * secondsPerDay),
+		Indexed: hexutil.Uint64(api.idx.Head()),
+		Days:    []DayStats{},
+	}
+	senders := make(map[common.Address]struct{})
+	for day := first; day <= last; day++ {
+		stats := readDayStats(api.idx.db, address, day)
+		if stats == nil {
+			continue
+		}
+		result.GasUsed += hexutil.Uint64(stats.GasUsed)
+		result.Calls += hexutil.Uint64(stats.Calls)
+		result.Days = append(result.Days, DayStats{
+			Day:     hexutil.Uint64(day * secondsPerDay),
+			GasUsed: hexutil.Uint64(stats.GasUsed),
+			Calls:   hexutil.Uint64(stats.Calls),
+			Senders: hexutil.Uint64(stats.Senders),
+		})
+		prefix := senderDayPrefix(address, day)
+		it := api.idx.db.NewIterator(prefix, nil)
+		for it.Next() {
+			senders[common.BytesToAddress(it.Key()[len(prefix):])] = struct{}{}
+		}
+		it.Release()
+	}
+	result.Senders = hexutil.Uint64(len(senders))
+	return result, nil
+}
+
+// blockTime resolves the timestamp of a block.
+func (api *API) blockTime(number rpc.BlockNumber) (uint64, error) {
+	if number < 0 {
+		return api.idx.chain.CurrentHeader().Time, nil
+	}
+	header := api.idx.chain.GetHeaderByNumber(uint64(number))
+	if header == nil {
+		return 0, fmt.Errorf("block #%d not found", number)
+	}
+	return header.Time, nil
+}
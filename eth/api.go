@@ -30,10 +30,13 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/forkid"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -67,6 +70,44 @@ func (api *PublicEthereumAPI) Hashrate() hexutil.Uint64 {
 	return hexutil.Uint64(api.e.Miner().Hashrate())
 }
 
+// pendingBlockDetails is the pending block along with the clique slot it's
+// assembled for.
+type pendingBlockDetails struct {
+	Block          map[string]interface{} `json:"block"`
+	SlotTime       hexutil.Uint64         `json:"slotTime"`                 // Earliest time the block may be sealed at
+	Difficulty     *hexutil.Big           `json:"difficulty"`               // Difficulty of the candidate, 2 if in-turn
+	ExpectedSealer *common.Address        `json:"expectedSealer,omitempty"` // In-turn signer, unset if not running clique
+	LocalSealer    bool                   `json:"localSealer"`              // Whether the local signer is the in-turn one
+}
+
+// GetPendingBlockDetailed returns the pending block, assembled as the candidate
+// for the next slot, along with the signer expected to seal it. If fullTx is
+// true all transactions in the block are returned in full detail, otherwise
+// only the transaction hash is returned.
+func (api *PublicEthereumAPI) GetPendingBlockDetailed(fullTx bool) (*pendingBlockDetails, error) {
+	block := api.e.Miner().PendingBlock()
+	if block == nil {
+		return nil, errors.New("pending block not available")
+	}
+	fields, err := ethapi.RPCMarshalBlock(block, true, fullTx, api.e.blockchain.Config())
+	if err != nil {
+		return nil, err
+	}
+	details := &pendingBlockDetails{
+		Block:      fields,
+		SlotTime:   hexutil.Uint64(block.Time()),
+		Difficulty: (*hexutil.Big)(block.Difficulty()),
+	}
+	if engine := api.e.cliqueEngine(); engine != nil {
+		sealer, local, err := engine.ExpectedSealer(api.e.blockchain, block.Header())
+		if err != nil {
+			return nil, err
+		}
+		details.ExpectedSealer, details.LocalSealer = &sealer, local
+	}
+	return details, nil
+}
+
 // PublicMinerAPI provides an API to control the miner.
 // It offers only methods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
@@ -147,6 +188,11 @@ func (api *PrivateMinerAPI) SetRecommitInterval(interval int) {
 	api.e.Miner().SetRecommitInterval(time.Duration(interval) * time.Millisecond)
 }
 
+// Sponsorship returns the state of the sponsorship of zero priced transactions.
+func (api *PrivateMinerAPI) Sponsorship() core.SponsorStatus {
+	return api.e.txPool.SponsorStatus()
+}
+
 // PrivateAdminAPI is the collection of Ethereum full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -257,6 +303,59 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// errMaintenanceNoClique is returned when entering maintenance on a chain not
+// running clique.
+var errMaintenanceNoClique = errors.New("maintenance mode requires a clique chain")
+
+// EnterMaintenance makes the local clique signer skip its slots for the given
+// number of blocks. The maintenance is announced in the next block it seals, so
+// that the other signers take its turns with a reduced wiggle meanwhile.
+func (api *PrivateAdminAPI) EnterMaintenance(blocks hexutil.Uint64) (clique.MaintenanceStatus, error) {
+	engine := api.eth.cliqueEngine()
+	if engine == nil {
+		return clique.MaintenanceStatus{}, errMaintenanceNoClique
+	}
+	if err := engine.EnterMaintenance(uint64(blocks)); err != nil {
+		return clique.MaintenanceStatus{}, err
+	}
+	return engine.Maintenance(), nil
+}
+
+// ExitMaintenance cancels the pending or ongoing maintenance of the local clique
+// signer, resuming sealing right away.
+func (api *PrivateAdminAPI) ExitMaintenance() (clique.MaintenanceStatus, error) {
+	engine := api.eth.cliqueEngine()
+	if engine == nil {
+		return clique.MaintenanceStatus{}, errMaintenanceNoClique
+	}
+	engine.ExitMaintenance()
+	return engine.Maintenance(), nil
+}
+
+// forkIDInfo is the EIP-2124 fork identifier of the local chain.
+type forkIDInfo struct {
+	Hash   hexutil.Bytes    `json:"hash"`   // CRC32 checksum of the genesis and the passed fork blocks
+	Next   uint64           `json:"next"`   // Next upcoming fork block, zero if none
+	Head   uint64           `json:"head"`   // Head the fork identifier is computed at
+	Forks  []hexutil.Uint64 `json:"forks"`  // Activation blocks the fork identifier is computed from
+	Strict bool             `json:"strict"` // Whether peers with a diverging fork schedule are rejected
+}
+
+// ForkId returns the fork identifier announced to the peers, along with the fork
+// schedule it's derived from, including the activation blocks of this fork.
+func (api *PrivateAdminAPI) ForkId() forkIDInfo {
+	var (
+		chain = api.eth.blockchain
+		head  = chain.CurrentHeader().Number.Uint64()
+		id    = forkid.NewID(chain.Config(), chain.Genesis().Hash(), head)
+		info  = forkIDInfo{Hash: id.Hash[:], Next: id.Next, Head: head, Forks: []hexutil.Uint64{}, Strict: api.eth.config.StrictForkID}
+	)
+	for _, fork := range forkid.Forks(chain.Config()) {
+		info.Forks = append(info.Forks, hexutil.Uint64(fork))
+	}
+	return info
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -357,6 +456,70 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs,
 	return results, nil
 }
 
+// RejectedHeaderArgs represents the entries in the list returned when the
+// quarantined headers are queried.
+type RejectedHeaderArgs struct {
+	Hash   common.Hash            `json:"hash"`
+	Header map[string]interface{} `json:"header"`
+	RLP    string                 `json:"rlp"`
+	Reason string                 `json:"reason"`
+	Peer   string                 `json:"peer,omitempty"` // Peer the header was received from, if known
+	Time   uint64                 `json:"time"`           // Unix time the header was rejected at
+}
+
+// GetRejectedHeaders returns the last headers which failed verification, most
+// recently rejected first, along with the reason and the peer which sent them.
+func (api *PrivateDebugAPI) GetRejectedHeaders(ctx context.Context) ([]*RejectedHeaderArgs, error) {
+	var (
+		rejected = rawdb.ReadRejectedHeaders(api.eth.chainDb)
+		results  = make([]*RejectedHeaderArgs, 0, len(rejected))
+	)
+	for _, entry := range rejected {
+		var headerRlp string
+		if rlpBytes, err := rlp.EncodeToBytes(entry.Header); err != nil {
+			headerRlp = err.Error()
+		} else {
+			headerRlp = fmt.Sprintf("0x%x", rlpBytes)
+		}
+		results = append(results, &RejectedHeaderArgs{
+			Hash:   entry.Header.Hash(),
+			Header: ethapi.RPCMarshalHeader(entry.Header),
+			RLP:    headerRlp,
+			Reason: entry.Reason,
+			Peer:   entry.Peer,
+			Time:   entry.Time,
+		})
+	}
+	return results, nil
+}
+
+// AuditEntryArgs represents the entries in the list returned when the operator
+// audit log is queried.
+type AuditEntryArgs struct {
+	Time      uint64 `json:"time"` // Unix time of the action
+	Operation string `json:"operation"`
+	Details   string `json:"details"`
+	Error     string `json:"error,omitempty"` // Reason the action was refused or failed
+}
+
+// GetAuditLog returns the recent operator actions on the chain, such as chain
+// rewinds, most recent first and including the refused ones.
+func (api *PrivateDebugAPI) GetAuditLog() []*AuditEntryArgs {
+	var (
+		entries = rawdb.ReadAuditLog(api.eth.chainDb)
+		results = make([]*AuditEntryArgs, 0, len(entries))
+	)
+	for _, entry := range entries {
+		results = append(results, &AuditEntryArgs{
+			Time:      entry.Time,
+			Operation: entry.Operation,
+			Details:   entry.Details,
+			Error:     entry.Error,
+		})
+	}
+	return results
+}
+
 // AccountRangeMaxResults is the maximum number of results to be returned per call
 const AccountRangeMaxResults = 256
 
@@ -414,6 +577,10 @@ func (api *PublicDebugAPI) AccountRange(blockNrOrHash rpc.BlockNumberOrHash, sta
 	return stateDb.IteratorDump(opts), nil
 }
 
+// StorageRangeMaxResults is the maximum number of storage slots returned per
+// storage range or storage keys call.
+const StorageRangeMaxResults = 1024
+
 // StorageRangeResult is the result of a debug_storageRangeAt API call.
 type StorageRangeResult struct {
 	Storage storageMap   `json:"storage"`
@@ -442,6 +609,9 @@ func (api *PrivateDebugAPI) StorageRangeAt(blockHash common.Hash, txIndex int, c
 	if st == nil {
 		return StorageRangeResult{}, fmt.Errorf("account %x doesn't exist", contractAddress)
 	}
+	if maxResult > StorageRangeMaxResults {
+		maxResult = StorageRangeMaxResults
+	}
 	return storageRangeAt(st, keyStart, maxResult)
 }
 
@@ -465,6 +635,100 @@ func storageRangeAt(st state.Trie, start []byte, maxResult int) (StorageRangeRes
 		next := common.BytesToHash(it.Key)
 		result.NextKey = &next
 	}
+	// Don't report a truncated range as complete if trie nodes are missing
+	if it.Err != nil {
+		return StorageRangeResult{}, it.Err
+	}
+	return result, nil
+}
+
+// StorageKeysResult is a page of the storage of an account.
+type StorageKeysResult struct {
+	Slots  []storageSlot `json:"slots"`  // Slots in ascending order of their hash
+	Next   *common.Hash  `json:"next"`   // Cursor of the next page, nil if the last slot was returned
+	Source string        `json:"source"` // Whether the slots were read from the snapshot or the trie
+}
+
+type storageSlot struct {
+	Hash  common.Hash  `json:"hash"`
+	Key   *common.Hash `json:"key"` // nil if the preimage of the hash is unknown
+	Value common.Hash  `json:"value"`
+}
+
+// GetStorageKeys enumerates the storage of an account at the given block in
+// ascending order of the slot hashes, starting at the cursor (inclusive) and
+// returning at most limit slots (StorageRangeMaxResults if zero). The slots are
+// read from the state snapshot if it covers the block, otherwise from the trie.
+func (api *PrivateDebugAPI) GetStorageKeys(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash, cursor *common.Hash, limit int) (*StorageKeysResult, error) {
+	header, err := api.eth.APIBackend.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("block not found")
+	}
+	if limit <= 0 || limit > StorageRangeMaxResults {
+		limit = StorageRangeMaxResults
+	}
+	var start common.Hash
+	if cursor != nil {
+		start = *cursor
+	}
+	accountHash := crypto.Keccak256Hash(address.Bytes())
+
+	// Prefer the flat snapshot, it doesn't need to resolve trie nodes
+	if snaps := api.eth.blockchain.Snapshots(); snaps != nil {
+		if snap := snaps.Snapshot(header.Root); snap != nil {
+			if account, err := snap.Account(accountHash); err == nil {
+				if account == nil {
+					return nil, fmt.Errorf("account %x doesn't exist", address)
+				}
+				if it, err := snaps.StorageIterator(header.Root, accountHash, start); err == nil {
+					defer it.Release()
+					return api.storageKeys(it.Next, it.Hash, it.Slot, it.Error, limit, "snapshot")
+				}
+			}
+		}
+	}
+	statedb, err := api.eth.blockchain.StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	st := statedb.StorageTrie(address)
+	if st == nil {
+		return nil, fmt.Errorf("account %x doesn't exist", address)
+	}
+	it := trie.NewIterator(st.NodeIterator(start[:]))
+	return api.storageKeys(it.Next,
+		func() common.Hash { return common.BytesToHash(it.Key) },
+		func() []byte { return it.Value },
+		func() error { return it.Err },
+		limit, "trie")
+}
+
+// storageKeys collects a page of storage slots from an iterator.
+func (api *PrivateDebugAPI) storageKeys(next func() bool, hash func() common.Hash, value func() []byte, iterErr func() error, limit int, source string) (*StorageKeysResult, error) {
+	result := &StorageKeysResult{Slots: []storageSlot{}, Source: source}
+	for next() {
+		if len(result.Slots) == limit {
+			cursor := hash()
+			result.Next = &cursor
+			break
+		}
+		_, content, _, err := rlp.Split(value())
+		if err != nil {
+			return nil, err
+		}
+		slot := storageSlot{Hash: hash(), Value: common.BytesToHash(content)}
+		if preimage := rawdb.ReadPreimage(api.eth.ChainDb(), slot.Hash); preimage != nil {
+			key := common.BytesToHash(preimage)
+			slot.Key = &key
+		}
+		result.Slots = append(result.Slots, slot)
+	}
+	if err := iterErr(); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
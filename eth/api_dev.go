@@ -0,0 +1,137 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// maxDevBlocks is the maximum number of blocks mined by a single dev_mine call.
+const maxDevBlocks = 10000
+
+// PrivateDevAPI provides block production and chain time controls for clique
+// developer chains, so tests don't need to wait for blocks or epochs.
+type PrivateDevAPI struct {
+	e      *Ethereum
+	engine *clique.Clique
+	lock   sync.Mutex // Serializes block production requests
+}
+
+// NewPrivateDevAPI creates a new developer control API, failing if the node
+// doesn't run the clique consensus engine.
+func NewPrivateDevAPI(e *Ethereum) (*PrivateDevAPI, error) {
+	engine := e.cliqueEngine()
+	if engine == nil {
+		return nil, errors.New("developer controls require clique consensus")
+	}
+	return &PrivateDevAPI{e: e, engine: engine}, nil
+}
+
+// Mine instantly seals count blocks (1 by default) on top of the current head,
+// including any pending transactions, and returns the new head number.
+func (api *PrivateDevAPI) Mine(count *hexutil.Uint64) (hexutil.Uint64, error) {
+	n := uint64(1)
+	if count != nil {
+		n = uint64(*count)
+	}
+	if n == 0 || n > maxDevBlocks {
+		return 0, fmt.Errorf("block count must be between 1 and %d", maxDevBlocks)
+	}
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	for i := uint64(0); i < n; i++ {
+		if _, err := api.mineBlock(); err != nil {
+			return hexutil.Uint64(api.e.blockchain.CurrentBlock().NumberU64()), err
+		}
+	}
+	return hexutil.Uint64(api.e.blockchain.CurrentBlock().NumberU64()), nil
+}
+
+// SetNextBlockTimestamp shifts the chain clock so the next block is sealed with
+// the given timestamp. Later blocks keep advancing from there.
+func (api *PrivateDevAPI) SetNextBlockTimestamp(timestamp hexutil.Uint64) error {
+	head := api.e.blockchain.CurrentHeader()
+	if min := head.Time + api.e.blockchain.Config().Clique.Period; uint64(timestamp) < min {
+		return fmt.Errorf("timestamp %d before earliest allowed %d", timestamp, min)
+	}
+	api.engine.SetTimeOffset(time.Until(time.Unix(int64(timestamp), 0)).Round(time.Second))
+	return nil
+}
+
+// IncreaseTime moves the chain clock forward by the given number of seconds
+// and returns the total offset from the wall clock in seconds.
+func (api *PrivateDevAPI) IncreaseTime(seconds hexutil.Uint64) hexutil.Uint64 {
+	offset := api.engine.TimeOffset() + time.Duration(seconds)*time.Second
+	api.engine.SetTimeOffset(offset)
+	return hexutil.Uint64(offset / time.Second)
+}
+
+// MineToNextEpoch advances the static validator registry to a new epoch and
+// instantly seals the block proposing it, returning its number.
+func (api *PrivateDevAPI) MineToNextEpoch() (hexutil.Uint64, error) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	epoch, err := api.engine.AdvanceEpoch()
+	if err != nil {
+		return 0, err
+	}
+	block, err := api.mineBlock()
+	if err != nil {
+		return 0, err
+	}
+	log.Info("Mined developer epoch block", "epoch", epoch, "number", block.Number(), "hash", block.Hash())
+	return hexutil.Uint64(block.NumberU64()), nil
+}
+
+// mineBlock assembles, seals and imports a single block on top of the head.
+func (api *PrivateDevAPI) mineBlock() (*types.Block, error) {
+	parent := api.e.blockchain.CurrentBlock()
+
+	timestamp := uint64(api.engine.Now().Unix())
+	if timestamp <= parent.Time() {
+		timestamp = parent.Time() + 1
+	}
+	// Clique resets the coinbase, the timestamp is recapped by the engine too
+	block, err := api.e.miner.GetSealingBlockSync(parent.Hash(), timestamp, common.Address{}, common.Hash{}, false)
+	if err != nil {
+		return nil, err
+	}
+	// The block period may stamp the block ahead of the clock, skip forward
+	// so the block isn't considered to be from the future.
+	if now := api.engine.Now(); block.Time() > uint64(now.Unix()) {
+		api.engine.SetTimeOffset(api.engine.TimeOffset() + time.Unix(int64(block.Time()), 0).Sub(now).Round(time.Second))
+	}
+	sealed, err := api.engine.SealNow(api.e.blockchain, block)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := api.e.blockchain.InsertChain(types.Blocks{sealed}); err != nil {
+		return nil, err
+	}
+	return sealed, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+// Tests that a developer chain produces blocks, travels in time and closes
+// epochs on demand through the dev namespace.
+func TestDevChain(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		signer = crypto.PubkeyToAddress(key.PublicKey)
+	)
+	stack, err := node.New(&node.Config{P2P: p2p.Config{NoDiscovery: true, ListenAddr: "127.0.0.1:0"}})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	defer stack.Close()
+
+	config := ethconfig.Defaults
+	config.Genesis = core.DeveloperGenesisBlock(0, 11500000, signer)
+	config.DevControls = true
+	backend, err := New(stack, &config)
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	engine := backend.cliqueEngine()
+	engine.Authorize(signer, func(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+		return crypto.Sign(crypto.Keccak256(data), key)
+	})
+	client, err := stack.Attach()
+	if err != nil {
+		t.Fatalf("failed to attach: %v", err)
+	}
+	defer client.Close()
+
+	// Mine a few blocks instantly, empty as they are
+	var head hexutil.Uint64
+	if err := client.Call(&head, "dev_mine", hexutil.Uint64(3)); err != nil {
+		t.Fatalf("failed to mine blocks: %v", err)
+	}
+	if head != 3 || backend.BlockChain().CurrentBlock().NumberU64() != 3 {
+		t.Fatalf("head mismatch after mining: have %d, want 3", head)
+	}
+	// Travel an hour forward and ensure the next block is stamped accordingly
+	var offset hexutil.Uint64
+	if err := client.Call(&offset, "dev_increaseTime", hexutil.Uint64(3600)); err != nil {
+		t.Fatalf("failed to increase time: %v", err)
+	}
+	if offset < 3600 {
+		t.Fatalf("clock offset mismatch: have %ds, want at least 3600s", offset)
+	}
+	if err := client.Call(&head, "dev_mine", nil); err != nil {
+		t.Fatalf("failed to mine block: %v", err)
+	}
+	if stamp, min := backend.BlockChain().CurrentBlock().Time(), uint64(time.Now().Add(time.Hour).Unix())-5; head != 4 || stamp < min {
+		t.Fatalf("block after time travel mismatch: number %d, time %d, want #4 after %d", head, stamp, min)
+	}
+	// Close the epoch and ensure the sealed block proposes the next one
+	if err := client.Call(&head, "dev_mineToNextEpoch"); err != nil {
+		t.Fatalf("failed to mine to the next epoch: %v", err)
+	}
+	block := backend.BlockChain().CurrentBlock()
+	if head != 5 || block.NumberU64() != 5 || block.Nonce() == 0 {
+		t.Fatalf("epoch block mismatch: have #%d with nonce %d, want #5 with an epoch nonce", block.NumberU64(), block.Nonce())
+	}
+	if err := client.Call(&head, "dev_mine", hexutil.Uint64(0)); err == nil {
+		t.Fatalf("mining zero blocks accepted")
+	}
+}
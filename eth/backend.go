@@ -38,15 +38,26 @@ import (
 	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/alerts"
+	"github.com/ethereum/go-ethereum/eth/analytics"
 	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/epochstats"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/exex"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/eth/protocols/mesh"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/eth/publisher"
+	"github.com/ethereum/go-ethereum/eth/replica"
+	"github.com/ethereum/go-ethereum/eth/sqlmirror"
+	"github.com/ethereum/go-ethereum/eth/txmgr"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/remotedb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/internal/maintenance"
 	"github.com/ethereum/go-ethereum/internal/shutdowncheck"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/miner"
@@ -74,6 +85,22 @@ type Ethereum struct {
 	ethDialCandidates  enode.Iterator
 	snapDialCandidates enode.Iterator
 	merger             *consensus.Merger
+	txManager          *txmgr.Manager
+	nonceAllocator     *txmgr.NonceAllocator
+	headWatch          *headWatchdog
+	analytics          *analytics.Indexer
+	epochStats         *epochstats.Indexer
+	exex               *exex.Manager
+	publisher          *publisher.Publisher
+	sqlmirror          *sqlmirror.Mirror
+	follower           *replica.Follower
+	standby            *replica.Standby
+	alerts             *alerts.Monitor
+	snapCheck          *snapshotChecker
+	preflight          *preflight
+	sentry             *sentryLinks
+	cacheTuner         *cacheTuner
+	stateSessions      *stateSessions
 
 	// DB interfaces
 	chainDb ethdb.Database // Block chain database
@@ -92,6 +119,9 @@ type Ethereum struct {
 	gasPrice  *big.Int
 	etherbase common.Address
 
+	signer       common.Address  // Signer handed over to with a passphrase
+	signerWallet accounts.Wallet // Wallet sealing with the passphrase of the signer
+
 	networkID     uint64
 	netRPCService *ethapi.PublicNetAPI
 
@@ -136,6 +166,17 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	if config.StateOverlay != "" {
+		overlay, err := remotedb.NewOverlay(chainDb, config.StateOverlay)
+		if err != nil {
+			return nil, err
+		}
+		if err := setupStateOverlay(overlay, config); err != nil {
+			overlay.Close()
+			return nil, err
+		}
+		chainDb = overlay
+	}
 	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlockWithOverride(chainDb, config.Genesis, config.OverrideArrowGlacier, config.OverrideTerminalTotalDifficulty)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
@@ -180,6 +221,26 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			rawdb.WriteDatabaseVersion(chainDb, core.BlockChainVersion)
 		}
 	}
+	if policy := (clique.TimestampPolicy{MaxFutureDrift: config.CliqueMaxFutureDrift, Jitter: config.CliqueTimestampJitter}); policy != (clique.TimestampPolicy{}) {
+		if cli := eth.cliqueEngine(); cli == nil {
+			log.Warn("Timestamp drift policy needs clique, ignoring")
+		} else if policy.MaxFutureDrift > 0 && policy.Jitter > policy.MaxFutureDrift {
+			return nil, fmt.Errorf("timestamp jitter %v above maximum future drift %v", policy.Jitter, policy.MaxFutureDrift)
+		} else {
+			cli.SetTimestampPolicy(policy)
+		}
+	}
+	if config.CliqueFastVerify != nil {
+		if cli := eth.cliqueEngine(); cli == nil {
+			log.Warn("Fast header verification needs clique, ignoring")
+		} else {
+			// Only the initial sync takes the shortcut, the handler being
+			// created further down
+			cli.SetFastVerify(config.CliqueFastVerify, func() bool {
+				return eth.handler != nil && atomic.LoadUint32(&eth.handler.acceptTxs) == 0 && eth.handler.downloader.Synchronising()
+			})
+		}
+	}
 	var (
 		vmConfig = vm.Config{
 			EnablePreimageRecording: config.EnablePreimageRecording,
@@ -196,6 +257,18 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			Preimages:           config.Preimages,
 		}
 	)
+	// Followers write the state diffs of their primary straight into the tries,
+	// bypassing the snapshot, which would only go stale
+	if config.Replica.Primary != "" && cacheConfig.SnapshotLimit > 0 {
+		log.Info("Disabling snapshot of follower")
+		cacheConfig.SnapshotLimit = 0
+	}
+	// Shape the background state maintenance before the chain starts any
+	var scheduler *maintenance.Scheduler
+	if config.Maintenance != (maintenance.Config{}) {
+		scheduler = maintenance.NewScheduler(config.Maintenance)
+		maintenance.Install(scheduler)
+	}
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, chainConfig, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit)
 	if err != nil {
 		return nil, err
@@ -211,6 +284,16 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.RemoteJournal != "" {
+		config.TxPool.RemoteJournal = stack.ResolvePath(config.TxPool.RemoteJournal)
+	}
+	// Sponsored transactions are zero priced, they can't pay a base fee
+	if len(config.TxPool.Sponsored) > 0 && chainConfig.LondonBlock != nil {
+		return nil, fmt.Errorf("transaction sponsorship unavailable on chains with a base fee (london block #%v)", chainConfig.LondonBlock)
+	}
+	if config.TxPool.SponsorJournal != "" {
+		config.TxPool.SponsorJournal = stack.ResolvePath(config.TxPool.SponsorJournal)
+	}
 	eth.txPool = core.NewTxPool(config.TxPool, chainConfig, eth.blockchain)
 
 	// Permit the downloader to use the trie cache allowance during fast sync
@@ -219,6 +302,13 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if checkpoint == nil {
 		checkpoint = params.TrustedCheckpoints[genesisHash]
 	}
+	if eth.sentry, err = newSentryLinks(config, &stack.Config().P2P); err != nil {
+		return nil, err
+	}
+	var linked map[string]bool
+	if eth.sentry != nil {
+		linked = eth.sentry.ids
+	}
 	if eth.handler, err = newHandler(&handlerConfig{
 		Database:       chainDb,
 		Chain:          eth.blockchain,
@@ -230,12 +320,120 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		EventMux:       eth.eventMux,
 		Checkpoint:     checkpoint,
 		RequiredBlocks: config.RequiredBlocks,
+		IsSigner:       eth.isSigner,
+		StrictForkID:   config.StrictForkID,
+		Linked:         linked,
 	}); err != nil {
 		return nil, err
 	}
+	if wiggle := config.Miner; wiggle.WiggleMin > 0 || wiggle.WiggleMax > 0 {
+		if cli := eth.cliqueEngine(); cli == nil {
+			log.Warn("Adaptive out-of-turn wiggle needs clique, disabling")
+		} else if wiggle.WiggleMax > 0 && wiggle.WiggleMin > wiggle.WiggleMax {
+			return nil, fmt.Errorf("adaptive wiggle lower bound %v above upper bound %v", wiggle.WiggleMin, wiggle.WiggleMax)
+		} else {
+			cli.SetAdaptiveWiggle(eth.handler.propagation.latency, wiggle.WiggleMin, wiggle.WiggleMax)
+		}
+	}
+
+	eth.nonceAllocator = txmgr.NewNonceAllocator(eth.txPool, config.TxManager.NonceLease)
+	if config.TxManager.Enabled {
+		eth.txManager = txmgr.New(config.TxManager, eth.blockchain, eth.txPool, chainDb, eth.accountManager, eth.handler.BroadcastTransactions)
+	}
+
+	if config.HeadLagPeriods > 0 {
+		if chainConfig.Clique != nil && chainConfig.Clique.Period > 0 {
+			period := time.Duration(chainConfig.Clique.Period) * time.Second
+			eth.headWatch = newHeadWatchdog(eth.blockchain, eth.handler, eth.p2pServer, period, config.HeadLagPeriods)
+		} else {
+			log.Warn("Head lag watchdog needs a clique chain with a fixed block period, disabling")
+		}
+	}
+	if config.ContractAnalytics {
+		eth.analytics = analytics.New(chainDb, eth.blockchain)
+	}
+	if config.EpochStats {
+		if cli := eth.cliqueEngine(); cli == nil {
+			log.Warn("Epoch stats need a clique chain, disabling")
+		} else if eth.epochStats, err = epochstats.New(chainDb, eth.blockchain, cli); err != nil {
+			return nil, err
+		}
+	}
+	if !config.NoPruning {
+		eth.cacheTuner = newCacheTuner(eth.blockchain, config.TrieDirtyCache, config.TrieDirtyAutoTune)
+	}
+	if config.ExExSocket != "" {
+		eth.exex = exex.New(chainDb, eth.blockchain, stack.ResolvePath(config.ExExSocket), config.ExExStateDiffs)
+	}
+
+	if config.Publisher.Backend != "" {
+		if eth.publisher, err = publisher.New(config.Publisher, chainDb, eth.blockchain, eth.cliqueEngine()); err != nil {
+			return nil, err
+		}
+	}
+	if config.SQLMirror.Driver != "" {
+		if eth.sqlmirror, err = sqlmirror.New(config.SQLMirror, eth.blockchain, eth.cliqueEngine()); err != nil {
+			return nil, err
+		}
+	}
+	if config.Replica.Primary != "" {
+		followerConfig := config.Replica
+		if followerConfig.JWTSecret == "" {
+			followerConfig.JWTSecret = stack.Config().JWTSecret
+		}
+		if eth.follower, err = replica.NewFollower(followerConfig, chainDb, eth.blockchain); err != nil {
+			return nil, err
+		}
+	}
+	if config.Replica.Standby != "" {
+		if eth.standby, err = replica.NewStandby(config.Replica.Standby, chainDb, eth.blockchain, eth.engine, cacheConfig); err != nil {
+			return nil, err
+		}
+	}
+	if len(config.Alerts.Rules) > 0 || config.Alerts.Anomalies {
+		if eth.alerts, err = alerts.New(config.Alerts, eth.blockchain, eth.cliqueEngine()); err != nil {
+			return nil, err
+		}
+	}
+
+	eth.stateSessions = newStateSessions(eth.blockchain)
+
+	if c := eth.cliqueEngine(); c != nil {
+		versions := newVersionTracker(c, eth.blockchain, eth.accountManager, params.VersionWithMeta, config.Miner.GitCommit)
+		eth.snapCheck = newSnapshotChecker(c, eth.blockchain, versions)
+		if !config.NoPreflight {
+			eth.preflight = newPreflight(eth, c, config.PreflightMaxLag, config.PreflightMinFreeDisk, stack.ResolvePath(""))
+		}
+	}
 
 	eth.miner = miner.New(eth, &config.Miner, chainConfig, eth.EventMux(), eth.engine, eth.isLocalBlock)
-	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
+	if config.Maintenance.InTurnWindow > 0 {
+		if eth.cliqueEngine() == nil {
+			log.Warn("Pausing state maintenance in-turn needs clique, ignoring")
+		} else {
+			scheduler.SetPause(eth.inTurnPause(config.Maintenance.InTurnWindow))
+		}
+	}
+	if (config.Miner.Vanity || config.Miner.DeterministicOrdering) && eth.cliqueEngine() != nil {
+		vanity, err := clique.NewVanity(config.Miner.GitCommit, config.Miner.VanityTag)
+		if err != nil {
+			return nil, err
+		}
+		if config.Miner.DeterministicOrdering {
+			vanity.Ordering = miner.DeterministicOrderingVersion
+		}
+		extra, err := clique.EncodeVanity(vanity)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("Announcing client in header vanity", "version", vanity.Version(), "build", vanity.Build, "ordering", vanity.Ordering, "tag", vanity.Tag)
+		eth.miner.SetExtra(extra)
+	} else {
+		if config.Miner.Vanity || config.Miner.DeterministicOrdering {
+			log.Warn("Structured header vanity needs a clique chain, using the extra data")
+		}
+		eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
+	}
 
 	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), stack.Config().AllowUnprotectedTxs, eth, nil}
 	if eth.APIBackend.allowUnprotectedTxs {
@@ -266,6 +464,15 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	stack.RegisterProtocols(eth.Protocols())
 	stack.RegisterLifecycle(eth)
 
+	// Persist the state a killed shutdown would corrupt or lose ahead of the rest
+	// of it: stop signing so the slash protection record covers every signed
+	// block, then flush the clique snapshot and the transaction journals.
+	if c := eth.cliqueEngine(); c != nil {
+		stack.RegisterFlusher("slashprotection", c.StopSealing)
+		stack.RegisterFlusher("clique", func() error { return c.FlushSnapshot(eth.blockchain) })
+	}
+	stack.RegisterFlusher("txpool", eth.txPool.FlushJournal)
+
 	// Successful startup; push a marker and check previous unclean shutdowns.
 	eth.shutdownTracker.MarkStartup()
 
@@ -297,6 +504,88 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Append the transaction manager APIs if tracking is enabled
+	if s.txManager != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "txmgr",
+			Version:   "1.0",
+			Service:   txmgr.NewPrivateTxManagerAPI(s.txManager),
+		})
+	}
+	// Append the contract analytics if indexing is enabled
+	if s.analytics != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "aks",
+			Version:   "1.0",
+			Service:   analytics.NewAPI(s.analytics),
+		})
+	}
+	// Append the epoch stats if the rollups are enabled
+	if s.epochStats != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "aks",
+			Version:   "1.0",
+			Service:   epochstats.NewAPI(s.epochStats),
+		})
+	}
+	// Append the execution extension controls if the socket is enabled
+	if s.exex != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "exex",
+			Version:   "1.0",
+			Service:   exex.NewPrivateExExAPI(s.exex),
+		})
+	}
+	// Append the chain stream of the followers if serving them is enabled
+	if s.config.Replica.Serve {
+		apis = append(apis, rpc.API{
+			Namespace:     "replica",
+			Version:       "1.0",
+			Service:       replica.NewPrimaryAPI(s.blockchain),
+			Authenticated: true,
+		})
+	}
+	// Append the runtime signer handover if running clique
+	if s.cliqueEngine() != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "clique",
+			Version:   "1.0",
+			Service:   &PrivateSignerAPI{s},
+		})
+	}
+	// Append the epoch snapshot cross-check if running clique
+	if s.snapCheck != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "clique",
+			Version:   "1.0",
+			Service:   &PrivateSnapshotCheckAPI{s.snapCheck},
+		}, rpc.API{
+			Namespace: "clique",
+			Version:   "1.0",
+			Service:   &PublicNetworkVersionsAPI{s.snapCheck.versions},
+			Public:    true,
+		})
+	}
+	// Append the message bus publisher controls if publishing is enabled
+	if s.publisher != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "publisher",
+			Version:   "1.0",
+			Service:   publisher.NewPrivatePublisherAPI(s.publisher),
+		})
+	}
+	// Append the developer chain controls if requested
+	if s.config.DevControls {
+		if api, err := NewPrivateDevAPI(s); err != nil {
+			log.Warn("Developer controls unavailable", "err", err)
+		} else {
+			apis = append(apis, rpc.API{
+				Namespace: "dev",
+				Version:   "1.0",
+				Service:   api,
+			})
+		}
+	}
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -314,6 +603,25 @@ func (s *Ethereum) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   downloader.NewPublicDownloaderAPI(s.handler.downloader, s.eventMux),
 			Public:    true,
+		}, {
+			Namespace: "txmgr",
+			Version:   "1.0",
+			Service:   txmgr.NewPrivateNonceAPI(s.nonceAllocator),
+		}, {
+			Namespace: "aks",
+			Version:   "1.0",
+			Service:   NewPublicIssuanceAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "aks",
+			Version:   "1.0",
+			Service:   NewPublicOrderingAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "aks",
+			Version:   "1.0",
+			Service:   NewPublicFeeMarketAPI(s),
+			Public:    true,
 		}, {
 			Namespace: "miner",
 			Version:   "1.0",
@@ -328,6 +636,10 @@ func (s *Ethereum) APIs() []rpc.API {
 			Namespace: "admin",
 			Version:   "1.0",
 			Service:   NewPrivateAdminAPI(s),
+		}, {
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   NewPrivateForkMonitorAPI(s),
 		}, {
 			Namespace: "debug",
 			Version:   "1.0",
@@ -465,21 +777,18 @@ func (s *Ethereum) StartMining(threads int) error {
 			log.Error("Cannot start mining without etherbase", "err", err)
 			return fmt.Errorf("etherbase missing: %v", err)
 		}
-		var cli *clique.Clique
-		if c, ok := s.engine.(*clique.Clique); ok {
-			cli = c
-		} else if cl, ok := s.engine.(*beacon.Beacon); ok {
-			if c, ok := cl.InnerEngine().(*clique.Clique); ok {
-				cli = c
-			}
-		}
-		if cli != nil {
+		if s.cliqueEngine() != nil {
 			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
 			if wallet == nil || err != nil {
 				log.Error("Etherbase account unavailable locally", "err", err)
 				return fmt.Errorf("signer missing: %v", err)
 			}
-			cli.Authorize(eb, wallet.SignData)
+			s.authorizeSigner(eb, wallet)
+
+			// Hold back sealing until the validator is fit to seal
+			if s.preflight != nil {
+				s.preflight.start()
+			}
 		}
 		// If mining is started, we can disable the transaction rejection mechanism
 		// introduced to speed sync times.
@@ -510,6 +819,7 @@ func (s *Ethereum) Miner() *miner.Miner { return s.miner }
 func (s *Ethereum) AccountManager() *accounts.Manager  { return s.accountManager }
 func (s *Ethereum) BlockChain() *core.BlockChain       { return s.blockchain }
 func (s *Ethereum) TxPool() *core.TxPool               { return s.txPool }
+func (s *Ethereum) TxManager() *txmgr.Manager          { return s.txManager }
 func (s *Ethereum) EventMux() *event.TypeMux           { return s.eventMux }
 func (s *Ethereum) Engine() consensus.Engine           { return s.engine }
 func (s *Ethereum) ChainDb() ethdb.Database            { return s.chainDb }
@@ -532,6 +842,9 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 	if s.config.SnapshotCache > 0 {
 		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates)...)
 	}
+	if s.snapCheck != nil {
+		protos = append(protos, mesh.MakeProtocols(s.snapCheck)...)
+	}
 	return protos
 }
 
@@ -556,18 +869,144 @@ func (s *Ethereum) Start() error {
 	}
 	// Start the networking layer and the light server if requested
 	s.handler.Start(maxPeers)
+
+	// Keep the sentry links connected if running a sentry architecture
+	if s.sentry != nil {
+		s.sentry.connect(s.p2pServer)
+	}
+
+	// Start resubmitting stuck local transactions if requested
+	if s.txManager != nil {
+		s.txManager.Start()
+	}
+	// Start rotating the peers if the chain head stalls
+	if s.headWatch != nil {
+		s.headWatch.Start()
+	}
+	// Start adapting the dirty trie cache, idle unless enabled
+	if s.cacheTuner != nil {
+		s.cacheTuner.Start()
+	}
+	// Start indexing the contract analytics if requested
+	if s.analytics != nil {
+		s.analytics.Start()
+	}
+	// Start rolling up the epoch stats if requested
+	if s.epochStats != nil {
+		s.epochStats.Start()
+	}
+	// Start publishing the chain to the message bus if requested
+	if s.publisher != nil {
+		s.publisher.Start()
+	}
+	// Start mirroring the chain metadata into SQL if requested
+	if s.sqlmirror != nil {
+		s.sqlmirror.Start()
+	}
+	// Start cross-checking the epoch snapshots with the validator mesh
+	if s.snapCheck != nil {
+		s.snapCheck.Start()
+	}
+	// Start following the primary if running as a follower
+	if s.follower != nil {
+		s.follower.Start()
+	}
+	// Start dual-writing the chain to the standby datadir if configured
+	if s.standby != nil {
+		s.standby.Start()
+	}
+	// Start evaluating the validator alert rules if configured
+	if s.alerts != nil {
+		s.alerts.Start()
+	}
+	// Start streaming the chain changes to the execution extensions
+	if s.exex != nil {
+		if err := s.exex.Start(); err != nil {
+			return err
+		}
+	}
+	// Index the sealers of blocks imported before the clique sealer index existed
+	// and start checking the local clock the sealing depends on
+	if c := s.cliqueEngine(); c != nil {
+		c.StartSealerIndexer(s.blockchain)
+		c.StartClockCheck(s.config.CliqueClock)
+	}
 	return nil
 }
 
+// SubscribeHeadLagEvent registers a subscription for the peer rotations of the
+// head lag watchdog. The subscription never fires if the watchdog is disabled.
+func (s *Ethereum) SubscribeHeadLagEvent(ch chan<- HeadLagEvent) event.Subscription {
+	if s.headWatch == nil {
+		return event.NewSubscription(func(quit <-chan struct{}) error {
+			<-quit
+			return nil
+		})
+	}
+	return s.headWatch.SubscribeHeadLagEvent(ch)
+}
+
+// cliqueEngine returns the clique consensus engine, unwrapping it from the
+// beacon engine if needed, or nil if the chain doesn't run clique.
+func (s *Ethereum) cliqueEngine() *clique.Clique {
+	engine := s.engine
+	if b, ok := engine.(*beacon.Beacon); ok {
+		engine = b.InnerEngine()
+	}
+	c, _ := engine.(*clique.Clique)
+	return c
+}
+
 // Stop implements node.Lifecycle, terminating all internal goroutines used by the
 // Ethereum protocol.
 func (s *Ethereum) Stop() error {
 	// Stop all the peer-related stuff first.
 	s.ethDialCandidates.Close()
 	s.snapDialCandidates.Close()
+	if s.headWatch != nil {
+		s.headWatch.Stop()
+	}
+	if s.cacheTuner != nil {
+		s.cacheTuner.Stop()
+	}
+	if s.snapCheck != nil {
+		s.snapCheck.Stop()
+	}
+	if s.preflight != nil {
+		s.preflight.stop()
+	}
 	s.handler.Stop()
 
 	// Then stop everything else.
+	s.stateSessions.close()
+	if s.txManager != nil {
+		s.txManager.Stop()
+	}
+	if s.analytics != nil {
+		s.analytics.Stop()
+	}
+	if s.epochStats != nil {
+		s.epochStats.Stop()
+	}
+	if s.exex != nil {
+		s.exex.Stop()
+	}
+	if s.publisher != nil {
+		s.publisher.Stop()
+	}
+	if s.sqlmirror != nil {
+		s.sqlmirror.Stop()
+	}
+	if s.follower != nil {
+		s.follower.Stop()
+	}
+	if s.standby != nil {
+		s.standby.Stop()
+	}
+	maintenance.Install(nil)
+	if s.alerts != nil {
+		s.alerts.Stop()
+	}
 	s.bloomIndexer.Close()
 	close(s.closeBloomHandler)
 	s.txPool.Stop()
@@ -0,0 +1,272 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	gopsutil "github.com/shirou/gopsutil/mem"
+)
+
+const (
+	// cacheTuneInterval is the time between two adjustments of the dirty trie
+	// cache by the tuner.
+	cacheTuneInterval = time.Minute
+
+	// minTrieDirtyLimit is the memory limit (MB) below which the tuner doesn't
+	// shrink the dirty trie cache.
+	minTrieDirtyLimit = 64
+)
+
+var (
+	cacheGrowMeter   = metrics.NewRegisteredMeter("eth/cachetune/grow", nil)
+	cacheShrinkMeter = metrics.NewRegisteredMeter("eth/cachetune/shrink", nil)
+)
+
+// cacheTuneChain is the subset of the blockchain the cache tuner adjusts.
+type cacheTuneChain interface {
+	CacheStatus() (core.CacheStatus, error)
+	SetCacheLimits(dirtyLimit int, timeLimit time.Duration) error
+}
+
+// systemMemory returns the total and available memory of the host in bytes.
+func systemMemory() (uint64, uint64, error) {
+	mem, err := gopsutil.VirtualMemory()
+	if err != nil {
+		return 0, 0, err
+	}
+	return mem.Total, mem.Available, nil
+}
+
+// cacheTuner adapts the dirty trie cache to the host: it shrinks the cache when
+// the host runs low on memory, and grows it when the cache is full and blocks
+// take notably longer to import than at best, while memory is plentiful. The
+// clean trie and snapshot caches are allocated at startup and left alone.
+type cacheTuner struct {
+	chain  cacheTuneChain
+	memory func() (total uint64, available uint64, err error)
+	max    int // Memory limit (MB) above which the cache isn't grown
+
+	enabled int32         // Whether the cache is adapted (atomic)
+	best    time.Duration // Lowest average import time observed
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newCacheTuner creates a tuner growing the dirty trie cache up to four times
+// its configured size.
+func newCacheTuner(chain cacheTuneChain, dirtyLimit int, enabled bool) *cacheTuner {
+	t := &cacheTuner{
+		chain:  chain,
+		memory: systemMemory,
+		max:    4 * dirtyLimit,
+		quit:   make(chan struct{}),
+	}
+	t.setEnabled(enabled)
+	return t
+}
+
+// Start launches the tuning loop.
+func (t *cacheTuner) Start() {
+	t.wg.Add(1)
+	go t.loop()
+}
+
+// Stop terminates the tuning loop.
+func (t *cacheTuner) Stop() {
+	close(t.quit)
+	t.wg.Wait()
+}
+
+// setEnabled switches the adaptive tuning on or off.
+func (t *cacheTuner) setEnabled(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&t.enabled, 1)
+	} else {
+		atomic.StoreInt32(&t.enabled, 0)
+	}
+}
+
+// isEnabled returns whether the cache is adapted.
+func (t *cacheTuner) isEnabled() bool {
+	return atomic.LoadInt32(&t.enabled) == 1
+}
+
+func (t *cacheTuner) loop() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(cacheTuneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !t.isEnabled() {
+				continue
+			}
+			if err := t.adjust(); err != nil {
+				log.Debug("Failed to tune trie cache", "err", err)
+			}
+		case <-t.quit:
+			return
+		}
+	}
+}
+
+// adjust applies the dirty trie cache limit suited to the current import time
+// and available memory.
+func (t *cacheTuner) adjust() error {
+	status, err := t.chain.CacheStatus()
+	if err != nil {
+		return err
+	}
+	total, available, err := t.memory()
+	if err != nil {
+		return err
+	}
+	limit := t.tune(status, total, available)
+	if limit == status.TrieDirtyLimit {
+		return nil
+	}
+	if limit > status.TrieDirtyLimit {
+		cacheGrowMeter.Mark(1)
+	} else {
+		cacheShrinkMeter.Mark(1)
+	}
+	log.Info("Tuning dirty trie cache", "limit", status.TrieDirtyLimit, "updated", limit,
+		"import", status.ImportTime, "available", available/1024/1024)
+	return t.chain.SetCacheLimits(limit, 0)
+}
+
+// tune returns the dirty trie cache limit (MB) to switch to.
+func (t *cacheTuner) tune(status core.CacheStatus, total, available uint64) int {
+	limit := status.TrieDirtyLimit
+	if status.ImportTime > 0 && (t.best == 0 || status.ImportTime < t.best) {
+		t.best = status.ImportTime
+	}
+	// Under memory pressure, give memory back regardless of the import time
+	if available < total/10 {
+		if shrunk := limit * 3 / 4; shrunk >= minTrieDirtyLimit {
+			return shrunk
+		}
+		if limit > minTrieDirtyLimit {
+			return minTrieDirtyLimit
+		}
+		return limit
+	}
+	// Grow the cache if it's flushing while imports slowed down, as long as
+	// memory is plentiful
+	full := uint64(status.TrieDirtySize) >= uint64(limit)*1024*1024*9/10
+	slow := status.ImportTime > t.best*5/4
+	if full && slow && available > total/4 && limit < t.max {
+		grown := limit * 5 / 4
+		if grown > t.max {
+			grown = t.max
+		}
+		if spare := limit + int(available/4/1024/1024); grown > spare {
+			grown = spare
+		}
+		return grown
+	}
+	return limit
+}
+
+// CacheConfigResult is the state of the in-memory caches of the chain.
+type CacheConfigResult struct {
+	TrieCleanLimit    int    `json:"trieCleanLimit"` // MB, fixed at startup
+	SnapshotLimit     int    `json:"snapshotLimit"`  // MB, fixed at startup
+	TrieDirtyLimit    int    `json:"trieDirtyLimit"` // MB
+	TrieTimeLimit     string `json:"trieTimeLimit"`
+	TrieDirtySize     string `json:"trieDirtySize"`
+	ImportTime        string `json:"importTime"`        // Moving average of the canonical block processing time
+	TrieDirtyAutoTune bool   `json:"trieDirtyAutoTune"` // Whether the dirty trie cache is adapted, the others never are
+}
+
+// CacheConfigArgs are the adjustments to the in-memory caches of the chain.
+// Unset fields are left unchanged.
+type CacheConfigArgs struct {
+	TrieDirtyLimit    *int    `json:"trieDirtyLimit"` // MB
+	TrieTimeLimit     *string `json:"trieTimeLimit"`  // Duration, e.g. "30m"
+	TrieDirtyAutoTune *bool   `json:"trieDirtyAutoTune"`
+}
+
+// errNoCacheTuner is returned when accessing the caches of a node without a
+// dirty trie cache.
+var errNoCacheTuner = errors.New("dirty trie cache not adjustable (archive node)")
+
+// CacheConfig returns the state of the in-memory caches of the chain.
+func (api *PrivateDebugAPI) CacheConfig() (*CacheConfigResult, error) {
+	status, err := api.eth.blockchain.CacheStatus()
+	if err != nil {
+		return nil, err
+	}
+	return &CacheConfigResult{
+		TrieCleanLimit:    status.TrieCleanLimit,
+		SnapshotLimit:     status.SnapshotLimit,
+		TrieDirtyLimit:    status.TrieDirtyLimit,
+		TrieTimeLimit:     status.TrieTimeLimit.String(),
+		TrieDirtySize:     status.TrieDirtySize.String(),
+		ImportTime:        status.ImportTime.String(),
+		TrieDirtyAutoTune: api.eth.cacheTuner != nil && api.eth.cacheTuner.isEnabled(),
+	}, nil
+}
+
+// SetCacheConfig adjusts the dirty trie cache limits and switches their adaptive
+// tuning on or off. Setting the dirty limit without enabling the tuning in the
+// same call switches the tuning off, so that it doesn't override the limit.
+func (api *PrivateDebugAPI) SetCacheConfig(args CacheConfigArgs) (*CacheConfigResult, error) {
+	if api.eth.cacheTuner == nil {
+		return nil, errNoCacheTuner
+	}
+	var (
+		dirty   int
+		timeout time.Duration
+	)
+	if args.TrieDirtyLimit != nil {
+		if *args.TrieDirtyLimit < minTrieDirtyLimit {
+			return nil, errors.New("dirty trie cache limit below minimum")
+		}
+		dirty = *args.TrieDirtyLimit
+	}
+	if args.TrieTimeLimit != nil {
+		d, err := time.ParseDuration(*args.TrieTimeLimit)
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, errors.New("non-positive trie time limit")
+		}
+		timeout = d
+	}
+	if err := api.eth.blockchain.SetCacheLimits(dirty, timeout); err != nil {
+		return nil, err
+	}
+	switch {
+	case args.TrieDirtyAutoTune != nil:
+		api.eth.cacheTuner.setEnabled(*args.TrieDirtyAutoTune)
+	case args.TrieDirtyLimit != nil:
+		api.eth.cacheTuner.setEnabled(false)
+	}
+	return api.CacheConfig()
+}
@@ -0,0 +1,62 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core"
+)
+
+// Tests that the dirty trie cache is shrunk under memory pressure, and grown
+// only when it's full, imports slowed down and memory is plentiful.
+func TestCacheTuning(t *testing.T) {
+	const gb = 1024 * 1024 * 1024
+
+	tuner := newCacheTuner(nil, 256, true)
+	status := core.CacheStatus{TrieDirtyLimit: 256, TrieDirtySize: 100 * 1024 * 1024, ImportTime: 100 * time.Millisecond}
+	if limit := tuner.tune(status, 16*gb, 8*gb); limit != 256 {
+		t.Fatalf("limit changed with cache room left: have %d, want 256", limit)
+	}
+	// A full cache with imports as fast as ever is left alone
+	status.TrieDirtySize = 250 * 1024 * 1024
+	if limit := tuner.tune(status, 16*gb, 8*gb); limit != 256 {
+		t.Fatalf("limit changed with fast imports: have %d, want 256", limit)
+	}
+	// Slower imports grow it, unless memory is scarce
+	status.ImportTime = 200 * time.Millisecond
+	if limit := tuner.tune(status, 16*gb, 8*gb); limit != 320 {
+		t.Fatalf("grown limit mismatch: have %d, want 320", limit)
+	}
+	if limit := tuner.tune(status, 16*gb, 3*gb); limit != 256 {
+		t.Fatalf("limit grown with scarce memory: have %d, want 256", limit)
+	}
+	// Memory pressure shrinks it down to the minimum
+	if limit := tuner.tune(status, 16*gb, gb); limit != 192 {
+		t.Fatalf("shrunk limit mismatch: have %d, want 192", limit)
+	}
+	status.TrieDirtyLimit = 80
+	if limit := tuner.tune(status, 16*gb, gb); limit != minTrieDirtyLimit {
+		t.Fatalf("shrunk limit mismatch: have %d, want %d", limit, minTrieDirtyLimit)
+	}
+	// Growth is capped at four times the configured limit
+	status.TrieDirtyLimit, status.TrieDirtySize = 1000, 1000*1024*1024
+	if limit := tuner.tune(status, 16*gb, 8*gb); limit != 1024 {
+		t.Fatalf("capped limit mismatch: have %d, want 1024", limit)
+	}
+}
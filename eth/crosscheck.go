@@ -0,0 +1,355 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/protocols/mesh"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"golang.org/x/time/rate"
+)
+
+const (
+	snapshotCheckInterval = 10 * time.Minute // Interval of the background cross-check of the current epoch
+	snapshotCheckTimeout  = 5 * time.Second  // Time allowance for a peer to answer a snapshot digest query
+
+	snapshotServeInterval = time.Second // Average interval between snapshot digest queries served to a peer
+	snapshotServeBurst    = 8           // Snapshot digest queries served to a peer in a burst
+)
+
+var snapshotMismatchMeter = metrics.NewRegisteredMeter("eth/crosscheck/mismatches", nil)
+
+// SnapshotPeerStatus is the result of comparing the epoch snapshot digest of a
+// single validator mesh peer against the local one.
+type SnapshotPeerStatus struct {
+	ID       string      `json:"id"`
+	Name     string      `json:"name"`
+	Trusted  bool        `json:"trusted"`
+	Known    bool        `json:"known"` // Whether the peer has a snapshot for the epoch
+	Hash     common.Hash `json:"hash,omitempty"`
+	RLPHash  common.Hash `json:"rlpHash,omitempty"`
+	Diverged bool        `json:"diverged"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// SnapshotCheck reports how the locally derived snapshot of an epoch compares
+// to the ones derived by the connected validator mesh peers.
+type SnapshotCheck struct {
+	Epoch       hexutil.Uint64        `json:"epoch"`
+	Number      hexutil.Uint64        `json:"number"`  // Number of the local epoch block
+	Hash        common.Hash           `json:"hash"`    // Hash of the local epoch block
+	RLPHash     common.Hash           `json:"rlpHash"` // Hash of the local snapshot encoding
+	Peers       []*SnapshotPeerStatus `json:"peers"`
+	Agreeing    int                   `json:"agreeing"`
+	Disagreeing int                   `json:"disagreeing"`
+	Unreachable int                   `json:"unreachable"` // Peers which failed to answer or lack the snapshot
+}
+
+// meshPeer is a peer connected on the `mesh` protocol.
+type meshPeer struct {
+	*mesh.Peer
+	trusted bool
+	limiter *rate.Limiter // Rate limit of the snapshot digest queries served
+}
+
+// snapshotChecker implements the mesh.Backend interface to serve the digests of
+// the local epoch snapshots, and cross-checks them in the background against the
+// ones of the validator mesh peers. It also gossips the version attestations of
+// the signers across the mesh.
+type snapshotChecker struct {
+	engine   *clique.Clique
+	chain    *core.BlockChain
+	versions *versionTracker
+
+	peers map[string]*meshPeer
+	lock  sync.RWMutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newSnapshotChecker creates a cross-checker of the snapshots of the engine,
+// gossiping the version attestations collected by the tracker.
+func newSnapshotChecker(engine *clique.Clique, chain *core.BlockChain, versions *versionTracker) *snapshotChecker {
+	c := &snapshotChecker{
+		engine:   engine,
+		chain:    chain,
+		versions: versions,
+		peers:    make(map[string]*meshPeer),
+		quit:     make(chan struct{}),
+	}
+	versions.broadcast = c.broadcastVersion
+	return c
+}
+
+// EpochSnapshot retrieves the digest of the local snapshot of an epoch. It is
+// only served to trusted peers and to the ones whose node record proves them to
+// be run by a signer, within the per peer rate limit.
+func (c *snapshotChecker) EpochSnapshot(peer *mesh.Peer, epoch uint64) (uint64, common.Hash, common.Hash, bool) {
+	c.lock.RLock()
+	p := c.peers[peer.ID()]
+	c.lock.RUnlock()
+
+	if p == nil {
+		return 0, common.Hash{}, common.Hash{}, false
+	}
+	if !p.limiter.Allow() {
+		p.Log().Debug("Rate limiting epoch snapshot queries", "epoch", epoch)
+		return 0, common.Hash{}, common.Hash{}, false
+	}
+	if !c.serves(p) {
+		p.Log().Debug("Refusing epoch snapshot query from non-validator", "epoch", epoch)
+		return 0, common.Hash{}, common.Hash{}, false
+	}
+	digest, err := c.engine.EpochSnapshotDigest(c.chain, epoch)
+	if err != nil {
+		return 0, common.Hash{}, common.Hash{}, false
+	}
+	return digest.Number, digest.Hash, digest.RLPHash, true
+}
+
+// RunPeer is invoked when a peer joins on the `mesh` protocol.
+func (c *snapshotChecker) RunPeer(peer *mesh.Peer, hand mesh.Handler) error {
+	p := &meshPeer{
+		Peer:    peer,
+		limiter: rate.NewLimiter(rate.Every(snapshotServeInterval), snapshotServeBurst),
+	}
+	if peer.Peer != nil {
+		p.trusted = peer.Peer.Info().Network.Trusted
+	}
+	c.lock.Lock()
+	c.peers[peer.ID()] = p
+	c.lock.Unlock()
+
+	// Catch the peer up on the attested versions of the signers
+	go func() {
+		for _, attestation := range c.versions.known() {
+			if err := peer.SendVersionAttestation(attestation); err != nil {
+				return
+			}
+		}
+	}()
+	defer func() {
+		c.lock.Lock()
+		delete(c.peers, peer.ID())
+		c.lock.Unlock()
+	}()
+	return hand(peer)
+}
+
+// serves reports whether snapshot digests are served to a peer, it being trusted
+// or its node record proving it to be run by a signer at the chain head.
+func (c *snapshotChecker) serves(p *meshPeer) bool {
+	if p.trusted {
+		return true
+	}
+	if p.Peer.Peer == nil {
+		return false
+	}
+	signer, ok := nodeValidator(p.Node())
+	if !ok {
+		return false
+	}
+	authorized, err := c.engine.IsSigner(c.chain, signer)
+	return err == nil && authorized
+}
+
+// PeerInfo retrieves all known `mesh` information about a peer.
+func (c *snapshotChecker) PeerInfo(id enode.ID) interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if p, ok := c.peers[id.String()]; ok {
+		return map[string]interface{}{"version": p.Version()}
+	}
+	return nil
+}
+
+// HandleVersionAttestation is invoked when a peer gossips the attested client
+// version of a validator.
+func (c *snapshotChecker) HandleVersionAttestation(peer *mesh.Peer, attestation *mesh.VersionAttestationPacket) error {
+	return c.versions.handle(peer.ID(), attestation)
+}
+
+// broadcastVersion gossips a version attestation to all mesh peers but the one
+// it was received from.
+func (c *snapshotChecker) broadcastVersion(attestation *mesh.VersionAttestationPacket, origin string) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	for id, p := range c.peers {
+		if id == origin {
+			continue
+		}
+		go func(p *meshPeer) {
+			if err := p.SendVersionAttestation(attestation); err != nil {
+				p.Log().Debug("Failed to gossip version attestation", "err", err)
+			}
+		}(p)
+	}
+}
+
+// validatorPeers returns the trusted mesh peers if there are any, or all mesh
+// peers otherwise.
+func (c *snapshotChecker) validatorPeers() []*meshPeer {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var all, trusted []*meshPeer
+	for _, p := range c.peers {
+		all = append(all, p)
+		if p.trusted {
+			trusted = append(trusted, p)
+		}
+	}
+	if len(trusted) > 0 {
+		return trusted
+	}
+	return all
+}
+
+// crossCheck requests the digest of the snapshot of an epoch from the validator
+// mesh peers and compares them with the local one.
+func (c *snapshotChecker) crossCheck(epoch uint64) (*SnapshotCheck, error) {
+	local, err := c.engine.EpochSnapshotDigest(c.chain, epoch)
+	if err != nil {
+		return nil, err
+	}
+	peers := c.validatorPeers()
+
+	check := &SnapshotCheck{
+		Epoch:   hexutil.Uint64(epoch),
+		Number:  hexutil.Uint64(local.Number),
+		Hash:    local.Hash,
+		RLPHash: local.RLPHash,
+		Peers:   make([]*SnapshotPeerStatus, len(peers)),
+	}
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		go func(i int, peer *meshPeer) {
+			defer wg.Done()
+			check.Peers[i] = checkPeerSnapshot(peer, local)
+		}(i, peer)
+	}
+	wg.Wait()
+
+	for _, peer := range check.Peers {
+		switch {
+		case peer.Error != "" || !peer.Known:
+			check.Unreachable++
+		case peer.Diverged:
+			check.Disagreeing++
+		default:
+			check.Agreeing++
+		}
+	}
+	return check, nil
+}
+
+// checkPeerSnapshot retrieves the snapshot digest of a peer and compares it
+// with the local one.
+func checkPeerSnapshot(peer *meshPeer, local *clique.EpochSnapshotDigest) *SnapshotPeerStatus {
+	status := &SnapshotPeerStatus{
+		ID:      peer.ID(),
+		Trusted: peer.trusted,
+	}
+	if peer.Peer.Peer != nil {
+		status.Name = peer.Name()
+	}
+	res, err := peer.RequestEpochSnapshot(local.Epoch, snapshotCheckTimeout)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Known = res.Known
+	if res.Known {
+		status.Hash, status.RLPHash = res.Hash, res.RLPHash
+		status.Diverged = res.Hash != local.Hash || res.RLPHash != local.RLPHash
+	}
+	return status
+}
+
+// Start launches the background cross-check loop and the version attestations.
+func (c *snapshotChecker) Start() {
+	c.wg.Add(1)
+	go c.loop()
+	c.versions.start()
+}
+
+// Stop terminates the background cross-check loop and the version attestations.
+func (c *snapshotChecker) Stop() {
+	c.versions.stop()
+	close(c.quit)
+	c.wg.Wait()
+}
+
+func (c *snapshotChecker) loop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(snapshotCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			epoch, err := c.engine.CurrentEpoch(c.chain)
+			if err != nil {
+				log.Debug("Failed to retrieve current epoch", "err", err)
+				continue
+			}
+			check, err := c.crossCheck(epoch)
+			if err != nil {
+				log.Debug("Epoch snapshot cross-check skipped", "epoch", epoch, "err", err)
+				continue
+			}
+			if check.Disagreeing > 0 {
+				snapshotMismatchMeter.Mark(int64(check.Disagreeing))
+				for _, peer := range check.Peers {
+					if peer.Diverged {
+						log.Error("Validator peer derived a different epoch snapshot", "epoch", epoch, "number", check.Number,
+							"hash", check.Hash, "rlphash", check.RLPHash, "peer", peer.ID, "peerhash", peer.Hash, "peerrlphash", peer.RLPHash)
+					}
+				}
+			} else {
+				log.Debug("Cross-checked epoch snapshot", "epoch", epoch, "agreeing", check.Agreeing, "unreachable", check.Unreachable)
+			}
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// PrivateSnapshotCheckAPI exposes the cross-check of the epoch snapshots
+// against the validator mesh peers in the clique namespace.
+type PrivateSnapshotCheckAPI struct {
+	checker *snapshotChecker
+}
+
+// CrossCheckSnapshot requests the digest of the snapshot of an epoch from the
+// connected validator mesh peers and compares them with the local one.
+func (api *PrivateSnapshotCheckAPI) CrossCheckSnapshot(epoch hexutil.Uint64) (*SnapshotCheck, error) {
+	return api.checker.crossCheck(uint64(epoch))
+}
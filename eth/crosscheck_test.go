@@ -0,0 +1,51 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/eth/protocols/mesh"
+	"golang.org/x/time/rate"
+)
+
+// Tests that epoch snapshot digests are not served to unknown peers, peers not
+// proven to be validators, or peers exceeding their rate limit.
+func TestEpochSnapshotServing(t *testing.T) {
+	checker := &snapshotChecker{peers: make(map[string]*meshPeer)}
+
+	unknown := mesh.NewFakePeer(mesh.MESH2, "0101010101010101", nil)
+	if _, _, _, known := checker.EpochSnapshot(unknown, 1); known {
+		t.Errorf("snapshot served to unregistered peer")
+	}
+	stranger := mesh.NewFakePeer(mesh.MESH2, "0202020202020202", nil)
+	checker.peers[stranger.ID()] = &meshPeer{Peer: stranger, limiter: rate.NewLimiter(rate.Inf, 1)}
+	if _, _, _, known := checker.EpochSnapshot(stranger, 1); known {
+		t.Errorf("snapshot served to non-validator peer")
+	}
+	if checker.serves(checker.peers[stranger.ID()]) {
+		t.Errorf("non-validator peer served")
+	}
+	trusted := mesh.NewFakePeer(mesh.MESH2, "0303030303030303", nil)
+	checker.peers[trusted.ID()] = &meshPeer{Peer: trusted, trusted: true, limiter: rate.NewLimiter(0, 0)}
+	if !checker.serves(checker.peers[trusted.ID()]) {
+		t.Errorf("trusted peer not served")
+	}
+	if _, _, _, known := checker.EpochSnapshot(trusted, 1); known {
+		t.Errorf("snapshot served beyond rate limit")
+	}
+}
@@ -19,6 +19,7 @@ package downloader
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/event"
@@ -32,6 +33,9 @@ type PublicDownloaderAPI struct {
 	mux                       *event.TypeMux
 	installSyncSubscription   chan chan interface{}
 	uninstallSyncSubscription chan *uninstallSyncSubscriptionRequest
+
+	tracker      progressTracker // Sliding window of progress samples for rate estimates
+	progressFeed event.Feed      // Detailed progress reports emitted while syncing
 }
 
 // NewPublicDownloaderAPI create a new PublicDownloaderAPI. The API has an internal event loop that
@@ -57,10 +61,20 @@ func (api *PublicDownloaderAPI) eventLoop() {
 	var (
 		sub               = api.mux.Subscribe(StartEvent{}, DoneEvent{}, FailedEvent{})
 		syncSubscriptions = make(map[chan interface{}]struct{})
+		sampler           = time.NewTicker(progressSampleInterval)
 	)
+	defer sampler.Stop()
 
 	for {
 		select {
+		case <-sampler.C:
+			if !api.d.Synchronising() {
+				continue
+			}
+			sample, _, _ := api.d.sampleProgress()
+			api.tracker.add(sample)
+			api.progressFeed.Send(api.d.detailedProgress(&api.tracker))
+
 		case i := <-api.installSyncSubscription:
 			syncSubscriptions[i] = struct{}{}
 		case u := <-api.uninstallSyncSubscription:
@@ -78,9 +92,11 @@ func (api *PublicDownloaderAPI) eventLoop() {
 					Syncing: true,
 					Status:  api.d.Progress(),
 				}
+				api.tracker.reset()
 			case DoneEvent, FailedEvent:
 				notification = false
 			}
+			api.progressFeed.Send(api.d.detailedProgress(&api.tracker))
 			// broadcast
 			for c := range syncSubscriptions {
 				c <- notification
@@ -119,6 +135,41 @@ func (api *PublicDownloaderAPI) Syncing(ctx context.Context) (*rpc.Subscription,
 	return rpcSub, nil
 }
 
+// SyncProgressDetailed returns the progress of each phase of the running sync,
+// the download rates averaged over the last minute and the estimated time until
+// the sync completes.
+func (api *PublicDownloaderAPI) SyncProgressDetailed() *DetailedSyncProgress {
+	return api.d.detailedProgress(&api.tracker)
+}
+
+// SyncProgress sends a detailed progress report every few seconds while the
+// node is synchronising, and once when a sync cycle starts or ends.
+func (api *PublicDownloaderAPI) SyncProgress(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		reports := make(chan *DetailedSyncProgress, 16)
+		sub := api.progressFeed.Subscribe(reports)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case report := <-reports:
+				notifier.Notify(rpcSub.ID, report)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // SyncingResult provides information about the current synchronisation status for this node.
 type SyncingResult struct {
 	Syncing bool                  `json:"syncing"`
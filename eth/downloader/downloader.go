@@ -106,6 +106,7 @@ type Downloader struct {
 	// Statistics
 	syncStatsChainOrigin uint64       // Origin block number where syncing started at
 	syncStatsChainHeight uint64       // Highest block number known when syncing started
+	syncStatsHeaders     uint64       // Highest header number retrieved in the current sync
 	syncStatsLock        sync.RWMutex // Lock protecting the sync stats fields
 
 	lightchain LightChain
@@ -530,6 +531,7 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td, ttd *
 		d.syncStatsChainOrigin = origin
 	}
 	d.syncStatsChainHeight = height
+	d.syncStatsHeaders = origin
 	d.syncStatsLock.Unlock()
 
 	// Ensure our origin point is below any snap sync pivot point
@@ -1455,6 +1457,7 @@ func (d *Downloader) processHeaders(origin uint64, td, ttd *big.Int, beaconMode
 			if d.syncStatsChainHeight < origin {
 				d.syncStatsChainHeight = origin - 1
 			}
+			d.syncStatsHeaders = origin - 1
 			d.syncStatsLock.Unlock()
 
 			// Signal the content downloaders of the availablility of new tasks
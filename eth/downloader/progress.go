@@ -0,0 +1,237 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	progressSampleInterval = 3 * time.Second // Interval between progress samples while syncing
+	progressSamples        = 20              // Number of samples the download rates are averaged over
+)
+
+// progressSample is a snapshot of the sync counters at a point in time.
+type progressSample struct {
+	time       time.Time
+	headers    uint64
+	bodies     uint64
+	receipts   uint64
+	accounts   uint64
+	completion float64 // Fraction of the account hash space downloaded
+	healed     uint64
+}
+
+// sampleProgress takes a snapshot of the sync counters of each phase.
+func (d *Downloader) sampleProgress() (progressSample, SyncMode, uint64) {
+	d.syncStatsLock.RLock()
+	var (
+		headers = d.syncStatsHeaders
+		highest = d.syncStatsChainHeight
+	)
+	d.syncStatsLock.RUnlock()
+
+	mode := d.getMode()
+	sample := progressSample{
+		time:    time.Now(),
+		headers: headers,
+	}
+	if mode != LightSync {
+		// Content retrieval trails the headers by the tasks still queued up
+		pending := uint64(d.queue.PendingBodies())
+		if pending < headers {
+			sample.bodies = headers - pending
+		}
+		if mode == SnapSync {
+			if pending = uint64(d.queue.PendingReceipts()); pending < headers {
+				sample.receipts = headers - pending
+			}
+		} else if d.blockchain != nil {
+			// Full sync generates the receipts by executing the blocks
+			sample.receipts = d.blockchain.CurrentBlock().NumberU64()
+		}
+	}
+	if mode == SnapSync {
+		progress, _ := d.SnapSyncer.Progress()
+		sample.accounts = progress.AccountSynced
+		sample.healed = progress.TrienodeHealSynced
+		sample.completion = d.SnapSyncer.Completion()
+	}
+	return sample, mode, highest
+}
+
+// SyncPhaseProgress is the progress of a single phase of the synchronisation.
+type SyncPhaseProgress struct {
+	Current hexutil.Uint64  `json:"current"`
+	Target  hexutil.Uint64  `json:"target"`
+	Rate    float64         `json:"rate"` // Items processed per second, averaged over the last minute
+	ETA     *hexutil.Uint64 `json:"eta"`  // Estimated seconds until the phase completes, nil if unknown
+}
+
+// DetailedSyncProgress is the progress of each phase of a running sync along
+// with the download rates and an estimate of the remaining time.
+type DetailedSyncProgress struct {
+	Syncing       bool              `json:"syncing"`
+	Mode          string            `json:"mode"`
+	StartingBlock hexutil.Uint64    `json:"startingBlock"`
+	CurrentBlock  hexutil.Uint64    `json:"currentBlock"`
+	HighestBlock  hexutil.Uint64    `json:"highestBlock"`
+	Headers       SyncPhaseProgress `json:"headers"`
+	Bodies        SyncPhaseProgress `json:"bodies"`
+	Receipts      SyncPhaseProgress `json:"receipts"`
+	State         SyncPhaseProgress `json:"state"` // Accounts downloaded, the target is estimated from the hash space covered
+	Heal          SyncPhaseProgress `json:"heal"`  // Trie nodes healed, the target includes the pending ones
+	ETA           *hexutil.Uint64   `json:"eta"`   // Estimated seconds until the sync completes, nil if unknown
+}
+
+// progressTracker keeps a sliding window of progress samples to derive the
+// download rate of each sync phase.
+type progressTracker struct {
+	samples []progressSample
+	lock    sync.Mutex
+}
+
+// reset drops all samples, called when a new sync cycle starts.
+func (t *progressTracker) reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.samples = t.samples[:0]
+}
+
+// add appends a sample to the window, evicting the oldest one if full.
+func (t *progressTracker) add(sample progressSample) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.samples) == progressSamples {
+		t.samples = append(t.samples[:0], t.samples[1:]...)
+	}
+	t.samples = append(t.samples, sample)
+}
+
+// oldest returns the oldest sample in the window, if any.
+func (t *progressTracker) oldest() (progressSample, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.samples) == 0 {
+		return progressSample{}, false
+	}
+	return t.samples[0], true
+}
+
+// rate calculates the per second rate of a counter between two samples.
+func rate(from, to uint64, elapsed float64) float64 {
+	if elapsed <= 0 || to <= from {
+		return 0
+	}
+	return float64(to-from) / elapsed
+}
+
+// eta estimates the seconds remaining until the target is reached at the given
+// rate, or nil if the rate is unknown.
+func eta(current, target uint64, rate float64) *hexutil.Uint64 {
+	if current >= target {
+		zero := hexutil.Uint64(0)
+		return &zero
+	}
+	if rate <= 0 {
+		return nil
+	}
+	secs := hexutil.Uint64(float64(target-current) / rate)
+	return &secs
+}
+
+// phase assembles the progress of a single counter.
+func phase(current, target uint64, rate float64) SyncPhaseProgress {
+	return SyncPhaseProgress{
+		Current: hexutil.Uint64(current),
+		Target:  hexutil.Uint64(target),
+		Rate:    rate,
+		ETA:     eta(current, target, rate),
+	}
+}
+
+// detailedProgress combines the current sync counters with the rates observed
+// over the tracker's window into a detailed progress report.
+func (d *Downloader) detailedProgress(tracker *progressTracker) *DetailedSyncProgress {
+	var (
+		sample, mode, highest = d.sampleProgress()
+		progress              = d.Progress()
+		result                = &DetailedSyncProgress{
+			Syncing:       d.Synchronising(),
+			Mode:          mode.String(),
+			StartingBlock: hexutil.Uint64(progress.StartingBlock),
+			CurrentBlock:  hexutil.Uint64(progress.CurrentBlock),
+			HighestBlock:  hexutil.Uint64(progress.HighestBlock),
+		}
+		oldest, ok = tracker.oldest()
+		elapsed    float64
+	)
+	if !ok {
+		oldest = sample
+	}
+	elapsed = sample.time.Sub(oldest.time).Seconds()
+
+	result.Headers = phase(sample.headers, highest, rate(oldest.headers, sample.headers, elapsed))
+	if mode != LightSync {
+		result.Bodies = phase(sample.bodies, highest, rate(oldest.bodies, sample.bodies, elapsed))
+		result.Receipts = phase(sample.receipts, highest, rate(oldest.receipts, sample.receipts, elapsed))
+	}
+	if mode == SnapSync {
+		// The account count is unknown, extrapolate it from the hash space covered
+		// and base the time estimate on how fast the hash space is being covered.
+		state := phase(sample.accounts, sample.accounts, rate(oldest.accounts, sample.accounts, elapsed))
+		state.ETA = nil
+		if sample.completion > 0 {
+			state.Target = hexutil.Uint64(float64(sample.accounts) / sample.completion)
+		}
+		if sample.completion >= 1 {
+			zero := hexutil.Uint64(0)
+			state.ETA = &zero
+		} else if elapsed > 0 && sample.completion > oldest.completion {
+			secs := hexutil.Uint64((1 - sample.completion) / ((sample.completion - oldest.completion) / elapsed))
+			state.ETA = &secs
+		}
+		result.State = state
+
+		_, pending := d.SnapSyncer.Progress()
+		result.Heal = phase(sample.healed, sample.healed+pending.TrienodeHeal, rate(oldest.healed, sample.healed, elapsed))
+	}
+	// The sync completes with its slowest phase
+	if result.Syncing {
+		phases := []SyncPhaseProgress{result.Headers, result.Bodies, result.Receipts}
+		if mode == SnapSync {
+			phases = append(phases, result.State, result.Heal)
+		}
+		var slowest hexutil.Uint64
+		for _, p := range phases {
+			if p.Target > 0 && p.ETA == nil {
+				return result
+			}
+			if p.ETA != nil && *p.ETA > slowest {
+				slowest = *p.ETA
+			}
+		}
+		result.ETA = &slowest
+	}
+	return result
+}
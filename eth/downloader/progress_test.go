@@ -0,0 +1,60 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that the progress tracker only keeps the most recent samples and that
+// the phase estimates are derived from the rate over the window.
+func TestProgressTracker(t *testing.T) {
+	var (
+		tracker progressTracker
+		start   = time.Now()
+	)
+	if _, ok := tracker.oldest(); ok {
+		t.Fatalf("empty tracker returned a sample")
+	}
+	for i := 0; i < progressSamples+5; i++ {
+		tracker.add(progressSample{time: start.Add(time.Duration(i) * time.Second), headers: uint64(i * 100)})
+	}
+	oldest, ok := tracker.oldest()
+	if !ok || oldest.headers != 500 {
+		t.Fatalf("oldest sample mismatch: have %d, want %d", oldest.headers, 500)
+	}
+	tracker.reset()
+	if _, ok := tracker.oldest(); ok {
+		t.Fatalf("reset tracker returned a sample")
+	}
+	// 100 headers per second, 1000 remaining
+	p := phase(2000, 3000, rate(1000, 2000, 10))
+	if p.Rate != 100 {
+		t.Fatalf("rate mismatch: have %v, want %v", p.Rate, 100)
+	}
+	if p.ETA == nil || *p.ETA != 10 {
+		t.Fatalf("eta mismatch: have %v, want %v", p.ETA, 10)
+	}
+	// Stalled phases have no estimate, finished ones are done
+	if p := phase(2000, 3000, 0); p.ETA != nil {
+		t.Fatalf("stalled phase has eta %v", *p.ETA)
+	}
+	if p := phase(3000, 3000, 0); p.ETA == nil || *p.ETA != 0 {
+		t.Fatalf("finished phase eta mismatch: have %v, want 0", p.ETA)
+	}
+}
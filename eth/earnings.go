@@ -0,0 +1,201 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxEarningsBlocks is the maximum number of blocks a single earnings request
+// may span.
+const maxEarningsBlocks = 50000
+
+var (
+	errEarningsRange      = errors.New("earnings range needs either a block range or an epoch range")
+	errEarningsEpochRange = errors.New("epoch ranges require a clique chain")
+)
+
+// EarningsRange selects the blocks to compute the validator earnings over, either
+// by block numbers or by clique epochs.
+type EarningsRange struct {
+	FromBlock *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock   *rpc.BlockNumber `json:"toBlock"`
+	FromEpoch *hexutil.Uint64  `json:"fromEpoch"` // First epoch, starting after its epoch block
+	ToEpoch   *hexutil.Uint64  `json:"toEpoch"`   // Last epoch, ending with the epoch block closing it
+	Rewards   bool             `json:"rewards"`   // Whether to include the protocol rewards, which need the state of the blocks
+}
+
+// ValidatorEarnings is the income of a sealer over a range of blocks.
+type ValidatorEarnings struct {
+	Sealer  common.Address `json:"sealer"`
+	Blocks  hexutil.Uint64 `json:"blocks"`  // Blocks sealed in the range
+	Tips    *hexutil.Big   `json:"tips"`    // Priority fees (all fees before London) of the transactions sealed
+	Rewards *hexutil.Big   `json:"rewards"` // Protocol rewards credited, none on clique chains
+	Total   *hexutil.Big   `json:"total"`
+}
+
+// EarningsReport is the income of all sealers over a range of blocks.
+type EarningsReport struct {
+	FromBlock hexutil.Uint64       `json:"fromBlock"`
+	ToBlock   hexutil.Uint64       `json:"toBlock"`
+	Burnt     *hexutil.Big         `json:"burnt"`   // Base fees burnt over the range
+	Sealers   []*ValidatorEarnings `json:"sealers"` // Earnings per sealer in ascending address order
+}
+
+// GetValidatorEarnings sums up the priority fees, and the protocol rewards if
+// requested, earned by each sealer over a range of blocks or clique epochs. The
+// fees are derived from the receipts and effective gas prices of the blocks'
+// transactions, without tracing.
+func (api *PublicIssuanceAPI) GetValidatorEarnings(ctx context.Context, query EarningsRange) (*EarningsReport, error) {
+	from, to, err := api.earningsRange(query)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		burnt    = new(big.Int)
+		earnings = make(map[common.Address]*ValidatorEarnings)
+	)
+	earner := func(account common.Address) *ValidatorEarnings {
+		if earnings[account] == nil {
+			earnings[account] = &ValidatorEarnings{Sealer: account, Tips: new(hexutil.Big), Rewards: new(hexutil.Big)}
+		}
+		return earnings[account]
+	}
+	for number := from; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block := api.eth.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		receipts := api.eth.blockchain.GetReceiptsByHash(block.Hash())
+		if len(receipts) != len(block.Transactions()) {
+			return nil, fmt.Errorf("receipts of block #%d not found", number)
+		}
+		header := block.Header()
+		sealer, err := api.eth.engine.Author(header)
+		if err != nil {
+			return nil, err
+		}
+		blockBurnt, tips := blockFees(header, block.Transactions(), receipts)
+		burnt.Add(burnt, blockBurnt)
+
+		entry := earner(sealer)
+		entry.Blocks++
+		entry.Tips.ToInt().Add(entry.Tips.ToInt(), tips)
+
+		if query.Rewards && api.eth.cliqueEngine() == nil {
+			rewards, err := api.blockRewards(block, sealer)
+			if err != nil {
+				return nil, err
+			}
+			for account, reward := range rewards {
+				entry := earner(account)
+				entry.Rewards.ToInt().Add(entry.Rewards.ToInt(), reward)
+			}
+		}
+	}
+	report := &EarningsReport{
+		FromBlock: hexutil.Uint64(from),
+		ToBlock:   hexutil.Uint64(to),
+		Burnt:     (*hexutil.Big)(burnt),
+		Sealers:   make([]*ValidatorEarnings, 0, len(earnings)),
+	}
+	for _, entry := range earnings {
+		entry.Total = (*hexutil.Big)(new(big.Int).Add(entry.Tips.ToInt(), entry.Rewards.ToInt()))
+		report.Sealers = append(report.Sealers, entry)
+	}
+	sort.Slice(report.Sealers, func(i, j int) bool {
+		return bytes.Compare(report.Sealers[i].Sealer[:], report.Sealers[j].Sealer[:]) < 0
+	})
+	return report, nil
+}
+
+// earningsRange resolves the range of canonical blocks an earnings request
+// spans.
+func (api *PublicIssuanceAPI) earningsRange(query EarningsRange) (uint64, uint64, error) {
+	var (
+		blocks = query.FromBlock != nil || query.ToBlock != nil
+		epochs = query.FromEpoch != nil || query.ToEpoch != nil
+		head   = api.eth.blockchain.CurrentHeader().Number.Uint64()
+
+		from, to uint64
+	)
+	switch {
+	case blocks == epochs:
+		return 0, 0, errEarningsRange
+
+	case blocks:
+		resolve := func(number *rpc.BlockNumber, fallback uint64) (uint64, error) {
+			switch {
+			case number == nil:
+				return fallback, nil
+			case *number == rpc.PendingBlockNumber:
+				return 0, errPendingIssuance
+			case *number < 0:
+				return head, nil
+			}
+			return uint64(*number), nil
+		}
+		var err error
+		if from, err = resolve(query.FromBlock, 0); err != nil {
+			return 0, 0, err
+		}
+		if to, err = resolve(query.ToBlock, head); err != nil {
+			return 0, 0, err
+		}
+
+	default:
+		engine := api.eth.cliqueEngine()
+		if engine == nil {
+			return 0, 0, errEarningsEpochRange
+		}
+		if query.FromEpoch == nil || query.ToEpoch == nil {
+			return 0, 0, errors.New("epoch range needs both ends")
+		}
+		var err error
+		if from, _, _, err = engine.EpochSpan(api.eth.blockchain, uint64(*query.FromEpoch)); err != nil {
+			return 0, 0, err
+		}
+		if _, to, _, err = engine.EpochSpan(api.eth.blockchain, uint64(*query.ToEpoch)); err != nil {
+			return 0, 0, err
+		}
+	}
+	if from == 0 {
+		from = 1 // The genesis block isn't sealed by anyone
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("from block #%d after to block #%d", from, to)
+	}
+	if to > head {
+		return 0, 0, fmt.Errorf("block #%d not found", to)
+	}
+	if to-from >= maxEarningsBlocks {
+		return 0, 0, fmt.Errorf("range spans too many blocks, max %d", maxEarningsBlocks)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package epochstats
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// SealerStats are the blocks of an epoch sealed by a signer.
+type SealerStats struct {
+	Signer common.Address `json:"signer"`
+	Blocks hexutil.Uint64 `json:"blocks"`
+	Share  float64        `json:"share"` // Fraction of the blocks of the epoch rolled up
+}
+
+// EpochStats are the rollup of the blocks of an epoch.
+type EpochStats struct {
+	Epoch       hexutil.Uint64 `json:"epoch"`
+	FirstBlock  hexutil.Uint64 `json:"firstBlock"` // First block rolled up, later than the epoch start if enabled mid-epoch
+	LastBlock   hexutil.Uint64 `json:"lastBlock"`  // Last block rolled up
+	Closed      bool           `json:"closed"`     // Whether the epoch was closed by the last block
+	Indexed     hexutil.Uint64 `json:"indexed"`    // Number of the last block rolled up over all epochs
+	Blocks      hexutil.Uint64 `json:"blocks"`
+	GasUsed     hexutil.Uint64 `json:"gasUsed"`
+	Txs         hexutil.Uint64 `json:"txs"`
+	Senders     hexutil.Uint64 `json:"uniqueSenders"`
+	AvgFullness float64        `json:"avgFullness"` // Average fraction of the gas limit used by the blocks
+	Sealers     []SealerStats  `json:"sealers"`
+}
+
+// API exposes the epoch stats in the aks namespace.
+type API struct {
+	idx *Indexer
+}
+
+// NewAPI creates the epoch stats API.
+func NewAPI(idx *Indexer) *API {
+	return &API{idx: idx}
+}
+
+// GetEpochStats returns the rollup of the confirmed blocks of an epoch: the gas
+// used, transaction count, unique senders, average block fullness and the
+// blocks sealed by each signer.
+func (api *API) GetEpochStats(epoch uint64) (*EpochStats, error) {
+	stats := readEpochStats(api.idx.db, epoch)
+	if stats == nil {
+		return nil, fmt.Errorf("epoch %d not indexed", epoch)
+	}
+	indexed, _ := api.idx.Head()
+	result := &EpochStats{
+		Epoch:      hexutil.Uint64(epoch),
+		FirstBlock: hexutil.Uint64(stats.FirstBlock),
+		LastBlock:  hexutil.Uint64(stats.LastBlock),
+		Closed:     stats.Closed,
+		Indexed:    hexutil.Uint64(indexed),
+		Blocks:     hexutil.Uint64(stats.Blocks),
+		GasUsed:    hexutil.Uint64(stats.GasUsed),
+		Txs:        hexutil.Uint64(stats.Txs),
+		Senders:    hexutil.Uint64(stats.Senders),
+		Sealers:    make([]SealerStats, 0, len(stats.Sealers)),
+	}
+	if stats.Blocks > 0 {
+		result.AvgFullness = float64(stats.Fullness) / fullnessUnit / float64(stats.Blocks)
+	}
+	for _, sealer := range stats.Sealers {
+		result.Sealers = append(result.Sealers, SealerStats{
+			Signer: sealer.Signer,
+			Blocks: hexutil.Uint64(sealer.Blocks),
+			Share:  float64(sealer.Blocks) / float64(stats.Blocks),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,341 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package epochstats implements an indexer rolling up the gas usage, transaction
+// counts, unique senders, block fullness and sealers of the clique epochs.
+package epochstats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Confirmations is the number of blocks a block must be buried under before it
+// is rolled up. Deeper reorgs leave the stats of the dropped blocks behind.
+const Confirmations = 16
+
+// fullnessUnit is the fixed point unit the block fullness is accumulated in.
+const fullnessUnit = 1_000_000
+
+var (
+	statsPrefix  = []byte("aks-epochstats-")   // statsPrefix + epoch (uint64 big endian) -> RLP(epochStats)
+	senderPrefix = []byte("aks-epochsender-")  // senderPrefix + epoch (uint64 big endian) + sender -> nil, until the epoch closes
+	headKey      = []byte("AksEpochStatsHead") // RLP(indexHead)
+)
+
+// epochStats is the rollup of the blocks of an epoch indexed so far.
+type epochStats struct {
+	FirstBlock uint64 // First block rolled up, later than the epoch start if enabled mid-epoch
+	LastBlock  uint64 // Last block rolled up
+	Closed     bool   // Whether the last block is the epoch block closing the epoch
+	Blocks     uint64
+	GasUsed    uint64
+	Txs        uint64
+	Senders    uint64
+	Fullness   uint64 // Sum of the gas used over the gas limit of the blocks, in millionths
+	Sealers    []sealerCount
+}
+
+// sealerCount is the number of blocks of an epoch sealed by a signer.
+type sealerCount struct {
+	Signer common.Address
+	Blocks uint64
+}
+
+// indexHead is the progress of the indexer.
+type indexHead struct {
+	Number uint64 // Number of the last block rolled up
+	Epoch  uint64 // Epoch the blocks following it are sealed in
+}
+
+// statsKey = statsPrefix + epoch (uint64 big endian)
+func statsKey(epoch uint64) []byte {
+	key := make([]byte, len(statsPrefix)+8)
+	copy(key, statsPrefix)
+	binary.BigEndian.PutUint64(key[len(statsPrefix):], epoch)
+	return key
+}
+
+// sendersKey = senderPrefix + epoch (uint64 big endian)
+func sendersKey(epoch uint64) []byte {
+	key := make([]byte, len(senderPrefix)+8)
+	copy(key, senderPrefix)
+	binary.BigEndian.PutUint64(key[len(senderPrefix):], epoch)
+	return key
+}
+
+// senderKey = senderPrefix + epoch (uint64 big endian) + sender
+func senderKey(epoch uint64, sender common.Address) []byte {
+	return append(sendersKey(epoch), sender[:]...)
+}
+
+// readEpochStats retrieves the rollup of an epoch, or nil if none of its blocks
+// were indexed.
+func readEpochStats(db ethdb.KeyValueReader, epoch uint64) *epochStats {
+	blob, err := db.Get(statsKey(epoch))
+	if err != nil || len(blob) == 0 {
+		return nil
+	}
+	stats := new(epochStats)
+	if err := rlp.DecodeBytes(blob, stats); err != nil {
+		log.Error("Invalid epoch stats", "epoch", epoch, "err", err)
+		return nil
+	}
+	return stats
+}
+
+// readHead retrieves the progress of the indexer.
+func readHead(db ethdb.KeyValueReader) *indexHead {
+	blob, err := db.Get(headKey)
+	if err != nil || len(blob) == 0 {
+		return nil
+	}
+	head := new(indexHead)
+	if err := rlp.DecodeBytes(blob, head); err != nil {
+		log.Error("Invalid epoch stats head", "err", err)
+		return nil
+	}
+	return head
+}
+
+// Chain is the subset of the blockchain the indexer follows.
+type Chain interface {
+	consensus.ChainHeaderReader
+	GetBlockByNumber(number uint64) *types.Block
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Engine is the subset of the clique engine the indexer relies on.
+type Engine interface {
+	Author(header *types.Header) (common.Address, error)
+	EpochAt(chain consensus.ChainHeaderReader, number uint64) (uint64, error)
+}
+
+// Indexer rolls up the blocks of each epoch as they get confirmed. Only blocks
+// imported after the indexer was enabled are rolled up, there is no backfill
+// of the existing chain.
+type Indexer struct {
+	db     ethdb.Database
+	chain  Chain
+	engine Engine
+
+	head indexHead // Progress of the indexer
+	lock sync.RWMutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates an epoch stats indexer, resuming from the last rolled up block or,
+// on first use, starting at the confirmed part of the current chain.
+func New(db ethdb.Database, chain Chain, engine Engine) (*Indexer, error) {
+	head := readHead(db)
+	if head == nil {
+		head = new(indexHead)
+		if number := chain.CurrentHeader().Number.Uint64(); number > Confirmations {
+			head.Number = number - Confirmations
+		}
+		epoch, err := engine.EpochAt(chain, head.Number)
+		if err != nil {
+			return nil, err
+		}
+		head.Epoch = epoch
+
+		blob, err := rlp.EncodeToBytes(head)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.Put(headKey, blob); err != nil {
+			return nil, err
+		}
+		log.Info("Started epoch stats index", "from", head.Number+1, "epoch", head.Epoch)
+	}
+	return &Indexer{
+		db:     db,
+		chain:  chain,
+		engine: engine,
+		head:   *head,
+		quit:   make(chan struct{}),
+	}, nil
+}
+
+// Start launches the indexing loop.
+func (idx *Indexer) Start() {
+	idx.wg.Add(1)
+	go idx.loop()
+}
+
+// Stop terminates the indexing loop.
+func (idx *Indexer) Stop() {
+	close(idx.quit)
+	idx.wg.Wait()
+}
+
+// Head returns the number of the last rolled up block and the epoch the blocks
+// following it are sealed in.
+func (idx *Indexer) Head() (uint64, uint64) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	return idx.head.Number, idx.head.Epoch
+}
+
+func (idx *Indexer) loop() {
+	defer idx.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := idx.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	idx.catchUp(idx.chain.CurrentHeader().Number.Uint64())
+	for {
+		select {
+		case ev := <-heads:
+			idx.catchUp(ev.Block.NumberU64())
+		case <-sub.Err():
+			return
+		case <-idx.quit:
+			return
+		}
+	}
+}
+
+// catchUp rolls up all blocks confirmed by the given head.
+func (idx *Indexer) catchUp(head uint64) {
+	for {
+		number, _ := idx.Head()
+		if head <= Confirmations || number >= head-Confirmations {
+			return
+		}
+		select {
+		case <-idx.quit:
+			return
+		default:
+		}
+		block := idx.chain.GetBlockByNumber(number + 1)
+		if block == nil {
+			return
+		}
+		if err := idx.index(block); err != nil {
+			log.Error("Failed to roll up epoch stats", "number", number+1, "err", err)
+			return
+		}
+	}
+}
+
+// index rolls up a single block into the stats of its epoch. Epoch blocks are
+// sealed by the signers of the epoch they close, so they're rolled up into it.
+func (idx *Indexer) index(block *types.Block) error {
+	_, epoch := idx.Head()
+
+	sealer, err := idx.engine.Author(block.Header())
+	if err != nil {
+		return err
+	}
+	stats := readEpochStats(idx.db, epoch)
+	if stats == nil {
+		stats = &epochStats{FirstBlock: block.NumberU64()}
+	}
+	stats.LastBlock = block.NumberU64()
+	stats.Blocks++
+	stats.GasUsed += block.GasUsed()
+	stats.Txs += uint64(len(block.Transactions()))
+	if limit := block.GasLimit(); limit > 0 {
+		stats.Fullness += block.GasUsed() * fullnessUnit / limit
+	}
+	stats.addSealer(sealer)
+
+	next := indexHead{Number: block.NumberU64(), Epoch: epoch}
+	if number, _, ok := clique.EpochSigners(block.Header()); ok {
+		stats.Closed, next.Epoch = true, number
+	}
+	var (
+		signer  = types.MakeSigner(idx.chain.Config(), block.Number())
+		senders = make(map[common.Address]struct{})
+		batch   = idx.db.NewBatch()
+	)
+	for _, tx := range block.Transactions() {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+		if _, ok := senders[from]; ok {
+			continue
+		}
+		senders[from] = struct{}{}
+		if ok, _ := idx.db.Has(senderKey(epoch, from)); !ok {
+			stats.Senders++
+			if !stats.Closed {
+				batch.Put(senderKey(epoch, from), nil)
+			}
+		}
+	}
+	// The senders are only needed to tell new ones apart until the epoch closes
+	if stats.Closed {
+		it := idx.db.NewIterator(sendersKey(epoch), nil)
+		for it.Next() {
+			batch.Delete(it.Key())
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return err
+		}
+	}
+	blob, err := rlp.EncodeToBytes(stats)
+	if err != nil {
+		return err
+	}
+	batch.Put(statsKey(epoch), blob)
+
+	if blob, err = rlp.EncodeToBytes(&next); err != nil {
+		return err
+	}
+	batch.Put(headKey, blob)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	idx.lock.Lock()
+	idx.head = next
+	idx.lock.Unlock()
+	return nil
+}
+
+// addSealer counts a block sealed by the given signer, keeping the sealers in
+// ascending order.
+func (s *epochStats) addSealer(signer common.Address) {
+	i := sort.Search(len(s.Sealers), func(i int) bool {
+		return bytes.Compare(s.Sealers[i].Signer[:], signer[:]) >= 0
+	})
+	if i < len(s.Sealers) && s.Sealers[i].Signer == signer {
+		s.Sealers[i].Blocks++
+		return
+	}
+	s.Sealers = append(s.Sealers, sealerCount{})
+	copy(s.Sealers[i+1:], s.Sealers[i:])
+	s.Sealers[i] = sealerCount{Signer: signer, Blocks: 1}
+}
@@ -0,0 +1,143 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package epochstats
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testEngine attributes blocks to their coinbase and starts the chain in the
+// given epoch.
+type testEngine struct {
+	epoch uint64
+}
+
+func (e *testEngine) Author(header *types.Header) (common.Address, error) {
+	return header.Coinbase, nil
+}
+
+func (e *testEngine) EpochAt(chain consensus.ChainHeaderReader, number uint64) (uint64, error) {
+	return e.epoch, nil
+}
+
+// Tests that confirmed blocks get rolled up into the epoch they're sealed in,
+// the epoch blocks closing the running epoch.
+func TestEpochStats(t *testing.T) {
+	var (
+		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		key2, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = crypto.PubkeyToAddress(key2.PublicKey)
+		sealers = []common.Address{{0x01}, {0x02}}
+		funds   = big.NewInt(params.Ether)
+		config  = params.TestChainConfig
+		signer  = types.LatestSigner(config)
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &core.Genesis{
+			Config: config,
+			Alloc:  core.GenesisAlloc{addr1: {Balance: funds}, addr2: {Balance: funds}},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	// Block 10 is the epoch block closing epoch 3, announcing epoch 4
+	blocks, _ := core.GenerateChain(config, genesis, ethash.NewFaker(), db, 30, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(sealers[i%2])
+		if i == 9 {
+			extra := make([]byte, 32+2*common.AddressLength+65)
+			copy(extra[32:], sealers[0][:])
+			copy(extra[32+common.AddressLength:], sealers[1][:])
+			b.SetExtra(extra)
+			b.SetNonce(types.EncodeNonce(4))
+		}
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr1), common.Address{0xff}, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key1)
+		b.AddTx(tx)
+		if i >= 10 {
+			tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(addr2), common.Address{0xff}, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key2)
+			b.AddTx(tx)
+		}
+	})
+	chain, err := core.NewBlockChain(db, nil, config, ethash.NewFullFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	idx, err := New(db, chain, &testEngine{epoch: 3})
+	if err != nil {
+		t.Fatalf("failed to create indexer: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	idx.catchUp(chain.CurrentHeader().Number.Uint64())
+	if head, epoch := idx.Head(); head != 30-Confirmations || epoch != 4 {
+		t.Fatalf("indexed head mismatch: have %d in epoch %d, want %d in epoch 4", head, epoch, 30-Confirmations)
+	}
+	api := NewAPI(idx)
+
+	// Blocks 1-10 make up epoch 3 with a single sender, closed by block 10
+	stats, err := api.GetEpochStats(3)
+	if err != nil {
+		t.Fatalf("failed to query epoch 3: %v", err)
+	}
+	if stats.FirstBlock != 1 || stats.LastBlock != 10 || !stats.Closed || stats.Blocks != 10 || stats.Txs != 10 || stats.Senders != 1 {
+		t.Errorf("epoch 3 mismatch: %+v", stats)
+	}
+	it := db.NewIterator(sendersKey(3), nil)
+	if it.Next() {
+		t.Errorf("senders of closed epoch 3 left behind: %x", it.Key())
+	}
+	it.Release()
+	if uint64(stats.GasUsed) != 10*params.TxGas {
+		t.Errorf("epoch 3 gas mismatch: have %d, want %d", stats.GasUsed, 10*params.TxGas)
+	}
+	if len(stats.Sealers) != 2 || stats.Sealers[0].Blocks != 5 || stats.Sealers[1].Blocks != 5 || stats.Sealers[0].Share != 0.5 {
+		t.Errorf("epoch 3 sealers mismatch: %+v", stats.Sealers)
+	}
+	if want := float64(params.TxGas) / float64(genesis.GasLimit()); stats.AvgFullness <= 0 || stats.AvgFullness > 2*want {
+		t.Errorf("epoch 3 fullness mismatch: have %v, want about %v", stats.AvgFullness, want)
+	}
+	// Blocks 11-14 are confirmed in the running epoch 4 with two senders
+	stats, err = api.GetEpochStats(4)
+	if err != nil {
+		t.Fatalf("failed to query epoch 4: %v", err)
+	}
+	if stats.FirstBlock != 11 || stats.LastBlock != 14 || stats.Closed || stats.Blocks != 4 || stats.Txs != 8 || stats.Senders != 2 {
+		t.Errorf("epoch 4 mismatch: %+v", stats)
+	}
+	if _, err := api.GetEpochStats(5); err == nil {
+		t.Errorf("unindexed epoch returned stats")
+	}
+	// The progress survives a restart
+	if idx, err = New(db, chain, &testEngine{epoch: 7}); err != nil {
+		t.Fatalf("failed to reopen indexer: %v", err)
+	}
+	if head, epoch := idx.Head(); head != 30-Confirmations || epoch != 4 {
+		t.Fatalf("reopened head mismatch: have %d in epoch %d", head, epoch)
+	}
+}
@@ -31,9 +31,15 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/alerts"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/publisher"
+	"github.com/ethereum/go-ethereum/eth/replica"
+	"github.com/ethereum/go-ethereum/eth/sqlmirror"
+	"github.com/ethereum/go-ethereum/eth/txmgr"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/maintenance"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
@@ -83,12 +89,20 @@ var Defaults = Config{
 	TrieDirtyCache:          256,
 	TrieTimeout:             60 * time.Minute,
 	SnapshotCache:           102,
+	PreflightMaxLag:         16,
+	PreflightMinFreeDisk:    2048,
 	Miner: miner.Config{
 		GasCeil:  30000000,
 		GasPrice: big.NewInt(params.GWei),
 		Recommit: 3 * time.Second,
 	},
 	TxPool:        core.DefaultTxPoolConfig,
+	TxManager:     txmgr.DefaultConfig,
+	Publisher:     publisher.DefaultConfig,
+	SQLMirror:     sqlmirror.DefaultConfig,
+	Replica:       replica.DefaultConfig,
+	Alerts:        alerts.DefaultConfig,
+	CliqueClock:   clique.DefaultClockConfig,
 	RPCGasCap:     50000000,
 	RPCEVMTimeout: 5 * time.Second,
 	GPO:           FullNodeGPO,
@@ -124,6 +138,16 @@ type Config struct {
 	// If nil, the Ethereum main net block is used.
 	Genesis *core.Genesis `toml:",omitempty"`
 
+	// State overlay options. If an endpoint is set, state missing locally is
+	// fetched on demand from the remote node and, on an empty database, the
+	// genesis is forked off the remote state at StateOverlayBlock (or latest).
+	StateOverlay      string  `toml:",omitempty"`
+	StateOverlayBlock *uint64 `toml:",omitempty"`
+
+	// Enables the dev namespace to produce blocks on demand and to shift the
+	// chain clock on clique developer chains.
+	DevControls bool `toml:",omitempty"`
+
 	// Protocol options
 	NetworkId uint64 // Network ID to use for selecting peers to connect to
 	SyncMode  downloader.SyncMode
@@ -143,6 +167,64 @@ type Config struct {
 	// presence of these blocks for every new peer connection.
 	RequiredBlocks map[uint64]common.Hash `toml:"-"`
 
+	// StrictForkID makes the fork ID filter also reject peers whose upcoming fork
+	// differs from the local fork schedule, disconnecting incompatible builds
+	// before the fork is reached rather than after.
+	StrictForkID bool `toml:",omitempty"`
+
+	// HeadLagPeriods is the number of block periods without a new chain head,
+	// despite connected peers, after which the peers are rotated. Zero disables
+	// the watchdog, which only runs on clique chains with a fixed block period.
+	HeadLagPeriods uint64 `toml:",omitempty"`
+
+	// AdvertiseValidator enables proving the clique signer running the node in
+	// its node record once sealing starts, so peers can prioritize validators.
+	AdvertiseValidator bool `toml:",omitempty"`
+
+	// SentryNodes are the enode URLs of the sentries a validator peers with
+	// exclusively, relaying its blocks and transactions to the public network.
+	SentryNodes []string `toml:",omitempty"`
+
+	// SentryValidators are the enode URLs of the validators a sentry relays for,
+	// kept connected and sent every block and transaction in full.
+	SentryValidators []string `toml:",omitempty"`
+
+	// CliqueMaxFutureDrift is how far ahead of the local clock a clique block's
+	// timestamp may be before the block is rejected rather than scheduled. Zero
+	// keeps scheduling them as future blocks.
+	CliqueMaxFutureDrift time.Duration `toml:",omitempty"`
+
+	// CliqueTimestampJitter is how far ahead of the local clock a clique block's
+	// timestamp may be while still being accepted right away, absorbing the clock
+	// jitter between the signers.
+	CliqueTimestampJitter time.Duration `toml:",omitempty"`
+
+	// CliqueFastVerify is the trusted checkpoint below which the initial sync
+	// checks the seals of the canonical clique headers against the cached
+	// signer keys instead of recovering them, nil to always fully verify.
+	CliqueFastVerify *clique.FastVerifyCheckpoint `toml:",omitempty"`
+
+	// NoPreflight disables the startup checks holding back clique sealing until
+	// the node is synced, its clock and disk are healthy and its signer is
+	// authorized and protected against double sealing.
+	NoPreflight          bool   `toml:",omitempty"`
+	PreflightMaxLag      uint64 `toml:",omitempty"` // Blocks the node may trail its best peer by when sealing starts
+	PreflightMinFreeDisk uint64 `toml:",omitempty"` // Megabytes of free disk space required when sealing starts
+
+	// ContractAnalytics enables indexing the daily gas consumption, call counts
+	// and unique senders of contracts, served over the aks namespace.
+	ContractAnalytics bool `toml:",omitempty"`
+
+	// EpochStats enables rolling up the gas usage, transaction counts, unique
+	// senders, block fullness and sealers of the clique epochs, served over the
+	// aks namespace.
+	EpochStats bool `toml:",omitempty"`
+
+	// ExExSocket is the path of the unix socket execution extensions connect to
+	// for streaming the chain changes. Empty disables the extensions.
+	ExExSocket     string `toml:",omitempty"`
+	ExExStateDiffs bool   `toml:",omitempty"` // Whether to record the state diffs of committed blocks
+
 	// Light client options
 	LightServ          int  `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightIngress       int  `toml:",omitempty"` // Incoming bandwidth limit for light servers
@@ -168,6 +250,7 @@ type Config struct {
 	TrieCleanCacheRejournal time.Duration `toml:",omitempty"` // Time interval to regenerate the journal for clean cache
 	TrieDirtyCache          int
 	TrieTimeout             time.Duration
+	TrieDirtyAutoTune       bool `toml:",omitempty"` // Whether to adapt the dirty trie cache to the import time and available memory
 	SnapshotCache           int
 	Preimages               bool
 
@@ -180,6 +263,27 @@ type Config struct {
 	// Transaction pool options
 	TxPool core.TxPoolConfig
 
+	// Transaction resubmission options
+	TxManager txmgr.Config
+
+	// Message bus publisher options
+	Publisher publisher.Config
+
+	// SQL chain metadata mirror options
+	SQLMirror sqlmirror.Config
+
+	// Chain replication options
+	Replica replica.Config
+
+	// Background state maintenance scheduling options
+	Maintenance maintenance.Config
+
+	// Validator alerting options
+	Alerts alerts.Config
+
+	// Clique local clock check options
+	CliqueClock clique.ClockConfig
+
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
@@ -195,10 +299,23 @@ type Config struct {
 	// RPCEVMTimeout is the global timeout for eth-call.
 	RPCEVMTimeout time.Duration
 
+	// RPCTraceCache is the disk allowance in megabytes for caching the results
+	// of debug_traceTransaction. Zero disables the cache.
+	RPCTraceCache int `toml:",omitempty"`
+
 	// RPCTxFeeCap is the global transaction fee(price * gaslimit) cap for
 	// send-transction variants. The unit is ether.
 	RPCTxFeeCap float64
 
+	// RPCGasPriceFloor and RPCGasPriceCeil bound the gas price suggested by
+	// eth_gasPrice, for deployments running with subsidized or fixed-price gas.
+	RPCGasPriceFloor *big.Int `toml:",omitempty"`
+	RPCGasPriceCeil  *big.Int `toml:",omitempty"`
+
+	// RPCCliqueFields enriches block and header responses with the clique
+	// sealer and whether it sealed in turn, the miner field being always zero.
+	RPCCliqueFields bool `toml:",omitempty"`
+
 	// Checkpoint is a hardcoded checkpoint which can be nil.
 	Checkpoint *params.TrustedCheckpoint `toml:",omitempty"`
 
@@ -7,10 +7,17 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/alerts"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/publisher"
+	"github.com/ethereum/go-ethereum/eth/replica"
+	"github.com/ethereum/go-ethereum/eth/sqlmirror"
+	"github.com/ethereum/go-ethereum/eth/txmgr"
+	"github.com/ethereum/go-ethereum/internal/maintenance"
 	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/params"
 )
@@ -19,26 +26,44 @@ import (
 func (c Config) MarshalTOML() (interface{}, error) {
 	type Config struct {
 		Genesis                         *core.Genesis `toml:",omitempty"`
+		StateOverlay                    string        `toml:",omitempty"`
+		StateOverlayBlock               *uint64       `toml:",omitempty"`
+		DevControls                     bool          `toml:",omitempty"`
 		NetworkId                       uint64
 		SyncMode                        downloader.SyncMode
 		EthDiscoveryURLs                []string
 		SnapDiscoveryURLs               []string
 		NoPruning                       bool
 		NoPrefetch                      bool
-		TxLookupLimit                   uint64                 `toml:",omitempty"`
-		RequiredBlocks                  map[uint64]common.Hash `toml:"-"`
-		LightServ                       int                    `toml:",omitempty"`
-		LightIngress                    int                    `toml:",omitempty"`
-		LightEgress                     int                    `toml:",omitempty"`
-		LightPeers                      int                    `toml:",omitempty"`
-		LightNoPrune                    bool                   `toml:",omitempty"`
-		LightNoSyncServe                bool                   `toml:",omitempty"`
-		SyncFromCheckpoint              bool                   `toml:",omitempty"`
-		UltraLightServers               []string               `toml:",omitempty"`
-		UltraLightFraction              int                    `toml:",omitempty"`
-		UltraLightOnlyAnnounce          bool                   `toml:",omitempty"`
-		SkipBcVersionCheck              bool                   `toml:"-"`
-		DatabaseHandles                 int                    `toml:"-"`
+		TxLookupLimit                   uint64                       `toml:",omitempty"`
+		RequiredBlocks                  map[uint64]common.Hash       `toml:"-"`
+		StrictForkID                    bool                         `toml:",omitempty"`
+		HeadLagPeriods                  uint64                       `toml:",omitempty"`
+		AdvertiseValidator              bool                         `toml:",omitempty"`
+		SentryNodes                     []string                     `toml:",omitempty"`
+		SentryValidators                []string                     `toml:",omitempty"`
+		CliqueMaxFutureDrift            time.Duration                `toml:",omitempty"`
+		CliqueTimestampJitter           time.Duration                `toml:",omitempty"`
+		CliqueFastVerify                *clique.FastVerifyCheckpoint `toml:",omitempty"`
+		NoPreflight                     bool                         `toml:",omitempty"`
+		PreflightMaxLag                 uint64                       `toml:",omitempty"`
+		PreflightMinFreeDisk            uint64                       `toml:",omitempty"`
+		ContractAnalytics               bool                         `toml:",omitempty"`
+		EpochStats                      bool                         `toml:",omitempty"`
+		ExExSocket                      string                       `toml:",omitempty"`
+		ExExStateDiffs                  bool                         `toml:",omitempty"`
+		LightServ                       int                          `toml:",omitempty"`
+		LightIngress                    int                          `toml:",omitempty"`
+		LightEgress                     int                          `toml:",omitempty"`
+		LightPeers                      int                          `toml:",omitempty"`
+		LightNoPrune                    bool                         `toml:",omitempty"`
+		LightNoSyncServe                bool                         `toml:",omitempty"`
+		SyncFromCheckpoint              bool                         `toml:",omitempty"`
+		UltraLightServers               []string                     `toml:",omitempty"`
+		UltraLightFraction              int                          `toml:",omitempty"`
+		UltraLightOnlyAnnounce          bool                         `toml:",omitempty"`
+		SkipBcVersionCheck              bool                         `toml:"-"`
+		DatabaseHandles                 int                          `toml:"-"`
 		DatabaseCache                   int
 		DatabaseFreezer                 string
 		TrieCleanCache                  int
@@ -46,17 +71,29 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TrieCleanCacheRejournal         time.Duration `toml:",omitempty"`
 		TrieDirtyCache                  int
 		TrieTimeout                     time.Duration
+		TrieDirtyAutoTune               bool `toml:",omitempty"`
 		SnapshotCache                   int
 		Preimages                       bool
 		Miner                           miner.Config
 		Ethash                          ethash.Config
 		TxPool                          core.TxPoolConfig
+		TxManager                       txmgr.Config
+		Publisher                       publisher.Config
+		SQLMirror                       sqlmirror.Config
+		Replica                         replica.Config
+		Maintenance                     maintenance.Config
+		Alerts                          alerts.Config
+		CliqueClock                     clique.ClockConfig
 		GPO                             gasprice.Config
 		EnablePreimageRecording         bool
 		DocRoot                         string `toml:"-"`
 		RPCGasCap                       uint64
 		RPCEVMTimeout                   time.Duration
+		RPCTraceCache                   int `toml:",omitempty"`
 		RPCTxFeeCap                     float64
+		RPCGasPriceFloor                *big.Int                       `toml:",omitempty"`
+		RPCGasPriceCeil                 *big.Int                       `toml:",omitempty"`
+		RPCCliqueFields                 bool                           `toml:",omitempty"`
 		Checkpoint                      *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle                *params.CheckpointOracleConfig `toml:",omitempty"`
 		OverrideArrowGlacier            *big.Int                       `toml:",omitempty"`
@@ -64,6 +101,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
+	enc.StateOverlay = c.StateOverlay
+	enc.StateOverlayBlock = c.StateOverlayBlock
+	enc.DevControls = c.DevControls
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
 	enc.EthDiscoveryURLs = c.EthDiscoveryURLs
@@ -72,6 +112,21 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.NoPrefetch = c.NoPrefetch
 	enc.TxLookupLimit = c.TxLookupLimit
 	enc.RequiredBlocks = c.RequiredBlocks
+	enc.StrictForkID = c.StrictForkID
+	enc.HeadLagPeriods = c.HeadLagPeriods
+	enc.AdvertiseValidator = c.AdvertiseValidator
+	enc.SentryNodes = c.SentryNodes
+	enc.SentryValidators = c.SentryValidators
+	enc.CliqueMaxFutureDrift = c.CliqueMaxFutureDrift
+	enc.CliqueTimestampJitter = c.CliqueTimestampJitter
+	enc.CliqueFastVerify = c.CliqueFastVerify
+	enc.NoPreflight = c.NoPreflight
+	enc.PreflightMaxLag = c.PreflightMaxLag
+	enc.PreflightMinFreeDisk = c.PreflightMinFreeDisk
+	enc.ContractAnalytics = c.ContractAnalytics
+	enc.EpochStats = c.EpochStats
+	enc.ExExSocket = c.ExExSocket
+	enc.ExExStateDiffs = c.ExExStateDiffs
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
 	enc.LightEgress = c.LightEgress
@@ -91,17 +146,29 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TrieCleanCacheRejournal = c.TrieCleanCacheRejournal
 	enc.TrieDirtyCache = c.TrieDirtyCache
 	enc.TrieTimeout = c.TrieTimeout
+	enc.TrieDirtyAutoTune = c.TrieDirtyAutoTune
 	enc.SnapshotCache = c.SnapshotCache
 	enc.Preimages = c.Preimages
 	enc.Miner = c.Miner
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
+	enc.TxManager = c.TxManager
+	enc.Publisher = c.Publisher
+	enc.SQLMirror = c.SQLMirror
+	enc.Replica = c.Replica
+	enc.Maintenance = c.Maintenance
+	enc.Alerts = c.Alerts
+	enc.CliqueClock = c.CliqueClock
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.DocRoot = c.DocRoot
 	enc.RPCGasCap = c.RPCGasCap
 	enc.RPCEVMTimeout = c.RPCEVMTimeout
+	enc.RPCTraceCache = c.RPCTraceCache
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
+	enc.RPCGasPriceFloor = c.RPCGasPriceFloor
+	enc.RPCGasPriceCeil = c.RPCGasPriceCeil
+	enc.RPCCliqueFields = c.RPCCliqueFields
 	enc.Checkpoint = c.Checkpoint
 	enc.CheckpointOracle = c.CheckpointOracle
 	enc.OverrideArrowGlacier = c.OverrideArrowGlacier
@@ -113,26 +180,44 @@ func (c Config) MarshalTOML() (interface{}, error) {
 func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	type Config struct {
 		Genesis                         *core.Genesis `toml:",omitempty"`
+		StateOverlay                    *string       `toml:",omitempty"`
+		StateOverlayBlock               *uint64       `toml:",omitempty"`
+		DevControls                     *bool         `toml:",omitempty"`
 		NetworkId                       *uint64
 		SyncMode                        *downloader.SyncMode
 		EthDiscoveryURLs                []string
 		SnapDiscoveryURLs               []string
 		NoPruning                       *bool
 		NoPrefetch                      *bool
-		TxLookupLimit                   *uint64                `toml:",omitempty"`
-		RequiredBlocks                  map[uint64]common.Hash `toml:"-"`
-		LightServ                       *int                   `toml:",omitempty"`
-		LightIngress                    *int                   `toml:",omitempty"`
-		LightEgress                     *int                   `toml:",omitempty"`
-		LightPeers                      *int                   `toml:",omitempty"`
-		LightNoPrune                    *bool                  `toml:",omitempty"`
-		LightNoSyncServe                *bool                  `toml:",omitempty"`
-		SyncFromCheckpoint              *bool                  `toml:",omitempty"`
-		UltraLightServers               []string               `toml:",omitempty"`
-		UltraLightFraction              *int                   `toml:",omitempty"`
-		UltraLightOnlyAnnounce          *bool                  `toml:",omitempty"`
-		SkipBcVersionCheck              *bool                  `toml:"-"`
-		DatabaseHandles                 *int                   `toml:"-"`
+		TxLookupLimit                   *uint64                      `toml:",omitempty"`
+		RequiredBlocks                  map[uint64]common.Hash       `toml:"-"`
+		StrictForkID                    *bool                        `toml:",omitempty"`
+		HeadLagPeriods                  *uint64                      `toml:",omitempty"`
+		AdvertiseValidator              *bool                        `toml:",omitempty"`
+		SentryNodes                     []string                     `toml:",omitempty"`
+		SentryValidators                []string                     `toml:",omitempty"`
+		CliqueMaxFutureDrift            *time.Duration               `toml:",omitempty"`
+		CliqueTimestampJitter           *time.Duration               `toml:",omitempty"`
+		CliqueFastVerify                *clique.FastVerifyCheckpoint `toml:",omitempty"`
+		NoPreflight                     *bool                        `toml:",omitempty"`
+		PreflightMaxLag                 *uint64                      `toml:",omitempty"`
+		PreflightMinFreeDisk            *uint64                      `toml:",omitempty"`
+		ContractAnalytics               *bool                        `toml:",omitempty"`
+		EpochStats                      *bool                        `toml:",omitempty"`
+		ExExSocket                      *string                      `toml:",omitempty"`
+		ExExStateDiffs                  *bool                        `toml:",omitempty"`
+		LightServ                       *int                         `toml:",omitempty"`
+		LightIngress                    *int                         `toml:",omitempty"`
+		LightEgress                     *int                         `toml:",omitempty"`
+		LightPeers                      *int                         `toml:",omitempty"`
+		LightNoPrune                    *bool                        `toml:",omitempty"`
+		LightNoSyncServe                *bool                        `toml:",omitempty"`
+		SyncFromCheckpoint              *bool                        `toml:",omitempty"`
+		UltraLightServers               []string                     `toml:",omitempty"`
+		UltraLightFraction              *int                         `toml:",omitempty"`
+		UltraLightOnlyAnnounce          *bool                        `toml:",omitempty"`
+		SkipBcVersionCheck              *bool                        `toml:"-"`
+		DatabaseHandles                 *int                         `toml:"-"`
 		DatabaseCache                   *int
 		DatabaseFreezer                 *string
 		TrieCleanCache                  *int
@@ -140,17 +225,29 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TrieCleanCacheRejournal         *time.Duration `toml:",omitempty"`
 		TrieDirtyCache                  *int
 		TrieTimeout                     *time.Duration
+		TrieDirtyAutoTune               *bool `toml:",omitempty"`
 		SnapshotCache                   *int
 		Preimages                       *bool
 		Miner                           *miner.Config
 		Ethash                          *ethash.Config
 		TxPool                          *core.TxPoolConfig
+		TxManager                       *txmgr.Config
+		Publisher                       *publisher.Config
+		SQLMirror                       *sqlmirror.Config
+		Replica                         *replica.Config
+		Maintenance                     *maintenance.Config
+		Alerts                          *alerts.Config
+		CliqueClock                     *clique.ClockConfig
 		GPO                             *gasprice.Config
 		EnablePreimageRecording         *bool
 		DocRoot                         *string `toml:"-"`
 		RPCGasCap                       *uint64
 		RPCEVMTimeout                   *time.Duration
+		RPCTraceCache                   *int `toml:",omitempty"`
 		RPCTxFeeCap                     *float64
+		RPCGasPriceFloor                *big.Int                       `toml:",omitempty"`
+		RPCGasPriceCeil                 *big.Int                       `toml:",omitempty"`
+		RPCCliqueFields                 *bool                          `toml:",omitempty"`
 		Checkpoint                      *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle                *params.CheckpointOracleConfig `toml:",omitempty"`
 		OverrideArrowGlacier            *big.Int                       `toml:",omitempty"`
@@ -163,6 +260,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.Genesis != nil {
 		c.Genesis = dec.Genesis
 	}
+	if dec.StateOverlay != nil {
+		c.StateOverlay = *dec.StateOverlay
+	}
+	if dec.StateOverlayBlock != nil {
+		c.StateOverlayBlock = dec.StateOverlayBlock
+	}
+	if dec.DevControls != nil {
+		c.DevControls = *dec.DevControls
+	}
 	if dec.NetworkId != nil {
 		c.NetworkId = *dec.NetworkId
 	}
@@ -187,6 +293,51 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.RequiredBlocks != nil {
 		c.RequiredBlocks = dec.RequiredBlocks
 	}
+	if dec.StrictForkID != nil {
+		c.StrictForkID = *dec.StrictForkID
+	}
+	if dec.HeadLagPeriods != nil {
+		c.HeadLagPeriods = *dec.HeadLagPeriods
+	}
+	if dec.AdvertiseValidator != nil {
+		c.AdvertiseValidator = *dec.AdvertiseValidator
+	}
+	if dec.SentryNodes != nil {
+		c.SentryNodes = dec.SentryNodes
+	}
+	if dec.SentryValidators != nil {
+		c.SentryValidators = dec.SentryValidators
+	}
+	if dec.CliqueMaxFutureDrift != nil {
+		c.CliqueMaxFutureDrift = *dec.CliqueMaxFutureDrift
+	}
+	if dec.CliqueTimestampJitter != nil {
+		c.CliqueTimestampJitter = *dec.CliqueTimestampJitter
+	}
+	if dec.CliqueFastVerify != nil {
+		c.CliqueFastVerify = dec.CliqueFastVerify
+	}
+	if dec.NoPreflight != nil {
+		c.NoPreflight = *dec.NoPreflight
+	}
+	if dec.PreflightMaxLag != nil {
+		c.PreflightMaxLag = *dec.PreflightMaxLag
+	}
+	if dec.PreflightMinFreeDisk != nil {
+		c.PreflightMinFreeDisk = *dec.PreflightMinFreeDisk
+	}
+	if dec.ContractAnalytics != nil {
+		c.ContractAnalytics = *dec.ContractAnalytics
+	}
+	if dec.EpochStats != nil {
+		c.EpochStats = *dec.EpochStats
+	}
+	if dec.ExExSocket != nil {
+		c.ExExSocket = *dec.ExExSocket
+	}
+	if dec.ExExStateDiffs != nil {
+		c.ExExStateDiffs = *dec.ExExStateDiffs
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
@@ -244,6 +395,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TrieTimeout != nil {
 		c.TrieTimeout = *dec.TrieTimeout
 	}
+	if dec.TrieDirtyAutoTune != nil {
+		c.TrieDirtyAutoTune = *dec.TrieDirtyAutoTune
+	}
 	if dec.SnapshotCache != nil {
 		c.SnapshotCache = *dec.SnapshotCache
 	}
@@ -259,6 +413,27 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
+	if dec.TxManager != nil {
+		c.TxManager = *dec.TxManager
+	}
+	if dec.Publisher != nil {
+		c.Publisher = *dec.Publisher
+	}
+	if dec.SQLMirror != nil {
+		c.SQLMirror = *dec.SQLMirror
+	}
+	if dec.Replica != nil {
+		c.Replica = *dec.Replica
+	}
+	if dec.Maintenance != nil {
+		c.Maintenance = *dec.Maintenance
+	}
+	if dec.Alerts != nil {
+		c.Alerts = *dec.Alerts
+	}
+	if dec.CliqueClock != nil {
+		c.CliqueClock = *dec.CliqueClock
+	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
@@ -274,9 +449,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.RPCEVMTimeout != nil {
 		c.RPCEVMTimeout = *dec.RPCEVMTimeout
 	}
+	if dec.RPCTraceCache != nil {
+		c.RPCTraceCache = *dec.RPCTraceCache
+	}
 	if dec.RPCTxFeeCap != nil {
 		c.RPCTxFeeCap = *dec.RPCTxFeeCap
 	}
+	if dec.RPCGasPriceFloor != nil {
+		c.RPCGasPriceFloor = dec.RPCGasPriceFloor
+	}
+	if dec.RPCGasPriceCeil != nil {
+		c.RPCGasPriceCeil = dec.RPCGasPriceCeil
+	}
+	if dec.RPCCliqueFields != nil {
+		c.RPCCliqueFields = *dec.RPCCliqueFields
+	}
 	if dec.Checkpoint != nil {
 		c.Checkpoint = dec.Checkpoint
 	}
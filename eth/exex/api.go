@@ -0,0 +1,66 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package exex
+
+import (
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ExtensionInfo is the progress of a registered execution extension.
+type ExtensionInfo struct {
+	Name      string         `json:"name"`
+	Connected bool           `json:"connected"`
+	Acked     hexutil.Uint64 `json:"acked"`   // Sequence number of the last notification acknowledged
+	Pending   hexutil.Uint64 `json:"pending"` // Number of notifications not yet acknowledged
+}
+
+// PrivateExExAPI exposes the management of the execution extensions.
+type PrivateExExAPI struct {
+	m *Manager
+}
+
+// NewPrivateExExAPI creates the execution extension management API.
+func NewPrivateExExAPI(m *Manager) *PrivateExExAPI {
+	return &PrivateExExAPI{m: m}
+}
+
+// Extensions returns the progress of all registered execution extensions.
+func (api *PrivateExExAPI) Extensions() []*ExtensionInfo {
+	api.m.lock.Lock()
+	defer api.m.lock.Unlock()
+
+	infos := make([]*ExtensionInfo, 0, len(api.m.cursors))
+	for name, cursor := range api.m.cursors {
+		_, connected := api.m.conns[name]
+		infos = append(infos, &ExtensionInfo{
+			Name:      name,
+			Connected: connected,
+			Acked:     hexutil.Uint64(cursor),
+			Pending:   hexutil.Uint64(api.m.head.Seq - cursor),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Remove unregisters an execution extension, releasing the notifications
+// retained for it. It starts from the current head if it registers again.
+func (api *PrivateExExAPI) Remove(name string) error {
+	return api.m.remove(name)
+}
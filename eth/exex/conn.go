@@ -0,0 +1,189 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package exex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// helloTimeout is the time an extension has to introduce itself after connecting.
+const helloTimeout = 10 * time.Second
+
+// Request is a message sent by an extension to the node. The first message on
+// a connection must be a hello, every later one an ack.
+type Request struct {
+	Method     string `json:"method"`               // "hello" or "ack"
+	Name       string `json:"name,omitempty"`       // Name the extension's progress is tracked by, in hello
+	StateDiffs bool   `json:"stateDiffs,omitempty"` // Whether to stream the recorded state diffs, in hello
+	Seq        uint64 `json:"seq,omitempty"`        // Last notification fully processed, in ack
+}
+
+// Notification is a message sent by the node to an extension. Every connection
+// starts with a welcome carrying the last acknowledged sequence number, followed
+// by the chain notifications in order. Acknowledgements are cumulative, any
+// unacknowledged notification is redelivered on reconnect.
+type Notification struct {
+	Type       string          `json:"type"` // "welcome", "committed", "reverted" or "error"
+	Seq        uint64          `json:"seq"`
+	Blocks     json.RawMessage `json:"blocks,omitempty"`     // Blocks with their receipts, see Block
+	StateDiffs json.RawMessage `json:"stateDiffs,omitempty"` // State diffs indexed like the blocks, null if unavailable
+	Error      string          `json:"error,omitempty"`
+}
+
+// conn is a connected execution extension.
+type conn struct {
+	rw   net.Conn
+	name string
+	sent uint64 // Sequence number of the last notification sent, atomically accessed
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// close disconnects the extension.
+func (c *conn) close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.rw.Close()
+	})
+}
+
+// accept serves the incoming extension connections.
+func (m *Manager) accept() {
+	defer m.wg.Done()
+
+	for {
+		rw, err := m.listener.Accept()
+		if err != nil {
+			select {
+			case <-m.quit:
+			default:
+				log.Error("Execution extension socket failed", "err", err)
+			}
+			return
+		}
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.serve(rw)
+		}()
+	}
+}
+
+// serve runs the protocol with a single extension until it disconnects.
+func (m *Manager) serve(rw net.Conn) {
+	var (
+		c   = &conn{rw: rw, closed: make(chan struct{})}
+		dec = json.NewDecoder(rw)
+		enc = json.NewEncoder(rw)
+	)
+	defer c.close()
+
+	var hello Request
+	rw.SetReadDeadline(time.Now().Add(helloTimeout))
+	if err := dec.Decode(&hello); err != nil {
+		log.Debug("Execution extension handshake failed", "err", err)
+		return
+	}
+	rw.SetReadDeadline(time.Time{})
+	if hello.Method != "hello" || hello.Name == "" {
+		enc.Encode(&Notification{Type: "error", Error: "expected hello with name"})
+		return
+	}
+	cursor, err := m.register(hello.Name, c)
+	if err != nil {
+		enc.Encode(&Notification{Type: "error", Error: err.Error()})
+		return
+	}
+	defer m.unregister(hello.Name, c)
+
+	c.name = hello.Name
+	c.sent = cursor
+	if err := enc.Encode(&Notification{Type: "welcome", Seq: cursor}); err != nil {
+		return
+	}
+	log.Info("Execution extension connected", "name", c.name, "seq", cursor)
+	defer log.Info("Execution extension disconnected", "name", c.name)
+
+	m.wg.Add(1)
+	go m.readAcks(c, dec)
+
+	for {
+		m.lock.Lock()
+		head, wake := m.head.Seq, m.wake
+		m.lock.Unlock()
+
+		if seq := atomic.LoadUint64(&c.sent); seq < head {
+			entry, err := m.readEntry(seq + 1)
+			if err != nil {
+				log.Error("Missing execution extension notification", "seq", seq+1, "err", err)
+				return
+			}
+			msg := &Notification{Type: entry.Type, Seq: seq + 1, Blocks: entry.Blocks}
+			if hello.StateDiffs && len(entry.Diffs) > 0 {
+				msg.StateDiffs = entry.Diffs
+			}
+			if err := enc.Encode(msg); err != nil {
+				return
+			}
+			atomic.StoreUint64(&c.sent, seq+1)
+			continue
+		}
+		select {
+		case <-wake:
+		case <-c.closed:
+			return
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// readAcks processes the acknowledgements of an extension, disconnecting it on
+// any protocol violation.
+func (m *Manager) readAcks(c *conn, dec *json.Decoder) {
+	defer m.wg.Done()
+	defer c.close()
+
+	for {
+		var req Request
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		var err error
+		switch {
+		case req.Method != "ack":
+			err = fmt.Errorf("unexpected method %q", req.Method)
+		case req.Seq > atomic.LoadUint64(&c.sent):
+			err = errors.New("ack of notification not yet sent")
+		default:
+			err = m.ack(c.name, req.Seq)
+		}
+		if err != nil {
+			log.Warn("Dropping misbehaving execution extension", "name", c.name, "err", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,532 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package exex implements execution extensions: external processes connected
+// over a local socket which are streamed the chain segments committed to and
+// reverted from the canonical chain, in order.
+//
+// Notifications are journalled to disk and retained until every registered
+// extension acknowledged them, so an extension which disconnects or crashes
+// resumes exactly where it left off.
+package exex
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// maxSegmentBlocks is the maximum number of blocks in a single notification,
+// longer segments are split into multiple ones.
+const maxSegmentBlocks = 64
+
+const (
+	Committed = "committed" // Blocks appended to the canonical chain, lowest first
+	Reverted  = "reverted"  // Blocks removed from the canonical chain, highest first
+)
+
+var (
+	walPrefix    = []byte("exex-wal-") // walPrefix + seq (uint64 big endian) -> RLP(walEntry)
+	cursorPrefix = []byte("exex-ack-") // cursorPrefix + name -> seq (uint64 big endian) acknowledged
+	headKey      = []byte("ExExHead")  // RLP(walHead)
+)
+
+var (
+	errDuplicateName = errors.New("extension already connected")
+	errUnknownName   = errors.New("unknown extension")
+	errClosed        = errors.New("execution extensions stopped")
+)
+
+// Block is a block along with its receipts as streamed to the extensions.
+type Block struct {
+	Header       *types.Header      `json:"header"`
+	Transactions types.Transactions `json:"transactions"`
+	Uncles       []*types.Header    `json:"uncles"`
+	Receipts     types.Receipts     `json:"receipts"`
+}
+
+// walEntry is a journalled notification.
+type walEntry struct {
+	Type   string
+	Blocks []byte // JSON encoded []*Block
+	Diffs  []byte // JSON encoded []*StateDiff, empty if not recorded
+}
+
+// walHead is the position of the notification journal.
+type walHead struct {
+	Seq    uint64      // Sequence number of the last notification
+	Number uint64      // Number of the last block notified
+	Hash   common.Hash // Hash of the last block notified
+}
+
+// walKey = walPrefix + seq (uint64 big endian)
+func walKey(seq uint64) []byte {
+	key := make([]byte, len(walPrefix)+8)
+	copy(key, walPrefix)
+	binary.BigEndian.PutUint64(key[len(walPrefix):], seq)
+	return key
+}
+
+// cursorKey = cursorPrefix + name
+func cursorKey(name string) []byte {
+	return append(append([]byte{}, cursorPrefix...), name...)
+}
+
+// Chain is the subset of the blockchain the extensions are notified of.
+type Chain interface {
+	CurrentBlock() *types.Block
+	GetHeader(hash common.Hash, number uint64) *types.Header
+	GetBlock(hash common.Hash, number uint64) *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetCanonicalHash(number uint64) common.Hash
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	StateCache() state.Database
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Manager journals the changes of the canonical chain and streams them to the
+// execution extensions connected to its socket.
+type Manager struct {
+	db         ethdb.Database
+	chain      Chain
+	endpoint   string // Path of the unix socket extensions connect to
+	stateDiffs bool   // Whether to record the state diffs of committed blocks
+
+	head    walHead
+	tail    uint64            // Sequence number of the oldest retained notification
+	cursors map[string]uint64 // Acknowledged sequence numbers of registered extensions
+	conns   map[string]*conn  // Connected extensions by name
+	wake    chan struct{}     // Closed and replaced when a notification is journalled
+	lock    sync.Mutex
+
+	listener net.Listener
+	quit     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New creates an execution extension manager listening on the given socket. On
+// first use the journal starts at the current head, there is no backfill.
+func New(db ethdb.Database, chain Chain, endpoint string, stateDiffs bool) *Manager {
+	m := &Manager{
+		db:         db,
+		chain:      chain,
+		endpoint:   endpoint,
+		stateDiffs: stateDiffs,
+		cursors:    make(map[string]uint64),
+		conns:      make(map[string]*conn),
+		wake:       make(chan struct{}),
+		quit:       make(chan struct{}),
+	}
+	if blob, err := db.Get(headKey); err == nil {
+		if err := rlp.DecodeBytes(blob, &m.head); err != nil {
+			log.Error("Invalid execution extension journal head", "err", err)
+		}
+	}
+	if m.head.Hash == (common.Hash{}) {
+		head := chain.CurrentBlock()
+		m.head.Number, m.head.Hash = head.NumberU64(), head.Hash()
+	}
+	m.tail = m.head.Seq + 1
+	it := db.NewIterator(walPrefix, nil)
+	if it.Next() && len(it.Key()) == len(walPrefix)+8 {
+		m.tail = binary.BigEndian.Uint64(it.Key()[len(walPrefix):])
+	}
+	it.Release()
+
+	it = db.NewIterator(cursorPrefix, nil)
+	for it.Next() {
+		if len(it.Value()) == 8 {
+			m.cursors[string(it.Key()[len(cursorPrefix):])] = binary.BigEndian.Uint64(it.Value())
+		}
+	}
+	it.Release()
+	return m
+}
+
+// Start opens the extension socket and starts journalling chain changes.
+func (m *Manager) Start() error {
+	if err := os.MkdirAll(filepath.Dir(m.endpoint), 0751); err != nil {
+		return err
+	}
+	os.Remove(m.endpoint)
+	listener, err := net.Listen("unix", m.endpoint)
+	if err != nil {
+		return err
+	}
+	os.Chmod(m.endpoint, 0600)
+	m.listener = listener
+
+	log.Info("Execution extension socket opened", "path", m.endpoint, "extensions", len(m.cursors), "retained", m.head.Seq+1-m.tail)
+	m.wg.Add(2)
+	go m.loop()
+	go m.accept()
+	return nil
+}
+
+// Stop closes the extension socket, disconnects all extensions and terminates
+// the journalling.
+func (m *Manager) Stop() {
+	close(m.quit)
+	if m.listener != nil {
+		m.listener.Close()
+	}
+
+	m.lock.Lock()
+	for _, c := range m.conns {
+		c.close()
+	}
+	m.lock.Unlock()
+
+	m.wg.Wait()
+	os.Remove(m.endpoint)
+}
+
+func (m *Manager) loop() {
+	defer m.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := m.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	m.advance(m.chain.CurrentBlock())
+	for {
+		select {
+		case ev := <-heads:
+			m.advance(ev.Block)
+		case <-sub.Err():
+			return
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// advance journals the blocks reverted and committed since the last notified
+// block, moving the journal to the given head.
+func (m *Manager) advance(head *types.Block) {
+	m.lock.Lock()
+	last, registered := m.head, len(m.cursors) > 0
+	m.lock.Unlock()
+
+	if last.Hash == head.Hash() {
+		return
+	}
+	// Nobody to retain the notifications for, just track the chain
+	if !registered {
+		m.lock.Lock()
+		m.head.Number, m.head.Hash = head.NumberU64(), head.Hash()
+		m.writeHead(m.db)
+		m.lock.Unlock()
+		return
+	}
+	// If the chain was simply extended, stream the new blocks from the database
+	// in segments, without loading the whole gap at once.
+	if head.NumberU64() > last.Number && m.chain.GetCanonicalHash(last.Number) == last.Hash {
+		parent := last.Hash
+		for number := last.Number + 1; number <= head.NumberU64(); {
+			var blocks []*types.Block
+			for ; number <= head.NumberU64() && len(blocks) < maxSegmentBlocks; number++ {
+				block := m.chain.GetBlockByNumber(number)
+				if block == nil || block.ParentHash() != parent {
+					break // Reorged meanwhile, the next head event sorts it out
+				}
+				blocks = append(blocks, block)
+				parent = block.Hash()
+			}
+			if len(blocks) == 0 {
+				return
+			}
+			if err := m.append(Committed, blocks); err != nil {
+				log.Error("Failed to journal committed blocks", "err", err)
+				return
+			}
+			select {
+			case <-m.quit:
+				return
+			default:
+			}
+		}
+		return
+	}
+	// Otherwise find the common ancestor of the old and new chain
+	oldBlock := m.chain.GetBlock(last.Hash, last.Number)
+	if oldBlock == nil {
+		log.Error("Last notified block missing, skipping to head", "number", last.Number, "hash", last.Hash)
+		m.lock.Lock()
+		m.head.Number, m.head.Hash = head.NumberU64(), head.Hash()
+		m.writeHead(m.db)
+		m.lock.Unlock()
+		return
+	}
+	var (
+		newBlock  = head
+		reverted  []*types.Block
+		committed []*types.Block
+	)
+	for oldBlock != nil && oldBlock.NumberU64() > newBlock.NumberU64() {
+		reverted = append(reverted, oldBlock)
+		oldBlock = m.chain.GetBlock(oldBlock.ParentHash(), oldBlock.NumberU64()-1)
+	}
+	for newBlock != nil && oldBlock != nil && newBlock.NumberU64() > oldBlock.NumberU64() {
+		committed = append(committed, newBlock)
+		newBlock = m.chain.GetBlock(newBlock.ParentHash(), newBlock.NumberU64()-1)
+	}
+	for oldBlock != nil && newBlock != nil && oldBlock.Hash() != newBlock.Hash() {
+		reverted = append(reverted, oldBlock)
+		committed = append(committed, newBlock)
+		oldBlock = m.chain.GetBlock(oldBlock.ParentHash(), oldBlock.NumberU64()-1)
+		newBlock = m.chain.GetBlock(newBlock.ParentHash(), newBlock.NumberU64()-1)
+	}
+	if oldBlock == nil || newBlock == nil {
+		log.Error("Invalid reorg, missing ancestor", "old", last.Hash, "new", head.Hash())
+		return
+	}
+	for len(reverted) > 0 {
+		n := len(reverted)
+		if n > maxSegmentBlocks {
+			n = maxSegmentBlocks
+		}
+		if err := m.append(Reverted, reverted[:n]); err != nil {
+			log.Error("Failed to journal reverted blocks", "err", err)
+			return
+		}
+		reverted = reverted[n:]
+	}
+	for len(committed) > 0 {
+		// Committed blocks were gathered highest first, stream them lowest first
+		n := len(committed)
+		if n > maxSegmentBlocks {
+			n = maxSegmentBlocks
+		}
+		segment := make([]*types.Block, n)
+		for i := range segment {
+			segment[i] = committed[len(committed)-1-i]
+		}
+		if err := m.append(Committed, segment); err != nil {
+			log.Error("Failed to journal committed blocks", "err", err)
+			return
+		}
+		committed = committed[:len(committed)-n]
+	}
+}
+
+// append journals a notification and wakes up the connected extensions.
+func (m *Manager) append(typ string, blocks []*types.Block) error {
+	data := make([]*Block, len(blocks))
+	for i, block := range blocks {
+		data[i] = &Block{
+			Header:       block.Header(),
+			Transactions: block.Transactions(),
+			Uncles:       block.Uncles(),
+			Receipts:     m.chain.GetReceiptsByHash(block.Hash()),
+		}
+	}
+	entry := walEntry{Type: typ}
+
+	var err error
+	if entry.Blocks, err = json.Marshal(data); err != nil {
+		return err
+	}
+	if m.stateDiffs && typ == Committed {
+		// State diffs are best effort, the parent state may be gone already when
+		// importing large batches. Their index matches the blocks.
+		diffs := make([]*StateDiff, len(blocks))
+		for i, block := range blocks {
+			parent := m.chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+			if parent == nil {
+				continue
+			}
+			if diffs[i], err = diffState(m.chain.StateCache(), parent.Root, block.Root()); err != nil {
+				log.Debug("State diff unavailable", "number", block.Number(), "hash", block.Hash(), "err", err)
+			}
+		}
+		if entry.Diffs, err = json.Marshal(diffs); err != nil {
+			return err
+		}
+	}
+	blob, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	last := blocks[len(blocks)-1]
+	head := walHead{Seq: m.head.Seq + 1, Number: last.NumberU64(), Hash: last.Hash()}
+	if typ == Reverted {
+		head.Number, head.Hash = last.NumberU64()-1, last.ParentHash()
+	}
+	batch := m.db.NewBatch()
+	batch.Put(walKey(head.Seq), blob)
+	m.head = head
+	m.writeHead(batch)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	close(m.wake)
+	m.wake = make(chan struct{})
+	return nil
+}
+
+// writeHead persists the journal head. The caller must hold the lock.
+func (m *Manager) writeHead(db ethdb.KeyValueWriter) {
+	blob, err := rlp.EncodeToBytes(m.head)
+	if err != nil {
+		log.Crit("Failed to encode execution extension head", "err", err)
+	}
+	if err := db.Put(headKey, blob); err != nil {
+		log.Crit("Failed to store execution extension head", "err", err)
+	}
+}
+
+// readEntry retrieves a journalled notification.
+func (m *Manager) readEntry(seq uint64) (*walEntry, error) {
+	blob, err := m.db.Get(walKey(seq))
+	if err != nil {
+		return nil, err
+	}
+	entry := new(walEntry)
+	if err := rlp.DecodeBytes(blob, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// register marks an extension connected, returning the sequence number of the
+// last notification it acknowledged. New extensions start at the journal head.
+func (m *Manager) register(name string, c *conn) (uint64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	select {
+	case <-m.quit:
+		return 0, errClosed
+	default:
+	}
+	if _, ok := m.conns[name]; ok {
+		return 0, errDuplicateName
+	}
+	cursor, ok := m.cursors[name]
+	if !ok {
+		cursor = m.head.Seq
+		if err := m.writeCursor(name, cursor); err != nil {
+			return 0, err
+		}
+		log.Info("Registered execution extension", "name", name, "seq", cursor)
+	}
+	m.conns[name] = c
+	return cursor, nil
+}
+
+// unregister marks an extension disconnected, retaining its notifications.
+func (m *Manager) unregister(name string, c *conn) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.conns[name] == c {
+		delete(m.conns, name)
+	}
+}
+
+// ack moves the acknowledged position of an extension and drops the
+// notifications consumed by all extensions.
+func (m *Manager) ack(name string, seq uint64) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	cursor, ok := m.cursors[name]
+	if !ok {
+		return errUnknownName
+	}
+	if seq <= cursor {
+		return nil
+	}
+	if err := m.writeCursor(name, seq); err != nil {
+		return err
+	}
+	m.prune()
+	return nil
+}
+
+// writeCursor persists the acknowledged position of an extension. The caller
+// must hold the lock.
+func (m *Manager) writeCursor(name string, seq uint64) error {
+	var blob [8]byte
+	binary.BigEndian.PutUint64(blob[:], seq)
+	if err := m.db.Put(cursorKey(name), blob[:]); err != nil {
+		return err
+	}
+	m.cursors[name] = seq
+	return nil
+}
+
+// prune drops the notifications acknowledged by all registered extensions. The
+// caller must hold the lock.
+func (m *Manager) prune() {
+	consumed := m.head.Seq
+	for _, cursor := range m.cursors {
+		if cursor < consumed {
+			consumed = cursor
+		}
+	}
+	if consumed < m.tail {
+		return
+	}
+	batch := m.db.NewBatch()
+	for seq := m.tail; seq <= consumed; seq++ {
+		batch.Delete(walKey(seq))
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to prune execution extension journal", "err", err)
+		return
+	}
+	m.tail = consumed + 1
+}
+
+// remove unregisters an extension, disconnecting it and dropping the
+// notifications retained only for it.
+func (m *Manager) remove(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.cursors[name]; !ok {
+		return errUnknownName
+	}
+	if c, ok := m.conns[name]; ok {
+		c.close()
+	}
+	if err := m.db.Delete(cursorKey(name)); err != nil {
+		return err
+	}
+	delete(m.cursors, name)
+	m.prune()
+
+	log.Info("Removed execution extension", "name", name)
+	return nil
+}
@@ -0,0 +1,205 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package exex
+
+import (
+	"encoding/json"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testExtension is an execution extension connected to the manager's socket.
+type testExtension struct {
+	t    *testing.T
+	conn net.Conn
+	dec  *json.Decoder
+	enc  *json.Encoder
+}
+
+func dialExtension(t *testing.T, endpoint string, name string, diffs bool) (*testExtension, uint64) {
+	conn, err := net.Dial("unix", endpoint)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	ext := &testExtension{t: t, conn: conn, dec: json.NewDecoder(conn), enc: json.NewEncoder(conn)}
+	if err := ext.enc.Encode(&Request{Method: "hello", Name: name, StateDiffs: diffs}); err != nil {
+		t.Fatalf("failed to send hello: %v", err)
+	}
+	welcome := ext.next()
+	if welcome.Type != "welcome" {
+		t.Fatalf("unexpected handshake reply: %+v", welcome)
+	}
+	return ext, welcome.Seq
+}
+
+func (ext *testExtension) next() *Notification {
+	ext.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msg := new(Notification)
+	if err := ext.dec.Decode(msg); err != nil {
+		ext.t.Fatalf("failed to read notification: %v", err)
+	}
+	return msg
+}
+
+// expect reads notifications until the given type and block numbers have been
+// streamed, returning the sequence number of the last one.
+func (ext *testExtension) expect(typ string, numbers ...uint64) uint64 {
+	var seq uint64
+	for len(numbers) > 0 {
+		msg := ext.next()
+		if msg.Type != typ {
+			ext.t.Fatalf("notification type mismatch: have %s, want %s", msg.Type, typ)
+		}
+		var blocks []*struct{ Header *types.Header }
+		if err := json.Unmarshal(msg.Blocks, &blocks); err != nil {
+			ext.t.Fatalf("failed to decode blocks: %v", err)
+		}
+		for _, block := range blocks {
+			if number := block.Header.Number.Uint64(); number != numbers[0] {
+				ext.t.Fatalf("%s block mismatch: have %d, want %d", typ, number, numbers[0])
+			}
+			numbers = numbers[1:]
+		}
+		seq = msg.Seq
+	}
+	return seq
+}
+
+func (ext *testExtension) ack(seq uint64) {
+	if err := ext.enc.Encode(&Request{Method: "ack", Seq: seq}); err != nil {
+		ext.t.Fatalf("failed to ack: %v", err)
+	}
+}
+
+// Tests that committed and reverted chain segments are streamed in order, and
+// that unacknowledged notifications are redelivered on reconnect.
+func TestExecutionExtensions(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		config  = params.TestChainConfig
+		signer  = types.LatestSigner(config)
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &core.Genesis{Config: config, Alloc: core.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}}}
+		genesis = gspec.MustCommit(db)
+	)
+	transfer := func(to common.Address) func(int, *core.BlockGen) {
+		return func(i int, b *core.BlockGen) {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), to, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	}
+	blocks, _ := core.GenerateChain(config, genesis, ethash.NewFaker(), db, 3, transfer(common.Address{0x01}))
+	forks, _ := core.GenerateChain(config, blocks[0], ethash.NewFaker(), db, 3, transfer(common.Address{0x02}))
+
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	endpoint := filepath.Join(t.TempDir(), "exex.ipc")
+	m := New(db, chain, endpoint, true)
+	if err := m.Start(); err != nil {
+		t.Fatalf("failed to start manager: %v", err)
+	}
+	defer m.Stop()
+
+	ext, seq := dialExtension(t, endpoint, "indexer", true)
+	if seq != 0 {
+		t.Fatalf("initial seq mismatch: have %d, want 0", seq)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	// Check the state diff of the first block, crediting the recipient
+	msg := ext.next()
+	var diffs []*StateDiff
+	if err := json.Unmarshal(msg.StateDiffs, &diffs); err != nil || len(diffs) == 0 || diffs[0] == nil {
+		t.Fatalf("missing state diffs: %v", err)
+	}
+	recipient := crypto.Keccak256Hash(common.Address{0x01}.Bytes())
+	var credited bool
+	for _, account := range diffs[0].Accounts {
+		if account.Hash == recipient {
+			credited = account.Balance.ToInt().Cmp(big.NewInt(1)) == 0
+		}
+	}
+	if !credited {
+		t.Errorf("recipient credit missing from state diff")
+	}
+	var blocksSeen []*struct{ Header *types.Header }
+	json.Unmarshal(msg.Blocks, &blocksSeen)
+	if last := blocksSeen[len(blocksSeen)-1].Header.Number.Uint64(); last < 3 {
+		seq = ext.expect(Committed, seqRange(last+1, 3)...)
+	} else {
+		seq = msg.Seq
+	}
+	ext.ack(seq)
+
+	// Reorg to the longer fork and check the old blocks get reverted first
+	if _, err := chain.InsertChain(forks); err != nil {
+		t.Fatalf("failed to import fork: %v", err)
+	}
+	ext.expect(Reverted, 3, 2)
+	last := ext.expect(Committed, 2, 3, 4)
+
+	// Reconnect without acknowledging and check the reorg is redelivered
+	ext.conn.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	ext, acked := dialExtension(t, endpoint, "indexer", false)
+	if acked != seq {
+		t.Fatalf("acked seq mismatch: have %d, want %d", acked, seq)
+	}
+	ext.expect(Reverted, 3, 2)
+	if redelivered := ext.expect(Committed, 2, 3, 4); redelivered != last {
+		t.Fatalf("redelivered seq mismatch: have %d, want %d", redelivered, last)
+	}
+	ext.ack(last)
+	time.Sleep(100 * time.Millisecond)
+
+	// All notifications consumed, the journal should be empty
+	it := db.NewIterator(walPrefix, nil)
+	if it.Next() {
+		t.Errorf("consumed notifications retained")
+	}
+	it.Release()
+	if infos := NewPrivateExExAPI(m).Extensions(); len(infos) != 1 || !infos[0].Connected || infos[0].Pending != 0 {
+		t.Errorf("extension info mismatch: %+v", infos[0])
+	}
+}
+
+func seqRange(from, to uint64) []uint64 {
+	var numbers []uint64
+	for n := from; n <= to; n++ {
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
@@ -0,0 +1,168 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package exex
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// StateDiff is the set of accounts and storage slots changed by a block. The
+// state is keyed by hashes, as the preimages are not generally available.
+type StateDiff struct {
+	Accounts []*AccountDiff `json:"accounts"`
+}
+
+// AccountDiff is the post-block state of a changed account.
+type AccountDiff struct {
+	Hash        common.Hash                   `json:"hash"` // Keccak256 hash of the address
+	Deleted     bool                          `json:"deleted,omitempty"`
+	Nonce       hexutil.Uint64                `json:"nonce"`
+	Balance     *hexutil.Big                  `json:"balance,omitempty"`
+	CodeHash    common.Hash                   `json:"codeHash"`
+	StorageRoot common.Hash                   `json:"storageRoot"`
+	Storage     map[common.Hash]hexutil.Bytes `json:"storage,omitempty"` // Changed slots by key hash, empty if cleared
+}
+
+// diffState computes the accounts and storage slots changed between two state
+// roots.
+func diffState(db state.Database, parent, root common.Hash) (*StateDiff, error) {
+	oldTrie, err := db.OpenTrie(parent)
+	if err != nil {
+		return nil, err
+	}
+	newTrie, err := db.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	updated, previous, deleted, err := diffTries(oldTrie, newTrie)
+	if err != nil {
+		return nil, err
+	}
+	diff := &StateDiff{Accounts: make([]*AccountDiff, 0, len(updated)+len(deleted))}
+	for hash, blob := range updated {
+		var account types.StateAccount
+		if err := rlp.DecodeBytes(blob, &account); err != nil {
+			return nil, err
+		}
+		oldRoot := types.EmptyRootHash
+		if blob, ok := previous[hash]; ok {
+			var old types.StateAccount
+			if err := rlp.DecodeBytes(blob, &old); err != nil {
+				return nil, err
+			}
+			oldRoot = old.Root
+		}
+		accDiff := &AccountDiff{
+			Hash:        hash,
+			Nonce:       hexutil.Uint64(account.Nonce),
+			Balance:     (*hexutil.Big)(account.Balance),
+			CodeHash:    common.BytesToHash(account.CodeHash),
+			StorageRoot: account.Root,
+		}
+		if account.Root != oldRoot {
+			if accDiff.Storage, err = diffStorage(db, hash, oldRoot, account.Root); err != nil {
+				return nil, err
+			}
+		}
+		diff.Accounts = append(diff.Accounts, accDiff)
+	}
+	for _, hash := range deleted {
+		diff.Accounts = append(diff.Accounts, &AccountDiff{Hash: hash, Deleted: true})
+	}
+	sort.Slice(diff.Accounts, func(i, j int) bool {
+		return bytes.Compare(diff.Accounts[i].Hash[:], diff.Accounts[j].Hash[:]) < 0
+	})
+	return diff, nil
+}
+
+// diffStorage computes the storage slots of an account changed between two
+// storage roots.
+func diffStorage(db state.Database, account, parent, root common.Hash) (map[common.Hash]hexutil.Bytes, error) {
+	oldTrie, err := db.OpenStorageTrie(account, parent)
+	if err != nil {
+		return nil, err
+	}
+	newTrie, err := db.OpenStorageTrie(account, root)
+	if err != nil {
+		return nil, err
+	}
+	updated, _, deleted, err := diffTries(oldTrie, newTrie)
+	if err != nil {
+		return nil, err
+	}
+	slots := make(map[common.Hash]hexutil.Bytes, len(updated)+len(deleted))
+	for hash, blob := range updated {
+		_, content, _, err := rlp.Split(blob)
+		if err != nil {
+			return nil, err
+		}
+		slots[hash] = content
+	}
+	for _, hash := range deleted {
+		slots[hash] = hexutil.Bytes{}
+	}
+	return slots, nil
+}
+
+// diffTries returns the leaves created or changed in the new trie, the previous
+// values of the changed ones and the keys of the leaves deleted from the old
+// trie.
+func diffTries(oldTrie, newTrie state.Trie) (map[common.Hash][]byte, map[common.Hash][]byte, []common.Hash, error) {
+	added, err := diffLeaves(oldTrie.NodeIterator(nil), newTrie.NodeIterator(nil))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Leaves only moved around by restructured nodes show up in both directions
+	// with the same value, leaves missing from the new trie got deleted.
+	removed, err := diffLeaves(newTrie.NodeIterator(nil), oldTrie.NodeIterator(nil))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var deleted []common.Hash
+	for key, blob := range removed {
+		if value, ok := added[key]; !ok {
+			deleted = append(deleted, key)
+			delete(removed, key)
+		} else if bytes.Equal(value, blob) {
+			delete(added, key)
+			delete(removed, key)
+		}
+	}
+	return added, removed, deleted, nil
+}
+
+// diffLeaves collects the leaves of the trie iterated by b which are not in the
+// trie iterated by a.
+func diffLeaves(a, b trie.NodeIterator) (map[common.Hash][]byte, error) {
+	it, _ := trie.NewDifferenceIterator(a, b)
+
+	leaves := make(map[common.Hash][]byte)
+	for it.Next(true) {
+		if it.Leaf() {
+			leaves[common.BytesToHash(it.LeafKey())] = common.CopyBytes(it.LeafBlob())
+		}
+	}
+	return leaves, it.Error()
+}
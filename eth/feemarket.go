@@ -0,0 +1,210 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// maxFeeMarketWindow is the maximum number of blocks a fee market simulation
+// can replay at once.
+const maxFeeMarketWindow = 8192
+
+// FeeMarketParams are the hypothetical fee market parameters to replay blocks
+// under. Unset fields keep the values in effect on the chain.
+type FeeMarketParams struct {
+	GasLimit                 *hexutil.Uint64 `json:"gasLimit"`                 // Gas limit of every block instead of their own
+	ElasticityMultiplier     *hexutil.Uint64 `json:"elasticityMultiplier"`     // Ratio of the gas limit to the gas target
+	BaseFeeChangeDenominator *hexutil.Uint64 `json:"baseFeeChangeDenominator"` // Bound of the base fee change between blocks
+	InitialBaseFee           *hexutil.Big    `json:"initialBaseFee"`           // Base fee of the first block instead of its own
+}
+
+// FeeMarketBlock is the outcome of a block replayed under the hypothetical fee
+// market parameters.
+type FeeMarketBlock struct {
+	Number        hexutil.Uint64 `json:"number"`
+	ActualBaseFee *hexutil.Big   `json:"actualBaseFee,omitempty"` // Base fee on chain, none before London
+	BaseFee       *hexutil.Big   `json:"baseFee"`                 // Simulated base fee
+	GasLimit      hexutil.Uint64 `json:"gasLimit"`                // Simulated gas limit
+	GasUsed       hexutil.Uint64 `json:"gasUsed"`                 // Gas used on chain
+	GasIncluded   hexutil.Uint64 `json:"gasIncluded"`             // Simulated gas used, including the backlog
+	Backlog       hexutil.Uint64 `json:"backlog"`                 // Gas demand left over for the next blocks
+}
+
+// FeeMarketSimulation is the fee trajectory of a range of blocks replayed under
+// hypothetical fee market parameters.
+type FeeMarketSimulation struct {
+	From                     hexutil.Uint64    `json:"from"`
+	To                       hexutil.Uint64    `json:"to"`
+	ElasticityMultiplier     hexutil.Uint64    `json:"elasticityMultiplier"`
+	BaseFeeChangeDenominator hexutil.Uint64    `json:"baseFeeChangeDenominator"`
+	Blocks                   []*FeeMarketBlock `json:"blocks"`
+	MinBaseFee               *hexutil.Big      `json:"minBaseFee"`
+	MaxBaseFee               *hexutil.Big      `json:"maxBaseFee"`
+	ActualBurnt              *hexutil.Big      `json:"actualBurnt"` // Base fees burnt on chain
+	Burnt                    *hexutil.Big      `json:"burnt"`       // Simulated base fees burnt
+	Backlog                  hexutil.Uint64    `json:"backlog"`     // Gas demand not included by the end of the range
+}
+
+// PublicFeeMarketAPI simulates the fee market under hypothetical parameters, to
+// inform governance votes on gas limit and base fee changes.
+type PublicFeeMarketAPI struct {
+	eth *Ethereum
+}
+
+// NewPublicFeeMarketAPI creates a new fee market simulation API.
+func NewPublicFeeMarketAPI(eth *Ethereum) *PublicFeeMarketAPI {
+	return &PublicFeeMarketAPI{eth: eth}
+}
+
+// simulateFeeMarket replays the gas demand of consecutive headers under the
+// given fee market parameters. The demand of a block is the gas it used, plus
+// the demand the previous blocks couldn't include under a lower gas limit. The
+// demand is assumed not to react to the simulated base fee.
+func simulateFeeMarket(headers []*types.Header, config FeeMarketParams) (*FeeMarketSimulation, error) {
+	if len(headers) == 0 {
+		return nil, errors.New("no blocks to simulate")
+	}
+	var (
+		elasticity  = uint64(params.ElasticityMultiplier)
+		denominator = uint64(params.BaseFeeChangeDenominator)
+	)
+	if config.ElasticityMultiplier != nil {
+		elasticity = uint64(*config.ElasticityMultiplier)
+	}
+	if config.BaseFeeChangeDenominator != nil {
+		denominator = uint64(*config.BaseFeeChangeDenominator)
+	}
+	if elasticity == 0 || denominator == 0 {
+		return nil, errors.New("elasticity multiplier and base fee change denominator must be positive")
+	}
+	if config.GasLimit != nil && uint64(*config.GasLimit) < params.MinGasLimit {
+		return nil, fmt.Errorf("gas limit below minimum of %d", params.MinGasLimit)
+	}
+	baseFee := new(big.Int).SetUint64(params.InitialBaseFee)
+	if headers[0].BaseFee != nil {
+		baseFee.Set(headers[0].BaseFee)
+	}
+	if config.InitialBaseFee != nil {
+		baseFee.Set(config.InitialBaseFee.ToInt())
+	}
+	var (
+		sim = &FeeMarketSimulation{
+			From:                     hexutil.Uint64(headers[0].Number.Uint64()),
+			To:                       hexutil.Uint64(headers[len(headers)-1].Number.Uint64()),
+			ElasticityMultiplier:     hexutil.Uint64(elasticity),
+			BaseFeeChangeDenominator: hexutil.Uint64(denominator),
+		}
+		minFee, maxFee = new(big.Int).Set(baseFee), new(big.Int).Set(baseFee)
+		actual, burnt  = new(big.Int), new(big.Int)
+		backlog        uint64
+	)
+	for _, header := range headers {
+		limit := header.GasLimit
+		if config.GasLimit != nil {
+			limit = uint64(*config.GasLimit)
+		}
+		demand := header.GasUsed + backlog
+		included := demand
+		if included > limit {
+			included = limit
+		}
+		backlog = demand - included
+
+		sim.Blocks = append(sim.Blocks, &FeeMarketBlock{
+			Number:        hexutil.Uint64(header.Number.Uint64()),
+			ActualBaseFee: (*hexutil.Big)(header.BaseFee),
+			BaseFee:       (*hexutil.Big)(new(big.Int).Set(baseFee)),
+			GasLimit:      hexutil.Uint64(limit),
+			GasUsed:       hexutil.Uint64(header.GasUsed),
+			GasIncluded:   hexutil.Uint64(included),
+			Backlog:       hexutil.Uint64(backlog),
+		})
+		if header.BaseFee != nil {
+			actual.Add(actual, new(big.Int).Mul(header.BaseFee, new(big.Int).SetUint64(header.GasUsed)))
+		}
+		burnt.Add(burnt, new(big.Int).Mul(baseFee, new(big.Int).SetUint64(included)))
+		if baseFee.Cmp(minFee) < 0 {
+			minFee.Set(baseFee)
+		}
+		if baseFee.Cmp(maxFee) > 0 {
+			maxFee.Set(baseFee)
+		}
+		baseFee = nextBaseFee(baseFee, included, limit/elasticity, denominator)
+	}
+	sim.MinBaseFee = (*hexutil.Big)(minFee)
+	sim.MaxBaseFee = (*hexutil.Big)(maxFee)
+	sim.ActualBurnt = (*hexutil.Big)(actual)
+	sim.Burnt = (*hexutil.Big)(burnt)
+	sim.Backlog = hexutil.Uint64(backlog)
+	return sim, nil
+}
+
+// nextBaseFee calculates the base fee following a block with the given gas used
+// and target, as in EIP-1559 with a configurable change denominator.
+func nextBaseFee(baseFee *big.Int, gasUsed, gasTarget, denominator uint64) *big.Int {
+	if gasUsed == gasTarget || gasTarget == 0 {
+		return new(big.Int).Set(baseFee)
+	}
+	if gasUsed > gasTarget {
+		delta := new(big.Int).Mul(baseFee, new(big.Int).SetUint64(gasUsed-gasTarget))
+		delta.Div(delta, new(big.Int).SetUint64(gasTarget))
+		delta.Div(delta, new(big.Int).SetUint64(denominator))
+		if delta.Sign() == 0 {
+			delta.SetUint64(1)
+		}
+		return delta.Add(baseFee, delta)
+	}
+	delta := new(big.Int).Mul(baseFee, new(big.Int).SetUint64(gasTarget-gasUsed))
+	delta.Div(delta, new(big.Int).SetUint64(gasTarget))
+	delta.Div(delta, new(big.Int).SetUint64(denominator))
+
+	next := delta.Sub(baseFee, delta)
+	if next.Sign() < 0 {
+		next.SetUint64(0)
+	}
+	return next
+}
+
+// SimulateFeeMarket replays the last window blocks of the chain under the given
+// hypothetical fee market parameters, reporting the resulting base fee
+// trajectory next to the actual one. It's meant to weigh gas limit and base fee
+// parameter changes before voting on them.
+func (api *PublicFeeMarketAPI) SimulateFeeMarket(config FeeMarketParams, window hexutil.Uint64) (*FeeMarketSimulation, error) {
+	if window == 0 || window > maxFeeMarketWindow {
+		return nil, fmt.Errorf("window must be between 1 and %d blocks", maxFeeMarketWindow)
+	}
+	head := api.eth.blockchain.CurrentHeader().Number.Uint64()
+	if uint64(window) > head {
+		window = hexutil.Uint64(head)
+	}
+	headers := make([]*types.Header, 0, window)
+	for number := head - uint64(window) + 1; number <= head; number++ {
+		header := api.eth.blockchain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("header #%d not found", number)
+		}
+		headers = append(headers, header)
+	}
+	return simulateFeeMarket(headers, config)
+}
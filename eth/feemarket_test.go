@@ -0,0 +1,62 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests that replaying blocks under a different gas limit carries the demand
+// not fitting into the blocks over, and moves the base fee accordingly.
+func TestSimulateFeeMarket(t *testing.T) {
+	var headers []*types.Header
+	for i := 0; i < 3; i++ {
+		headers = append(headers, &types.Header{Number: big.NewInt(int64(i + 1)), GasLimit: 8000000, GasUsed: 8000000, BaseFee: big.NewInt(1000)})
+	}
+	// Doubling the gas limit keeps the blocks at their target
+	limit := hexutil.Uint64(16000000)
+	sim, err := simulateFeeMarket(headers, FeeMarketParams{GasLimit: &limit})
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+	if sim.MaxBaseFee.ToInt().Int64() != 1000 || sim.Backlog != 0 {
+		t.Fatalf("simulation mismatch: max base fee %v, backlog %d", sim.MaxBaseFee, sim.Backlog)
+	}
+	// Lowering it leaves a growing backlog, with the blocks full
+	limit = 5000000
+	if sim, err = simulateFeeMarket(headers, FeeMarketParams{GasLimit: &limit}); err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+	if last := sim.Blocks[2]; last.BaseFee.ToInt().Int64() != 1265 || last.GasIncluded != 5000000 || last.Backlog != 9000000 {
+		t.Fatalf("last block mismatch: %+v", last)
+	}
+	if burnt := sim.Burnt.ToInt(); burnt.Cmp(big.NewInt(16950000000)) != 0 {
+		t.Fatalf("burnt mismatch: have %v, want %v", burnt, 16950000000)
+	}
+	if actual := sim.ActualBurnt.ToInt(); actual.Cmp(big.NewInt(24000000000)) != 0 {
+		t.Fatalf("actual burnt mismatch: have %v, want %v", actual, 24000000000)
+	}
+	// Invalid parameters are rejected
+	zero := hexutil.Uint64(0)
+	if _, err := simulateFeeMarket(headers, FeeMarketParams{ElasticityMultiplier: &zero}); err == nil {
+		t.Fatalf("zero elasticity multiplier accepted")
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	defaultForkCheckDepth = 8               // Number of recent heights compared with each peer by default
+	maxForkCheckDepth     = 128             // Maximum number of heights compared with each peer
+	forkCheckTimeout      = 5 * time.Second // Time allowance for a peer to answer a header query
+)
+
+// ForkPeerStatus is the result of comparing the recent canonical chain of a
+// single peer against the local one.
+type ForkPeerStatus struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	Trusted    bool             `json:"trusted"`
+	Validator  *common.Address  `json:"validator,omitempty"` // Clique signer proven to run the peer, if any
+	Head       common.Hash      `json:"head"`
+	Checked    int              `json:"checked"`              // Number of heights the peer answered for
+	Mismatches []hexutil.Uint64 `json:"mismatches,omitempty"` // Heights where the peer's hash differs from ours
+	Diverged   bool             `json:"diverged"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// ForkStatus reports how the recent local chain compares to the chains of the
+// connected validator peers.
+type ForkStatus struct {
+	Number      hexutil.Uint64    `json:"number"` // Local head number
+	Hash        common.Hash       `json:"hash"`   // Local head hash
+	From        hexutil.Uint64    `json:"from"`   // First height compared
+	Peers       []*ForkPeerStatus `json:"peers"`
+	Agreeing    int               `json:"agreeing"`
+	Disagreeing int               `json:"disagreeing"`
+	Unreachable int               `json:"unreachable"`
+}
+
+// forkStatus queries the connected peers for their headers at the last depth
+// heights and compares them with the local canonical chain. If any trusted or
+// proven validator peers are connected, only those are considered to be part
+// of the validator mesh; otherwise all eth peers are queried.
+func (h *handler) forkStatus(depth uint64) (*ForkStatus, error) {
+	if depth == 0 || depth > maxForkCheckDepth {
+		return nil, fmt.Errorf("invalid fork check depth %d, must be between 1 and %d", depth, maxForkCheckDepth)
+	}
+	head := h.chain.CurrentHeader()
+	number := head.Number.Uint64()
+
+	from := uint64(0)
+	if number+1 > depth {
+		from = number + 1 - depth
+	}
+	hashes := make([]common.Hash, 0, number-from+1)
+	for n := from; n <= number; n++ {
+		hashes = append(hashes, h.chain.GetCanonicalHash(n))
+	}
+	peers := h.validatorPeers()
+
+	status := &ForkStatus{
+		Number: hexutil.Uint64(number),
+		Hash:   head.Hash(),
+		From:   hexutil.Uint64(from),
+		Peers:  make([]*ForkPeerStatus, len(peers)),
+	}
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		go func(i int, peer *forkPeer) {
+			defer wg.Done()
+			status.Peers[i] = checkPeerFork(peer, from, hashes)
+		}(i, peer)
+	}
+	wg.Wait()
+
+	for _, peer := range status.Peers {
+		switch {
+		case peer.Error != "":
+			status.Unreachable++
+		case peer.Diverged:
+			status.Disagreeing++
+		default:
+			status.Agreeing++
+		}
+	}
+	return status, nil
+}
+
+// forkPeer is an eth peer selected for fork monitoring.
+type forkPeer struct {
+	*ethPeer
+	trusted bool
+}
+
+// validatorPeers returns the trusted and proven validator eth peers if there
+// are any, or all eth peers otherwise.
+func (h *handler) validatorPeers() []*forkPeer {
+	h.peers.lock.RLock()
+	defer h.peers.lock.RUnlock()
+
+	var all, mesh []*forkPeer
+	for _, p := range h.peers.peers {
+		peer := &forkPeer{ethPeer: p, trusted: p.Peer.Info().Network.Trusted}
+		all = append(all, peer)
+		if peer.trusted || peer.validator != nil {
+			mesh = append(mesh, peer)
+		}
+	}
+	if len(mesh) > 0 {
+		return mesh
+	}
+	return all
+}
+
+// checkPeerFork retrieves the headers of a peer starting at from and compares
+// them with the given local canonical hashes.
+func checkPeerFork(peer *forkPeer, from uint64, hashes []common.Hash) *ForkPeerStatus {
+	head, _ := peer.Head()
+	status := &ForkPeerStatus{
+		ID:        peer.ID(),
+		Name:      peer.Name(),
+		Trusted:   peer.trusted,
+		Validator: peer.validator,
+		Head:      head,
+	}
+	resCh := make(chan *eth.Response)
+	req, err := peer.RequestHeadersByNumber(from, len(hashes), 0, false, resCh)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	defer req.Close()
+
+	timeout := time.NewTimer(forkCheckTimeout)
+	defer timeout.Stop()
+
+	select {
+	case <-timeout.C:
+		status.Error = "header request timed out"
+		return status
+
+	case res := <-resCh:
+		res.Done <- nil
+
+		for _, header := range *res.Res.(*eth.BlockHeadersPacket) {
+			number := header.Number.Uint64()
+			if number < from || number >= from+uint64(len(hashes)) {
+				continue
+			}
+			status.Checked++
+			if header.Hash() != hashes[number-from] {
+				status.Mismatches = append(status.Mismatches, hexutil.Uint64(number))
+			}
+		}
+		status.Diverged = len(status.Mismatches) > 0
+		return status
+	}
+}
+
+// PrivateForkMonitorAPI exposes chain split detection against the connected
+// validator peers in the admin namespace.
+type PrivateForkMonitorAPI struct {
+	e *Ethereum
+}
+
+// NewPrivateForkMonitorAPI creates a new fork monitoring API.
+func NewPrivateForkMonitorAPI(e *Ethereum) *PrivateForkMonitorAPI {
+	return &PrivateForkMonitorAPI{e: e}
+}
+
+// ForkStatus compares the last depth canonical blocks (8 by default) with the
+// chains of the connected validator peers.
+func (api *PrivateForkMonitorAPI) ForkStatus(depth *hexutil.Uint64) (*ForkStatus, error) {
+	n := uint64(defaultForkCheckDepth)
+	if depth != nil {
+		n = uint64(*depth)
+	}
+	return api.e.handler.forkStatus(n)
+}
+
+// ForkAlerts creates a subscription that fires a fork status report on every
+// new local head at which at least threshold validator peers disagree with
+// the local chain.
+func (api *PrivateForkMonitorAPI) ForkAlerts(ctx context.Context, threshold hexutil.Uint64, depth *hexutil.Uint64) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if threshold == 0 {
+		return nil, errors.New("alert threshold must be at least one peer")
+	}
+	n := uint64(defaultForkCheckDepth)
+	if depth != nil {
+		n = uint64(*depth)
+	}
+	if n == 0 || n > maxForkCheckDepth {
+		return nil, fmt.Errorf("invalid fork check depth %d, must be between 1 and %d", n, maxForkCheckDepth)
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		heads := make(chan core.ChainHeadEvent, 1)
+		headSub := api.e.blockchain.SubscribeChainHeadEvent(heads)
+		defer headSub.Unsubscribe()
+
+		// Peer queries may take a while, run them in the background to avoid
+		// blocking the chain head feed and skip heads arriving meanwhile.
+		var (
+			running bool
+			results = make(chan *ForkStatus, 1)
+		)
+		for {
+			select {
+			case <-heads:
+				if running {
+					continue
+				}
+				running = true
+				go func() {
+					status, err := api.e.handler.forkStatus(n)
+					if err != nil {
+						log.Warn("Fork status check failed", "err", err)
+					}
+					results <- status
+				}()
+			case status := <-results:
+				running = false
+				if status != nil && status.Disagreeing >= int(threshold) {
+					log.Warn("Validator peers disagree with local chain", "number", status.Number, "hash", status.Hash, "disagreeing", status.Disagreeing, "agreeing", status.Agreeing)
+					notifier.Notify(rpcSub.ID, status)
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-headSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
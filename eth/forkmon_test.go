@@ -0,0 +1,95 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the recent chain of a peer is reported as agreeing if it matches
+// the local one, even if the peer lags behind, and as diverged otherwise.
+func TestForkStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		common     int // Number of blocks shared with the local chain
+		forked     int // Number of blocks on top, differing from the local chain
+		checked    int
+		mismatches []hexutil.Uint64
+		diverged   bool
+	}{
+		{name: "match", common: 16, checked: 8},
+		{name: "behind", common: 12, checked: 4},
+		{name: "diverged", common: 12, forked: 4, checked: 8, mismatches: []hexutil.Uint64{13, 14, 15, 16}, diverged: true},
+	}
+	for _, tt := range tests {
+		local := newTestHandlerWithBlocks(16)
+		remote := newTestHandlerWithBlocks(tt.common)
+
+		forked, _ := core.GenerateChain(params.TestChainConfig, remote.chain.CurrentBlock(), ethash.NewFaker(), remote.db, tt.forked, func(i int, block *core.BlockGen) {
+			block.SetExtra([]byte("fork"))
+		})
+		if _, err := remote.chain.InsertChain(forked); err != nil {
+			t.Fatalf("%s: failed to import fork: %v", tt.name, err)
+		}
+		localPipe, remotePipe := p2p.MsgPipe()
+		localPeer := eth.NewPeer(eth.ETH66, p2p.NewPeer(enode.ID{1}, "", nil), localPipe, local.txpool)
+		remotePeer := eth.NewPeer(eth.ETH66, p2p.NewPeer(enode.ID{2}, "", nil), remotePipe, remote.txpool)
+
+		go local.handler.runEthPeer(localPeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(local.handler), peer)
+		})
+		go remote.handler.runEthPeer(remotePeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(remote.handler), peer)
+		})
+		for i := 0; local.handler.peers.len() == 0; i++ {
+			if i == 100 {
+				t.Fatalf("%s: peer not registered", tt.name)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		status, err := local.handler.forkStatus(defaultForkCheckDepth)
+		if err != nil {
+			t.Fatalf("%s: failed to check fork status: %v", tt.name, err)
+		}
+		if status.Number != 16 || status.From != 9 || len(status.Peers) != 1 {
+			t.Fatalf("%s: status mismatch: have %+v", tt.name, status)
+		}
+		peer := status.Peers[0]
+		if peer.Error != "" || peer.Checked != tt.checked || !reflect.DeepEqual(peer.Mismatches, tt.mismatches) || peer.Diverged != tt.diverged {
+			t.Errorf("%s: peer status mismatch: have %+v", tt.name, peer)
+		}
+		if tt.diverged && (status.Agreeing != 0 || status.Disagreeing != 1) || !tt.diverged && (status.Agreeing != 1 || status.Disagreeing != 0) {
+			t.Errorf("%s: tally mismatch: have %d agreeing, %d disagreeing", tt.name, status.Agreeing, status.Disagreeing)
+		}
+		localPeer.Close()
+		remotePeer.Close()
+		localPipe.Close()
+		remotePipe.Close()
+		local.close()
+		remote.close()
+	}
+}
@@ -47,6 +47,7 @@ type Config struct {
 	Default          *big.Int `toml:",omitempty"`
 	MaxPrice         *big.Int `toml:",omitempty"`
 	IgnorePrice      *big.Int `toml:",omitempty"`
+	FixedTip         *big.Int `toml:",omitempty"` // Constant tip suggested instead of sampling blocks (nil = sample)
 }
 
 // OracleBackend includes all necessary background APIs for oracle.
@@ -67,6 +68,7 @@ type Oracle struct {
 	lastPrice   *big.Int
 	maxPrice    *big.Int
 	ignorePrice *big.Int
+	fixedTip    *big.Int
 	cacheLock   sync.RWMutex
 	fetchLock   sync.Mutex
 
@@ -103,6 +105,13 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 	} else if ignorePrice.Int64() > 0 {
 		log.Info("Gasprice oracle is ignoring threshold set", "threshold", ignorePrice)
 	}
+	if params.FixedTip != nil {
+		if params.FixedTip.Sign() < 0 {
+			log.Warn("Sanitizing invalid gasprice oracle fixed tip", "provided", params.FixedTip, "updated", 0)
+			params.FixedTip = new(big.Int)
+		}
+		log.Info("Gasprice oracle is suggesting a fixed tip", "tip", params.FixedTip)
+	}
 	maxHeaderHistory := params.MaxHeaderHistory
 	if maxHeaderHistory < 1 {
 		maxHeaderHistory = 1
@@ -132,6 +141,7 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 		lastPrice:        params.Default,
 		maxPrice:         maxPrice,
 		ignorePrice:      ignorePrice,
+		fixedTip:         params.FixedTip,
 		checkBlocks:      blocks,
 		percentile:       percent,
 		maxHeaderHistory: maxHeaderHistory,
@@ -147,6 +157,10 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 // necessary to add the basefee to the returned number to fall back to the legacy
 // behavior.
 func (oracle *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
+	// Deployments with subsidized or fixed-price gas don't sample the chain
+	if oracle.fixedTip != nil {
+		return new(big.Int).Set(oracle.fixedTip), nil
+	}
 	head, _ := oracle.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
 	headHash := head.Hash()
 
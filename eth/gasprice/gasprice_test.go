@@ -190,3 +190,21 @@ func TestSuggestTipCap(t *testing.T) {
 		}
 	}
 }
+
+func TestSuggestTipCapFixed(t *testing.T) {
+	config := Config{
+		Blocks:     3,
+		Percentile: 60,
+		Default:    big.NewInt(params.GWei),
+		FixedTip:   new(big.Int),
+	}
+	oracle := NewOracle(newTestBackend(t, big.NewInt(0), false), config)
+
+	got, err := oracle.SuggestTipCap(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to retrieve recommended gas price: %v", err)
+	}
+	if got.Sign() != 0 {
+		t.Fatalf("Gas price mismatch, want 0, got %d", got)
+	}
+}
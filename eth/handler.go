@@ -87,6 +87,9 @@ type handlerConfig struct {
 	EventMux       *event.TypeMux            // Legacy event mux, deprecate for `feed`
 	Checkpoint     *params.TrustedCheckpoint // Hard coded checkpoint for sync challenges
 	RequiredBlocks map[uint64]common.Hash    // Hard coded map of required block hashes for sync challenges
+	IsSigner       func(common.Address) bool // Reports whether an address is a clique signer, nil if not running clique
+	StrictForkID   bool                      // Whether to reject peers with a diverging fork schedule
+	Linked         map[string]bool           // Peers on the other side of the sentry links, sent everything in full
 }
 
 type handler struct {
@@ -109,6 +112,7 @@ type handler struct {
 	txFetcher    *fetcher.TxFetcher
 	peers        *peerSet
 	merger       *consensus.Merger
+	propagation  *propagationTracker
 
 	eventMux      *event.TypeMux
 	txsCh         chan core.NewTxsEvent
@@ -116,6 +120,8 @@ type handler struct {
 	minedBlockSub *event.TypeMuxSubscription
 
 	requiredBlocks map[uint64]common.Hash
+	isSigner       func(common.Address) bool
+	linked         map[string]bool
 
 	// channels for fetcher, syncer, txsyncLoop
 	quitSync chan struct{}
@@ -139,10 +145,16 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		txpool:         config.TxPool,
 		chain:          config.Chain,
 		peers:          newPeerSet(),
+		propagation:    newPropagationTracker(),
 		merger:         config.Merger,
 		requiredBlocks: config.RequiredBlocks,
+		isSigner:       config.IsSigner,
+		linked:         config.Linked,
 		quitSync:       make(chan struct{}),
 	}
+	if config.StrictForkID {
+		h.forkFilter = forkid.NewStrictFilter(config.Chain)
+	}
 	if config.Sync == downloader.FullSync {
 		// The database seems empty as the current block is the genesis. Yet the snap
 		// block is ahead, so snap sync was enabled for this node at a certain point.
@@ -275,13 +287,25 @@ func newHandler(config *handlerConfig) (*handler, error) {
 			}
 			return 0, nil
 		}
+		now := time.Now()
+		for _, block := range blocks {
+			h.propagation.received("", block, now)
+		}
 		n, err := h.chain.InsertChain(blocks)
 		if err == nil {
 			atomic.StoreUint32(&h.acceptTxs, 1) // Mark initial sync done on any fetcher import
 		}
+		imported := blocks
+		if err != nil {
+			imported = blocks[:n]
+		}
+		now = time.Now()
+		for _, block := range imported {
+			h.propagation.imported(block, now)
+		}
 		return n, err
 	}
-	h.blockFetcher = fetcher.NewBlockFetcher(false, nil, h.chain.GetBlockByHash, validator, h.BroadcastBlock, heighter, nil, inserter, h.removePeer)
+	h.blockFetcher = fetcher.NewBlockFetcher(false, nil, h.chain.GetBlockByHash, validator, h.BroadcastBlock, heighter, nil, inserter, h.removePeer, h.chain.QuarantineHeader)
 
 	fetchTx := func(peer string, hashes []common.Hash) error {
 		p := h.peers.peer(peer)
@@ -336,16 +360,21 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 			}
 		}
 	}
-	// Ignore maxPeers if this is a trusted peer
-	if !peer.Peer.Info().Network.Trusted {
+	// Check whether the peer's node record proves it to be run by a signer
+	var validator *common.Address
+	if signer, ok := nodeValidator(peer.Node()); ok && h.isSigner != nil && h.isSigner(signer) {
+		validator = &signer
+	}
+	// Ignore maxPeers if this is a trusted peer or the first one of a validator
+	if !peer.Peer.Info().Network.Trusted && (validator == nil || h.peers.hasValidator(*validator)) {
 		if reject || h.peers.len() >= h.maxPeers {
 			return p2p.DiscTooManyPeers
 		}
 	}
-	peer.Log().Debug("Ethereum peer connected", "name", peer.Name())
+	peer.Log().Debug("Ethereum peer connected", "name", peer.Name(), "validator", validator)
 
 	// Register the peer locally
-	if err := h.peers.registerPeer(peer, snap); err != nil {
+	if err := h.peers.registerPeer(peer, snap, validator); err != nil {
 		peer.Log().Error("Ethereum peer registration failed", "err", err)
 		return err
 	}
@@ -583,8 +612,9 @@ func (h *handler) BroadcastBlock(block *types.Block, propagate bool) {
 			log.Error("Propagating dangling block", "number", block.Number(), "hash", hash)
 			return
 		}
-		// Send the block to a subset of our peers
-		transfer := peers[:int(math.Sqrt(float64(len(peers))))]
+		// Send the block to the sentry links and a subset of our other peers
+		linked, others := h.splitLinked(peers)
+		transfer := append(linked, others[:int(math.Sqrt(float64(len(others))))]...)
 		for _, peer := range transfer {
 			peer.AsyncSendNewBlock(block, td)
 		}
@@ -617,7 +647,15 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 	)
 	// Broadcast transactions to a batch of peers not knowing about it
 	for _, tx := range txs {
-		peers := h.peers.peersWithoutTransaction(tx.Hash())
+		// Conditional transactions stay local, their preconditions are lost
+		// on the wire and the submitter only trusts us to check them
+		if tx.Conditional() != nil {
+			continue
+		}
+		linked, peers := h.splitLinked(h.peers.peersWithoutTransaction(tx.Hash()))
+		for _, peer := range linked {
+			txset[peer] = append(txset[peer], tx.Hash())
+		}
 		// Send the tx unconditionally to a subset of our peers
 		numDirect := int(math.Sqrt(float64(len(peers))))
 		for _, peer := range peers[:numDirect] {
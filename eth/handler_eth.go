@@ -93,6 +93,10 @@ func (h *ethHandler) handleBlockAnnounces(peer *eth.Peer, hashes []common.Hash,
 		// return errors.New("unexpected block announces")
 	}
 	// Schedule all the unknown hashes for retrieval
+	now := time.Now()
+	for i := 0; i < len(hashes); i++ {
+		h.propagation.announced(peer.ID(), hashes[i], numbers[i], now)
+	}
 	var (
 		unknownHashes  = make([]common.Hash, 0, len(hashes))
 		unknownNumbers = make([]uint64, 0, len(numbers))
@@ -104,7 +108,7 @@ func (h *ethHandler) handleBlockAnnounces(peer *eth.Peer, hashes []common.Hash,
 		}
 	}
 	for i := 0; i < len(unknownHashes); i++ {
-		h.blockFetcher.Notify(peer.ID(), unknownHashes[i], unknownNumbers[i], time.Now(), peer.RequestOneHeader, peer.RequestBodies)
+		h.blockFetcher.Notify(peer.ID(), unknownHashes[i], unknownNumbers[i], now, peer.RequestOneHeader, peer.RequestBodies)
 	}
 	return nil
 }
@@ -121,6 +125,7 @@ func (h *ethHandler) handleBlockBroadcast(peer *eth.Peer, block *types.Block, td
 		// return errors.New("unexpected block announces")
 	}
 	// Schedule the block for import
+	h.propagation.received(peer.ID(), block, time.Now())
 	h.blockFetcher.Enqueue(peer.ID(), block)
 
 	// Assuming the block is importable by the peer, but possibly not yet done so,
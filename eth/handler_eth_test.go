@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
@@ -450,6 +451,108 @@ func testTransactionPropagation(t *testing.T, protocol uint) {
 	}
 }
 
+// Tests that conditional transactions are neither broadcast nor announced, be it
+// when they enter the pool or when a peer connects, while the other
+// transactions still propagate.
+func TestConditionalTransactionPropagation66(t *testing.T) {
+	testConditionalTransactionPropagation(t, eth.ETH66)
+}
+
+func testConditionalTransactionPropagation(t *testing.T, protocol uint) {
+	t.Parallel()
+
+	source := newTestHandler()
+	source.handler.snapSync = 0 // Avoid requiring snap, otherwise some will be dropped below
+	defer source.close()
+
+	sign := func(nonce uint64, cond *types.TransactionConditional) *types.Transaction {
+		tx := types.NewTransaction(nonce, common.Address{}, big.NewInt(0), 100000, big.NewInt(0), nil)
+		tx, _ = types.SignTx(tx, types.HomesteadSigner{}, testKey)
+		if cond != nil {
+			tx.SetConditional(cond)
+		}
+		return tx
+	}
+	// Pool a conditional transaction before the peers connect
+	conditional := map[common.Hash]bool{}
+	pooled := sign(0, &types.TransactionConditional{BlockNumberMax: (*hexutil.Big)(big.NewInt(1000))})
+	conditional[pooled.Hash()] = true
+	source.txpool.AddRemotes([]*types.Transaction{pooled})
+
+	sinks := make([]*testHandler, 4)
+	for i := 0; i < len(sinks); i++ {
+		sinks[i] = newTestHandler()
+		defer sinks[i].close()
+
+		sinks[i].handler.acceptTxs = 1 // mark synced to accept transactions
+	}
+	txChs := make([]chan core.NewTxsEvent, len(sinks))
+	for i := 0; i < len(sinks); i++ {
+		txChs[i] = make(chan core.NewTxsEvent, 1024)
+
+		sub := sinks[i].txpool.SubscribeNewTxsEvent(txChs[i])
+		defer sub.Unsubscribe()
+	}
+	for i, sink := range sinks {
+		sink := sink // Closure for gorotuine below
+
+		sourcePipe, sinkPipe := p2p.MsgPipe()
+		defer sourcePipe.Close()
+		defer sinkPipe.Close()
+
+		sourcePeer := eth.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{byte(i + 1)}, "", nil, sourcePipe), sourcePipe, source.txpool)
+		sinkPeer := eth.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{0}, "", nil, sinkPipe), sinkPipe, sink.txpool)
+		defer sourcePeer.Close()
+		defer sinkPeer.Close()
+
+		go source.handler.runEthPeer(sourcePeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(source.handler), peer)
+		})
+		go sink.handler.runEthPeer(sinkPeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(sink.handler), peer)
+		})
+	}
+	// Pool a mix of plain and conditional transactions once connected
+	var (
+		txs   []*types.Transaction
+		plain int
+	)
+	for nonce := uint64(1); nonce <= 16; nonce++ {
+		var cond *types.TransactionConditional
+		if nonce%4 == 0 {
+			cond = &types.TransactionConditional{BlockNumberMax: (*hexutil.Big)(big.NewInt(1000))}
+		}
+		tx := sign(nonce, cond)
+		if cond != nil {
+			conditional[tx.Hash()] = true
+		} else {
+			plain++
+		}
+		txs = append(txs, tx)
+	}
+	source.txpool.AddRemotes(txs)
+
+	// Ensure the sinks get the plain transactions but none of the conditional ones
+	for i := range sinks {
+		for arrived, timeout := 0, false; !timeout; {
+			select {
+			case event := <-txChs[i]:
+				for _, tx := range event.Txs {
+					if conditional[tx.Hash()] {
+						t.Errorf("sink %d: conditional transaction %x propagated", i, tx.Hash())
+					}
+				}
+				arrived += len(event.Txs)
+			case <-time.After(time.Second):
+				if arrived != plain {
+					t.Errorf("sink %d: transaction propagation mismatch: have %d, want %d", i, arrived, plain)
+				}
+				timeout = true
+			}
+		}
+	}
+}
+
 // Tests that post eth protocol handshake, clients perform a mutual checkpoint
 // challenge to validate each other's chains. Hash mismatches, or missing ones
 // during a fast sync should lead to the peer getting dropped.
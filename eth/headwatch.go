@@ -0,0 +1,198 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+var (
+	headLagGauge  = metrics.NewRegisteredGauge("eth/headwatch/lag", nil)
+	headLagMeter  = metrics.NewRegisteredMeter("eth/headwatch/stalls", nil)
+	peerDropMeter = metrics.NewRegisteredMeter("eth/headwatch/dropped", nil)
+)
+
+// HeadLagEvent is posted when the chain head hasn't advanced for longer than the
+// configured number of block periods while peers are connected, after the peers
+// got rotated.
+type HeadLagEvent struct {
+	Number   uint64        // Number of the stale head
+	Hash     common.Hash   // Hash of the stale head
+	Lag      time.Duration // Time since the head was imported
+	Peers    int           // Number of connected peers when the stall was detected
+	Dropped  int           // Number of peers dropped to make room for new ones
+	Redialed int           // Number of validator mesh peers reconnected
+}
+
+// headWatchChain is the subset of the blockchain the head watchdog follows.
+type headWatchChain interface {
+	CurrentHeader() *types.Header
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// headWatchServer is the subset of the p2p server used to re-dial peers.
+type headWatchServer interface {
+	AddPeer(node *enode.Node)
+}
+
+// headWatchdog detects a node which stopped importing new heads despite being
+// connected to peers, and rotates its peers to get it moving again. A running
+// sync is considered progress, as snap sync doesn't import heads until done.
+type headWatchdog struct {
+	chain   headWatchChain
+	handler *handler
+	server  headWatchServer
+	syncing func() bool   // Whether the downloader is synchronising
+	limit   time.Duration // Head age after which the node is considered stuck
+
+	feed event.Feed
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newHeadWatchdog creates a watchdog rotating the peers whenever the chain head
+// is older than the given number of block periods.
+func newHeadWatchdog(chain headWatchChain, handler *handler, server headWatchServer, period time.Duration, periods uint64) *headWatchdog {
+	return &headWatchdog{
+		chain:   chain,
+		handler: handler,
+		server:  server,
+		syncing: handler.downloader.Synchronising,
+		limit:   period * time.Duration(periods),
+		quit:    make(chan struct{}),
+	}
+}
+
+// Start launches the watchdog loop.
+func (w *headWatchdog) Start() {
+	w.wg.Add(1)
+	go w.loop()
+}
+
+// Stop terminates the watchdog loop.
+func (w *headWatchdog) Stop() {
+	close(w.quit)
+	w.wg.Wait()
+}
+
+// SubscribeHeadLagEvent registers a subscription for peer rotations triggered
+// by a stalled chain head.
+func (w *headWatchdog) SubscribeHeadLagEvent(ch chan<- HeadLagEvent) event.Subscription {
+	return w.feed.Subscribe(ch)
+}
+
+func (w *headWatchdog) loop() {
+	defer w.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := w.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	// Check a few times per limit, and after a rotation give the new peers a
+	// full limit to deliver a head before rotating again.
+	ticker := time.NewTicker(w.limit / 4)
+	defer ticker.Stop()
+
+	var (
+		imported = time.Now()
+		rotated  time.Time
+	)
+	for {
+		select {
+		case <-heads:
+			imported = time.Now()
+			headLagGauge.Update(0)
+
+		case <-ticker.C:
+			if w.syncing() {
+				// Rotating would abort the sync, give it a full limit once done
+				imported = time.Now()
+				headLagGauge.Update(0)
+				continue
+			}
+			lag := time.Since(imported)
+			headLagGauge.Update(int64(lag / time.Second))
+			if lag < w.limit || time.Since(rotated) < w.limit {
+				continue
+			}
+			peers := w.handler.peers.len()
+			if peers == 0 {
+				// Nothing to rotate, the server is dialing anyway
+				continue
+			}
+			head := w.chain.CurrentHeader()
+			log.Warn("Chain head stalled, rotating peers", "number", head.Number, "hash", head.Hash(), "lag", common.PrettyDuration(lag), "peers", peers)
+
+			dropped, redialed := w.rotate()
+			rotated = time.Now()
+			headLagMeter.Mark(1)
+			peerDropMeter.Mark(int64(dropped + redialed))
+
+			w.feed.Send(HeadLagEvent{
+				Number:   head.Number.Uint64(),
+				Hash:     head.Hash(),
+				Lag:      lag,
+				Peers:    peers,
+				Dropped:  dropped,
+				Redialed: redialed,
+			})
+
+		case <-sub.Err():
+			return
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// rotate disconnects the validator mesh peers and re-dials them, and drops half
+// of the remaining peers to make room for fresh ones.
+func (w *headWatchdog) rotate() (dropped int, redialed int) {
+	var mesh, others []*ethPeer
+
+	w.handler.peers.lock.RLock()
+	for _, p := range w.handler.peers.peers {
+		if p.Peer.Info().Network.Trusted {
+			mesh = append(mesh, p)
+		} else {
+			others = append(others, p)
+		}
+	}
+	w.handler.peers.lock.RUnlock()
+
+	for _, p := range mesh {
+		node := p.Peer.Node()
+		p.Peer.Disconnect(p2p.DiscRequested)
+		w.server.AddPeer(node)
+		redialed++
+	}
+	for _, p := range others[:(len(others)+1)/2] {
+		p.Peer.Disconnect(p2p.DiscUselessPeer)
+		dropped++
+	}
+	return dropped, redialed
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// testHeadWatchServer is a p2p server not re-dialing any peers.
+type testHeadWatchServer struct{}
+
+func (testHeadWatchServer) AddPeer(node *enode.Node) {}
+
+// Tests that the head watchdog rotates the peers of a stalled node, but leaves
+// them be while the downloader is synchronising.
+func TestHeadWatchdog(t *testing.T) {
+	handler := newTestHandler()
+	defer handler.close()
+
+	for i := 0; i < 2; i++ {
+		peer := eth.NewPeer(eth.ETH66, p2p.NewPeer(enode.ID{byte(i + 1)}, "", nil), nil, nil)
+		if err := handler.handler.peers.registerPeer(peer, nil, nil); err != nil {
+			t.Fatalf("failed to register peer: %v", err)
+		}
+	}
+	var syncing int32 = 1
+
+	watchdog := newHeadWatchdog(handler.chain, handler.handler, testHeadWatchServer{}, 10*time.Millisecond, 4)
+	watchdog.syncing = func() bool { return atomic.LoadInt32(&syncing) == 1 }
+
+	events := make(chan HeadLagEvent, 1)
+	sub := watchdog.SubscribeHeadLagEvent(events)
+	defer sub.Unsubscribe()
+
+	watchdog.Start()
+	defer watchdog.Stop()
+
+	select {
+	case ev := <-events:
+		t.Fatalf("peers rotated while synchronising: %+v", ev)
+	case <-time.After(200 * time.Millisecond):
+	}
+	atomic.StoreInt32(&syncing, 0)
+
+	select {
+	case ev := <-events:
+		if ev.Peers != 2 || ev.Dropped != 1 {
+			t.Errorf("rotation mismatch: have %d peers and %d dropped, want 2 and 1", ev.Peers, ev.Dropped)
+		}
+		if ev.Lag < 40*time.Millisecond {
+			t.Errorf("peers rotated before the limit: lag %v", ev.Lag)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("stalled head not detected after the sync")
+	}
+}
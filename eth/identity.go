@@ -0,0 +1,154 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// identityDomain separates identity attestation digests from any other data
+// signed by the same keys.
+const identityDomain = "aksara-node-identity"
+
+// maxIdentityNonce is the maximum size of the nonce of an identity attestation.
+const maxIdentityNonce = 256
+
+// IdentityAttestation binds the node's enode, its chain head and its clique
+// signer (if any) to a verifier chosen nonce. The node key signs the digest of
+// the attestation and, if the node is a sealer, the signer key signs it too as
+// an EIP-191 text message, so both keys are proven to be controlled by the same
+// operator.
+type IdentityAttestation struct {
+	Nonce  hexutil.Bytes   `json:"nonce"`
+	Enode  string          `json:"enode"`
+	Number hexutil.Uint64  `json:"number"` // Number of the chain head
+	Hash   common.Hash     `json:"hash"`   // Hash of the chain head
+	Signer *common.Address `json:"signer,omitempty"`
+
+	Digest          common.Hash   `json:"digest"`
+	NodeSignature   hexutil.Bytes `json:"nodeSignature"`
+	SignerSignature hexutil.Bytes `json:"signerSignature,omitempty"`
+}
+
+// digest computes the hash signed by the attesting keys.
+func (a *IdentityAttestation) digest() common.Hash {
+	var signer common.Address
+	if a.Signer != nil {
+		signer = *a.Signer
+	}
+	blob, _ := rlp.EncodeToBytes([]interface{}{identityDomain, []byte(a.Nonce), a.Enode, uint64(a.Number), a.Hash, signer})
+	return crypto.Keccak256Hash(blob)
+}
+
+// Verify checks that the attestation is signed by the key of its enode and, if
+// it names a signer, by the signer key too.
+func (a *IdentityAttestation) Verify() error {
+	node, err := enode.ParseV4(a.Enode)
+	if err != nil {
+		return fmt.Errorf("invalid enode: %v", err)
+	}
+	digest := a.digest()
+	if digest != a.Digest {
+		return errors.New("digest mismatch")
+	}
+	if len(a.NodeSignature) != crypto.SignatureLength {
+		return errors.New("invalid node signature length")
+	}
+	pubkey, err := crypto.SigToPub(digest[:], a.NodeSignature)
+	if err != nil {
+		return fmt.Errorf("invalid node signature: %v", err)
+	}
+	if enode.PubkeyToIDV4(pubkey) != node.ID() {
+		return errors.New("node signature not made by the enode key")
+	}
+	if a.Signer == nil {
+		return nil
+	}
+	if len(a.SignerSignature) != crypto.SignatureLength {
+		return errors.New("invalid signer signature length")
+	}
+	sig := common.CopyBytes(a.SignerSignature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pubkey, err = crypto.SigToPub(accounts.TextHash(digest[:]), sig)
+	if err != nil {
+		return fmt.Errorf("invalid signer signature: %v", err)
+	}
+	if crypto.PubkeyToAddress(*pubkey) != *a.Signer {
+		return errors.New("signer signature not made by the signer key")
+	}
+	return nil
+}
+
+// SignIdentity returns an attestation of the node's identity, chain head and
+// clique signer for the given nonce, signed by the node key and the signer key
+// if the node is authorized to seal blocks. Verifiers pick the nonce to prevent
+// replaying attestations.
+func (api *PrivateAdminAPI) SignIdentity(nonce hexutil.Bytes) (*IdentityAttestation, error) {
+	if len(nonce) == 0 || len(nonce) > maxIdentityNonce {
+		return nil, fmt.Errorf("nonce must be between 1 and %d bytes", maxIdentityNonce)
+	}
+	key := api.eth.p2pServer.PrivateKey
+	if key == nil {
+		return nil, errors.New("node key unavailable")
+	}
+	head := api.eth.BlockChain().CurrentHeader()
+	attestation := &IdentityAttestation{
+		Nonce:  nonce,
+		Enode:  api.eth.p2pServer.Self().URLv4(),
+		Number: hexutil.Uint64(head.Number.Uint64()),
+		Hash:   head.Hash(),
+	}
+	var wallet accounts.Wallet
+	if cli := api.eth.cliqueEngine(); cli != nil {
+		if signer := cli.Signer(); signer != (common.Address{}) {
+			w, err := api.eth.signingWallet(signer)
+			if err != nil {
+				return nil, fmt.Errorf("signer %v unavailable: %v", signer, err)
+			}
+			wallet, attestation.Signer = w, &signer
+		}
+	}
+	attestation.Digest = attestation.digest()
+
+	sig, err := crypto.Sign(attestation.Digest[:], key)
+	if err != nil {
+		return nil, err
+	}
+	attestation.NodeSignature = sig
+
+	if wallet != nil {
+		sig, err := wallet.SignText(accounts.Account{Address: *attestation.Signer}, attestation.Digest[:])
+		if err != nil {
+			return nil, fmt.Errorf("signer signature failed: %v", err)
+		}
+		if sig[crypto.RecoveryIDOffset] < 27 {
+			sig[crypto.RecoveryIDOffset] += 27 // Transform V from 0/1 to 27/28 like personal_sign
+		}
+		attestation.SignerSignature = sig
+	}
+	return attestation, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Tests that identity attestations verify, and that tampering with any of the
+// attested fields is detected.
+func TestIdentityAttestation(t *testing.T) {
+	nodeKey, _ := crypto.GenerateKey()
+	signerKey, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(signerKey.PublicKey)
+
+	attest := func() *IdentityAttestation {
+		a := &IdentityAttestation{
+			Nonce:  []byte("challenge"),
+			Enode:  enode.NewV4(&nodeKey.PublicKey, nil, 30303, 30303).URLv4(),
+			Number: 100,
+			Hash:   common.HexToHash("0x01"),
+			Signer: &signer,
+		}
+		a.Digest = a.digest()
+		a.NodeSignature, _ = crypto.Sign(a.Digest[:], nodeKey)
+		a.SignerSignature, _ = crypto.Sign(accounts.TextHash(a.Digest[:]), signerKey)
+		a.SignerSignature[crypto.RecoveryIDOffset] += 27
+		return a
+	}
+	if err := attest().Verify(); err != nil {
+		t.Fatalf("valid attestation rejected: %v", err)
+	}
+	tampers := map[string]func(a *IdentityAttestation){
+		"nonce":  func(a *IdentityAttestation) { a.Nonce = []byte("replayed") },
+		"number": func(a *IdentityAttestation) { a.Number++ },
+		"signer": func(a *IdentityAttestation) { a.Signer = &common.Address{1} },
+		"enode": func(a *IdentityAttestation) {
+			other, _ := crypto.GenerateKey()
+			a.Enode = enode.NewV4(&other.PublicKey, nil, 30303, 30303).URLv4()
+		},
+	}
+	for name, tamper := range tampers {
+		a := attest()
+		tamper(a)
+		a.Digest = a.digest()
+		if err := a.Verify(); err == nil {
+			t.Errorf("tampered %s accepted", name)
+		}
+	}
+}
+
+// Tests that the identity is signed with the passphrase the signer was handed
+// over with, as blocks are sealed, rather than requiring an unlocked account.
+func TestIdentitySigningWallet(t *testing.T) {
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("passphrase")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	eth := &Ethereum{accountManager: accounts.NewManager(&accounts.Config{}, ks)}
+	defer eth.accountManager.Close()
+
+	wallet, err := eth.signingWallet(account.Address)
+	if err != nil {
+		t.Fatalf("failed to find wallet: %v", err)
+	}
+	if _, err := wallet.SignText(account, []byte("digest")); err == nil {
+		t.Fatalf("locked account signed without the handover passphrase")
+	}
+	eth.signer, eth.signerWallet = account.Address, &passphraseWallet{Wallet: wallet, passphrase: "passphrase"}
+	if wallet, err = eth.signingWallet(account.Address); err != nil {
+		t.Fatalf("failed to find handover wallet: %v", err)
+	}
+	if _, err := wallet.SignText(account, []byte("digest")); err != nil {
+		t.Fatalf("failed to sign with the handover passphrase: %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// errPendingIssuance is returned when requesting the issuance of the pending
+// block, whose receipts and state are not final.
+var errPendingIssuance = errors.New("issuance of the pending block is not available")
+
+// BlockIssuance is the value moved by a block outside of its transactions'
+// transfers: the base fee burnt, the priority fees credited to the beneficiary
+// and the protocol rewards credited when finalizing the block.
+type BlockIssuance struct {
+	Number      hexutil.Uint64                  `json:"number"`
+	Hash        common.Hash                     `json:"hash"`
+	Beneficiary common.Address                  `json:"beneficiary"` // Account credited with the tips, the sealer on clique chains
+	BaseFee     *hexutil.Big                    `json:"baseFeePerGas,omitempty"`
+	GasUsed     hexutil.Uint64                  `json:"gasUsed"`
+	Burnt       *hexutil.Big                    `json:"burnt"`       // Base fee times gas used, zero before London
+	Tips        *hexutil.Big                    `json:"tips"`        // Priority fees (all fees before London) credited to the beneficiary
+	Rewards     map[common.Address]*hexutil.Big `json:"rewards"`     // Protocol rewards credited when finalizing, none on clique chains
+	SupplyDelta *hexutil.Big                    `json:"supplyDelta"` // Rewards minus the burnt fees
+}
+
+// PublicIssuanceAPI provides the accounting of the value issued and burnt by
+// blocks, for reconciling validator income without tracing.
+type PublicIssuanceAPI struct {
+	eth *Ethereum
+}
+
+// NewPublicIssuanceAPI creates a new block issuance API.
+func NewPublicIssuanceAPI(eth *Ethereum) *PublicIssuanceAPI {
+	return &PublicIssuanceAPI{eth: eth}
+}
+
+// GetBlockIssuance summarizes the fees burnt, the tips credited to the
+// beneficiary and the protocol rewards of a block. The rewards of non-clique
+// blocks are derived from the block's state, which has to be available.
+func (api *PublicIssuanceAPI) GetBlockIssuance(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*BlockIssuance, error) {
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber {
+		return nil, errPendingIssuance
+	}
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %v not found", blockNrOrHash)
+	}
+	receipts := api.eth.blockchain.GetReceiptsByHash(block.Hash())
+	if len(receipts) != len(block.Transactions()) {
+		return nil, fmt.Errorf("receipts of block #%d not found", block.NumberU64())
+	}
+	header := block.Header()
+
+	beneficiary := header.Coinbase
+	if header.Number.Sign() > 0 {
+		if beneficiary, err = api.eth.engine.Author(header); err != nil {
+			return nil, err
+		}
+	}
+	burnt, tips := blockFees(header, block.Transactions(), receipts)
+
+	rewards := make(map[common.Address]*big.Int)
+	if api.eth.cliqueEngine() == nil && header.Number.Sign() > 0 {
+		if rewards, err = api.blockRewards(block, beneficiary); err != nil {
+			return nil, err
+		}
+	}
+	issuance := &BlockIssuance{
+		Number:      hexutil.Uint64(header.Number.Uint64()),
+		Hash:        block.Hash(),
+		Beneficiary: beneficiary,
+		BaseFee:     (*hexutil.Big)(header.BaseFee),
+		GasUsed:     hexutil.Uint64(header.GasUsed),
+		Burnt:       (*hexutil.Big)(burnt),
+		Tips:        (*hexutil.Big)(tips),
+		Rewards:     make(map[common.Address]*hexutil.Big, len(rewards)),
+	}
+	supply := new(big.Int).Neg(burnt)
+	for account, reward := range rewards {
+		issuance.Rewards[account] = (*hexutil.Big)(reward)
+		supply.Add(supply, reward)
+	}
+	issuance.SupplyDelta = (*hexutil.Big)(supply)
+	return issuance, nil
+}
+
+// blockFees splits the fees paid by the transactions of a block into the base
+// fee burnt and the tips credited to the beneficiary.
+func blockFees(header *types.Header, txs types.Transactions, receipts types.Receipts) (burnt *big.Int, tips *big.Int) {
+	burnt, tips = new(big.Int), new(big.Int)
+	if header.BaseFee != nil {
+		burnt.Mul(header.BaseFee, new(big.Int).SetUint64(header.GasUsed))
+	}
+	for i, tx := range txs {
+		tip := tx.GasPrice()
+		if header.BaseFee != nil {
+			tip = tx.EffectiveGasTipValue(header.BaseFee)
+		}
+		tips.Add(tips, tip.Mul(tip, new(big.Int).SetUint64(receipts[i].GasUsed)))
+	}
+	return burnt, tips
+}
+
+// blockRewards measures the protocol rewards credited when finalizing a block,
+// by finalizing it once more on top of its own state. Rewards are only credited
+// to the beneficiary and the uncle miners, and are independent of the state,
+// so they show up as the balance differences of these accounts.
+func (api *PublicIssuanceAPI) blockRewards(block *types.Block, beneficiary common.Address) (map[common.Address]*big.Int, error) {
+	statedb, err := api.eth.blockchain.StateAt(block.Root())
+	if err != nil {
+		return nil, err
+	}
+	accounts := []common.Address{beneficiary}
+	for _, uncle := range block.Uncles() {
+		accounts = append(accounts, uncle.Coinbase)
+	}
+	before := make(map[common.Address]*big.Int, len(accounts))
+	for _, account := range accounts {
+		before[account] = new(big.Int).Set(statedb.GetBalance(account))
+	}
+	api.eth.engine.Finalize(api.eth.blockchain, block.Header(), statedb, block.Transactions(), block.Uncles())
+
+	rewards := make(map[common.Address]*big.Int)
+	for account, balance := range before {
+		if reward := new(big.Int).Sub(statedb.GetBalance(account), balance); reward.Sign() != 0 {
+			rewards[account] = reward
+		}
+	}
+	return rewards, nil
+}
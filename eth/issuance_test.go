@@ -0,0 +1,52 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests that block fees are split into the burnt base fee and the tips of the
+// transactions, capped by their fee caps.
+func TestBlockFees(t *testing.T) {
+	var (
+		to  = common.Address{0x01}
+		txs = types.Transactions{
+			types.NewTx(&types.LegacyTx{To: &to, Gas: 21000, GasPrice: big.NewInt(15)}),
+			types.NewTx(&types.DynamicFeeTx{To: &to, Gas: 50000, GasFeeCap: big.NewInt(30), GasTipCap: big.NewInt(2)}),
+			types.NewTx(&types.DynamicFeeTx{To: &to, Gas: 50000, GasFeeCap: big.NewInt(11), GasTipCap: big.NewInt(5)}),
+		}
+		receipts = types.Receipts{{GasUsed: 21000}, {GasUsed: 30000}, {GasUsed: 40000}}
+	)
+	// Before London, the whole gas price is credited to the beneficiary
+	burnt, tips := blockFees(&types.Header{GasUsed: 91000}, txs[:1], receipts[:1])
+	if burnt.Sign() != 0 || tips.Cmp(big.NewInt(15*21000)) != 0 {
+		t.Errorf("pre-London fees mismatch: burnt %v, tips %v", burnt, tips)
+	}
+	// After London, the base fee is burnt and the tips are capped by the fee cap
+	burnt, tips = blockFees(&types.Header{GasUsed: 91000, BaseFee: big.NewInt(10)}, txs, receipts)
+	if want := big.NewInt(10 * 91000); burnt.Cmp(want) != 0 {
+		t.Errorf("burnt mismatch: have %v, want %v", burnt, want)
+	}
+	if want := big.NewInt(5*21000 + 2*30000 + 1*40000); tips.Cmp(want) != 0 {
+		t.Errorf("tips mismatch: have %v, want %v", tips, want)
+	}
+}
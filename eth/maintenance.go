@@ -0,0 +1,54 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/maintenance"
+)
+
+// inTurnPause creates the pause of the background state maintenance around the
+// slots the local signer is in-turn for: from the given window before the slot
+// of the next block until the window after it, or until the block is imported.
+func (s *Ethereum) inTurnPause(window time.Duration) maintenance.PauseFn {
+	var (
+		engine = s.cliqueEngine()
+		period = time.Duration(s.blockchain.Config().Clique.Period) * time.Second
+	)
+	return func() time.Duration {
+		if !s.IsMining() {
+			return 0
+		}
+		head := s.blockchain.CurrentHeader()
+		next := &types.Header{Number: new(big.Int).Add(head.Number, common.Big1), ParentHash: head.Hash()}
+		if _, local, err := engine.ExpectedSealer(s.blockchain, next); err != nil || !local {
+			return 0
+		}
+		var (
+			slot = time.Unix(int64(head.Time), 0).Add(period)
+			now  = engine.Now()
+		)
+		if now.Before(slot.Add(-window)) || !now.Before(slot.Add(window)) {
+			return 0
+		}
+		return slot.Add(window).Sub(now)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/miner"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// BlockOrdering reports whether the transactions of a block respect the
+// deterministic ordering of the miner.
+type BlockOrdering struct {
+	Number    hexutil.Uint64  `json:"number"`
+	Hash      common.Hash     `json:"hash"`
+	Sealer    common.Address  `json:"sealer"`
+	Committed uint8           `json:"committed"` // Ordering version announced in the vanity, zero if none
+	Version   uint8           `json:"version"`   // Ordering version verified against
+	Valid     bool            `json:"valid"`
+	Violation *hexutil.Uint64 `json:"violation,omitempty"` // Index of the first out of order transaction
+	Error     string          `json:"error,omitempty"`
+}
+
+// OrderingDeviation is a transaction included at another position than the
+// canonical ordering policy places it.
+type OrderingDeviation struct {
+	Hash     common.Hash    `json:"hash"`
+	From     common.Address `json:"from"`
+	Tip      *hexutil.Big   `json:"tip"`      // Effective tip paid to the sealer
+	Index    hexutil.Uint64 `json:"index"`    // Position in the block
+	Expected hexutil.Uint64 `json:"expected"` // Position under the canonical policy
+}
+
+// BlockOrderingAudit compares the transaction order of a block with the one
+// the canonical ordering policy derives from the transactions' fee parameters.
+type BlockOrderingAudit struct {
+	Number       hexutil.Uint64       `json:"number"`
+	Hash         common.Hash          `json:"hash"`
+	Sealer       common.Address       `json:"sealer"`
+	Committed    uint8                `json:"committed"` // Ordering version announced in the vanity, zero if none
+	Policy       uint8                `json:"policy"`    // Ordering version audited against
+	Transactions int                  `json:"transactions"`
+	Expected     []common.Hash        `json:"expected"`     // Transaction hashes in canonical order
+	Deviations   []*OrderingDeviation `json:"deviations"`   // Transactions out of their canonical position
+	Displacement hexutil.Uint64       `json:"displacement"` // Sum of the distances of the deviations from their positions
+}
+
+// PublicOrderingAPI verifies the transaction ordering of the blocks, so that the
+// validators sealing with deterministic ordering can be checked for reordering
+// transactions to their advantage.
+type PublicOrderingAPI struct {
+	eth *Ethereum
+}
+
+// NewPublicOrderingAPI creates a new transaction ordering verification API.
+func NewPublicOrderingAPI(eth *Ethereum) *PublicOrderingAPI {
+	return &PublicOrderingAPI{eth: eth}
+}
+
+// VerifyBlockOrdering checks that the transactions of a block could have been
+// picked in the deterministic order from some pending set. Blocks which don't
+// commit to the ordering are verified too, but only the ones that do violate
+// a promise if they fail.
+func (api *PublicOrderingAPI) VerifyBlockOrdering(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*BlockOrdering, error) {
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %v not found", blockNrOrHash)
+	}
+	header := block.Header()
+
+	res := &BlockOrdering{
+		Number:  hexutil.Uint64(header.Number.Uint64()),
+		Hash:    block.Hash(),
+		Sealer:  header.Coinbase,
+		Version: miner.DeterministicOrderingVersion,
+	}
+	if header.Number.Sign() > 0 {
+		if res.Sealer, err = api.eth.engine.Author(header); err != nil {
+			return nil, err
+		}
+	}
+	if vanity, err := clique.DecodeVanity(header.Extra); err == nil {
+		res.Committed = vanity.Ordering
+	}
+	signer := types.MakeSigner(api.eth.blockchain.Config(), header.Number)
+	if n, err := types.VerifyPriceNonceAndHashOrder(signer, block.Transactions(), header.BaseFee); err != nil {
+		violation := hexutil.Uint64(n)
+		res.Violation, res.Error = &violation, err.Error()
+	} else {
+		res.Valid = true
+	}
+	return res, nil
+}
+
+// AuditBlockOrdering recomputes the order of the transactions of a block under
+// the canonical ordering policy (by effective tip, then nonce, then hash, each
+// account's transactions in nonce order) and reports the transactions placed
+// elsewhere. Unlike VerifyBlockOrdering, it assumes no other pending transaction
+// was skipped, so it also flags the displacements a skipped transaction causes.
+func (api *PublicOrderingAPI) AuditBlockOrdering(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*BlockOrderingAudit, error) {
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %v not found", blockNrOrHash)
+	}
+	header := block.Header()
+
+	audit := &BlockOrderingAudit{
+		Number:       hexutil.Uint64(header.Number.Uint64()),
+		Hash:         block.Hash(),
+		Sealer:       header.Coinbase,
+		Policy:       miner.DeterministicOrderingVersion,
+		Transactions: len(block.Transactions()),
+		Deviations:   []*OrderingDeviation{},
+	}
+	if header.Number.Sign() > 0 {
+		if audit.Sealer, err = api.eth.engine.Author(header); err != nil {
+			return nil, err
+		}
+	}
+	if vanity, err := clique.DecodeVanity(header.Extra); err == nil {
+		audit.Committed = vanity.Ordering
+	}
+	// Group the transactions by account, keeping their nonce order, and replay
+	// the canonical policy over them
+	var (
+		signer  = types.MakeSigner(api.eth.blockchain.Config(), header.Number)
+		senders = make([]common.Address, len(block.Transactions()))
+		groups  = make(map[common.Address]types.Transactions)
+		index   = make(map[common.Hash]int)
+	)
+	for i, tx := range block.Transactions() {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		senders[i] = from
+		groups[from] = append(groups[from], tx)
+		index[tx.Hash()] = i
+	}
+	txset := types.NewTransactionsByPriceNonceAndHash(signer, groups, header.BaseFee)
+	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
+		audit.Expected = append(audit.Expected, tx.Hash())
+		txset.Shift()
+	}
+	if len(audit.Expected) != len(block.Transactions()) {
+		return nil, fmt.Errorf("block #%d contains transactions below the base fee", block.NumberU64())
+	}
+	for expected, hash := range audit.Expected {
+		i := index[hash]
+		if i == expected {
+			continue
+		}
+		tx := block.Transactions()[i]
+		tip, _ := tx.EffectiveGasTip(header.BaseFee)
+		audit.Deviations = append(audit.Deviations, &OrderingDeviation{
+			Hash:     hash,
+			From:     senders[i],
+			Tip:      (*hexutil.Big)(tip),
+			Index:    hexutil.Uint64(i),
+			Expected: hexutil.Uint64(expected),
+		})
+		distance := i - expected
+		if distance < 0 {
+			distance = -distance
+		}
+		audit.Displacement += hexutil.Uint64(distance)
+	}
+	return audit, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethdb/remotedb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// setupStateOverlay forks the configured genesis off the state of the remote
+// node if the local database is still empty. The local chain shares no blocks
+// with the remote one, only its state at the pinned block, which is pulled in
+// lazily as it is accessed.
+func setupStateOverlay(db *remotedb.Overlay, config *ethconfig.Config) error {
+	// Snapshot generation would iterate, and thus download, the entire state
+	if config.SnapshotCache > 0 {
+		log.Info("Disabling state snapshots for state overlay")
+		config.SnapshotCache = 0
+	}
+	if hash := rawdb.ReadCanonicalHash(db, 0); hash != (common.Hash{}) {
+		log.Info("Reusing existing state overlay chain", "genesis", hash)
+		config.Genesis = nil
+		return nil
+	}
+	if config.Genesis == nil {
+		return errors.New("state overlay requires a genesis specification")
+	}
+	tag := "latest"
+	if config.StateOverlayBlock != nil {
+		tag = hexutil.EncodeUint64(*config.StateOverlayBlock)
+	}
+	var parent *types.Header
+	if err := db.Client().Call(&parent, "eth_getBlockByNumber", tag, false); err != nil {
+		return fmt.Errorf("failed to retrieve overlay block %s: %v", tag, err)
+	}
+	if parent == nil {
+		return fmt.Errorf("overlay block %s not found", tag)
+	}
+	block, err := config.Genesis.CommitFork(db, parent)
+	if err != nil {
+		return fmt.Errorf("failed to fork overlay genesis: %v", err)
+	}
+	log.Info("Forked genesis off remote state", "number", parent.Number, "hash", parent.Hash(), "root", parent.Root, "genesis", block.Hash())
+	config.Genesis = nil
+	return nil
+}
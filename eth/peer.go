@@ -19,6 +19,7 @@ package eth
 import (
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 )
@@ -29,13 +30,16 @@ type ethPeerInfo struct {
 	Version    uint     `json:"version"`    // Ethereum protocol version negotiated
 	Difficulty *big.Int `json:"difficulty"` // Total difficulty of the peer's blockchain
 	Head       string   `json:"head"`       // Hex hash of the peer's best owned block
+
+	Validator *common.Address `json:"validator,omitempty"` // Clique signer proven to run the peer, if any
 }
 
 // ethPeer is a wrapper around eth.Peer to maintain a few extra metadata.
 type ethPeer struct {
 	*eth.Peer
-	snapExt  *snapPeer     // Satellite `snap` connection
-	snapWait chan struct{} // Notification channel for snap connections
+	snapExt   *snapPeer       // Satellite `snap` connection
+	snapWait  chan struct{}   // Notification channel for snap connections
+	validator *common.Address // Clique signer proven by the node record to run the peer
 }
 
 // info gathers and returns some `eth` protocol metadata known about a peer.
@@ -46,6 +50,7 @@ func (p *ethPeer) info() *ethPeerInfo {
 		Version:    p.Version(),
 		Difficulty: td,
 		Head:       hash.Hex(),
+		Validator:  p.validator,
 	}
 }
 
@@ -132,8 +132,9 @@ func (ps *peerSet) waitSnapExtension(peer *eth.Peer) (*snap.Peer, error) {
 }
 
 // registerPeer injects a new `eth` peer into the working set, or returns an error
-// if the peer is already known.
-func (ps *peerSet) registerPeer(peer *eth.Peer, ext *snap.Peer) error {
+// if the peer is already known. The validator is the clique signer proven to run
+// the peer, if any.
+func (ps *peerSet) registerPeer(peer *eth.Peer, ext *snap.Peer, validator *common.Address) error {
 	// Start tracking the new peer
 	ps.lock.Lock()
 	defer ps.lock.Unlock()
@@ -146,7 +147,8 @@ func (ps *peerSet) registerPeer(peer *eth.Peer, ext *snap.Peer) error {
 		return errPeerAlreadyRegistered
 	}
 	eth := &ethPeer{
-		Peer: peer,
+		Peer:      peer,
+		validator: validator,
 	}
 	if ext != nil {
 		eth.snapExt = &snapPeer{ext}
@@ -229,6 +231,20 @@ func (ps *peerSet) snapLen() int {
 	return ps.snapPeers
 }
 
+// hasValidator returns whether a peer run by the given clique signer is already
+// connected.
+func (ps *peerSet) hasValidator(signer common.Address) bool {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	for _, p := range ps.peers {
+		if p.validator != nil && *p.validator == signer {
+			return true
+		}
+	}
+	return false
+}
+
 // peerWithHighestTD retrieves the known peer with the currently highest total
 // difficulty, but below the given PoS switchover threshold.
 func (ps *peerSet) peerWithHighestTD() *eth.Peer {
@@ -247,6 +263,28 @@ func (ps *peerSet) peerWithHighestTD() *eth.Peer {
 	return bestPeer
 }
 
+// trustedPeerWithHighestTD retrieves the trusted or static peer with the
+// currently highest total difficulty, nil if none is connected. Unlike those of
+// arbitrary peers, their advertised heads can be relied upon.
+func (ps *peerSet) trustedPeerWithHighestTD() *eth.Peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	var (
+		bestPeer *eth.Peer
+		bestTd   *big.Int
+	)
+	for _, p := range ps.peers {
+		if info := p.Peer.Info(); !info.Network.Trusted && !info.Network.Static {
+			continue
+		}
+		if _, td := p.Head(); bestPeer == nil || td.Cmp(bestTd) > 0 {
+			bestPeer, bestTd = p.Peer, td
+		}
+	}
+	return bestPeer
+}
+
 // close disconnects all peers.
 func (ps *peerSet) close() {
 	ps.lock.Lock()
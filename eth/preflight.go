@@ -0,0 +1,214 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/shirou/gopsutil/disk"
+)
+
+// preflightInterval is the time between two runs of the startup checks while
+// they don't pass.
+const preflightInterval = 10 * time.Second
+
+// preflight holds back clique sealing when mining starts until the startup
+// checks of the validator pass: the chain is synced with the peers, the local
+// clock is accurate, the signer is authorized and not sealing from another
+// datadir, and the disk has room left. The outcome is logged and reported by
+// the clique health API.
+type preflight struct {
+	eth         *Ethereum
+	engine      *clique.Clique
+	maxLag      uint64 // Blocks the node may trail its best peer by
+	minFreeDisk uint64 // Bytes of free disk space required
+	datadir     string
+
+	once sync.Once
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newPreflight(eth *Ethereum, engine *clique.Clique, maxLag uint64, minFreeDisk uint64, datadir string) *preflight {
+	return &preflight{
+		eth:         eth,
+		engine:      engine,
+		maxLag:      maxLag,
+		minFreeDisk: minFreeDisk * 1024 * 1024,
+		datadir:     datadir,
+		quit:        make(chan struct{}),
+	}
+}
+
+// start holds back sealing and runs the checks until they pass. Only the first
+// start of the mining is gated.
+func (p *preflight) start() {
+	p.once.Do(func() {
+		p.engine.SetPreflight(&clique.PreflightStatus{Checked: time.Now()})
+
+		p.wg.Add(1)
+		go p.loop()
+	})
+}
+
+// stop terminates the checks if they're still running.
+func (p *preflight) stop() {
+	close(p.quit)
+	p.wg.Wait()
+}
+
+func (p *preflight) loop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			status := p.run()
+			p.engine.SetPreflight(status)
+			if status.Passed {
+				log.Info("Validator preflight passed, sealing enabled")
+				return
+			}
+			for _, check := range status.Checks {
+				if !check.Passed {
+					log.Warn("Validator preflight failed, holding back sealing", "check", check.Name, "reason", check.Detail)
+				}
+			}
+			timer.Reset(preflightInterval)
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// run evaluates all the startup checks.
+func (p *preflight) run() *clique.PreflightStatus {
+	status := &clique.PreflightStatus{Passed: true, Checked: time.Now()}
+	add := func(name string, err error, detail string) {
+		check := clique.PreflightCheck{Name: name, Passed: err == nil, Detail: detail}
+		if err != nil {
+			check.Detail, status.Passed = err.Error(), false
+		}
+		status.Checks = append(status.Checks, check)
+	}
+	signer := p.engine.Signer()
+
+	detail, err := p.checkSync(signer)
+	add("sync", err, detail)
+	detail, err = p.checkClock()
+	add("clock", err, detail)
+	add("signer", p.checkSigner(signer), signer.Hex())
+	add("slashProtection", p.engine.CheckSlashProtection(p.eth.blockchain, signer), "")
+	detail, err = p.checkDisk()
+	add("disk", err, detail)
+
+	return status
+}
+
+// checkSync verifies that the chain is within the allowed lag of the highest
+// block known to the downloader, and of the best trusted or static peer. The
+// heads advertised by other peers are unverified, and are not relied upon.
+// Without peers, only a sole signer may seal.
+func (p *preflight) checkSync(signer common.Address) (string, error) {
+	var (
+		chain  = p.eth.blockchain
+		head   = chain.CurrentHeader()
+		number = head.Number.Uint64()
+	)
+	if progress := p.eth.Downloader().Progress(); progress.HighestBlock > number+p.maxLag {
+		return "", fmt.Errorf("syncing, at block %d of %d", number, progress.HighestBlock)
+	}
+	if p.eth.handler.peers.len() == 0 {
+		signers, err := p.engine.SignerCount(chain)
+		if err != nil {
+			return "", err
+		}
+		if signers > 1 {
+			return "", fmt.Errorf("no peers, %d signers authorized", signers)
+		}
+		return fmt.Sprintf("sole signer at block %d", number), nil
+	}
+	peer := p.eth.handler.peers.trustedPeerWithHighestTD()
+	if peer == nil {
+		return fmt.Sprintf("at block %d, no trusted peers", number), nil
+	}
+	// Clique blocks weigh at most 2, so the difficulty gap bounds the lag
+	_, peerTd := peer.Head()
+	localTd := chain.GetTd(head.Hash(), number)
+	if localTd == nil {
+		return "", fmt.Errorf("total difficulty of head %d missing", number)
+	}
+	if gap := new(big.Int).Sub(peerTd, localTd); gap.Cmp(new(big.Int).SetUint64(2*p.maxLag)) > 0 {
+		return "", fmt.Errorf("trusted peer %s ahead by %v difficulty at block %d", peer.ID(), gap, number)
+	}
+	return fmt.Sprintf("at block %d", number), nil
+}
+
+// checkClock verifies that the last clock check found the local clock accurate.
+func (p *preflight) checkClock() (string, error) {
+	status, enabled := p.engine.ClockStatus()
+	switch {
+	case !enabled:
+		return "check disabled", nil
+	case status == nil:
+		return "", fmt.Errorf("clock not checked yet")
+	case !status.Healthy:
+		return "", fmt.Errorf("drifted %.3fs, max %.3fs", *status.Drift, status.MaxDrift)
+	case status.Drift == nil:
+		return "no NTP server answered", nil
+	}
+	return fmt.Sprintf("drift %.3fs", *status.Drift), nil
+}
+
+// checkSigner verifies that the signer is authorized at the head of the chain.
+func (p *preflight) checkSigner(signer common.Address) error {
+	if signer == (common.Address{}) {
+		return fmt.Errorf("no signer configured")
+	}
+	authorized, err := p.engine.IsSigner(p.eth.blockchain, signer)
+	if err != nil {
+		return err
+	}
+	if !authorized {
+		return fmt.Errorf("%s not authorized in the latest snapshot", signer)
+	}
+	return nil
+}
+
+// checkDisk verifies that the disk holding the datadir has room left.
+func (p *preflight) checkDisk() (string, error) {
+	if p.datadir == "" {
+		return "ephemeral datadir", nil
+	}
+	usage, err := disk.Usage(p.datadir)
+	if err != nil {
+		return "", err
+	}
+	if usage.Free < p.minFreeDisk {
+		return "", fmt.Errorf("%v free, %v required", common.StorageSize(usage.Free), common.StorageSize(p.minFreeDisk))
+	}
+	return fmt.Sprintf("%v free", common.StorageSize(usage.Free)), nil
+}
@@ -0,0 +1,323 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	maxPropagationBlocks = 256 // Number of recent blocks the propagation is tracked for
+	minLatencyBlocks     = 8   // Number of in-turn blocks observed before estimating the latency
+	latencyPercentile    = 90  // Percentile of the first sighting delays taken as the latency
+)
+
+// inturnDifficulty is the difficulty of in-turn clique blocks, which are sealed
+// at their timestamp rather than after a random delay.
+var inturnDifficulty = big.NewInt(2)
+
+var (
+	propAnnounceTimer = metrics.NewRegisteredTimer("eth/propagation/announce", nil) // Block timestamp to first announcement
+	propBlockTimer    = metrics.NewRegisteredTimer("eth/propagation/block", nil)    // Block timestamp to first full block
+	propFetchTimer    = metrics.NewRegisteredTimer("eth/propagation/fetch", nil)    // First announcement to first full block
+	propImportTimer   = metrics.NewRegisteredTimer("eth/propagation/import", nil)   // Block timestamp to import completion
+)
+
+// peerPropagation is the time a peer first announced and delivered a block.
+type peerPropagation struct {
+	announced time.Time
+	received  time.Time
+}
+
+// blockPropagation is the propagation timeline of a block across the peers.
+type blockPropagation struct {
+	number    uint64
+	hash      common.Hash
+	timestamp uint64 // Header timestamp, zero until the block arrived
+	inturn    bool   // Whether the block was sealed in-turn, unknown until the block arrived
+
+	announced time.Time // First announcement by any peer
+	announcer string
+	received  time.Time // First full block, broadcast or fetched
+	sender    string    // Peer broadcasting the first full block, empty if fetched
+	imported  time.Time
+
+	peers map[string]*peerPropagation
+}
+
+// propagationTracker records when recent blocks were first announced, received
+// in full and imported, overall and per peer.
+type propagationTracker struct {
+	blocks map[common.Hash]*blockPropagation
+	order  []common.Hash // Tracked blocks, oldest first
+	lock   sync.Mutex
+}
+
+// newPropagationTracker creates an empty block propagation tracker.
+func newPropagationTracker() *propagationTracker {
+	return &propagationTracker{
+		blocks: make(map[common.Hash]*blockPropagation),
+	}
+}
+
+// block returns the record of a block, starting to track it if unknown. The
+// caller must hold the lock.
+func (t *propagationTracker) block(hash common.Hash, number uint64) *blockPropagation {
+	if record := t.blocks[hash]; record != nil {
+		return record
+	}
+	if len(t.order) >= maxPropagationBlocks {
+		delete(t.blocks, t.order[0])
+		t.order = t.order[1:]
+	}
+	record := &blockPropagation{number: number, hash: hash, peers: make(map[string]*peerPropagation)}
+	t.blocks[hash] = record
+	t.order = append(t.order, hash)
+	return record
+}
+
+// peer returns the record of a peer for a block. The caller must hold the lock.
+func (record *blockPropagation) peer(id string) *peerPropagation {
+	p := record.peers[id]
+	if p == nil {
+		p = new(peerPropagation)
+		record.peers[id] = p
+	}
+	return p
+}
+
+// announced records a peer announcing a block.
+func (t *propagationTracker) announced(peer string, hash common.Hash, number uint64, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	record := t.block(hash, number)
+	if p := record.peer(peer); p.announced.IsZero() {
+		p.announced = now
+	}
+	if record.announced.IsZero() {
+		record.announced, record.announcer = now, peer
+	}
+}
+
+// received records a full block arriving, either broadcast by a peer or fetched
+// after an announcement, in which case the peer is empty.
+func (t *propagationTracker) received(peer string, block *types.Block, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	record := t.block(block.Hash(), block.NumberU64())
+	if peer != "" {
+		if p := record.peer(peer); p.received.IsZero() {
+			p.received = now
+		}
+	}
+	if !record.received.IsZero() {
+		return
+	}
+	record.received, record.sender, record.timestamp = now, peer, block.Time()
+	record.inturn = block.Difficulty().Cmp(inturnDifficulty) == 0
+
+	sealed := time.Unix(int64(record.timestamp), 0)
+	propBlockTimer.Update(nonNegative(now.Sub(sealed)))
+	if !record.announced.IsZero() && !record.announced.After(now) {
+		propAnnounceTimer.Update(nonNegative(record.announced.Sub(sealed)))
+		propFetchTimer.Update(now.Sub(record.announced))
+	}
+}
+
+// imported records a block having been imported into the chain.
+func (t *propagationTracker) imported(block *types.Block, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	record := t.blocks[block.Hash()]
+	if record == nil || !record.imported.IsZero() {
+		return
+	}
+	record.imported, record.timestamp = now, block.Time()
+	propImportTimer.Update(nonNegative(now.Sub(time.Unix(int64(record.timestamp), 0))))
+}
+
+// latency estimates the block propagation latency of the network as the 90th
+// percentile of the delays between the timestamps of the recent in-turn blocks
+// and their first announcement or delivery. Out-of-turn blocks are skipped as
+// their delay includes the random wiggle.
+func (t *propagationTracker) latency() (time.Duration, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var delays []time.Duration
+	for _, record := range t.blocks {
+		if record.received.IsZero() || !record.inturn {
+			continue
+		}
+		seen := record.received
+		if !record.announced.IsZero() && record.announced.Before(seen) {
+			seen = record.announced
+		}
+		delays = append(delays, nonNegative(seen.Sub(time.Unix(int64(record.timestamp), 0))))
+	}
+	if len(delays) < minLatencyBlocks {
+		return 0, false
+	}
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+	return delays[(len(delays)-1)*latencyPercentile/100], true
+}
+
+// nonNegative clamps durations skewed below zero by clock drift.
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// millis converts a duration to fractional milliseconds.
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// PropagationEvent is the first completion of a propagation step of a block.
+type PropagationEvent struct {
+	Peer  string    `json:"peer,omitempty"` // Peer the step was first completed through, if known
+	Time  time.Time `json:"time"`
+	Delay *float64  `json:"delay,omitempty"` // Milliseconds since the block's timestamp, unset until the block arrived
+}
+
+// PeerPropagation is the lag of a peer behind the first one to announce and to
+// deliver a block.
+type PeerPropagation struct {
+	Peer      string   `json:"peer"`
+	Announced *float64 `json:"announced,omitempty"` // Milliseconds after the first announcement
+	Received  *float64 `json:"received,omitempty"`  // Milliseconds after the first full block
+}
+
+// BlockPropagation is the propagation timeline of a block.
+type BlockPropagation struct {
+	Number    hexutil.Uint64     `json:"number"`
+	Hash      common.Hash        `json:"hash"`
+	Timestamp uint64             `json:"timestamp,omitempty"` // Unset until the block arrived
+	Announced *PropagationEvent  `json:"announced,omitempty"`
+	Received  *PropagationEvent  `json:"received,omitempty"`
+	Imported  *PropagationEvent  `json:"imported,omitempty"`
+	Peers     []*PeerPropagation `json:"peers"`
+}
+
+// PeerLatency is the average lag of a peer behind the first one to announce and
+// to deliver the blocks.
+type PeerLatency struct {
+	Peer          string  `json:"peer"`
+	Announcements int     `json:"announcements"`
+	AnnounceLag   float64 `json:"announceLag"` // Average milliseconds after the first announcement
+	Blocks        int     `json:"blocks"`
+	BlockLag      float64 `json:"blockLag"` // Average milliseconds after the first full block
+}
+
+// PropagationStats is the propagation of the recent blocks.
+type PropagationStats struct {
+	Blocks []*BlockPropagation `json:"blocks"` // Most recently seen first
+	Peers  []*PeerLatency      `json:"peers"`  // Averaged over the blocks reported
+}
+
+// stats reports the propagation of the last n blocks seen.
+func (t *propagationTracker) stats(n int) *PropagationStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var (
+		stats   = &PropagationStats{Blocks: []*BlockPropagation{}, Peers: []*PeerLatency{}}
+		latency = make(map[string]*PeerLatency)
+	)
+	for i := len(t.order) - 1; i >= 0 && len(stats.Blocks) < n; i-- {
+		record := t.blocks[t.order[i]]
+		block := &BlockPropagation{
+			Number:    hexutil.Uint64(record.number),
+			Hash:      record.hash,
+			Timestamp: record.timestamp,
+			Peers:     make([]*PeerPropagation, 0, len(record.peers)),
+		}
+		event := func(peer string, at time.Time) *PropagationEvent {
+			if at.IsZero() {
+				return nil
+			}
+			event := &PropagationEvent{Peer: peer, Time: at}
+			if record.timestamp != 0 {
+				delay := millis(at.Sub(time.Unix(int64(record.timestamp), 0)))
+				event.Delay = &delay
+			}
+			return event
+		}
+		block.Announced = event(record.announcer, record.announced)
+		block.Received = event(record.sender, record.received)
+		block.Imported = event("", record.imported)
+
+		for id, p := range record.peers {
+			stat := latency[id]
+			if stat == nil {
+				stat = &PeerLatency{Peer: id}
+				latency[id] = stat
+			}
+			peer := &PeerPropagation{Peer: id}
+			if !p.announced.IsZero() {
+				lag := millis(p.announced.Sub(record.announced))
+				peer.Announced = &lag
+				stat.Announcements++
+				stat.AnnounceLag += lag
+			}
+			if !p.received.IsZero() {
+				lag := millis(nonNegative(p.received.Sub(record.received)))
+				peer.Received = &lag
+				stat.Blocks++
+				stat.BlockLag += lag
+			}
+			block.Peers = append(block.Peers, peer)
+		}
+		sort.Slice(block.Peers, func(i, j int) bool { return block.Peers[i].Peer < block.Peers[j].Peer })
+		stats.Blocks = append(stats.Blocks, block)
+	}
+	for _, stat := range latency {
+		if stat.Announcements > 0 {
+			stat.AnnounceLag /= float64(stat.Announcements)
+		}
+		if stat.Blocks > 0 {
+			stat.BlockLag /= float64(stat.Blocks)
+		}
+		stats.Peers = append(stats.Peers, stat)
+	}
+	sort.Slice(stats.Peers, func(i, j int) bool { return stats.Peers[i].Peer < stats.Peers[j].Peer })
+	return stats
+}
+
+// PropagationStats reports when the last n blocks seen were first announced,
+// received in full and imported, along with the lag of each peer behind the
+// fastest one, to diagnose the propagation delays causing out-of-turn races.
+func (api *PrivateDebugAPI) PropagationStats(n uint64) (*PropagationStats, error) {
+	if n == 0 || n > maxPropagationBlocks {
+		return nil, fmt.Errorf("invalid block count %d, must be between 1 and %d", n, maxPropagationBlocks)
+	}
+	return api.eth.handler.propagation.stats(int(n)), nil
+}
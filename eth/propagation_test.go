@@ -0,0 +1,121 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests that the propagation timeline of blocks is tracked overall and per peer.
+func TestPropagationTracker(t *testing.T) {
+	var (
+		tracker = newPropagationTracker()
+		sealed  = time.Unix(1650000000, 0)
+		block   = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10), Time: uint64(sealed.Unix())})
+	)
+	tracker.announced("a", block.Hash(), 10, sealed.Add(100*time.Millisecond))
+	tracker.announced("b", block.Hash(), 10, sealed.Add(300*time.Millisecond))
+	tracker.announced("a", block.Hash(), 10, sealed.Add(time.Second)) // repeated, ignored
+	tracker.received("b", block, sealed.Add(400*time.Millisecond))
+	tracker.received("", block, sealed.Add(500*time.Millisecond)) // fetched, ignored
+	tracker.imported(block, sealed.Add(600*time.Millisecond))
+
+	stats := tracker.stats(1)
+	if len(stats.Blocks) != 1 {
+		t.Fatalf("block count mismatch: have %d, want 1", len(stats.Blocks))
+	}
+	prop := stats.Blocks[0]
+	if prop.Timestamp != uint64(sealed.Unix()) {
+		t.Errorf("timestamp mismatch: have %d, want %d", prop.Timestamp, sealed.Unix())
+	}
+	for name, check := range map[string]struct {
+		event *PropagationEvent
+		peer  string
+		delay float64
+	}{
+		"announced": {prop.Announced, "a", 100},
+		"received":  {prop.Received, "b", 400},
+		"imported":  {prop.Imported, "", 600},
+	} {
+		if check.event == nil || check.event.Delay == nil {
+			t.Errorf("%s event missing: %+v", name, check.event)
+			continue
+		}
+		if check.event.Peer != check.peer || *check.event.Delay != check.delay {
+			t.Errorf("%s event mismatch: have %s/%v, want %s/%v", name, check.event.Peer, *check.event.Delay, check.peer, check.delay)
+		}
+	}
+	if len(stats.Peers) != 2 {
+		t.Fatalf("peer count mismatch: have %d, want 2", len(stats.Peers))
+	}
+	if lag := stats.Peers[1]; lag.Peer != "b" || lag.AnnounceLag != 200 || lag.Blocks != 1 || lag.BlockLag != 0 {
+		t.Errorf("peer latency mismatch: %+v", lag)
+	}
+	if stats.Peers[0].Blocks != 0 {
+		t.Errorf("peer without block delivery counted: %+v", stats.Peers[0])
+	}
+}
+
+// Tests that only the most recent blocks are tracked.
+func TestPropagationTrackerLimit(t *testing.T) {
+	tracker := newPropagationTracker()
+	for i := 0; i < maxPropagationBlocks+10; i++ {
+		block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(int64(i))})
+		tracker.announced("a", block.Hash(), uint64(i), time.Now())
+	}
+	stats := tracker.stats(maxPropagationBlocks + 10)
+	if len(stats.Blocks) != maxPropagationBlocks {
+		t.Fatalf("tracked block count mismatch: have %d, want %d", len(stats.Blocks), maxPropagationBlocks)
+	}
+	if number := stats.Blocks[0].Number; number != maxPropagationBlocks+9 {
+		t.Errorf("most recent block mismatch: have %d, want %d", number, maxPropagationBlocks+9)
+	}
+}
+
+// Tests that the propagation latency is estimated from the in-turn blocks only.
+func TestPropagationLatency(t *testing.T) {
+	var (
+		tracker = newPropagationTracker()
+		sealed  = time.Unix(1650000000, 0)
+	)
+	for i := 0; i < 2*minLatencyBlocks; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Time: uint64(sealed.Unix()), Difficulty: big.NewInt(1)}
+		delay := 5 * time.Second
+		if i%2 == 0 {
+			header.Difficulty = inturnDifficulty
+			delay = time.Duration(i+1) * 10 * time.Millisecond
+		}
+		block := types.NewBlockWithHeader(header)
+		tracker.announced("a", block.Hash(), block.NumberU64(), sealed.Add(delay))
+		tracker.received("a", block, sealed.Add(delay+time.Second))
+
+		if _, ok := tracker.latency(); ok != (i/2+1 >= minLatencyBlocks) {
+			t.Fatalf("block %d: latency estimation mismatch: have %v", i, ok)
+		}
+	}
+	latency, ok := tracker.latency()
+	if !ok {
+		t.Fatalf("latency not estimated")
+	}
+	if want := 130 * time.Millisecond; latency != want {
+		t.Errorf("latency mismatch: have %v, want %v", latency, want)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package mesh
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// testBackend serves the snapshot digests of a fixed set of epochs.
+type testBackend map[uint64]common.Hash
+
+func (b testBackend) EpochSnapshot(peer *Peer, epoch uint64) (uint64, common.Hash, common.Hash, bool) {
+	hash, ok := b[epoch]
+	return epoch * 100, common.Hash{byte(epoch)}, hash, ok
+}
+
+func (b testBackend) HandleVersionAttestation(peer *Peer, attestation *VersionAttestationPacket) error {
+	return nil
+}
+
+func (b testBackend) RunPeer(peer *Peer, handler Handler) error { return handler(peer) }
+func (b testBackend) PeerInfo(id enode.ID) interface{}          { return nil }
+
+// attestationBackend collects the gossiped version attestations.
+type attestationBackend struct {
+	testBackend
+	attestations chan *VersionAttestationPacket
+}
+
+func (b *attestationBackend) HandleVersionAttestation(peer *Peer, attestation *VersionAttestationPacket) error {
+	b.attestations <- attestation
+	return nil
+}
+
+// Tests that epoch snapshot digests are served and matched up with the requests.
+func TestEpochSnapshotRequest(t *testing.T) {
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+
+	local := NewFakePeer(MESH1, "0102030405060708", app)
+	remote := NewFakePeer(MESH1, "0807060504030201", net)
+
+	go Handle(testBackend{}, local)
+	go Handle(testBackend{3: common.Hash{0xaa}}, remote)
+
+	res, err := local.RequestEpochSnapshot(3, time.Second)
+	if err != nil {
+		t.Fatalf("failed to request snapshot: %v", err)
+	}
+	if !res.Known || res.Number != 300 || res.Hash != (common.Hash{3}) || res.RLPHash != (common.Hash{0xaa}) {
+		t.Fatalf("snapshot digest mismatch: %+v", res)
+	}
+	if res, err = local.RequestEpochSnapshot(4, time.Second); err != nil {
+		t.Fatalf("failed to request unknown snapshot: %v", err)
+	}
+	if res.Known {
+		t.Fatalf("unknown snapshot reported as known: %+v", res)
+	}
+	// Pending requests fail once the peer disconnects
+	net.Close()
+	if _, err := local.RequestEpochSnapshot(3, time.Second); err == nil {
+		t.Fatalf("request to disconnected peer succeeded")
+	}
+}
+
+// Tests that version attestations are delivered to mesh/2 peers and rejected
+// from mesh/1 ones.
+func TestVersionAttestation(t *testing.T) {
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+
+	local := NewFakePeer(MESH2, "0102030405060708", app)
+	remote := NewFakePeer(MESH2, "0807060504030201", net)
+
+	backend := &attestationBackend{attestations: make(chan *VersionAttestationPacket, 1)}
+	go Handle(backend, remote)
+
+	sent := &VersionAttestationPacket{
+		Signer:    common.Address{0x01},
+		Version:   "1.10.17-stable",
+		Build:     []byte{0xde, 0xad},
+		Number:    100,
+		Time:      1650000000,
+		Signature: make([]byte, 65),
+	}
+	if err := local.SendVersionAttestation(sent); err != nil {
+		t.Fatalf("failed to send attestation: %v", err)
+	}
+	select {
+	case got := <-backend.attestations:
+		if got.Signer != sent.Signer || got.Version != sent.Version || got.Number != sent.Number || got.Time != sent.Time {
+			t.Fatalf("attestation mismatch: have %+v, want %+v", got, sent)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("attestation not delivered")
+	}
+	// Older peers are skipped instead of being sent an unknown message
+	old := NewFakePeer(MESH1, "0102030405060708", app)
+	if err := old.SendVersionAttestation(sent); err != nil {
+		t.Fatalf("attestation to mesh/1 peer failed: %v", err)
+	}
+	// Attestations from older peers are invalid
+	app2, net2 := p2p.MsgPipe()
+	defer app2.Close()
+
+	errc := make(chan error, 1)
+	go func() { errc <- Handle(backend, NewFakePeer(MESH1, "0807060504030201", net2)) }()
+	if err := p2p.Send(app2, VersionAttestationMsg, sent); err != nil {
+		t.Fatalf("failed to send raw attestation: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if !errors.Is(err, errInvalidMsgCode) {
+			t.Fatalf("mesh/1 attestation error mismatch: have %v, want %v", err, errInvalidMsgCode)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("mesh/1 attestation accepted")
+	}
+}
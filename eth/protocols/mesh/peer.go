@@ -0,0 +1,150 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package mesh
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+// Peer is a collection of relevant information we have about a `mesh` peer.
+type Peer struct {
+	id string // Unique ID for the peer, cached
+
+	*p2p.Peer                   // The embedded P2P package peer
+	rw        p2p.MsgReadWriter // Input/output streams for mesh
+	version   uint              // Protocol version negotiated
+
+	reqID   uint64                               // Last request ID issued
+	pending map[uint64]chan *EpochSnapshotPacket // Requests waiting for a response
+	closed  bool                                 // Whether the peer was disconnected
+	lock    sync.Mutex                           // Protects the requests
+
+	logger log.Logger // Contextual logger with the peer id injected
+}
+
+// NewPeer create a wrapper for a network connection and negotiated  protocol
+// version.
+func NewPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
+	id := p.ID().String()
+	return newPeer(version, id, p, rw)
+}
+
+// NewFakePeer create a fake mesh peer without a backing p2p peer, for testing purposes.
+func NewFakePeer(version uint, id string, rw p2p.MsgReadWriter) *Peer {
+	return newPeer(version, id, nil, rw)
+}
+
+func newPeer(version uint, id string, p *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
+	return &Peer{
+		id:      id,
+		Peer:    p,
+		rw:      rw,
+		version: version,
+		pending: make(map[uint64]chan *EpochSnapshotPacket),
+		logger:  log.New("peer", id[:8]),
+	}
+}
+
+// ID retrieves the peer's unique identifier.
+func (p *Peer) ID() string {
+	return p.id
+}
+
+// Version retrieves the peer's negotiated `mesh` protocol version.
+func (p *Peer) Version() uint {
+	return p.version
+}
+
+// Log overrides the P2P logget with the higher level one containing only the id.
+func (p *Peer) Log() log.Logger {
+	return p.logger
+}
+
+// RequestEpochSnapshot fetches the digest of the snapshot the peer derived at
+// the block of the given epoch, waiting at most timeout for the response.
+func (p *Peer) RequestEpochSnapshot(epoch uint64, timeout time.Duration) (*EpochSnapshotPacket, error) {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil, errDisconnected
+	}
+	p.reqID++
+	id, resCh := p.reqID, make(chan *EpochSnapshotPacket, 1)
+	p.pending[id] = resCh
+	p.lock.Unlock()
+
+	defer func() {
+		p.lock.Lock()
+		delete(p.pending, id)
+		p.lock.Unlock()
+	}()
+	p.logger.Trace("Fetching epoch snapshot digest", "reqid", id, "epoch", epoch)
+	if err := p2p.Send(p.rw, GetEpochSnapshotMsg, &GetEpochSnapshotPacket{ID: id, Epoch: epoch}); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case res, ok := <-resCh:
+		if !ok {
+			return nil, errDisconnected
+		}
+		return res, nil
+	case <-timer.C:
+		return nil, errTimeout
+	}
+}
+
+// SendVersionAttestation gossips the attested client version of a validator to
+// the peer. Peers speaking mesh/1 don't take attestations and are skipped.
+func (p *Peer) SendVersionAttestation(attestation *VersionAttestationPacket) error {
+	if p.version < MESH2 {
+		return nil
+	}
+	return p2p.Send(p.rw, VersionAttestationMsg, attestation)
+}
+
+// deliver hands a response over to the request waiting for it.
+func (p *Peer) deliver(res *EpochSnapshotPacket) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	resCh, ok := p.pending[res.ID]
+	if !ok {
+		return errUnexpectedMsg
+	}
+	delete(p.pending, res.ID)
+	resCh <- res
+	return nil
+}
+
+// close fails the pending requests once the peer disconnected.
+func (p *Peer) close() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.closed = true
+	for id, resCh := range p.pending {
+		close(resCh)
+		delete(p.pending, id)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package mesh implements the `mesh` protocol, spoken between the validators of
+// a clique network to compare their view of the consensus state.
+package mesh
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	MESH1 = 1
+	MESH2 = 2
+)
+
+// ProtocolName is the official short name of the `mesh` protocol used during
+// devp2p capability negotiation.
+const ProtocolName = "mesh"
+
+// ProtocolVersions are the supported versions of the `mesh` protocol (first
+// is primary).
+var ProtocolVersions = []uint{MESH2, MESH1}
+
+// protocolLengths are the number of implemented message corresponding to
+// different protocol versions.
+var protocolLengths = map[uint]uint64{MESH2: 3, MESH1: 2}
+
+// maxMessageSize is the maximum cap on the size of a protocol message.
+const maxMessageSize = 1024
+
+const (
+	GetEpochSnapshotMsg = 0x00
+	EpochSnapshotMsg    = 0x01
+
+	// Protocol messages introduced in mesh/2
+	VersionAttestationMsg = 0x02
+)
+
+var (
+	errMsgTooLarge    = errors.New("message too long")
+	errDecode         = errors.New("invalid message")
+	errInvalidMsgCode = errors.New("invalid message code")
+	errUnexpectedMsg  = errors.New("unexpected response")
+	errTimeout        = errors.New("request timed out")
+	errDisconnected   = errors.New("peer disconnected")
+)
+
+// Packet represents a p2p message in the `mesh` protocol.
+type Packet interface {
+	Name() string // Name returns a string corresponding to the message type.
+	Kind() byte   // Kind returns the message type.
+}
+
+// GetEpochSnapshotPacket requests the digest of the clique snapshot a peer
+// derived at the block of an epoch.
+type GetEpochSnapshotPacket struct {
+	ID    uint64 // Request ID to match up responses with
+	Epoch uint64 // Epoch whose snapshot is requested
+}
+
+// EpochSnapshotPacket is the digest of an epoch snapshot sent in response.
+type EpochSnapshotPacket struct {
+	ID      uint64      // ID of the request this is a response for
+	Known   bool        // Whether the peer has a snapshot for the epoch
+	Number  uint64      // Number of the epoch block
+	Hash    common.Hash // Hash of the epoch block
+	RLPHash common.Hash // Keccak256 hash of the canonical snapshot encoding
+}
+
+// VersionAttestationPacket is the client version a validator runs, signed by its
+// clique signer key and gossiped across the validator mesh.
+type VersionAttestationPacket struct {
+	Signer    common.Address // Clique signer attesting its client
+	Version   string         // Textual client version, e.g. "1.10.17-stable"
+	Build     []byte         // Git commit of the build, empty if unknown
+	Number    uint64         // Number of the chain head when attesting
+	Time      uint64         // Unix time of the attestation
+	Signature []byte         // EIP-191 signature of the attestation digest by the signer
+}
+
+func (*GetEpochSnapshotPacket) Name() string { return "GetEpochSnapshot" }
+func (*GetEpochSnapshotPacket) Kind() byte   { return GetEpochSnapshotMsg }
+
+func (*EpochSnapshotPacket) Name() string { return "EpochSnapshot" }
+func (*EpochSnapshotPacket) Kind() byte   { return EpochSnapshotMsg }
+
+func (*VersionAttestationPacket) Name() string { return "VersionAttestation" }
+func (*VersionAttestationPacket) Kind() byte   { return VersionAttestationMsg }
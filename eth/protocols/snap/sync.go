@@ -18,6 +18,7 @@ package snap
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -36,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/maintenance"
 	"github.com/ethereum/go-ethereum/light"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/msgrate"
@@ -422,7 +424,8 @@ type Syncer struct {
 	storageSynced  uint64             // Number of storage slots downloaded
 	storageBytes   common.StorageSize // Number of storage trie bytes persisted to disk
 
-	extProgress *SyncProgress // progress that can be exposed to external caller.
+	extProgress   *SyncProgress // progress that can be exposed to external caller.
+	extCompletion float64       // Fraction of the account hash space already downloaded
 
 	// Request tracking during healing phase
 	trienodeHealIdlers map[string]struct{} // Peers that aren't serving trie node requests
@@ -651,6 +654,7 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 			BytecodeHealSynced: s.bytecodeHealSynced,
 			BytecodeHealBytes:  s.bytecodeHealBytes,
 		}
+		s.extCompletion = s.accountCompletion()
 		s.lock.Unlock()
 		// Wait for something to happen
 		select {
@@ -832,6 +836,30 @@ func (s *Syncer) Progress() (*SyncProgress, *SyncPending) {
 	return s.extProgress, pending
 }
 
+// Completion returns the fraction of the account hash space downloaded by the
+// running or last sync cycle, between 0 and 1.
+func (s *Syncer) Completion() float64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.extCompletion
+}
+
+// accountCompletion calculates the fraction of the account hash space already
+// covered by the account range tasks.
+func (s *Syncer) accountCompletion() float64 {
+	if len(s.tasks) == 0 {
+		return 1
+	}
+	accountGaps := new(big.Int)
+	for _, task := range s.tasks {
+		accountGaps.Add(accountGaps, new(big.Int).Sub(task.Last.Big(), task.Next.Big()))
+	}
+	accountFills := new(big.Int).Sub(hashSpace, accountGaps)
+	fraction, _ := new(big.Float).Quo(new(big.Float).SetInt(accountFills), new(big.Float).SetInt(hashSpace)).Float64()
+	return fraction
+}
+
 // cleanAccountTasks removes account range retrieval tasks that have already been
 // completed.
 func (s *Syncer) cleanAccountTasks() {
@@ -2118,6 +2146,7 @@ func (s *Syncer) processTrienodeHealResponse(res *trienodeHealResponse) {
 	if err := s.healer.scheduler.Commit(batch); err != nil {
 		log.Error("Failed to commit healing data", "err", err)
 	}
+	maintenance.Wait(context.Background(), batch.ValueSize())
 	if err := batch.Write(); err != nil {
 		log.Crit("Failed to persist healing data", "err", err)
 	}
@@ -2154,6 +2183,7 @@ func (s *Syncer) processBytecodeHealResponse(res *bytecodeHealResponse) {
 	if err := s.healer.scheduler.Commit(batch); err != nil {
 		log.Error("Failed to commit healing data", "err", err)
 	}
+	maintenance.Wait(context.Background(), batch.ValueSize())
 	if err := batch.Write(); err != nil {
 		log.Crit("Failed to persist healing data", "err", err)
 	}
@@ -2794,6 +2824,7 @@ func (s *Syncer) onHealState(paths [][]byte, value []byte) error {
 		s.storageHealedBytes += common.StorageSize(1 + 2*common.HashLength + len(value))
 	}
 	if s.stateWriter.ValueSize() > ethdb.IdealBatchSize {
+		maintenance.Wait(context.Background(), s.stateWriter.ValueSize())
 		s.stateWriter.Write() // It's fine to ignore the error here
 		s.stateWriter.Reset()
 	}
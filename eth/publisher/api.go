@@ -0,0 +1,47 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package publisher
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// PrivatePublisherAPI exposes the progress of the message bus publisher and
+// allows replaying the chain.
+type PrivatePublisherAPI struct {
+	p *Publisher
+}
+
+// NewPrivatePublisherAPI creates the message bus publisher API.
+func NewPrivatePublisherAPI(p *Publisher) *PrivatePublisherAPI {
+	return &PrivatePublisherAPI{p: p}
+}
+
+// Status returns the last block published and the last publishing error.
+func (api *PrivatePublisherAPI) Status() *Status {
+	return api.p.Status()
+}
+
+// Replay publishes the canonical chain again starting at the given block.
+func (api *PrivatePublisherAPI) Replay(from rpc.BlockNumber) error {
+	if from < 0 {
+		return errors.New("replay start must be a block number")
+	}
+	return api.p.Replay(uint64(from))
+}
@@ -0,0 +1,101 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package publisher
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Feeds the publisher can push, each to its own topic named by the topic prefix
+// and the feed, e.g. "aksara.blocks".
+const (
+	FeedBlocks   = "blocks"   // Block headers along with their transactions
+	FeedReceipts = "receipts" // Receipts of all transactions in a block
+	FeedLogs     = "logs"     // Individual logs, flagged removed on reorgs
+	FeedReorgs   = "reorgs"   // Blocks dropped from the canonical chain
+	FeedClique   = "clique"   // Block signers and epoch signer sets
+)
+
+// Message buses the publisher supports.
+const (
+	BackendKafka = "kafka" // Kafka, through a REST proxy speaking the v2 API
+	BackendNATS  = "nats"  // NATS JetStream
+)
+
+var allFeeds = []string{FeedBlocks, FeedReceipts, FeedLogs, FeedReorgs, FeedClique}
+
+// Config are the configuration parameters of the message bus publisher.
+type Config struct {
+	Backend       string        // Message bus to publish to, empty disables the publisher
+	URL           string        // Kafka REST proxy endpoint or NATS server URL
+	TopicPrefix   string        // Prefix of the topics (Kafka) or subjects (NATS) published to
+	Feeds         []string      `toml:",omitempty"` // Feeds to publish, all of them if empty
+	Timeout       time.Duration // Time to wait for the bus to accept a block's messages
+	RetryInterval time.Duration // Delay before retrying a failed publish
+}
+
+// DefaultConfig contains the default settings for the message bus publisher.
+var DefaultConfig = Config{
+	TopicPrefix:   "aksara",
+	Timeout:       10 * time.Second,
+	RetryInterval: 5 * time.Second,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *Config) sanitize() (Config, error) {
+	conf := *config
+	if conf.Backend != BackendKafka && conf.Backend != BackendNATS {
+		return conf, fmt.Errorf("unknown publisher backend %q", conf.Backend)
+	}
+	if conf.URL == "" {
+		return conf, fmt.Errorf("missing %s publisher URL", conf.Backend)
+	}
+	if len(conf.Feeds) == 0 {
+		conf.Feeds = allFeeds
+	}
+	for _, feed := range conf.Feeds {
+		if !validFeed(feed) {
+			return conf, fmt.Errorf("unknown publisher feed %q", feed)
+		}
+	}
+	if conf.TopicPrefix == "" {
+		log.Warn("Sanitizing invalid publisher topic prefix", "provided", conf.TopicPrefix, "updated", DefaultConfig.TopicPrefix)
+		conf.TopicPrefix = DefaultConfig.TopicPrefix
+	}
+	if conf.Timeout <= 0 {
+		log.Warn("Sanitizing invalid publisher timeout", "provided", conf.Timeout, "updated", DefaultConfig.Timeout)
+		conf.Timeout = DefaultConfig.Timeout
+	}
+	if conf.RetryInterval <= 0 {
+		log.Warn("Sanitizing invalid publisher retry interval", "provided", conf.RetryInterval, "updated", DefaultConfig.RetryInterval)
+		conf.RetryInterval = DefaultConfig.RetryInterval
+	}
+	return conf, nil
+}
+
+func validFeed(feed string) bool {
+	for _, f := range allFeeds {
+		if f == feed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package publisher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// kafkaContentType is the media type of JSON records in the REST proxy v2 API.
+const kafkaContentType = "application/vnd.kafka.json.v2+json"
+
+// kafkaRecord is a single record produced through the REST proxy.
+type kafkaRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// kafkaProduceResponse is the reply of the REST proxy to a produce request.
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		Partition int     `json:"partition"`
+		Offset    int64   `json:"offset"`
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+}
+
+// kafkaSink produces messages to Kafka through a REST proxy. The proxy only
+// replies after the brokers acknowledged the records.
+type kafkaSink struct {
+	endpoint string
+	prefix   string
+	client   *http.Client
+}
+
+func newKafkaSink(endpoint string, prefix string, timeout time.Duration) *kafkaSink {
+	return &kafkaSink{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		prefix:   prefix,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Publish implements sink, producing the messages of each feed in order with a
+// single request per topic.
+func (s *kafkaSink) Publish(msgs []*Message) error {
+	var (
+		topics  []string
+		records = make(map[string][]kafkaRecord)
+	)
+	for _, msg := range msgs {
+		topic := s.prefix + "." + msg.Feed
+		if _, ok := records[topic]; !ok {
+			topics = append(topics, topic)
+		}
+		records[topic] = append(records[topic], kafkaRecord{Key: msg.Key, Value: msg.Value})
+	}
+	for _, topic := range topics {
+		if err := s.produce(topic, records[topic]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *kafkaSink) produce(topic string, records []kafkaRecord) error {
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint+"/topics/"+url.PathEscape(topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	blob, err := io.ReadAll(io.LimitReader(res.Body, 1024*1024))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("kafka proxy returned %s: %s", res.Status, strings.TrimSpace(string(blob)))
+	}
+	var reply kafkaProduceResponse
+	if err := json.Unmarshal(blob, &reply); err != nil {
+		return err
+	}
+	if len(reply.Offsets) != len(records) {
+		return fmt.Errorf("kafka proxy acknowledged %d of %d records to %s", len(reply.Offsets), len(records), topic)
+	}
+	for i, offset := range reply.Offsets {
+		if offset.ErrorCode != nil || offset.Error != nil {
+			var reason string
+			if offset.Error != nil {
+				reason = *offset.Error
+			}
+			return fmt.Errorf("kafka rejected record %d to %s: %s", i, topic, reason)
+		}
+	}
+	return nil
+}
+
+// Close implements sink.
+func (s *kafkaSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
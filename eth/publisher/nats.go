@@ -0,0 +1,247 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package publisher
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// natsPubAck is the acknowledgement of JetStream for a published message.
+type natsPubAck struct {
+	Stream    string `json:"stream"`
+	Seq       uint64 `json:"seq"`
+	Duplicate bool   `json:"duplicate"`
+	Error     *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+// natsSink publishes messages to NATS JetStream over the plain text client
+// protocol, waiting for the stream's acknowledgement of every message. Each
+// message carries its key as Nats-Msg-Id, letting JetStream drop redeliveries
+// within its deduplication window.
+type natsSink struct {
+	addr    string
+	user    string
+	pass    string
+	prefix  string
+	timeout time.Duration
+
+	conn  net.Conn
+	r     *bufio.Reader
+	inbox string // Subject prefix acknowledgements are received on
+	next  uint64 // Sequence number of the last reply subject used
+}
+
+func newNATSSink(rawurl string, prefix string, timeout time.Duration) (*natsSink, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "nats" {
+		return nil, fmt.Errorf("unsupported NATS URL scheme %q", u.Scheme)
+	}
+	s := &natsSink{addr: u.Host, prefix: prefix, timeout: timeout}
+	if u.Port() == "" {
+		s.addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	if u.User != nil {
+		s.user = u.User.Username()
+		s.pass, _ = u.User.Password()
+	}
+	return s, nil
+}
+
+// connect dials the server, performs the handshake and subscribes to the inbox
+// receiving the acknowledgements.
+func (s *natsSink) connect() error {
+	conn, err := net.DialTimeout("tcp", s.addr, s.timeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(s.timeout))
+	s.conn, s.r = conn, bufio.NewReader(conn)
+
+	line, err := s.readLine()
+	if err != nil {
+		return s.fail(err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return s.fail(fmt.Errorf("unexpected NATS greeting: %s", line))
+	}
+	opts := map[string]interface{}{
+		"verbose":       false,
+		"pedantic":      false,
+		"headers":       true,
+		"no_responders": true,
+		"protocol":      1,
+		"lang":          "go",
+		"name":          "geth-publisher",
+	}
+	if s.user != "" && s.pass == "" {
+		opts["auth_token"] = s.user
+	} else if s.user != "" {
+		opts["user"], opts["pass"] = s.user, s.pass
+	}
+	blob, _ := json.Marshal(opts)
+
+	var nonce [8]byte
+	rand.Read(nonce[:])
+	s.inbox = "_INBOX." + hex.EncodeToString(nonce[:])
+
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nSUB %s.* 1\r\nPING\r\n", blob, s.inbox); err != nil {
+		return s.fail(err)
+	}
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return s.fail(err)
+		}
+		switch {
+		case line == "PONG":
+			conn.SetDeadline(time.Time{})
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return s.fail(fmt.Errorf("NATS handshake failed: %s", line))
+		}
+	}
+}
+
+// Publish implements sink, pipelining all messages and waiting for JetStream to
+// acknowledge each of them.
+func (s *natsSink) Publish(msgs []*Message) error {
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	s.conn.SetDeadline(time.Now().Add(s.timeout))
+	defer func() {
+		if s.conn != nil {
+			s.conn.SetDeadline(time.Time{})
+		}
+	}()
+
+	var (
+		buf     bytes.Buffer
+		pending = make(map[string]struct{}, len(msgs))
+	)
+	for _, msg := range msgs {
+		s.next++
+		reply := s.inbox + "." + strconv.FormatUint(s.next, 10)
+		pending[reply] = struct{}{}
+
+		hdr := "NATS/1.0\r\nNats-Msg-Id: " + msg.Key + "\r\n\r\n"
+		fmt.Fprintf(&buf, "HPUB %s.%s %s %d %d\r\n%s%s\r\n", s.prefix, msg.Feed, reply, len(hdr), len(hdr)+len(msg.Value), hdr, msg.Value)
+	}
+	if _, err := s.conn.Write(buf.Bytes()); err != nil {
+		return s.fail(err)
+	}
+	for len(pending) > 0 {
+		line, err := s.readLine()
+		if err != nil {
+			return s.fail(err)
+		}
+		switch {
+		case line == "PING":
+			if _, err := io.WriteString(s.conn, "PONG\r\n"); err != nil {
+				return s.fail(err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return s.fail(fmt.Errorf("NATS error: %s", line))
+
+		case strings.HasPrefix(line, "MSG "):
+			subject, payload, err := s.readPayload(line)
+			if err != nil {
+				return s.fail(err)
+			}
+			var ack natsPubAck
+			if err := json.Unmarshal(payload, &ack); err != nil {
+				return s.fail(err)
+			}
+			if ack.Error != nil {
+				return s.fail(fmt.Errorf("JetStream rejected message: %s (%d)", ack.Error.Description, ack.Error.Code))
+			}
+			delete(pending, subject)
+
+		case strings.HasPrefix(line, "HMSG "):
+			// Headers only replies are status messages, e.g. 503 if no stream
+			// captures the subject
+			_, payload, err := s.readPayload(line)
+			if err != nil {
+				return s.fail(err)
+			}
+			status := strings.SplitN(string(payload), "\r\n", 2)[0]
+			return s.fail(fmt.Errorf("message not stored by JetStream: %s", status))
+		}
+	}
+	return nil
+}
+
+// readLine reads a protocol control line.
+func (s *natsSink) readLine() (string, error) {
+	line, err := s.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readPayload reads the payload of a MSG or HMSG control line, returning the
+// subject it was received on.
+func (s *natsSink) readPayload(line string) (string, []byte, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return "", nil, fmt.Errorf("malformed NATS message: %s", line)
+	}
+	size, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || size < 0 {
+		return "", nil, fmt.Errorf("malformed NATS message size: %s", line)
+	}
+	payload := make([]byte, size+2)
+	if _, err := io.ReadFull(s.r, payload); err != nil {
+		return "", nil, err
+	}
+	return fields[1], payload[:size], nil
+}
+
+// fail drops the connection, so the next publish reconnects and ignores any
+// acknowledgement still in flight.
+func (s *natsSink) fail(err error) error {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn, s.r = nil, nil
+	}
+	return err
+}
+
+// Close implements sink.
+func (s *natsSink) Close() error {
+	return s.fail(nil)
+}
@@ -0,0 +1,446 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package publisher pushes the chain's blocks, receipts, logs, reorgs and clique
+// signer events to a Kafka or NATS message bus.
+//
+// Publishing is at-least-once: a persisted cursor tracks the last block whose
+// messages the bus accepted, and everything after it is published again after
+// a failure or restart. Every message is keyed, so consumers can deduplicate.
+package publisher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// cursorKey tracks the last block whose messages were accepted by the bus.
+var cursorKey = []byte("PublisherCursor")
+
+// cursor is a position in the chain.
+type cursor struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// Message is a single message published to the bus.
+type Message struct {
+	Feed  string // Feed the message belongs to, appended to the topic prefix
+	Key   string // Key consumers can deduplicate redelivered messages by
+	Value []byte // JSON encoded payload
+}
+
+// sink is a message bus connection.
+type sink interface {
+	// Publish sends a batch of messages, returning only once the bus durably
+	// accepted all of them.
+	Publish(msgs []*Message) error
+
+	// Close terminates the connection to the bus.
+	Close() error
+}
+
+// Chain is the subset of the blockchain the publisher follows.
+type Chain interface {
+	CurrentBlock() *types.Block
+	GetBlock(hash common.Hash, number uint64) *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetCanonicalHash(number uint64) common.Hash
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Publisher follows the canonical chain and publishes the changes to a message
+// bus in order.
+type Publisher struct {
+	config Config
+	db     ethdb.KeyValueStore
+	chain  Chain
+	clique *clique.Clique // Signer recovery for the clique feed, nil if not running clique
+	sink   sink
+	feeds  map[string]bool
+
+	cursor    cursor
+	published uint64 // Number of messages published since startup
+	lastErr   error  // Error of the last failed publish, nil after a success
+	lock      sync.Mutex
+
+	wake chan struct{}
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a publisher pushing the chain changes to the configured bus. On
+// first use it starts at the current head, use Replay to publish older blocks.
+func New(config Config, db ethdb.KeyValueStore, chain Chain, engine *clique.Clique) (*Publisher, error) {
+	conf, err := config.sanitize()
+	if err != nil {
+		return nil, err
+	}
+	var s sink
+	switch conf.Backend {
+	case BackendKafka:
+		s = newKafkaSink(conf.URL, conf.TopicPrefix, conf.Timeout)
+	case BackendNATS:
+		if s, err = newNATSSink(conf.URL, conf.TopicPrefix, conf.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	return newPublisher(conf, db, chain, engine, s), nil
+}
+
+func newPublisher(config Config, db ethdb.KeyValueStore, chain Chain, engine *clique.Clique, s sink) *Publisher {
+	p := &Publisher{
+		config: config,
+		db:     db,
+		chain:  chain,
+		clique: engine,
+		sink:   s,
+		feeds:  make(map[string]bool),
+		wake:   make(chan struct{}, 1),
+		quit:   make(chan struct{}),
+	}
+	for _, feed := range config.Feeds {
+		p.feeds[feed] = true
+	}
+	if blob, err := db.Get(cursorKey); err == nil {
+		if err := rlp.DecodeBytes(blob, &p.cursor); err != nil {
+			log.Error("Invalid publisher cursor", "err", err)
+		}
+	}
+	if p.cursor.Hash == (common.Hash{}) {
+		head := chain.CurrentBlock()
+		p.cursor = cursor{Number: head.NumberU64(), Hash: head.Hash()}
+		p.writeCursor()
+	}
+	return p
+}
+
+// Start launches the publishing loop.
+func (p *Publisher) Start() {
+	log.Info("Started message bus publisher", "backend", p.config.Backend, "prefix", p.config.TopicPrefix, "feeds", p.config.Feeds, "cursor", p.cursor.Number)
+
+	p.wg.Add(1)
+	go p.loop()
+}
+
+// Stop terminates the publishing loop and disconnects from the bus.
+func (p *Publisher) Stop() {
+	close(p.quit)
+	p.wg.Wait()
+	p.sink.Close()
+}
+
+func (p *Publisher) loop() {
+	defer p.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := p.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	retry := time.NewTimer(0)
+	defer retry.Stop()
+
+	var failing bool
+	for {
+		select {
+		case <-heads:
+			if failing {
+				continue // Wait for the retry instead of hammering the bus
+			}
+		case <-p.wake:
+		case <-retry.C:
+		case <-sub.Err():
+			return
+		case <-p.quit:
+			return
+		}
+		if err := p.catchUp(); err != nil {
+			log.Warn("Failed to publish chain changes", "backend", p.config.Backend, "err", err)
+			failing = true
+			retry.Reset(p.config.RetryInterval)
+		} else {
+			failing = false
+		}
+	}
+}
+
+// catchUp publishes the chain changes between the cursor and the current head,
+// block by block. Blocks dropped from the canonical chain are reverted one at a
+// time until the cursor is back on the canonical chain.
+func (p *Publisher) catchUp() error {
+	for {
+		select {
+		case <-p.quit:
+			return nil
+		default:
+		}
+		p.lock.Lock()
+		cur := p.cursor
+		p.lock.Unlock()
+
+		head := p.chain.CurrentBlock()
+		if cur.Hash == head.Hash() {
+			return nil
+		}
+		var (
+			msgs []*Message
+			next cursor
+			err  error
+		)
+		if p.chain.GetCanonicalHash(cur.Number) == cur.Hash {
+			if cur.Number >= head.NumberU64() {
+				return nil
+			}
+			block := p.chain.GetBlockByNumber(cur.Number + 1)
+			if block == nil || block.ParentHash() != cur.Hash {
+				return nil // Reorged meanwhile, the next head event sorts it out
+			}
+			msgs, err = p.committed(block)
+			next = cursor{Number: block.NumberU64(), Hash: block.Hash()}
+		} else {
+			block := p.chain.GetBlock(cur.Hash, cur.Number)
+			if block == nil {
+				return fmt.Errorf("cursor block #%d [%x] missing", cur.Number, cur.Hash)
+			}
+			msgs, err = p.reverted(block, head)
+			next = cursor{Number: block.NumberU64() - 1, Hash: block.ParentHash()}
+		}
+		if err != nil {
+			return err
+		}
+		if len(msgs) > 0 {
+			err = p.sink.Publish(msgs)
+		}
+		p.lock.Lock()
+		if err != nil {
+			p.lastErr = err
+		} else if p.cursor == cur {
+			// Only move the cursor if it wasn't rewound meanwhile
+			p.lastErr = nil
+			p.published += uint64(len(msgs))
+			p.cursor = next
+			p.writeCursor()
+		}
+		p.lock.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeCursor persists the publishing cursor. The caller must hold the lock,
+// except on construction.
+func (p *Publisher) writeCursor() {
+	blob, err := rlp.EncodeToBytes(p.cursor)
+	if err != nil {
+		log.Crit("Failed to encode publisher cursor", "err", err)
+	}
+	if err := p.db.Put(cursorKey, blob); err != nil {
+		log.Crit("Failed to store publisher cursor", "err", err)
+	}
+}
+
+// blockMessage is the payload of the blocks feed.
+type blockMessage struct {
+	Number       hexutil.Uint64     `json:"number"`
+	Hash         common.Hash        `json:"hash"`
+	Header       *types.Header      `json:"header"`
+	Transactions types.Transactions `json:"transactions"`
+}
+
+// receiptsMessage is the payload of the receipts feed.
+type receiptsMessage struct {
+	Number   hexutil.Uint64 `json:"number"`
+	Hash     common.Hash    `json:"hash"`
+	Receipts types.Receipts `json:"receipts"`
+}
+
+// reorgMessage is the payload of the reorgs feed, one per dropped block.
+type reorgMessage struct {
+	Number     hexutil.Uint64 `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	ParentHash common.Hash    `json:"parentHash"`
+	NewHead    common.Hash    `json:"newHead"` // Canonical head at the time of the reorg
+}
+
+// cliqueMessage is the payload of the clique feed.
+type cliqueMessage struct {
+	Type    string           `json:"type"` // "signer" for every block, "epoch" for epoch blocks
+	Number  hexutil.Uint64   `json:"number"`
+	Hash    common.Hash      `json:"hash"`
+	Signer  *common.Address  `json:"signer,omitempty"`
+	InTurn  *bool            `json:"inTurn,omitempty"`
+	Epoch   *hexutil.Uint64  `json:"epoch,omitempty"`
+	Signers []common.Address `json:"signers,omitempty"`
+}
+
+// committed assembles the messages of a block added to the canonical chain.
+func (p *Publisher) committed(block *types.Block) ([]*Message, error) {
+	var (
+		msgs     []*Message
+		hash     = block.Hash()
+		number   = hexutil.Uint64(block.NumberU64())
+		receipts types.Receipts
+	)
+	if p.feeds[FeedReceipts] || p.feeds[FeedLogs] {
+		receipts = p.chain.GetReceiptsByHash(hash)
+		if len(receipts) != len(block.Transactions()) {
+			return nil, errors.New("missing block receipts")
+		}
+	}
+	add := func(feed string, key string, payload interface{}) error {
+		blob, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, &Message{Feed: feed, Key: key, Value: blob})
+		return nil
+	}
+	if p.feeds[FeedBlocks] {
+		if err := add(FeedBlocks, hash.Hex(), &blockMessage{number, hash, block.Header(), block.Transactions()}); err != nil {
+			return nil, err
+		}
+	}
+	if p.feeds[FeedReceipts] {
+		if err := add(FeedReceipts, hash.Hex(), &receiptsMessage{number, hash, receipts}); err != nil {
+			return nil, err
+		}
+	}
+	if p.feeds[FeedLogs] {
+		for _, receipt := range receipts {
+			for _, l := range receipt.Logs {
+				if err := add(FeedLogs, fmt.Sprintf("%s-%d", hash.Hex(), l.Index), l); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if p.feeds[FeedClique] && p.clique != nil {
+		header := block.Header()
+		signer, err := p.clique.Author(header)
+		if err != nil {
+			return nil, err
+		}
+		inturn := clique.InTurn(header)
+		if err := add(FeedClique, hash.Hex()+"-signer", &cliqueMessage{Type: "signer", Number: number, Hash: hash, Signer: &signer, InTurn: &inturn}); err != nil {
+			return nil, err
+		}
+		if epoch, signers, ok := clique.EpochSigners(header); ok {
+			epochNum := hexutil.Uint64(epoch)
+			if err := add(FeedClique, hash.Hex()+"-epoch", &cliqueMessage{Type: "epoch", Number: number, Hash: hash, Epoch: &epochNum, Signers: signers}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return msgs, nil
+}
+
+// reverted assembles the messages of a block dropped from the canonical chain:
+// the reorg notice and its logs flagged as removed.
+func (p *Publisher) reverted(block *types.Block, head *types.Block) ([]*Message, error) {
+	var (
+		msgs []*Message
+		hash = block.Hash()
+	)
+	if p.feeds[FeedReorgs] {
+		blob, err := json.Marshal(&reorgMessage{hexutil.Uint64(block.NumberU64()), hash, block.ParentHash(), head.Hash()})
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, &Message{Feed: FeedReorgs, Key: hash.Hex(), Value: blob})
+	}
+	if p.feeds[FeedLogs] {
+		for _, receipt := range p.chain.GetReceiptsByHash(hash) {
+			for _, l := range receipt.Logs {
+				removed := *l
+				removed.Removed = true
+				blob, err := json.Marshal(&removed)
+				if err != nil {
+					return nil, err
+				}
+				msgs = append(msgs, &Message{Feed: FeedLogs, Key: fmt.Sprintf("%s-%d-removed", hash.Hex(), l.Index), Value: blob})
+			}
+		}
+	}
+	return msgs, nil
+}
+
+// Replay rewinds the cursor so the canonical chain is published again starting
+// at the given block.
+func (p *Publisher) Replay(from uint64) error {
+	if from == 0 {
+		return errors.New("cannot replay the genesis block")
+	}
+	if head := p.chain.CurrentBlock().NumberU64(); from > head {
+		return fmt.Errorf("replay start #%d beyond head #%d", from, head)
+	}
+	hash := p.chain.GetCanonicalHash(from - 1)
+	if hash == (common.Hash{}) {
+		return fmt.Errorf("block #%d unavailable", from-1)
+	}
+	p.lock.Lock()
+	p.cursor = cursor{Number: from - 1, Hash: hash}
+	p.writeCursor()
+	p.lock.Unlock()
+
+	log.Info("Replaying chain to message bus", "from", from)
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Status is the progress of the publisher.
+type Status struct {
+	Backend   string         `json:"backend"`
+	Number    hexutil.Uint64 `json:"number"` // Number of the last block published
+	Hash      common.Hash    `json:"hash"`   // Hash of the last block published
+	Published hexutil.Uint64 `json:"published"`
+	Error     string         `json:"error,omitempty"` // Error of the last failed publish
+}
+
+// Status returns the progress of the publisher.
+func (p *Publisher) Status() *Status {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	status := &Status{
+		Backend:   p.config.Backend,
+		Number:    hexutil.Uint64(p.cursor.Number),
+		Hash:      p.cursor.Hash,
+		Published: hexutil.Uint64(p.published),
+	}
+	if p.lastErr != nil {
+		status.Error = p.lastErr.Error()
+	}
+	return status
+}
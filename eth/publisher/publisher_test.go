@@ -0,0 +1,287 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package publisher
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testSink collects the published messages, failing on demand.
+type testSink struct {
+	msgs []*Message
+	fail bool
+}
+
+func (s *testSink) Publish(msgs []*Message) error {
+	if s.fail {
+		return errors.New("bus unavailable")
+	}
+	s.msgs = append(s.msgs, msgs...)
+	return nil
+}
+
+func (s *testSink) Close() error { return nil }
+
+// drain returns the feeds of the messages published since the last call.
+func (s *testSink) drain() []string {
+	feeds := make([]string, len(s.msgs))
+	for i, msg := range s.msgs {
+		feeds[i] = msg.Feed
+	}
+	s.msgs = nil
+	return feeds
+}
+
+func repeatFeeds(n int, feeds ...string) []string {
+	var all []string
+	for i := 0; i < n; i++ {
+		all = append(all, feeds...)
+	}
+	return all
+}
+
+// Tests that blocks are published in order, reorged blocks are reverted before
+// the new ones get published, and that the cursor survives failures, replays
+// and restarts.
+func TestPublisher(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		emitter = common.HexToAddress("0xe0")
+		config  = params.TestChainConfig
+		signer  = types.LatestSigner(config)
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &core.Genesis{
+			Config: config,
+			Alloc: core.GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+				// PUSH1 0 PUSH1 0 LOG0
+				emitter: {Code: []byte{0x60, 0x00, 0x60, 0x00, 0xa0}, Balance: new(big.Int)},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	call := func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), emitter, nil, 50000, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	}
+	blocks, _ := core.GenerateChain(config, genesis, ethash.NewFaker(), db, 3, call)
+	forks, _ := core.GenerateChain(config, blocks[0], ethash.NewFaker(), db, 3, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+		call(i, b)
+	})
+	chain, err := core.NewBlockChain(db, nil, config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	conf, _ := (&Config{Backend: BackendKafka, URL: "http://localhost"}).sanitize()
+	sink := new(testSink)
+	p := newPublisher(conf, db, chain, nil, sink)
+
+	// Publish the initial chain
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	if err := p.catchUp(); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	if msg := sink.msgs[0]; msg.Key != blocks[0].Hash().Hex() {
+		t.Errorf("first message key mismatch: have %s, want %s", msg.Key, blocks[0].Hash().Hex())
+	}
+	if have, want := sink.drain(), repeatFeeds(3, FeedBlocks, FeedReceipts, FeedLogs); fmt.Sprint(have) != fmt.Sprint(want) {
+		t.Errorf("published feeds mismatch: have %v, want %v", have, want)
+	}
+	// Reorg to the fork and check the old blocks are reverted highest first
+	if _, err := chain.InsertChain(forks); err != nil {
+		t.Fatalf("failed to import fork: %v", err)
+	}
+	if err := p.catchUp(); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	var reorg reorgMessage
+	if err := json.Unmarshal(sink.msgs[0].Value, &reorg); err != nil || reorg.Hash != blocks[2].Hash() {
+		t.Errorf("first reorg mismatch: have %x, want %x (%v)", reorg.Hash, blocks[2].Hash(), err)
+	}
+	var removed types.Log
+	if err := json.Unmarshal(sink.msgs[1].Value, &removed); err != nil || !removed.Removed {
+		t.Errorf("reverted log not flagged removed: %v", err)
+	}
+	want := append(repeatFeeds(2, FeedReorgs, FeedLogs), repeatFeeds(3, FeedBlocks, FeedReceipts, FeedLogs)...)
+	if have := sink.drain(); fmt.Sprint(have) != fmt.Sprint(want) {
+		t.Errorf("reorg feeds mismatch: have %v, want %v", have, want)
+	}
+	// Replay while the bus is down, the cursor must stay put until it recovers
+	sink.fail = true
+	if err := p.Replay(3); err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	if err := p.catchUp(); err == nil {
+		t.Fatalf("publish succeeded with bus down")
+	}
+	if status := p.Status(); status.Number != 2 || status.Error == "" {
+		t.Errorf("status mismatch after failure: number %d, error %q", status.Number, status.Error)
+	}
+	sink.fail = false
+	if err := p.catchUp(); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	if have, want := sink.drain(), repeatFeeds(2, FeedBlocks, FeedReceipts, FeedLogs); fmt.Sprint(have) != fmt.Sprint(want) {
+		t.Errorf("replayed feeds mismatch: have %v, want %v", have, want)
+	}
+	// Restart and ensure nothing is republished
+	p = newPublisher(conf, db, chain, nil, sink)
+	if status := p.Status(); uint64(status.Number) != chain.CurrentBlock().NumberU64() || status.Hash != chain.CurrentBlock().Hash() {
+		t.Errorf("cursor not persisted: have #%d [%x]", status.Number, status.Hash)
+	}
+	if err := p.catchUp(); err != nil || len(sink.msgs) != 0 {
+		t.Errorf("republished after restart: %d messages, %v", len(sink.msgs), err)
+	}
+}
+
+// Tests that the Kafka sink produces each feed to its topic and reports records
+// rejected by the brokers.
+func TestKafkaSink(t *testing.T) {
+	var (
+		topics []string
+		reject bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != kafkaContentType {
+			http.Error(w, "bad content type", http.StatusUnsupportedMediaType)
+			return
+		}
+		var body struct{ Records []kafkaRecord }
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		topics = append(topics, strings.TrimPrefix(r.URL.Path, "/topics/"))
+
+		var offsets []string
+		for i := range body.Records {
+			if reject {
+				offsets = append(offsets, `{"partition":0,"offset":null,"error_code":50003,"error":"broker unavailable"}`)
+			} else {
+				offsets = append(offsets, fmt.Sprintf(`{"partition":0,"offset":%d}`, i))
+			}
+		}
+		fmt.Fprintf(w, `{"offsets":[%s]}`, strings.Join(offsets, ","))
+	}))
+	defer srv.Close()
+
+	sink := newKafkaSink(srv.URL, "aksara", time.Second)
+	msgs := []*Message{
+		{Feed: FeedBlocks, Key: "a", Value: []byte(`{}`)},
+		{Feed: FeedLogs, Key: "b", Value: []byte(`{}`)},
+		{Feed: FeedLogs, Key: "c", Value: []byte(`{}`)},
+	}
+	if err := sink.Publish(msgs); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	if have := strings.Join(topics, ","); have != "aksara.blocks,aksara.logs" {
+		t.Errorf("topics mismatch: have %s", have)
+	}
+	reject = true
+	if err := sink.Publish(msgs); err == nil {
+		t.Errorf("rejected records reported as published")
+	}
+}
+
+// Tests that the NATS sink publishes to the feed subjects with deduplication ids
+// and waits for the JetStream acknowledgements.
+func TestNATSSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 16)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		io.WriteString(conn, "INFO {\"headers\":true,\"jetstream\":true}\r\n")
+		var seq int
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "PING":
+				io.WriteString(conn, "PONG\r\n")
+			case "HPUB":
+				total, _ := strconv.Atoi(fields[4])
+				payload := make([]byte, total+2)
+				io.ReadFull(r, payload)
+				hdrlen, _ := strconv.Atoi(fields[3])
+				received <- fields[1] + " " + strings.TrimSpace(string(payload[:hdrlen]))
+
+				seq++
+				ack := fmt.Sprintf(`{"stream":"AKSARA","seq":%d}`, seq)
+				fmt.Fprintf(conn, "MSG %s 1 %d\r\n%s\r\n", fields[2], len(ack), ack)
+			}
+		}
+	}()
+	sink, err := newNATSSink("nats://"+listener.Addr().String(), "aksara", time.Second)
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	defer sink.Close()
+
+	msgs := []*Message{
+		{Feed: FeedBlocks, Key: "a", Value: []byte(`{}`)},
+		{Feed: FeedLogs, Key: "b", Value: []byte(`{}`)},
+	}
+	if err := sink.Publish(msgs); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	for _, want := range []string{"aksara.blocks NATS/1.0\r\nNats-Msg-Id: a", "aksara.logs NATS/1.0\r\nNats-Msg-Id: b"} {
+		if have := <-received; have != want {
+			t.Errorf("published message mismatch: have %q, want %q", have, want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Chain is the subset of the blockchain a primary streams to its followers.
+type Chain interface {
+	CurrentBlock() *types.Block
+	GetHeader(hash common.Hash, number uint64) *types.Header
+	GetBlockByNumber(number uint64) *types.Block
+	GetCanonicalHash(number uint64) common.Hash
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	StateCache() state.Database
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// PrimaryAPI streams the canonical chain of a primary node to its followers. It
+// is only served on the authenticated RPC endpoint.
+type PrimaryAPI struct {
+	chain Chain
+}
+
+// NewPrimaryAPI creates the API followers follow the primary's chain with.
+func NewPrimaryAPI(chain Chain) *PrimaryAPI {
+	return &PrimaryAPI{chain: chain}
+}
+
+// Blocks streams the RLP encoded canonical blocks from the given number onwards,
+// first the ones already in the chain, then each new one as it's imported. On a
+// reorg the stream resumes from the first block of the new branch, so that the
+// follower can reorg the same way.
+func (api *PrimaryAPI) Blocks(ctx context.Context, from hexutil.Uint64) (*rpc.Subscription, error) {
+	return api.stream(ctx, uint64(from), func(block *types.Block) interface{} {
+		return block
+	})
+}
+
+// Diffs streams the canonical blocks like Blocks, each RLP encoded along with
+// its receipts and state diff, so that the followers can write the blocks
+// without executing them. The diff is left out of the blocks whose parent
+// state the primary has already pruned, the followers execute those.
+func (api *PrimaryAPI) Diffs(ctx context.Context, from hexutil.Uint64) (*rpc.Subscription, error) {
+	return api.stream(ctx, uint64(from), api.replicate)
+}
+
+// replicate bundles a block with its receipts and, if available, state diff.
+func (api *PrimaryAPI) replicate(block *types.Block) interface{} {
+	receipts := api.chain.GetReceiptsByHash(block.Hash())
+	replicated := &replicatedBlock{
+		Block:    block,
+		Receipts: make([]*types.ReceiptForStorage, len(receipts)),
+	}
+	for i, receipt := range receipts {
+		replicated.Receipts[i] = (*types.ReceiptForStorage)(receipt)
+	}
+	if parent := api.chain.GetHeader(block.ParentHash(), block.NumberU64()-1); parent != nil {
+		nodes, codes, err := stateDiff(api.chain.StateCache(), parent.Root, block.Root())
+		if err != nil {
+			log.Debug("Replicating block without state diff", "number", block.Number(), "hash", block.Hash(), "err", err)
+		} else {
+			replicated.Diff, replicated.Nodes, replicated.Codes = true, nodes, codes
+		}
+	}
+	return replicated
+}
+
+// stream notifies the subscriber of the canonical blocks from the given number
+// onwards, each encoded with the given function.
+func (api *PrimaryAPI) stream(ctx context.Context, from uint64, replicate func(block *types.Block) interface{}) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		heads := make(chan core.ChainHeadEvent, 16)
+		headSub := api.chain.SubscribeChainHeadEvent(heads)
+		defer headSub.Unsubscribe()
+
+		var (
+			next = from
+			last common.Hash // Hash of the last block streamed
+		)
+		for {
+			// Rewind to the fork point if the streamed blocks got reorged out
+			if last != (common.Hash{}) && api.chain.GetCanonicalHash(next-1) != last {
+				number, hash := next-1, last
+				for number > 0 && api.chain.GetCanonicalHash(number) != hash {
+					header := api.chain.GetHeader(hash, number)
+					if header == nil {
+						break
+					}
+					number, hash = number-1, header.ParentHash
+				}
+				next, last = number+1, hash
+			}
+			// Stream the blocks up to the current head
+			for head := api.chain.CurrentBlock().NumberU64(); next <= head; next++ {
+				block := api.chain.GetBlockByNumber(next)
+				if block == nil {
+					break
+				}
+				enc, err := rlp.EncodeToBytes(replicate(block))
+				if err != nil {
+					log.Error("Failed to encode replicated block", "number", next, "err", err)
+					return
+				}
+				if err := notifier.Notify(rpcSub.ID, hexutil.Bytes(enc)); err != nil {
+					return
+				}
+				last = block.Hash()
+
+				select {
+				case <-rpcSub.Err():
+					return
+				default:
+				}
+			}
+			select {
+			case <-heads:
+			case <-headSub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var emptyCodeHash = crypto.Keccak256(nil)
+
+// replicatedBlock is a canonical block streamed to the followers, along with
+// its receipts and, if the primary still has the state of its parent, the
+// state diff it introduces: the trie nodes and contract codes not present in
+// the state of its parent. A block streamed with its diff is written as is by
+// the followers, one without is executed.
+type replicatedBlock struct {
+	Block    *types.Block
+	Receipts []*types.ReceiptForStorage
+	Diff     bool     // Whether the state diff is included, it's empty if the block leaves the state untouched
+	Nodes    [][]byte // Trie nodes of the accounts and storage slots modified by the block
+	Codes    [][]byte // Contract codes deployed by the block
+}
+
+// stateDiff collects the trie nodes and contract codes of the state at root
+// which are not present in the state at parent. Every new node gets resolved,
+// so the diff also fails if the state at root is incomplete in the database.
+func stateDiff(db state.Database, parent, root common.Hash) (nodes, codes [][]byte, err error) {
+	oldTrie, err := trie.New(parent, db.TrieDB())
+	if err != nil {
+		return nil, nil, err
+	}
+	newTrie, err := trie.New(root, db.TrieDB())
+	if err != nil {
+		return nil, nil, err
+	}
+	it, _ := trie.NewDifferenceIterator(oldTrie.NodeIterator(nil), newTrie.NodeIterator(nil))
+	for it.Next(true) {
+		if !it.Leaf() {
+			if blob := it.NodeBlob(); blob != nil {
+				nodes = append(nodes, blob)
+			}
+			continue
+		}
+		// A new or modified account, diff its storage and code
+		var account types.StateAccount
+		if err := rlp.DecodeBytes(it.LeafBlob(), &account); err != nil {
+			return nil, nil, err
+		}
+		prev := types.StateAccount{Root: types.EmptyRootHash, CodeHash: emptyCodeHash}
+		if blob, err := oldTrie.TryGet(it.LeafKey()); err != nil {
+			return nil, nil, err
+		} else if len(blob) > 0 {
+			if err := rlp.DecodeBytes(blob, &prev); err != nil {
+				return nil, nil, err
+			}
+		}
+		if account.Root != prev.Root {
+			storage, err := storageDiff(db.TrieDB(), prev.Root, account.Root)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, storage...)
+		}
+		if !bytes.Equal(account.CodeHash, prev.CodeHash) && !bytes.Equal(account.CodeHash, emptyCodeHash) {
+			code, err := db.ContractCode(common.BytesToHash(it.LeafKey()), common.BytesToHash(account.CodeHash))
+			if err != nil {
+				return nil, nil, err
+			}
+			codes = append(codes, code)
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, nil, err
+	}
+	return nodes, codes, nil
+}
+
+// storageDiff collects the nodes of the storage trie at root which are not
+// present in the storage trie at parent.
+func storageDiff(db *trie.Database, parent, root common.Hash) ([][]byte, error) {
+	oldTrie, err := trie.New(parent, db)
+	if err != nil {
+		return nil, err
+	}
+	newTrie, err := trie.New(root, db)
+	if err != nil {
+		return nil, err
+	}
+	var nodes [][]byte
+	it, _ := trie.NewDifferenceIterator(oldTrie.NodeIterator(nil), newTrie.NodeIterator(nil))
+	for it.Next(true) {
+		if blob := it.NodeBlob(); blob != nil {
+			nodes = append(nodes, blob)
+		}
+	}
+	return nodes, it.Error()
+}
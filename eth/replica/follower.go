@@ -0,0 +1,327 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package replica implements chain replication: followers, nodes which don't
+// sync over p2p but import the chain of a primary node, streamed to them over
+// the primary's authenticated RPC endpoint as soon as its blocks are imported,
+// and warm standbys, datadirs the chain is dual-written to.
+//
+// Followers are sent each block along with its receipts and state diff, the
+// trie nodes and contract codes it introduces, and write it without executing
+// it, so they lag their primary by little more than the network round trip.
+// The blocks whose parent state the primary has already pruned, such as the
+// old ones streamed to a follower catching up, come without a diff and get
+// executed by the follower.
+package replica
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// reorgDepth is the number of blocks below its head a follower resumes the
+	// stream from, so that reorgs of the primary while disconnected are caught.
+	reorgDepth = 64
+
+	// maxImportBatch is the maximum number of streamed blocks imported at once.
+	maxImportBatch = 256
+)
+
+var errInvalidSecret = errors.New("invalid JWT secret")
+
+// Config are the configuration parameters of the replication.
+type Config struct {
+	Serve         bool          // Whether to stream the chain to followers over the authenticated endpoint
+	Primary       string        // Websocket URL of the primary's authenticated endpoint, empty disables follower mode
+	JWTSecret     string        // Path of the primary's JWT secret
+	RetryInterval time.Duration // Delay before reconnecting to the primary
+	Standby       string        // Path of a warm standby datadir the chain is dual-written to, empty disables it
+}
+
+// DefaultConfig contains the default settings for the replication.
+var DefaultConfig = Config{
+	RetryInterval: 5 * time.Second,
+}
+
+// BlockChain is the subset of the blockchain a follower imports the streamed
+// blocks into.
+type BlockChain interface {
+	consensus.ChainHeaderReader
+
+	CurrentBlock() *types.Block
+	GetCanonicalHash(number uint64) common.Hash
+	Engine() consensus.Engine
+	StateCache() state.Database
+	InsertChain(chain types.Blocks) (int, error)
+	SetCanonical(head *types.Block) (common.Hash, error)
+}
+
+// Follower follows the canonical chain of a primary node, writing its blocks
+// along with their state diffs as they are streamed.
+type Follower struct {
+	db    ethdb.Database
+	chain BlockChain
+	dial  func(ctx context.Context) (*rpc.Client, error)
+	retry time.Duration
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewFollower creates a follower of the primary configured, authenticating with
+// the JWT secret shared with the primary. The state diffs are written to the
+// chain database given.
+func NewFollower(config Config, db ethdb.Database, chain BlockChain) (*Follower, error) {
+	secret, err := readSecret(config.JWTSecret)
+	if err != nil {
+		return nil, err
+	}
+	dial := func(ctx context.Context) (*rpc.Client, error) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		}).SignedString(secret)
+		if err != nil {
+			return nil, err
+		}
+		header := http.Header{"Authorization": []string{"Bearer " + token}}
+		return rpc.DialWebsocketWithHeader(ctx, config.Primary, "", header)
+	}
+	return newFollower(db, chain, dial, config.RetryInterval), nil
+}
+
+func newFollower(db ethdb.Database, chain BlockChain, dial func(ctx context.Context) (*rpc.Client, error), retry time.Duration) *Follower {
+	if retry <= 0 {
+		retry = DefaultConfig.RetryInterval
+	}
+	return &Follower{
+		db:    db,
+		chain: chain,
+		dial:  dial,
+		retry: retry,
+		quit:  make(chan struct{}),
+	}
+}
+
+// readSecret loads a hex encoded 32 byte JWT secret.
+func readSecret(path string) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("missing JWT secret of the primary")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	secret := common.FromHex(strings.TrimSpace(string(data)))
+	if len(secret) != 32 {
+		return nil, errInvalidSecret
+	}
+	return secret, nil
+}
+
+// Start starts following the primary.
+func (f *Follower) Start() {
+	f.wg.Add(1)
+	go f.loop()
+}
+
+// Stop disconnects from the primary and waits for the block import in progress.
+func (f *Follower) Stop() {
+	close(f.quit)
+	f.wg.Wait()
+}
+
+func (f *Follower) loop() {
+	defer f.wg.Done()
+
+	for {
+		if err := f.follow(); err != nil {
+			log.Warn("Following primary interrupted", "err", err, "retry", f.retry)
+		}
+		select {
+		case <-time.After(f.retry):
+		case <-f.quit:
+			return
+		}
+	}
+}
+
+// follow connects to the primary and imports the streamed blocks until the
+// connection fails or the follower is stopped.
+func (f *Follower) follow() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-f.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	client, err := f.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	from := uint64(1)
+	if head := f.chain.CurrentBlock().NumberU64(); head > reorgDepth {
+		from = head - reorgDepth
+	}
+	stream := make(chan hexutil.Bytes, maxImportBatch)
+	sub, err := client.Subscribe(ctx, "replica", stream, "diffs", hexutil.Uint64(from))
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	log.Info("Following chain of primary", "from", from)
+
+	for {
+		select {
+		case enc := <-stream:
+			// Import the blocks queued up meanwhile along with this one, writing
+			// those with a state diff and executing the others in batches
+			var blocks types.Blocks
+			for {
+				replicated := new(replicatedBlock)
+				if err := rlp.DecodeBytes(enc, replicated); err != nil {
+					return err
+				}
+				if !replicated.Diff {
+					blocks = append(blocks, replicated.Block)
+				} else {
+					if len(blocks) > 0 {
+						if _, err := f.chain.InsertChain(blocks); err != nil {
+							return err
+						}
+						blocks = nil
+					}
+					if err := f.writeBlock(replicated); err != nil {
+						return err
+					}
+				}
+				if len(blocks) == maxImportBatch || len(stream) == 0 {
+					break
+				}
+				enc = <-stream
+			}
+			if len(blocks) > 0 {
+				if _, err := f.chain.InsertChain(blocks); err != nil {
+					return err
+				}
+			}
+		case err := <-sub.Err():
+			return err
+		case <-f.quit:
+			return nil
+		}
+	}
+}
+
+// writeBlock imports a streamed block without executing it: the header is
+// verified, the body and receipts are checked against it, the state diff is
+// written on top of the state of its parent and the block is set as the head.
+func (f *Follower) writeBlock(replicated *replicatedBlock) error {
+	var (
+		block  = replicated.Block
+		header = block.Header()
+		number = block.NumberU64()
+	)
+	// Skip the blocks already followed, streamed again on reconnects
+	if f.chain.GetCanonicalHash(number) == block.Hash() {
+		return nil
+	}
+	parent := f.chain.GetHeader(block.ParentHash(), number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	if err := f.chain.Engine().VerifyHeader(f.chain, header, true); err != nil {
+		return err
+	}
+	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != header.TxHash {
+		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
+	}
+	if hash := types.CalcUncleHash(block.Uncles()); hash != header.UncleHash {
+		return fmt.Errorf("uncle root hash mismatch: have %x, want %x", hash, header.UncleHash)
+	}
+	receipts := make(types.Receipts, len(replicated.Receipts))
+	for i, receipt := range replicated.Receipts {
+		receipts[i] = (*types.Receipt)(receipt)
+	}
+	if err := receipts.DeriveFields(f.chain.Config(), block.Hash(), number, block.Transactions()); err != nil {
+		return err
+	}
+	if hash := types.DeriveSha(receipts, trie.NewStackTrie(nil)); hash != header.ReceiptHash {
+		return fmt.Errorf("receipt root hash mismatch: have %x, want %x", hash, header.ReceiptHash)
+	}
+	if bloom := types.CreateBloom(receipts); bloom != header.Bloom {
+		return fmt.Errorf("bloom mismatch: have %x, want %x", bloom, header.Bloom)
+	}
+	// Flush the parent state held in memory, the diff only references it
+	triedb := f.chain.StateCache().TrieDB()
+	if err := triedb.Commit(parent.Root, false, nil); err != nil {
+		return err
+	}
+	batch := f.db.NewBatch()
+	for _, node := range replicated.Nodes {
+		rawdb.WriteTrieNode(batch, crypto.Keccak256Hash(node), node)
+	}
+	for _, code := range replicated.Codes {
+		rawdb.WriteCode(batch, crypto.Keccak256Hash(code), code)
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	// The nodes being keyed by their hashes, the state is the one committed to
+	// by the header as long as the diff leaves no node of it missing
+	if _, _, err := stateDiff(f.chain.StateCache(), parent.Root, header.Root); err != nil {
+		return fmt.Errorf("incomplete state diff of block #%d: %v", number, err)
+	}
+	ptd := f.chain.GetTd(parent.Hash(), number-1)
+	if ptd == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	batch.Reset()
+	rawdb.WriteTd(batch, block.Hash(), number, new(big.Int).Add(ptd, block.Difficulty()))
+	rawdb.WriteBlock(batch, block)
+	rawdb.WriteReceipts(batch, block.Hash(), number, receipts)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	_, err := f.chain.SetCanonical(block)
+	return err
+}
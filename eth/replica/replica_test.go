@@ -0,0 +1,261 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+
+	// testInitCode sets the first storage slot of the contract to 1 and deploys
+	// the single byte 0x42 as its code.
+	testInitCode = common.FromHex("0x6001600055604260005360016000f3")
+)
+
+func newTestChain(t *testing.T, gspec *core.Genesis) (*core.BlockChain, ethdb.Database) {
+	db := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	return chain, db
+}
+
+// waitHead waits until the chain's head is the given block.
+func waitHead(t *testing.T, chain *core.BlockChain, want *types.Block) {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if chain.CurrentBlock().Hash() == want.Hash() {
+			return
+		}
+	}
+	head := chain.CurrentBlock()
+	t.Fatalf("follower head mismatch: have #%d [%x], want #%d [%x]", head.NumberU64(), head.Hash().Bytes()[:4], want.NumberU64(), want.Hash().Bytes()[:4])
+}
+
+// prunedChain is a primary which, while pruned is set, lacks the state of its
+// blocks, so that it streams them without state diffs.
+type prunedChain struct {
+	*core.BlockChain
+	pruned int32
+}
+
+func (c *prunedChain) StateCache() state.Database {
+	if atomic.LoadInt32(&c.pruned) == 1 {
+		return state.NewDatabase(rawdb.NewMemoryDatabase())
+	}
+	return c.BlockChain.StateCache()
+}
+
+// countingChain is a follower counting the blocks it executes.
+type countingChain struct {
+	*core.BlockChain
+	executed int32
+}
+
+func (c *countingChain) InsertChain(blocks types.Blocks) (int, error) {
+	atomic.AddInt32(&c.executed, int32(len(blocks)))
+	return c.BlockChain.InsertChain(blocks)
+}
+
+// Tests that a follower catches up with the chain of its primary, executing the
+// blocks streamed without state diffs, follows its new blocks by writing their
+// state diffs and reorgs the same way.
+func TestFollower(t *testing.T) {
+	var (
+		gspec = &core.Genesis{
+			Config:  params.TestChainConfig,
+			BaseFee: big.NewInt(params.InitialBaseFee),
+			Alloc:   core.GenesisAlloc{testAddress: {Balance: big.NewInt(params.Ether)}},
+		}
+		primaryChain, _           = newTestChain(t, gspec)
+		followerChain, followerDb = newTestChain(t, gspec)
+
+		primary  = &prunedChain{BlockChain: primaryChain, pruned: 1}
+		follower = &countingChain{BlockChain: followerChain}
+		genesis  = primaryChain.Genesis()
+		signer   = types.LatestSigner(gspec.Config)
+		contract = crypto.CreateAddress(testAddress, 6)
+	)
+	defer primaryChain.Stop()
+	defer followerChain.Stop()
+
+	genDb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(genDb)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), genDb, 10, func(i int, b *core.BlockGen) {
+		tx := &types.LegacyTx{Nonce: uint64(i), Gas: params.TxGas, GasPrice: b.BaseFee(), To: &common.Address{byte(i + 1)}, Value: big.NewInt(1)}
+		if i == 6 {
+			tx.Gas, tx.To, tx.Data = 100000, nil, testInitCode
+		}
+		b.AddTx(types.MustSignNewTx(testKey, signer, tx))
+	})
+	forks, _ := core.GenerateChain(gspec.Config, blocks[5], ethash.NewFaker(), genDb, 8, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := primaryChain.InsertChain(blocks[:6]); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("replica", NewPrimaryAPI(primary)); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	f := newFollower(followerDb, follower, func(ctx context.Context) (*rpc.Client, error) {
+		return rpc.DialInProc(server), nil
+	}, time.Second)
+	f.Start()
+	defer f.Stop()
+
+	// The follower catches up with the existing blocks by executing them, then
+	// follows the new ones by writing their state diffs
+	waitHead(t, followerChain, blocks[5])
+	if executed := atomic.LoadInt32(&follower.executed); executed != 6 {
+		t.Fatalf("executed block count mismatch: have %d, want 6", executed)
+	}
+	atomic.StoreInt32(&primary.pruned, 0)
+	if _, err := primaryChain.InsertChain(blocks[6:]); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	waitHead(t, followerChain, blocks[9])
+	if executed := atomic.LoadInt32(&follower.executed); executed != 6 {
+		t.Fatalf("blocks with state diffs executed: have %d executed, want 6", executed)
+	}
+	statedb, err := followerChain.State()
+	if err != nil {
+		t.Fatalf("follower head state missing: %v", err)
+	}
+	if code := statedb.GetCode(contract); !bytes.Equal(code, []byte{0x42}) {
+		t.Errorf("deployed code mismatch: have %x, want 42", code)
+	}
+	if slot := statedb.GetState(contract, common.Hash{}); slot != common.BigToHash(common.Big1) {
+		t.Errorf("contract storage mismatch: have %x, want 1", slot)
+	}
+	if nonce := statedb.GetNonce(testAddress); nonce != 10 {
+		t.Errorf("sender nonce mismatch: have %d, want 10", nonce)
+	}
+	if receipts := followerChain.GetReceiptsByHash(blocks[6].Hash()); len(receipts) != 1 || receipts[0].ContractAddress != contract {
+		t.Errorf("receipts of written block mismatch: %v", receipts)
+	}
+	// A heavier branch of the primary replaces the followed blocks
+	if _, err := primaryChain.InsertChain(forks); err != nil {
+		t.Fatalf("failed to import fork: %v", err)
+	}
+	waitHead(t, followerChain, forks[len(forks)-1])
+	if hash := followerChain.GetCanonicalHash(blocks[6].NumberU64()); hash != forks[0].Hash() {
+		t.Fatalf("follower did not reorg: canonical #%d is %x", blocks[6].NumberU64(), hash)
+	}
+	if executed := atomic.LoadInt32(&follower.executed); executed != 6 {
+		t.Fatalf("reorged blocks executed: have %d executed, want 6", executed)
+	}
+	if _, err := followerChain.State(); err != nil {
+		t.Fatalf("follower reorged head state missing: %v", err)
+	}
+}
+
+// Tests that the standby datadir is initialized with the genesis of the node,
+// follows its chain through reorgs and retains it across restarts.
+func TestStandby(t *testing.T) {
+	var (
+		gspec   = &core.Genesis{Config: params.TestChainConfig, BaseFee: big.NewInt(params.InitialBaseFee)}
+		nodeDb  = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(nodeDb)
+		path    = t.TempDir()
+	)
+	primary, err := core.NewBlockChain(nodeDb, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer primary.Stop()
+
+	genDb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(genDb)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), genDb, 10, nil)
+	forks, _ := core.GenerateChain(gspec.Config, blocks[5], ethash.NewFaker(), genDb, 8, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := primary.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	metadata := []byte("clique-meta")
+	if err := nodeDb.Put(metadata, []byte{0x01}); err != nil {
+		t.Fatalf("failed to write metadata: %v", err)
+	}
+	standby, err := NewStandby(path, nodeDb, primary, ethash.NewFaker(), nil)
+	if err != nil {
+		t.Fatalf("failed to open standby: %v", err)
+	}
+	if err := standby.sync(); err != nil {
+		t.Fatalf("failed to write standby: %v", err)
+	}
+	if head := standby.Head(); head.Hash() != blocks[9].Hash() {
+		t.Fatalf("standby head mismatch: have #%d, want #%d", head.NumberU64(), blocks[9].NumberU64())
+	}
+	// A heavier branch of the node replaces the written blocks
+	if _, err := primary.InsertChain(forks); err != nil {
+		t.Fatalf("failed to import fork: %v", err)
+	}
+	if err := standby.sync(); err != nil {
+		t.Fatalf("failed to write fork to standby: %v", err)
+	}
+	if head := standby.Head(); head.Hash() != forks[len(forks)-1].Hash() {
+		t.Fatalf("standby did not reorg: head #%d", head.NumberU64())
+	}
+	standby.Start()
+	standby.Stop()
+
+	// The standby datadir reopens as a chain of its own, with the metadata
+	db, err := rawdb.NewLevelDBDatabaseWithFreezer(filepath.Join(path, "geth", "chaindata"), 16, 16, filepath.Join(path, "geth", "chaindata", "ancient"), "", false)
+	if err != nil {
+		t.Fatalf("failed to reopen standby: %v", err)
+	}
+	defer db.Close()
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to promote standby: %v", err)
+	}
+	defer chain.Stop()
+	if head := chain.CurrentBlock(); head.Hash() != forks[len(forks)-1].Hash() {
+		t.Fatalf("promoted head mismatch: have #%d, want #%d", head.NumberU64(), forks[len(forks)-1].NumberU64())
+	}
+	if _, err := chain.State(); err != nil {
+		t.Fatalf("promoted head state missing: %v", err)
+	}
+	if blob, _ := db.Get(metadata); len(blob) != 1 {
+		t.Fatalf("consensus metadata not mirrored")
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// standbyCache is the database cache allowance of the standby, in megabytes.
+	standbyCache = 64
+
+	// standbyHandles is the number of open files allowed to the standby database.
+	standbyHandles = 128
+
+	// metadataInterval is the number of blocks written to the standby between
+	// two refreshes of its consensus metadata.
+	metadataInterval = 128
+)
+
+// standbyMetadata are the key prefixes of the consensus metadata persisted by
+// the engine next to the chain, mirrored to the standby so that it can seal and
+// verify right after promotion: the clique snapshots and the DNR registry.
+var standbyMetadata = [][]byte{[]byte("clique-"), []byte("dnr-")}
+
+// Standby dual-writes the canonical chain of the node to a second datadir, a
+// warm standby which can be started as a node of its own whenever the primary
+// datadir is lost. The committed chain segments are imported into the standby
+// as they land, so its state is kept up to date rather than rebuilt on
+// promotion.
+type Standby struct {
+	path    string
+	source  ethdb.Database   // Database of the node, the consensus metadata is mirrored from
+	primary *core.BlockChain // Chain of the node the standby follows
+	db      ethdb.Database   // Chain database of the standby datadir
+	chain   *core.BlockChain // Chain of the standby, importing the primary's blocks
+
+	written uint64 // Head of the standby at the last metadata refresh
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewStandby opens, or on first use initializes with the genesis of the node,
+// the standby datadir at the given path, which may be a local or a mounted
+// network path. Blocks are verified by the node before reaching the standby so
+// the engine of the node is shared with it.
+func NewStandby(path string, source ethdb.Database, primary *core.BlockChain, engine consensus.Engine, cacheConfig *core.CacheConfig) (*Standby, error) {
+	dir := filepath.Join(path, "geth", "chaindata")
+	db, err := rawdb.NewLevelDBDatabaseWithFreezer(dir, standbyCache, standbyHandles, filepath.Join(dir, "ancient"), "eth/db/standby/", false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open standby datadir: %v", err)
+	}
+	genesis, err := core.ReadGenesis(source)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, hash, err := core.SetupGenesisBlock(db, genesis); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize standby datadir: %v", err)
+	} else if hash != primary.Genesis().Hash() {
+		db.Close()
+		return nil, fmt.Errorf("standby genesis mismatch: have %x, want %x", hash, primary.Genesis().Hash())
+	}
+	// The clean trie cache journal belongs to the node, don't share it
+	if cacheConfig != nil {
+		config := *cacheConfig
+		config.TrieCleanJournal = ""
+		cacheConfig = &config
+	}
+	chain, err := core.NewBlockChain(db, cacheConfig, primary.Config(), engine, vm.Config{}, nil, nil)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	log.Info("Opened standby datadir", "path", path, "head", chain.CurrentBlock().NumberU64())
+
+	return &Standby{
+		path:    path,
+		source:  source,
+		primary: primary,
+		db:      db,
+		chain:   chain,
+		written: chain.CurrentBlock().NumberU64(),
+		quit:    make(chan struct{}),
+	}, nil
+}
+
+// Start launches the writer of the standby.
+func (s *Standby) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop waits for the segment being written, flushes the standby's state and
+// closes its datadir.
+func (s *Standby) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+
+	if err := s.mirrorMetadata(); err != nil {
+		log.Warn("Failed to mirror consensus metadata to standby", "err", err)
+	}
+	s.chain.Stop()
+	s.db.Close()
+}
+
+// Head returns the current head of the standby.
+func (s *Standby) Head() *types.Block {
+	return s.chain.CurrentBlock()
+}
+
+func (s *Standby) loop() {
+	defer s.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := s.primary.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	s.write()
+	for {
+		select {
+		case <-heads:
+			s.write()
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// write brings the standby up to the head of the node, logging any failure.
+// Writes failing on an unavailable network path are retried on the next head.
+func (s *Standby) write() {
+	if err := s.sync(); err != nil {
+		log.Warn("Failed to write chain to standby", "path", s.path, "err", err)
+	}
+}
+
+// sync imports the canonical blocks of the node the standby is missing, going
+// back to where the two chains share a block to follow reorgs of the node.
+func (s *Standby) sync() error {
+	var (
+		head   = s.primary.CurrentBlock().NumberU64()
+		number = s.chain.CurrentBlock().NumberU64()
+	)
+	if number > head {
+		number = head
+	}
+	// Find the last block of the standby still canonical on the node
+	for ; number > 0; number-- {
+		if s.primary.GetCanonicalHash(number) == s.chain.GetCanonicalHash(number) {
+			break
+		}
+	}
+	for number < head {
+		select {
+		case <-s.quit:
+			return nil
+		default:
+		}
+		blocks := make(types.Blocks, 0, maxImportBatch)
+		for next := number + 1; next <= head && len(blocks) < maxImportBatch; next++ {
+			block := s.primary.GetBlockByNumber(next)
+			if block == nil {
+				break // Reorged meanwhile, picked up on the next head
+			}
+			blocks = append(blocks, block)
+		}
+		if len(blocks) == 0 {
+			return nil
+		}
+		if _, err := s.chain.InsertChain(blocks); err != nil {
+			return err
+		}
+		number = blocks[len(blocks)-1].NumberU64()
+	}
+	if number >= s.written+metadataInterval || number < s.written {
+		if err := s.mirrorMetadata(); err != nil {
+			return err
+		}
+		s.written = number
+	}
+	return nil
+}
+
+// mirrorMetadata copies the consensus metadata of the node not yet, or no longer
+// equally, present in the standby.
+func (s *Standby) mirrorMetadata() error {
+	batch := s.db.NewBatch()
+	for _, prefix := range standbyMetadata {
+		it := s.source.NewIterator(prefix, nil)
+		for it.Next() {
+			if have, _ := s.db.Get(it.Key()); bytes.Equal(have, it.Value()) {
+				continue
+			}
+			if err := batch.Put(it.Key(), it.Value()); err != nil {
+				it.Release()
+				return err
+			}
+			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		err := it.Error()
+		it.Release()
+		if err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// sentryLinks are the nodes on the other side of the sentry architecture: the
+// sentries of a validator, or the validators behind a sentry. The links are
+// kept connected as trusted peers over the node key authenticated devp2p
+// transport, and are sent every block and transaction in full.
+type sentryLinks struct {
+	nodes     []*enode.Node
+	ids       map[string]bool
+	validator bool // Whether the local node is a validator behind the linked sentries
+}
+
+// newSentryLinks parses the sentry configuration, verifying that a validator
+// behind sentries doesn't peer with or get discovered by anyone else. Nil is
+// returned if the node isn't part of a sentry architecture.
+func newSentryLinks(config *ethconfig.Config, p2pConfig *p2p.Config) (*sentryLinks, error) {
+	if len(config.SentryNodes) > 0 && len(config.SentryValidators) > 0 {
+		return nil, errors.New("node can't both run behind sentries and be a sentry")
+	}
+	urls, validator := config.SentryValidators, false
+	if len(config.SentryNodes) > 0 {
+		urls, validator = config.SentryNodes, true
+	}
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	if validator && (!p2pConfig.TrustedOnly || !p2pConfig.NoDiscovery || p2pConfig.DiscoveryV5) {
+		return nil, errors.New("validator behind sentries must disable discovery and only peer with trusted nodes")
+	}
+	links := &sentryLinks{ids: make(map[string]bool), validator: validator}
+	for _, url := range urls {
+		node, err := enode.Parse(enode.ValidSchemes, url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sentry link %q: %v", url, err)
+		}
+		links.nodes = append(links.nodes, node)
+		links.ids[node.ID().String()] = true
+	}
+	return links, nil
+}
+
+// connect adds the linked nodes as trusted static peers.
+func (l *sentryLinks) connect(srv *p2p.Server) {
+	for _, node := range l.nodes {
+		srv.AddTrustedPeer(node)
+		srv.AddPeer(node)
+	}
+	if l.validator {
+		log.Info("Peering exclusively with sentries", "sentries", len(l.nodes))
+	} else {
+		log.Info("Relaying for validators behind the sentry", "validators", len(l.nodes))
+	}
+}
+
+// splitLinked separates the peers on the other side of the sentry links from
+// the rest.
+func (h *handler) splitLinked(peers []*ethPeer) ([]*ethPeer, []*ethPeer) {
+	if len(h.linked) == 0 {
+		return nil, peers
+	}
+	var linked, others []*ethPeer
+	for _, peer := range peers {
+		if h.linked[peer.ID()] {
+			linked = append(linked, peer)
+		} else {
+			others = append(others, peer)
+		}
+	}
+	return linked, others
+}
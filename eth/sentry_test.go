@@ -0,0 +1,68 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Tests that the sentry links are parsed, and that validators behind sentries
+// are required to stay hidden from the network.
+func TestSentryLinks(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	url := enode.NewV4(&key.PublicKey, nil, 30303, 30303).URLv4()
+
+	// Nodes outside of a sentry architecture have no links
+	if links, err := newSentryLinks(&ethconfig.Config{}, &p2p.Config{}); err != nil || links != nil {
+		t.Fatalf("unexpected links without sentries: %v, %v", links, err)
+	}
+	// Sentries relay for their validators without restrictions
+	links, err := newSentryLinks(&ethconfig.Config{SentryValidators: []string{url}}, &p2p.Config{})
+	if err != nil {
+		t.Fatalf("failed to link sentry: %v", err)
+	}
+	if links.validator || !links.ids[enode.PubkeyToIDV4(&key.PublicKey).String()] {
+		t.Fatalf("sentry links mismatch: %+v", links)
+	}
+	// Validators must only peer with trusted nodes and not be discoverable
+	config := &ethconfig.Config{SentryNodes: []string{url}}
+	if _, err := newSentryLinks(config, &p2p.Config{NoDiscovery: true}); err == nil {
+		t.Fatalf("validator accepting untrusted peers linked")
+	}
+	if _, err := newSentryLinks(config, &p2p.Config{TrustedOnly: true}); err == nil {
+		t.Fatalf("discoverable validator linked")
+	}
+	links, err = newSentryLinks(config, &p2p.Config{NoDiscovery: true, TrustedOnly: true})
+	if err != nil {
+		t.Fatalf("failed to link validator: %v", err)
+	}
+	if !links.validator || len(links.nodes) != 1 {
+		t.Fatalf("validator links mismatch: %+v", links)
+	}
+	// Nodes can't be both, and links must be valid
+	if _, err := newSentryLinks(&ethconfig.Config{SentryNodes: []string{url}, SentryValidators: []string{url}}, &p2p.Config{NoDiscovery: true, TrustedOnly: true}); err == nil {
+		t.Fatalf("node linked as both validator and sentry")
+	}
+	if _, err := newSentryLinks(&ethconfig.Config{SentryValidators: []string{"enode://invalid"}}, &p2p.Config{}); err == nil {
+		t.Fatalf("invalid link accepted")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// errSetHeadSealing is returned when rewinding the chain of a sealing node
+	// whose sealing isn't paused.
+	errSetHeadSealing = errors.New("node is sealing, pause the sealing before rewinding the chain")
+
+	// errSetHeadFuture is returned when rewinding the chain to above its head.
+	errSetHeadFuture = errors.New("block above the chain head")
+)
+
+// setHead rewinds the chain to a previous block, guarding validators against
+// mistyped targets: the sealing must be paused, and rewinding past the epoch
+// block the current signer set was persisted at requires force. Every attempt
+// is recorded in the operator audit log, refused ones included.
+func (s *Ethereum) setHead(number uint64, force bool) error {
+	head := s.blockchain.CurrentBlock().NumberU64()
+	err := s.checkSetHead(number, head, force)
+	entry := &rawdb.AuditEntry{
+		Time:      uint64(time.Now().Unix()),
+		Operation: "debug_setHead",
+		Details:   fmt.Sprintf("number=%d head=%d force=%t", number, head, force),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		rawdb.WriteAuditEntry(s.chainDb, entry)
+		log.Warn("Refused to rewind chain", "number", number, "head", head, "force", force, "err", err)
+		return err
+	}
+	s.handler.downloader.Cancel()
+	if err := s.blockchain.SetHead(number); err != nil {
+		entry.Error = err.Error()
+		rawdb.WriteAuditEntry(s.chainDb, entry)
+		return err
+	}
+	newHead := s.blockchain.CurrentBlock().NumberU64()
+	entry.Details += fmt.Sprintf(" newhead=%d", newHead)
+
+	// Drop the clique snapshots and checkpoints of the rewound epoch blocks, so
+	// they aren't mistaken for the ones of the blocks imported next
+	if s.cliqueEngine() != nil {
+		deleted, err := clique.RewindCheckpoints(s.chainDb, s.blockchain.Config().Clique, newHead)
+		if err != nil {
+			entry.Error = err.Error()
+			rawdb.WriteAuditEntry(s.chainDb, entry)
+			return err
+		}
+		entry.Details += fmt.Sprintf(" deleted=%d", deleted)
+	}
+	rawdb.WriteAuditEntry(s.chainDb, entry)
+	log.Warn("Rewound chain on operator request", "number", number, "head", head, "newhead", newHead, "force", force)
+	return nil
+}
+
+// checkSetHead returns the reason the chain may not be rewound to the given
+// block, if any.
+func (s *Ethereum) checkSetHead(number, head uint64, force bool) error {
+	if number > head {
+		return fmt.Errorf("%w: #%d, head #%d", errSetHeadFuture, number, head)
+	}
+	cli := s.cliqueEngine()
+	if cli == nil {
+		return nil
+	}
+	if s.IsMining() && !cli.SealingPaused() {
+		return errSetHeadSealing
+	}
+	if force {
+		return nil
+	}
+	epoch, err := cli.LastEpochBlock(s.blockchain)
+	if err != nil {
+		return fmt.Errorf("failed to find the last epoch block, force to rewind anyway: %v", err)
+	}
+	if number < epoch {
+		return fmt.Errorf("block #%d is before the last epoch block #%d, force to rewind past it", number, epoch)
+	}
+	return nil
+}
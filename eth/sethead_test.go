@@ -0,0 +1,155 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/miner"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newSetHeadTestBackend creates a clique backend with a chain of four blocks,
+// the second one being the last epoch block.
+func newSetHeadTestBackend(t *testing.T) *Ethereum {
+	var (
+		db        = rawdb.NewMemoryDatabase()
+		validator = common.Address{0x01}
+		config    = *params.AllCliqueProtocolChanges
+	)
+	config.Clique = &params.CliqueConfig{Period: 1, InitialValidators: []common.Address{validator}}
+
+	extra := append(make([]byte, 32), validator[:]...)
+	extra = append(extra, make([]byte, 65)...)
+
+	genesis := (&core.Genesis{Config: &config, ExtraData: extra}).MustCommit(db)
+	clique.NewDNR(config.Clique, db)
+
+	// The chain is imported without the clique rules, only its snapshots matter
+	blocks, _ := core.GenerateChain(&config, genesis, ethash.NewFaker(), db, 4, func(i int, block *core.BlockGen) {
+		if i == 1 {
+			block.SetExtra(extra)
+			block.SetNonce(types.EncodeNonce(1))
+		}
+	})
+	chain, err := core.NewBlockChain(db, nil, &config, ethash.NewFullFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	if _, err := clique.RebuildSnapshots(db, config.Clique, nil); err != nil {
+		t.Fatalf("failed to build snapshots: %v", err)
+	}
+	mux := new(event.TypeMux)
+	eth := &Ethereum{
+		engine:     clique.New(config.Clique, db),
+		blockchain: chain,
+		chainDb:    db,
+		eventMux:   mux,
+		handler:    &handler{downloader: downloader.New(0, db, mux, chain, nil, func(string) {}, nil)},
+	}
+	eth.txPool = core.NewTxPool(core.DefaultTxPoolConfig, &config, chain)
+	eth.miner = miner.New(eth, &miner.Config{GasCeil: params.GenesisGasLimit, Recommit: time.Second}, &config, eth.eventMux, eth.engine, nil)
+
+	t.Cleanup(func() {
+		eth.miner.Close()
+		eth.handler.downloader.Terminate()
+		eth.txPool.Stop()
+		chain.Stop()
+	})
+	return eth
+}
+
+// Tests that rewinding a sealing validator is refused until its sealing is
+// paused, that rewinding past the last epoch block requires force, and that
+// the refused attempts are audited.
+func TestSetHeadGuards(t *testing.T) {
+	eth := newSetHeadTestBackend(t)
+
+	eth.miner.Start(common.Address{0x01})
+	for i := 0; !eth.IsMining(); i++ {
+		if i == 100 {
+			t.Fatalf("miner not started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := eth.setHead(3, false); !errors.Is(err, errSetHeadSealing) {
+		t.Fatalf("rewind while sealing: have %v, want %v", err, errSetHeadSealing)
+	}
+	if _, err := eth.engine.(*clique.Clique).PauseSealing(4, "rewind"); err != nil {
+		t.Fatalf("failed to pause sealing: %v", err)
+	}
+	if err := eth.setHead(5, false); !errors.Is(err, errSetHeadFuture) {
+		t.Fatalf("rewind above head: have %v, want %v", err, errSetHeadFuture)
+	}
+	if err := eth.setHead(1, false); err == nil || !strings.Contains(err.Error(), "last epoch block #2") {
+		t.Fatalf("rewind past the epoch: have %v, want refusal", err)
+	}
+	if err := eth.checkSetHead(2, 4, false); err != nil {
+		t.Fatalf("rewind to the epoch refused: %v", err)
+	}
+	if err := eth.checkSetHead(1, 4, true); err != nil {
+		t.Fatalf("forced rewind past the epoch refused: %v", err)
+	}
+	if head := eth.blockchain.CurrentBlock().NumberU64(); head != 4 {
+		t.Fatalf("chain rewound by a refused attempt: head #%d", head)
+	}
+	// Every refused attempt must have been recorded, most recent first
+	entries := rawdb.ReadAuditLog(eth.chainDb)
+	if len(entries) != 3 {
+		t.Fatalf("audit entry count mismatch: have %d, want 3", len(entries))
+	}
+	for i, details := range []string{"number=1 head=4 force=false", "number=5 head=4 force=false", "number=3 head=4 force=false"} {
+		if entries[i].Operation != "debug_setHead" || entries[i].Details != details || entries[i].Error == "" {
+			t.Errorf("audit entry %d mismatch: have %+v, want refused %q", i, entries[i], details)
+		}
+	}
+}
+
+// Tests that a rewind past an epoch block drops the clique snapshot and
+// checkpoint persisted for it, as a rollback does.
+func TestSetHeadRewindsCheckpoints(t *testing.T) {
+	eth := newSetHeadTestBackend(t)
+
+	if checkpoints := clique.ReadEpochCheckpoints(eth.chainDb); len(checkpoints) != 1 || checkpoints[0].Number != 2 {
+		t.Fatalf("checkpoints mismatch before rewind: have %+v, want epoch block #2", checkpoints)
+	}
+	if err := eth.setHead(1, true); err != nil {
+		t.Fatalf("forced rewind past the epoch failed: %v", err)
+	}
+	if checkpoints := clique.ReadEpochCheckpoints(eth.chainDb); len(checkpoints) != 0 {
+		t.Fatalf("checkpoints of rewound epoch blocks retained: %+v", checkpoints)
+	}
+	// The snapshot and the checkpoint of the epoch block were both deleted
+	if entries := rawdb.ReadAuditLog(eth.chainDb); len(entries) != 1 || !strings.HasSuffix(entries[0].Details, "newhead=1 deleted=2") {
+		t.Fatalf("audit entry mismatch: have %+v", entries)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// errNoClique is returned if the signer is changed on a node not running clique.
+	errNoClique = errors.New("signer handover requires clique")
+
+	// errSignerUnlockForbidden is returned if a signer passphrase is sent while
+	// the RPC APIs are exposed, the same as for personal_unlockAccount.
+	errSignerUnlockForbidden = errors.New("signer unlock with HTTP access is forbidden")
+)
+
+// SignerStatus is the sealing account of the node after a signer change.
+type SignerStatus struct {
+	Signer     common.Address `json:"signer"`
+	Authorized bool           `json:"authorized"` // Whether the signer is authorized to seal at the current head
+	Mining     bool           `json:"mining"`
+}
+
+// passphraseWallet is a keystore wallet signing with the passphrase of the
+// sealing account, so that sealing doesn't unlock the account for every other
+// user of the keystore.
+type passphraseWallet struct {
+	accounts.Wallet
+	passphrase string
+}
+
+// SignData implements accounts.Wallet, signing with the sealing passphrase.
+func (w *passphraseWallet) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	return w.Wallet.SignDataWithPassphrase(account, w.passphrase, mimeType, data)
+}
+
+// SignText implements accounts.Wallet, signing with the sealing passphrase.
+func (w *passphraseWallet) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	return w.Wallet.SignTextWithPassphrase(account, w.passphrase, text)
+}
+
+// authorizeSigner makes clique seal with the given account held by the wallet,
+// advertising the validator role if requested. If the signer was handed over
+// with a passphrase, sealing signs with it instead of the wallet's session.
+func (s *Ethereum) authorizeSigner(signer common.Address, wallet accounts.Wallet) {
+	s.lock.RLock()
+	if s.signerWallet != nil && s.signer == signer {
+		wallet = s.signerWallet
+	}
+	s.lock.RUnlock()
+
+	s.cliqueEngine().Authorize(signer, wallet.SignData)
+	if err := s.advertiseValidator(signer, wallet); err != nil {
+		log.Warn("Failed to advertise validator role", "err", err)
+	}
+}
+
+// signingWallet returns the wallet signing for the given signer: the one kept
+// with the passphrase the signer was handed over with, if any, otherwise the
+// one holding its account.
+func (s *Ethereum) signingWallet(signer common.Address) (accounts.Wallet, error) {
+	s.lock.RLock()
+	if s.signerWallet != nil && s.signer == signer {
+		defer s.lock.RUnlock()
+		return s.signerWallet, nil
+	}
+	s.lock.RUnlock()
+
+	return s.accountManager.Find(accounts.Account{Address: signer})
+}
+
+// sealingWallet returns the wallet clique seals with for the given signer. A
+// passphrase is checked and kept by the returned wallet rather than unlocking
+// the account, and refused if the RPC APIs are exposed over HTTP unless
+// insecure unlocking is allowed.
+func (s *Ethereum) sealingWallet(signer common.Address, passphrase *string) (accounts.Wallet, error) {
+	account := accounts.Account{Address: signer}
+	wallet, err := s.accountManager.Find(account)
+	if err != nil {
+		return nil, fmt.Errorf("signer %s unavailable locally: %v", signer, err)
+	}
+	backends := s.accountManager.Backends(keystore.KeyStoreType)
+	if len(backends) == 0 || wallet.URL().Scheme != keystore.KeyStoreScheme {
+		if passphrase != nil {
+			return nil, fmt.Errorf("signer %s is not a keystore account, can't unlock with passphrase", signer)
+		}
+		return wallet, nil
+	}
+	// Keystore accounts need a passphrase or to be unlocked to seal, check
+	// before handing over
+	ks := backends[0].(*keystore.KeyStore)
+	if passphrase == nil {
+		if _, err := ks.SignHash(account, make([]byte, common.HashLength)); err != nil {
+			return nil, fmt.Errorf("signer %s unusable: %v", signer, err)
+		}
+		return wallet, nil
+	}
+	if s.APIBackend.ExtRPCEnabled() && !s.accountManager.Config().InsecureUnlockAllowed {
+		return nil, errSignerUnlockForbidden
+	}
+	if _, err := ks.SignHashWithPassphrase(account, *passphrase, make([]byte, common.HashLength)); err != nil {
+		return nil, fmt.Errorf("failed to unlock signer %s: %v", signer, err)
+	}
+	return &passphraseWallet{Wallet: wallet, passphrase: *passphrase}, nil
+}
+
+// SetSigner changes the account clique seals blocks with at runtime. Keystore
+// accounts are sealed with the given passphrase, if any, without unlocking them.
+// Accounts of hardware and external wallets are used as their wallet's session
+// allows. If the node is mining, the next block is sealed with the new signer.
+func (s *Ethereum) SetSigner(signer common.Address, passphrase *string) (*SignerStatus, error) {
+	cli := s.cliqueEngine()
+	if cli == nil {
+		return nil, errNoClique
+	}
+	wallet, err := s.sealingWallet(signer, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	previous, _ := s.Etherbase()
+	s.SetEtherbase(signer)
+
+	s.lock.Lock()
+	s.signer, s.signerWallet = signer, nil
+	if passphrase != nil {
+		s.signerWallet = wallet
+	}
+	s.lock.Unlock()
+
+	mining := s.IsMining()
+	if mining {
+		s.authorizeSigner(signer, wallet)
+	}
+	authorized, err := cli.IsSigner(s.blockchain, signer)
+	if err != nil {
+		return nil, err
+	}
+	if !authorized {
+		log.Warn("Clique signer not authorized at the current head", "signer", signer)
+	}
+	log.Info("Changed clique signer", "previous", previous, "signer", signer, "mining", mining)
+	return &SignerStatus{Signer: signer, Authorized: authorized, Mining: mining}, nil
+}
+
+// PrivateSignerAPI provides an API to hand the clique sealing over to another
+// account without restarting the node.
+type PrivateSignerAPI struct {
+	e *Ethereum
+}
+
+// SetSigner changes the account clique seals blocks with, sealing with the
+// passphrase if it's a keystore account and one is given.
+func (api *PrivateSignerAPI) SetSigner(signer common.Address, passphrase *string) (*SignerStatus, error) {
+	return api.e.SetSigner(signer, passphrase)
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// newTestSignerBackend creates a backend with a keystore holding a single locked
+// account, its RPC APIs exposed over HTTP or not.
+func newTestSignerBackend(t *testing.T, extRPC bool, insecureUnlock bool) (*Ethereum, *keystore.KeyStore, accounts.Account) {
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("secret")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	eth := &Ethereum{
+		accountManager: accounts.NewManager(&accounts.Config{InsecureUnlockAllowed: insecureUnlock}, ks),
+		APIBackend:     &EthAPIBackend{extRPCEnabled: extRPC},
+	}
+	t.Cleanup(func() { eth.accountManager.Close() })
+	return eth, ks, account
+}
+
+// Tests that a signer handed over with a passphrase seals with it while staying
+// locked in the keystore.
+func TestSealingWalletPassphrase(t *testing.T) {
+	eth, ks, account := newTestSignerBackend(t, false, false)
+
+	wrong := "wrong"
+	if _, err := eth.sealingWallet(account.Address, &wrong); err == nil {
+		t.Fatalf("wrong passphrase accepted")
+	}
+	passphrase := "secret"
+	wallet, err := eth.sealingWallet(account.Address, &passphrase)
+	if err != nil {
+		t.Fatalf("failed to get sealing wallet: %v", err)
+	}
+	if _, err := wallet.SignData(account, accounts.MimetypeClique, []byte{0x01}); err != nil {
+		t.Errorf("failed to seal with passphrase: %v", err)
+	}
+	if _, err := ks.SignHash(account, make([]byte, common.HashLength)); err != keystore.ErrLocked {
+		t.Errorf("signer unlocked in the keystore: %v", err)
+	}
+	// Without a passphrase, the account has to be unlocked
+	if _, err := eth.sealingWallet(account.Address, nil); err == nil {
+		t.Errorf("locked signer accepted without passphrase")
+	}
+	if err := ks.Unlock(account, "secret"); err != nil {
+		t.Fatalf("failed to unlock account: %v", err)
+	}
+	if _, err := eth.sealingWallet(account.Address, nil); err != nil {
+		t.Errorf("unlocked signer rejected: %v", err)
+	}
+}
+
+// Tests that signer passphrases are refused while the RPC APIs are exposed over
+// HTTP, unless insecure unlocking is allowed.
+func TestSealingWalletExternalRPC(t *testing.T) {
+	passphrase := "secret"
+
+	eth, _, account := newTestSignerBackend(t, true, false)
+	if _, err := eth.sealingWallet(account.Address, &passphrase); err != errSignerUnlockForbidden {
+		t.Errorf("passphrase accepted with HTTP access: %v", err)
+	}
+	eth, _, account = newTestSignerBackend(t, true, true)
+	if _, err := eth.sealingWallet(account.Address, &passphrase); err != nil {
+		t.Errorf("passphrase rejected with insecure unlock allowed: %v", err)
+	}
+}
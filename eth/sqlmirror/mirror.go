@@ -0,0 +1,417 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package sqlmirror mirrors the canonical chain's block headers, transaction
+// summaries and clique sealer attribution into an external SQL database.
+//
+// The mirror speaks database/sql, with the drivers of the supported databases
+// linked in: github.com/lib/pq for "postgres" and github.com/mattn/go-sqlite3
+// for "sqlite3".
+package sqlmirror
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+
+	// Drivers of the supported databases
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var headGauge = metrics.NewRegisteredGauge("sqlmirror/head", nil)
+
+// Config are the configuration parameters of the SQL mirror.
+type Config struct {
+	Driver        string        // Driver of the database: "postgres" or "sqlite3", empty disables the mirror
+	DSN           string        // Data source name of the database
+	StartBlock    uint64        // First block mirrored into an empty database
+	BatchSize     int           // Maximum number of blocks written per database transaction
+	RetryInterval time.Duration // Delay before retrying after a database error
+}
+
+// DefaultConfig contains the default settings for the SQL mirror.
+var DefaultConfig = Config{
+	BatchSize:     128,
+	RetryInterval: 10 * time.Second,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *Config) sanitize() Config {
+	conf := *config
+	if conf.BatchSize < 1 {
+		log.Warn("Sanitizing invalid SQL mirror batch size", "provided", conf.BatchSize, "updated", DefaultConfig.BatchSize)
+		conf.BatchSize = DefaultConfig.BatchSize
+	}
+	if conf.RetryInterval <= 0 {
+		log.Warn("Sanitizing invalid SQL mirror retry interval", "provided", conf.RetryInterval, "updated", DefaultConfig.RetryInterval)
+		conf.RetryInterval = DefaultConfig.RetryInterval
+	}
+	return conf
+}
+
+// Chain is the subset of the blockchain the mirror follows.
+type Chain interface {
+	Config() *params.ChainConfig
+	CurrentBlock() *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetCanonicalHash(number uint64) common.Hash
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Mirror follows the canonical chain and writes its metadata into a SQL
+// database, rewinding the mirrored blocks on reorgs.
+type Mirror struct {
+	config  Config
+	dialect *dialect
+	db      *sql.DB
+	chain   Chain
+	clique  *clique.Clique // Sealer recovery, nil if not running clique
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a mirror writing into the configured database. The connection is
+// only established when the mirror starts.
+func New(config Config, chain Chain, engine *clique.Clique) (*Mirror, error) {
+	conf := config.sanitize()
+	d, ok := dialects[conf.Driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SQL mirror driver %q", conf.Driver)
+	}
+	db, err := sql.Open(conf.Driver, conf.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQL mirror database (%s): %v", d.name, err)
+	}
+	if d.name == "sqlite3" {
+		// SQLite doesn't support concurrent writers
+		db.SetMaxOpenConns(1)
+	}
+	return &Mirror{
+		config:  conf,
+		dialect: d,
+		db:      db,
+		chain:   chain,
+		clique:  engine,
+		quit:    make(chan struct{}),
+	}, nil
+}
+
+// Start launches the mirroring loop.
+func (m *Mirror) Start() {
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Stop terminates the mirroring loop and closes the database.
+func (m *Mirror) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+	m.db.Close()
+}
+
+func (m *Mirror) loop() {
+	defer m.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := m.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	retry := time.NewTimer(0)
+	defer retry.Stop()
+
+	var (
+		migrated bool
+		failing  bool
+	)
+	for {
+		select {
+		case <-heads:
+			if failing {
+				continue
+			}
+		case <-retry.C:
+		case <-sub.Err():
+			return
+		case <-m.quit:
+			return
+		}
+		var err error
+		if !migrated {
+			if err = migrate(m.db, m.dialect); err == nil {
+				migrated = true
+			}
+		}
+		if err == nil {
+			err = m.sync()
+		}
+		if err != nil {
+			log.Warn("Failed to mirror chain to SQL", "driver", m.config.Driver, "err", err)
+			failing = true
+			retry.Reset(m.config.RetryInterval)
+		} else {
+			failing = false
+		}
+	}
+}
+
+// cursor returns the number and hash of the last mirrored block.
+func (m *Mirror) cursor() (uint64, common.Hash, bool, error) {
+	var (
+		number int64
+		hash   string
+	)
+	err := m.db.QueryRow(`SELECT number, hash FROM blocks ORDER BY number DESC LIMIT 1`).Scan(&number, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, common.Hash{}, false, nil
+	}
+	if err != nil {
+		return 0, common.Hash{}, false, err
+	}
+	return uint64(number), common.HexToHash(hash), true, nil
+}
+
+// sync rewinds the mirrored blocks no longer canonical and writes the blocks
+// missing up to the current head.
+func (m *Mirror) sync() error {
+	number, hash, ok, err := m.cursor()
+	if err != nil {
+		return err
+	}
+	for ok && m.chain.GetCanonicalHash(number) != hash {
+		if err := m.rewind(number); err != nil {
+			return err
+		}
+		if number, hash, ok, err = m.cursor(); err != nil {
+			return err
+		}
+	}
+	next := m.config.StartBlock
+	if ok {
+		next = number + 1
+	}
+	head := m.chain.CurrentBlock().NumberU64()
+	for next <= head {
+		select {
+		case <-m.quit:
+			return nil
+		default:
+		}
+		last := next + uint64(m.config.BatchSize) - 1
+		if last > head {
+			last = head
+		}
+		written, err := m.write(next, last, hash, ok)
+		if err != nil {
+			return err
+		}
+		if written == 0 {
+			return nil // Reorged meanwhile, the next head event sorts it out
+		}
+		next += written
+		hash, ok = m.chain.GetCanonicalHash(next-1), true
+		headGauge.Update(int64(next - 1))
+	}
+	return nil
+}
+
+// rewind deletes all mirrored data from the given block onwards.
+func (m *Mirror) rewind(from uint64) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, stmt := range []string{
+		`DELETE FROM transactions WHERE block_number >= ?`,
+		`DELETE FROM epochs WHERE block_number >= ?`,
+		`DELETE FROM blocks WHERE number >= ?`,
+	} {
+		if _, err := tx.Exec(m.dialect.rebind(stmt), int64(from)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Info("Rewound SQL mirror", "number", from)
+	return nil
+}
+
+// write mirrors a range of canonical blocks in a single transaction, stopping
+// early if the range no longer links up due to a reorg.
+func (m *Mirror) write(from, to uint64, parent common.Hash, linked bool) (uint64, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	var written uint64
+	for number := from; number <= to; number++ {
+		block := m.chain.GetBlockByNumber(number)
+		if block == nil || (linked && block.ParentHash() != parent) {
+			break
+		}
+		if err := m.writeBlock(tx, block); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		parent, linked = block.Hash(), true
+		written++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+// writeBlock inserts the rows of a single block.
+func (m *Mirror) writeBlock(tx *sql.Tx, block *types.Block) error {
+	receipts := m.chain.GetReceiptsByHash(block.Hash())
+	if len(receipts) != len(block.Transactions()) {
+		return fmt.Errorf("missing receipts of block #%d", block.NumberU64())
+	}
+	row, err := m.blockRow(block)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(m.dialect.rebind(`INSERT INTO blocks (number, hash, parent_hash, timestamp, coinbase, sealer, in_turn, difficulty, gas_limit, gas_used, base_fee, tx_count, extra) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`), row...); err != nil {
+		return err
+	}
+	rows, err := transactionRows(m.chain.Config(), block, receipts)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := tx.Exec(m.dialect.rebind(`INSERT INTO transactions (hash, block_number, tx_index, type, sender, recipient, contract_address, value, nonce, gas_limit, gas_used, gas_price, status, log_count) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`), row...); err != nil {
+			return err
+		}
+	}
+	if m.clique != nil {
+		if epoch, signers, ok := clique.EpochSigners(block.Header()); ok {
+			list := make([]string, len(signers))
+			for i, signer := range signers {
+				list[i] = hexAddress(signer)
+			}
+			if _, err := tx.Exec(m.dialect.rebind(`INSERT INTO epochs (block_number, epoch, signers) VALUES (?, ?, ?)`), int64(block.NumberU64()), int64(epoch), strings.Join(list, ",")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// blockRow assembles the values of a block's row, attributing it to the clique
+// sealer if running clique, or the coinbase otherwise.
+func (m *Mirror) blockRow(block *types.Block) ([]interface{}, error) {
+	header := block.Header()
+	sealer := header.Coinbase
+	var inturn interface{}
+	if m.clique != nil {
+		var err error
+		if sealer, err = m.clique.Author(header); err != nil {
+			return nil, err
+		}
+		inturn = clique.InTurn(header)
+	}
+	return []interface{}{
+		int64(header.Number.Uint64()),
+		header.Hash().Hex(),
+		header.ParentHash.Hex(),
+		int64(header.Time),
+		hexAddress(header.Coinbase),
+		hexAddress(sealer),
+		inturn,
+		header.Difficulty.String(),
+		int64(header.GasLimit),
+		int64(header.GasUsed),
+		bigString(header.BaseFee),
+		len(block.Transactions()),
+		header.Extra,
+	}, nil
+}
+
+// transactionRows assembles the summary rows of a block's transactions.
+func transactionRows(config *params.ChainConfig, block *types.Block, receipts types.Receipts) ([][]interface{}, error) {
+	var (
+		signer  = types.MakeSigner(config, block.Number())
+		baseFee = block.BaseFee()
+		rows    = make([][]interface{}, len(block.Transactions()))
+	)
+	for i, tx := range block.Transactions() {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		var recipient, contract interface{}
+		if to := tx.To(); to != nil {
+			recipient = hexAddress(*to)
+		} else {
+			contract = hexAddress(receipts[i].ContractAddress)
+		}
+		price := tx.GasPrice()
+		if baseFee != nil {
+			price = new(big.Int).Add(tx.EffectiveGasTipValue(baseFee), baseFee)
+		}
+		rows[i] = []interface{}{
+			tx.Hash().Hex(),
+			int64(block.NumberU64()),
+			i,
+			int(tx.Type()),
+			hexAddress(from),
+			recipient,
+			contract,
+			tx.Value().String(),
+			int64(tx.Nonce()),
+			int64(tx.Gas()),
+			int64(receipts[i].GasUsed),
+			price.String(),
+			int(receipts[i].Status),
+			len(receipts[i].Logs),
+		}
+	}
+	return rows, nil
+}
+
+// hexAddress formats an address as lowercase hex, so it can be compared without
+// caring about checksums.
+func hexAddress(addr common.Address) string {
+	return hexutil.Encode(addr[:])
+}
+
+// bigString formats an optional big number as a decimal string.
+func bigString(n *big.Int) interface{} {
+	if n == nil {
+		return nil
+	}
+	return n.String()
+}
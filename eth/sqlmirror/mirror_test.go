@@ -0,0 +1,221 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package sqlmirror
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that positional placeholders are only numbered for dialects requiring it.
+func TestRebind(t *testing.T) {
+	query := `INSERT INTO epochs (block_number, epoch, signers) VALUES (?, ?, ?)`
+	if have := dialects["sqlite3"].rebind(query); have != query {
+		t.Errorf("sqlite query rewritten: %s", have)
+	}
+	want := `INSERT INTO epochs (block_number, epoch, signers) VALUES ($1, $2, $3)`
+	if have := dialects["postgres"].rebind(query); have != want {
+		t.Errorf("postgres query mismatch: have %s, want %s", have, want)
+	}
+}
+
+// Tests that the migrations are strictly ordered and render for every dialect.
+func TestMigrations(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("migration %d: version mismatch: have %d, want %d", i, m.version, i+1)
+		}
+		for name, d := range dialects {
+			for _, stmt := range m.statements(d) {
+				if strings.Contains(stmt, "?") {
+					t.Errorf("migration %d (%s): placeholder in schema statement: %s", m.version, name, stmt)
+				}
+			}
+		}
+	}
+}
+
+// Tests that unknown drivers are rejected, and the supported ones are linked in.
+func TestNewDriver(t *testing.T) {
+	if _, err := New(Config{Driver: "mysql"}, nil, nil); err == nil {
+		t.Errorf("unsupported driver accepted")
+	}
+	for driver := range dialects {
+		m, err := New(Config{Driver: driver}, nil, nil)
+		if err != nil {
+			t.Errorf("supported driver %s rejected: %v", driver, err)
+			continue
+		}
+		m.db.Close()
+	}
+}
+
+// Tests that the mirror writes the canonical chain into an SQLite database and
+// rewinds the blocks reorged out.
+func TestMirrorSQLite(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		db     = rawdb.NewMemoryDatabase()
+		gspec  = &core.Genesis{
+			Config: config,
+			Alloc:  core.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	generate := func(n int, coinbase common.Address) []*types.Block {
+		blocks, _ := core.GenerateChain(config, genesis, ethash.NewFaker(), db, n, func(i int, b *core.BlockGen) {
+			b.SetCoinbase(coinbase)
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), coinbase, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		})
+		return blocks
+	}
+	chain, err := core.NewBlockChain(db, nil, config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	m, err := New(Config{Driver: "sqlite3", DSN: ":memory:", BatchSize: 2}, chain, nil)
+	if err != nil {
+		t.Fatalf("failed to create mirror: %v", err)
+	}
+	defer m.db.Close()
+	if err := migrate(m.db, m.dialect); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	check := func(blocks int) {
+		t.Helper()
+		if err := m.sync(); err != nil {
+			t.Fatalf("failed to mirror chain: %v", err)
+		}
+		var count, txs int
+		if err := m.db.QueryRow(`SELECT COUNT(*) FROM blocks`).Scan(&count); err != nil {
+			t.Fatalf("failed to count blocks: %v", err)
+		}
+		if err := m.db.QueryRow(`SELECT COUNT(*) FROM transactions`).Scan(&txs); err != nil {
+			t.Fatalf("failed to count transactions: %v", err)
+		}
+		if count != blocks+1 || txs != blocks {
+			t.Fatalf("mirrored rows mismatch: have %d blocks and %d transactions, want %d and %d", count, txs, blocks+1, blocks)
+		}
+		for number := uint64(0); number <= uint64(blocks); number++ {
+			var hash string
+			if err := m.db.QueryRow(`SELECT hash FROM blocks WHERE number = ?`, int64(number)).Scan(&hash); err != nil {
+				t.Fatalf("failed to query block #%d: %v", number, err)
+			}
+			if want := chain.GetCanonicalHash(number).Hex(); hash != want {
+				t.Fatalf("block #%d hash mismatch: have %s, want %s", number, hash, want)
+			}
+		}
+	}
+	if _, err := chain.InsertChain(generate(3, common.Address{0xaa})); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	check(3)
+
+	// Reorg onto a longer fork, the mirrored blocks must follow
+	if _, err := chain.InsertChain(generate(5, common.Address{0xbb})); err != nil {
+		t.Fatalf("failed to import fork: %v", err)
+	}
+	check(5)
+}
+
+// Tests that the block and transaction rows carry the expected values.
+func TestRows(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		db     = rawdb.NewMemoryDatabase()
+		gspec  = &core.Genesis{
+			Config: config,
+			Alloc:  core.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(config, genesis, ethash.NewFaker(), db, 1, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{0xc0})
+		transfer, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{0xaa}, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(transfer)
+		create, _ := types.SignTx(types.NewContractCreation(b.TxNonce(addr), nil, 100000, b.BaseFee(), []byte{0x00}), signer, key)
+		b.AddTx(create)
+	})
+	chain, err := core.NewBlockChain(db, nil, config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	block := blocks[0]
+
+	m := &Mirror{chain: chain}
+	row, err := m.blockRow(block)
+	if err != nil {
+		t.Fatalf("failed to build block row: %v", err)
+	}
+	if have, want := row[1], block.Hash().Hex(); have != want {
+		t.Errorf("block hash mismatch: have %v, want %v", have, want)
+	}
+	if have, want := row[5], hexAddress(common.Address{0xc0}); have != want {
+		t.Errorf("sealer not attributed to coinbase: have %v, want %v", have, want)
+	}
+	if row[6] != nil {
+		t.Errorf("in-turn flag set without clique: %v", row[6])
+	}
+	if have, want := row[10], block.BaseFee().String(); have != want {
+		t.Errorf("base fee mismatch: have %v, want %v", have, want)
+	}
+	rows, err := transactionRows(config, block, chain.GetReceiptsByHash(block.Hash()))
+	if err != nil {
+		t.Fatalf("failed to build transaction rows: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("transaction row count mismatch: have %d, want 2", len(rows))
+	}
+	if have, want := rows[0][4], hexAddress(addr); have != want {
+		t.Errorf("sender mismatch: have %v, want %v", have, want)
+	}
+	if have, want := rows[0][5], hexAddress(common.Address{0xaa}); have != want || rows[0][6] != nil {
+		t.Errorf("transfer recipient mismatch: have %v/%v, want %v", have, rows[0][6], want)
+	}
+	if have, want := rows[0][10], int64(params.TxGas); have != want {
+		t.Errorf("gas used mismatch: have %v, want %v", have, want)
+	}
+	if have, want := rows[0][11], block.BaseFee().String(); have != want {
+		t.Errorf("effective gas price mismatch: have %v, want %v", have, want)
+	}
+	if have, want := rows[1][6], hexAddress(crypto.CreateAddress(addr, 1)); rows[1][5] != nil || have != want {
+		t.Errorf("contract creation mismatch: recipient %v, contract %v, want %v", rows[1][5], have, want)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package sqlmirror
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// dialect captures the differences between the supported SQL databases.
+type dialect struct {
+	name        string
+	numbered    bool // Whether placeholders are numbered ($1) instead of positional (?)
+	bytesType   string
+	booleanType string
+}
+
+var dialects = map[string]*dialect{
+	"postgres": {name: "postgres", numbered: true, bytesType: "BYTEA", booleanType: "BOOLEAN"},
+	"sqlite3":  {name: "sqlite3", bytesType: "BLOB", booleanType: "INTEGER"},
+}
+
+// rebind rewrites the positional placeholders of a query into the dialect's
+// placeholder syntax.
+func (d *dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var (
+		b strings.Builder
+		n int
+	)
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// migration is a single schema change. Migrations are applied in order and
+// never modified once released, new changes go into new migrations.
+type migration struct {
+	version    int
+	statements func(d *dialect) []string
+}
+
+// migrations is the ordered list of schema changes. Hashes and addresses are
+// stored as 0x prefixed hex strings to keep ad-hoc queries readable, big
+// numbers as decimal strings as they may overflow 64 bits.
+var migrations = []migration{
+	{
+		version: 1,
+		statements: func(d *dialect) []string {
+			return []string{
+				`CREATE TABLE blocks (
+					number      BIGINT PRIMARY KEY,
+					hash        TEXT NOT NULL UNIQUE,
+					parent_hash TEXT NOT NULL,
+					timestamp   BIGINT NOT NULL,
+					coinbase    TEXT NOT NULL,
+					sealer      TEXT NOT NULL,
+					in_turn     ` + d.booleanType + `,
+					difficulty  TEXT NOT NULL,
+					gas_limit   BIGINT NOT NULL,
+					gas_used    BIGINT NOT NULL,
+					base_fee    TEXT,
+					tx_count    INTEGER NOT NULL,
+					extra       ` + d.bytesType + `
+				)`,
+				`CREATE INDEX blocks_sealer ON blocks (sealer)`,
+				`CREATE INDEX blocks_timestamp ON blocks (timestamp)`,
+				`CREATE TABLE transactions (
+					hash             TEXT PRIMARY KEY,
+					block_number     BIGINT NOT NULL,
+					tx_index         INTEGER NOT NULL,
+					type             INTEGER NOT NULL,
+					sender           TEXT NOT NULL,
+					recipient        TEXT,
+					contract_address TEXT,
+					value            TEXT NOT NULL,
+					nonce            BIGINT NOT NULL,
+					gas_limit        BIGINT NOT NULL,
+					gas_used         BIGINT NOT NULL,
+					gas_price        TEXT NOT NULL,
+					status           INTEGER NOT NULL,
+					log_count        INTEGER NOT NULL
+				)`,
+				`CREATE INDEX transactions_block ON transactions (block_number)`,
+				`CREATE INDEX transactions_sender ON transactions (sender)`,
+				`CREATE INDEX transactions_recipient ON transactions (recipient)`,
+				`CREATE TABLE epochs (
+					block_number BIGINT PRIMARY KEY,
+					epoch        BIGINT NOT NULL,
+					signers      TEXT NOT NULL
+				)`,
+			}
+		},
+	},
+}
+
+// migrate brings the database schema up to date, applying each pending
+// migration in its own transaction.
+func migrate(db *sql.DB, d *dialect) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at BIGINT NOT NULL)`); err != nil {
+		return err
+	}
+	var current sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if int64(m.version) <= current.Int64 {
+			continue
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, stmt := range m.statements(d) {
+			if _, err := tx.Exec(stmt); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migration %d failed: %v", m.version, err)
+			}
+		}
+		if _, err := tx.Exec(d.rebind(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`), m.version, time.Now().Unix()); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Info("Applied SQL mirror migration", "version", m.version)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxStateSessions is the maximum number of state sessions open at once,
+	// bounding the number of states pinned in memory by them.
+	maxStateSessions = 256
+
+	// stateSessionTTL is the time a state session is kept open without being
+	// used, each call reading through it renewing it.
+	stateSessionTTL = 5 * time.Minute
+)
+
+var (
+	// errUnknownStateSession is returned when reading through a state session
+	// which was released, expired or never opened.
+	errUnknownStateSession = errors.New("unknown or expired state session")
+
+	// errTooManyStateSessions is returned when opening a state session while
+	// the maximum number of them is open.
+	errTooManyStateSessions = errors.New("too many open state sessions")
+
+	stateSessionsGauge = metrics.NewRegisteredGauge("eth/statesessions", nil)
+)
+
+// stateSession is a block whose state is read by a series of calls.
+type stateSession struct {
+	hash    common.Hash
+	number  uint64
+	root    common.Hash
+	expires time.Time
+}
+
+// stateSessions tracks the open state sessions. Each session references the
+// state root of its block in the trie cache, so that its state isn't garbage
+// collected once the block leaves the window of recent states, until the session
+// is released or expires. At most maxStateSessions roots are pinned this way.
+type stateSessions struct {
+	chain    *core.BlockChain
+	sessions map[string]*stateSession
+	lock     sync.Mutex
+}
+
+// newStateSessions creates the state session tracker of a chain.
+func newStateSessions(chain *core.BlockChain) *stateSessions {
+	return &stateSessions{
+		chain:    chain,
+		sessions: make(map[string]*stateSession),
+	}
+}
+
+// open starts a session on the state of a block and returns its token.
+func (s *stateSessions) open(header *types.Header) (string, *stateSession, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.expire(time.Now())
+	if len(s.sessions) >= maxStateSessions {
+		return "", nil, errTooManyStateSessions
+	}
+	if _, err := s.chain.StateAt(header.Root); err != nil {
+		return "", nil, err
+	}
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", nil, err
+	}
+	token := hexutil.Encode(id[:])
+	session := &stateSession{
+		hash:    header.Hash(),
+		number:  header.Number.Uint64(),
+		root:    header.Root,
+		expires: time.Now().Add(stateSessionTTL),
+	}
+	s.chain.StateCache().TrieDB().Reference(session.root, common.Hash{})
+	s.sessions[token] = session
+	stateSessionsGauge.Update(int64(len(s.sessions)))
+
+	log.Debug("Opened state session", "number", session.number, "hash", session.hash)
+	return token, session, nil
+}
+
+// resolve returns the block of a session, renewing it.
+func (s *stateSessions) resolve(token string) (common.Hash, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	s.expire(now)
+	session, ok := s.sessions[token]
+	if !ok {
+		return common.Hash{}, errUnknownStateSession
+	}
+	session.expires = now.Add(stateSessionTTL)
+	return session.hash, nil
+}
+
+// release closes a session. It reports whether the session was open.
+func (s *stateSessions) release(token string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	session, ok := s.sessions[token]
+	if !ok {
+		return false
+	}
+	s.drop(token, session)
+	return true
+}
+
+// close releases all the open sessions.
+func (s *stateSessions) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for token, session := range s.sessions {
+		s.drop(token, session)
+	}
+}
+
+// expire releases the sessions unused for longer than their lifetime. The lock
+// must be held.
+func (s *stateSessions) expire(now time.Time) {
+	for token, session := range s.sessions {
+		if now.After(session.expires) {
+			log.Debug("Expired state session", "number", session.number, "hash", session.hash)
+			s.drop(token, session)
+		}
+	}
+}
+
+// drop removes a session and unpins its state. The lock must be held.
+func (s *stateSessions) drop(token string, session *stateSession) {
+	s.chain.StateCache().TrieDB().Dereference(session.root)
+	delete(s.sessions, token)
+	stateSessionsGauge.Update(int64(len(s.sessions)))
+}
+
+// resolveStateSession replaces a state session in a block selector with the
+// hash of its block.
+func (s *Ethereum) resolveStateSession(blockNrOrHash rpc.BlockNumberOrHash) (rpc.BlockNumberOrHash, error) {
+	token, ok := blockNrOrHash.Session()
+	if !ok {
+		return blockNrOrHash, nil
+	}
+	hash, err := s.stateSessions.resolve(token)
+	if err != nil {
+		return blockNrOrHash, err
+	}
+	return rpc.BlockNumberOrHashWithHash(hash, false), nil
+}
+
+// StateSession is a state session opened for a series of consistent reads.
+type StateSession struct {
+	Token       string         `json:"token"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	StateRoot   common.Hash    `json:"stateRoot"`
+	TTL         uint64         `json:"ttl"` // Seconds the session is kept open without being used
+}
+
+// CreateStateSession opens a session on the state of a block and returns a token
+// which calls can pass as their block parameter, {"stateSession": token}, to
+// read exactly that state until the session is released or left unused for
+// longer than its TTL. The state is kept in memory meanwhile, even once the
+// block is no longer recent.
+func (api *PublicEthereumAPI) CreateStateSession(blockNrOrHash rpc.BlockNumberOrHash) (*StateSession, error) {
+	if _, ok := blockNrOrHash.Session(); ok {
+		return nil, errors.New("cannot open a state session from another one")
+	}
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber {
+		return nil, errors.New("cannot open a state session on the pending block")
+	}
+	header, err := api.e.APIBackend.HeaderByNumberOrHash(context.Background(), blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("header not found")
+	}
+	token, session, err := api.e.stateSessions.open(header)
+	if err != nil {
+		return nil, err
+	}
+	return &StateSession{
+		Token:       token,
+		BlockHash:   session.hash,
+		BlockNumber: hexutil.Uint64(session.number),
+		StateRoot:   session.root,
+		TTL:         uint64(stateSessionTTL / time.Second),
+	}, nil
+}
+
+// ReleaseStateSession closes a state session, reporting whether it was open.
+func (api *PublicEthereumAPI) ReleaseStateSession(token string) bool {
+	return api.e.stateSessions.release(token)
+}
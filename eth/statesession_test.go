@@ -0,0 +1,116 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that state sessions resolve to their block until released or expired.
+func TestStateSessions(t *testing.T) {
+	handler := newTestHandlerWithBlocks(int(core.TriesInMemory) + 8)
+	defer handler.close()
+
+	sessions := newStateSessions(handler.chain)
+	head := handler.chain.CurrentHeader()
+
+	token, session, err := sessions.open(head)
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	if hash, err := sessions.resolve(token); err != nil || hash != head.Hash() {
+		t.Errorf("session resolved to %x (%v), want %x", hash, err, head.Hash())
+	}
+	if _, err := sessions.resolve("0xdeadbeef"); err != errUnknownStateSession {
+		t.Errorf("unknown session resolved: %v", err)
+	}
+	if !sessions.release(token) {
+		t.Errorf("open session not released")
+	}
+	if sessions.release(token) {
+		t.Errorf("released session released again")
+	}
+	if _, err := sessions.resolve(token); err != errUnknownStateSession {
+		t.Errorf("released session resolved: %v", err)
+	}
+	// Unused sessions expire
+	token, session, err = sessions.open(head)
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	session.expires = time.Now().Add(-time.Second)
+	if _, err := sessions.resolve(token); err != errUnknownStateSession {
+		t.Errorf("expired session resolved: %v", err)
+	}
+	if len(sessions.sessions) != 0 {
+		t.Errorf("expired session kept: %d open", len(sessions.sessions))
+	}
+}
+
+// Tests that the state of a session is kept past the window of recent states
+// until the session is released.
+func TestStateSessionPinning(t *testing.T) {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		gspec = &core.Genesis{Config: params.TestChainConfig}
+		genDb = rawdb.NewMemoryDatabase()
+	)
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// The blocks are generated apart so that their states are only in memory
+	blocks, _ := core.GenerateChain(gspec.Config, gspec.MustCommit(genDb), ethash.NewFaker(), genDb, int(core.TriesInMemory)+2, nil)
+	if _, err := chain.InsertChain(blocks[:2]); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	sessions := newStateSessions(chain)
+	defer sessions.close()
+
+	pinned, unpinned := blocks[1].Header(), blocks[0].Header()
+	token, _, err := sessions.open(pinned)
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks[2 : 2+core.TriesInMemory]); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	if _, err := chain.StateAt(unpinned.Root); err == nil {
+		t.Fatalf("state left the recent window without a session retained")
+	}
+	if _, err := chain.StateAt(pinned.Root); err != nil {
+		t.Fatalf("state of open session garbage collected: %v", err)
+	}
+	if hash, err := sessions.resolve(token); err != nil || hash != pinned.Hash() {
+		t.Fatalf("session past the recent window resolved to %x (%v), want %x", hash, err, pinned.Hash())
+	}
+	// Once released, the state gets garbage collected like any other
+	sessions.release(token)
+	if _, err := chain.StateAt(pinned.Root); err == nil {
+		t.Fatalf("state of released session retained")
+	}
+}
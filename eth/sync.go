@@ -54,9 +54,15 @@ func (h *handler) syncTransactions(p *eth.Peer) {
 	// The eth/65 protocol introduces proper transaction announcements, so instead
 	// of dripping transactions across multiple peers, just send the entire list as
 	// an announcement and let the remote side decide what they need (likely nothing).
-	hashes := make([]common.Hash, len(txs))
-	for i, tx := range txs {
-		hashes[i] = tx.Hash()
+	// Conditional transactions stay local and are not announced.
+	hashes := make([]common.Hash, 0, len(txs))
+	for _, tx := range txs {
+		if tx.Conditional() == nil {
+			hashes = append(hashes, tx.Hash())
+		}
+	}
+	if len(hashes) == 0 {
+		return
 	}
 	p.AsyncSendPooledTransactionHashes(hashes)
 }
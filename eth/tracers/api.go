@@ -84,6 +84,7 @@ type Backend interface {
 // API is the collection of tracing APIs exposed over the private debugging endpoint.
 type API struct {
 	backend Backend
+	cache   *TraceCache // Cache of transaction traces, nil if disabled
 }
 
 // NewAPI creates a new API definition for the tracing methods of the Ethereum service.
@@ -783,6 +784,11 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 	if blockNumber == 0 {
 		return nil, errors.New("genesis is not traceable")
 	}
+	if api.cache != nil {
+		if result, ok := api.cache.Get(blockHash, hash, config); ok {
+			return result, nil
+		}
+	}
 	reexec := defaultTraceReexec
 	if config != nil && config.Reexec != nil {
 		reexec = *config.Reexec
@@ -800,7 +806,20 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 		TxIndex:   int(index),
 		TxHash:    hash,
 	}
-	return api.traceTx(ctx, msg, txctx, vmctx, statedb, config)
+	result, err := api.traceTx(ctx, msg, txctx, vmctx, statedb, config)
+	if err == nil && api.cache != nil {
+		api.cache.Put(blockHash, hash, config, result)
+	}
+	return result, err
+}
+
+// TraceCacheStats returns the usage statistics of the transaction trace cache.
+func (api *API) TraceCacheStats() (*TraceCacheStats, error) {
+	if api.cache == nil {
+		return nil, errors.New("trace cache disabled")
+	}
+	stats := api.cache.Stats()
+	return &stats, nil
 }
 
 // TraceCall lets you trace a given eth_call. It collects the structured logs
@@ -905,12 +924,18 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 
 // APIs return the collection of RPC services the tracer package offers.
 func APIs(backend Backend) []rpc.API {
+	return APIsWithCache(backend, nil)
+}
+
+// APIsWithCache returns the collection of RPC services the tracer package
+// offers, serving repeated transaction traces from the given cache.
+func APIsWithCache(backend Backend, cache *TraceCache) []rpc.API {
 	// Append all the local APIs and return
 	return []rpc.API{
 		{
 			Namespace: "debug",
 			Version:   "1.0",
-			Service:   NewAPI(backend),
+			Service:   &API{backend: backend, cache: cache},
 			Public:    false,
 		},
 	}
@@ -0,0 +1,270 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// traceCacheKeyLength is the length of a cache key: the hash of the block
+// containing the transaction, the transaction hash and the tracer config hash.
+// Keying by block first allows dropping all traces of a reorged block at once.
+const traceCacheKeyLength = 3 * common.HashLength
+
+// chainSideSubscriber is the part of the backend announcing blocks which got
+// reorged out of the canonical chain.
+type chainSideSubscriber interface {
+	SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription
+}
+
+// traceCacheEntry is the in-memory bookkeeping of a cached trace.
+type traceCacheEntry struct {
+	key  string
+	size uint64
+}
+
+// TraceCacheStats are the usage statistics of the trace cache.
+type TraceCacheStats struct {
+	Entries       int    `json:"entries"`
+	Size          uint64 `json:"size"`     // Bytes of traces stored
+	Capacity      uint64 `json:"capacity"` // Maximum bytes of traces stored
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`     // Traces dropped to make room for new ones
+	Invalidations uint64 `json:"invalidations"` // Traces dropped as their block got reorged out
+}
+
+// TraceCache is a size bounded least recently used cache of transaction traces,
+// persisted to disk so it survives restarts. Traces are stored per tracer config
+// and are dropped when their block leaves the canonical chain.
+type TraceCache struct {
+	db       ethdb.KeyValueStore
+	chain    chainSideSubscriber
+	capacity uint64
+
+	entries *list.List               // Entries from most to least recently used
+	index   map[string]*list.Element // Entries by key
+	size    uint64
+	stats   TraceCacheStats
+	lock    sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewTraceCache creates a trace cache storing up to capacity bytes of traces in
+// the given database, loading the traces persisted by previous runs.
+func NewTraceCache(db ethdb.KeyValueStore, chain chainSideSubscriber, capacity uint64) *TraceCache {
+	c := &TraceCache{
+		db:       db,
+		chain:    chain,
+		capacity: capacity,
+		entries:  list.New(),
+		index:    make(map[string]*list.Element),
+		quit:     make(chan struct{}),
+	}
+	it := db.NewIterator(nil, nil)
+	for it.Next() {
+		if len(it.Key()) != traceCacheKeyLength {
+			continue
+		}
+		entry := &traceCacheEntry{key: string(it.Key()), size: uint64(len(it.Value()))}
+		c.index[entry.key] = c.entries.PushBack(entry)
+		c.size += entry.size
+	}
+	it.Release()
+	c.evict()
+
+	log.Info("Loaded transaction trace cache", "traces", c.entries.Len(), "size", common.StorageSize(c.size), "capacity", common.StorageSize(capacity))
+	return c
+}
+
+// Start implements node.Lifecycle, invalidating the traces of reorged blocks.
+func (c *TraceCache) Start() error {
+	c.wg.Add(1)
+	go c.loop()
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the invalidation loop.
+func (c *TraceCache) Stop() error {
+	close(c.quit)
+	c.wg.Wait()
+	return nil
+}
+
+func (c *TraceCache) loop() {
+	defer c.wg.Done()
+
+	sides := make(chan core.ChainSideEvent, 64)
+	sub := c.chain.SubscribeChainSideEvent(sides)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-sides:
+			c.Invalidate(ev.Block.Hash())
+		case <-sub.Err():
+			return
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// traceCacheKey derives the key a trace is stored under.
+func traceCacheKey(block, tx common.Hash, config *TraceConfig) string {
+	var spec struct {
+		Config *logger.Config
+		Tracer *string
+	}
+	if config != nil {
+		spec.Config, spec.Tracer = config.Config, config.Tracer
+	}
+	blob, _ := json.Marshal(spec)
+
+	key := make([]byte, 0, traceCacheKeyLength)
+	key = append(key, block[:]...)
+	key = append(key, tx[:]...)
+	key = append(key, crypto.Keccak256(blob)...)
+	return string(key)
+}
+
+// Get retrieves the trace of a transaction in the given block with the given
+// tracer config.
+func (c *TraceCache) Get(block, tx common.Hash, config *TraceConfig) (json.RawMessage, bool) {
+	key := traceCacheKey(block, tx, config)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.index[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	blob, err := c.db.Get([]byte(key))
+	if err != nil {
+		log.Warn("Cached transaction trace missing", "tx", tx, "err", err)
+		c.remove(elem)
+		c.stats.Misses++
+		return nil, false
+	}
+	c.entries.MoveToFront(elem)
+	c.stats.Hits++
+	return blob, true
+}
+
+// Put stores the trace of a transaction in the given block with the given
+// tracer config, evicting the least recently used traces if over capacity.
+func (c *TraceCache) Put(block, tx common.Hash, config *TraceConfig, result interface{}) {
+	blob, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	// Don't let a single huge trace flush the whole cache
+	if uint64(len(blob)) > c.capacity/2 {
+		return
+	}
+	key := traceCacheKey(block, tx, config)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.index[key]; ok {
+		c.remove(elem)
+	}
+	if err := c.db.Put([]byte(key), blob); err != nil {
+		log.Warn("Failed to cache transaction trace", "tx", tx, "err", err)
+		return
+	}
+	entry := &traceCacheEntry{key: key, size: uint64(len(blob))}
+	c.index[key] = c.entries.PushFront(entry)
+	c.size += entry.size
+	c.evict()
+}
+
+// Invalidate drops all traces of transactions in the given block, returning the
+// number of traces dropped.
+func (c *TraceCache) Invalidate(block common.Hash) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var keys []string
+	it := c.db.NewIterator(block[:], nil)
+	for it.Next() {
+		keys = append(keys, string(it.Key()))
+	}
+	it.Release()
+
+	var dropped int
+	for _, key := range keys {
+		if elem, ok := c.index[key]; ok {
+			c.remove(elem)
+			dropped++
+		}
+	}
+	c.stats.Invalidations += uint64(dropped)
+	return dropped
+}
+
+// Stats returns the usage statistics of the cache.
+func (c *TraceCache) Stats() TraceCacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.entries.Len()
+	stats.Size = c.size
+	stats.Capacity = c.capacity
+	return stats
+}
+
+// evict drops the least recently used traces until the cache fits into its
+// capacity. The caller must hold the lock.
+func (c *TraceCache) evict() {
+	for c.size > c.capacity {
+		elem := c.entries.Back()
+		if elem == nil {
+			return
+		}
+		c.remove(elem)
+		c.stats.Evictions++
+	}
+}
+
+// remove drops a trace from the cache and the disk. The caller must hold the
+// lock.
+func (c *TraceCache) remove(elem *list.Element) {
+	entry := c.entries.Remove(elem).(*traceCacheEntry)
+	delete(c.index, entry.key)
+	c.size -= entry.size
+
+	if err := c.db.Delete([]byte(entry.key)); err != nil {
+		log.Warn("Failed to delete cached transaction trace", "err", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+type testSideChain struct{ feed event.Feed }
+
+func (c *testSideChain) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+// Tests that the trace cache serves traces per tracer config, evicts the least
+// recently used ones, invalidates reorged blocks and survives restarts.
+func TestTraceCache(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		chain  = new(testSideChain)
+		cache  = NewTraceCache(db, chain, 100)
+		block1 = common.HexToHash("0x01")
+		block2 = common.HexToHash("0x02")
+		tracer = "callTracer"
+		native = &TraceConfig{Tracer: &tracer}
+	)
+	trace := strings.Repeat("a", 18) // 20 bytes once JSON encoded
+
+	cache.Put(block1, common.HexToHash("0xa1"), nil, trace)
+	cache.Put(block1, common.HexToHash("0xa1"), native, trace)
+	cache.Put(block2, common.HexToHash("0xb1"), nil, trace)
+
+	if _, ok := cache.Get(block1, common.HexToHash("0xa1"), nil); !ok {
+		t.Fatalf("struct logger trace missing")
+	}
+	if blob, ok := cache.Get(block1, common.HexToHash("0xa1"), native); !ok || string(blob) != `"`+trace+`"` {
+		t.Fatalf("native tracer trace mismatch: have %s, want %q", blob, trace)
+	}
+	if _, ok := cache.Get(block2, common.HexToHash("0xa1"), nil); ok {
+		t.Fatalf("trace served for wrong block")
+	}
+	// Fill up the cache, the trace in block2 is least recently used
+	cache.Put(block2, common.HexToHash("0xb2"), nil, trace)
+	cache.Put(block2, common.HexToHash("0xb3"), nil, trace)
+	cache.Put(block2, common.HexToHash("0xb4"), nil, trace)
+	if _, ok := cache.Get(block2, common.HexToHash("0xb1"), nil); ok {
+		t.Fatalf("least recently used trace not evicted")
+	}
+	if stats := cache.Stats(); stats.Entries != 5 || stats.Size != 100 || stats.Evictions != 1 {
+		t.Fatalf("stats mismatch: have %+v", stats)
+	}
+	// Reload the cache from disk and drop a reorged block
+	cache = NewTraceCache(db, chain, 100)
+	if stats := cache.Stats(); stats.Entries != 5 {
+		t.Fatalf("reloaded entries mismatch: have %d, want %d", stats.Entries, 5)
+	}
+	if dropped := cache.Invalidate(block1); dropped != 2 {
+		t.Fatalf("invalidated traces mismatch: have %d, want %d", dropped, 2)
+	}
+	if _, ok := cache.Get(block1, common.HexToHash("0xa1"), native); ok {
+		t.Fatalf("trace of reorged block served")
+	}
+	if _, ok := cache.Get(block2, common.HexToHash("0xb4"), nil); !ok {
+		t.Fatalf("trace of canonical block dropped")
+	}
+}
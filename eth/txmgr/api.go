@@ -0,0 +1,73 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txmgr
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PrivateTxManagerAPI exposes the transaction manager over the txmgr namespace.
+type PrivateTxManagerAPI struct {
+	m *Manager
+}
+
+// NewPrivateTxManagerAPI creates a new API definition for the transaction manager.
+func NewPrivateTxManagerAPI(m *Manager) *PrivateTxManagerAPI {
+	return &PrivateTxManagerAPI{m: m}
+}
+
+// Transactions returns all the transactions currently being tracked.
+func (api *PrivateTxManagerAPI) Transactions() []*TrackedTx {
+	return api.m.Tracked()
+}
+
+// Transaction returns the tracking status of a single transaction.
+func (api *PrivateTxManagerAPI) Transaction(hash common.Hash) (*TrackedTx, error) {
+	if tx := api.m.Get(hash); tx != nil {
+		return tx, nil
+	}
+	return nil, errUnknownTx
+}
+
+// Untrack stops tracking the given transaction.
+func (api *PrivateTxManagerAPI) Untrack(hash common.Hash) bool {
+	return api.m.Untrack(hash)
+}
+
+// Rebroadcast immediately reinjects and re-announces a tracked transaction.
+func (api *PrivateTxManagerAPI) Rebroadcast(hash common.Hash) (common.Hash, error) {
+	return api.m.Resubmit(hash, PolicyRebroadcast)
+}
+
+// Bump immediately replaces a tracked transaction with a higher fee version,
+// returning the hash of the replacement.
+func (api *PrivateTxManagerAPI) Bump(hash common.Hash) (common.Hash, error) {
+	return api.m.Resubmit(hash, PolicyBump)
+}
+
+// Policy returns the active resubmission policy.
+func (api *PrivateTxManagerAPI) Policy() Policy {
+	return api.m.Policy()
+}
+
+// SetPolicy changes the resubmission policy applied to stuck transactions.
+func (api *PrivateTxManagerAPI) SetPolicy(policy Policy) (bool, error) {
+	if err := api.m.SetPolicy(policy); err != nil {
+		return false, err
+	}
+	return true, nil
+}
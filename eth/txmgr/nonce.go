@@ -0,0 +1,273 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txmgr
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	maxNonceRange     = 1024 // Maximum number of nonces reserved in a single lease
+	maxLeasesPerOwner = 256  // Maximum number of concurrent leases per address
+	maxLeases         = 4096 // Maximum number of concurrent leases across all addresses
+
+	// DefaultNonceLease is the time a reserved nonce range is held for its
+	// requester before the nonces are handed out again.
+	DefaultNonceLease = time.Minute
+)
+
+var (
+	errInvalidRange = fmt.Errorf("nonce range must be between 1 and %d", maxNonceRange)
+	errTooManyLease = fmt.Errorf("too many outstanding nonce leases (max %d)", maxLeasesPerOwner)
+	errLeasesFull   = fmt.Errorf("too many outstanding nonce leases across all addresses (max %d)", maxLeases)
+	errUnknownLease = errors.New("unknown nonce lease")
+)
+
+// NoncePool is the subset of the transaction pool the allocator needs to know
+// which nonces have already been consumed.
+type NoncePool interface {
+	Nonce(addr common.Address) uint64
+}
+
+// NonceLease is a contiguous range of nonces reserved for a single requester.
+// The lease is identified by a random token, only known to its requester.
+type NonceLease struct {
+	ID      string         `json:"id"`
+	Address common.Address `json:"address"`
+	Start   hexutil.Uint64 `json:"start"`
+	Count   hexutil.Uint64 `json:"count"`
+	Expires time.Time      `json:"expires"`
+}
+
+func (l *NonceLease) end() uint64 { return uint64(l.Start) + uint64(l.Count) }
+
+// NonceAllocator hands out non-overlapping nonce ranges to multiple senders
+// sharing one account. Leases expire after a timeout, after which their unused
+// nonces are reissued to fill the gaps they would otherwise leave behind.
+type NonceAllocator struct {
+	pool    NoncePool
+	timeout time.Duration
+	now     func() time.Time // Overridable for tests
+
+	leases map[common.Address][]*NonceLease // Active leases per address, sorted by start
+	total  int                              // Number of active leases across all addresses
+	lock   sync.Mutex
+}
+
+// NewNonceAllocator creates a nonce allocator on top of the given pool.
+func NewNonceAllocator(pool NoncePool, timeout time.Duration) *NonceAllocator {
+	if timeout <= 0 {
+		log.Warn("Sanitizing invalid nonce lease timeout", "provided", timeout, "updated", DefaultNonceLease)
+		timeout = DefaultNonceLease
+	}
+	return &NonceAllocator{
+		pool:    pool,
+		timeout: timeout,
+		now:     time.Now,
+		leases:  make(map[common.Address][]*NonceLease),
+	}
+}
+
+// Reserve leases count consecutive nonces for addr. The lowest range not
+// covered by the pool or any live lease is returned, so nonces abandoned by
+// expired or released leases are reused before new ones are allocated.
+func (a *NonceAllocator) Reserve(addr common.Address, count uint64) (*NonceLease, error) {
+	if count == 0 || count > maxNonceRange {
+		return nil, errInvalidRange
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	leases := a.prune(addr)
+	if len(leases) >= maxLeasesPerOwner {
+		return nil, errTooManyLease
+	}
+	if a.total >= maxLeases {
+		a.pruneAll()
+		if leases = a.leases[addr]; a.total >= maxLeases {
+			return nil, errLeasesFull
+		}
+	}
+	id, err := newLeaseID()
+	if err != nil {
+		return nil, err
+	}
+	// Find the first gap large enough to hold the requested range
+	start := a.pool.Nonce(addr)
+	pos := 0
+	for ; pos < len(leases); pos++ {
+		if uint64(leases[pos].Start) >= start+count {
+			break
+		}
+		if end := leases[pos].end(); end > start {
+			start = end
+		}
+	}
+	lease := &NonceLease{
+		ID:      id,
+		Address: addr,
+		Start:   hexutil.Uint64(start),
+		Count:   hexutil.Uint64(count),
+		Expires: a.now().Add(a.timeout),
+	}
+	leases = append(leases, nil)
+	copy(leases[pos+1:], leases[pos:])
+	leases[pos] = lease
+	a.store(addr, leases)
+
+	cpy := *lease
+	return &cpy, nil
+}
+
+// Renew extends the expiration of a live lease by another timeout period.
+func (a *NonceAllocator) Renew(addr common.Address, id string) (*NonceLease, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for _, lease := range a.prune(addr) {
+		if lease.ID == id {
+			lease.Expires = a.now().Add(a.timeout)
+			cpy := *lease
+			return &cpy, nil
+		}
+	}
+	return nil, errUnknownLease
+}
+
+// Release returns a lease to the allocator before it expires, making all its
+// unused nonces available again.
+func (a *NonceAllocator) Release(addr common.Address, id string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	leases := a.prune(addr)
+	for i, lease := range leases {
+		if lease.ID == id {
+			a.store(addr, append(leases[:i], leases[i+1:]...))
+			return nil
+		}
+	}
+	return errUnknownLease
+}
+
+// Leases returns the live leases held on addr.
+func (a *NonceAllocator) Leases(addr common.Address) []*NonceLease {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	leases := a.prune(addr)
+	res := make([]*NonceLease, 0, len(leases))
+	for _, lease := range leases {
+		cpy := *lease
+		res = append(res, &cpy)
+	}
+	return res
+}
+
+// prune drops the expired and fully consumed leases of addr, returning the
+// remaining ones. The caller must hold the allocator lock.
+func (a *NonceAllocator) prune(addr common.Address) []*NonceLease {
+	var (
+		now    = a.now()
+		used   = a.pool.Nonce(addr)
+		leases = a.leases[addr][:0]
+	)
+	for _, lease := range a.leases[addr] {
+		if lease.end() <= used {
+			continue
+		}
+		if now.After(lease.Expires) {
+			log.Debug("Nonce lease expired", "address", addr, "id", lease.ID, "start", lease.Start, "count", lease.Count)
+			continue
+		}
+		leases = append(leases, lease)
+	}
+	sort.Slice(leases, func(i, j int) bool { return leases[i].Start < leases[j].Start })
+	a.store(addr, leases)
+	return leases
+}
+
+// pruneAll drops the expired and fully consumed leases of all addresses. The
+// caller must hold the allocator lock.
+func (a *NonceAllocator) pruneAll() {
+	for addr := range a.leases {
+		a.prune(addr)
+	}
+}
+
+// store replaces the leases of addr, keeping the total count up to date. The
+// caller must hold the allocator lock.
+func (a *NonceAllocator) store(addr common.Address, leases []*NonceLease) {
+	a.total += len(leases) - len(a.leases[addr])
+	if len(leases) == 0 {
+		delete(a.leases, addr)
+		return
+	}
+	a.leases[addr] = leases
+}
+
+// newLeaseID generates a random lease token, so that leases can't be renewed or
+// released by anyone but their requester.
+func newLeaseID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	return hexutil.Encode(id[:]), nil
+}
+
+// PrivateNonceAPI exposes the nonce allocator in the txmgr namespace.
+type PrivateNonceAPI struct {
+	a *NonceAllocator
+}
+
+// NewPrivateNonceAPI creates a new API definition for the nonce allocator.
+func NewPrivateNonceAPI(a *NonceAllocator) *PrivateNonceAPI {
+	return &PrivateNonceAPI{a: a}
+}
+
+// ReserveNonceRange leases count consecutive nonces of address to the caller.
+func (api *PrivateNonceAPI) ReserveNonceRange(address common.Address, count hexutil.Uint64) (*NonceLease, error) {
+	return api.a.Reserve(address, uint64(count))
+}
+
+// RenewNonceRange extends the lifetime of a previously reserved nonce range.
+func (api *PrivateNonceAPI) RenewNonceRange(address common.Address, id string) (*NonceLease, error) {
+	return api.a.Renew(address, id)
+}
+
+// ReleaseNonceRange returns the unused nonces of a lease to the allocator.
+func (api *PrivateNonceAPI) ReleaseNonceRange(address common.Address, id string) (bool, error) {
+	if err := api.a.Release(address, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// NonceLeases returns the live nonce leases held on address.
+func (api *PrivateNonceAPI) NonceLeases(address common.Address) []*NonceLease {
+	return api.a.Leases(address)
+}
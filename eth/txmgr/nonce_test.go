@@ -0,0 +1,118 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txmgr
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type testNoncePool map[common.Address]uint64
+
+func (p testNoncePool) Nonce(addr common.Address) uint64 { return p[addr] }
+
+func TestNonceAllocator(t *testing.T) {
+	var (
+		addr  = common.Address{0x01}
+		pool  = testNoncePool{addr: 5}
+		now   = time.Unix(0, 0)
+		alloc = NewNonceAllocator(pool, time.Minute)
+	)
+	alloc.now = func() time.Time { return now }
+
+	reserve := func(count uint64, want uint64) *NonceLease {
+		t.Helper()
+		lease, err := alloc.Reserve(addr, count)
+		if err != nil {
+			t.Fatalf("failed to reserve %d nonces: %v", count, err)
+		}
+		if uint64(lease.Start) != want {
+			t.Fatalf("lease start mismatch: have %d, want %d", lease.Start, want)
+		}
+		return lease
+	}
+	// Consecutive reservations must not overlap and start at the pool nonce
+	first := reserve(3, 5)
+	second := reserve(2, 8)
+
+	// Releasing the first lease leaves a gap that must be refilled first
+	if err := alloc.Release(addr, first.ID); err != nil {
+		t.Fatalf("failed to release lease: %v", err)
+	}
+	reserve(2, 5)
+	reserve(2, 10) // Gap at 7 is too small for 2 nonces
+
+	// Expired leases heal their gap, consumed ones are dropped
+	now = now.Add(30 * time.Second)
+	if _, err := alloc.Renew(addr, second.ID); err != nil {
+		t.Fatalf("failed to renew lease: %v", err)
+	}
+	now = now.Add(45 * time.Second)
+	if leases := alloc.Leases(addr); len(leases) != 1 || leases[0].ID != second.ID {
+		t.Fatalf("live leases mismatch: %v", leases)
+	}
+	reserve(1, 5)
+	pool[addr] = 10
+	if leases := alloc.Leases(addr); len(leases) != 0 {
+		t.Fatalf("consumed leases retained: %v", leases)
+	}
+	reserve(1, 10)
+
+	if _, err := alloc.Reserve(addr, 0); err != errInvalidRange {
+		t.Fatalf("empty range error mismatch: have %v, want %v", err, errInvalidRange)
+	}
+	if first.ID == second.ID || len(first.ID) != 34 {
+		t.Fatalf("lease tokens not random: %s, %s", first.ID, second.ID)
+	}
+	if err := alloc.Release(addr, "0x01"); err != errUnknownLease {
+		t.Fatalf("unknown lease error mismatch: have %v, want %v", err, errUnknownLease)
+	}
+	if _, err := alloc.Renew(common.Address{0x02}, second.ID); err != errUnknownLease {
+		t.Fatalf("foreign lease renewed: %v", err)
+	}
+}
+
+// Tests that the number of leases across all addresses is capped, and that
+// expired leases make room for new ones.
+func TestNonceAllocatorCap(t *testing.T) {
+	var (
+		now   = time.Unix(0, 0)
+		alloc = NewNonceAllocator(testNoncePool{}, time.Minute)
+	)
+	alloc.now = func() time.Time { return now }
+
+	for i := 0; i < maxLeases; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i)))
+		if _, err := alloc.Reserve(addr, 1); err != nil {
+			t.Fatalf("failed to reserve lease %d: %v", i, err)
+		}
+	}
+	addr := common.Address{0xff}
+	if _, err := alloc.Reserve(addr, 1); err != errLeasesFull {
+		t.Fatalf("lease beyond the cap error mismatch: have %v, want %v", err, errLeasesFull)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := alloc.Reserve(addr, 1); err != nil {
+		t.Fatalf("failed to reserve lease after expiry: %v", err)
+	}
+	if len(alloc.leases) != 1 || alloc.total != 1 {
+		t.Fatalf("expired leases retained: %d addresses, %d leases", len(alloc.leases), alloc.total)
+	}
+}
@@ -0,0 +1,488 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package txmgr tracks transactions submitted through the local node and
+// rebroadcasts or fee-bumps them when they get stuck in the pool.
+package txmgr
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Policy selects what the manager does with a transaction once it is stuck.
+type Policy string
+
+const (
+	PolicyNone        Policy = "none"        // Only track, never act
+	PolicyRebroadcast Policy = "rebroadcast" // Reinject and re-announce the same transaction
+	PolicyBump        Policy = "bump"        // Re-sign the transaction with a higher fee
+)
+
+var (
+	errUnknownTx     = errors.New("transaction not tracked")
+	errUnknownPolicy = errors.New("unknown resubmission policy")
+	errMaxBumps      = errors.New("maximum number of fee bumps reached")
+)
+
+var (
+	trackedGauge      = metrics.NewRegisteredGauge("txmgr/tracked", nil)
+	rebroadcastMeter  = metrics.NewRegisteredMeter("txmgr/rebroadcast", nil)
+	bumpMeter         = metrics.NewRegisteredMeter("txmgr/bump", nil)
+	includedMeter     = metrics.NewRegisteredMeter("txmgr/included", nil)
+	replacedMeter     = metrics.NewRegisteredMeter("txmgr/replaced", nil)
+	resubmitFailMeter = metrics.NewRegisteredMeter("txmgr/failed", nil)
+)
+
+// Config are the configuration parameters of the transaction manager.
+type Config struct {
+	Enabled     bool   // Whether locally submitted transactions are tracked at all
+	StuckBlocks uint64 // Number of blocks a transaction may stay pending before being resubmitted
+	Policy      Policy // Action to take on stuck transactions
+	BumpPercent uint64 // Fee increase applied on every bump, in percent
+	MaxBumps    int    // Maximum number of fee bumps per transaction
+	MaxTracked  int    // Maximum number of transactions tracked at once
+
+	NonceLease time.Duration // Lifetime of nonce ranges reserved via the allocator
+}
+
+// DefaultConfig contains the default settings for the transaction manager.
+var DefaultConfig = Config{
+	StuckBlocks: 10,
+	Policy:      PolicyRebroadcast,
+	BumpPercent: 10,
+	MaxBumps:    3,
+	MaxTracked:  4096,
+	NonceLease:  DefaultNonceLease,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *Config) sanitize() Config {
+	conf := *config
+	if conf.StuckBlocks == 0 {
+		log.Warn("Sanitizing invalid txmgr stuck blocks", "provided", conf.StuckBlocks, "updated", DefaultConfig.StuckBlocks)
+		conf.StuckBlocks = DefaultConfig.StuckBlocks
+	}
+	if !conf.Policy.valid() {
+		log.Warn("Sanitizing invalid txmgr policy", "provided", conf.Policy, "updated", DefaultConfig.Policy)
+		conf.Policy = DefaultConfig.Policy
+	}
+	// The pool rejects replacements below its price bump, stay above it
+	if conf.BumpPercent < core.DefaultTxPoolConfig.PriceBump {
+		log.Warn("Sanitizing invalid txmgr bump percent", "provided", conf.BumpPercent, "updated", core.DefaultTxPoolConfig.PriceBump)
+		conf.BumpPercent = core.DefaultTxPoolConfig.PriceBump
+	}
+	if conf.MaxTracked < 1 {
+		log.Warn("Sanitizing invalid txmgr tracked limit", "provided", conf.MaxTracked, "updated", DefaultConfig.MaxTracked)
+		conf.MaxTracked = DefaultConfig.MaxTracked
+	}
+	return conf
+}
+
+func (p Policy) valid() bool {
+	switch p {
+	case PolicyNone, PolicyRebroadcast, PolicyBump:
+		return true
+	}
+	return false
+}
+
+// Chain is the subset of the blockchain the manager needs to follow inclusion.
+type Chain interface {
+	Config() *params.ChainConfig
+	CurrentBlock() *types.Block
+	State() (*state.StateDB, error)
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// TxPool is the subset of the transaction pool the manager resubmits into.
+type TxPool interface {
+	AddLocal(tx *types.Transaction) error
+	Has(hash common.Hash) bool
+	GasPrice() *big.Int
+}
+
+// BroadcastFn announces transactions to the connected peers.
+type BroadcastFn func(txs types.Transactions)
+
+// tracked is the bookkeeping kept for a single submitted transaction.
+type tracked struct {
+	tx       *types.Transaction
+	from     common.Address
+	original common.Hash // Hash of the first submitted version
+	added    uint64      // Block number at which the transaction was first seen
+	last     uint64      // Block number of the last submission or resubmission
+	resends  int         // Number of rebroadcasts performed
+	bumps    int         // Number of fee bumps performed
+}
+
+// Manager tracks locally submitted transactions and resubmits them according
+// to the configured policy if they are not included in time.
+type Manager struct {
+	config    Config
+	chain     Chain
+	pool      TxPool
+	db        ethdb.Reader
+	am        *accounts.Manager
+	broadcast BroadcastFn
+
+	txs  map[common.Hash]*tracked // Tracked transactions keyed by their current hash
+	lock sync.RWMutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a transaction manager. The account manager is only needed for
+// the fee bumping policy and may be nil otherwise.
+func New(config Config, chain Chain, pool TxPool, db ethdb.Reader, am *accounts.Manager, broadcast BroadcastFn) *Manager {
+	return &Manager{
+		config:    (&config).sanitize(),
+		chain:     chain,
+		pool:      pool,
+		db:        db,
+		am:        am,
+		broadcast: broadcast,
+		txs:       make(map[common.Hash]*tracked),
+		quit:      make(chan struct{}),
+	}
+}
+
+// Start launches the chain head follower.
+func (m *Manager) Start() {
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Stop terminates the chain head follower.
+func (m *Manager) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// Track starts watching a transaction submitted through the local node.
+func (m *Manager) Track(tx *types.Transaction) error {
+	signer := types.LatestSigner(m.chain.Config())
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return err
+	}
+	number := m.chain.CurrentBlock().NumberU64()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.txs[tx.Hash()]; ok {
+		return nil
+	}
+	if len(m.txs) >= m.config.MaxTracked {
+		return fmt.Errorf("too many tracked transactions: %d", len(m.txs))
+	}
+	m.txs[tx.Hash()] = &tracked{
+		tx:       tx,
+		from:     from,
+		original: tx.Hash(),
+		added:    number,
+		last:     number,
+	}
+	trackedGauge.Update(int64(len(m.txs)))
+	return nil
+}
+
+// Untrack stops watching a transaction, returning whether it was tracked.
+func (m *Manager) Untrack(hash common.Hash) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.txs[hash]; !ok {
+		return false
+	}
+	delete(m.txs, hash)
+	trackedGauge.Update(int64(len(m.txs)))
+	return true
+}
+
+// Policy returns the currently active resubmission policy.
+func (m *Manager) Policy() Policy {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.config.Policy
+}
+
+// SetPolicy changes the resubmission policy of the manager.
+func (m *Manager) SetPolicy(policy Policy) error {
+	if !policy.valid() {
+		return errUnknownPolicy
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.config.Policy = policy
+	return nil
+}
+
+// loop follows the chain head and processes the tracked transactions on
+// every new block.
+func (m *Manager) loop() {
+	defer m.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := m.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-heads:
+			m.update(ev.Block.NumberU64())
+		case <-sub.Err():
+			return
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// update drops included or replaced transactions and resubmits the ones that
+// have been pending for too long.
+func (m *Manager) update(head uint64) {
+	statedb, err := m.chain.State()
+	if err != nil {
+		log.Warn("Failed to retrieve head state for txmgr", "err", err)
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for hash, t := range m.txs {
+		if rawdb.ReadTxLookupEntry(m.db, hash) != nil {
+			log.Debug("Tracked transaction included", "hash", hash, "original", t.original, "blocks", head-t.added)
+			includedMeter.Mark(1)
+			delete(m.txs, hash)
+			continue
+		}
+		if statedb.GetNonce(t.from) > t.tx.Nonce() {
+			log.Debug("Tracked transaction replaced", "hash", hash, "from", t.from, "nonce", t.tx.Nonce())
+			replacedMeter.Mark(1)
+			delete(m.txs, hash)
+			continue
+		}
+		if head < t.last+m.config.StuckBlocks {
+			continue
+		}
+		if _, err := m.resubmit(t, head, m.config.Policy); err != nil {
+			log.Warn("Failed to resubmit stuck transaction", "hash", hash, "policy", m.config.Policy, "err", err)
+			resubmitFailMeter.Mark(1)
+		}
+	}
+	trackedGauge.Update(int64(len(m.txs)))
+}
+
+// Resubmit forces the resubmission of a tracked transaction with the given
+// policy, returning the hash of the transaction now being tracked.
+func (m *Manager) Resubmit(hash common.Hash, policy Policy) (common.Hash, error) {
+	if !policy.valid() {
+		return common.Hash{}, errUnknownPolicy
+	}
+	head := m.chain.CurrentBlock().NumberU64()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	t, ok := m.txs[hash]
+	if !ok {
+		return common.Hash{}, errUnknownTx
+	}
+	return m.resubmit(t, head, policy)
+}
+
+// resubmit performs the policy action on a tracked transaction. The caller
+// must hold the manager lock.
+func (m *Manager) resubmit(t *tracked, head uint64, policy Policy) (common.Hash, error) {
+	switch policy {
+	case PolicyRebroadcast:
+		if !m.pool.Has(t.tx.Hash()) {
+			if err := m.pool.AddLocal(t.tx); err != nil {
+				return common.Hash{}, err
+			}
+		}
+		if m.broadcast != nil {
+			m.broadcast(types.Transactions{t.tx})
+		}
+		t.resends++
+		t.last = head
+		rebroadcastMeter.Mark(1)
+		log.Info("Rebroadcast stuck transaction", "hash", t.tx.Hash(), "from", t.from, "nonce", t.tx.Nonce(), "pending", head-t.added)
+
+	case PolicyBump:
+		if t.bumps >= m.config.MaxBumps {
+			return common.Hash{}, errMaxBumps
+		}
+		bumped, err := m.bump(t)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if err := m.pool.AddLocal(bumped); err != nil {
+			return common.Hash{}, err
+		}
+		delete(m.txs, t.tx.Hash())
+		log.Info("Bumped stuck transaction fee", "old", t.tx.Hash(), "new", bumped.Hash(), "from", t.from, "nonce", bumped.Nonce(), "bumps", t.bumps+1)
+
+		t.tx, t.last = bumped, head
+		t.bumps++
+		m.txs[bumped.Hash()] = t
+		bumpMeter.Mark(1)
+	}
+	return t.tx.Hash(), nil
+}
+
+// bump re-signs the tracked transaction with its fees raised by the configured
+// percentage, using the wallet holding the sender account.
+func (m *Manager) bump(t *tracked) (*types.Transaction, error) {
+	if m.am == nil {
+		return nil, errors.New("no account manager available for fee bumping")
+	}
+	account := accounts.Account{Address: t.from}
+	wallet, err := m.am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		tx    = t.tx
+		floor = m.pool.GasPrice()
+		inner types.TxData
+	)
+	switch tx.Type() {
+	case types.LegacyTxType:
+		inner = &types.LegacyTx{
+			Nonce:    tx.Nonce(),
+			GasPrice: bumpFee(tx.GasPrice(), floor, m.config.BumpPercent),
+			Gas:      tx.Gas(),
+			To:       tx.To(),
+			Value:    tx.Value(),
+			Data:     tx.Data(),
+		}
+	case types.AccessListTxType:
+		inner = &types.AccessListTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      tx.Nonce(),
+			GasPrice:   bumpFee(tx.GasPrice(), floor, m.config.BumpPercent),
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		}
+	case types.DynamicFeeTxType:
+		inner = &types.DynamicFeeTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      tx.Nonce(),
+			GasTipCap:  bumpFee(tx.GasTipCap(), floor, m.config.BumpPercent),
+			GasFeeCap:  bumpFee(tx.GasFeeCap(), floor, m.config.BumpPercent),
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		}
+	default:
+		return nil, fmt.Errorf("unsupported transaction type %d", tx.Type())
+	}
+	return wallet.SignTx(account, types.NewTx(inner), m.chain.Config().ChainID)
+}
+
+// bumpFee raises a fee by the given percentage, rounding up so that even tiny
+// fees satisfy the pool's replacement rules. Fees below the pool's price floor,
+// zero tips included, are raised from the floor instead, or from 1 wei if the
+// pool has none, as a zero fee would otherwise stay zero.
+func bumpFee(fee, floor *big.Int, percent uint64) *big.Int {
+	if floor == nil || floor.Sign() <= 0 {
+		floor = common.Big1
+	}
+	if fee.Cmp(floor) < 0 {
+		fee = floor
+	}
+	bumped := new(big.Int).Mul(fee, new(big.Int).SetUint64(100+percent))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// TrackedTx is the RPC representation of a tracked transaction.
+type TrackedTx struct {
+	Hash     common.Hash    `json:"hash"`
+	Original common.Hash    `json:"original"`
+	From     common.Address `json:"from"`
+	Nonce    uint64         `json:"nonce"`
+	Added    uint64         `json:"added"`
+	Last     uint64         `json:"lastSubmitted"`
+	Resends  int            `json:"resends"`
+	Bumps    int            `json:"bumps"`
+	InPool   bool           `json:"inPool"`
+}
+
+// Tracked returns a snapshot of all the transactions being tracked.
+func (m *Manager) Tracked() []*TrackedTx {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	txs := make([]*TrackedTx, 0, len(m.txs))
+	for _, t := range m.txs {
+		txs = append(txs, m.export(t))
+	}
+	return txs
+}
+
+// Get returns the tracking information of a single transaction, or nil if it
+// is not tracked.
+func (m *Manager) Get(hash common.Hash) *TrackedTx {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	if t, ok := m.txs[hash]; ok {
+		return m.export(t)
+	}
+	return nil
+}
+
+func (m *Manager) export(t *tracked) *TrackedTx {
+	return &TrackedTx{
+		Hash:     t.tx.Hash(),
+		Original: t.original,
+		From:     t.from,
+		Nonce:    t.tx.Nonce(),
+		Added:    t.added,
+		Last:     t.last,
+		Resends:  t.resends,
+		Bumps:    t.bumps,
+		InPool:   m.pool.Has(t.tx.Hash()),
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txmgr
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+type testChain struct {
+	head    uint64
+	statedb *state.StateDB
+	feed    event.Feed
+}
+
+func (c *testChain) Config() *params.ChainConfig { return params.TestChainConfig }
+func (c *testChain) CurrentBlock() *types.Block {
+	return types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(c.head)})
+}
+func (c *testChain) State() (*state.StateDB, error) { return c.statedb, nil }
+func (c *testChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+type testPool struct {
+	txs   map[common.Hash]*types.Transaction
+	added int
+	floor *big.Int
+}
+
+func (p *testPool) AddLocal(tx *types.Transaction) error {
+	p.txs[tx.Hash()] = tx
+	p.added++
+	return nil
+}
+func (p *testPool) Has(hash common.Hash) bool { return p.txs[hash] != nil }
+func (p *testPool) GasPrice() *big.Int        { return p.floor }
+
+func TestBumpFee(t *testing.T) {
+	tests := []struct {
+		fee     int64
+		floor   int64
+		percent uint64
+		want    int64
+	}{
+		{100, 0, 10, 110},
+		{1, 0, 10, 2},
+		{0, 0, 10, 2},
+		{1000000000, 0, 12, 1120000000},
+		{0, 1000000000, 10, 1100000000},
+		{500000000, 1000000000, 10, 1100000000},
+		{2000000000, 1000000000, 10, 2200000000},
+	}
+	for i, tt := range tests {
+		if have := bumpFee(big.NewInt(tt.fee), big.NewInt(tt.floor), tt.percent); have.Int64() != tt.want {
+			t.Errorf("test %d: bumped fee mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+func TestRebroadcastStuck(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		chain     = &testChain{head: 100, statedb: statedb}
+		pool      = &testPool{txs: make(map[common.Hash]*types.Transaction)}
+		announced int
+		manager   = New(Config{StuckBlocks: 5, Policy: PolicyRebroadcast}, chain, pool, rawdb.NewMemoryDatabase(), nil, func(txs types.Transactions) {
+			announced += len(txs)
+		})
+	)
+	signer := types.LatestSigner(params.TestChainConfig)
+	tx := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, Gas: 21000, GasPrice: big.NewInt(1)})
+	if err := manager.Track(tx); err != nil {
+		t.Fatalf("failed to track transaction: %v", err)
+	}
+	// Not yet stuck, nothing must happen
+	manager.update(104)
+	if announced != 0 || pool.added != 0 {
+		t.Fatalf("premature resubmission: announced %d, added %d", announced, pool.added)
+	}
+	// Stuck and missing from the pool, must be reinjected and announced
+	manager.update(105)
+	if announced != 1 || pool.added != 1 {
+		t.Fatalf("resubmission mismatch: announced %d, added %d", announced, pool.added)
+	}
+	if tracked := manager.Get(tx.Hash()); tracked == nil || tracked.Resends != 1 || tracked.Last != 105 {
+		t.Fatalf("tracking state mismatch: %+v", tracked)
+	}
+	// Once the sender nonce moves past the transaction, it must be dropped
+	statedb.SetNonce(crypto.PubkeyToAddress(key.PublicKey), 1)
+	manager.update(106)
+	if manager.Get(tx.Hash()) != nil {
+		t.Fatalf("replaced transaction still tracked")
+	}
+}
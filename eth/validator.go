@@ -0,0 +1,114 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// validatorDomain separates validator record proofs from any other data signed
+// by the signer keys.
+const validatorDomain = "aksara-validator-enr"
+
+// validatorEntry is the ENR entry advertising that a node is run by a clique
+// signer. The signer key signs the node ID as an EIP-191 text message, so the
+// entry can't be copied into the record of another node.
+type validatorEntry struct {
+	Signer    common.Address
+	Signature []byte
+
+	// Ignore additional fields (for forward compatibility).
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+// ENRKey implements enr.Entry.
+func (e validatorEntry) ENRKey() string {
+	return "aksval"
+}
+
+// validatorDigest computes the hash signed by the signer key for a node.
+func validatorDigest(id enode.ID) []byte {
+	blob, _ := rlp.EncodeToBytes([]interface{}{validatorDomain, id})
+	return crypto.Keccak256(blob)
+}
+
+// newValidatorEntry creates the entry proving that the node is run by the owner
+// of the signer account held by the given wallet.
+func newValidatorEntry(id enode.ID, signer common.Address, wallet accounts.Wallet) (*validatorEntry, error) {
+	sig, err := wallet.SignText(accounts.Account{Address: signer}, validatorDigest(id))
+	if err != nil {
+		return nil, err
+	}
+	return &validatorEntry{Signer: signer, Signature: sig}, nil
+}
+
+// nodeValidator returns the signer the record of a node proves to be running
+// it, if any. Whether the signer is authorized is up to the caller to check.
+func nodeValidator(node *enode.Node) (common.Address, bool) {
+	var entry validatorEntry
+	if node == nil || node.Load(&entry) != nil {
+		return common.Address{}, false
+	}
+	if len(entry.Signature) != crypto.SignatureLength {
+		return common.Address{}, false
+	}
+	sig := common.CopyBytes(entry.Signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pubkey, err := crypto.SigToPub(accounts.TextHash(validatorDigest(node.ID())), sig)
+	if err != nil || crypto.PubkeyToAddress(*pubkey) != entry.Signer {
+		return common.Address{}, false
+	}
+	return entry.Signer, true
+}
+
+// advertiseValidator adds the validator entry of the signer to the local node
+// record, if enabled.
+func (s *Ethereum) advertiseValidator(signer common.Address, wallet accounts.Wallet) error {
+	if !s.config.AdvertiseValidator {
+		return nil
+	}
+	ln := s.p2pServer.LocalNode()
+	if ln == nil {
+		return errors.New("p2p server not running")
+	}
+	entry, err := newValidatorEntry(ln.ID(), signer, wallet)
+	if err != nil {
+		return err
+	}
+	ln.Set(entry)
+	log.Info("Advertising validator role in node record", "signer", signer)
+	return nil
+}
+
+// isSigner reports whether an address is a clique signer at the chain head.
+func (s *Ethereum) isSigner(signer common.Address) bool {
+	cli := s.cliqueEngine()
+	if cli == nil {
+		return false
+	}
+	ok, err := cli.IsSigner(s.blockchain, signer)
+	return err == nil && ok
+}
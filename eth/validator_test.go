@@ -0,0 +1,76 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+// Tests that validator entries are only accepted in the record of the node the
+// signer key signed.
+func TestNodeValidator(t *testing.T) {
+	var (
+		nodeKey, _   = crypto.GenerateKey()
+		otherKey, _  = crypto.GenerateKey()
+		signerKey, _ = crypto.GenerateKey()
+		signer       = crypto.PubkeyToAddress(signerKey.PublicKey)
+	)
+	// proof signs the validator entry of a node with the signer key
+	proof := func(key *ecdsa.PrivateKey, claimed common.Address) *validatorEntry {
+		id := enode.PubkeyToIDV4(&key.PublicKey)
+		sig, _ := crypto.Sign(accounts.TextHash(validatorDigest(id)), signerKey)
+		sig[crypto.RecoveryIDOffset] += 27
+		return &validatorEntry{Signer: claimed, Signature: sig}
+	}
+	// record creates the signed record of a node carrying the given entry
+	record := func(key *ecdsa.PrivateKey, entry *validatorEntry) *enode.Node {
+		var r enr.Record
+		if entry != nil {
+			r.Set(entry)
+		}
+		if err := enode.SignV4(&r, key); err != nil {
+			t.Fatalf("failed to sign record: %v", err)
+		}
+		node, err := enode.New(enode.ValidSchemes, &r)
+		if err != nil {
+			t.Fatalf("failed to create node: %v", err)
+		}
+		return node
+	}
+	if have, ok := nodeValidator(record(nodeKey, proof(nodeKey, signer))); !ok || have != signer {
+		t.Errorf("valid entry rejected: have %v, %v", have, ok)
+	}
+	if _, ok := nodeValidator(record(otherKey, proof(nodeKey, signer))); ok {
+		t.Errorf("entry copied to another node accepted")
+	}
+	if _, ok := nodeValidator(record(nodeKey, proof(nodeKey, common.Address{1}))); ok {
+		t.Errorf("entry claiming another signer accepted")
+	}
+	if _, ok := nodeValidator(record(nodeKey, nil)); ok {
+		t.Errorf("record without entry accepted")
+	}
+	if _, ok := nodeValidator(enode.NewV4(&nodeKey.PublicKey, nil, 30303, 30303)); ok {
+		t.Errorf("unsigned record accepted")
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/mesh"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// versionDomain separates version attestation digests from any other data
+// signed by the signer keys.
+const versionDomain = "aksara-node-version"
+
+const (
+	versionAttestInterval = 10 * time.Minute // Interval between two attestations of the local signer
+	versionAttestMinGap   = time.Minute      // Minimum time between two accepted attestations of a signer
+	versionAttestMaxSkew  = time.Minute      // Maximum time an attestation may be ahead of the local clock
+	versionAttestExpiry   = time.Hour        // Age after which an attestation is stale and no longer relayed
+)
+
+var versionRejectMeter = metrics.NewRegisteredMeter("eth/versions/rejected", nil)
+
+// versionAttestationDigest computes the hash the signer key signs as an EIP-191
+// text message.
+func versionAttestationDigest(a *mesh.VersionAttestationPacket) common.Hash {
+	blob, _ := rlp.EncodeToBytes([]interface{}{versionDomain, a.Signer, a.Version, a.Build, a.Number, a.Time})
+	return crypto.Keccak256Hash(blob)
+}
+
+// verifyVersionAttestation checks that the attestation is signed by the signer
+// it names.
+func verifyVersionAttestation(a *mesh.VersionAttestationPacket) error {
+	if len(a.Signature) != crypto.SignatureLength {
+		return errors.New("invalid signature length")
+	}
+	digest := versionAttestationDigest(a)
+
+	sig := common.CopyBytes(a.Signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pubkey, err := crypto.SigToPub(accounts.TextHash(digest[:]), sig)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+	if crypto.PubkeyToAddress(*pubkey) != a.Signer {
+		return errors.New("signature not made by the signer key")
+	}
+	return nil
+}
+
+// versionTracker periodically attests the client version of the local signer
+// to the validator mesh, and collects and relays the attestations of the other
+// authorized signers.
+type versionTracker struct {
+	engine  *clique.Clique
+	chain   *core.BlockChain
+	manager *accounts.Manager
+	version string // Textual version of the local client
+	build   []byte // Git commit of the local build, empty if unknown
+
+	broadcast func(attestation *mesh.VersionAttestationPacket, origin string) // Gossips an attestation to the mesh peers
+
+	attestations map[common.Address]*mesh.VersionAttestationPacket // Latest attestation of every signer
+	lock         sync.RWMutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newVersionTracker creates a tracker of the client versions of the signers
+// running the engine.
+func newVersionTracker(engine *clique.Clique, chain *core.BlockChain, manager *accounts.Manager, version string, gitCommit string) *versionTracker {
+	return &versionTracker{
+		engine:       engine,
+		chain:        chain,
+		manager:      manager,
+		version:      version,
+		build:        common.FromHex(gitCommit),
+		broadcast:    func(*mesh.VersionAttestationPacket, string) {},
+		attestations: make(map[common.Address]*mesh.VersionAttestationPacket),
+		quit:         make(chan struct{}),
+	}
+}
+
+// known returns the attestations which aren't stale yet, to be sent to newly
+// connected peers.
+func (t *versionTracker) known() []*mesh.VersionAttestationPacket {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	var (
+		known  []*mesh.VersionAttestationPacket
+		cutoff = uint64(time.Now().Add(-versionAttestExpiry).Unix())
+	)
+	for _, attestation := range t.attestations {
+		if attestation.Time >= cutoff {
+			known = append(known, attestation)
+		}
+	}
+	return known
+}
+
+// add validates an attestation and stores it if it's newer than the last one of
+// its signer, reporting whether it should be relayed.
+func (t *versionTracker) add(a *mesh.VersionAttestationPacket) (bool, error) {
+	now := time.Now()
+	switch {
+	case a.Time > uint64(now.Add(versionAttestMaxSkew).Unix()):
+		return false, fmt.Errorf("attestation from the future: %d", a.Time)
+	case a.Time < uint64(now.Add(-versionAttestExpiry).Unix()):
+		return false, nil // Stale, drop silently
+	case len(a.Version) > 64 || len(a.Build) > common.HashLength:
+		return false, errors.New("oversized attestation")
+	}
+	t.lock.RLock()
+	prev := t.attestations[a.Signer]
+	t.lock.RUnlock()
+
+	if prev != nil && a.Time < prev.Time+uint64(versionAttestMinGap/time.Second) {
+		return false, nil // Already known or too frequent
+	}
+	if err := verifyVersionAttestation(a); err != nil {
+		return false, err
+	}
+	authorized, err := t.engine.IsSigner(t.chain, a.Signer)
+	if err != nil {
+		return false, err
+	}
+	if !authorized {
+		return false, nil // Possibly not yet synced, don't penalize the peer
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if prev := t.attestations[a.Signer]; prev != nil && a.Time <= prev.Time {
+		return false, nil
+	}
+	t.attestations[a.Signer] = a
+	return true, nil
+}
+
+// handle processes an attestation gossiped by a mesh peer, relaying it to the
+// other peers if new. Invalid attestations fail the peer.
+func (t *versionTracker) handle(origin string, a *mesh.VersionAttestationPacket) error {
+	fresh, err := t.add(a)
+	if err != nil {
+		versionRejectMeter.Mark(1)
+		return err
+	}
+	if fresh {
+		log.Debug("Received version attestation", "signer", a.Signer, "version", a.Version, "peer", origin)
+		t.broadcast(a, origin)
+	}
+	return nil
+}
+
+// attest signs the client version of the local signer and gossips it to the
+// mesh, if the node is an authorized signer.
+func (t *versionTracker) attest() error {
+	signer := t.engine.Signer()
+	if signer == (common.Address{}) {
+		return nil
+	}
+	if authorized, err := t.engine.IsSigner(t.chain, signer); err != nil || !authorized {
+		return err
+	}
+	wallet, err := t.manager.Find(accounts.Account{Address: signer})
+	if err != nil {
+		return fmt.Errorf("signer %v unavailable: %v", signer, err)
+	}
+	attestation := &mesh.VersionAttestationPacket{
+		Signer:  signer,
+		Version: t.version,
+		Build:   t.build,
+		Number:  t.chain.CurrentHeader().Number.Uint64(),
+		Time:    uint64(time.Now().Unix()),
+	}
+	digest := versionAttestationDigest(attestation)
+	sig, err := wallet.SignText(accounts.Account{Address: signer}, digest[:])
+	if err != nil {
+		return fmt.Errorf("signer signature failed: %v", err)
+	}
+	if sig[crypto.RecoveryIDOffset] < 27 {
+		sig[crypto.RecoveryIDOffset] += 27 // Transform V from 0/1 to 27/28 like personal_sign
+	}
+	attestation.Signature = sig
+
+	t.lock.Lock()
+	t.attestations[signer] = attestation
+	t.lock.Unlock()
+
+	t.broadcast(attestation, "")
+	return nil
+}
+
+// start launches the periodic attestation of the local signer.
+func (t *versionTracker) start() {
+	t.wg.Add(1)
+	go t.loop()
+}
+
+// stop terminates the periodic attestation.
+func (t *versionTracker) stop() {
+	close(t.quit)
+	t.wg.Wait()
+}
+
+func (t *versionTracker) loop() {
+	defer t.wg.Done()
+
+	timer := time.NewTimer(time.Minute) // Leave some time to connect to the mesh
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if err := t.attest(); err != nil {
+				log.Warn("Failed to attest client version", "err", err)
+			}
+			timer.Reset(versionAttestInterval)
+		case <-t.quit:
+			return
+		}
+	}
+}
+
+// SignerVersion is the client version attested by an authorized signer.
+type SignerVersion struct {
+	Signer   common.Address  `json:"signer"`
+	Attested bool            `json:"attested"`
+	Stale    bool            `json:"stale"` // Whether the attestation is older than the expiry
+	Version  string          `json:"version,omitempty"`
+	Build    hexutil.Bytes   `json:"build,omitempty"`
+	Number   *hexutil.Uint64 `json:"number,omitempty"` // Chain head of the signer when attesting
+	Time     *hexutil.Uint64 `json:"time,omitempty"`
+}
+
+// NetworkVersions reports the client versions attested by the signers
+// authorized at the head of the chain.
+type NetworkVersions struct {
+	Number     hexutil.Uint64   `json:"number"`
+	Signers    []*SignerVersion `json:"signers"`
+	Versions   map[string]int   `json:"versions"`   // Number of signers per attested version, fresh attestations only
+	Unattested int              `json:"unattested"` // Signers without a fresh attestation
+}
+
+// networkVersions assembles the attested versions of the authorized signers.
+func (t *versionTracker) networkVersions() (*NetworkVersions, error) {
+	signers, err := t.engine.Signers(t.chain)
+	if err != nil {
+		return nil, err
+	}
+	res := &NetworkVersions{
+		Number:   hexutil.Uint64(t.chain.CurrentHeader().Number.Uint64()),
+		Versions: make(map[string]int),
+	}
+	cutoff := uint64(time.Now().Add(-versionAttestExpiry).Unix())
+
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for _, signer := range signers {
+		status := &SignerVersion{Signer: signer}
+		if a := t.attestations[signer]; a != nil {
+			number, stamp := hexutil.Uint64(a.Number), hexutil.Uint64(a.Time)
+			status.Attested, status.Stale = true, a.Time < cutoff
+			status.Version, status.Build = a.Version, common.CopyBytes(a.Build)
+			status.Number, status.Time = &number, &stamp
+		}
+		if status.Attested && !status.Stale {
+			res.Versions[status.Version]++
+		} else {
+			res.Unattested++
+		}
+		res.Signers = append(res.Signers, status)
+	}
+	return res, nil
+}
+
+// PublicNetworkVersionsAPI exposes the client versions attested by the signers
+// in the clique namespace.
+type PublicNetworkVersionsAPI struct {
+	tracker *versionTracker
+}
+
+// GetNetworkVersions returns the client versions attested by the signers
+// authorized at the head of the chain, to verify the consortium upgraded
+// before a fork.
+func (api *PublicNetworkVersionsAPI) GetNetworkVersions() (*NetworkVersions, error) {
+	return api.tracker.networkVersions()
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/mesh"
+)
+
+// Tests that version attestations verify, and that tampering with any of the
+// attested fields is detected.
+func TestVersionAttestation(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	attest := func() *mesh.VersionAttestationPacket {
+		a := &mesh.VersionAttestationPacket{
+			Signer:  crypto.PubkeyToAddress(key.PublicKey),
+			Version: "1.10.17-stable",
+			Build:   common.FromHex("0xdeadbeef"),
+			Number:  100,
+			Time:    1650000000,
+		}
+		digest := versionAttestationDigest(a)
+		a.Signature, _ = crypto.Sign(accounts.TextHash(digest[:]), key)
+		a.Signature[crypto.RecoveryIDOffset] += 27
+		return a
+	}
+	if err := verifyVersionAttestation(attest()); err != nil {
+		t.Fatalf("valid attestation rejected: %v", err)
+	}
+	tampers := map[string]func(a *mesh.VersionAttestationPacket){
+		"signer":  func(a *mesh.VersionAttestationPacket) { a.Signer = common.Address{1} },
+		"version": func(a *mesh.VersionAttestationPacket) { a.Version = "1.10.18-stable" },
+		"build":   func(a *mesh.VersionAttestationPacket) { a.Build = common.FromHex("0xcafebabe") },
+		"time":    func(a *mesh.VersionAttestationPacket) { a.Time++ },
+		"sig":     func(a *mesh.VersionAttestationPacket) { a.Signature = a.Signature[:10] },
+	}
+	for name, tamper := range tampers {
+		a := attest()
+		tamper(a)
+		if err := verifyVersionAttestation(a); err == nil {
+			t.Errorf("tampered %s accepted", name)
+		}
+	}
+}
@@ -0,0 +1,606 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package cliqueclient provides an RPC client for the clique and aks APIs.
+package cliqueclient
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Client is a wrapper around rpc.Client that implements the clique consensus
+// and aks specific functionality.
+//
+// If you want to use the standardized Ethereum RPC functionality, use ethclient.Client instead.
+type Client struct {
+	c *rpc.Client
+}
+
+// Dial connects a client to the given URL.
+func Dial(rawurl string) (*Client, error) {
+	return DialContext(context.Background(), rawurl)
+}
+
+// DialContext connects a client to the given URL with the given context.
+func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	c, err := rpc.DialContext(ctx, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return New(c), nil
+}
+
+// New creates a client that uses the given RPC client.
+func New(c *rpc.Client) *Client {
+	return &Client{c}
+}
+
+// Close closes the underlying RPC connection.
+func (ec *Client) Close() {
+	ec.c.Close()
+}
+
+// Snapshot is the state of the signer set at a given block.
+type Snapshot struct {
+	Number             uint64                    `json:"number"`                       // Block number where the snapshot was created
+	PreviousSnapNumber *uint64                   `json:"previousSnapNumber,omitempty"` // Block number of the previous epoch's snapshot
+	PreviousSnapHash   *common.Hash              `json:"previousSnapHash,omitempty"`   // Block hash of the previous epoch's snapshot
+	Epoch              uint64                    `json:"epoch"`                        // Registry epoch the snapshot was created for
+	Hash               common.Hash               `json:"hash"`                         // Block hash where the snapshot was created
+	Signers            map[common.Address]bool   `json:"signers"`                      // Set of authorized signers at this moment
+	Recents            map[uint64]common.Address `json:"recents"`                      // Set of recent signers for spam protections
+}
+
+// RawSnapshot is the canonical RLP encoding of a snapshot and its hash.
+type RawSnapshot struct {
+	Number  uint64        `json:"number"`
+	Hash    common.Hash   `json:"hash"` // Hash of the snapshot block
+	RLP     hexutil.Bytes `json:"rlp"`
+	RLPHash common.Hash   `json:"rlpHash"`
+}
+
+// SnapshotCommitment is the snapshot commitment of an epoch block compared to
+// the snapshot derived by the node.
+type SnapshotCommitment struct {
+	Number    uint64       `json:"number"`
+	Epoch     uint64       `json:"epoch"`
+	Committed *common.Hash `json:"committed,omitempty"` // Commitment in the header, unset if none
+	Derived   common.Hash  `json:"derived"`             // Commitment of the node's snapshot
+	Valid     bool         `json:"valid"`               // Whether the node's snapshot matches the commitment
+}
+
+// RecentSeal is a block sealed within the recently-signed window.
+type RecentSeal struct {
+	Block  uint64         `json:"block"`
+	Signer common.Address `json:"signer"`
+}
+
+// Recents are the seals observed within the recently-signed window at a block,
+// which isn't enforced by consensus.
+type Recents struct {
+	Number  uint64           `json:"number"`
+	Limit   uint64           `json:"limit"`   // Half the signers plus one, the window spans limit-1 blocks
+	Recents []RecentSeal     `json:"recents"` // Last block of each signer within the window, in ascending block order
+	Sealed  []common.Address `json:"sealed"`  // Current signers observed sealing within the window
+	Window  []RecentSeal     `json:"window"`  // Sealers of the last limit-1 canonical blocks
+}
+
+// Status is the sealing activity of the signers over the last blocks.
+type Status struct {
+	InturnPercent float64                `json:"inturnPercent"`
+	SigningStatus map[common.Address]int `json:"sealerActivity"`
+	NumBlocks     uint64                 `json:"numBlocks"`
+}
+
+// MaintenanceStatus is the maintenance state of the local signer.
+type MaintenanceStatus struct {
+	Pending uint64 `json:"pending"` // Number of blocks to skip, announced in the next sealed block
+	Until   uint64 `json:"until"`   // Last block of the announced maintenance, zero if none
+}
+
+// NTPServerStatus is the outcome of the last measurement against an NTP server.
+type NTPServerStatus struct {
+	Server string   `json:"server"`
+	Drift  *float64 `json:"drift,omitempty"` // Seconds the local clock is ahead of the server, negative if behind
+	Error  string   `json:"error,omitempty"`
+}
+
+// ClockStatus is the outcome of the last check of the local clock.
+type ClockStatus struct {
+	Checked  time.Time          `json:"checked"`
+	Drift    *float64           `json:"drift,omitempty"` // Median seconds the local clock is ahead of the servers, unset if none answered
+	MaxDrift float64            `json:"maxDrift"`        // Seconds of drift beyond which the clock is unhealthy
+	Healthy  bool               `json:"healthy"`         // Whether the drift is within the limit, or unknown
+	Enforced bool               `json:"enforced"`        // Whether sealing is refused while the clock is unhealthy
+	Servers  []*NTPServerStatus `json:"servers"`
+}
+
+// Health is the sealing health of the node.
+type Health struct {
+	Signer      common.Address    `json:"signer"`
+	Sealing     bool              `json:"sealing"`          // Whether the health checks allow sealing
+	Reason      string            `json:"reason,omitempty"` // Reason sealing is refused, if it is
+	Maintenance MaintenanceStatus `json:"maintenance"`
+	Pause       *SealingPause     `json:"pause,omitempty"` // Ongoing sealing pause, if any
+	Clock       *ClockStatus      `json:"clock"`           // Last local clock check, unset if disabled or not yet done
+}
+
+// SealingPause is a window during which the operator paused the sealing of the
+// node's signer.
+type SealingPause struct {
+	Reason     string     `json:"reason"`
+	Start      time.Time  `json:"start"`
+	StartBlock uint64     `json:"startBlock"`         // Head when the sealing was paused
+	End        *time.Time `json:"end,omitempty"`      // Unset while the pause is ongoing
+	EndBlock   *uint64    `json:"endBlock,omitempty"` // Head when the sealing was resumed
+	Skipped    uint64     `json:"skipped"`            // Number of blocks the signer refused to seal meanwhile
+}
+
+// EpochPerformance is the sealing activity of the signers of an epoch.
+type EpochPerformance struct {
+	InturnPercent float64                `json:"inturnPercent"`
+	SigningStatus map[common.Address]int `json:"sealerActivity"`
+	NumBlocks     uint64                 `json:"numBlocks"`
+	NextEpoch     uint64                 `json:"nextEpoch"`
+	StartBlock    uint64                 `json:"startBlock"`
+	EndBlock      uint64                 `json:"endBlock"`       // Last block scanned
+	Next          *uint64                `json:"next,omitempty"` // First block not yet scanned if the scan was cut short
+}
+
+// EpochBoundary is the estimated position of a future epoch block.
+type EpochBoundary struct {
+	Block uint64 `json:"block"`
+	Time  uint64 `json:"time"` // Estimated unix timestamp
+}
+
+// EpochSchedule is the estimated end of the current epoch and the following
+// epoch boundaries.
+type EpochSchedule struct {
+	Epoch      uint64          `json:"epoch"`      // Current epoch number
+	EpochBlock uint64          `json:"epochBlock"` // Block which started the current epoch
+	Head       uint64          `json:"head"`
+	Length     uint64          `json:"length"`     // Estimated epoch length in blocks
+	Samples    int             `json:"samples"`    // Number of past epochs the length is averaged over
+	BlockTime  float64         `json:"blockTime"`  // Block interval in seconds used for time estimates
+	Overdue    bool            `json:"overdue"`    // Whether the current epoch already exceeded the estimated length
+	End        EpochBoundary   `json:"end"`        // Estimated end of the current epoch
+	Boundaries []EpochBoundary `json:"boundaries"` // Estimated subsequent epoch boundaries
+}
+
+// SignerSkew is the estimated clock skew of a signer.
+type SignerSkew struct {
+	Signer    common.Address `json:"signer"`
+	Skew      float64        `json:"skew"`      // Median seconds the signer's blocks arrived ahead of their timestamp, negative if late
+	Samples   int            `json:"samples"`   // Number of recent in-turn blocks the skew is estimated over
+	LastBlock uint64         `json:"lastBlock"` // Last in-turn block of the signer measured
+}
+
+// SignerPolicy is the signer policy of the chain along with the verdict on the
+// registry epoch pending to be applied.
+type SignerPolicy struct {
+	Policy            *params.CliqueSignerPolicy `json:"policy"`            // Signer policy of the chain, unset if none
+	Enforced          bool                       `json:"enforced"`          // Whether the policy is enforced for the next block
+	Signers           int                        `json:"signers"`           // Size of the signer set at the head
+	LastAuthorization uint64                     `json:"lastAuthorization"` // Last epoch block authorizing new signers, zero if unknown
+	Pending           *uint64                    `json:"pending,omitempty"` // Registry epoch pending to be applied, if any
+	Violation         string                     `json:"violation,omitempty"`
+}
+
+// Anomaly is a statistical irregularity in the sealing record of an epoch.
+type Anomaly struct {
+	Kind      string          `json:"kind"`
+	Epoch     uint64          `json:"epoch"`
+	Validator *common.Address `json:"validator,omitempty"` // Validator the anomaly is attributed to, if any
+	Block     *uint64         `json:"block,omitempty"`     // First block of the anomaly, if bound to blocks
+	Value     float64         `json:"value"`               // Observed value, durations in seconds
+	Threshold float64         `json:"threshold"`           // Value the observation is anomalous beyond
+}
+
+// EpochAnomalies are the anomalies detected over the blocks of an epoch.
+type EpochAnomalies struct {
+	Epoch      uint64     `json:"epoch"`
+	EpochBlock uint64     `json:"epochBlock"` // Block which started the epoch
+	StartBlock uint64     `json:"startBlock"` // First block analyzed
+	EndBlock   uint64     `json:"endBlock"`   // Last block analyzed
+	Closed     bool       `json:"closed"`     // Whether the epoch was closed by the end block
+	Anomalies  []*Anomaly `json:"anomalies"`
+}
+
+// PerformanceProof is the merkle proof of a validator's performance in a closed
+// epoch.
+type PerformanceProof struct {
+	Epoch       uint64         `json:"epoch"`       // Epoch the performance is proven for
+	EpochBlock  uint64         `json:"epochBlock"`  // Block which opened the epoch
+	CommitBlock uint64         `json:"commitBlock"` // Epoch block which closed the epoch and carries the root
+	Committed   bool           `json:"committed"`   // Whether the root is embedded in the commit block
+	Root        common.Hash    `json:"root"`
+	Validator   common.Address `json:"validator"`
+	Count       uint64         `json:"count"` // Number of blocks sealed by the validator in the epoch
+	Leaf        common.Hash    `json:"leaf"`
+	Index       int            `json:"index"` // Position of the leaf among the validators in ascending order
+	Proof       []common.Hash  `json:"proof"` // Sibling hashes from the leaf up to the root
+}
+
+// SealVerdict is the outcome of verifying a header against the clique rules.
+type SealVerdict struct {
+	Valid  bool        `json:"valid"`
+	Error  string      `json:"error,omitempty"` // First consensus rule violated, if any
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+	Epoch  uint64      `json:"epoch"` // Epoch of the snapshot the header is checked against
+
+	Signer             *common.Address `json:"signer,omitempty"` // Recovered sealer, unset if the seal is malformed
+	Authorized         bool            `json:"authorized"`       // Whether the sealer is in the signer set
+	RecentlySigned     bool            `json:"recentlySigned"`   // Whether the sealer signed one of the recent blocks
+	Inturn             bool            `json:"inturn"`
+	Difficulty         *hexutil.Big    `json:"difficulty"`
+	ExpectedDifficulty *hexutil.Big    `json:"expectedDifficulty,omitempty"`
+}
+
+// ForkPeerStatus is the result of comparing the recent canonical chain of a
+// single peer against the local one.
+type ForkPeerStatus struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	Trusted    bool             `json:"trusted"`
+	Validator  *common.Address  `json:"validator,omitempty"` // Clique signer proven to run the peer, if any
+	Head       common.Hash      `json:"head"`
+	Checked    int              `json:"checked"`              // Number of heights the peer answered for
+	Mismatches []hexutil.Uint64 `json:"mismatches,omitempty"` // Heights where the peer's hash differs from ours
+	Diverged   bool             `json:"diverged"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// ForkStatus reports how the recent local chain compares to the chains of the
+// connected validator peers.
+type ForkStatus struct {
+	Number      hexutil.Uint64    `json:"number"` // Local head number
+	Hash        common.Hash       `json:"hash"`   // Local head hash
+	From        hexutil.Uint64    `json:"from"`   // First height compared
+	Peers       []*ForkPeerStatus `json:"peers"`
+	Agreeing    int               `json:"agreeing"`
+	Disagreeing int               `json:"disagreeing"`
+	Unreachable int               `json:"unreachable"`
+}
+
+// BlockIssuance is the value issued and burnt by a block.
+type BlockIssuance struct {
+	Number      hexutil.Uint64                  `json:"number"`
+	Hash        common.Hash                     `json:"hash"`
+	Beneficiary common.Address                  `json:"beneficiary"` // Account credited with the tips, the sealer on clique chains
+	BaseFee     *hexutil.Big                    `json:"baseFeePerGas,omitempty"`
+	GasUsed     hexutil.Uint64                  `json:"gasUsed"`
+	Burnt       *hexutil.Big                    `json:"burnt"`       // Base fee times gas used, zero before London
+	Tips        *hexutil.Big                    `json:"tips"`        // Priority fees (all fees before London) credited to the beneficiary
+	Rewards     map[common.Address]*hexutil.Big `json:"rewards"`     // Protocol rewards credited when finalizing, none on clique chains
+	SupplyDelta *hexutil.Big                    `json:"supplyDelta"` // Rewards minus the burnt fees
+}
+
+// BlockOrdering reports whether the transactions of a block respect the
+// deterministic ordering of the miner.
+type BlockOrdering struct {
+	Number    hexutil.Uint64  `json:"number"`
+	Hash      common.Hash     `json:"hash"`
+	Sealer    common.Address  `json:"sealer"`
+	Committed uint8           `json:"committed"` // Ordering version announced in the vanity, zero if none
+	Version   uint8           `json:"version"`   // Ordering version verified against
+	Valid     bool            `json:"valid"`
+	Violation *hexutil.Uint64 `json:"violation,omitempty"` // Index of the first out of order transaction
+	Error     string          `json:"error,omitempty"`
+}
+
+// GetSnapshot retrieves the state snapshot at a given block. The block number
+// can be nil, in which case the snapshot is taken at the latest known block.
+func (ec *Client) GetSnapshot(ctx context.Context, number *big.Int) (*Snapshot, error) {
+	var snap Snapshot
+	if err := ec.c.CallContext(ctx, &snap, "clique_getSnapshot", toBlockNumArg(number)); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// GetSnapshotAtHash retrieves the state snapshot at a given block.
+func (ec *Client) GetSnapshotAtHash(ctx context.Context, hash common.Hash) (*Snapshot, error) {
+	var snap Snapshot
+	if err := ec.c.CallContext(ctx, &snap, "clique_getSnapshotAtHash", hash); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// GetSnapshotRaw retrieves the canonical RLP encoding of the snapshot at a given
+// block along with its hash. The block number can be nil, in which case the
+// snapshot is taken at the latest known block.
+func (ec *Client) GetSnapshotRaw(ctx context.Context, number *big.Int) (*RawSnapshot, error) {
+	var raw RawSnapshot
+	if err := ec.c.CallContext(ctx, &raw, "clique_getSnapshotRaw", toBlockNumArg(number)); err != nil {
+		return nil, err
+	}
+	return &raw, nil
+}
+
+// VerifySnapshotCommitment compares the snapshot of the node at a given block
+// with the commitment in the header of the epoch block producing it. The block
+// number can be nil, in which case the latest known block is used.
+func (ec *Client) VerifySnapshotCommitment(ctx context.Context, number *big.Int) (*SnapshotCommitment, error) {
+	var commit SnapshotCommitment
+	if err := ec.c.CallContext(ctx, &commit, "clique_verifySnapshotCommitment", toBlockNumArg(number)); err != nil {
+		return nil, err
+	}
+	return &commit, nil
+}
+
+// GetSigners retrieves the list of authorized signers at the specified block.
+// The block number can be nil, in which case the latest known block is used.
+func (ec *Client) GetSigners(ctx context.Context, number *big.Int) ([]common.Address, error) {
+	var signers []common.Address
+	err := ec.c.CallContext(ctx, &signers, "clique_getSigners", toBlockNumArg(number))
+	return signers, err
+}
+
+// GetSignersAtHash retrieves the list of authorized signers at the specified block.
+func (ec *Client) GetSignersAtHash(ctx context.Context, hash common.Hash) ([]common.Address, error) {
+	var signers []common.Address
+	err := ec.c.CallContext(ctx, &signers, "clique_getSignersAtHash", hash)
+	return signers, err
+}
+
+// GetSigner returns the signer of the specified block. The block number can be
+// nil, in which case the latest known block is used.
+func (ec *Client) GetSigner(ctx context.Context, number *big.Int) (common.Address, error) {
+	var signer common.Address
+	err := ec.c.CallContext(ctx, &signer, "clique_getSigner", toBlockNumArg(number))
+	return signer, err
+}
+
+// GetRecents retrieves the seals observed within the recently-signed window at
+// the specified block. The block number can be nil, in which case the latest
+// known block is used.
+func (ec *Client) GetRecents(ctx context.Context, number *big.Int) (*Recents, error) {
+	var recents Recents
+	if err := ec.c.CallContext(ctx, &recents, "clique_getRecents", toBlockNumArg(number)); err != nil {
+		return nil, err
+	}
+	return &recents, nil
+}
+
+// Status returns the sealing activity of the signers over the last blocks.
+func (ec *Client) Status(ctx context.Context) (*Status, error) {
+	var status Status
+	if err := ec.c.CallContext(ctx, &status, "clique_status"); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// SignerStatus is the sealing account of the node after a signer change.
+type SignerStatus struct {
+	Signer     common.Address `json:"signer"`
+	Authorized bool           `json:"authorized"` // Whether the signer is authorized to seal at the current head
+	Mining     bool           `json:"mining"`
+}
+
+// SetSigner changes the account the node seals blocks with, unlocking it with
+// the passphrase if it's a keystore account and one is given. The method is only
+// served over authenticated endpoints and IPC.
+func (ec *Client) SetSigner(ctx context.Context, signer common.Address, passphrase *string) (*SignerStatus, error) {
+	var status SignerStatus
+	if err := ec.c.CallContext(ctx, &status, "clique_setSigner", signer, passphrase); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// PauseSealing stops the node's signer from taking its slots until resumed,
+// recording the reason. The method is only served over authenticated endpoints
+// and IPC.
+func (ec *Client) PauseSealing(ctx context.Context, reason string) (*SealingPause, error) {
+	var pause SealingPause
+	if err := ec.c.CallContext(ctx, &pause, "clique_pauseSealing", reason); err != nil {
+		return nil, err
+	}
+	return &pause, nil
+}
+
+// ResumeSealing ends the ongoing sealing pause of the node's signer. The method
+// is only served over authenticated endpoints and IPC.
+func (ec *Client) ResumeSealing(ctx context.Context) (*SealingPause, error) {
+	var pause SealingPause
+	if err := ec.c.CallContext(ctx, &pause, "clique_resumeSealing"); err != nil {
+		return nil, err
+	}
+	return &pause, nil
+}
+
+// GetSealingLog returns the recorded sealing pauses of the node, oldest first.
+func (ec *Client) GetSealingLog(ctx context.Context) ([]*SealingPause, error) {
+	var pauses []*SealingPause
+	err := ec.c.CallContext(ctx, &pauses, "clique_getSealingLog")
+	return pauses, err
+}
+
+// Health returns the sealing health of the node, including the last check of
+// its local clock against the NTP servers.
+func (ec *Client) Health(ctx context.Context) (*Health, error) {
+	var health Health
+	if err := ec.c.CallContext(ctx, &health, "clique_health"); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// EpochPerformance returns the sealing activity of the signers of an epoch. The
+// limit of blocks scanned can be zero, in which case the node's maximum is used.
+func (ec *Client) EpochPerformance(ctx context.Context, epoch, epochBlock, limit uint64) (*EpochPerformance, error) {
+	var (
+		perf EpochPerformance
+		err  error
+	)
+	if limit == 0 {
+		err = ec.c.CallContext(ctx, &perf, "clique_epochPerformance", epoch, epochBlock)
+	} else {
+		err = ec.c.CallContext(ctx, &perf, "clique_epochPerformance", epoch, epochBlock, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &perf, nil
+}
+
+// EpochPerformanceRange returns the sealing activity of the signers of an epoch
+// over at most limit blocks starting at from. Long running epochs are consumed
+// page by page, continuing at the returned Next block until it is unset.
+func (ec *Client) EpochPerformanceRange(ctx context.Context, epoch, epochBlock, from, limit uint64) (*EpochPerformance, error) {
+	var perf EpochPerformance
+	if err := ec.c.CallContext(ctx, &perf, "clique_epochPerformanceRange", epoch, epochBlock, from, limit); err != nil {
+		return nil, err
+	}
+	return &perf, nil
+}
+
+// EpochSchedule returns the estimated end of the current epoch along with the
+// next count epoch boundaries after it.
+func (ec *Client) EpochSchedule(ctx context.Context, count uint64) (*EpochSchedule, error) {
+	var schedule EpochSchedule
+	if err := ec.c.CallContext(ctx, &schedule, "clique_epochSchedule", count); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// SlasherStat returns the sealing activity of the signers over a whole epoch,
+// as used to decide on slashing.
+func (ec *Client) SlasherStat(ctx context.Context, epoch uint64) (*EpochPerformance, error) {
+	var perf EpochPerformance
+	if err := ec.c.CallContext(ctx, &perf, "clique_slasherStat", epoch); err != nil {
+		return nil, err
+	}
+	return &perf, nil
+}
+
+// GetPerformanceProof returns the merkle proof of the number of blocks sealed by
+// a validator in a closed epoch.
+func (ec *Client) GetPerformanceProof(ctx context.Context, epoch uint64, validator common.Address) (*PerformanceProof, error) {
+	var proof PerformanceProof
+	if err := ec.c.CallContext(ctx, &proof, "clique_getPerformanceProof", epoch, validator); err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+// SkewedSigners returns the signers whose clocks appear skewed by more than the
+// threshold in seconds. A zero threshold uses the node's default.
+func (ec *Client) SkewedSigners(ctx context.Context, threshold float64) ([]*SignerSkew, error) {
+	var (
+		signers []*SignerSkew
+		err     error
+	)
+	if threshold == 0 {
+		err = ec.c.CallContext(ctx, &signers, "clique_skewedSigners")
+	} else {
+		err = ec.c.CallContext(ctx, &signers, "clique_skewedSigners", threshold)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return signers, nil
+}
+
+// GetSignerPolicy returns the signer policy of the chain and whether the
+// registry epoch pending to be applied violates it.
+func (ec *Client) GetSignerPolicy(ctx context.Context) (*SignerPolicy, error) {
+	var policy SignerPolicy
+	if err := ec.c.CallContext(ctx, &policy, "clique_getSignerPolicy"); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// GetAnomalies returns the statistical anomalies detected in the sealing record
+// of an epoch.
+func (ec *Client) GetAnomalies(ctx context.Context, epoch uint64) (*EpochAnomalies, error) {
+	var result EpochAnomalies
+	if err := ec.c.CallContext(ctx, &result, "clique_getAnomalies", epoch); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// VerifyHeaderSeal checks whether a header would be accepted on top of its
+// parent, without importing it.
+func (ec *Client) VerifyHeaderSeal(ctx context.Context, header *types.Header) (*SealVerdict, error) {
+	blob, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return nil, err
+	}
+	var verdict SealVerdict
+	if err := ec.c.CallContext(ctx, &verdict, "clique_verifyHeaderSeal", hexutil.Bytes(blob)); err != nil {
+		return nil, err
+	}
+	return &verdict, nil
+}
+
+// SubscribeForkAlerts subscribes to fork status reports, sent on every new local
+// head at which at least threshold validator peers disagree with the local chain.
+// The depth can be zero, in which case the node's default is used.
+func (ec *Client) SubscribeForkAlerts(ctx context.Context, ch chan<- *ForkStatus, threshold, depth uint64) (*rpc.ClientSubscription, error) {
+	if depth == 0 {
+		return ec.c.Subscribe(ctx, "admin", ch, "forkAlerts", hexutil.Uint64(threshold))
+	}
+	return ec.c.Subscribe(ctx, "admin", ch, "forkAlerts", hexutil.Uint64(threshold), hexutil.Uint64(depth))
+}
+
+// GetBlockIssuance returns the fees burnt, the tips credited to the beneficiary
+// and the protocol rewards of a block. The block number can be nil, in which
+// case the latest known block is used.
+func (ec *Client) GetBlockIssuance(ctx context.Context, number *big.Int) (*BlockIssuance, error) {
+	var issuance BlockIssuance
+	if err := ec.c.CallContext(ctx, &issuance, "aks_getBlockIssuance", toBlockNumArg(number)); err != nil {
+		return nil, err
+	}
+	return &issuance, nil
+}
+
+// VerifyBlockOrdering checks whether the transactions of a block respect the
+// deterministic ordering of the miner. The block number can be nil, in which
+// case the latest known block is used.
+func (ec *Client) VerifyBlockOrdering(ctx context.Context, number *big.Int) (*BlockOrdering, error) {
+	var ordering BlockOrdering
+	if err := ec.c.CallContext(ctx, &ordering, "aks_verifyBlockOrdering", toBlockNumArg(number)); err != nil {
+		return nil, err
+	}
+	return &ordering, nil
+}
+
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	pending := big.NewInt(-1)
+	if number.Cmp(pending) == 0 {
+		return "pending"
+	}
+	return hexutil.EncodeBig(number)
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package cliqueclient
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var testSigner = common.HexToAddress("0x0102")
+
+// testCliqueAPI mimics the clique API, echoing the arguments into the results.
+type testCliqueAPI struct{}
+
+func (api *testCliqueAPI) GetSnapshot(number *rpc.BlockNumber) map[string]interface{} {
+	return map[string]interface{}{
+		"number":  number.Int64(),
+		"epoch":   3,
+		"signers": map[common.Address]bool{testSigner: true},
+		"recents": map[uint64]common.Address{7: testSigner},
+	}
+}
+
+func (api *testCliqueAPI) GetSigners(number *rpc.BlockNumber) []common.Address {
+	return []common.Address{testSigner}
+}
+
+func (api *testCliqueAPI) EpochPerformance(epoch, epochBlock uint64, limit *uint64) map[string]interface{} {
+	result := map[string]interface{}{
+		"sealerActivity": map[common.Address]int{testSigner: int(epoch)},
+		"startBlock":     epochBlock + 1,
+	}
+	if limit != nil {
+		result["next"] = epochBlock + 1 + *limit
+	}
+	return result
+}
+
+func (api *testCliqueAPI) VerifyHeaderSeal(blob hexutil.Bytes) map[string]interface{} {
+	return map[string]interface{}{"valid": len(blob) > 0, "difficulty": "0x2"}
+}
+
+// testAksAPI mimics the aks API.
+type testAksAPI struct{}
+
+func (api *testAksAPI) GetBlockIssuance(blockNrOrHash rpc.BlockNumberOrHash) map[string]interface{} {
+	number, _ := blockNrOrHash.Number()
+	return map[string]interface{}{"number": hexutil.Uint64(number), "burnt": "0x10"}
+}
+
+// testAdminAPI mimics the fork monitor API.
+type testAdminAPI struct{}
+
+func (api *testAdminAPI) ForkAlerts(ctx context.Context, threshold hexutil.Uint64, depth *hexutil.Uint64) (*rpc.Subscription, error) {
+	notifier, _ := rpc.NotifierFromContext(ctx)
+	sub := notifier.CreateSubscription()
+	go notifier.Notify(sub.ID, map[string]interface{}{"disagreeing": int(threshold)})
+	return sub, nil
+}
+
+func newTestClient(t *testing.T) *Client {
+	server := rpc.NewServer()
+	for namespace, service := range map[string]interface{}{"clique": new(testCliqueAPI), "aks": new(testAksAPI), "admin": new(testAdminAPI)} {
+		if err := server.RegisterName(namespace, service); err != nil {
+			t.Fatalf("failed to register %s service: %v", namespace, err)
+		}
+	}
+	t.Cleanup(server.Stop)
+	return New(rpc.DialInProc(server))
+}
+
+// Tests that the typed calls encode their arguments and decode the results.
+func TestClient(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+	ctx := context.Background()
+
+	snap, err := client.GetSnapshot(ctx, big.NewInt(42))
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	if snap.Number != 42 || snap.Epoch != 3 || !snap.Signers[testSigner] || snap.Recents[7] != testSigner {
+		t.Errorf("snapshot mismatch: %+v", snap)
+	}
+	signers, err := client.GetSigners(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve signers: %v", err)
+	}
+	if !reflect.DeepEqual(signers, []common.Address{testSigner}) {
+		t.Errorf("signers mismatch: have %v", signers)
+	}
+	perf, err := client.EpochPerformance(ctx, 5, 100, 0)
+	if err != nil {
+		t.Fatalf("failed to retrieve epoch performance: %v", err)
+	}
+	if perf.SigningStatus[testSigner] != 5 || perf.StartBlock != 101 || perf.Next != nil {
+		t.Errorf("epoch performance mismatch: %+v", perf)
+	}
+	if perf, err = client.EpochPerformance(ctx, 5, 100, 10); err != nil {
+		t.Fatalf("failed to retrieve limited epoch performance: %v", err)
+	}
+	if perf.Next == nil || *perf.Next != 111 {
+		t.Errorf("next block mismatch: have %v, want 111", perf.Next)
+	}
+	verdict, err := client.VerifyHeaderSeal(ctx, &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(2)})
+	if err != nil {
+		t.Fatalf("failed to verify header: %v", err)
+	}
+	if !verdict.Valid || verdict.Difficulty.ToInt().Uint64() != 2 {
+		t.Errorf("verdict mismatch: %+v", verdict)
+	}
+	issuance, err := client.GetBlockIssuance(ctx, big.NewInt(9))
+	if err != nil {
+		t.Fatalf("failed to retrieve issuance: %v", err)
+	}
+	if issuance.Number != 9 || issuance.Burnt.ToInt().Uint64() != 16 {
+		t.Errorf("issuance mismatch: %+v", issuance)
+	}
+}
+
+// Tests that fork alerts are delivered through the typed subscription.
+func TestSubscribeForkAlerts(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+
+	ch := make(chan *ForkStatus)
+	sub, err := client.SubscribeForkAlerts(context.Background(), ch, 2, 0)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	select {
+	case status := <-ch:
+		if status.Disagreeing != 2 {
+			t.Errorf("alert mismatch: have %d disagreeing, want 2", status.Disagreeing)
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(time.Second):
+		t.Fatalf("alert not delivered")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotedb
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	errNotFound = errors.New("not found")
+
+	overlayFetchMeter   = metrics.NewRegisteredMeter("remotedb/overlay/fetch", nil)
+	overlayFailureMeter = metrics.NewRegisteredMeter("remotedb/overlay/failure", nil)
+)
+
+// Overlay is a local database that lazily pulls missing state data from a
+// remote node. Only trie nodes and contract codes are retrieved remotely, as
+// they are content addressed and can be verified against their key; everything
+// else is served by the local database exclusively. Retrieved items are
+// persisted locally so every item is only fetched once.
+type Overlay struct {
+	ethdb.Database
+	remote *rpc.Client
+}
+
+// NewOverlay wraps the local database with lazy state retrieval from the remote
+// node at endpoint, which must expose the debug_dbGet method.
+func NewOverlay(db ethdb.Database, endpoint string) (*Overlay, error) {
+	client, err := dialRPC(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &Overlay{Database: db, remote: client}, nil
+}
+
+// Client returns the RPC client connected to the remote node.
+func (db *Overlay) Client() *rpc.Client {
+	return db.remote
+}
+
+// Has retrieves if a key is present in the local database, or for state data,
+// in the remote one.
+func (db *Overlay) Has(key []byte) (bool, error) {
+	if has, err := db.Database.Has(key); has || err != nil {
+		return has, err
+	}
+	if _, err := db.fetch(key); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// Get retrieves the given key from the local database, falling back to the
+// remote node for missing state data.
+func (db *Overlay) Get(key []byte) ([]byte, error) {
+	val, err := db.Database.Get(key)
+	if err == nil {
+		return val, nil
+	}
+	if val, ferr := db.fetch(key); ferr == nil {
+		return val, nil
+	}
+	return nil, err
+}
+
+// fetch retrieves a state item from the remote node, verifies it against its
+// hash and stores it in the local database.
+func (db *Overlay) fetch(key []byte) ([]byte, error) {
+	var hash []byte
+	if ok, h := rawdb.IsCodeKey(key); ok {
+		hash = h
+	} else if len(key) == common.HashLength {
+		hash = key
+	} else {
+		return nil, errNotFound
+	}
+	overlayFetchMeter.Mark(1)
+
+	var resp hexutil.Bytes
+	if err := db.remote.Call(&resp, "debug_dbGet", hexutil.Bytes(key)); err != nil {
+		overlayFailureMeter.Mark(1)
+		return nil, err
+	}
+	if len(resp) == 0 || common.BytesToHash(hash) != crypto.Keccak256Hash(resp) {
+		overlayFailureMeter.Mark(1)
+		log.Warn("Remote returned invalid state data", "key", hexutil.Bytes(key))
+		return nil, errNotFound
+	}
+	if err := db.Database.Put(key, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Close closes the connection to the remote node and the local database.
+func (db *Overlay) Close() error {
+	db.remote.Close()
+	return db.Database.Close()
+}
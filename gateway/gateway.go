@@ -0,0 +1,485 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package gateway implements a lightweight RPC gateway serving the JSON-RPC API
+// over HTTP by proxying the calls to a pool of upstream nodes. The upstreams are
+// health checked, clients are pinned to a single upstream so consecutive calls
+// observe a consistent chain head, and slow calls are hedged to a second one.
+// Calls are forwarded as they are, so the clique and other chain specific
+// namespaces are served like the standard ones.
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// maxRequestSize is the maximum accepted size of a request body, the same
+	// as the one of the node's HTTP-RPC server.
+	maxRequestSize = 5 * 1024 * 1024
+
+	// SessionHeader is the request header pinning the clients sharing its value
+	// to the same upstream. Without it, clients are pinned by their IP address.
+	SessionHeader = "X-Gateway-Session"
+
+	// upstreamHeader reports the index of the upstream which served a response.
+	upstreamHeader = "X-Gateway-Upstream"
+
+	// localNamespace is the namespace of the methods served by the gateway itself.
+	localNamespace = "gateway"
+)
+
+// Config contains the settings of the gateway.
+type Config struct {
+	Upstreams     []string      // HTTP-RPC endpoints of the upstream nodes
+	Modules       []string      // API namespaces forwarded to the upstreams, all if empty
+	CheckInterval time.Duration // Interval between two health checks of the upstreams
+	CheckTimeout  time.Duration // Time an upstream may take to answer a health check
+	MaxLag        uint64        // Blocks an upstream may trail the best one by while healthy
+	HedgeDelay    time.Duration // Time after which a call is also sent to another upstream, zero to disable
+	Timeout       time.Duration // Maximum time to wait for the upstreams to answer a call
+}
+
+// DefaultConfig contains the default gateway settings.
+var DefaultConfig = Config{
+	Modules:       []string{"eth", "net", "web3", "txpool", "clique", "aks"},
+	CheckInterval: 5 * time.Second,
+	CheckTimeout:  2 * time.Second,
+	MaxLag:        8,
+	HedgeDelay:    300 * time.Millisecond,
+	Timeout:       30 * time.Second,
+}
+
+// unhedgedMethods are the methods whose calls aren't worth duplicating, as a
+// second answer would only report the first one as already known.
+var unhedgedMethods = map[string]bool{
+	"eth_sendRawTransaction": true,
+	"eth_sendTransaction":    true,
+}
+
+var (
+	requestMeter  = metrics.NewRegisteredMeter("gateway/requests", nil)
+	hedgeMeter    = metrics.NewRegisteredMeter("gateway/hedged", nil)
+	failoverMeter = metrics.NewRegisteredMeter("gateway/failovers", nil)
+	failureMeter  = metrics.NewRegisteredMeter("gateway/failures", nil)
+)
+
+var errNoUpstream = errors.New("no upstream available")
+
+// Gateway is an http.Handler serving JSON-RPC calls from a pool of upstreams.
+type Gateway struct {
+	config    Config
+	modules   map[string]bool
+	upstreams []*upstream
+	client    *http.Client
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a gateway proxying to the configured upstreams. The upstreams are
+// only checked once the gateway is started.
+func New(config Config) (*Gateway, error) {
+	if len(config.Upstreams) == 0 {
+		return nil, errors.New("no upstreams configured")
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = DefaultConfig.CheckInterval
+	}
+	if config.CheckTimeout <= 0 {
+		config.CheckTimeout = DefaultConfig.CheckTimeout
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultConfig.Timeout
+	}
+	g := &Gateway{
+		config: config,
+		client: new(http.Client),
+		quit:   make(chan struct{}),
+	}
+	if len(config.Modules) > 0 {
+		g.modules = make(map[string]bool)
+		for _, module := range config.Modules {
+			g.modules[module] = true
+		}
+	}
+	for i, endpoint := range config.Upstreams {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid upstream %q: not an HTTP endpoint", endpoint)
+		}
+		g.upstreams = append(g.upstreams, &upstream{index: i, url: endpoint})
+	}
+	return g, nil
+}
+
+// Start checks the health of the upstreams and keeps checking them in the
+// background until the gateway is stopped.
+func (g *Gateway) Start() {
+	g.checkUpstreams()
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		ticker := time.NewTicker(g.config.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				g.checkUpstreams()
+			case <-g.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop terminates the health checks of the upstreams.
+func (g *Gateway) Stop() {
+	close(g.quit)
+	g.wg.Wait()
+}
+
+// rpcCall is the part of a JSON-RPC call the gateway looks at.
+type rpcCall struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+}
+
+// rpcError is a JSON-RPC error response.
+type rpcError struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// errorResponse creates a JSON-RPC error response to the call with the given id.
+func errorResponse(id json.RawMessage, code int, msg string) json.RawMessage {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	resp := rpcError{Version: "2.0", ID: id}
+	resp.Error.Code, resp.Error.Message = code, msg
+	blob, _ := json.Marshal(resp)
+	return blob
+}
+
+// resultResponse creates a JSON-RPC result response to the call with the given id.
+func resultResponse(id json.RawMessage, result interface{}) json.RawMessage {
+	blob, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(id, -32603, err.Error())
+	}
+	return json.RawMessage(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":%s}`, id, blob))
+}
+
+// ServeHTTP implements http.Handler, serving the calls of the gateway namespace
+// locally, refusing the ones of the namespaces not forwarded and proxying the
+// others to the upstream the client is pinned to.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	requestMeter.Mark(1)
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxRequestSize {
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	// Split the calls into the ones answered locally and the forwarded ones
+	var (
+		trimmed = bytes.TrimSpace(body)
+		batch   = len(trimmed) > 0 && trimmed[0] == '['
+		raws    []json.RawMessage
+	)
+	switch {
+	case !json.Valid(trimmed):
+		err = errors.New("invalid json")
+	case batch:
+		err = json.Unmarshal(trimmed, &raws)
+	default:
+		raws = []json.RawMessage{trimmed}
+	}
+	if err != nil {
+		g.reply(w, nil, errorResponse(nil, -32700, "parse error"))
+		return
+	}
+	if len(raws) == 0 {
+		g.reply(w, nil, errorResponse(nil, -32600, "empty batch"))
+		return
+	}
+	var (
+		local     []json.RawMessage
+		forwarded []json.RawMessage
+		hedge     = g.config.HedgeDelay > 0
+	)
+	for _, raw := range raws {
+		var call rpcCall
+		if err := json.Unmarshal(raw, &call); err != nil {
+			local = append(local, errorResponse(nil, -32600, "invalid request"))
+			continue
+		}
+		if resp := g.serveLocal(&call); resp != nil {
+			local = append(local, resp)
+			continue
+		}
+		if unhedgedMethods[call.Method] {
+			hedge = false
+		}
+		forwarded = append(forwarded, raw)
+	}
+	if len(forwarded) == 0 {
+		g.respond(w, nil, batch, local, nil)
+		return
+	}
+	if len(local) > 0 {
+		if batch {
+			body, _ = json.Marshal(forwarded)
+		} else {
+			body = forwarded[0]
+		}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), g.config.Timeout)
+	defer cancel()
+
+	resp, served, err := g.forward(ctx, g.order(sessionKey(r)), body, hedge)
+	if err != nil {
+		failureMeter.Mark(1)
+		log.Debug("Failed to forward RPC request", "err", err)
+		for _, raw := range forwarded {
+			var call rpcCall
+			json.Unmarshal(raw, &call)
+			local = append(local, errorResponse(call.ID, -32603, "upstream unavailable: "+err.Error()))
+		}
+		g.respond(w, nil, batch, local, nil)
+		return
+	}
+	g.respond(w, served, batch, local, resp)
+}
+
+// serveLocal answers a call locally if it's of the gateway namespace or of a
+// namespace not forwarded, returning nil for the calls to forward.
+func (g *Gateway) serveLocal(call *rpcCall) json.RawMessage {
+	namespace := call.Method
+	if i := strings.IndexByte(namespace, '_'); i >= 0 {
+		namespace = namespace[:i]
+	}
+	switch {
+	case call.Method == localNamespace+"_upstreams":
+		return resultResponse(call.ID, g.Status())
+	case namespace == localNamespace || (g.modules != nil && !g.modules[namespace]):
+		return errorResponse(call.ID, -32601, fmt.Sprintf("the method %s does not exist/is not available", call.Method))
+	}
+	return nil
+}
+
+// respond writes the local responses merged with the upstream one.
+func (g *Gateway) respond(w http.ResponseWriter, served *upstream, batch bool, local []json.RawMessage, upstream []byte) {
+	if !batch {
+		if upstream != nil {
+			g.reply(w, served, upstream)
+		} else {
+			g.reply(w, nil, local[0])
+		}
+		return
+	}
+	if len(local) == 0 {
+		g.reply(w, served, upstream)
+		return
+	}
+	var merged []json.RawMessage
+	if upstream != nil {
+		trimmed := bytes.TrimSpace(upstream)
+		if len(trimmed) > 0 && trimmed[0] == '[' {
+			if err := json.Unmarshal(trimmed, &merged); err != nil {
+				merged = nil
+			}
+		} else if len(trimmed) > 0 {
+			merged = append(merged, trimmed) // Error response to the whole batch
+		}
+	}
+	merged = append(merged, local...)
+	blob, _ := json.Marshal(merged)
+	g.reply(w, served, blob)
+}
+
+// reply writes a JSON response, tagged with the upstream which served it.
+func (g *Gateway) reply(w http.ResponseWriter, served *upstream, blob []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	if served != nil {
+		w.Header().Set(upstreamHeader, strconv.Itoa(served.index))
+	}
+	w.Write(blob)
+}
+
+// sessionKey returns the key a client is pinned to an upstream by.
+func sessionKey(r *http.Request) string {
+	if session := r.Header.Get(SessionHeader); session != "" {
+		return "session:" + session
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// order returns the upstreams in the order a client tries them in, the healthy
+// ones first. The order is the client's rendezvous hashing ranking, so it stays
+// pinned to the same upstream as long as that one is healthy, and clients are
+// spread evenly over the pool.
+func (g *Gateway) order(key string) []*upstream {
+	type ranked struct {
+		up      *upstream
+		healthy bool
+		score   uint64
+	}
+	ranking := make([]ranked, len(g.upstreams))
+	for i, up := range g.upstreams {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(up.url))
+		ranking[i] = ranked{up: up, healthy: up.status().Healthy, score: h.Sum64()}
+	}
+	sort.Slice(ranking, func(i, j int) bool {
+		if ranking[i].healthy != ranking[j].healthy {
+			return ranking[i].healthy
+		}
+		return ranking[i].score > ranking[j].score
+	})
+	order := make([]*upstream, len(ranking))
+	for i, r := range ranking {
+		order[i] = r.up
+	}
+	return order
+}
+
+// forwardResult is the outcome of forwarding a request to an upstream.
+type forwardResult struct {
+	up   *upstream
+	resp []byte
+	err  error
+}
+
+// forward sends a request to the first upstream of the order, failing over to
+// the next ones on errors. If hedging, the request is also sent to the next
+// upstream once the first didn't answer within the hedge delay, provided that
+// one isn't behind it, and the first answer is returned.
+func (g *Gateway) forward(ctx context.Context, order []*upstream, body []byte, hedge bool) ([]byte, *upstream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		results = make(chan forwardResult, len(order))
+		next    = 0
+		pending = 0
+		primary uint64
+		lastErr = errNoUpstream
+	)
+	launch := func() {
+		up := order[next]
+		next, pending = next+1, pending+1
+		go func() {
+			resp, err := g.post(ctx, up.url, body)
+			results <- forwardResult{up: up, resp: resp, err: err}
+		}()
+	}
+	launch()
+	primary = order[0].status().Head
+
+	var hedgeCh <-chan time.Time
+	if hedge && len(order) > 1 {
+		timer := time.NewTimer(g.config.HedgeDelay)
+		defer timer.Stop()
+		hedgeCh = timer.C
+	}
+	for pending > 0 {
+		select {
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				return res.resp, res.up, nil
+			}
+			lastErr = res.err
+			res.up.failed(res.err)
+			if pending == 0 && next < len(order) {
+				failoverMeter.Mark(1)
+				launch()
+			}
+		case <-hedgeCh:
+			hedgeCh = nil
+			if next < len(order) && order[next].status().Head >= primary {
+				hedgeMeter.Mark(1)
+				launch()
+			}
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+	return nil, nil, lastErr
+}
+
+// post sends a JSON-RPC request to an upstream.
+func (g *Gateway) post(ctx context.Context, endpoint string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resp, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream responded %s", res.Status)
+	}
+	return resp, nil
+}
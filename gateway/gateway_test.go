@@ -0,0 +1,189 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testUpstream is a fake node answering the health checks with its head and
+// every other call with its name.
+type testUpstream struct {
+	name  string
+	head  uint64
+	delay time.Duration
+	down  int32 // Answers with a server error if set (atomic)
+	calls int32 // Number of calls other than health checks (atomic)
+}
+
+func (u *testUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&u.down) != 0 {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+		return
+	}
+	var body json.RawMessage
+	json.NewDecoder(r.Body).Decode(&body)
+	if strings.Contains(string(body), "eth_blockNumber") {
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":"0x%x"}`, u.head)
+		return
+	}
+	atomic.AddInt32(&u.calls, 1)
+	time.Sleep(u.delay)
+	if strings.HasPrefix(string(body), "[") {
+		fmt.Fprintf(w, `[{"jsonrpc":"2.0","id":1,"result":%q}]`, u.name)
+		return
+	}
+	fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":%q}`, u.name)
+}
+
+// call sends a request through the gateway, returning the response and the
+// upstream that served it.
+func call(t *testing.T, g *Gateway, session, body string) (string, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(SessionHeader, session)
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+	return strings.TrimSpace(rec.Body.String()), rec.Header().Get(upstreamHeader)
+}
+
+func newTestGateway(t *testing.T, config Config, upstreams ...*testUpstream) *Gateway {
+	for _, u := range upstreams {
+		srv := httptest.NewServer(u)
+		t.Cleanup(srv.Close)
+		config.Upstreams = append(config.Upstreams, srv.URL)
+	}
+	g, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create gateway: %v", err)
+	}
+	g.checkUpstreams()
+	return g
+}
+
+// Tests that clients stay pinned to their upstream, fail over when it goes down
+// and avoid upstreams lagging behind.
+func TestPinningAndFailover(t *testing.T) {
+	var (
+		a      = &testUpstream{name: "a", head: 100}
+		b      = &testUpstream{name: "b", head: 100}
+		c      = &testUpstream{name: "c", head: 50}
+		config = DefaultConfig
+	)
+	config.HedgeDelay = 0
+	g := newTestGateway(t, config, a, b, c)
+
+	if status := g.Status(); !status[0].Healthy || !status[1].Healthy || status[2].Healthy {
+		t.Fatalf("upstream health mismatch: %+v", status)
+	}
+	served := make(map[string]int)
+	for i := 0; i < 32; i++ {
+		session := fmt.Sprintf("client-%d", i)
+		_, first := call(t, g, session, `{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["latest",false]}`)
+		for j := 0; j < 3; j++ {
+			if _, again := call(t, g, session, `{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`); again != first {
+				t.Fatalf("session %s moved from upstream %s to %s", session, first, again)
+			}
+		}
+		served[first]++
+	}
+	if served["2"] != 0 {
+		t.Errorf("lagging upstream served %d sessions", served["2"])
+	}
+	if served["0"] == 0 || served["1"] == 0 {
+		t.Errorf("sessions not spread over the healthy upstreams: %v", served)
+	}
+	// Take down the first upstream, its clients must fail over
+	atomic.StoreInt32(&a.down, 1)
+	for i := 0; i < 8; i++ {
+		resp, _ := call(t, g, fmt.Sprintf("client-%d", i), `{"jsonrpc":"2.0","id":1,"method":"clique_getSigners","params":[]}`)
+		if !strings.Contains(resp, `"result"`) || strings.Contains(resp, `"a"`) {
+			t.Errorf("call not failed over: %s", resp)
+		}
+	}
+}
+
+// Tests that slow calls are hedged to the next upstream.
+func TestHedging(t *testing.T) {
+	var (
+		slow   = &testUpstream{name: "slow", head: 100, delay: time.Second}
+		fast   = &testUpstream{name: "fast", head: 100}
+		config = DefaultConfig
+	)
+	config.HedgeDelay = 20 * time.Millisecond
+	g := newTestGateway(t, config, slow, fast)
+
+	// Find a session pinned to the slow upstream
+	var session string
+	for i := 0; session == ""; i++ {
+		if order := g.order("session:" + fmt.Sprint(i)); order[0].url == g.upstreams[0].url {
+			session = fmt.Sprint(i)
+		}
+	}
+	start := time.Now()
+	resp, _ := call(t, g, session, `{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":[]}`)
+	if !strings.Contains(resp, `"fast"`) {
+		t.Errorf("call not hedged: %s", resp)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("hedged call took %v", elapsed)
+	}
+	// Transactions are never duplicated
+	before := atomic.LoadInt32(&fast.calls)
+	resp, _ = call(t, g, session, `{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["0x00"]}`)
+	if !strings.Contains(resp, `"slow"`) || atomic.LoadInt32(&fast.calls) != before {
+		t.Errorf("transaction hedged: %s", resp)
+	}
+}
+
+// Tests that the calls of namespaces not forwarded are refused, and the ones of
+// the gateway namespace served locally, also within batches.
+func TestLocalCalls(t *testing.T) {
+	config := DefaultConfig
+	g := newTestGateway(t, config, &testUpstream{name: "a", head: 100})
+
+	resp, _ := call(t, g, "", `{"jsonrpc":"2.0","id":7,"method":"admin_peers","params":[]}`)
+	if !strings.Contains(resp, `"code":-32601`) || !strings.Contains(resp, `"id":7`) {
+		t.Errorf("admin call not refused: %s", resp)
+	}
+	resp, _ = call(t, g, "", `{"jsonrpc":"2.0","id":1,"method":"gateway_upstreams","params":[]}`)
+	var status struct {
+		Result []UpstreamStatus `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(resp), &status); err != nil || len(status.Result) != 1 || !status.Result[0].Healthy {
+		t.Errorf("upstream status mismatch: %s", resp)
+	}
+	resp, _ = call(t, g, "", `[{"jsonrpc":"2.0","id":1,"method":"eth_chainId"},{"jsonrpc":"2.0","id":2,"method":"debug_traceTransaction"}]`)
+	var batch []json.RawMessage
+	if err := json.Unmarshal([]byte(resp), &batch); err != nil || len(batch) != 2 {
+		t.Fatalf("batch response mismatch: %s", resp)
+	}
+	if !strings.Contains(string(batch[0]), `"a"`) || !strings.Contains(string(batch[1]), `-32601`) {
+		t.Errorf("batch responses mismatch: %s", resp)
+	}
+	if resp, _ = call(t, g, "", `{not json`); !strings.Contains(resp, `-32700`) {
+		t.Errorf("invalid request not rejected: %s", resp)
+	}
+}
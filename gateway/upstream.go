@@ -0,0 +1,169 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// healthCheck is the call the health of the upstreams is checked with.
+var healthCheck = []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+
+// UpstreamStatus is the health of an upstream as of its last check.
+type UpstreamStatus struct {
+	URL       string    `json:"url"`
+	Healthy   bool      `json:"healthy"`
+	Head      uint64    `json:"head"`            // Head block reported by the upstream
+	Latency   string    `json:"latency"`         // Time the upstream took to answer the last check
+	Failures  uint64    `json:"failures"`        // Number of failed checks and calls
+	LastCheck time.Time `json:"lastCheck"`       // Time of the last check
+	Error     string    `json:"error,omitempty"` // Error of the last check or call, if it failed
+}
+
+// upstream is a node of the pool the gateway forwards the calls to.
+type upstream struct {
+	index int
+	url   string
+
+	state UpstreamStatus
+	lock  sync.RWMutex
+}
+
+// status returns the health of the upstream.
+func (u *upstream) status() UpstreamStatus {
+	u.lock.RLock()
+	defer u.lock.RUnlock()
+
+	state := u.state
+	state.URL = u.url
+	return state
+}
+
+// failed marks the upstream unhealthy after a call failed, until the next check.
+func (u *upstream) failed(err error) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if u.state.Healthy {
+		log.Warn("Gateway upstream failed", "url", u.url, "err", err)
+	}
+	u.state.Healthy, u.state.Failures, u.state.Error = false, u.state.Failures+1, err.Error()
+}
+
+// check queries the head block of an upstream.
+func (g *Gateway) check(u *upstream) (uint64, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), g.config.CheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	blob, err := g.post(ctx, u.url, healthCheck)
+	if err != nil {
+		return 0, 0, err
+	}
+	var resp struct {
+		Result *hexutil.Uint64 `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(blob, &resp); err != nil {
+		return 0, 0, err
+	}
+	if resp.Error != nil {
+		return 0, 0, fmt.Errorf("health check failed: %s", resp.Error.Message)
+	}
+	if resp.Result == nil {
+		return 0, 0, errors.New("health check returned no head")
+	}
+	return uint64(*resp.Result), time.Since(start), nil
+}
+
+// checkUpstreams checks all the upstreams concurrently, marking the ones which
+// failed or trail the best one by more than the allowed lag unhealthy.
+func (g *Gateway) checkUpstreams() {
+	type outcome struct {
+		head    uint64
+		latency time.Duration
+		err     error
+	}
+	var (
+		outcomes = make([]outcome, len(g.upstreams))
+		wg       sync.WaitGroup
+	)
+	for i, u := range g.upstreams {
+		wg.Add(1)
+		go func(i int, u *upstream) {
+			defer wg.Done()
+			head, latency, err := g.check(u)
+			outcomes[i] = outcome{head, latency, err}
+		}(i, u)
+	}
+	wg.Wait()
+
+	var best uint64
+	for _, o := range outcomes {
+		if o.err == nil && o.head > best {
+			best = o.head
+		}
+	}
+	now := time.Now()
+	for i, u := range g.upstreams {
+		o := outcomes[i]
+
+		u.lock.Lock()
+		was := u.state.Healthy
+		u.state.LastCheck = now
+		switch {
+		case o.err != nil:
+			u.state.Healthy, u.state.Failures, u.state.Error = false, u.state.Failures+1, o.err.Error()
+		case o.head+g.config.MaxLag < best:
+			u.state.Healthy, u.state.Head, u.state.Error = false, o.head, fmt.Sprintf("head %d trails best %d", o.head, best)
+		default:
+			u.state.Healthy, u.state.Head, u.state.Error = true, o.head, ""
+		}
+		if o.err == nil {
+			u.state.Latency = o.latency.String()
+		}
+		healthy, reason := u.state.Healthy, u.state.Error
+		u.lock.Unlock()
+
+		if was != healthy {
+			if healthy {
+				log.Info("Gateway upstream healthy", "url", u.url, "head", o.head)
+			} else {
+				log.Warn("Gateway upstream unhealthy", "url", u.url, "reason", reason)
+			}
+		}
+	}
+}
+
+// Status returns the health of the upstreams.
+func (g *Gateway) Status() []UpstreamStatus {
+	status := make([]UpstreamStatus, len(g.upstreams))
+	for i, u := range g.upstreams {
+		status[i] = u.status()
+	}
+	return status
+}
@@ -32,9 +32,12 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -68,6 +71,13 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*hexutil.Big, error)
 	if head := s.b.CurrentHeader(); head.BaseFee != nil {
 		tipcap.Add(tipcap, head.BaseFee)
 	}
+	floor, ceil := s.b.RPCGasPriceBounds()
+	if floor != nil && tipcap.Cmp(floor) < 0 {
+		tipcap.Set(floor)
+	}
+	if ceil != nil && tipcap.Cmp(ceil) > 0 {
+		tipcap.Set(ceil)
+	}
 	return (*hexutil.Big)(tipcap), err
 }
 
@@ -736,6 +746,79 @@ func (s *PublicBlockChainAPI) GetHeaderByHash(ctx context.Context, hash common.H
 	return nil
 }
 
+// maxHeaderRange is the maximum number of headers returned by a single
+// eth_getHeadersByRange call.
+const maxHeaderRange = 10000
+
+// GetHeadersByRange returns the selected fields of the canonical headers from
+// and to the given block numbers inclusive, as one array of field values per
+// header in the order of the fields. The fields are named as in the result of
+// eth_getHeaderByNumber. The headers are read sequentially in one pass, without
+// the block bodies.
+func (s *PublicBlockChainAPI) GetHeadersByRange(ctx context.Context, from, to rpc.BlockNumber, fields []string) ([][]interface{}, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("no header fields selected")
+	}
+	sample := RPCMarshalHeader(&types.Header{Number: new(big.Int), Difficulty: new(big.Int), BaseFee: new(big.Int)})
+	for _, field := range fields {
+		if _, ok := sample[field]; !ok {
+			return nil, fmt.Errorf("unknown header field %q", field)
+		}
+	}
+	head := s.b.CurrentHeader().Number.Uint64()
+	resolve := func(number rpc.BlockNumber) (uint64, error) {
+		switch {
+		case number == rpc.LatestBlockNumber:
+			return head, nil
+		case number < 0:
+			return 0, fmt.Errorf("unsupported block number %d", number)
+		}
+		return uint64(number), nil
+	}
+	start, err := resolve(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := resolve(to)
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, fmt.Errorf("invalid range %d-%d", start, end)
+	}
+	if end-start >= maxHeaderRange {
+		return nil, fmt.Errorf("range of %d headers exceeds the limit of %d", end-start+1, maxHeaderRange)
+	}
+	if end > head {
+		end = head
+	}
+	if start > end {
+		return [][]interface{}{}, nil
+	}
+	var (
+		blobs  = rawdb.ReadCanonicalHeaderRange(s.b.ChainDb(), start, end-start+1)
+		result = make([][]interface{}, 0, len(blobs))
+	)
+	for _, blob := range blobs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		header := new(types.Header)
+		if err := rlp.DecodeBytes(blob, header); err != nil {
+			return nil, err
+		}
+		marshalled := RPCMarshalHeader(header)
+		marshalled["size"] = hexutil.Uint64(len(blob))
+
+		values := make([]interface{}, len(fields))
+		for i, field := range fields {
+			values[i] = marshalled[field]
+		}
+		result = append(result, values)
+	}
+	return result, nil
+}
+
 // GetBlockByNumber returns the requested canonical block.
 // * When blockNr is -1 the chain head is returned.
 // * When blockNr is -2 the pending chain head is returned.
@@ -888,6 +971,64 @@ func (diff *StateOverride) Apply(state *state.StateDB) error {
 	return nil
 }
 
+// BlockOverrides is a set of header fields to override when executing against
+// a block. If Next is set, the header is first derived as the child of the given
+// block: the number incremented, the timestamp advanced by the clique period (or
+// one second, if the chain has no fixed period) and the base fee recalculated.
+type BlockOverrides struct {
+	Next     bool            `json:"next"`
+	Number   *hexutil.Big    `json:"number"`
+	Time     *hexutil.Uint64 `json:"time"`
+	GasLimit *hexutil.Uint64 `json:"gasLimit"`
+	Coinbase *common.Address `json:"coinbase"`
+	BaseFee  *hexutil.Big    `json:"baseFee"`
+}
+
+// Apply returns a copy of the given header with the overrides applied.
+func (o *BlockOverrides) Apply(config *params.ChainConfig, header *types.Header) *types.Header {
+	if o == nil {
+		return header
+	}
+	header = types.CopyHeader(header)
+	if o.Next {
+		parent := header
+		header = &types.Header{
+			ParentHash: parent.Hash(),
+			Coinbase:   parent.Coinbase,
+			Difficulty: parent.Difficulty,
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit:   parent.GasLimit,
+			Time:       parent.Time + 1,
+		}
+		if config.Clique != nil {
+			// The in-turn validator seals the next block a full period later
+			header.Difficulty = big.NewInt(2)
+			if config.Clique.Period > 0 {
+				header.Time = parent.Time + config.Clique.Period
+			}
+		}
+		if config.IsLondon(header.Number) {
+			header.BaseFee = misc.CalcBaseFee(config, parent)
+		}
+	}
+	if o.Number != nil {
+		header.Number = o.Number.ToInt()
+	}
+	if o.Time != nil {
+		header.Time = uint64(*o.Time)
+	}
+	if o.GasLimit != nil {
+		header.GasLimit = uint64(*o.GasLimit)
+	}
+	if o.Coinbase != nil {
+		header.Coinbase = *o.Coinbase
+	}
+	if o.BaseFee != nil {
+		header.BaseFee = o.BaseFee.ToInt()
+	}
+	return header
+}
+
 func DoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
@@ -1191,6 +1332,7 @@ func RPCMarshalBlock(block *types.Block, inclTx bool, fullTx bool, config *param
 func (s *PublicBlockChainAPI) rpcMarshalHeader(ctx context.Context, header *types.Header) map[string]interface{} {
 	fields := RPCMarshalHeader(header)
 	fields["totalDifficulty"] = (*hexutil.Big)(s.b.GetTd(ctx, header.Hash()))
+	s.addCliqueFields(fields, header)
 	return fields
 }
 
@@ -1204,9 +1346,32 @@ func (s *PublicBlockChainAPI) rpcMarshalBlock(ctx context.Context, b *types.Bloc
 	if inclTx {
 		fields["totalDifficulty"] = (*hexutil.Big)(s.b.GetTd(ctx, b.Hash()))
 	}
+	s.addCliqueFields(fields, b.Header())
 	return fields, err
 }
 
+// addCliqueFields adds the sealer of a clique block and whether it sealed in
+// turn, if enabled. The miner field of clique blocks is always zero, the sealer
+// being only recoverable from the seal. Unsealed (pending) blocks are skipped.
+func (s *PublicBlockChainAPI) addCliqueFields(fields map[string]interface{}, header *types.Header) {
+	if !s.b.RPCCliqueFields() || header.Number.Sign() == 0 {
+		return
+	}
+	engine := s.b.Engine()
+	if b, ok := engine.(*beacon.Beacon); ok {
+		engine = b.InnerEngine()
+	}
+	if _, ok := engine.(*clique.Clique); !ok {
+		return
+	}
+	sealer, err := engine.Author(header)
+	if err != nil {
+		return
+	}
+	fields["sealer"] = sealer
+	fields["inTurn"] = clique.InTurn(header)
+}
+
 // RPCTransaction represents a transaction that will serialize to the RPC representation of a transaction
 type RPCTransaction struct {
 	BlockHash        *common.Hash      `json:"blockHash"`
@@ -1329,12 +1494,12 @@ type accessListResult struct {
 
 // CreateAccessList creates a EIP-2930 type AccessList for the given transaction.
 // Reexec and BlockNrOrHash can be specified to create the accessList on top of a certain state.
-func (s *PublicBlockChainAPI) CreateAccessList(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash) (*accessListResult, error) {
+func (s *PublicBlockChainAPI) CreateAccessList(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides) (*accessListResult, error) {
 	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
 	if blockNrOrHash != nil {
 		bNrOrHash = *blockNrOrHash
 	}
-	acl, gasUsed, vmerr, err := AccessList(ctx, s.b, bNrOrHash, args)
+	acl, gasUsed, vmerr, err := AccessList(ctx, s.b, bNrOrHash, args, overrides, blockOverrides)
 	if err != nil {
 		return nil, err
 	}
@@ -1348,12 +1513,19 @@ func (s *PublicBlockChainAPI) CreateAccessList(ctx context.Context, args Transac
 // AccessList creates an access list for the given transaction.
 // If the accesslist creation fails an error is returned.
 // If the transaction itself fails, an vmErr is returned.
-func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash, args TransactionArgs) (acl types.AccessList, gasUsed uint64, vmErr error, err error) {
+//
+// The state overrides are applied on top of the state of the given block, and
+// the block overrides can target the transaction at the block after it.
+func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash, args TransactionArgs, overrides *StateOverride, blockOverrides *BlockOverrides) (acl types.AccessList, gasUsed uint64, vmErr error, err error) {
 	// Retrieve the execution context
 	db, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if db == nil || err != nil {
 		return nil, 0, nil, err
 	}
+	if err := overrides.Apply(db); err != nil {
+		return nil, 0, nil, err
+	}
+	header = blockOverrides.Apply(b.ChainConfig(), header)
 	// If the gas amount is not set, extract this as it will depend on access
 	// lists and we'll need to reestimate every time
 	nogas := args.Gas == nil
@@ -1560,12 +1732,27 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	// Derive the sender.
 	bigblock := new(big.Int).SetUint64(blockNumber)
 	signer := types.MakeSigner(s.b.ChainConfig(), bigblock)
+
+	var baseFee *big.Int
+	if s.b.ChainConfig().IsLondon(bigblock) {
+		header, err := s.b.HeaderByHash(ctx, blockHash)
+		if err != nil {
+			return nil, err
+		}
+		baseFee = header.BaseFee
+	}
+	return marshalReceipt(receipt, blockHash, blockNumber, signer, tx, index, baseFee), nil
+}
+
+// marshalReceipt converts a transaction receipt into the JSON-RPC representation.
+// The base fee is nil for blocks before London.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, signer types.Signer, tx *types.Transaction, index uint64, baseFee *big.Int) map[string]interface{} {
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
+		"transactionHash":   tx.Hash(),
 		"transactionIndex":  hexutil.Uint64(index),
 		"from":              from,
 		"to":                tx.To(),
@@ -1577,14 +1764,10 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 		"type":              hexutil.Uint(tx.Type()),
 	}
 	// Assign the effective gas price paid
-	if !s.b.ChainConfig().IsLondon(bigblock) {
+	if baseFee == nil {
 		fields["effectiveGasPrice"] = hexutil.Uint64(tx.GasPrice().Uint64())
 	} else {
-		header, err := s.b.HeaderByHash(ctx, blockHash)
-		if err != nil {
-			return nil, err
-		}
-		gasPrice := new(big.Int).Add(header.BaseFee, tx.EffectiveGasTipValue(header.BaseFee))
+		gasPrice := new(big.Int).Add(baseFee, tx.EffectiveGasTipValue(baseFee))
 		fields["effectiveGasPrice"] = hexutil.Uint64(gasPrice.Uint64())
 	}
 	// Assign receipt status or post state.
@@ -1600,7 +1783,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -1954,9 +2137,11 @@ func (api *PrivateDebugAPI) ChaindbCompact() error {
 	return nil
 }
 
-// SetHead rewinds the head of the blockchain to a previous block.
-func (api *PrivateDebugAPI) SetHead(number hexutil.Uint64) {
-	api.b.SetHead(uint64(number))
+// SetHead rewinds the head of the blockchain to a previous block. Validators
+// refuse unless their sealing is paused, and rewinding past the last epoch block
+// requires force.
+func (api *PrivateDebugAPI) SetHead(number hexutil.Uint64, force *bool) error {
+	return api.b.SetHead(uint64(number), force != nil && *force)
 }
 
 // PublicNetAPI offers network related RPC methods
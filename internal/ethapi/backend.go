@@ -48,13 +48,15 @@ type Backend interface {
 	ChainDb() ethdb.Database
 	AccountManager() *accounts.Manager
 	ExtRPCEnabled() bool
-	RPCGasCap() uint64            // global gas cap for eth_call over rpc: DoS protection
-	RPCEVMTimeout() time.Duration // global timeout for eth_call over rpc: DoS protection
-	RPCTxFeeCap() float64         // global tx fee cap for all transaction related APIs
-	UnprotectedAllowed() bool     // allows only for EIP155 transactions.
+	RPCGasCap() uint64                         // global gas cap for eth_call over rpc: DoS protection
+	RPCEVMTimeout() time.Duration              // global timeout for eth_call over rpc: DoS protection
+	RPCTxFeeCap() float64                      // global tx fee cap for all transaction related APIs
+	RPCGasPriceBounds() (floor, ceil *big.Int) // bounds of the gas price suggested over rpc, nil if unbounded
+	RPCCliqueFields() bool                     // whether block responses carry the clique sealer and turn-ness
+	UnprotectedAllowed() bool                  // allows only for EIP155 transactions.
 
 	// Blockchain API
-	SetHead(number uint64)
+	SetHead(number uint64, force bool) error // force skips the safety checks which can be overridden
 	HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error)
 	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
 	HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error)
@@ -118,6 +120,11 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicTxPoolAPI(apiBackend),
 			Public:    true,
+		}, {
+			Namespace: "parity",
+			Version:   "1.0",
+			Service:   NewPublicParityAPI(apiBackend),
+			Public:    true,
 		}, {
 			Namespace: "debug",
 			Version:   "1.0",
@@ -0,0 +1,167 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxBundleCalls is the maximum number of calls simulated in a single bundle.
+const maxBundleCalls = 256
+
+// conditionalRejectedError is an API error returned if the preconditions of a
+// conditional transaction don't hold at submission.
+type conditionalRejectedError struct{ error }
+
+// ErrorCode returns the JSON error code for a rejected conditional transaction.
+func (e *conditionalRejectedError) ErrorCode() int {
+	return -32003
+}
+
+// SendRawTransactionConditional adds the signed transaction to the transaction
+// pool, to be included only in a block satisfying the given preconditions on
+// the block number, timestamp and storage of known accounts. The preconditions
+// are checked against the current head at submission and again by the miner
+// for every block the transaction is considered for. Transactions which can't
+// be satisfied by future blocks anymore are dropped from the pool.
+func (s *PublicTransactionPoolAPI) SendRawTransactionConditional(ctx context.Context, input hexutil.Bytes, cond types.TransactionConditional) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	if err := cond.Validate(); err != nil {
+		return common.Hash{}, err
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return common.Hash{}, err
+	}
+	if cond.Expired(header.Number, header.Time) {
+		return common.Hash{}, &conditionalRejectedError{errors.New("conditional expired")}
+	}
+	if err := core.CheckConditionalState(&cond, state); err != nil {
+		return common.Hash{}, &conditionalRejectedError{err}
+	}
+	tx.SetConditional(&cond)
+	return SubmitTransaction(ctx, s.b, tx)
+}
+
+// bundleCallResult is the outcome of a single call of a simulated bundle.
+type bundleCallResult struct {
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	Logs       []*types.Log   `json:"logs"`
+	Error      string         `json:"error,omitempty"`
+	Revert     hexutil.Bytes  `json:"revert,omitempty"`
+}
+
+// SimulateBundle executes a sequence of calls on top of each other on the state
+// of the given block (latest by default), returning the outcome and logs of each
+// call. It allows account abstraction bundlers to simulate handleOps bundles and
+// inspect the emitted UserOperation events before submitting them. Failing calls
+// don't abort the simulation, their state changes are reverted as usual.
+func (api *PublicDebugAPI) SimulateBundle(ctx context.Context, calls []TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride) ([]*bundleCallResult, error) {
+	if len(calls) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	if len(calls) > maxBundleCalls {
+		return nil, fmt.Errorf("too many calls in bundle, max %d", maxBundleCalls)
+	}
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	state, header, err := api.b.StateAndHeaderByNumberOrHash(ctx, bNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
+	var cancel context.CancelFunc
+	if timeout := api.b.RPCEVMTimeout(); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	var (
+		gp      = new(core.GasPool).AddGas(math.MaxUint64)
+		results = make([]*bundleCallResult, 0, len(calls))
+	)
+	for i, args := range calls {
+		msg, err := args.ToMessage(api.b.RPCGasCap(), header.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %v", i, err)
+		}
+		evm, vmError, err := api.b.GetEVM(ctx, msg, state, header, &vm.Config{NoBaseFee: true})
+		if err != nil {
+			return nil, err
+		}
+		// Logs are collected under a placeholder hash per call
+		txHash := common.BigToHash(big.NewInt(int64(i + 1)))
+		state.Prepare(txHash, i)
+
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		result, err := core.ApplyMessage(evm, msg, gp)
+		close(done)
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", api.b.RPCEVMTimeout())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w (supplied gas %d)", i, err, msg.Gas())
+		}
+		state.Finalise(true)
+
+		res := &bundleCallResult{
+			GasUsed:    hexutil.Uint64(result.UsedGas),
+			ReturnData: result.Return(),
+			Logs:       state.GetLogs(txHash, header.Hash()),
+		}
+		if res.Logs == nil {
+			res.Logs = []*types.Log{}
+		}
+		if result.Err != nil {
+			res.Error = result.Err.Error()
+			res.Revert = result.Revert()
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
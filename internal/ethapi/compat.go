@@ -0,0 +1,189 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// This file contains methods of other clients commonly relied upon by tooling,
+// so it can point at this node unchanged.
+
+// AccountInfo is the account object returned by eth_getAccount.
+type AccountInfo struct {
+	Balance     *hexutil.Big   `json:"balance"`
+	Nonce       hexutil.Uint64 `json:"nonce"`
+	CodeHash    common.Hash    `json:"codeHash"`
+	StorageRoot common.Hash    `json:"storageRoot"`
+}
+
+// GetAccount returns the balance, nonce, code hash and storage root of an
+// account, as implemented by Nethermind and Erigon. Missing accounts are
+// reported as empty.
+func (s *PublicBlockChainAPI) GetAccount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*AccountInfo, error) {
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	info := &AccountInfo{
+		Balance:     (*hexutil.Big)(state.GetBalance(address)),
+		Nonce:       hexutil.Uint64(state.GetNonce(address)),
+		CodeHash:    crypto.Keccak256Hash(nil),
+		StorageRoot: types.EmptyRootHash,
+	}
+	if storageTrie := state.StorageTrie(address); storageTrie != nil {
+		info.CodeHash = state.GetCodeHash(address)
+		info.StorageRoot = storageTrie.Hash()
+	}
+	return info, state.Error()
+}
+
+// PublicParityAPI implements the methods of the OpenEthereum parity namespace
+// still in common use.
+type PublicParityAPI struct {
+	b Backend
+}
+
+// NewPublicParityAPI creates a new parity compatibility API.
+func NewPublicParityAPI(b Backend) *PublicParityAPI {
+	return &PublicParityAPI{b}
+}
+
+// ParityValueFilter is an OpenEthereum filter on a numeric transaction field,
+// matching values equal to, greater than or lower than the given one.
+type ParityValueFilter struct {
+	Eq *hexutil.Big `json:"eq"`
+	Gt *hexutil.Big `json:"gt"`
+	Lt *hexutil.Big `json:"lt"`
+}
+
+// matches checks whether a value passes the filter.
+func (f *ParityValueFilter) matches(value *big.Int) bool {
+	if f == nil {
+		return true
+	}
+	if f.Eq != nil && value.Cmp(f.Eq.ToInt()) != 0 {
+		return false
+	}
+	if f.Gt != nil && value.Cmp(f.Gt.ToInt()) <= 0 {
+		return false
+	}
+	if f.Lt != nil && value.Cmp(f.Lt.ToInt()) >= 0 {
+		return false
+	}
+	return true
+}
+
+// ParityAddressFilter is an OpenEthereum filter on the sender or recipient of a
+// transaction. Recipients may also be filtered for contract creations.
+type ParityAddressFilter struct {
+	Eq     *common.Address `json:"eq"`
+	Action string          `json:"action"`
+}
+
+// matches checks whether an address passes the filter, nil denoting contract
+// creations.
+func (f *ParityAddressFilter) matches(addr *common.Address) bool {
+	switch {
+	case f == nil:
+		return true
+	case f.Action == "contract_creation":
+		return addr == nil
+	case f.Eq != nil:
+		return addr != nil && *addr == *f.Eq
+	}
+	return true
+}
+
+// ParityTransactionFilter is the filter accepted by parity_pendingTransactions.
+type ParityTransactionFilter struct {
+	From     *ParityAddressFilter `json:"from"`
+	To       *ParityAddressFilter `json:"to"`
+	Gas      *ParityValueFilter   `json:"gas"`
+	GasPrice *ParityValueFilter   `json:"gas_price"`
+	Value    *ParityValueFilter   `json:"value"`
+	Nonce    *ParityValueFilter   `json:"nonce"`
+}
+
+// PendingTransactions returns the executable transactions of the pool, up to
+// the limit if given, optionally filtered as parity_pendingTransactions does.
+func (s *PublicParityAPI) PendingTransactions(limit *hexutil.Uint64, filter *ParityTransactionFilter) ([]*RPCTransaction, error) {
+	if filter != nil && filter.From != nil && filter.From.Action != "" {
+		return nil, fmt.Errorf("unsupported sender filter action %q", filter.From.Action)
+	}
+	if filter != nil && filter.To != nil && filter.To.Action != "" && filter.To.Action != "contract_creation" {
+		return nil, fmt.Errorf("unsupported recipient filter action %q", filter.To.Action)
+	}
+	pending, err := s.b.GetPoolTransactions()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		config = s.b.ChainConfig()
+		head   = s.b.CurrentHeader()
+		signer = types.LatestSigner(config)
+	)
+	transactions := make([]*RPCTransaction, 0, len(pending))
+	for _, tx := range pending {
+		if limit != nil && uint64(len(transactions)) >= uint64(*limit) {
+			break
+		}
+		if filter != nil {
+			from, _ := types.Sender(signer, tx)
+			if !filter.From.matches(&from) || !filter.To.matches(tx.To()) ||
+				!filter.Gas.matches(new(big.Int).SetUint64(tx.Gas())) ||
+				!filter.GasPrice.matches(tx.GasPrice()) ||
+				!filter.Value.matches(tx.Value()) ||
+				!filter.Nonce.matches(new(big.Int).SetUint64(tx.Nonce())) {
+				continue
+			}
+		}
+		transactions = append(transactions, newRPCPendingTransaction(tx, head, config))
+	}
+	return transactions, nil
+}
+
+// GetBlockReceipts returns the receipts of all transactions in a block.
+func (s *PublicParityAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipts length mismatch: %d vs %d", len(receipts), len(txs))
+	}
+	signer := types.MakeSigner(s.b.ChainConfig(), block.Number())
+
+	result := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		result[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), signer, txs[i], uint64(i), block.BaseFee())
+	}
+	return result, nil
+}
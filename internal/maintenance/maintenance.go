@@ -0,0 +1,163 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package maintenance schedules the background state maintenance of the node,
+// snap sync healing and snapshot generation, so that it doesn't compete with
+// sealing for the disk: its writes are shaped to a bandwidth and IOPS budget and
+// held back while the local signer is in-turn.
+package maintenance
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"golang.org/x/time/rate"
+)
+
+// pollInterval is the interval the pause of the maintenance is re-evaluated at.
+const pollInterval = 100 * time.Millisecond
+
+var (
+	throttleTimer = metrics.NewRegisteredTimer("state/maintenance/throttle", nil)
+	pauseTimer    = metrics.NewRegisteredTimer("state/maintenance/pause", nil)
+)
+
+// Config are the scheduling limits of the background state maintenance.
+type Config struct {
+	Bandwidth    int           // Maximum bytes written per second, 0 = unlimited
+	IOPS         int           // Maximum database write batches per second, 0 = unlimited
+	InTurnWindow time.Duration // Time around the local signer's in-turn slots maintenance is paused for, 0 = never paused
+}
+
+// PauseFn returns how long the maintenance should hold off before writing, zero
+// if it may proceed.
+type PauseFn func() time.Duration
+
+// Scheduler shapes the writes of the background state maintenance.
+type Scheduler struct {
+	bandwidth *rate.Limiter // Byte budget of the writes, nil if unlimited
+	writes    *rate.Limiter // Batch budget of the writes, nil if unlimited
+
+	pause PauseFn
+	lock  sync.RWMutex
+}
+
+// NewScheduler creates a scheduler enforcing the given limits.
+func NewScheduler(config Config) *Scheduler {
+	s := new(Scheduler)
+	if config.Bandwidth > 0 {
+		s.bandwidth = rate.NewLimiter(rate.Limit(config.Bandwidth), config.Bandwidth)
+	}
+	if config.IOPS > 0 {
+		s.writes = rate.NewLimiter(rate.Limit(config.IOPS), config.IOPS)
+	}
+	return s
+}
+
+// SetPause sets the callback the scheduler consults before every write to see
+// whether the maintenance needs to be paused.
+func (s *Scheduler) SetPause(pause PauseFn) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.pause = pause
+}
+
+// Wait blocks until a batch of the given size may be written: until any pause
+// is over and the batch fits the budget. Batches larger than a second's worth
+// of bandwidth are spread over multiple seconds. It returns early with the
+// context's error if the context is cancelled meanwhile.
+func (s *Scheduler) Wait(ctx context.Context, size int) error {
+	s.lock.RLock()
+	pause := s.pause
+	s.lock.RUnlock()
+
+	if pause != nil {
+		var (
+			start  = time.Now()
+			paused bool
+		)
+		for delay := pause(); delay > 0; delay = pause() {
+			if delay > pollInterval {
+				delay = pollInterval
+			}
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+			paused = true
+		}
+		if paused {
+			pauseTimer.UpdateSince(start)
+		}
+	}
+	start := time.Now()
+	defer throttleTimer.UpdateSince(start)
+
+	if s.writes != nil {
+		if err := s.writes.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if s.bandwidth != nil {
+		for size > 0 {
+			chunk := size
+			if burst := s.bandwidth.Burst(); chunk > burst {
+				chunk = burst
+			}
+			if err := s.bandwidth.WaitN(ctx, chunk); err != nil {
+				return err
+			}
+			size -= chunk
+		}
+	}
+	return nil
+}
+
+// scheduler is the process wide scheduler of the state maintenance, nil if the
+// maintenance is unrestricted.
+var (
+	scheduler     *Scheduler
+	schedulerLock sync.RWMutex
+)
+
+// Install sets the scheduler all background state maintenance of the process
+// goes through, nil lifting all restrictions.
+func Install(s *Scheduler) {
+	schedulerLock.Lock()
+	defer schedulerLock.Unlock()
+
+	scheduler = s
+}
+
+// Wait blocks until the installed scheduler lets a batch of the given size be
+// written, returning immediately if none is installed, or early with the
+// context's error if it's cancelled meanwhile.
+func Wait(ctx context.Context, size int) error {
+	schedulerLock.RLock()
+	s := scheduler
+	schedulerLock.RUnlock()
+
+	if s != nil {
+		return s.Wait(ctx, size)
+	}
+	return nil
+}
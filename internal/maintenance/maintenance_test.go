@@ -0,0 +1,86 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package maintenance
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Tests that writes are spread out to the bandwidth budget, batches beyond the
+// burst included.
+func TestSchedulerBandwidth(t *testing.T) {
+	s := NewScheduler(Config{Bandwidth: 1000})
+
+	start := time.Now()
+	s.Wait(context.Background(), 1000) // Fits the initial burst
+	s.Wait(context.Background(), 2500) // Needs another two and a half seconds
+	if elapsed := time.Since(start); elapsed < 2*time.Second || elapsed > 4*time.Second {
+		t.Fatalf("shaped writes took %v, want about 2.5s", elapsed)
+	}
+}
+
+// Tests that writes are held back while paused and proceed once the pause is
+// lifted.
+func TestSchedulerPause(t *testing.T) {
+	var (
+		s     = NewScheduler(Config{})
+		until = time.Now().Add(300 * time.Millisecond)
+	)
+	s.SetPause(func() time.Duration {
+		return time.Until(until)
+	})
+	start := time.Now()
+	s.Wait(context.Background(), 1)
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatalf("paused write went through after %v", elapsed)
+	}
+	// Without an installed scheduler, nothing is held back
+	Install(nil)
+	start = time.Now()
+	Wait(context.Background(), 1<<30)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("unrestricted write took %v", elapsed)
+	}
+}
+
+// Tests that waits are cut short once their context is cancelled, be it while
+// paused or while throttled.
+func TestSchedulerCancel(t *testing.T) {
+	s := NewScheduler(Config{Bandwidth: 1000})
+	s.SetPause(func() time.Duration { return time.Hour })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := s.Wait(ctx, 1); err != context.DeadlineExceeded {
+		t.Fatalf("paused wait error mismatch: have %v, want %v", err, context.DeadlineExceeded)
+	}
+	s.SetPause(nil)
+	s.Wait(context.Background(), 1000) // Drain the burst
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	if err := s.Wait(ctx, 10000); err == nil {
+		t.Fatalf("cancelled throttled wait succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("cancelled throttled wait took %v", elapsed)
+	}
+}
@@ -18,18 +18,24 @@
 package web3ext
 
 var Modules = map[string]string{
-	"admin":    AdminJs,
-	"clique":   CliqueJs,
-	"ethash":   EthashJs,
-	"debug":    DebugJs,
-	"eth":      EthJs,
-	"miner":    MinerJs,
-	"net":      NetJs,
-	"personal": PersonalJs,
-	"rpc":      RpcJs,
-	"txpool":   TxpoolJs,
-	"les":      LESJs,
-	"vflux":    VfluxJs,
+	"admin":     AdminJs,
+	"aks":       AksJs,
+	"clique":    CliqueJs,
+	"ethash":    EthashJs,
+	"debug":     DebugJs,
+	"eth":       EthJs,
+	"exex":      ExExJs,
+	"miner":     MinerJs,
+	"net":       NetJs,
+	"parity":    ParityJs,
+	"personal":  PersonalJs,
+	"publisher": PublisherJs,
+	"rpc":       RpcJs,
+	"txpool":    TxpoolJs,
+	"txmgr":     TxmgrJs,
+	"dev":       DevJs,
+	"les":       LESJs,
+	"vflux":     VfluxJs,
 }
 
 const CliqueJs = `
@@ -47,6 +53,50 @@ web3._extend({
 			call: 'clique_getSnapshotAtHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getSnapshotRaw',
+			call: 'clique_getSnapshotRaw',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'verifySnapshotCommitment',
+			call: 'clique_verifySnapshotCommitment',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getBridgeEpoch',
+			call: 'clique_getBridgeEpoch',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getVanity',
+			call: 'clique_getVanity',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'crossCheckSnapshot',
+			call: 'clique_crossCheckSnapshot',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getNetworkVersions',
+			call: 'clique_getNetworkVersions',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getPerformanceProof',
+			call: 'clique_getPerformanceProof',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getAuthorizationHistory',
+			call: 'clique_getAuthorizationHistory',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getSigners',
 			call: 'clique_getSigners',
@@ -58,6 +108,12 @@ web3._extend({
 			call: 'clique_getSignersAtHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getRecents',
+			call: 'clique_getRecents',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'propose',
 			call: 'clique_propose',
@@ -73,12 +129,96 @@ web3._extend({
 			call: 'clique_status',
 			params: 0
 		}),
+		new web3._extend.Method({
+			name: 'slasherStat',
+			call: 'clique_slasherStat',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'epochPerformance',
+			call: 'clique_epochPerformance',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'epochPerformanceRange',
+			call: 'clique_epochPerformanceRange',
+			params: 4
+		}),
+		new web3._extend.Method({
+			name: 'estimateEpochEnd',
+			call: 'clique_estimateEpochEnd',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'epochSchedule',
+			call: 'clique_epochSchedule',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'health',
+			call: 'clique_health'
+		}),
+		new web3._extend.Method({
+			name: 'setSigner',
+			call: 'clique_setSigner',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'pauseSealing',
+			call: 'clique_pauseSealing',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'resumeSealing',
+			call: 'clique_resumeSealing'
+		}),
+		new web3._extend.Method({
+			name: 'getSealingLog',
+			call: 'clique_getSealingLog'
+		}),
+		new web3._extend.Method({
+			name: 'skewedSigners',
+			call: 'clique_skewedSigners',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'getSignerPolicy',
+			call: 'clique_getSignerPolicy'
+		}),
+		new web3._extend.Method({
+			name: 'getAnomalies',
+			call: 'clique_getAnomalies',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getSigner',
 			call: 'clique_getSigner',
 			params: 1,
 			inputFormatter: [null]
 		}),
+		new web3._extend.Method({
+			name: 'verifyHeaderSeal',
+			call: 'clique_verifyHeaderSeal',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getDutySchedule',
+			call: 'clique_getDutySchedule',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'simulateOutage',
+			call: 'clique_simulateOutage',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getVoteLatencyStats',
+			call: 'clique_getVoteLatencyStats'
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -136,11 +276,22 @@ web3._extend({
 			call: 'admin_addTrustedPeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'forkStatus',
+			call: 'admin_forkStatus',
+			params: 1,
+			inputFormatter: [null]
+		}),
 		new web3._extend.Method({
 			name: 'removeTrustedPeer',
 			call: 'admin_removeTrustedPeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'setNetRules',
+			call: 'admin_setNetRules',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -152,6 +303,25 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'enterMaintenance',
+			call: 'admin_enterMaintenance',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'exitMaintenance',
+			call: 'admin_exitMaintenance'
+		}),
+		new web3._extend.Method({
+			name: 'forkId',
+			call: 'admin_forkId'
+		}),
+		new web3._extend.Method({
+			name: 'signIdentity',
+			call: 'admin_signIdentity',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -199,6 +369,10 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'netRules',
+			getter: 'admin_netRules'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
@@ -238,10 +412,17 @@ web3._extend({
 			call: 'debug_getRawReceipts',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'simulateBundle',
+			call: 'debug_simulateBundle',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'setHead',
 			call: 'debug_setHead',
-			params: 1
+			params: 2,
+			inputFormatter: [null, null]
 		}),
 		new web3._extend.Method({
 			name: 'seedHash',
@@ -425,6 +606,11 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'traceCacheStats',
+			call: 'debug_traceCacheStats',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'traceCall',
 			call: 'debug_traceCall',
@@ -442,11 +628,27 @@ web3._extend({
 			call: 'debug_getBadBlocks',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'getRejectedHeaders',
+			call: 'debug_getRejectedHeaders',
+			params: 0,
+		}),
+		new web3._extend.Method({
+			name: 'getAuditLog',
+			call: 'debug_getAuditLog',
+			params: 0,
+		}),
 		new web3._extend.Method({
 			name: 'storageRangeAt',
 			call: 'debug_storageRangeAt',
 			params: 5,
 		}),
+		new web3._extend.Method({
+			name: 'getStorageKeys',
+			call: 'debug_getStorageKeys',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, null, null]
+		}),
 		new web3._extend.Method({
 			name: 'getModifiedAccountsByNumber',
 			call: 'debug_getModifiedAccountsByNumber',
@@ -464,6 +666,20 @@ web3._extend({
 			call: 'debug_freezeClient',
 			params: 1,
 		}),
+		new web3._extend.Method({
+			name: 'cacheConfig',
+			call: 'debug_cacheConfig',
+		}),
+		new web3._extend.Method({
+			name: 'setCacheConfig',
+			call: 'debug_setCacheConfig',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'propagationStats',
+			call: 'debug_propagationStats',
+			params: 1,
+		}),
 		new web3._extend.Method({
 			name: 'getAccessibleState',
 			call: 'debug_getAccessibleState',
@@ -511,6 +727,11 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
 		}),
+		new web3._extend.Method({
+			name: 'sendRawTransactionConditional',
+			call: 'eth_sendRawTransactionConditional',
+			params: 2
+		}),
 		new web3._extend.Method({
 			name: 'signTransaction',
 			call: 'eth_signTransaction',
@@ -547,6 +768,12 @@ web3._extend({
 			call: 'eth_getHeaderByHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getHeadersByRange',
+			call: 'eth_getHeadersByRange',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'getBlockByNumber',
 			call: 'eth_getBlockByNumber',
@@ -572,17 +799,45 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
 		}),
+		new web3._extend.Method({
+			name: 'createStateSession',
+			call: 'eth_createStateSession',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'releaseStateSession',
+			call: 'eth_releaseStateSession',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'syncProgressDetailed',
+			call: 'eth_syncProgressDetailed',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getPendingBlockDetailed',
+			call: 'eth_getPendingBlockDetailed',
+			params: 1,
+			inputFormatter: [function (val) { return !!val; }]
+		}),
 		new web3._extend.Method({
 			name: 'getProof',
 			call: 'eth_getProof',
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'getAccount',
+			call: 'eth_getAccount',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'createAccessList',
 			call: 'eth_createAccessList',
-			params: 2,
-			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter],
+			params: 4,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null, null],
 		}),
 		new web3._extend.Method({
 			name: 'feeHistory',
@@ -664,6 +919,10 @@ web3._extend({
 			name: 'getHashrate',
 			call: 'miner_getHashrate'
 		}),
+		new web3._extend.Method({
+			name: 'sponsorship',
+			call: 'miner_sponsorship'
+		}),
 	],
 	properties: []
 });
@@ -867,3 +1126,210 @@ web3._extend({
 	]
 });
 `
+
+const TxmgrJs = `
+web3._extend({
+	property: 'txmgr',
+	methods: [
+		new web3._extend.Method({
+			name: 'transaction',
+			call: 'txmgr_transaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'untrack',
+			call: 'txmgr_untrack',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'rebroadcast',
+			call: 'txmgr_rebroadcast',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'bump',
+			call: 'txmgr_bump',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setPolicy',
+			call: 'txmgr_setPolicy',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'reserveNonceRange',
+			call: 'txmgr_reserveNonceRange',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'renewNonceRange',
+			call: 'txmgr_renewNonceRange',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'releaseNonceRange',
+			call: 'txmgr_releaseNonceRange',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'nonceLeases',
+			call: 'txmgr_nonceLeases',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'transactions',
+			getter: 'txmgr_transactions'
+		}),
+		new web3._extend.Property({
+			name: 'policy',
+			getter: 'txmgr_policy'
+		}),
+	]
+});
+`
+
+const AksJs = `
+web3._extend({
+	property: 'aks',
+	methods: [
+		new web3._extend.Method({
+			name: 'contractStats',
+			call: 'aks_contractStats',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getEpochStats',
+			call: 'aks_getEpochStats',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getBlockIssuance',
+			call: 'aks_getBlockIssuance',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getValidatorEarnings',
+			call: 'aks_getValidatorEarnings',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'simulateFeeMarket',
+			call: 'aks_simulateFeeMarket',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'auditBlockOrdering',
+			call: 'aks_auditBlockOrdering',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'verifyBlockOrdering',
+			call: 'aks_verifyBlockOrdering',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+	]
+});
+`
+
+const ExExJs = `
+web3._extend({
+	property: 'exex',
+	methods: [
+		new web3._extend.Method({
+			name: 'remove',
+			call: 'exex_remove',
+			params: 1
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'extensions',
+			getter: 'exex_extensions'
+		}),
+	]
+});
+`
+
+const PublisherJs = `
+web3._extend({
+	property: 'publisher',
+	methods: [
+		new web3._extend.Method({
+			name: 'replay',
+			call: 'publisher_replay',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'status',
+			getter: 'publisher_status'
+		}),
+	]
+});
+`
+
+const ParityJs = `
+web3._extend({
+	property: 'parity',
+	methods: [
+		new web3._extend.Method({
+			name: 'pendingTransactions',
+			call: 'parity_pendingTransactions',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getBlockReceipts',
+			call: 'parity_getBlockReceipts',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+	]
+});
+`
+
+const DevJs = `
+web3._extend({
+	property: 'dev',
+	methods: [
+		new web3._extend.Method({
+			name: 'mine',
+			call: 'dev_mine',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal],
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'setNextBlockTimestamp',
+			call: 'dev_setNextBlockTimestamp',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'increaseTime',
+			call: 'dev_increaseTime',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal],
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'mineToNextEpoch',
+			call: 'dev_mineToNextEpoch',
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+	]
+});
+`
@@ -43,17 +43,31 @@ type Backend interface {
 	StateAtBlock(block *types.Block, reexec uint64, base *state.StateDB, checkLive bool, preferDisk bool) (statedb *state.StateDB, err error)
 }
 
+// DeterministicOrderingVersion is the version of the deterministic transaction
+// ordering announced in the vanity of the blocks built with it: by effective
+// tip, then nonce, then hash, without prioritizing local transactions.
+const DeterministicOrderingVersion = 1
+
 // Config is the configuration parameters of mining.
 type Config struct {
 	Etherbase  common.Address `toml:",omitempty"` // Public address for block mining rewards (default = first account)
 	Notify     []string       `toml:",omitempty"` // HTTP URL list to be notified of new work packages (only useful in ethash).
 	NotifyFull bool           `toml:",omitempty"` // Notify with pending block headers instead of work packages
 	ExtraData  hexutil.Bytes  `toml:",omitempty"` // Block extra data set by the miner
+	Vanity     bool           // Announce the client in the structured clique vanity instead of the extra data
+	VanityTag  string         `toml:",omitempty"` // Operator tag announced in the structured clique vanity
+	GitCommit  string         `toml:"-"`          // Git commit of the build, announced in the structured clique vanity
 	GasFloor   uint64         // Target gas floor for mined blocks.
 	GasCeil    uint64         // Target gas ceiling for mined blocks.
 	GasPrice   *big.Int       // Minimum gas price for mining a transaction
 	Recommit   time.Duration  // The time interval for miner to re-create mining work.
 	Noverify   bool           // Disable remote mining solution verification(only useful in ethash).
+
+	DeterministicOrdering bool // Order transactions only by price, nonce and hash, committing to it in the vanity
+	Prefetch              bool // Warm the trie nodes of the pending transactions between sealing slots (clique only)
+
+	WiggleMin time.Duration `toml:",omitempty"` // Lower bound of the out-of-turn wiggle adapted to the network latency (clique only)
+	WiggleMax time.Duration `toml:",omitempty"` // Upper bound of the adaptive out-of-turn wiggle, none if zero (clique only)
 }
 
 // Miner creates blocks and searches for proof-of-work values.
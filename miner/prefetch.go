@@ -0,0 +1,231 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// prefetchInterval is the minimum time between two prefetches of the pending
+	// transactions, batching up the ones arriving in the meantime.
+	prefetchInterval = 100 * time.Millisecond
+
+	// accessListCacheSize is the number of pending transactions whose access lists
+	// are kept to warm their state again on top of new heads.
+	accessListCacheSize = 8192
+)
+
+var (
+	prefetchTimer          = metrics.NewRegisteredTimer("miner/prefetch/executes", nil)
+	prefetchTxMeter        = metrics.NewRegisteredMeter("miner/prefetch/txs", nil)
+	prefetchInterruptMeter = metrics.NewRegisteredMeter("miner/prefetch/interrupts", nil)
+
+	prefetchListTimer = metrics.NewRegisteredTimer("miner/prefetch/accesslist/reads", nil)
+	prefetchListMeter = metrics.NewRegisteredMeter("miner/prefetch/accesslist/txs", nil)
+	prefetchHitMeter  = metrics.NewRegisteredMeter("miner/prefetch/accesslist/hit", nil)
+	prefetchMissMeter = metrics.NewRegisteredMeter("miner/prefetch/accesslist/miss", nil)
+)
+
+// prefetchLoop is a standalone goroutine which, between the sealing slots of a
+// clique validator, speculatively runs the pending transactions expected in the
+// next block on top of the current head. With short periods cold trie reads are
+// the main cause of blocks sealed late, warming the trie nodes the next block
+// touches ahead of time takes them off the critical path. The transactions are
+// run as they arrive, each one once while it's pending. The state they access is
+// cached as access lists, read again on top of every new head without running
+// them to keep it warm until the slot of the validator arrives.
+func (w *worker) prefetchLoop() {
+	defer w.wg.Done()
+
+	var (
+		txsCh   = make(chan core.NewTxsEvent, txChanSize)
+		headCh  = make(chan core.ChainHeadEvent, chainHeadChanSize)
+		txsSub  = w.eth.TxPool().SubscribeNewTxsEvent(txsCh)
+		headSub = w.chain.SubscribeChainHeadEvent(headCh)
+		ticker  = time.NewTicker(prefetchInterval)
+
+		dirty     bool                                // Whether transactions arrived since the last prefetch
+		warmed    = make(map[common.Hash]common.Hash) // Transactions already warmed, mapped to the head they were warmed on
+		interrupt *uint32                             // Interrupt flag of the running prefetch, nil if none
+		done      chan map[common.Hash]common.Hash    // Channel delivering the warmed transactions of the running prefetch
+	)
+	defer txsSub.Unsubscribe()
+	defer headSub.Unsubscribe()
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-txsCh:
+			dirty = true
+
+		case <-headCh:
+			// The running prefetch is on top of a stale head, cut it short. The
+			// transactions warmed so far are mostly still warm, keep them and read
+			// their access lists again on top of the new head.
+			if interrupt != nil {
+				atomic.StoreUint32(interrupt, 1)
+				prefetchInterruptMeter.Mark(1)
+			}
+			dirty = true
+
+		case <-ticker.C:
+			if !dirty || done != nil || !w.isRunning() {
+				continue
+			}
+			interrupt, done, dirty = new(uint32), make(chan map[common.Hash]common.Hash, 1), false
+			go func(warmed map[common.Hash]common.Hash, interrupt *uint32, done chan map[common.Hash]common.Hash) {
+				done <- w.prefetchPending(warmed, interrupt)
+			}(warmed, interrupt, done)
+
+		case warmed = <-done:
+			interrupt, done = nil, nil
+
+		case <-w.exitCh:
+			if interrupt != nil {
+				atomic.StoreUint32(interrupt, 1)
+				<-done
+			}
+			return
+		case <-txsSub.Err():
+			return
+		case <-headSub.Err():
+			return
+		}
+	}
+}
+
+// prefetchPending runs the pending transactions expected in the next block which
+// weren't warmed yet on top of the current head, caching the state they access.
+// The ones warmed on top of an earlier head have their cached state read again
+// instead. It returns the warmed transactions still pending.
+func (w *worker) prefetchPending(warmed map[common.Hash]common.Hash, interrupt *uint32) map[common.Hash]common.Hash {
+	parent := w.chain.CurrentBlock()
+
+	w.mu.RLock()
+	coinbase := w.coinbase
+	w.mu.RUnlock()
+
+	num := parent.Number()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     num.Add(num, common.Big1),
+		GasLimit:   core.CalcGasLimit(parent.GasLimit(), w.config.GasCeil),
+		Time:       parent.Time() + w.chainConfig.Clique.Period,
+		Coinbase:   coinbase,
+		Difficulty: new(big.Int).Set(parent.Difficulty()),
+	}
+	if w.chainConfig.IsLondon(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(w.chainConfig, parent.Header())
+		if !w.chainConfig.IsLondon(parent.Number()) {
+			header.GasLimit = core.CalcGasLimit(parent.GasLimit()*params.ElasticityMultiplier, w.config.GasCeil)
+		}
+	}
+	// Pick the transactions the next block is expected to hold, the same way the
+	// worker will, skipping the ones already warmed
+	var (
+		pending = w.eth.TxPool().Pending(true)
+		txs     = types.NewTransactionsByPriceAndNonce(types.MakeSigner(w.chainConfig, header.Number), pending, header.BaseFee)
+		next    = make(map[common.Hash]common.Hash)
+		batch   []*types.Transaction
+		stale   []common.Hash      // Transactions warmed on top of an earlier head
+		lists   []types.AccessList // Cached access lists of the stale transactions
+		gas     uint64
+	)
+	for tx := txs.Peek(); tx != nil; tx = txs.Peek() {
+		if gas+tx.Gas() > header.GasLimit {
+			txs.Pop()
+			continue
+		}
+		gas += tx.Gas()
+
+		hash := tx.Hash()
+		if head, ok := warmed[hash]; !ok {
+			batch = append(batch, tx)
+		} else if list, cached := w.accessLists.Get(hash); cached && head != parent.Hash() {
+			stale, lists = append(stale, hash), append(lists, list.(types.AccessList))
+		} else {
+			next[hash] = head
+		}
+		txs.Shift()
+	}
+	if len(lists) > 0 {
+		start := time.Now()
+		read, err := w.chain.PrefetchAccessLists(header, lists, interrupt)
+		if err != nil {
+			log.Debug("Failed to prefetch access lists", "err", err)
+			return next
+		}
+		prefetchListTimer.UpdateSince(start)
+		prefetchListMeter.Mark(int64(read))
+
+		// Access lists cut short by an interrupt are read again on the next prefetch
+		for i, hash := range stale {
+			if i < read {
+				next[hash] = parent.Hash()
+			} else {
+				next[hash] = warmed[hash]
+			}
+		}
+	}
+	if len(batch) == 0 {
+		return next
+	}
+	start := time.Now()
+	ran, err := w.chain.PrefetchTransactions(header, batch, interrupt)
+	if err != nil {
+		log.Debug("Failed to prefetch pending transactions", "err", err)
+		return next
+	}
+	prefetchTimer.UpdateSince(start)
+	prefetchTxMeter.Mark(int64(len(ran)))
+
+	for hash, list := range ran {
+		w.accessLists.Add(hash, list)
+	}
+	// Transactions cut short by an interrupt are run again on the next prefetch
+	if atomic.LoadUint32(interrupt) == 0 {
+		for _, tx := range batch {
+			next[tx.Hash()] = parent.Hash()
+		}
+	}
+	log.Trace("Prefetched pending transactions", "number", header.Number, "txs", len(ran), "lists", len(lists), "elapsed", common.PrettyDuration(time.Since(start)))
+	return next
+}
+
+// markPrefetched records whether a transaction committed while sealing had its
+// state prefetched, tracking the hit rate of the prefetches.
+func (w *worker) markPrefetched(tx *types.Transaction) {
+	if w.accessLists == nil || !w.isRunning() {
+		return
+	}
+	if w.accessLists.Contains(tx.Hash()) {
+		prefetchHitMeter.Mark(1)
+	} else {
+		prefetchMissMeter.Mark(1)
+	}
+}
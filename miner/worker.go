@@ -35,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 const (
@@ -230,6 +231,8 @@ type worker struct {
 	snapshotReceipts types.Receipts
 	snapshotState    *state.StateDB
 
+	accessLists *lru.Cache // Access lists of the prefetched pending transactions, nil if not prefetching
+
 	// atomic status counters
 	running int32 // The indicator whether the consensus engine is running or not.
 	newTxs  int32 // New arrival transaction count since last sealing work submitting.
@@ -295,6 +298,16 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 	go worker.resultLoop()
 	go worker.taskLoop()
 
+	if config.Prefetch {
+		if chainConfig.Clique != nil {
+			worker.accessLists, _ = lru.New(accessListCacheSize)
+			worker.wg.Add(1)
+			go worker.prefetchLoop()
+		} else {
+			log.Warn("Pending transaction prefetching is only supported by clique")
+		}
+	}
+
 	// Submit first work to initialize pending state.
 	if init {
 		worker.startCh <- struct{}{}
@@ -739,6 +752,9 @@ func (w *worker) resultLoop() {
 			log.Info("Successfully sealed new block", "number", block.Number(), "sealhash", sealhash, "hash", hash,
 				"elapsed", common.PrettyDuration(time.Since(task.createdAt)))
 
+			// Account the sponsored transactions included against the sponsor budget
+			w.eth.TxPool().ChargeSponsored(block, receipts)
+
 			// Broadcast the block and announce chain insertion event
 			w.mux.Post(core.NewMinedBlockEvent{Block: block})
 
@@ -816,18 +832,20 @@ func (w *worker) commitUncle(env *environment, uncle *types.Header) error {
 	return nil
 }
 
-// updateSnapshot updates pending snapshot block, receipts and state.
+// updateSnapshot updates pending snapshot block, receipts and state. The block
+// is assembled by the consensus engine like a sealing candidate, so that pending
+// is the same whether the node is sealing or not.
+// Note the assumption is held that the mutation is allowed to the passed env.
 func (w *worker) updateSnapshot(env *environment) {
+	block, err := w.engine.FinalizeAndAssemble(w.chain, env.header, env.state, env.txs, env.unclelist(), env.receipts)
+	if err != nil {
+		log.Warn("Failed to assemble pending block", "number", env.header.Number, "err", err)
+		block = types.NewBlock(env.header, env.txs, env.unclelist(), env.receipts, trie.NewStackTrie(nil))
+	}
 	w.snapshotMu.Lock()
 	defer w.snapshotMu.Unlock()
 
-	w.snapshotBlock = types.NewBlock(
-		env.header,
-		env.txs,
-		env.unclelist(),
-		env.receipts,
-		trie.NewStackTrie(nil),
-	)
+	w.snapshotBlock = block
 	w.snapshotReceipts = copyReceipts(env.receipts)
 	w.snapshotState = env.state.Copy()
 }
@@ -898,6 +916,19 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			txs.Pop()
 			continue
 		}
+		// Skip the sender if the preconditions of a conditional transaction
+		// don't hold on top of the transactions included so far.
+		if cond := tx.Conditional(); cond != nil {
+			err := cond.CheckBlock(env.header.Number, env.header.Time)
+			if err == nil {
+				err = core.CheckConditionalState(cond, env.state)
+			}
+			if err != nil {
+				log.Trace("Skipping conditional transaction", "hash", tx.Hash(), "err", err)
+				txs.Pop()
+				continue
+			}
+		}
 		// Start executing the transaction
 		env.state.Prepare(tx.Hash(), env.tcount)
 
@@ -923,6 +954,7 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			coalescedLogs = append(coalescedLogs, logs...)
 			env.tcount++
 			txs.Shift()
+			w.markPrefetched(tx)
 
 		case errors.Is(err, core.ErrTxTypeNotSupported):
 			// Pop the unsupported transaction without shifting in the next from the account
@@ -1061,6 +1093,14 @@ func (w *worker) fillTransactions(interrupt *int32, env *environment) error {
 	// Split the pending transactions into locals and remotes
 	// Fill the block with all available pending transactions.
 	pending := w.eth.TxPool().Pending(true)
+	if w.config.DeterministicOrdering {
+		// Order all of them the same way regardless of origin and arrival
+		if len(pending) > 0 {
+			txs := types.NewTransactionsByPriceNonceAndHash(env.signer, pending, env.header.BaseFee)
+			return w.commitTransactions(env, txs, interrupt)
+		}
+		return nil
+	}
 	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
 	for _, account := range w.eth.TxPool().Locals() {
 		if txs := remoteTxs[account]; len(txs) > 0 {
@@ -125,6 +125,29 @@ func (api *privateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
 	return true, nil
 }
 
+// SetNetRules replaces the rules allowing or denying connections by IP network,
+// country, autonomous system and node ID, disconnecting the peers they deny.
+func (api *privateAdminAPI) SetNetRules(rules p2p.NetRules) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	if err := server.SetNetRules(rules); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// NetRules returns the rules gating the connections of the node.
+func (api *privateAdminAPI) NetRules() (p2p.NetRules, error) {
+	server := api.node.Server()
+	if server == nil {
+		return p2p.NetRules{}, ErrNodeStopped
+	}
+	return server.CurrentNetRules(), nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *privateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
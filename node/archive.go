@@ -0,0 +1,156 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// archiveProvenance is reported in the responses served by the archive node.
+const archiveProvenance = "archive"
+
+// DefaultArchiveMethods are the methods reading historical state whose calls
+// are offloaded to the archive node by default.
+var DefaultArchiveMethods = []string{
+	"eth_call", "eth_estimateGas", "eth_createAccessList", "eth_getProof",
+	"eth_getBalance", "eth_getCode", "eth_getStorageAt", "eth_getTransactionCount",
+	"debug_traceCall", "debug_traceTransaction", "debug_traceBlockByNumber", "debug_traceBlockByHash",
+}
+
+var (
+	archiveOffloadMeter = metrics.NewRegisteredMeter("rpc/archive/offloaded", nil)
+	archiveFailureMeter = metrics.NewRegisteredMeter("rpc/archive/failures", nil)
+)
+
+// archiveFallback forwards the calls failing locally for missing historical
+// state, as on pruned nodes, to an archive node.
+type archiveFallback struct {
+	endpoint string   // RPC endpoint of the archive node
+	methods  []string // Methods whose calls are offloaded
+
+	client *rpc.Client // Connection to the archive node, dialed on first use
+	lock   sync.Mutex
+}
+
+// newArchiveFallback creates a fallback of the given methods to the archive node
+// at the endpoint.
+func newArchiveFallback(endpoint string, methods []string) *archiveFallback {
+	if len(methods) == 0 {
+		methods = DefaultArchiveMethods
+	}
+	return &archiveFallback{endpoint: endpoint, methods: methods}
+}
+
+// isMissingState returns whether an error is caused by the state of the block
+// requested not being available locally.
+func isMissingState(err error) bool {
+	var missing *trie.MissingNodeError
+	if errors.As(err, &missing) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "missing trie node") || strings.Contains(msg, "historical state unavailable")
+}
+
+// call is the rpc.FallbackFn forwarding the calls failing for missing state to
+// the archive node, keeping the local error of the others.
+func (a *archiveFallback) call(ctx context.Context, method string, params json.RawMessage, err error) (json.RawMessage, string, error) {
+	if !isMissingState(err) {
+		return nil, "", err
+	}
+	var args []json.RawMessage
+	if len(params) > 0 {
+		if perr := json.Unmarshal(params, &args); perr != nil {
+			return nil, "", err
+		}
+	}
+	client, derr := a.dial(ctx)
+	if derr != nil {
+		log.Warn("Failed to connect to archive node", "err", derr)
+		archiveFailureMeter.Mark(1)
+		return nil, "", err
+	}
+	var (
+		result json.RawMessage
+		iargs  = make([]interface{}, len(args))
+	)
+	for i, arg := range args {
+		iargs[i] = arg
+	}
+	if cerr := client.CallContext(ctx, &result, method, iargs...); cerr != nil {
+		// Relay the errors of the archive node itself, fall back to the local
+		// error if it couldn't be reached
+		var rerr rpc.Error
+		if errors.As(cerr, &rerr) {
+			archiveOffloadMeter.Mark(1)
+			return nil, archiveProvenance, cerr
+		}
+		log.Debug("Failed to offload call to archive node", "method", method, "err", cerr)
+		archiveFailureMeter.Mark(1)
+		a.reset(client)
+		return nil, "", err
+	}
+	archiveOffloadMeter.Mark(1)
+	return result, archiveProvenance, nil
+}
+
+// dial returns the connection to the archive node, establishing it if needed.
+func (a *archiveFallback) dial(ctx context.Context) (*rpc.Client, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.client == nil {
+		client, err := rpc.DialContext(ctx, a.endpoint)
+		if err != nil {
+			return nil, err
+		}
+		a.client = client
+	}
+	return a.client, nil
+}
+
+// reset drops a failed connection to the archive node, to be dialed again on
+// the next call.
+func (a *archiveFallback) reset(client *rpc.Client) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.client == client {
+		a.client.Close()
+		a.client = nil
+	}
+}
+
+// close drops the connection to the archive node.
+func (a *archiveFallback) close() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.client != nil {
+		a.client.Close()
+		a.client = nil
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that only the calls failing for missing historical state are forwarded
+// to the archive node.
+func TestArchiveFallbackMissingState(t *testing.T) {
+	missing := &trie.MissingNodeError{NodeHash: common.Hash{0x01}}
+	if !isMissingState(missing) || !isMissingState(fmt.Errorf("wrapped: %w", missing)) {
+		t.Fatalf("missing trie node not detected")
+	}
+	if !isMissingState(errors.New("required historical state unavailable (reexec=128)")) {
+		t.Fatalf("unavailable historical state not detected")
+	}
+	local := errors.New("execution reverted")
+	if isMissingState(local) {
+		t.Fatalf("unrelated error detected as missing state")
+	}
+	// Other errors are left alone without contacting the archive node
+	archive := newArchiveFallback("http://127.0.0.1:0", nil)
+	if _, provenance, err := archive.call(context.Background(), "eth_call", nil, local); err != local || provenance != "" {
+		t.Fatalf("unrelated error offloaded: %v (%q)", err, provenance)
+	}
+	if len(archive.methods) != len(DefaultArchiveMethods) {
+		t.Fatalf("default methods not offloaded")
+	}
+}
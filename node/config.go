@@ -138,6 +138,11 @@ type Config struct {
 	// HTTPPathPrefix specifies a path prefix on which http-rpc is to be served.
 	HTTPPathPrefix string `toml:",omitempty"`
 
+	// HTTPCompressionThreshold is the minimum size in bytes of http-rpc responses
+	// gzipped for clients accepting it. Zero compresses all responses, negative
+	// values disable compression.
+	HTTPCompressionThreshold int `toml:",omitempty"`
+
 	// AuthAddr is the listening address on which authenticated APIs are provided.
 	AuthAddr string `toml:",omitempty"`
 
@@ -165,6 +170,14 @@ type Config struct {
 	// cannot verify the validity of the request header.
 	WSOrigins []string `toml:",omitempty"`
 
+	// WSCompression enables the permessage-deflate extension for websocket clients
+	// negotiating it.
+	WSCompression bool `toml:",omitempty"`
+
+	// WSCompressionThreshold is the minimum size in bytes of websocket messages
+	// compressed when permessage-deflate is negotiated.
+	WSCompressionThreshold int `toml:",omitempty"`
+
 	// WSModules is a list of API modules to expose via the websocket RPC interface.
 	// If the module list is empty, all RPC API endpoints designated public will be
 	// exposed.
@@ -177,6 +190,25 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// RPCSignedMethods is a list of methods whose responses over HTTP and websocket
+	// carry a signature by the node key, letting clients detect responses tampered
+	// with by proxies. The authenticated endpoints never sign responses.
+	RPCSignedMethods []string `toml:",omitempty"`
+
+	// ArchiveEndpoint is the RPC endpoint of an archive node to which the calls of
+	// the ArchiveMethods over HTTP and websocket failing for missing historical
+	// state are forwarded. Their responses are marked with their provenance.
+	ArchiveEndpoint string `toml:",omitempty"`
+
+	// ArchiveMethods is the list of methods offloaded to the archive node, the
+	// DefaultArchiveMethods if empty.
+	ArchiveMethods []string `toml:",omitempty"`
+
+	// RPCQuotas caps the CPU share of the calls of namespaces over HTTP and
+	// websocket, so that heavy calls like tracing don't degrade the latency of
+	// the others. The quotas are shared by the endpoints.
+	RPCQuotas map[string]rpc.Quota `toml:",omitempty"`
+
 	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
@@ -48,17 +48,18 @@ var (
 
 // DefaultConfig contains reasonable default settings.
 var DefaultConfig = Config{
-	DataDir:             DefaultDataDir(),
-	HTTPPort:            DefaultHTTPPort,
-	AuthAddr:            DefaultAuthHost,
-	AuthPort:            DefaultAuthPort,
-	AuthVirtualHosts:    DefaultAuthVhosts,
-	HTTPModules:         []string{"net", "web3"},
-	HTTPVirtualHosts:    []string{"localhost"},
-	HTTPTimeouts:        rpc.DefaultHTTPTimeouts,
-	WSPort:              DefaultWSPort,
-	WSModules:           []string{"net", "web3"},
-	GraphQLVirtualHosts: []string{"localhost"},
+	DataDir:                DefaultDataDir(),
+	HTTPPort:               DefaultHTTPPort,
+	AuthAddr:               DefaultAuthHost,
+	AuthPort:               DefaultAuthPort,
+	AuthVirtualHosts:       DefaultAuthVhosts,
+	HTTPModules:            []string{"net", "web3"},
+	HTTPVirtualHosts:       []string{"localhost"},
+	HTTPTimeouts:           rpc.DefaultHTTPTimeouts,
+	WSPort:                 DefaultWSPort,
+	WSModules:              []string{"net", "web3"},
+	WSCompressionThreshold: 1024,
+	GraphQLVirtualHosts:    []string{"localhost"},
 	P2P: p2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   50,
@@ -0,0 +1,104 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package embed
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// CliqueStatus is the sealing activity of the recent blocks.
+type CliqueStatus struct {
+	InturnPercent float64                `json:"inturnPercent"`
+	SigningStatus map[common.Address]int `json:"sealerActivity"`
+	NumBlocks     uint64                 `json:"numBlocks"`
+}
+
+// CliqueClient is a typed client of the clique API. It works over any RPC
+// connection, not only the in-process one of embedded nodes.
+type CliqueClient struct {
+	c *rpc.Client
+}
+
+// NewCliqueClient creates a clique API client on an RPC connection.
+func NewCliqueClient(c *rpc.Client) *CliqueClient {
+	return &CliqueClient{c}
+}
+
+// GetSnapshot retrieves the clique snapshot at a block, the latest if number
+// is nil.
+func (cc *CliqueClient) GetSnapshot(ctx context.Context, number *big.Int) (*clique.Snapshot, error) {
+	var snap *clique.Snapshot
+	if err := cc.c.CallContext(ctx, &snap, "clique_getSnapshot", toBlockNumArg(number)); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// GetSnapshotAtHash retrieves the clique snapshot at a block hash.
+func (cc *CliqueClient) GetSnapshotAtHash(ctx context.Context, hash common.Hash) (*clique.Snapshot, error) {
+	var snap *clique.Snapshot
+	if err := cc.c.CallContext(ctx, &snap, "clique_getSnapshotAtHash", hash); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// GetSigners retrieves the authorized signers at a block, the latest if number
+// is nil.
+func (cc *CliqueClient) GetSigners(ctx context.Context, number *big.Int) ([]common.Address, error) {
+	var signers []common.Address
+	err := cc.c.CallContext(ctx, &signers, "clique_getSigners", toBlockNumArg(number))
+	return signers, err
+}
+
+// GetSignersAtHash retrieves the authorized signers at a block hash.
+func (cc *CliqueClient) GetSignersAtHash(ctx context.Context, hash common.Hash) ([]common.Address, error) {
+	var signers []common.Address
+	err := cc.c.CallContext(ctx, &signers, "clique_getSignersAtHash", hash)
+	return signers, err
+}
+
+// GetSigner retrieves the sealer of a block, the latest if number is nil.
+func (cc *CliqueClient) GetSigner(ctx context.Context, number *big.Int) (common.Address, error) {
+	var signer common.Address
+	err := cc.c.CallContext(ctx, &signer, "clique_getSigner", toBlockNumArg(number))
+	return signer, err
+}
+
+// Status retrieves the sealing activity of the recent blocks.
+func (cc *CliqueClient) Status(ctx context.Context) (*CliqueStatus, error) {
+	var status *CliqueStatus
+	if err := cc.c.CallContext(ctx, &status, "clique_status"); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// toBlockNumArg encodes a block number as an RPC argument, nil denoting the
+// latest block.
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(number)
+}
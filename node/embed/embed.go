@@ -0,0 +1,285 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package embed runs a full node in-process, for test harnesses and products
+// embedding the chain. Nodes are configured through functional options and
+// expose typed handles to the blockchain and the clique API.
+package embed
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// config is the configuration assembled by the options.
+type config struct {
+	node   node.Config
+	eth    ethconfig.Config
+	signer *ecdsa.PrivateKey
+}
+
+// Option configures an embedded node.
+type Option func(*config) error
+
+// WithDataDir stores the node's data in the given directory. Without it, the
+// chain is kept in memory and lost on close.
+func WithDataDir(dir string) Option {
+	return func(c *config) error {
+		c.node.DataDir = dir
+		return nil
+	}
+}
+
+// WithGenesis initializes the chain with the given genesis, which must match
+// the one of an existing data directory.
+func WithGenesis(genesis *core.Genesis) Option {
+	return func(c *config) error {
+		if genesis == nil || genesis.Config == nil {
+			return errors.New("genesis without chain config")
+		}
+		c.eth.Genesis = genesis
+		c.eth.NetworkId = genesis.Config.ChainID.Uint64()
+		return nil
+	}
+}
+
+// WithSigner seals blocks with the given key once the node is started, which
+// must belong to an authorized clique signer to produce blocks.
+func WithSigner(key *ecdsa.PrivateKey) Option {
+	return func(c *config) error {
+		if key == nil {
+			return errors.New("nil signer key")
+		}
+		c.signer = key
+		return nil
+	}
+}
+
+// WithHTTP serves HTTP-RPC on the given host and port. The RPC modules exposed
+// are set with WithRPCModules.
+func WithHTTP(host string, port int) Option {
+	return func(c *config) error {
+		c.node.HTTPHost, c.node.HTTPPort = host, port
+		return nil
+	}
+}
+
+// WithWS serves WS-RPC on the given host and port. The RPC modules exposed are
+// set with WithRPCModules.
+func WithWS(host string, port int) Option {
+	return func(c *config) error {
+		c.node.WSHost, c.node.WSPort = host, port
+		return nil
+	}
+}
+
+// WithRPCModules sets the API modules exposed over HTTP and WS. The in-process
+// client has access to all of them regardless.
+func WithRPCModules(modules ...string) Option {
+	return func(c *config) error {
+		c.node.HTTPModules = modules
+		c.node.WSModules = modules
+		return nil
+	}
+}
+
+// WithP2P sets the address listened on for peers and the maximum number of
+// them, along with the nodes to bootstrap from. Without it, the node runs
+// isolated.
+func WithP2P(listenAddr string, maxPeers int, bootnodes ...string) Option {
+	return func(c *config) error {
+		c.node.P2P.ListenAddr = listenAddr
+		c.node.P2P.MaxPeers = maxPeers
+		c.node.P2P.NoDiscovery = len(bootnodes) == 0
+		for _, url := range bootnodes {
+			n, err := enode.Parse(enode.ValidSchemes, url)
+			if err != nil {
+				return err
+			}
+			c.node.P2P.BootstrapNodes = append(c.node.P2P.BootstrapNodes, n)
+		}
+		return nil
+	}
+}
+
+// WithNodeConfig applies arbitrary changes to the node configuration, for the
+// settings not covered by the other options.
+func WithNodeConfig(fn func(*node.Config)) Option {
+	return func(c *config) error {
+		fn(&c.node)
+		return nil
+	}
+}
+
+// WithEthConfig applies arbitrary changes to the protocol configuration, for
+// the settings not covered by the other options.
+func WithEthConfig(fn func(*ethconfig.Config)) Option {
+	return func(c *config) error {
+		fn(&c.eth)
+		return nil
+	}
+}
+
+// Node is a node running in-process.
+type Node struct {
+	stack  *node.Node
+	eth    *eth.Ethereum
+	client *rpc.Client
+	signer *ecdsa.PrivateKey
+	keydir string // Temporary keystore holding the signer key
+}
+
+// New creates an embedded node. It has to be started before use.
+func New(opts ...Option) (*Node, error) {
+	cfg := &config{
+		node: node.DefaultConfig,
+		eth:  ethconfig.Defaults,
+	}
+	cfg.node.Name = "geth" // Keep the data directory layout of geth
+	cfg.node.DataDir = ""
+	cfg.node.P2P = p2p.Config{
+		ListenAddr:  "",
+		NoDiscovery: true,
+		MaxPeers:    0,
+	}
+	for _, opt := range opts {
+		if err := opt(cfg); err != nil {
+			return nil, err
+		}
+	}
+	stack, err := node.New(&cfg.node)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := eth.New(stack, &cfg.eth)
+	if err != nil {
+		stack.Close()
+		return nil, err
+	}
+	stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
+
+	return &Node{stack: stack, eth: backend, signer: cfg.signer}, nil
+}
+
+// Start starts the node and, if a signer is configured, block sealing.
+func (n *Node) Start() error {
+	if n.signer != nil {
+		if err := n.addSigner(); err != nil {
+			return err
+		}
+	}
+	if err := n.stack.Start(); err != nil {
+		return err
+	}
+	client, err := n.stack.Attach()
+	if err != nil {
+		return err
+	}
+	n.client = client
+
+	if n.signer != nil {
+		n.eth.SetEtherbase(crypto.PubkeyToAddress(n.signer.PublicKey))
+		if err := n.eth.StartMining(0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addSigner makes the signer key available to the miner through a temporary
+// keystore, removed when the node is closed. The key is never stored in the
+// node's data directory.
+func (n *Node) addSigner() error {
+	keydir, err := os.MkdirTemp("", "embed-signer-")
+	if err != nil {
+		return err
+	}
+	n.keydir = keydir
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	passphrase := hex.EncodeToString(secret)
+
+	ks := keystore.NewKeyStore(keydir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.ImportECDSA(n.signer, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := ks.Unlock(account, passphrase); err != nil {
+		return err
+	}
+	n.stack.AccountManager().AddBackend(ks)
+	return nil
+}
+
+// Close stops the node and releases its resources.
+func (n *Node) Close() error {
+	if n.client != nil {
+		n.client.Close()
+	}
+	err := n.stack.Close()
+	if n.keydir != "" {
+		os.RemoveAll(n.keydir)
+	}
+	return err
+}
+
+// Stack returns the underlying protocol stack.
+func (n *Node) Stack() *node.Node { return n.stack }
+
+// Ethereum returns the protocol service of the node.
+func (n *Node) Ethereum() *eth.Ethereum { return n.eth }
+
+// BlockChain returns the chain of the node.
+func (n *Node) BlockChain() *core.BlockChain { return n.eth.BlockChain() }
+
+// Signer returns the address of the configured signer, or the zero address.
+func (n *Node) Signer() common.Address {
+	if n.signer == nil {
+		return common.Address{}
+	}
+	return crypto.PubkeyToAddress(n.signer.PublicKey)
+}
+
+// RPC returns the in-process RPC client of a started node, with access to all
+// API modules.
+func (n *Node) RPC() *rpc.Client { return n.client }
+
+// Client returns an Ethereum client of a started node over the in-process RPC
+// connection.
+func (n *Node) Client() *ethclient.Client { return ethclient.NewClient(n.client) }
+
+// Clique returns a typed client of the clique API of a started node over the
+// in-process RPC connection.
+func (n *Node) Clique() *CliqueClient { return NewCliqueClient(n.client) }
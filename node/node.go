@@ -63,6 +63,12 @@ type Node struct {
 	ipc           *ipcServer  // Stores information about the ipc http server
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
 
+	archive *archiveFallback // Offloading of historical state calls, nil if disabled
+	quotas  *rpc.Quotas      // Resource quotas of the namespaces, nil if unlimited
+
+	flushers []flusher      // State persisted ahead of the rest of the shutdown
+	shutdown *shutdownState // Shutdown in progress, nil if not shutting down via Shutdown
+
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
 
@@ -111,6 +117,20 @@ func New(conf *Config) (*Node, error) {
 		databases:     make(map[*closeTrackingDB]struct{}),
 	}
 
+	if conf.ArchiveEndpoint != "" {
+		node.archive = newArchiveFallback(conf.ArchiveEndpoint, conf.ArchiveMethods)
+		node.log.Info("Offloading historical state calls to archive node", "methods", len(node.archive.methods))
+	}
+
+	if len(conf.RPCQuotas) > 0 {
+		quotas, err := rpc.NewQuotas(conf.RPCQuotas)
+		if err != nil {
+			return nil, err
+		}
+		node.quotas = quotas
+		node.log.Info("Enforcing RPC namespace quotas", "namespaces", len(conf.RPCQuotas))
+	}
+
 	// Register built-in APIs.
 	node.rpcAPIs = append(node.rpcAPIs, node.apis()...)
 
@@ -251,7 +271,12 @@ func (n *Node) doClose(errs []error) error {
 	// Release instance directory lock.
 	n.closeDataDir()
 
-	// Unblock n.Wait.
+	// Report the outcome of the shutdown, then unblock n.Wait.
+	if len(errs) == 0 {
+		n.reportShutdown(ShutdownClean, "")
+	} else {
+		n.reportShutdown(ShutdownDegraded, "")
+	}
 	close(n.stop)
 
 	// Report any errors that might have occurred.
@@ -404,6 +429,11 @@ func (n *Node) startRPC() error {
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
 			prefix:             n.config.HTTPPathPrefix,
+			compressThreshold:  n.config.HTTPCompressionThreshold,
+			signedMethods:      n.config.RPCSignedMethods,
+			signKey:            n.server.PrivateKey,
+			archive:            n.archive,
+			quotas:             n.quotas,
 		}); err != nil {
 			return err
 		}
@@ -417,9 +447,15 @@ func (n *Node) startRPC() error {
 			return err
 		}
 		if err := server.enableWS(n.rpcAPIs, wsConfig{
-			Modules: n.config.WSModules,
-			Origins: n.config.WSOrigins,
-			prefix:  n.config.WSPathPrefix,
+			Modules:           n.config.WSModules,
+			Origins:           n.config.WSOrigins,
+			prefix:            n.config.WSPathPrefix,
+			compression:       n.config.WSCompression,
+			compressThreshold: n.config.WSCompressionThreshold,
+			signedMethods:     n.config.RPCSignedMethods,
+			signKey:           n.server.PrivateKey,
+			archive:           n.archive,
+			quotas:            n.quotas,
 		}); err != nil {
 			return err
 		}
@@ -428,6 +464,8 @@ func (n *Node) startRPC() error {
 	}
 
 	initAuth := func(apis []rpc.API, port int, secret []byte) error {
+		modules := authModules(apis)
+
 		// Enable auth via HTTP
 		server := n.httpAuth
 		if err := server.setListenAddr(n.config.AuthAddr, port); err != nil {
@@ -436,7 +474,7 @@ func (n *Node) startRPC() error {
 		if err := server.enableRPC(apis, httpConfig{
 			CorsAllowedOrigins: DefaultAuthCors,
 			Vhosts:             n.config.AuthVirtualHosts,
-			Modules:            DefaultAuthModules,
+			Modules:            modules,
 			prefix:             DefaultAuthPrefix,
 			jwtSecret:          secret,
 		}); err != nil {
@@ -449,7 +487,7 @@ func (n *Node) startRPC() error {
 			return err
 		}
 		if err := server.enableWS(apis, wsConfig{
-			Modules:   DefaultAuthModules,
+			Modules:   modules,
 			Origins:   DefaultAuthOrigins,
 			prefix:    DefaultAuthPrefix,
 			jwtSecret: secret,
@@ -511,6 +549,9 @@ func (n *Node) stopRPC() {
 	n.wsAuth.stop()
 	n.ipc.stop()
 	n.stopInProc()
+	if n.archive != nil {
+		n.archive.close()
+	}
 }
 
 // startInProc registers all RPC APIs on the inproc server.
@@ -558,6 +599,24 @@ func (n *Node) RegisterProtocols(protocols []p2p.Protocol) {
 	n.server.Protocols = append(n.server.Protocols, protocols...)
 }
 
+// authModules returns the modules served on the authenticated endpoint: the
+// default ones, plus the namespaces of the authenticated-only APIs registered
+// by the services, such as the chain stream of a primary serving followers.
+func authModules(apis []rpc.API) []string {
+	modules := append([]string{}, DefaultAuthModules...)
+	served := make(map[string]bool)
+	for _, module := range modules {
+		served[module] = true
+	}
+	for _, api := range apis {
+		if api.Authenticated && !served[api.Namespace] {
+			modules = append(modules, api.Namespace)
+			served[api.Namespace] = true
+		}
+	}
+	return modules
+}
+
 // RegisterAPIs registers the APIs a service provides on the node.
 func (n *Node) RegisterAPIs(apis []rpc.API) {
 	n.lock.Lock()
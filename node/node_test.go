@@ -549,6 +549,24 @@ func TestNodeRPCPrefix(t *testing.T) {
 	}
 }
 
+// Tests that the authenticated endpoint serves the namespaces of the registered
+// authenticated-only APIs on top of the default modules, and only those.
+func TestAuthModules(t *testing.T) {
+	apis := []rpc.API{
+		{Namespace: "eth"},
+		{Namespace: "debug"},
+		{Namespace: "engine", Authenticated: true},
+		{Namespace: "replica", Authenticated: true},
+		{Namespace: "replica", Authenticated: true},
+	}
+	if have, want := authModules(apis), []string{"eth", "engine", "replica"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("auth modules mismatch: have %v, want %v", have, want)
+	}
+	if have := authModules(nil); !reflect.DeepEqual(have, DefaultAuthModules) {
+		t.Errorf("auth modules without authenticated APIs mismatch: have %v, want %v", have, DefaultAuthModules)
+	}
+}
+
 func (test rpcPrefixTest) check(t *testing.T, node *Node) {
 	t.Helper()
 	httpBase := "http://" + node.http.listenAddr()
@@ -19,6 +19,7 @@ package node
 import (
 	"compress/gzip"
 	"context"
+	"crypto/ecdsa"
 	"fmt"
 	"io"
 	"net"
@@ -28,6 +29,7 @@ import (
 	"sync"
 	"sync/atomic"
 
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/rs/cors"
@@ -40,6 +42,12 @@ type httpConfig struct {
 	Vhosts             []string
 	prefix             string // path prefix on which to mount http handler
 	jwtSecret          []byte // optional JWT secret
+	compressThreshold  int    // minimum response size to gzip, negative disables
+
+	signedMethods []string          // methods whose responses are signed
+	signKey       *ecdsa.PrivateKey // key signing the responses
+	archive       *archiveFallback  // offloading of historical state calls, nil if disabled
+	quotas        *rpc.Quotas       // resource quotas of the namespaces, nil if unlimited
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
@@ -48,6 +56,14 @@ type wsConfig struct {
 	Modules   []string
 	prefix    string // path prefix on which to mount ws handler
 	jwtSecret []byte // optional JWT secret
+
+	compression       bool // whether to negotiate permessage-deflate
+	compressThreshold int  // minimum message size to compress
+
+	signedMethods []string          // methods whose responses are signed
+	signKey       *ecdsa.PrivateKey // key signing the responses
+	archive       *archiveFallback  // offloading of historical state calls, nil if disabled
+	quotas        *rpc.Quotas       // resource quotas of the namespaces, nil if unlimited
 }
 
 type rpcHandler struct {
@@ -284,9 +300,14 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 	if err := RegisterApis(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
+	signResponses(srv, config.signedMethods, config.signKey)
+	offloadArchive(srv, config.archive)
+	if config.quotas != nil {
+		srv.SetQuotas(config.quotas)
+	}
 	h.httpConfig = config
 	h.httpHandler.Store(&rpcHandler{
-		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts, config.jwtSecret),
+		Handler: newHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts, config.jwtSecret, config.compressThreshold),
 		server:  srv,
 	})
 	return nil
@@ -315,9 +336,19 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	if err := RegisterApis(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
+	signResponses(srv, config.signedMethods, config.signKey)
+	offloadArchive(srv, config.archive)
+	if config.quotas != nil {
+		srv.SetQuotas(config.quotas)
+	}
 	h.wsConfig = config
+
+	handler := srv.WebsocketHandler(config.Origins)
+	if config.compression {
+		handler = srv.WebsocketHandlerWithCompression(config.Origins, config.compressThreshold)
+	}
 	h.wsHandler.Store(&rpcHandler{
-		Handler: NewWSHandlerStack(srv.WebsocketHandler(config.Origins), config.jwtSecret),
+		Handler: NewWSHandlerStack(handler, config.jwtSecret),
 		server:  srv,
 	})
 	return nil
@@ -363,13 +394,22 @@ func isWebsocket(r *http.Request) bool {
 
 // NewHTTPHandlerStack returns wrapped http-related handlers
 func NewHTTPHandlerStack(srv http.Handler, cors []string, vhosts []string, jwtSecret []byte) http.Handler {
+	return newHTTPHandlerStack(srv, cors, vhosts, jwtSecret, 0)
+}
+
+// newHTTPHandlerStack returns wrapped http-related handlers, gzipping responses
+// of at least compressThreshold bytes. Negative thresholds disable compression.
+func newHTTPHandlerStack(srv http.Handler, cors []string, vhosts []string, jwtSecret []byte, compressThreshold int) http.Handler {
 	// Wrap the CORS-handler within a host-handler
 	handler := newCorsHandler(srv, cors)
 	handler = newVHostHandler(vhosts, handler)
 	if len(jwtSecret) != 0 {
 		handler = newJWTHandler(jwtSecret, handler)
 	}
-	return newGzipHandler(handler)
+	if compressThreshold < 0 {
+		return handler
+	}
+	return newGzipHandler(handler, compressThreshold)
 }
 
 // NewWSHandlerStack returns a wrapped ws-related handler.
@@ -448,36 +488,73 @@ var gzPool = sync.Pool{
 	},
 }
 
+// gzipResponseWriter buffers a response until it reaches the compression
+// threshold, gzipping it from there on. Smaller responses are sent as is.
 type gzipResponseWriter struct {
-	io.Writer
 	http.ResponseWriter
+	threshold int
+
+	status int          // status code, deferred until compression is decided
+	buf    []byte       // response buffered until the threshold is reached
+	gz     *gzip.Writer // compressor, set once the threshold is reached
 }
 
 func (w *gzipResponseWriter) WriteHeader(status int) {
-	w.Header().Del("Content-Length")
-	w.ResponseWriter.WriteHeader(status)
+	w.status = status
 }
 
 func (w *gzipResponseWriter) Write(b []byte) (int, error) {
-	return w.Writer.Write(b)
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) < w.threshold {
+		return len(b), nil
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Del("Content-Length")
+	w.writeHeader()
+
+	w.gz = gzPool.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+	if _, err := w.gz.Write(w.buf); err != nil {
+		return 0, err
+	}
+	w.buf = nil
+	return len(b), nil
 }
 
-func newGzipHandler(next http.Handler) http.Handler {
+// writeHeader sends the deferred status code, if any was set.
+func (w *gzipResponseWriter) writeHeader() {
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// close flushes the response, sending it uncompressed if it stayed below the
+// threshold.
+func (w *gzipResponseWriter) close() {
+	if w.gz == nil {
+		w.writeHeader()
+		w.ResponseWriter.Write(w.buf)
+		return
+	}
+	w.gz.Close()
+	gzPool.Put(w.gz)
+}
+
+func newGzipHandler(next http.Handler, threshold int) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
 			next.ServeHTTP(w, r)
 			return
 		}
+		w.Header().Add("Vary", "Accept-Encoding")
 
-		w.Header().Set("Content-Encoding", "gzip")
-
-		gz := gzPool.Get().(*gzip.Writer)
-		defer gzPool.Put(gz)
+		gw := &gzipResponseWriter{ResponseWriter: w, threshold: threshold}
+		defer gw.close()
 
-		gz.Reset(w)
-		defer gz.Close()
-
-		next.ServeHTTP(&gzipResponseWriter{ResponseWriter: w, Writer: gz}, r)
+		next.ServeHTTP(gw, r)
 	})
 }
 
@@ -547,3 +624,23 @@ func RegisterApis(apis []rpc.API, modules []string, srv *rpc.Server, exposeAll b
 	}
 	return nil
 }
+
+// offloadArchive makes the server forward the calls failing for missing state to
+// the archive node, if any.
+func offloadArchive(srv *rpc.Server, archive *archiveFallback) {
+	if archive == nil {
+		return
+	}
+	srv.SetFallback(archive.methods, archive.call)
+}
+
+// signResponses makes the server sign the responses of the given methods with
+// the key, if any.
+func signResponses(srv *rpc.Server, methods []string, key *ecdsa.PrivateKey) {
+	if len(methods) == 0 || key == nil {
+		return
+	}
+	srv.SignResponses(methods, func(digest []byte) ([]byte, error) {
+		return crypto.Sign(digest, key)
+	})
+}
@@ -18,8 +18,11 @@ package node
 
 import (
 	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strconv"
 	"strings"
@@ -47,6 +50,39 @@ func TestCorsHandler(t *testing.T) {
 	assert.Equal(t, "", resp2.Header.Get("Access-Control-Allow-Origin"))
 }
 
+// TestGzipHandler makes sure only responses reaching the threshold are compressed.
+func TestGzipHandler(t *testing.T) {
+	for _, size := range []int{0, 100, 1023, 1024, 4096} {
+		body := strings.Repeat("x", size)
+		handler := newGzipHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte(body))
+		}), 1024)
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusTeapot, rec.Code)
+		if size < 1024 {
+			assert.Equal(t, "", rec.Header().Get("Content-Encoding"))
+			assert.Equal(t, body, rec.Body.String())
+			continue
+		}
+		assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		gz, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("size %d: invalid gzip stream: %v", size, err)
+		}
+		blob, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatalf("size %d: failed to decompress: %v", size, err)
+		}
+		assert.Equal(t, body, string(blob))
+	}
+}
+
 // TestVhosts makes sure vhosts are properly handled on the http server.
 func TestVhosts(t *testing.T) {
 	srv := createAndStartServer(t, &httpConfig{Vhosts: []string{"test"}}, false, &wsConfig{})
@@ -0,0 +1,167 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Shutdown statuses reported in the final health status line.
+const (
+	ShutdownClean    = "clean"    // Flushed and closed without errors
+	ShutdownDegraded = "degraded" // Closed, but flushing or closing failed
+	ShutdownTimeout  = "timeout"  // Shutdown budget exhausted before the node closed
+)
+
+// flusher persists state that must survive a restart ahead of the rest of the
+// shutdown.
+type flusher struct {
+	name  string
+	flush func() error
+}
+
+// shutdownState tracks a shutdown in progress to report its outcome.
+type shutdownState struct {
+	start   time.Time
+	budget  time.Duration
+	pending []string // Flushers which haven't finished
+	flushed []string // Flushers which succeeded
+	failed  []string // Flushers which failed
+	once    sync.Once
+	lock    sync.Mutex
+}
+
+// RegisterFlusher registers a function persisting state that must survive a
+// restart, run in registration order by Shutdown before the node is closed.
+func (n *Node) RegisterFlusher(name string, flush func() error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.flushers = append(n.flushers, flusher{name: name, flush: flush})
+}
+
+// Shutdown runs the flushers, then closes the node, emitting a final health
+// status line with the outcome. With a non-zero budget, it gives up once the
+// budget is exhausted, so that the process can exit before being killed by its
+// supervisor, and reports whether the node closed in time.
+func (n *Node) Shutdown(budget time.Duration) bool {
+	state := &shutdownState{start: time.Now(), budget: budget}
+	n.lock.Lock()
+	n.shutdown = state
+	flushers := n.flushers
+	n.lock.Unlock()
+
+	for _, f := range flushers {
+		state.pending = append(state.pending, f.name)
+	}
+
+	var expired <-chan time.Time
+	if budget > 0 {
+		timer := time.NewTimer(budget)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		for _, f := range flushers {
+			start := time.Now()
+			if err := f.flush(); err != nil {
+				n.log.Error("Failed to flush on shutdown", "name", f.name, "err", err)
+				state.record(f.name, false)
+				continue
+			}
+			n.log.Info("Flushed on shutdown", "name", f.name, "elapsed", common.PrettyDuration(time.Since(start)))
+			state.record(f.name, true)
+		}
+	}()
+	select {
+	case <-flushed:
+	case <-expired:
+		n.reportShutdown(ShutdownTimeout, "flushing")
+		return false
+	}
+	go n.Close()
+
+	select {
+	case <-n.stop:
+		return true
+	case <-expired:
+		n.reportShutdown(ShutdownTimeout, "closing")
+		return false
+	}
+}
+
+// record tracks the outcome of a flusher.
+func (s *shutdownState) record(name string, ok bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i, pending := range s.pending {
+		if pending == name {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			break
+		}
+	}
+	if ok {
+		s.flushed = append(s.flushed, name)
+	} else {
+		s.failed = append(s.failed, name)
+	}
+}
+
+// reportShutdown emits the final health status line of a shutdown started by
+// Shutdown, at most once.
+func (n *Node) reportShutdown(status string, phase string) {
+	n.lock.Lock()
+	state := n.shutdown
+	n.lock.Unlock()
+
+	if state == nil {
+		return
+	}
+	state.once.Do(func() {
+		state.lock.Lock()
+		defer state.lock.Unlock()
+
+		if status == ShutdownClean && len(state.failed) > 0 {
+			status = ShutdownDegraded
+		}
+		ctx := []interface{}{
+			"status", status,
+			"elapsed", common.PrettyDuration(time.Since(state.start)),
+			"budget", state.budget,
+			"flushed", strings.Join(state.flushed, ","),
+			"failed", strings.Join(state.failed, ","),
+		}
+		if len(state.pending) > 0 {
+			ctx = append(ctx, "pending", strings.Join(state.pending, ","))
+		}
+		if phase != "" {
+			ctx = append(ctx, "phase", phase)
+		}
+		if status == ShutdownClean {
+			n.log.Info("Shutdown finished", ctx...)
+		} else {
+			n.log.Error("Shutdown finished", ctx...)
+		}
+	})
+}
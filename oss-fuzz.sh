@@ -125,5 +125,9 @@ compile_fuzzer tests/fuzzers/snap  FuzzSRange fuzz_storage_range
 compile_fuzzer tests/fuzzers/snap  FuzzByteCodes fuzz_byte_codes
 compile_fuzzer tests/fuzzers/snap  FuzzTrieNodes fuzz_trie_nodes
 
+compile_fuzzer tests/fuzzers/clique  FuzzVerifyHeader fuzz_clique_verify_header
+compile_fuzzer tests/fuzzers/clique  FuzzSnapshot fuzz_clique_snapshot
+compile_fuzzer tests/fuzzers/clique  FuzzBlockReference fuzz_clique_block_reference
+
 #TODO: move this to tests/fuzzers, if possible
 compile_fuzzer crypto/blake2b  Fuzz      fuzzBlake2b
@@ -0,0 +1,265 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/geoip"
+)
+
+// Actions of the network rules.
+const (
+	NetRuleAllow = "allow"
+	NetRuleDeny  = "deny"
+)
+
+var (
+	errGated = errors.New("denied by network rules")
+
+	gatedMeter = metrics.NewRegisteredMeter("p2p/gated", nil)
+)
+
+// NetRule allows or denies connections with the nodes matching all of its set
+// criteria.
+type NetRule struct {
+	Action  string `json:"action"`            // "allow" or "deny"
+	CIDR    string `json:"cidr,omitempty"`    // IP network, e.g. "10.0.0.0/8"
+	Country string `json:"country,omitempty"` // ISO 3166-1 country code, needs a GeoIP country database
+	ASN     uint32 `json:"asn,omitempty"`     // Autonomous system number, needs a GeoIP ASN database
+	Enode   string `json:"enode,omitempty"`   // Node ID or enode URL, a trailing '*' matches ID prefixes
+
+	network *net.IPNet
+	id      string // Lowercase hex node ID, or its prefix if wildcard
+	prefix  bool
+}
+
+// NetRules are the rules gating the connections of the server, evaluated in
+// order with the first matching rule deciding.
+type NetRules struct {
+	Default string    `json:"default,omitempty"` // Action if no rule matches, allow if empty
+	Rules   []NetRule `json:"rules"`
+}
+
+// LoadNetRules reads the network rules from a JSON file.
+func LoadNetRules(path string) (*NetRules, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules := new(NetRules)
+	if err := json.Unmarshal(blob, rules); err != nil {
+		return nil, fmt.Errorf("invalid network rules %s: %v", path, err)
+	}
+	return rules, nil
+}
+
+// compile validates the rules and prepares them for matching.
+func (rules *NetRules) compile(geo bool) error {
+	switch rules.Default {
+	case "", NetRuleAllow, NetRuleDeny:
+	default:
+		return fmt.Errorf("invalid default action %q", rules.Default)
+	}
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		if rule.Action != NetRuleAllow && rule.Action != NetRuleDeny {
+			return fmt.Errorf("rule %d: invalid action %q", i, rule.Action)
+		}
+		if rule.CIDR == "" && rule.Country == "" && rule.ASN == 0 && rule.Enode == "" {
+			return fmt.Errorf("rule %d: no criteria", i)
+		}
+		if rule.CIDR != "" {
+			_, network, err := net.ParseCIDR(rule.CIDR)
+			if err != nil {
+				return fmt.Errorf("rule %d: %v", i, err)
+			}
+			rule.network = network
+		}
+		if (rule.Country != "" || rule.ASN != 0) && !geo {
+			return fmt.Errorf("rule %d: country and ASN rules need a GeoIP database", i)
+		}
+		rule.Country = strings.ToUpper(rule.Country)
+		if rule.Enode != "" {
+			id := strings.ToLower(strings.TrimPrefix(rule.Enode, "enode://"))
+			if at := strings.IndexByte(id, '@'); at >= 0 {
+				id = id[:at]
+			}
+			rule.id, rule.prefix = strings.TrimSuffix(id, "*"), strings.HasSuffix(id, "*")
+			if !rule.prefix {
+				if _, err := enode.ParseID(rule.id); err != nil {
+					return fmt.Errorf("rule %d: %v", i, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// connGater decides which nodes the server connects with by the network rules,
+// looking up the countries and autonomous systems in the GeoIP databases.
+type connGater struct {
+	geo   []*geoip.Reader
+	rules *NetRules
+	lock  sync.RWMutex
+}
+
+// newConnGater opens the GeoIP databases and installs the initial rules.
+func newConnGater(databases []string, rules *NetRules) (*connGater, error) {
+	g := new(connGater)
+	for _, path := range databases {
+		db, err := geoip.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open GeoIP database %s: %v", path, err)
+		}
+		g.geo = append(g.geo, db)
+	}
+	if rules == nil {
+		rules = new(NetRules)
+	}
+	if err := g.setRules(rules); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// setRules validates and installs new network rules.
+func (g *connGater) setRules(rules *NetRules) error {
+	cpy := &NetRules{Default: rules.Default, Rules: append([]NetRule(nil), rules.Rules...)}
+	if err := cpy.compile(len(g.geo) > 0); err != nil {
+		return err
+	}
+	g.lock.Lock()
+	g.rules = cpy
+	g.lock.Unlock()
+	return nil
+}
+
+// getRules returns the installed network rules.
+func (g *connGater) getRules() NetRules {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	return NetRules{Default: g.rules.Default, Rules: append([]NetRule(nil), g.rules.Rules...)}
+}
+
+// lookup merges the information the GeoIP databases hold about an address.
+func (g *connGater) lookup(ip net.IP) geoip.Record {
+	var rec geoip.Record
+	for _, db := range g.geo {
+		found, ok, err := db.Lookup(ip)
+		if err != nil || !ok {
+			continue
+		}
+		if rec.Country == "" {
+			rec.Country = found.Country
+		}
+		if rec.ASN == 0 {
+			rec.ASN, rec.Org = found.ASN, found.Org
+		}
+	}
+	return rec
+}
+
+// check returns an error if connections with a node are denied. Before the
+// handshake the node ID is unknown (zero) and rules matching on it can't decide,
+// so the connection is allowed as long as such a rule could still match first.
+func (g *connGater) check(ip net.IP, id enode.ID) error {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	if len(g.rules.Rules) == 0 && g.rules.Default != NetRuleDeny {
+		return nil
+	}
+	var (
+		rec    geoip.Record
+		looked bool
+	)
+	for _, rule := range g.rules.Rules {
+		if rule.network != nil && (ip == nil || !rule.network.Contains(ip)) {
+			continue
+		}
+		if rule.Country != "" || rule.ASN != 0 {
+			if ip == nil {
+				continue
+			}
+			if !looked {
+				rec, looked = g.lookup(ip), true
+			}
+			if (rule.Country != "" && rule.Country != rec.Country) || (rule.ASN != 0 && rule.ASN != rec.ASN) {
+				continue
+			}
+		}
+		if rule.Enode != "" {
+			if id == (enode.ID{}) {
+				return nil // Undecided until the handshake
+			}
+			hex := id.String()
+			if (rule.prefix && !strings.HasPrefix(hex, rule.id)) || (!rule.prefix && hex != rule.id) {
+				continue
+			}
+		}
+		if rule.Action == NetRuleDeny {
+			gatedMeter.Mark(1)
+			return errGated
+		}
+		return nil
+	}
+	if g.rules.Default == NetRuleDeny {
+		gatedMeter.Mark(1)
+		return errGated
+	}
+	return nil
+}
+
+// CurrentNetRules returns the network rules gating the connections of the server.
+func (srv *Server) CurrentNetRules() NetRules {
+	if srv.gater == nil {
+		return NetRules{}
+	}
+	return srv.gater.getRules()
+}
+
+// SetNetRules replaces the network rules gating the connections of the server,
+// disconnecting the peers they deny.
+func (srv *Server) SetNetRules(rules NetRules) error {
+	if srv.gater == nil {
+		return errServerStopped
+	}
+	if err := srv.gater.setRules(&rules); err != nil {
+		return err
+	}
+	for _, peer := range srv.Peers() {
+		var ip net.IP
+		if addr, ok := peer.RemoteAddr().(*net.TCPAddr); ok {
+			ip = addr.IP
+		}
+		if err := srv.gater.check(ip, peer.ID()); err != nil {
+			peer.Log().Debug("Disconnecting peer denied by network rules")
+			peer.Disconnect(DiscUselessPeer)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Tests that the network rules are evaluated in order, and that rules on node
+// IDs leave connections undecided before the handshake.
+func TestConnGater(t *testing.T) {
+	var (
+		member = enode.HexID("a1b2000000000000000000000000000000000000000000000000000000000000")
+		other  = enode.HexID("c3d4000000000000000000000000000000000000000000000000000000000000")
+	)
+	gater, err := newConnGater(nil, &NetRules{
+		Default: NetRuleDeny,
+		Rules: []NetRule{
+			{Action: NetRuleDeny, CIDR: "10.1.0.0/16"},
+			{Action: NetRuleAllow, CIDR: "10.0.0.0/8"},
+			{Action: NetRuleAllow, Enode: "enode://a1b2*"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create gater: %v", err)
+	}
+	tests := []struct {
+		ip      string
+		id      enode.ID
+		allowed bool
+	}{
+		{"10.1.2.3", member, false},     // Denied network before the allowed node
+		{"10.2.3.4", other, true},       // Allowed network
+		{"192.0.2.1", member, true},     // Allowed node prefix
+		{"192.0.2.1", other, false},     // Default deny
+		{"192.0.2.1", enode.ID{}, true}, // Undecided before the handshake
+		{"10.1.2.3", enode.ID{}, false}, // Decided before reaching the node rule
+	}
+	for _, tt := range tests {
+		err := gater.check(net.ParseIP(tt.ip), tt.id)
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("%s %x: allowed %v, want %v", tt.ip, tt.id[:2], allowed, tt.allowed)
+		}
+	}
+	// Invalid rules are rejected without replacing the installed ones
+	invalid := []NetRules{
+		{Default: "maybe"},
+		{Rules: []NetRule{{Action: "drop", CIDR: "10.0.0.0/8"}}},
+		{Rules: []NetRule{{Action: NetRuleDeny}}},
+		{Rules: []NetRule{{Action: NetRuleDeny, CIDR: "10.0.0.0"}}},
+		{Rules: []NetRule{{Action: NetRuleDeny, Enode: "0xnotanid"}}},
+		{Rules: []NetRule{{Action: NetRuleDeny, Country: "DE"}}}, // No GeoIP database
+	}
+	for i, rules := range invalid {
+		if err := gater.setRules(&rules); err == nil {
+			t.Errorf("invalid rules %d accepted", i)
+		}
+	}
+	if rules := gater.getRules(); rules.Default != NetRuleDeny || len(rules.Rules) != 3 {
+		t.Errorf("installed rules replaced: %+v", rules)
+	}
+	// Without rules, everything is allowed
+	if err := gater.setRules(&NetRules{}); err != nil {
+		t.Fatalf("failed to clear rules: %v", err)
+	}
+	if err := gater.check(net.ParseIP("192.0.2.1"), other); err != nil {
+		t.Errorf("connection denied without rules: %v", err)
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package geoip reads the country and autonomous system of IP addresses from
+// local databases in the MaxMind DB format, e.g. GeoLite2-Country.mmdb and
+// GeoLite2-ASN.mmdb.
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// metadataMarker precedes the metadata section at the end of the database.
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSeparator is the number of zero bytes between the search tree and the
+// data section.
+const dataSeparator = 16
+
+// maxDepth caps the nesting of decoded data structures.
+const maxDepth = 32
+
+var (
+	errInvalidDatabase = errors.New("invalid MaxMind database")
+	errCorruptData     = errors.New("corrupt MaxMind database data")
+)
+
+// Record is the information a database holds about an IP address. Fields the
+// database doesn't provide are left empty.
+type Record struct {
+	Country string `json:"country,omitempty"` // ISO 3166-1 country code
+	ASN     uint32 `json:"asn,omitempty"`     // Autonomous system number
+	Org     string `json:"org,omitempty"`     // Autonomous system organization
+}
+
+// Reader looks up IP addresses in a MaxMind database held in memory.
+type Reader struct {
+	tree       []byte
+	data       []byte
+	nodeCount  uint32
+	recordSize uint32
+	ipVersion  uint32
+	ipv4Start  uint32 // Node of the IPv4 subtree in IPv6 databases
+	dbType     string
+}
+
+// Open reads a MaxMind database from disk.
+func Open(path string) (*Reader, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(blob)
+}
+
+// New parses a MaxMind database.
+func New(blob []byte) (*Reader, error) {
+	start := bytes.LastIndex(blob, metadataMarker)
+	if start < 0 {
+		return nil, fmt.Errorf("%w: metadata missing", errInvalidDatabase)
+	}
+	meta, _, err := decode(blob[start+len(metadataMarker):], 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: metadata: %v", errInvalidDatabase, err)
+	}
+	fields, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata not a map", errInvalidDatabase)
+	}
+	r := &Reader{
+		nodeCount:  uint32(toUint(fields["node_count"])),
+		recordSize: uint32(toUint(fields["record_size"])),
+		ipVersion:  uint32(toUint(fields["ip_version"])),
+	}
+	r.dbType, _ = fields["database_type"].(string)
+
+	switch r.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported record size %d", errInvalidDatabase, r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("%w: unsupported IP version %d", errInvalidDatabase, r.ipVersion)
+	}
+	treeSize := uint64(r.nodeCount) * uint64(r.recordSize) / 4
+	if treeSize+dataSeparator > uint64(start) {
+		return nil, fmt.Errorf("%w: search tree exceeds the file", errInvalidDatabase)
+	}
+	r.tree = blob[:treeSize]
+	r.data = blob[treeSize+dataSeparator : start]
+
+	// Locate the IPv4 subtree (::/96) once in IPv6 databases
+	if r.ipVersion == 6 {
+		node := uint32(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Type returns the database type from the metadata, e.g. "GeoLite2-ASN".
+func (r *Reader) Type() string {
+	return r.dbType
+}
+
+// record reads the left (bit 0) or right (bit 1) record of a tree node.
+func (r *Reader) record(node uint32, bit uint) uint32 {
+	switch r.recordSize {
+	case 24:
+		b := r.tree[node*6+uint32(bit)*3:]
+		return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+	case 28:
+		b := r.tree[node*7:]
+		if bit == 0 {
+			return uint32(b[3]&0xf0)<<20 | uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+		}
+		return uint32(b[3]&0x0f)<<24 | uint32(b[4])<<16 | uint32(b[5])<<8 | uint32(b[6])
+	default:
+		return binary.BigEndian.Uint32(r.tree[node*8+uint32(bit)*4:])
+	}
+}
+
+// Lookup returns the information held about an IP address, false if there is
+// none.
+func (r *Reader) Lookup(ip net.IP) (Record, bool, error) {
+	var (
+		bits []byte
+		node uint32
+	)
+	if ip4 := ip.To4(); ip4 != nil {
+		bits, node = ip4, r.ipv4Start
+	} else if r.ipVersion == 6 && len(ip) == net.IPv6len {
+		bits = ip
+	} else {
+		return Record{}, false, nil
+	}
+	for i := 0; i < len(bits)*8 && node < r.nodeCount; i++ {
+		node = r.record(node, uint(bits[i/8]>>(7-i%8))&1)
+	}
+	if node == r.nodeCount {
+		return Record{}, false, nil // Explicitly empty
+	}
+	if node < r.nodeCount {
+		return Record{}, false, errCorruptData
+	}
+	offset := uint64(node) - uint64(r.nodeCount) - dataSeparator
+	if offset >= uint64(len(r.data)) {
+		return Record{}, false, errCorruptData
+	}
+	value, _, err := decode(r.data, uint(offset), 0)
+	if err != nil {
+		return Record{}, false, err
+	}
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return Record{}, false, errCorruptData
+	}
+	var rec Record
+	for _, key := range []string{"country", "registered_country"} {
+		if country, ok := fields[key].(map[string]interface{}); ok {
+			if code, ok := country["iso_code"].(string); ok {
+				rec.Country = code
+				break
+			}
+		}
+	}
+	rec.ASN = uint32(toUint(fields["autonomous_system_number"]))
+	rec.Org, _ = fields["autonomous_system_organization"].(string)
+	return rec, true, nil
+}
+
+// decode decodes the data field at the given offset of a data section, returning
+// it along with the offset following it.
+func decode(data []byte, offset uint, depth int) (interface{}, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, errCorruptData
+	}
+	if offset >= uint(len(data)) {
+		return nil, 0, errCorruptData
+	}
+	ctrl := data[offset]
+	offset++
+
+	kind := uint(ctrl >> 5)
+	if kind == 1 { // Pointer, resolved and followed by the data after it
+		size := uint(ctrl>>3) & 0x3
+		if offset+size+1 > uint(len(data)) {
+			return nil, 0, errCorruptData
+		}
+		var ptr uint
+		switch size {
+		case 0:
+			ptr = uint(ctrl&0x7)<<8 | uint(data[offset])
+		case 1:
+			ptr = (uint(ctrl&0x7)<<16 | uint(data[offset])<<8 | uint(data[offset+1])) + 2048
+		case 2:
+			ptr = (uint(ctrl&0x7)<<24 | uint(data[offset])<<16 | uint(data[offset+1])<<8 | uint(data[offset+2])) + 526336
+		case 3:
+			ptr = uint(binary.BigEndian.Uint32(data[offset:]))
+		}
+		value, _, err := decode(data, ptr, depth+1)
+		return value, offset + size + 1, err
+	}
+	if kind == 0 { // Extended type
+		if offset >= uint(len(data)) {
+			return nil, 0, errCorruptData
+		}
+		kind = 7 + uint(data[offset])
+		offset++
+	}
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		extra := size - 28
+		if offset+extra > uint(len(data)) {
+			return nil, 0, errCorruptData
+		}
+		var n uint
+		for i := uint(0); i < extra; i++ {
+			n = n<<8 | uint(data[offset+i])
+		}
+		offset += extra
+		switch extra {
+		case 1:
+			size = 29 + n
+		case 2:
+			size = 285 + n
+		default:
+			size = 65821 + n
+		}
+	}
+	switch kind {
+	case 7: // Map
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := decode(data, offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, errCorruptData
+			}
+			value, next, err := decode(data, next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[name], offset = value, next
+		}
+		return m, offset, nil
+
+	case 11: // Array
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := decode(data, offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a, offset = append(a, value), next
+		}
+		return a, offset, nil
+
+	case 14: // Boolean, the size is the value
+		return size != 0, offset, nil
+	}
+	if offset+size > uint(len(data)) {
+		return nil, 0, errCorruptData
+	}
+	raw := data[offset : offset+size]
+	offset += size
+
+	switch kind {
+	case 2: // UTF-8 string
+		return string(raw), offset, nil
+	case 3: // Double
+		if size != 8 {
+			return nil, 0, errCorruptData
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), offset, nil
+	case 4, 10: // Bytes, uint128
+		return append([]byte(nil), raw...), offset, nil
+	case 5, 6, 9: // Unsigned integers
+		if size > 8 {
+			return nil, 0, errCorruptData
+		}
+		var n uint64
+		for _, b := range raw {
+			n = n<<8 | uint64(b)
+		}
+		return n, offset, nil
+	case 8: // Signed 32 bit integer
+		if size > 4 {
+			return nil, 0, errCorruptData
+		}
+		var n uint32
+		for _, b := range raw {
+			n = n<<8 | uint32(b)
+		}
+		if size == 4 {
+			return int64(int32(n)), offset, nil
+		}
+		return int64(n), offset, nil
+	case 15: // Float
+		if size != 4 {
+			return nil, 0, errCorruptData
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), offset, nil
+	default:
+		return nil, 0, fmt.Errorf("%w: unsupported type %d", errCorruptData, kind)
+	}
+}
+
+// toUint converts a decoded unsigned integer, zero if it's of another type.
+func toUint(value interface{}) uint64 {
+	n, _ := value.(uint64)
+	return n
+}
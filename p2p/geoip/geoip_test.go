@@ -0,0 +1,167 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+// mmdbString encodes a UTF-8 string data field of up to 284 bytes.
+func mmdbString(s string) []byte {
+	if len(s) < 29 {
+		return append([]byte{2<<5 | byte(len(s))}, s...)
+	}
+	return append([]byte{2<<5 | 29, byte(len(s) - 29)}, s...)
+}
+
+// mmdbUint encodes an unsigned integer data field of the given type.
+func mmdbUint(kind byte, size int, n uint64) []byte {
+	var enc []byte
+	if kind >= 8 {
+		enc = []byte{byte(size), kind - 7}
+	} else {
+		enc = []byte{kind<<5 | byte(size)}
+	}
+	for i := size - 1; i >= 0; i-- {
+		enc = append(enc, byte(n>>(8*i)))
+	}
+	return enc
+}
+
+// mmdbMap encodes a map data field from alternating encoded keys and values.
+func mmdbMap(fields ...[]byte) []byte {
+	return append([]byte{7<<5 | byte(len(fields)/2)}, bytes.Join(fields, nil)...)
+}
+
+// mmdbPointer encodes a pointer to an offset of the data section.
+func mmdbPointer(offset int) []byte {
+	return []byte{1<<5 | byte(offset>>8)&0x7, byte(offset)}
+}
+
+// buildDatabase assembles a MaxMind database with 24 bit records mapping the
+// given IPv4 networks to their data fields.
+func buildDatabase(t *testing.T, version int, nets []string, data [][]byte) []byte {
+	const empty, dataRef = 1 << 30, 1 << 29
+
+	nodes := [][2]uint32{{empty, empty}}
+	for i, cidr := range nets {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bits, _ := network.Mask.Size()
+		addr := []byte(network.IP.To4())
+		if version == 6 {
+			addr, bits = append(make([]byte, 12), addr...), bits+96
+		}
+		node := uint32(0)
+		for j := 0; j < bits; j++ {
+			bit := (addr[j/8] >> (7 - j%8)) & 1
+			if j == bits-1 {
+				nodes[node][bit] = dataRef | uint32(i)
+				break
+			}
+			if nodes[node][bit] == empty {
+				nodes = append(nodes, [2]uint32{empty, empty})
+				nodes[node][bit] = uint32(len(nodes) - 1)
+			}
+			node = nodes[node][bit]
+		}
+	}
+	var (
+		count   = uint32(len(nodes))
+		offsets []int
+		section []byte
+	)
+	for _, field := range data {
+		offsets = append(offsets, len(section))
+		section = append(section, field...)
+	}
+	var tree []byte
+	for _, node := range nodes {
+		for _, rec := range node {
+			switch {
+			case rec == empty:
+				rec = count
+			case rec&dataRef != 0:
+				rec = count + dataSeparator + uint32(offsets[rec&^dataRef])
+			}
+			var enc [4]byte
+			binary.BigEndian.PutUint32(enc[:], rec)
+			tree = append(tree, enc[1:]...)
+		}
+	}
+	blob := append(tree, make([]byte, dataSeparator)...)
+	blob = append(blob, section...)
+	blob = append(blob, metadataMarker...)
+	return append(blob, mmdbMap(
+		mmdbString("node_count"), mmdbUint(6, 4, uint64(count)),
+		mmdbString("record_size"), mmdbUint(5, 2, 24),
+		mmdbString("ip_version"), mmdbUint(5, 2, uint64(version)),
+		mmdbString("database_type"), mmdbString("Test-DB"),
+	)...)
+}
+
+// Tests that countries and autonomous systems are looked up, pointers into the
+// data section followed, and unknown addresses reported as such.
+func TestLookup(t *testing.T) {
+	country := mmdbMap(mmdbString("iso_code"), mmdbString("DE"))
+	first := mmdbMap(
+		mmdbString("country"), country,
+		mmdbString("autonomous_system_number"), mmdbUint(6, 2, 64500),
+		mmdbString("autonomous_system_organization"), mmdbString("Example"),
+	)
+	// The country map starts after the map header and the "country" key
+	second := mmdbMap(mmdbString("registered_country"), mmdbPointer(1+len(mmdbString("country"))))
+
+	for _, version := range []int{4, 6} {
+		db, err := New(buildDatabase(t, version, []string{"10.0.0.0/8", "192.0.2.0/24"}, [][]byte{first, second}))
+		if err != nil {
+			t.Fatalf("v%d: failed to open database: %v", version, err)
+		}
+		if db.Type() != "Test-DB" {
+			t.Errorf("v%d: database type mismatch: %q", version, db.Type())
+		}
+		tests := []struct {
+			ip    string
+			found bool
+			rec   Record
+		}{
+			{"10.1.2.3", true, Record{Country: "DE", ASN: 64500, Org: "Example"}},
+			{"192.0.2.7", true, Record{Country: "DE"}},
+			{"192.0.3.1", false, Record{}},
+			{"8.8.8.8", false, Record{}},
+			{"2001:db8::1", false, Record{}},
+		}
+		for _, tt := range tests {
+			rec, found, err := db.Lookup(net.ParseIP(tt.ip))
+			if err != nil {
+				t.Errorf("v%d %s: lookup failed: %v", version, tt.ip, err)
+				continue
+			}
+			if found != tt.found || rec != tt.rec {
+				t.Errorf("v%d %s: have %+v (found %v), want %+v (found %v)", version, tt.ip, rec, found, tt.rec, tt.found)
+			}
+		}
+	}
+	if _, err := New([]byte("not a database")); err == nil {
+		t.Errorf("invalid database opened")
+	}
+}
@@ -493,6 +493,7 @@ type PeerInfo struct {
 		Inbound       bool   `json:"inbound"`
 		Trusted       bool   `json:"trusted"`
 		Static        bool   `json:"static"`
+		SessionAge    uint64 `json:"sessionAge"` // Seconds since the session keys were negotiated
 	} `json:"network"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
 }
@@ -520,6 +521,7 @@ func (p *Peer) Info() *PeerInfo {
 	info.Network.Inbound = p.rw.is(inboundConn)
 	info.Network.Trusted = p.rw.is(trustedConn)
 	info.Network.Static = p.rw.is(staticDialedConn)
+	info.Network.SessionAge = uint64(time.Duration(mclock.Now()-p.created) / time.Second)
 
 	// Gather all the running protocol infos
 	for _, proto := range p.running {
@@ -0,0 +1,82 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// rekeyInterval is how often the sessions are checked against their maximum age.
+const rekeyInterval = time.Minute
+
+var (
+	rekeyMeter          = metrics.NewRegisteredMeter("p2p/sessions/rekeys", nil)
+	oldestSessionGauge  = metrics.NewRegisteredGauge("p2p/sessions/oldest", nil)
+	sessionAgeHistogram = metrics.NewRegisteredHistogram("p2p/sessions/age", nil, metrics.NewExpDecaySample(1028, 0.015))
+)
+
+// sessionLimit returns the age at which the session with a peer is rotated. The
+// RLPx session keys are derived from the ephemeral keys of the handshake, so the
+// only way to rotate them is a fresh handshake on a new connection. Limits are
+// spread by up to an eighth by node ID so the peers don't all reconnect at once,
+// and inbound sessions get another quarter so that the dialing side, which
+// reconnects right away, usually rotates first.
+func (srv *Server) sessionLimit(p *Peer) time.Duration {
+	limit := srv.MaxSessionAge + srv.MaxSessionAge/8*time.Duration(p.ID()[0])/256
+	if p.Inbound() {
+		limit += srv.MaxSessionAge / 4
+	}
+	return limit
+}
+
+// expiredSessions returns the peers whose sessions outlived their limit, and
+// the age of the oldest session.
+func (srv *Server) expiredSessions(peers map[enode.ID]*Peer, now mclock.AbsTime) ([]*Peer, time.Duration) {
+	var (
+		expired []*Peer
+		oldest  time.Duration
+	)
+	for _, p := range peers {
+		age := time.Duration(now - p.created)
+		if age > oldest {
+			oldest = age
+		}
+		if srv.MaxSessionAge > 0 && age > srv.sessionLimit(p) {
+			expired = append(expired, p)
+		}
+	}
+	return expired, oldest
+}
+
+// rotateSessions disconnects the peers whose sessions are due for new keys.
+// Static and trusted peers are redialed by the dial scheduler, and the other
+// ones are replaced like any dropped peer.
+func (srv *Server) rotateSessions(peers map[enode.ID]*Peer) {
+	expired, oldest := srv.expiredSessions(peers, mclock.Now())
+	oldestSessionGauge.Update(int64(oldest / time.Second))
+
+	for _, p := range expired {
+		p.log.Debug("Rotating p2p session keys", "age", common.PrettyDuration(mclock.Now()-p.created))
+		rekeyMeter.Mark(1)
+		p.Disconnect(DiscRequested)
+	}
+}
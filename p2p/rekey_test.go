@@ -0,0 +1,61 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Tests that sessions are rotated once past their limit, with inbound sessions
+// given extra time so the dialing side reconnects first.
+func TestExpiredSessions(t *testing.T) {
+	var (
+		now      = mclock.AbsTime(100 * time.Hour)
+		outbound = NewPeer(enode.ID{0x00}, "outbound", nil)
+		inbound  = NewPeer(enode.ID{0x00}, "inbound", nil)
+		fresh    = NewPeer(enode.ID{0xff}, "fresh", nil)
+	)
+	inbound.rw.set(inboundConn, true)
+	outbound.created = now - mclock.AbsTime(11*time.Hour)
+	inbound.created = now - mclock.AbsTime(11*time.Hour)
+	fresh.created = now - mclock.AbsTime(time.Hour)
+
+	peers := map[enode.ID]*Peer{{1}: outbound, {2}: inbound, {3}: fresh}
+
+	// Without a maximum age, nothing is rotated but the age still tracked
+	srv := &Server{}
+	expired, oldest := srv.expiredSessions(peers, now)
+	if len(expired) != 0 || oldest != 11*time.Hour {
+		t.Fatalf("rotation without maximum age: %d expired, oldest %v", len(expired), oldest)
+	}
+	srv.MaxSessionAge = 10 * time.Hour
+	if expired, _ = srv.expiredSessions(peers, now); len(expired) != 1 || expired[0] != outbound {
+		t.Fatalf("expired sessions mismatch: %v", expired)
+	}
+	// Inbound sessions expire after the extra grace, and the jitter is bounded
+	now += mclock.AbsTime(2 * time.Hour)
+	if expired, _ = srv.expiredSessions(peers, now); len(expired) != 2 {
+		t.Fatalf("inbound session not expired: %v", expired)
+	}
+	if limit := srv.sessionLimit(fresh); limit < srv.MaxSessionAge || limit > srv.MaxSessionAge*9/8 {
+		t.Fatalf("session limit out of bounds: %v", limit)
+	}
+}
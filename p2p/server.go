@@ -114,11 +114,28 @@ type Config struct {
 	// allowed to connect, even above the peer limit.
 	TrustedNodes []*enode.Node
 
+	// If TrustedOnly is set, connections to and from any node but the trusted
+	// ones are refused, e.g. for a validator only peering with its sentries.
+	TrustedOnly bool `toml:",omitempty"`
+
 	// Connectivity can be restricted to certain IP networks.
 	// If this option is set to a non-nil value, only hosts which match one of the
 	// IP networks contained in the list are considered.
 	NetRestrict *netutil.Netlist `toml:",omitempty"`
 
+	// NetRules allow or deny connections by IP network, country, autonomous
+	// system and node ID. They can be replaced at runtime with SetNetRules.
+	NetRules *NetRules `toml:",omitempty"`
+
+	// GeoIPDatabases are the paths of the MaxMind databases the countries and
+	// autonomous systems of the network rules are looked up in.
+	GeoIPDatabases []string `toml:",omitempty"`
+
+	// MaxSessionAge is the age at which connections are dropped to renegotiate
+	// the session keys, with static and trusted peers redialed. Zero disables
+	// the rotation.
+	MaxSessionAge time.Duration `toml:",omitempty"`
+
 	// NodeDatabase is the path to the database containing the previously seen
 	// live nodes in the network.
 	NodeDatabase string `toml:",omitempty"`
@@ -184,6 +201,7 @@ type Server struct {
 	DiscV5    *discover.UDPv5
 	discmix   *enode.FairMix
 	dialsched *dialScheduler
+	gater     *connGater
 
 	// Channels into the run loop.
 	quit                    chan struct{}
@@ -271,6 +289,17 @@ func (f connFlag) String() string {
 	return s
 }
 
+// remoteIP returns the IP address of the remote end, nil if unknown.
+func (c *conn) remoteIP() net.IP {
+	if addr, ok := c.fd.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP
+	}
+	if c.node != nil {
+		return c.node.IP()
+	}
+	return nil
+}
+
 func (c *conn) is(f connFlag) bool {
 	flags := connFlag(atomic.LoadInt32((*int32)(&c.flags)))
 	return flags&f != 0
@@ -461,6 +490,9 @@ func (srv *Server) Start() (err error) {
 	if srv.listenFunc == nil {
 		srv.listenFunc = net.Listen
 	}
+	if srv.gater, err = newConnGater(srv.GeoIPDatabases, srv.NetRules); err != nil {
+		return err
+	}
 	srv.quit = make(chan struct{})
 	srv.delpeer = make(chan peerDrop)
 	srv.checkpointPostHandshake = make(chan *conn)
@@ -621,6 +653,7 @@ func (srv *Server) setupDialScheduler() {
 		maxActiveDials: srv.MaxPendingPeers,
 		log:            srv.Logger,
 		netRestrict:    srv.NetRestrict,
+		gater:          srv.gater,
 		dialer:         srv.Dialer,
 		clock:          srv.clock,
 	}
@@ -702,7 +735,10 @@ func (srv *Server) run() {
 		peers        = make(map[enode.ID]*Peer)
 		inboundCount = 0
 		trusted      = make(map[enode.ID]bool, len(srv.TrustedNodes))
+		rekey        = time.NewTicker(rekeyInterval)
 	)
+	defer rekey.Stop()
+
 	// Put trusted nodes into a map to speed up checks.
 	// Trusted peers are loaded on startup or added via AddTrustedPeer RPC.
 	for _, n := range srv.TrustedNodes {
@@ -739,6 +775,10 @@ running:
 			op(peers)
 			srv.peerOpDone <- struct{}{}
 
+		case <-rekey.C:
+			// Rotate the keys of the sessions that outlived their maximum age.
+			srv.rotateSessions(peers)
+
 		case c := <-srv.checkpointPostHandshake:
 			// A connection has passed the encryption handshake so
 			// the remote identity is known (but hasn't been verified yet).
@@ -768,6 +808,7 @@ running:
 		case pd := <-srv.delpeer:
 			// A peer disconnected.
 			d := common.PrettyDuration(mclock.Now() - pd.created)
+			sessionAgeHistogram.Update(int64(time.Duration(d) / time.Second))
 			delete(peers, pd.ID())
 			srv.log.Debug("Removing p2p peer", "peercount", len(peers), "id", pd.ID(), "duration", d, "req", pd.requested, "err", pd.err)
 			srv.dialsched.peerRemoved(pd.rw)
@@ -802,6 +843,10 @@ running:
 
 func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount int, c *conn) error {
 	switch {
+	case !c.is(trustedConn) && srv.TrustedOnly:
+		return DiscUselessPeer
+	case srv.gater != nil && srv.gater.check(c.remoteIP(), c.node.ID()) != nil:
+		return DiscUselessPeer
 	case !c.is(trustedConn) && len(peers) >= srv.MaxPeers:
 		return DiscTooManyPeers
 	case !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns():
@@ -905,6 +950,12 @@ func (srv *Server) checkInboundConn(remoteIP net.IP) error {
 	if srv.NetRestrict != nil && !srv.NetRestrict.Contains(remoteIP) {
 		return fmt.Errorf("not in netrestrict list")
 	}
+	// Reject connections denied by the network rules.
+	if srv.gater != nil {
+		if err := srv.gater.check(remoteIP, enode.ID{}); err != nil {
+			return err
+		}
+	}
 	// Reject Internet peers that try too often.
 	now := srv.clock.Now()
 	srv.inboundHistory.expire(now, nil)
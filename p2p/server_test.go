@@ -300,6 +300,44 @@ func TestServerAtCap(t *testing.T) {
 	}
 }
 
+// Tests that only trusted nodes may connect if the server is restricted to them.
+func TestServerTrustedOnly(t *testing.T) {
+	trustedID, otherID := randomID(), randomID()
+	srv := &Server{
+		Config: Config{
+			PrivateKey:   newkey(),
+			MaxPeers:     10,
+			NoDial:       true,
+			NoDiscovery:  true,
+			TrustedNodes: []*enode.Node{newNode(trustedID, "")},
+			TrustedOnly:  true,
+			Logger:       testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id enode.ID) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&newkey().PublicKey, fd, nil)
+		node := enode.SignNull(new(enr.Record), id)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, node: node, cont: make(chan error)}
+	}
+	if err := srv.checkpoint(newconn(otherID), srv.checkpointPostHandshake); err != DiscUselessPeer {
+		t.Error("wrong error for untrusted conn:", err)
+	}
+	if err := srv.checkpoint(newconn(trustedID), srv.checkpointPostHandshake); err != nil {
+		t.Error("unexpected error for trusted conn @posthandshake:", err)
+	}
+	// Nodes trusted later on are allowed too
+	srv.AddTrustedPeer(newNode(otherID, ""))
+	if err := srv.checkpoint(newconn(otherID), srv.checkpointPostHandshake); err != nil {
+		t.Error("unexpected error for newly trusted conn @posthandshake:", err)
+	}
+}
+
 func TestServerPeerLimits(t *testing.T) {
 	srvkey := newkey()
 	clientkey := newkey()
@@ -20,6 +20,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"golang.org/x/crypto/sha3"
@@ -257,16 +258,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, new(EthashConfig), nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, &CliqueConfig{Period: 0}}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, &CliqueConfig{Period: 0}}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, new(EthashConfig), nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int), false)
 )
 
@@ -352,6 +353,10 @@ type ChainConfig struct {
 	// the network that triggers the consensus upgrade.
 	TerminalTotalDifficulty *big.Int `json:"terminalTotalDifficulty,omitempty"`
 
+	// PrecompileExtensions activates additional precompiled contracts from the
+	// extension registry of the EVM, mapping their names to activation blocks.
+	PrecompileExtensions map[string]*big.Int `json:"precompileExtensions,omitempty"`
+
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
@@ -372,6 +377,21 @@ type CliqueConfig struct {
 	EpochBlock        uint64           `json:"epochBlock"`        // epoch block from where the dnr registry is monitored
 	API               string           `json:"api"`               // Ethereum RPC URL
 	InitialValidators []common.Address `json:"initialValidators"` // initial validators incase initialized from non-zero epoch
+
+	PerformanceCommitBlock *big.Int `json:"performanceCommitBlock,omitempty"` // Block from which epoch blocks commit to the closing epoch's validator performance (nil = no fork)
+	SnapshotCommitBlock    *big.Int `json:"snapshotCommitBlock,omitempty"`    // Block from which epoch blocks commit to the snapshot they produce (nil = no fork)
+
+	SignerPolicy *CliqueSignerPolicy `json:"signerPolicy,omitempty"` // Guardrails on the signer set changes applied by epoch blocks (nil = none)
+}
+
+// CliqueSignerPolicy are the guardrails on the signer set changes applied by the
+// epoch blocks. Epoch blocks violating the policy are rejected, so it has to be
+// scheduled network wide like any other fork.
+type CliqueSignerPolicy struct {
+	Block      *big.Int `json:"block"`                // Block from which the policy is enforced
+	MinSigners uint64   `json:"minSigners,omitempty"` // Minimum size of the signer set (0 = no limit)
+	MaxSigners uint64   `json:"maxSigners,omitempty"` // Maximum size of the signer set (0 = no limit)
+	Cooldown   uint64   `json:"cooldown,omitempty"`   // Minimum number of blocks between two epoch blocks authorizing new signers
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -379,6 +399,30 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// IsPrecompileExtension returns whether the named precompile extension is active
+// at the given block.
+func (c *ChainConfig) IsPrecompileExtension(name string, num *big.Int) bool {
+	return isForked(c.PrecompileExtensions[name], num)
+}
+
+// IsPerformanceCommit returns whether num is either equal to the performance
+// commitment fork block or greater.
+func (c *CliqueConfig) IsPerformanceCommit(num *big.Int) bool {
+	return isForked(c.PerformanceCommitBlock, num)
+}
+
+// IsSnapshotCommit returns whether num is either equal to the snapshot
+// commitment fork block or greater.
+func (c *CliqueConfig) IsSnapshotCommit(num *big.Int) bool {
+	return isForked(c.SnapshotCommitBlock, num)
+}
+
+// IsSignerPolicy returns whether the signer set policy is enforced at the given
+// block.
+func (c *CliqueConfig) IsSignerPolicy(num *big.Int) bool {
+	return c.SignerPolicy != nil && isForked(c.SignerPolicy.Block, num)
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -548,6 +592,11 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
+	if c.Clique != nil && c.Clique.SignerPolicy != nil {
+		if policy := c.Clique.SignerPolicy; policy.MaxSigners != 0 && policy.MinSigners > policy.MaxSigners {
+			return fmt.Errorf("invalid clique signer policy: minimum signers %d above maximum %d", policy.MinSigners, policy.MaxSigners)
+		}
+	}
 	return nil
 }
 
@@ -604,6 +653,34 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.MergeForkBlock, newcfg.MergeForkBlock, head) {
 		return newCompatError("Merge Start fork block", c.MergeForkBlock, newcfg.MergeForkBlock)
 	}
+	for name := range c.PrecompileExtensions {
+		if isForkIncompatible(c.PrecompileExtensions[name], newcfg.PrecompileExtensions[name], head) {
+			return newCompatError("Precompile extension "+name+" fork block", c.PrecompileExtensions[name], newcfg.PrecompileExtensions[name])
+		}
+	}
+	for name := range newcfg.PrecompileExtensions {
+		if _, ok := c.PrecompileExtensions[name]; !ok && isForkIncompatible(nil, newcfg.PrecompileExtensions[name], head) {
+			return newCompatError("Precompile extension "+name+" fork block", nil, newcfg.PrecompileExtensions[name])
+		}
+	}
+	if c.Clique != nil && newcfg.Clique != nil && isForkIncompatible(c.Clique.PerformanceCommitBlock, newcfg.Clique.PerformanceCommitBlock, head) {
+		return newCompatError("Clique performance commitment fork block", c.Clique.PerformanceCommitBlock, newcfg.Clique.PerformanceCommitBlock)
+	}
+	if c.Clique != nil && newcfg.Clique != nil && isForkIncompatible(c.Clique.SnapshotCommitBlock, newcfg.Clique.SnapshotCommitBlock, head) {
+		return newCompatError("Clique snapshot commitment fork block", c.Clique.SnapshotCommitBlock, newcfg.Clique.SnapshotCommitBlock)
+	}
+	if c.Clique != nil && newcfg.Clique != nil {
+		var s1, s2 *big.Int
+		if c.Clique.SignerPolicy != nil {
+			s1 = c.Clique.SignerPolicy.Block
+		}
+		if newcfg.Clique.SignerPolicy != nil {
+			s2 = newcfg.Clique.SignerPolicy.Block
+		}
+		if isForkIncompatible(s1, s2, head) {
+			return newCompatError("Clique signer policy fork block", s1, s2)
+		}
+	}
 	return nil
 }
 
@@ -673,6 +750,8 @@ type Rules struct {
 	IsByzantium, IsConstantinople, IsPetersburg, IsIstanbul bool
 	IsBerlin, IsLondon                                      bool
 	IsMerge                                                 bool
+
+	PrecompileExtensions []string // Names of the active precompile extensions, ascending
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -681,6 +760,14 @@ func (c *ChainConfig) Rules(num *big.Int, isMerge bool) Rules {
 	if chainID == nil {
 		chainID = new(big.Int)
 	}
+	var extensions []string
+	for name := range c.PrecompileExtensions {
+		if c.IsPrecompileExtension(name, num) {
+			extensions = append(extensions, name)
+		}
+	}
+	sort.Strings(extensions)
+
 	return Rules{
 		ChainID:          new(big.Int).Set(chainID),
 		IsHomestead:      c.IsHomestead(num),
@@ -694,5 +781,7 @@ func (c *ChainConfig) Rules(num *big.Int, isMerge bool) Rules {
 		IsBerlin:         c.IsBerlin(num),
 		IsLondon:         c.IsLondon(num),
 		IsMerge:          isMerge,
+
+		PrecompileExtensions: extensions,
 	}
 }
@@ -0,0 +1,380 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rosetta
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	curveSecp256k1    = "secp256k1"
+	signatureRecovery = "ecdsa_recovery"
+)
+
+// transferOptions are the parameters of a transfer, as derived from its
+// operations during preprocessing.
+type transferOptions struct {
+	From     common.Address  `json:"from"`
+	To       common.Address  `json:"to"`
+	Value    *hexutil.Big    `json:"value"`
+	GasLimit *hexutil.Uint64 `json:"gas_limit,omitempty"`
+}
+
+// transferMetadata are the online parameters needed to construct a transfer.
+type transferMetadata struct {
+	Nonce     hexutil.Uint64 `json:"nonce"`
+	GasLimit  hexutil.Uint64 `json:"gas_limit"`
+	GasTipCap *hexutil.Big   `json:"gas_tip_cap"`
+	GasFeeCap *hexutil.Big   `json:"gas_fee_cap"`
+	ChainID   *hexutil.Big   `json:"chain_id"`
+}
+
+// unsignedTransaction is the opaque unsigned transaction handed to the signers.
+type unsignedTransaction struct {
+	From      common.Address `json:"from"`
+	To        common.Address `json:"to"`
+	Value     *hexutil.Big   `json:"value"`
+	Nonce     hexutil.Uint64 `json:"nonce"`
+	GasLimit  hexutil.Uint64 `json:"gas_limit"`
+	GasTipCap *hexutil.Big   `json:"gas_tip_cap"`
+	GasFeeCap *hexutil.Big   `json:"gas_fee_cap"`
+	ChainID   *hexutil.Big   `json:"chain_id"`
+}
+
+// transaction returns the dynamic fee transaction to be signed.
+func (u *unsignedTransaction) transaction() *types.Transaction {
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   u.ChainID.ToInt(),
+		Nonce:     uint64(u.Nonce),
+		GasTipCap: u.GasTipCap.ToInt(),
+		GasFeeCap: u.GasFeeCap.ToInt(),
+		Gas:       uint64(u.GasLimit),
+		To:        &u.To,
+		Value:     u.Value.ToInt(),
+	})
+}
+
+// decodeHex decodes the hex bytes of a Rosetta model, which are customarily not
+// 0x prefixed.
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
+
+// parseTransfer extracts the sender, recipient and value of a transfer from its
+// debit and credit operations.
+func (h *handler) parseTransfer(ops []*Operation) (common.Address, common.Address, *big.Int, *Error) {
+	if len(ops) != 2 {
+		return common.Address{}, common.Address{}, nil, errInvalidOperations.withDetail(errors.New("expected a debit and a credit operation"))
+	}
+	var (
+		from, to      common.Address
+		debit, credit *big.Int
+	)
+	for _, op := range ops {
+		if op.Type != opCall || op.Amount == nil || op.Amount.Currency == nil || *op.Amount.Currency != *h.currency {
+			return common.Address{}, common.Address{}, nil, errInvalidOperations.withDetail(fmt.Errorf("expected %s operations of %s", opCall, h.currency.Symbol))
+		}
+		addr, err := parseAccount(op.Account)
+		if err != nil {
+			return common.Address{}, common.Address{}, nil, err
+		}
+		value, ok := new(big.Int).SetString(op.Amount.Value, 10)
+		if !ok {
+			return common.Address{}, common.Address{}, nil, errInvalidOperations.withDetail(fmt.Errorf("invalid amount %q", op.Amount.Value))
+		}
+		if value.Sign() < 0 {
+			from, debit = addr, value.Neg(value)
+		} else {
+			to, credit = addr, value
+		}
+	}
+	if debit == nil || credit == nil || debit.Cmp(credit) != 0 {
+		return common.Address{}, common.Address{}, nil, errInvalidOperations.withDetail(errors.New("debit and credit don't balance"))
+	}
+	return from, to, credit, nil
+}
+
+// constructionDerive returns the address of a secp256k1 public key.
+func (h *handler) constructionDerive(ctx context.Context, body []byte) (interface{}, *Error) {
+	var req deriveRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.PublicKey == nil || req.PublicKey.CurveType != curveSecp256k1 {
+		return nil, errInvalidPublicKey
+	}
+	blob, err := decodeHex(req.PublicKey.HexBytes)
+	if err != nil {
+		return nil, errInvalidPublicKey.withDetail(err)
+	}
+	var addr common.Address
+	if len(blob) == 33 {
+		key, err := crypto.DecompressPubkey(blob)
+		if err != nil {
+			return nil, errInvalidPublicKey.withDetail(err)
+		}
+		addr = crypto.PubkeyToAddress(*key)
+	} else {
+		key, err := crypto.UnmarshalPubkey(blob)
+		if err != nil {
+			return nil, errInvalidPublicKey.withDetail(err)
+		}
+		addr = crypto.PubkeyToAddress(*key)
+	}
+	return &deriveResponse{AccountIdentifier: &AccountIdentifier{Address: addr.Hex()}}, nil
+}
+
+// constructionPreprocess derives the transfer options from its operations. An
+// explicit gas limit may be given in the metadata for transfers to contracts.
+func (h *handler) constructionPreprocess(ctx context.Context, body []byte) (interface{}, *Error) {
+	var req preprocessRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	from, to, value, err := h.parseTransfer(req.Operations)
+	if err != nil {
+		return nil, err
+	}
+	opts := &transferOptions{From: from, To: to, Value: (*hexutil.Big)(value)}
+	if limit, ok := req.Metadata["gas_limit"].(string); ok {
+		gas, err := hexutil.DecodeUint64(limit)
+		if err != nil || gas < params.TxGas {
+			return nil, errInvalidRequest.withDetail(fmt.Errorf("invalid gas limit %q", limit))
+		}
+		opts.GasLimit = (*hexutil.Uint64)(&gas)
+	}
+	return &preprocessResponse{
+		Options:            opts,
+		RequiredPublicKeys: []*AccountIdentifier{{Address: from.Hex()}},
+	}, nil
+}
+
+// constructionMetadata fetches the nonce and fee parameters of a transfer. The
+// fee cap leaves room for the base fee doubling before inclusion.
+func (h *handler) constructionMetadata(ctx context.Context, body []byte) (interface{}, *Error) {
+	var req metadataRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.Options == nil {
+		return nil, errInvalidRequest.withDetail(errors.New("missing options"))
+	}
+	nonce, err := h.backend.GetPoolNonce(ctx, req.Options.From)
+	if err != nil {
+		return nil, errInternal.withDetail(err)
+	}
+	tip, err := h.backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, errInternal.withDetail(err)
+	}
+	feeCap := new(big.Int).Set(tip)
+	if baseFee := h.backend.CurrentHeader().BaseFee; baseFee != nil {
+		feeCap.Add(feeCap, new(big.Int).Mul(baseFee, big.NewInt(2)))
+	}
+	gas := params.TxGas
+	if req.Options.GasLimit != nil {
+		gas = uint64(*req.Options.GasLimit)
+	}
+	return &metadataResponse{
+		Metadata: &transferMetadata{
+			Nonce:     hexutil.Uint64(nonce),
+			GasLimit:  hexutil.Uint64(gas),
+			GasTipCap: (*hexutil.Big)(tip),
+			GasFeeCap: (*hexutil.Big)(feeCap),
+			ChainID:   (*hexutil.Big)(h.backend.ChainConfig().ChainID),
+		},
+		SuggestedFee: []*Amount{h.amount(new(big.Int).Mul(feeCap, new(big.Int).SetUint64(gas)), false)},
+	}, nil
+}
+
+// constructionPayloads assembles the unsigned transfer and the payload its
+// sender has to sign.
+func (h *handler) constructionPayloads(ctx context.Context, body []byte) (interface{}, *Error) {
+	var req payloadsRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.Metadata == nil || req.Metadata.GasTipCap == nil || req.Metadata.GasFeeCap == nil || req.Metadata.ChainID == nil {
+		return nil, errInvalidRequest.withDetail(errors.New("missing metadata"))
+	}
+	if req.Metadata.ChainID.ToInt().Cmp(h.backend.ChainConfig().ChainID) != 0 {
+		return nil, errInvalidRequest.withDetail(errors.New("chain id mismatch"))
+	}
+	from, to, value, err := h.parseTransfer(req.Operations)
+	if err != nil {
+		return nil, err
+	}
+	unsigned := &unsignedTransaction{
+		From:      from,
+		To:        to,
+		Value:     (*hexutil.Big)(value),
+		Nonce:     req.Metadata.Nonce,
+		GasLimit:  req.Metadata.GasLimit,
+		GasTipCap: req.Metadata.GasTipCap,
+		GasFeeCap: req.Metadata.GasFeeCap,
+		ChainID:   req.Metadata.ChainID,
+	}
+	blob, _ := json.Marshal(unsigned)
+
+	signer := types.LatestSignerForChainID(req.Metadata.ChainID.ToInt())
+	hash := signer.Hash(unsigned.transaction())
+	return &payloadsResponse{
+		UnsignedTransaction: string(blob),
+		Payloads: []*SigningPayload{{
+			AccountIdentifier: &AccountIdentifier{Address: from.Hex()},
+			HexBytes:          hex.EncodeToString(hash[:]),
+			SignatureType:     signatureRecovery,
+		}},
+	}, nil
+}
+
+// constructionCombine attaches the sender's signature to an unsigned transfer.
+func (h *handler) constructionCombine(ctx context.Context, body []byte) (interface{}, *Error) {
+	var req combineRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	unsigned, err := h.parseUnsigned(req.UnsignedTransaction)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Signatures) != 1 || req.Signatures[0].SignatureType != signatureRecovery {
+		return nil, errInvalidSignature.withDetail(fmt.Errorf("expected a single %s signature", signatureRecovery))
+	}
+	sig, derr := decodeHex(req.Signatures[0].HexBytes)
+	if derr != nil || len(sig) != crypto.SignatureLength {
+		return nil, errInvalidSignature
+	}
+	signer := types.LatestSignerForChainID(unsigned.ChainID.ToInt())
+	tx, serr := unsigned.transaction().WithSignature(signer, sig)
+	if serr != nil {
+		return nil, errInvalidSignature.withDetail(serr)
+	}
+	if sender, serr := types.Sender(signer, tx); serr != nil || sender != unsigned.From {
+		return nil, errInvalidSignature.withDetail(errors.New("signature not made by the sender"))
+	}
+	blob, serr := tx.MarshalBinary()
+	if serr != nil {
+		return nil, errInternal.withDetail(serr)
+	}
+	return &combineResponse{SignedTransaction: hexutil.Encode(blob)}, nil
+}
+
+// constructionParse returns the operations and signers of a transfer, signed or
+// not, so callers can verify it before broadcasting.
+func (h *handler) constructionParse(ctx context.Context, body []byte) (interface{}, *Error) {
+	var req parseRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	var (
+		from, to common.Address
+		value    *big.Int
+		signers  = []*AccountIdentifier{}
+	)
+	if req.Signed {
+		tx, err := h.parseSigned(req.Transaction)
+		if err != nil {
+			return nil, err
+		}
+		if tx.To() == nil {
+			return nil, errInvalidTransaction.withDetail(errors.New("contract creation"))
+		}
+		sender, serr := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if serr != nil {
+			return nil, errInvalidSignature.withDetail(serr)
+		}
+		from, to, value = sender, *tx.To(), tx.Value()
+		signers = append(signers, &AccountIdentifier{Address: sender.Hex()})
+	} else {
+		unsigned, err := h.parseUnsigned(req.Transaction)
+		if err != nil {
+			return nil, err
+		}
+		from, to, value = unsigned.From, unsigned.To, unsigned.Value.ToInt()
+	}
+	return &parseResponse{
+		Operations:               h.transferOperations(opCall, from, to, value, nil),
+		AccountIdentifierSigners: signers,
+	}, nil
+}
+
+// constructionHash returns the hash of a signed transaction.
+func (h *handler) constructionHash(ctx context.Context, body []byte) (interface{}, *Error) {
+	var req signedTransactionRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	tx, err := h.parseSigned(req.SignedTransaction)
+	if err != nil {
+		return nil, err
+	}
+	return &transactionIdentifierResponse{TransactionIdentifier: &TransactionIdentifier{Hash: tx.Hash().Hex()}}, nil
+}
+
+// constructionSubmit injects a signed transaction into the pool.
+func (h *handler) constructionSubmit(ctx context.Context, body []byte) (interface{}, *Error) {
+	var req signedTransactionRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	tx, err := h.parseSigned(req.SignedTransaction)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.backend.SendTx(ctx, tx); err != nil {
+		return nil, errSubmitFailed.withDetail(err)
+	}
+	return &transactionIdentifierResponse{TransactionIdentifier: &TransactionIdentifier{Hash: tx.Hash().Hex()}}, nil
+}
+
+// parseUnsigned decodes an unsigned transfer created by the payloads endpoint.
+func (h *handler) parseUnsigned(s string) (*unsignedTransaction, *Error) {
+	unsigned := new(unsignedTransaction)
+	if err := json.Unmarshal([]byte(s), unsigned); err != nil {
+		return nil, errInvalidTransaction.withDetail(err)
+	}
+	if unsigned.Value == nil || unsigned.GasTipCap == nil || unsigned.GasFeeCap == nil || unsigned.ChainID == nil {
+		return nil, errInvalidTransaction.withDetail(errors.New("missing fields"))
+	}
+	return unsigned, nil
+}
+
+// parseSigned decodes a signed transaction in its binary encoding.
+func (h *handler) parseSigned(s string) (*types.Transaction, *Error) {
+	blob, err := hexutil.Decode(s)
+	if err != nil {
+		return nil, errInvalidTransaction.withDetail(err)
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(blob); err != nil {
+		return nil, errInvalidTransaction.withDetail(err)
+	}
+	return tx, nil
+}
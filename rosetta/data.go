@@ -0,0 +1,242 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rosetta
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// networkList returns the single network served by the node.
+func (h *handler) networkList(ctx context.Context, body []byte) (interface{}, *Error) {
+	return &networkListResponse{NetworkIdentifiers: []*NetworkIdentifier{h.network}}, nil
+}
+
+// networkStatus returns the current head, the genesis block and the sync
+// progress of the node.
+func (h *handler) networkStatus(ctx context.Context, body []byte) (interface{}, *Error) {
+	genesis, err := h.backend.HeaderByNumber(ctx, 0)
+	if err != nil || genesis == nil {
+		return nil, errInternal
+	}
+	var (
+		head     = h.backend.CurrentHeader()
+		progress = h.backend.SyncProgress()
+		current  = head.Number.Uint64()
+		target   = current
+	)
+	if progress.HighestBlock > target {
+		target = progress.HighestBlock
+	}
+	peers := h.peers()
+	if peers == nil {
+		peers = []*Peer{}
+	}
+	return &networkStatusResponse{
+		CurrentBlockIdentifier: blockIdentifier(head),
+		CurrentBlockTimestamp:  int64(head.Time) * 1000,
+		GenesisBlockIdentifier: blockIdentifier(genesis),
+		SyncStatus: &SyncStatus{
+			CurrentIndex: int64(current),
+			TargetIndex:  int64(target),
+			Synced:       current >= target,
+		},
+		Peers: peers,
+	}, nil
+}
+
+// networkOptions returns the versions and the operation types, statuses and
+// errors the node may return.
+func (h *handler) networkOptions(ctx context.Context, body []byte) (interface{}, *Error) {
+	return &networkOptionsResponse{
+		Version: &Version{
+			RosettaVersion: rosettaVersion,
+			NodeVersion:    params.VersionWithMeta,
+		},
+		Allow: &Allow{
+			OperationStatuses: []*OperationStatus{
+				{Status: statusSuccess, Successful: true},
+				{Status: statusFailure, Successful: false},
+			},
+			OperationTypes:          operationTypes,
+			Errors:                  allErrors,
+			HistoricalBalanceLookup: true,
+			CallMethods:             []string{},
+			BalanceExemptions:       []interface{}{},
+		},
+	}, nil
+}
+
+// accountBalance returns the balance and nonce of an account at the requested
+// block, the current head if none is given.
+func (h *handler) accountBalance(ctx context.Context, body []byte) (interface{}, *Error) {
+	var req accountBalanceRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	addr, err := parseAccount(req.AccountIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	header, err := h.header(ctx, req.BlockIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	statedb, _, serr := h.backend.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(header.Hash(), false))
+	if serr != nil || statedb == nil {
+		return nil, errStateUnavailable
+	}
+	return &accountBalanceResponse{
+		BlockIdentifier: blockIdentifier(header),
+		Balances:        []*Amount{h.amount(statedb.GetBalance(addr), false)},
+		Metadata: map[string]interface{}{
+			"nonce": hexutil.Uint64(statedb.GetNonce(addr)),
+		},
+	}, nil
+}
+
+// block returns a block with all its balance changing operations.
+func (h *handler) block(ctx context.Context, body []byte) (interface{}, *Error) {
+	var req blockRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	header, err := h.header(ctx, req.BlockIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	block, err := h.buildBlock(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	return &blockResponse{Block: block}, nil
+}
+
+// blockTransaction returns a single transaction of a block, which may also be
+// the block's sealer reward.
+func (h *handler) blockTransaction(ctx context.Context, body []byte) (interface{}, *Error) {
+	var req blockTransactionRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.BlockIdentifier == nil || req.TransactionIdentifier == nil {
+		return nil, errInvalidRequest
+	}
+	index := req.BlockIdentifier.Index
+	header, err := h.header(ctx, &PartialBlockIdentifier{Index: &index, Hash: &req.BlockIdentifier.Hash})
+	if err != nil {
+		return nil, err
+	}
+	block, err := h.buildBlock(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range block.Transactions {
+		if tx.TransactionIdentifier.Hash == req.TransactionIdentifier.Hash {
+			return &transactionResponse{Transaction: tx}, nil
+		}
+	}
+	return nil, errTransactionNotFound
+}
+
+// mempool returns the hashes of all transactions in the pool.
+func (h *handler) mempool(ctx context.Context, body []byte) (interface{}, *Error) {
+	txs, err := h.backend.GetPoolTransactions()
+	if err != nil {
+		return nil, errInternal.withDetail(err)
+	}
+	ids := make([]*TransactionIdentifier, len(txs))
+	for i, tx := range txs {
+		ids[i] = &TransactionIdentifier{Hash: tx.Hash().Hex()}
+	}
+	return &mempoolResponse{TransactionIdentifiers: ids}, nil
+}
+
+// mempoolTransaction returns the transfer a pooled transaction is expected to
+// make. Fees and internal transfers are only known once it's executed.
+func (h *handler) mempoolTransaction(ctx context.Context, body []byte) (interface{}, *Error) {
+	var req mempoolTransactionRequest
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if req.TransactionIdentifier == nil {
+		return nil, errInvalidRequest
+	}
+	tx := h.backend.GetPoolTransaction(common.HexToHash(req.TransactionIdentifier.Hash))
+	if tx == nil {
+		return nil, errTransactionNotFound
+	}
+	from, err := types.Sender(types.LatestSigner(h.backend.ChainConfig()), tx)
+	if err != nil {
+		return nil, errInvalidTransaction.withDetail(err)
+	}
+	var ops []*Operation
+	if to := tx.To(); to != nil && tx.Value().Sign() > 0 {
+		ops = h.transferOperations(opCall, from, *to, tx.Value(), nil)
+	}
+	if ops == nil {
+		ops = []*Operation{}
+	}
+	return &transactionResponse{Transaction: &Transaction{
+		TransactionIdentifier: &TransactionIdentifier{Hash: tx.Hash().Hex()},
+		Operations:            ops,
+	}}, nil
+}
+
+// header resolves a partial block identifier into a canonical header.
+func (h *handler) header(ctx context.Context, id *PartialBlockIdentifier) (*types.Header, *Error) {
+	var (
+		header *types.Header
+		err    error
+	)
+	switch {
+	case id == nil || (id.Index == nil && id.Hash == nil):
+		header = h.backend.CurrentHeader()
+	case id.Hash != nil:
+		header, err = h.backend.HeaderByHash(ctx, common.HexToHash(*id.Hash))
+		if header != nil && id.Index != nil && header.Number.Int64() != *id.Index {
+			return nil, errBlockNotFound
+		}
+	default:
+		if *id.Index < 0 {
+			return nil, errInvalidRequest
+		}
+		header, err = h.backend.HeaderByNumber(ctx, rpc.BlockNumber(*id.Index))
+	}
+	if err != nil || header == nil {
+		return nil, errBlockNotFound
+	}
+	return header, nil
+}
+
+// blockIdentifier returns the Rosetta identifier of a header.
+func blockIdentifier(header *types.Header) *BlockIdentifier {
+	return &BlockIdentifier{Index: header.Number.Int64(), Hash: header.Hash().Hex()}
+}
+
+// parseAccount parses the address of an account identifier.
+func parseAccount(id *AccountIdentifier) (common.Address, *Error) {
+	if id == nil || !common.IsHexAddress(id.Address) {
+		return common.Address{}, errInvalidAddress
+	}
+	return common.HexToAddress(id.Address), nil
+}
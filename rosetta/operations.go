@@ -0,0 +1,258 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rosetta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+const (
+	statusSuccess = "SUCCESS"
+	statusFailure = "FAILURE"
+
+	opFee          = "FEE"           // Gas paid by the sender of a transaction
+	opCall         = "CALL"          // Value transferred by a message call
+	opCreate       = "CREATE"        // Value endowed to a contract created with CREATE
+	opCreate2      = "CREATE2"       // Value endowed to a contract created with CREATE2
+	opSelfDestruct = "SELFDESTRUCT"  // Balance moved to the beneficiary of a destructed contract
+	opSealerReward = "SEALER_REWARD" // Priority fees of a block credited to its clique sealer
+)
+
+// operationTypes are all operation types the node may return.
+var operationTypes = []string{opFee, opCall, opCreate, opCreate2, opSelfDestruct, opSealerReward}
+
+// callTracer is the tracer used to find the value transfers of transactions.
+var callTracer = "callTracer"
+
+// callFrame is a call frame as reported by the native call tracer.
+type callFrame struct {
+	Type  string      `json:"type"`
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Value string      `json:"value"`
+	Error string      `json:"error"`
+	Calls []callFrame `json:"calls"`
+}
+
+// amount returns the Rosetta amount of a value in the native currency.
+func (h *handler) amount(value *big.Int, negate bool) *Amount {
+	if negate {
+		value = new(big.Int).Neg(value)
+	}
+	return &Amount{Value: value.String(), Currency: h.currency}
+}
+
+// operationStatus returns the status of an executed operation.
+func operationStatus(failed bool) *string {
+	status := statusSuccess
+	if failed {
+		status = statusFailure
+	}
+	return &status
+}
+
+// appendOperation adds an operation to a transaction, optionally relating it to
+// the previous operation.
+func appendOperation(ops []*Operation, op *Operation, related bool) []*Operation {
+	op.OperationIdentifier = &OperationIdentifier{Index: int64(len(ops))}
+	if related && len(ops) > 0 {
+		op.RelatedOperations = []*OperationIdentifier{ops[len(ops)-1].OperationIdentifier}
+	}
+	return append(ops, op)
+}
+
+// transferOperations returns the debit and credit operations of a transfer.
+// The status is nil for operations of transactions yet to be executed.
+func (h *handler) transferOperations(typ string, from, to common.Address, value *big.Int, status *string) []*Operation {
+	ops := appendOperation(nil, &Operation{
+		Type:    typ,
+		Status:  status,
+		Account: &AccountIdentifier{Address: from.Hex()},
+		Amount:  h.amount(value, true),
+	}, false)
+	return appendOperation(ops, &Operation{
+		Type:    typ,
+		Status:  status,
+		Account: &AccountIdentifier{Address: to.Hex()},
+		Amount:  h.amount(value, false),
+	}, true)
+}
+
+// appendFrame adds the value transfers of a call frame and its children to the
+// operations. Transfers of reverted frames are reported as failed.
+func (h *handler) appendFrame(ops []*Operation, frame *callFrame, failed bool) []*Operation {
+	failed = failed || frame.Error != ""
+
+	switch frame.Type {
+	case opCall, opCreate, opCreate2, opSelfDestruct:
+		value, err := hexutil.DecodeBig(frame.Value)
+		if err != nil || value.Sign() == 0 {
+			break
+		}
+		status := operationStatus(failed)
+		from, to := common.HexToAddress(frame.From), common.HexToAddress(frame.To)
+		if frame.Type == opSelfDestruct && from == to {
+			// Destructing to itself burns the balance, there's no credit
+			ops = appendOperation(ops, &Operation{
+				Type:    frame.Type,
+				Status:  status,
+				Account: &AccountIdentifier{Address: from.Hex()},
+				Amount:  h.amount(value, true),
+			}, false)
+			break
+		}
+		for i, op := range h.transferOperations(frame.Type, from, to, value, status) {
+			op.RelatedOperations = nil
+			ops = appendOperation(ops, op, i > 0)
+		}
+	}
+	for i := range frame.Calls {
+		ops = h.appendFrame(ops, &frame.Calls[i], failed)
+	}
+	return ops
+}
+
+// buildBlock assembles the Rosetta representation of a canonical block. The
+// operations are derived from call traces, so the state of the parent must be
+// available or regenerable.
+func (h *handler) buildBlock(ctx context.Context, header *types.Header) (*Block, *Error) {
+	block, err := h.backend.BlockByHash(ctx, header.Hash())
+	if err != nil || block == nil {
+		return nil, errBlockNotFound
+	}
+	parent := blockIdentifier(header)
+	if header.Number.Sign() > 0 {
+		parent = &BlockIdentifier{Index: header.Number.Int64() - 1, Hash: header.ParentHash.Hex()}
+	}
+	sealer, err := h.backend.Engine().Author(header)
+	if err != nil {
+		return nil, errInternal.withDetail(err)
+	}
+	txs, reward, rerr := h.buildTransactions(ctx, block)
+	if rerr != nil {
+		return nil, rerr
+	}
+	if reward.Sign() > 0 {
+		// Clique mints no coins, sealers are rewarded with the priority fees of
+		// the block, which are reported as a transaction keyed by the block hash
+		ops := appendOperation(nil, &Operation{
+			Type:    opSealerReward,
+			Status:  operationStatus(false),
+			Account: &AccountIdentifier{Address: sealer.Hex()},
+			Amount:  h.amount(reward, false),
+		}, false)
+		txs = append([]*Transaction{{
+			TransactionIdentifier: &TransactionIdentifier{Hash: block.Hash().Hex()},
+			Operations:            ops,
+		}}, txs...)
+	}
+	meta := map[string]interface{}{
+		"sealer":     sealer,
+		"difficulty": (*hexutil.Big)(header.Difficulty),
+		"gas_limit":  hexutil.Uint64(header.GasLimit),
+		"gas_used":   hexutil.Uint64(header.GasUsed),
+	}
+	if header.BaseFee != nil {
+		meta["base_fee"] = (*hexutil.Big)(header.BaseFee)
+	}
+	return &Block{
+		BlockIdentifier:       blockIdentifier(header),
+		ParentBlockIdentifier: parent,
+		Timestamp:             int64(header.Time) * 1000,
+		Transactions:          txs,
+		Metadata:              meta,
+	}, nil
+}
+
+// buildTransactions assembles the transactions of a block, returning them along
+// with the priority fees earned by the sealer.
+func (h *handler) buildTransactions(ctx context.Context, block *types.Block) ([]*Transaction, *big.Int, *Error) {
+	var (
+		txs     = []*Transaction{}
+		reward  = new(big.Int)
+		baseFee = block.BaseFee()
+	)
+	if len(block.Transactions()) == 0 {
+		return txs, reward, nil
+	}
+	receipts, err := h.backend.GetReceipts(ctx, block.Hash())
+	if err != nil || len(receipts) != len(block.Transactions()) {
+		return nil, nil, errInternal.withDetail(errors.New("receipts unavailable"))
+	}
+	traces, err := h.tracer.TraceBlockByHash(ctx, block.Hash(), &tracers.TraceConfig{Tracer: &callTracer})
+	if err != nil {
+		return nil, nil, errStateUnavailable.withDetail(err)
+	}
+	signer := types.MakeSigner(h.backend.ChainConfig(), block.Number())
+	for i, tx := range block.Transactions() {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, nil, errInternal.withDetail(err)
+		}
+		if traces[i].Error != "" {
+			return nil, nil, errInternal.withDetail(errors.New(traces[i].Error))
+		}
+		blob, err := json.Marshal(traces[i].Result)
+		if err != nil {
+			return nil, nil, errInternal.withDetail(err)
+		}
+		var frame callFrame
+		if err := json.Unmarshal(blob, &frame); err != nil {
+			return nil, nil, errInternal.withDetail(err)
+		}
+		var (
+			gasUsed = new(big.Int).SetUint64(receipts[i].GasUsed)
+			tip     = tx.EffectiveGasTipValue(baseFee)
+			price   = new(big.Int).Set(tip)
+		)
+		if baseFee != nil {
+			price.Add(price, baseFee)
+		}
+		reward.Add(reward, new(big.Int).Mul(gasUsed, tip))
+
+		ops := appendOperation(nil, &Operation{
+			Type:    opFee,
+			Status:  operationStatus(false),
+			Account: &AccountIdentifier{Address: from.Hex()},
+			Amount:  h.amount(new(big.Int).Mul(gasUsed, price), true),
+		}, false)
+		ops = h.appendFrame(ops, &frame, false)
+
+		meta := map[string]interface{}{
+			"gas_used":  hexutil.Uint64(receipts[i].GasUsed),
+			"gas_price": (*hexutil.Big)(price),
+			"status":    hexutil.Uint64(receipts[i].Status),
+		}
+		if tx.To() == nil {
+			meta["contract_address"] = receipts[i].ContractAddress
+		}
+		txs = append(txs, &Transaction{
+			TransactionIdentifier: &TransactionIdentifier{Hash: tx.Hash().Hex()},
+			Operations:            ops,
+			Metadata:              meta,
+		})
+	}
+	return txs, reward, nil
+}
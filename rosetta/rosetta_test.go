@@ -0,0 +1,407 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rosetta
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testSealer  = common.HexToAddress("0xc0")
+	testForward = common.HexToAddress("0xf0") // Forwards 200 wei to 0xbb on every call
+	testRevert  = common.HexToAddress("0xfd") // Reverts every call
+)
+
+// testBackend is a Rosetta backend over an archive chain and a fake pool.
+type testBackend struct {
+	db    ethdb.Database
+	chain *core.BlockChain
+	sent  []*types.Transaction
+}
+
+func newTestBackend(t *testing.T) *testBackend {
+	var (
+		config = params.TestChainConfig
+		signer = types.LatestSigner(config)
+		db     = rawdb.NewMemoryDatabase()
+		gspec  = &core.Genesis{
+			Config: config,
+			Alloc: core.GenesisAlloc{
+				testAddr: {Balance: big.NewInt(params.Ether)},
+				// CALL(gas, 0xbb, 200, 0, 0, 0, 0) STOP
+				testForward: {Code: []byte{0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0xc8, 0x60, 0xbb, 0x5a, 0xf1, 0x00}, Balance: new(big.Int)},
+				// REVERT(0, 0)
+				testRevert: {Code: []byte{0x60, 0x00, 0x60, 0x00, 0xfd}, Balance: new(big.Int)},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(config, genesis, ethash.NewFaker(), db, 2, func(i int, b *core.BlockGen) {
+		if i == 1 {
+			return
+		}
+		b.SetCoinbase(testSealer)
+		price := new(big.Int).Add(b.BaseFee(), big.NewInt(5))
+		for _, transfer := range []struct {
+			to    common.Address
+			value int64
+		}{
+			{common.HexToAddress("0xaa"), 1000},
+			{testForward, 500},
+			{testRevert, 300},
+		} {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(testAddr), transfer.to, big.NewInt(transfer.value), 100000, price, nil), signer, testKey)
+			b.AddTx(tx)
+		}
+	})
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	return &testBackend{db: db, chain: chain}
+}
+
+func (b *testBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.chain.GetHeaderByHash(hash), nil
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.LatestBlockNumber {
+		return b.chain.CurrentHeader(), nil
+	}
+	return b.chain.GetHeaderByNumber(uint64(number)), nil
+}
+
+func (b *testBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return b.chain.GetBlockByHash(hash), nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber {
+		return b.chain.CurrentBlock(), nil
+	}
+	return b.chain.GetBlockByNumber(uint64(number)), nil
+}
+
+func (b *testBackend) GetTransaction(ctx context.Context, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
+	tx, blockHash, number, index := rawdb.ReadTransaction(b.db, hash)
+	return tx, blockHash, number, index, nil
+}
+
+func (b *testBackend) RPCGasCap() uint64                { return 25000000 }
+func (b *testBackend) ChainConfig() *params.ChainConfig { return b.chain.Config() }
+func (b *testBackend) Engine() consensus.Engine         { return b.chain.Engine() }
+func (b *testBackend) ChainDb() ethdb.Database          { return b.db }
+func (b *testBackend) CurrentHeader() *types.Header     { return b.chain.CurrentHeader() }
+
+func (b *testBackend) StateAtBlock(ctx context.Context, block *types.Block, reexec uint64, base *state.StateDB, checkLive, preferDisk bool) (*state.StateDB, error) {
+	return b.chain.StateAt(block.Root())
+}
+
+func (b *testBackend) StateAtTransaction(ctx context.Context, block *types.Block, txIndex int, reexec uint64) (core.Message, vm.BlockContext, *state.StateDB, error) {
+	return nil, vm.BlockContext{}, nil, errors.New("not implemented")
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.chain.GetReceiptsByHash(hash), nil
+}
+
+func (b *testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	hash, _ := blockNrOrHash.Hash()
+	header := b.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, nil, errors.New("header not found")
+	}
+	statedb, err := b.chain.StateAt(header.Root)
+	return statedb, header, err
+}
+
+func (b *testBackend) SyncProgress() ethereum.SyncProgress { return ethereum.SyncProgress{} }
+
+func (b *testBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(params.GWei), nil
+}
+
+func (b *testBackend) GetPoolTransactions() (types.Transactions, error) { return b.sent, nil }
+
+func (b *testBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	for _, tx := range b.sent {
+		if tx.Hash() == hash {
+			return tx
+		}
+	}
+	return nil
+}
+
+func (b *testBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	statedb, err := b.chain.State()
+	if err != nil {
+		return 0, err
+	}
+	return statedb.GetNonce(addr), nil
+}
+
+func (b *testBackend) SendTx(ctx context.Context, tx *types.Transaction) error {
+	b.sent = append(b.sent, tx)
+	return nil
+}
+
+// call posts a request to a Rosetta endpoint, decoding the response into res
+// and returning the Rosetta error if the request failed.
+func call(t *testing.T, h *handler, path string, req map[string]interface{}, res interface{}) *Error {
+	t.Helper()
+
+	if _, ok := req["network_identifier"]; !ok {
+		req["network_identifier"] = h.network
+	}
+	body, _ := json.Marshal(req)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/rosetta"+path, bytes.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		var rerr Error
+		if err := json.Unmarshal(rec.Body.Bytes(), &rerr); err != nil {
+			t.Fatalf("%s: failed to decode error: %v", path, err)
+		}
+		return &rerr
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), res); err != nil {
+		t.Fatalf("%s: failed to decode response: %v", path, err)
+	}
+	return nil
+}
+
+// summary renders the operations of a transaction in a compact form.
+func summary(tx *Transaction) string {
+	var buf bytes.Buffer
+	for _, op := range tx.Operations {
+		fmt.Fprintf(&buf, "%s/%s %s %s;", op.Type, *op.Status, op.Account.Address, op.Amount.Value)
+	}
+	return buf.String()
+}
+
+// Tests that blocks report fees, value transfers including internal and failed
+// ones, and the sealer's reward.
+func TestBlock(t *testing.T) {
+	backend := newTestBackend(t)
+	defer backend.chain.Stop()
+	h := newHandler(backend, DefaultConfig, func() []*Peer { return nil })
+
+	var res blockResponse
+	if err := call(t, h, "/block", map[string]interface{}{"block_identifier": map[string]interface{}{"index": 1}}, &res); err != nil {
+		t.Fatalf("failed to retrieve block: %v", err)
+	}
+	var (
+		block    = backend.chain.GetBlockByNumber(1)
+		receipts = backend.chain.GetReceiptsByHash(block.Hash())
+		price    = new(big.Int).Add(block.BaseFee(), big.NewInt(5))
+		fee      = func(i int) string {
+			return new(big.Int).Mul(price, new(big.Int).SetUint64(receipts[i].GasUsed)).String()
+		}
+		reward = new(big.Int)
+	)
+	for _, receipt := range receipts {
+		reward.Add(reward, new(big.Int).SetUint64(5*receipt.GasUsed))
+	}
+	if res.Block.BlockIdentifier.Hash != block.Hash().Hex() || res.Block.ParentBlockIdentifier.Hash != block.ParentHash().Hex() {
+		t.Errorf("block identifiers mismatch: have %v/%v", res.Block.BlockIdentifier, res.Block.ParentBlockIdentifier)
+	}
+	sender := testAddr.Hex()
+	want := []string{
+		fmt.Sprintf("SEALER_REWARD/SUCCESS %s %s;", testSealer.Hex(), reward),
+		fmt.Sprintf("FEE/SUCCESS %s -%s;CALL/SUCCESS %s -1000;CALL/SUCCESS %s 1000;", sender, fee(0), sender, common.HexToAddress("0xaa").Hex()),
+		fmt.Sprintf("FEE/SUCCESS %s -%s;CALL/SUCCESS %s -500;CALL/SUCCESS %s 500;CALL/SUCCESS %s -200;CALL/SUCCESS %s 200;", sender, fee(1), sender, testForward.Hex(), testForward.Hex(), common.HexToAddress("0xbb").Hex()),
+		fmt.Sprintf("FEE/SUCCESS %s -%s;CALL/FAILURE %s -300;CALL/FAILURE %s 300;", sender, fee(2), sender, testRevert.Hex()),
+	}
+	if len(res.Block.Transactions) != len(want) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(res.Block.Transactions), len(want))
+	}
+	for i, tx := range res.Block.Transactions {
+		if have := summary(tx); have != want[i] {
+			t.Errorf("transaction %d operations mismatch:\nhave %s\nwant %s", i, have, want[i])
+		}
+	}
+	// Retrieve the internal transfer individually
+	var single transactionResponse
+	req := map[string]interface{}{
+		"block_identifier":       res.Block.BlockIdentifier,
+		"transaction_identifier": map[string]interface{}{"hash": block.Transactions()[1].Hash().Hex()},
+	}
+	if err := call(t, h, "/block/transaction", req, &single); err != nil {
+		t.Fatalf("failed to retrieve transaction: %v", err)
+	}
+	if have := summary(single.Transaction); have != want[2] {
+		t.Errorf("single transaction mismatch: have %s, want %s", have, want[2])
+	}
+	// Empty blocks carry no reward
+	if err := call(t, h, "/block", map[string]interface{}{"block_identifier": map[string]interface{}{"index": 2}}, &res); err != nil {
+		t.Fatalf("failed to retrieve block: %v", err)
+	}
+	if len(res.Block.Transactions) != 0 {
+		t.Errorf("empty block has %d transactions", len(res.Block.Transactions))
+	}
+	if err := call(t, h, "/block", map[string]interface{}{"block_identifier": map[string]interface{}{"index": 3}}, &res); err == nil || err.Code != errBlockNotFound.Code {
+		t.Errorf("future block error mismatch: have %v, want %v", err, errBlockNotFound)
+	}
+}
+
+// Tests that balances are served at historical blocks and requests to other
+// networks are rejected.
+func TestAccountBalance(t *testing.T) {
+	backend := newTestBackend(t)
+	defer backend.chain.Stop()
+	h := newHandler(backend, DefaultConfig, func() []*Peer { return nil })
+
+	var res accountBalanceResponse
+	req := map[string]interface{}{
+		"account_identifier": map[string]interface{}{"address": testAddr.Hex()},
+		"block_identifier":   map[string]interface{}{"index": 0},
+	}
+	if err := call(t, h, "/account/balance", req, &res); err != nil {
+		t.Fatalf("failed to retrieve balance: %v", err)
+	}
+	if res.Balances[0].Value != big.NewInt(params.Ether).String() || res.Balances[0].Currency.Symbol != "AKS" {
+		t.Errorf("genesis balance mismatch: have %s %s", res.Balances[0].Value, res.Balances[0].Currency.Symbol)
+	}
+	delete(req, "block_identifier")
+	if err := call(t, h, "/account/balance", req, &res); err != nil {
+		t.Fatalf("failed to retrieve balance: %v", err)
+	}
+	statedb, _ := backend.chain.State()
+	if res.Balances[0].Value != statedb.GetBalance(testAddr).String() || res.BlockIdentifier.Index != 2 {
+		t.Errorf("head balance mismatch: have %s at #%d", res.Balances[0].Value, res.BlockIdentifier.Index)
+	}
+	req["network_identifier"] = &NetworkIdentifier{Blockchain: "Ethereum", Network: "1"}
+	if err := call(t, h, "/account/balance", req, &res); err == nil || err.Code != errUnsupportedNetwork.Code {
+		t.Errorf("foreign network error mismatch: have %v, want %v", err, errUnsupportedNetwork)
+	}
+}
+
+// Tests the full construction flow of a transfer, from its operations to its
+// submission.
+func TestConstruction(t *testing.T) {
+	backend := newTestBackend(t)
+	defer backend.chain.Stop()
+	h := newHandler(backend, DefaultConfig, func() []*Peer { return nil })
+
+	var derived deriveResponse
+	pubkey := map[string]interface{}{"hex_bytes": hex.EncodeToString(crypto.CompressPubkey(&testKey.PublicKey)), "curve_type": curveSecp256k1}
+	if err := call(t, h, "/construction/derive", map[string]interface{}{"public_key": pubkey}, &derived); err != nil {
+		t.Fatalf("failed to derive address: %v", err)
+	}
+	if derived.AccountIdentifier.Address != testAddr.Hex() {
+		t.Fatalf("derived address mismatch: have %s, want %s", derived.AccountIdentifier.Address, testAddr.Hex())
+	}
+	recipient := common.HexToAddress("0xaa")
+	ops := h.transferOperations(opCall, testAddr, recipient, big.NewInt(12345), nil)
+
+	var pre preprocessResponse
+	if err := call(t, h, "/construction/preprocess", map[string]interface{}{"operations": ops}, &pre); err != nil {
+		t.Fatalf("failed to preprocess: %v", err)
+	}
+	var meta metadataResponse
+	if err := call(t, h, "/construction/metadata", map[string]interface{}{"options": pre.Options}, &meta); err != nil {
+		t.Fatalf("failed to fetch metadata: %v", err)
+	}
+	if meta.Metadata.Nonce != 3 || meta.Metadata.GasLimit != 21000 {
+		t.Errorf("metadata mismatch: nonce %d, gas %d", meta.Metadata.Nonce, meta.Metadata.GasLimit)
+	}
+	var payloads payloadsResponse
+	if err := call(t, h, "/construction/payloads", map[string]interface{}{"operations": ops, "metadata": meta.Metadata}, &payloads); err != nil {
+		t.Fatalf("failed to create payloads: %v", err)
+	}
+	var parsed parseResponse
+	if err := call(t, h, "/construction/parse", map[string]interface{}{"signed": false, "transaction": payloads.UnsignedTransaction}, &parsed); err != nil {
+		t.Fatalf("failed to parse unsigned transaction: %v", err)
+	}
+	if len(parsed.Operations) != 2 || parsed.Operations[1].Amount.Value != "12345" || len(parsed.AccountIdentifierSigners) != 0 {
+		t.Errorf("unsigned transaction parse mismatch: %d ops, %d signers", len(parsed.Operations), len(parsed.AccountIdentifierSigners))
+	}
+	digest, _ := hex.DecodeString(payloads.Payloads[0].HexBytes)
+	sig, err := crypto.Sign(digest, testKey)
+	if err != nil {
+		t.Fatalf("failed to sign payload: %v", err)
+	}
+	var combined combineResponse
+	signatures := []map[string]interface{}{{
+		"signing_payload": payloads.Payloads[0],
+		"public_key":      pubkey,
+		"signature_type":  signatureRecovery,
+		"hex_bytes":       hex.EncodeToString(sig),
+	}}
+	if err := call(t, h, "/construction/combine", map[string]interface{}{"unsigned_transaction": payloads.UnsignedTransaction, "signatures": signatures}, &combined); err != nil {
+		t.Fatalf("failed to combine: %v", err)
+	}
+	if err := call(t, h, "/construction/parse", map[string]interface{}{"signed": true, "transaction": combined.SignedTransaction}, &parsed); err != nil {
+		t.Fatalf("failed to parse signed transaction: %v", err)
+	}
+	if len(parsed.AccountIdentifierSigners) != 1 || parsed.AccountIdentifierSigners[0].Address != testAddr.Hex() {
+		t.Errorf("signers mismatch: %v", parsed.AccountIdentifierSigners)
+	}
+	var hashed, submitted transactionIdentifierResponse
+	if err := call(t, h, "/construction/hash", map[string]interface{}{"signed_transaction": combined.SignedTransaction}, &hashed); err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	if err := call(t, h, "/construction/submit", map[string]interface{}{"signed_transaction": combined.SignedTransaction}, &submitted); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if len(backend.sent) != 1 || backend.sent[0].Hash().Hex() != hashed.TransactionIdentifier.Hash || submitted.TransactionIdentifier.Hash != hashed.TransactionIdentifier.Hash {
+		t.Fatalf("submitted transaction mismatch")
+	}
+	var pooled transactionResponse
+	if err := call(t, h, "/mempool/transaction", map[string]interface{}{"transaction_identifier": hashed.TransactionIdentifier}, &pooled); err != nil {
+		t.Fatalf("failed to retrieve pooled transaction: %v", err)
+	}
+	if len(pooled.Transaction.Operations) != 2 || pooled.Transaction.Operations[0].Account.Address != testAddr.Hex() {
+		t.Errorf("pooled transaction operations mismatch: %v", pooled.Transaction.Operations)
+	}
+	// Signatures of anyone but the sender must be rejected
+	other, _ := crypto.GenerateKey()
+	sig, _ = crypto.Sign(digest, other)
+	signatures[0]["hex_bytes"] = hex.EncodeToString(sig)
+	if err := call(t, h, "/construction/combine", map[string]interface{}{"unsigned_transaction": payloads.UnsignedTransaction, "signatures": signatures}, &combined); err == nil || err.Code != errInvalidSignature.Code {
+		t.Errorf("foreign signature error mismatch: have %v, want %v", err, errInvalidSignature)
+	}
+}
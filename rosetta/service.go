@@ -0,0 +1,208 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package rosetta implements the Rosetta Data and Construction APIs on top of
+// the node's HTTP-RPC server, so that exchanges and custodians can integrate
+// the chain through their standard Rosetta tooling.
+package rosetta
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	// Force-load the native call tracer, operations are derived from its frames
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
+)
+
+// rosettaVersion is the version of the Rosetta specification implemented.
+const rosettaVersion = "1.4.13"
+
+// maxRequestSize is the maximum accepted size of a request body.
+const maxRequestSize = 1024 * 1024
+
+// Config contains the identity of the network served over Rosetta.
+type Config struct {
+	Blockchain string // Name of the blockchain in the network identifier
+	Symbol     string // Symbol of the native currency
+}
+
+// DefaultConfig contains the default Rosetta network identity.
+var DefaultConfig = Config{
+	Blockchain: "Aksara",
+	Symbol:     "AKS",
+}
+
+// Backend is the set of node methods the Rosetta service is built on.
+type Backend interface {
+	tracers.Backend
+
+	CurrentHeader() *types.Header
+	GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error)
+	StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error)
+	SyncProgress() ethereum.SyncProgress
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	GetPoolTransactions() (types.Transactions, error)
+	GetPoolTransaction(txHash common.Hash) *types.Transaction
+	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
+	SendTx(ctx context.Context, signedTx *types.Transaction) error
+}
+
+// Errors returned by the Rosetta endpoints, all of them are listed in the
+// network options.
+var (
+	errInvalidRequest      = &Error{Code: 1, Message: "Invalid request"}
+	errUnsupportedNetwork  = &Error{Code: 2, Message: "Network is not supported"}
+	errUnknownEndpoint     = &Error{Code: 3, Message: "Endpoint not found"}
+	errBlockNotFound       = &Error{Code: 4, Message: "Block not found", Retriable: true}
+	errTransactionNotFound = &Error{Code: 5, Message: "Transaction not found", Retriable: true}
+	errStateUnavailable    = &Error{Code: 6, Message: "Historical state unavailable"}
+	errInvalidAddress      = &Error{Code: 7, Message: "Invalid address"}
+	errInvalidPublicKey    = &Error{Code: 8, Message: "Invalid public key"}
+	errInvalidOperations   = &Error{Code: 9, Message: "Unsupported operations"}
+	errInvalidTransaction  = &Error{Code: 10, Message: "Invalid transaction"}
+	errInvalidSignature    = &Error{Code: 11, Message: "Invalid signature"}
+	errSubmitFailed        = &Error{Code: 12, Message: "Transaction rejected"}
+	errInternal            = &Error{Code: 13, Message: "Internal error", Retriable: true}
+
+	allErrors = []*Error{
+		errInvalidRequest, errUnsupportedNetwork, errUnknownEndpoint, errBlockNotFound,
+		errTransactionNotFound, errStateUnavailable, errInvalidAddress, errInvalidPublicKey,
+		errInvalidOperations, errInvalidTransaction, errInvalidSignature, errSubmitFailed,
+		errInternal,
+	}
+)
+
+// endpoint is a Rosetta endpoint, decoding its own request from the body.
+type endpoint func(ctx context.Context, body []byte) (interface{}, *Error)
+
+// handler serves the Rosetta endpoints.
+type handler struct {
+	backend  Backend
+	tracer   *tracers.API
+	network  *NetworkIdentifier
+	currency *Currency
+	peers    func() []*Peer
+
+	endpoints map[string]endpoint
+}
+
+// NewHandler creates the Rosetta request handler, serving the endpoints below
+// the /rosetta path. Peers reports the peers the node is connected to.
+func NewHandler(backend Backend, config Config, peers func() []*Peer) http.Handler {
+	if backend == nil {
+		panic("missing backend")
+	}
+	return newHandler(backend, config, peers)
+}
+
+// newHandler creates the Rosetta request handler.
+func newHandler(backend Backend, config Config, peers func() []*Peer) *handler {
+	h := &handler{
+		backend: backend,
+		tracer:  tracers.NewAPI(backend),
+		network: &NetworkIdentifier{
+			Blockchain: config.Blockchain,
+			Network:    backend.ChainConfig().ChainID.String(),
+		},
+		currency: &Currency{Symbol: config.Symbol, Decimals: 18},
+		peers:    peers,
+	}
+	h.endpoints = map[string]endpoint{
+		"/network/list":            h.networkList,
+		"/network/status":          h.networkStatus,
+		"/network/options":         h.networkOptions,
+		"/account/balance":         h.accountBalance,
+		"/block":                   h.block,
+		"/block/transaction":       h.blockTransaction,
+		"/mempool":                 h.mempool,
+		"/mempool/transaction":     h.mempoolTransaction,
+		"/construction/derive":     h.constructionDerive,
+		"/construction/preprocess": h.constructionPreprocess,
+		"/construction/metadata":   h.constructionMetadata,
+		"/construction/payloads":   h.constructionPayloads,
+		"/construction/combine":    h.constructionCombine,
+		"/construction/parse":      h.constructionParse,
+		"/construction/hash":       h.constructionHash,
+		"/construction/submit":     h.constructionSubmit,
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler, dispatching the request to the endpoint
+// after checking it targets the served network.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/rosetta")
+	fn, ok := h.endpoints[path]
+	if !ok {
+		h.respond(w, nil, errUnknownEndpoint)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		h.respond(w, nil, errInvalidRequest.withDetail(err))
+		return
+	}
+	if path != "/network/list" {
+		var req networkRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			h.respond(w, nil, errInvalidRequest.withDetail(err))
+			return
+		}
+		if req.NetworkIdentifier == nil || *req.NetworkIdentifier != *h.network {
+			h.respond(w, nil, errUnsupportedNetwork)
+			return
+		}
+	}
+	res, rerr := fn(r.Context(), body)
+	h.respond(w, res, rerr)
+}
+
+// respond writes the result of an endpoint, or its error with a 500 status as
+// mandated by the specification.
+func (h *handler) respond(w http.ResponseWriter, res interface{}, err *Error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		res = err
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Debug("Failed to write Rosetta response", "err", err)
+	}
+}
+
+// decode unmarshals the request body of an endpoint.
+func decode(body []byte, req interface{}) *Error {
+	if err := json.Unmarshal(body, req); err != nil {
+		return errInvalidRequest.withDetail(err)
+	}
+	return nil
+}
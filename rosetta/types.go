@@ -0,0 +1,300 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rosetta
+
+// This file contains the subset of the Rosetta API models served by the node.
+// See https://www.rosetta-api.org/docs/Reference.html for the full definitions.
+
+// NetworkIdentifier specifies which network a request targets.
+type NetworkIdentifier struct {
+	Blockchain string `json:"blockchain"`
+	Network    string `json:"network"`
+}
+
+// BlockIdentifier uniquely identifies a block.
+type BlockIdentifier struct {
+	Index int64  `json:"index"`
+	Hash  string `json:"hash"`
+}
+
+// PartialBlockIdentifier selects a block by index or hash, the current head
+// if neither is set.
+type PartialBlockIdentifier struct {
+	Index *int64  `json:"index,omitempty"`
+	Hash  *string `json:"hash,omitempty"`
+}
+
+// TransactionIdentifier uniquely identifies a transaction within a block.
+type TransactionIdentifier struct {
+	Hash string `json:"hash"`
+}
+
+// AccountIdentifier identifies an account by its address.
+type AccountIdentifier struct {
+	Address string `json:"address"`
+}
+
+// Currency is the native currency of the chain.
+type Currency struct {
+	Symbol   string `json:"symbol"`
+	Decimals int32  `json:"decimals"`
+}
+
+// Amount is a signed value of a currency in its smallest unit.
+type Amount struct {
+	Value    string    `json:"value"`
+	Currency *Currency `json:"currency"`
+}
+
+// OperationIdentifier identifies an operation within a transaction.
+type OperationIdentifier struct {
+	Index int64 `json:"index"`
+}
+
+// Operation is a single balance change, or a failed attempt at one.
+type Operation struct {
+	OperationIdentifier *OperationIdentifier   `json:"operation_identifier"`
+	RelatedOperations   []*OperationIdentifier `json:"related_operations,omitempty"`
+	Type                string                 `json:"type"`
+	Status              *string                `json:"status,omitempty"`
+	Account             *AccountIdentifier     `json:"account,omitempty"`
+	Amount              *Amount                `json:"amount,omitempty"`
+}
+
+// Transaction is a set of operations applied atomically.
+type Transaction struct {
+	TransactionIdentifier *TransactionIdentifier `json:"transaction_identifier"`
+	Operations            []*Operation           `json:"operations"`
+	Metadata              map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Block is a set of transactions applied at a point of the chain.
+type Block struct {
+	BlockIdentifier       *BlockIdentifier       `json:"block_identifier"`
+	ParentBlockIdentifier *BlockIdentifier       `json:"parent_block_identifier"`
+	Timestamp             int64                  `json:"timestamp"` // Milliseconds since the Unix epoch
+	Transactions          []*Transaction         `json:"transactions"`
+	Metadata              map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// PublicKey is a public key in its compressed or uncompressed form.
+type PublicKey struct {
+	HexBytes  string `json:"hex_bytes"`
+	CurveType string `json:"curve_type"`
+}
+
+// SigningPayload is a payload the signers of a transaction have to sign.
+type SigningPayload struct {
+	AccountIdentifier *AccountIdentifier `json:"account_identifier"`
+	HexBytes          string             `json:"hex_bytes"`
+	SignatureType     string             `json:"signature_type"`
+}
+
+// Signature is the signature of a signing payload.
+type Signature struct {
+	SigningPayload *SigningPayload `json:"signing_payload"`
+	PublicKey      *PublicKey      `json:"public_key"`
+	SignatureType  string          `json:"signature_type"`
+	HexBytes       string          `json:"hex_bytes"`
+}
+
+// OperationStatus is a status operations may have.
+type OperationStatus struct {
+	Status     string `json:"status"`
+	Successful bool   `json:"successful"`
+}
+
+// Peer is a peer the node is connected to.
+type Peer struct {
+	PeerID string `json:"peer_id"`
+}
+
+// SyncStatus is the synchronisation progress of the node.
+type SyncStatus struct {
+	CurrentIndex int64 `json:"current_index"`
+	TargetIndex  int64 `json:"target_index"`
+	Synced       bool  `json:"synced"`
+}
+
+// Version reports the versions of the API and the node.
+type Version struct {
+	RosettaVersion string `json:"rosetta_version"`
+	NodeVersion    string `json:"node_version"`
+}
+
+// Allow reports the operation types, statuses and errors the node may return.
+type Allow struct {
+	OperationStatuses       []*OperationStatus `json:"operation_statuses"`
+	OperationTypes          []string           `json:"operation_types"`
+	Errors                  []*Error           `json:"errors"`
+	HistoricalBalanceLookup bool               `json:"historical_balance_lookup"`
+	CallMethods             []string           `json:"call_methods"`
+	BalanceExemptions       []interface{}      `json:"balance_exemptions"`
+	MempoolCoins            bool               `json:"mempool_coins"`
+}
+
+// Error is the body of every failed request.
+type Error struct {
+	Code      int32                  `json:"code"`
+	Message   string                 `json:"message"`
+	Retriable bool                   `json:"retriable"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// withDetail returns a copy of the error carrying the cause of the failure.
+func (e *Error) withDetail(err error) *Error {
+	cpy := *e
+	cpy.Details = map[string]interface{}{"error": err.Error()}
+	return &cpy
+}
+
+type networkRequest struct {
+	NetworkIdentifier *NetworkIdentifier `json:"network_identifier"`
+}
+
+type networkListResponse struct {
+	NetworkIdentifiers []*NetworkIdentifier `json:"network_identifiers"`
+}
+
+type networkStatusResponse struct {
+	CurrentBlockIdentifier *BlockIdentifier `json:"current_block_identifier"`
+	CurrentBlockTimestamp  int64            `json:"current_block_timestamp"`
+	GenesisBlockIdentifier *BlockIdentifier `json:"genesis_block_identifier"`
+	SyncStatus             *SyncStatus      `json:"sync_status"`
+	Peers                  []*Peer          `json:"peers"`
+}
+
+type networkOptionsResponse struct {
+	Version *Version `json:"version"`
+	Allow   *Allow   `json:"allow"`
+}
+
+type accountBalanceRequest struct {
+	NetworkIdentifier *NetworkIdentifier      `json:"network_identifier"`
+	AccountIdentifier *AccountIdentifier      `json:"account_identifier"`
+	BlockIdentifier   *PartialBlockIdentifier `json:"block_identifier,omitempty"`
+}
+
+type accountBalanceResponse struct {
+	BlockIdentifier *BlockIdentifier       `json:"block_identifier"`
+	Balances        []*Amount              `json:"balances"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type blockRequest struct {
+	NetworkIdentifier *NetworkIdentifier      `json:"network_identifier"`
+	BlockIdentifier   *PartialBlockIdentifier `json:"block_identifier"`
+}
+
+type blockResponse struct {
+	Block *Block `json:"block"`
+}
+
+type blockTransactionRequest struct {
+	NetworkIdentifier     *NetworkIdentifier     `json:"network_identifier"`
+	BlockIdentifier       *BlockIdentifier       `json:"block_identifier"`
+	TransactionIdentifier *TransactionIdentifier `json:"transaction_identifier"`
+}
+
+type transactionResponse struct {
+	Transaction *Transaction `json:"transaction"`
+}
+
+type mempoolResponse struct {
+	TransactionIdentifiers []*TransactionIdentifier `json:"transaction_identifiers"`
+}
+
+type mempoolTransactionRequest struct {
+	NetworkIdentifier     *NetworkIdentifier     `json:"network_identifier"`
+	TransactionIdentifier *TransactionIdentifier `json:"transaction_identifier"`
+}
+
+type deriveRequest struct {
+	NetworkIdentifier *NetworkIdentifier `json:"network_identifier"`
+	PublicKey         *PublicKey         `json:"public_key"`
+}
+
+type deriveResponse struct {
+	AccountIdentifier *AccountIdentifier `json:"account_identifier"`
+}
+
+type preprocessRequest struct {
+	NetworkIdentifier *NetworkIdentifier     `json:"network_identifier"`
+	Operations        []*Operation           `json:"operations"`
+	Metadata          map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type preprocessResponse struct {
+	Options            *transferOptions     `json:"options"`
+	RequiredPublicKeys []*AccountIdentifier `json:"required_public_keys"`
+}
+
+type metadataRequest struct {
+	NetworkIdentifier *NetworkIdentifier `json:"network_identifier"`
+	Options           *transferOptions   `json:"options"`
+}
+
+type metadataResponse struct {
+	Metadata     *transferMetadata `json:"metadata"`
+	SuggestedFee []*Amount         `json:"suggested_fee"`
+}
+
+type payloadsRequest struct {
+	NetworkIdentifier *NetworkIdentifier `json:"network_identifier"`
+	Operations        []*Operation       `json:"operations"`
+	Metadata          *transferMetadata  `json:"metadata"`
+}
+
+type payloadsResponse struct {
+	UnsignedTransaction string            `json:"unsigned_transaction"`
+	Payloads            []*SigningPayload `json:"payloads"`
+}
+
+type combineRequest struct {
+	NetworkIdentifier   *NetworkIdentifier `json:"network_identifier"`
+	UnsignedTransaction string             `json:"unsigned_transaction"`
+	Signatures          []*Signature       `json:"signatures"`
+}
+
+type combineResponse struct {
+	SignedTransaction string `json:"signed_transaction"`
+}
+
+type parseRequest struct {
+	NetworkIdentifier *NetworkIdentifier `json:"network_identifier"`
+	Signed            bool               `json:"signed"`
+	Transaction       string             `json:"transaction"`
+}
+
+type parseResponse struct {
+	Operations               []*Operation         `json:"operations"`
+	AccountIdentifierSigners []*AccountIdentifier `json:"account_identifier_signers"`
+}
+
+type signedTransactionRequest struct {
+	NetworkIdentifier *NetworkIdentifier `json:"network_identifier"`
+	SignedTransaction string             `json:"signed_transaction"`
+}
+
+type transactionIdentifierResponse struct {
+	TransactionIdentifier *TransactionIdentifier `json:"transaction_identifier"`
+}
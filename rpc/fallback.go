@@ -0,0 +1,59 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// FallbackFn serves a call that failed locally with the given error from another
+// source. It returns the result or error of that source along with its
+// provenance, reported to the client in the response, or an empty provenance
+// and the local error if it doesn't serve the call.
+type FallbackFn func(ctx context.Context, method string, params json.RawMessage, err error) (result json.RawMessage, provenance string, ferr error)
+
+// SetFallback makes the server try the fallback for the calls of the given
+// methods failing locally. Responses served by the fallback carry its provenance
+// in the "provenance" member and are never signed. Calling it again replaces the
+// methods.
+func (s *Server) SetFallback(methods []string, fallback FallbackFn) {
+	s.services.setFallback(methods, fallback)
+}
+
+// setFallback sets the methods whose failed calls are retried by the fallback.
+func (r *serviceRegistry) setFallback(methods []string, fallback FallbackFn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fallbacks = make(map[string]bool, len(methods))
+	for _, method := range methods {
+		r.fallbacks[method] = true
+	}
+	r.fallback = fallback
+}
+
+// fallbackFor returns the fallback of the given method, nil if it has none.
+func (r *serviceRegistry) fallbackFor(method string) FallbackFn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.fallbacks[method] {
+		return nil
+	}
+	return r.fallback
+}
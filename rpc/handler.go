@@ -333,6 +333,13 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 	if err != nil {
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
+	if quota := h.reg.quotaFor(msg.Method); quota != nil && callb != h.unsubscribeCb {
+		done, err := quota.acquire(cp.ctx)
+		if err != nil {
+			return msg.errorResponse(err)
+		}
+		defer done()
+	}
 	start := time.Now()
 	answer := h.runMethod(cp.ctx, msg, callb, args)
 
@@ -388,9 +395,27 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value) *jsonrpcMessage {
 	result, err := callb.call(ctx, msg.Method, args)
 	if err != nil {
+		if fallback := h.reg.fallbackFor(msg.Method); fallback != nil {
+			result, provenance, err := fallback(ctx, msg.Method, msg.Params, err)
+			if err == nil {
+				return &jsonrpcMessage{Version: vsn, ID: msg.ID, Result: result, Provenance: provenance}
+			}
+			resp := msg.errorResponse(err)
+			resp.Provenance = provenance
+			return resp
+		}
 		return msg.errorResponse(err)
 	}
-	return msg.response(result)
+	resp := msg.response(result)
+	if sign := h.reg.signer(msg.Method); sign != nil && resp.Error == nil {
+		sig, err := sign(ResponseDigest(msg.Method, msg.Params, resp.Result).Bytes())
+		if err != nil {
+			h.log.Warn("Failed to sign response", "method", msg.Method, "err", err)
+		} else {
+			resp.Signature = sig
+		}
+	}
+	return resp
 }
 
 // unsubscribe is the callback function for all *_unsubscribe calls.
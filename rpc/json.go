@@ -27,6 +27,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
 const (
@@ -55,6 +57,9 @@ type jsonrpcMessage struct {
 	Params  json.RawMessage `json:"params,omitempty"`
 	Error   *jsonError      `json:"error,omitempty"`
 	Result  json.RawMessage `json:"result,omitempty"`
+
+	Signature  hexutil.Bytes `json:"signature,omitempty"`  // signature of the response digest, see Server.SignResponses
+	Provenance string        `json:"provenance,omitempty"` // source of a result not served locally, see Server.SetFallback
 }
 
 func (msg *jsonrpcMessage) isNotification() bool {
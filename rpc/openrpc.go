@@ -0,0 +1,344 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// discoverMethod is the method name the OpenRPC specification reserves for
+// service discovery, served by the discover method of the metadata service.
+const discoverMethod = "rpc.discover"
+
+// openrpcVersion is the version of the OpenRPC specification the discovery
+// document follows.
+const openrpcVersion = "1.2.6"
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	quantityPattern = "^0x(0|[1-9a-f][0-9a-f]*)$"
+
+	// knownSchemas are the schemas of the types with a custom JSON encoding
+	// commonly used by the APIs.
+	knownSchemas = map[reflect.Type]*JSONSchema{
+		reflect.TypeOf(hexutil.Big{}):       {Type: "string", Pattern: quantityPattern},
+		reflect.TypeOf(hexutil.Uint64(0)):   {Type: "string", Pattern: quantityPattern},
+		reflect.TypeOf(hexutil.Uint(0)):     {Type: "string", Pattern: quantityPattern},
+		reflect.TypeOf(hexutil.Bytes{}):     {Type: "string", Pattern: "^0x([0-9a-fA-F]{2})*$"},
+		reflect.TypeOf(common.Address{}):    {Type: "string", Pattern: "^0x[0-9a-fA-F]{40}$"},
+		reflect.TypeOf(common.Hash{}):       {Type: "string", Pattern: "^0x[0-9a-fA-F]{64}$"},
+		reflect.TypeOf(big.Int{}):           {Type: "integer"},
+		reflect.TypeOf(time.Time{}):         {Type: "string", Format: "date-time"},
+		reflect.TypeOf(json.RawMessage{}):   {},
+		reflect.TypeOf(BlockNumber(0)):      blockNumberSchema,
+		reflect.TypeOf(BlockNumberOrHash{}): {OneOf: []*JSONSchema{blockNumberSchema, {Type: "string", Pattern: "^0x[0-9a-fA-F]{64}$"}, blockNumberOrHashObject}},
+		reflect.TypeOf(ID("")):              {Type: "string"},
+	}
+	blockNumberSchema = &JSONSchema{OneOf: []*JSONSchema{
+		{Type: "string", Enum: []interface{}{"earliest", "latest", "pending", "finalized"}},
+		{Type: "string", Pattern: quantityPattern},
+	}}
+	blockNumberOrHashObject = &JSONSchema{
+		Type: "object",
+		Properties: map[string]*JSONSchema{
+			"blockNumber":      blockNumberSchema,
+			"blockHash":        {Type: "string", Pattern: "^0x[0-9a-fA-F]{64}$"},
+			"requireCanonical": {Type: "boolean"},
+		},
+	}
+)
+
+// JSONSchema is the subset of JSON Schema used to describe the parameters and
+// results of the RPC methods.
+type JSONSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	OneOf                []*JSONSchema          `json:"oneOf,omitempty"`
+}
+
+// OpenRPCDocument is the OpenRPC description of the methods served.
+type OpenRPCDocument struct {
+	OpenRPC    string            `json:"openrpc"`
+	Info       OpenRPCInfo       `json:"info"`
+	Methods    []*OpenRPCMethod  `json:"methods"`
+	Components OpenRPCComponents `json:"components"`
+}
+
+// OpenRPCInfo is the metadata of an OpenRPC document.
+type OpenRPCInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenRPCMethod is the description of a single RPC method.
+type OpenRPCMethod struct {
+	Name   string               `json:"name"`
+	Params []*OpenRPCDescriptor `json:"params"`
+	Result *OpenRPCDescriptor   `json:"result"`
+}
+
+// OpenRPCDescriptor describes a parameter or the result of a method.
+type OpenRPCDescriptor struct {
+	Name     string      `json:"name"`
+	Required bool        `json:"required,omitempty"`
+	Schema   *JSONSchema `json:"schema"`
+}
+
+// OpenRPCComponents holds the schemas of the named types, referenced by the
+// method descriptors as #/components/schemas/<name>.
+type OpenRPCComponents struct {
+	Schemas map[string]*JSONSchema `json:"schemas"`
+}
+
+// Discover returns the OpenRPC document describing the methods served, their
+// parameters and results. Parameter names are positional as Go doesn't retain
+// them. Subscriptions are described by the subscribe method of each service,
+// taking the subscription name and its arguments.
+func (s *RPCService) Discover() *OpenRPCDocument {
+	s.server.services.mu.Lock()
+	defer s.server.services.mu.Unlock()
+
+	var (
+		builder = newSchemaBuilder()
+		doc     = &OpenRPCDocument{
+			OpenRPC: openrpcVersion,
+			Info:    OpenRPCInfo{Title: "JSON-RPC API", Version: "1.0"},
+			Methods: []*OpenRPCMethod{},
+		}
+	)
+	for name, svc := range s.server.services.services {
+		for method, cb := range svc.callbacks {
+			doc.Methods = append(doc.Methods, builder.method(name+serviceMethodSeparator+method, cb))
+		}
+		if len(svc.subscriptions) > 0 {
+			doc.Methods = append(doc.Methods, builder.subscribe(name, svc.subscriptions))
+			doc.Methods = append(doc.Methods, &OpenRPCMethod{
+				Name:   name + unsubscribeMethodSuffix,
+				Params: []*OpenRPCDescriptor{{Name: "id", Required: true, Schema: &JSONSchema{Type: "string"}}},
+				Result: &OpenRPCDescriptor{Name: "result", Schema: &JSONSchema{Type: "boolean"}},
+			})
+		}
+	}
+	sort.Slice(doc.Methods, func(i, j int) bool { return doc.Methods[i].Name < doc.Methods[j].Name })
+	doc.Components.Schemas = builder.defs
+	return doc
+}
+
+// schemaBuilder derives JSON schemas from Go types, collecting the schemas of
+// named struct types as components to support recursive types.
+type schemaBuilder struct {
+	defs  map[string]*JSONSchema
+	names map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		defs:  make(map[string]*JSONSchema),
+		names: make(map[reflect.Type]string),
+	}
+}
+
+// method describes a callback. Trailing pointer arguments may be omitted by
+// the caller and are thus optional.
+func (b *schemaBuilder) method(name string, cb *callback) *OpenRPCMethod {
+	m := &OpenRPCMethod{
+		Name:   name,
+		Params: make([]*OpenRPCDescriptor, len(cb.argTypes)),
+		Result: &OpenRPCDescriptor{Name: "result", Schema: &JSONSchema{Type: "null"}},
+	}
+	optional := true
+	for i := len(cb.argTypes) - 1; i >= 0; i-- {
+		typ := cb.argTypes[i]
+		optional = optional && typ.Kind() == reflect.Ptr
+		m.Params[i] = &OpenRPCDescriptor{
+			Name:     fmt.Sprintf("arg%d", i),
+			Required: !optional,
+			Schema:   b.schema(typ),
+		}
+	}
+	if fntype := cb.fn.Type(); fntype.NumOut() > 0 && cb.errPos != 0 {
+		m.Result.Schema = b.schema(fntype.Out(0))
+	}
+	return m
+}
+
+// subscribe describes the subscribe method of a service, taking the name of
+// the subscription followed by its arguments.
+func (b *schemaBuilder) subscribe(service string, subs map[string]*callback) *OpenRPCMethod {
+	names := make([]string, 0, len(subs))
+	for name := range subs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	kinds := make([]interface{}, len(names))
+	for i, name := range names {
+		kinds[i] = name
+	}
+	return &OpenRPCMethod{
+		Name: service + subscribeMethodSuffix,
+		Params: []*OpenRPCDescriptor{
+			{Name: "subscription", Required: true, Schema: &JSONSchema{Type: "string", Enum: kinds}},
+			{Name: "params", Schema: &JSONSchema{Description: "Arguments of the subscription"}},
+		},
+		Result: &OpenRPCDescriptor{Name: "id", Schema: &JSONSchema{Type: "string"}},
+	}
+}
+
+// schema returns the JSON schema of the JSON encoding of a Go type.
+func (b *schemaBuilder) schema(typ reflect.Type) *JSONSchema {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if known, ok := knownSchemas[typ]; ok {
+		return known
+	}
+	ptr := reflect.PtrTo(typ)
+	if typ.Implements(jsonMarshalerType) || ptr.Implements(jsonMarshalerType) {
+		return &JSONSchema{Description: fmt.Sprintf("Custom encoding of %v", typ)}
+	}
+	if typ.Implements(textMarshalerType) || ptr.Implements(textMarshalerType) {
+		return &JSONSchema{Type: "string"}
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return &JSONSchema{Type: "string", Format: "byte"}
+		}
+		return &JSONSchema{Type: "array", Items: b.schema(typ.Elem())}
+	case reflect.Array:
+		return &JSONSchema{Type: "array", Items: b.schema(typ.Elem())}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: b.schema(typ.Elem())}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return b.object(typ)
+		}
+		return &JSONSchema{Ref: "#/components/schemas/" + b.define(typ)}
+	default:
+		// Interfaces may hold anything, other kinds can't be encoded
+		return &JSONSchema{}
+	}
+}
+
+// define registers the schema of a named struct type as a component, returning
+// its name. The name is reserved before the fields are described, so recursive
+// types refer to themselves.
+func (b *schemaBuilder) define(typ reflect.Type) string {
+	if name, ok := b.names[typ]; ok {
+		return name
+	}
+	base := strings.Title(path.Base(typ.PkgPath())) + strings.Title(typ.Name())
+	name := base
+	for i := 2; b.defs[name] != nil; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	b.names[typ] = name
+	b.defs[name] = &JSONSchema{}
+
+	schema := b.object(typ)
+	schema.Title = typ.Name()
+	b.defs[name] = schema
+	return name
+}
+
+// object describes the JSON object encoding of a struct following the rules of
+// encoding/json: fields of embedded structs are promoted unless tagged, fields
+// without omitempty are always present.
+func (b *schemaBuilder) object(typ reflect.Type) *JSONSchema {
+	schema := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema)}
+	b.fields(schema, typ)
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (b *schemaBuilder) fields(schema *JSONSchema, typ reflect.Type) {
+	var embedded []reflect.Type
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+		ftype := field.Type
+		if field.Anonymous && name == "" {
+			if ftype.Kind() == reflect.Ptr {
+				ftype = ftype.Elem()
+			}
+			if ftype.Kind() == reflect.Struct {
+				embedded = append(embedded, ftype)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue // Unexported
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := schema.Properties[name]; ok {
+			continue // Shadowed by a shallower field
+		}
+		if strings.Contains(","+opts+",", ",string,") {
+			schema.Properties[name] = &JSONSchema{Type: "string"}
+		} else {
+			schema.Properties[name] = b.schema(field.Type)
+		}
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	// Promote the fields of embedded structs not shadowed by the direct ones
+	for _, ftype := range embedded {
+		b.fields(schema, ftype)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Tests that rpc.discover describes the registered methods and their types.
+func TestDiscover(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var doc OpenRPCDocument
+	if err := client.Call(&doc, "rpc.discover"); err != nil {
+		t.Fatalf("discovery failed: %v", err)
+	}
+	if doc.OpenRPC != openrpcVersion {
+		t.Errorf("openrpc version mismatch: have %q, want %q", doc.OpenRPC, openrpcVersion)
+	}
+	methods := make(map[string]*OpenRPCMethod)
+	for _, method := range doc.Methods {
+		methods[method.Name] = method
+	}
+	for _, name := range []string{"rpc_modules", "rpc_discover", "test_echo", "test_noArgsRets", "nftest_subscribe", "nftest_unsubscribe"} {
+		if methods[name] == nil {
+			t.Errorf("method %s missing", name)
+		}
+	}
+	// Trailing pointer arguments are optional, others required
+	echo := methods["test_echo"]
+	if len(echo.Params) != 3 {
+		t.Fatalf("echo param count mismatch: have %d, want 3", len(echo.Params))
+	}
+	for i, want := range []bool{true, true, false} {
+		if echo.Params[i].Required != want {
+			t.Errorf("echo param %d required mismatch: have %v, want %v", i, echo.Params[i].Required, want)
+		}
+	}
+	if typ := echo.Params[1].Schema.Type; typ != "integer" {
+		t.Errorf("echo param 1 type mismatch: have %q, want integer", typ)
+	}
+	// Named structs are referenced as components
+	ref := echo.Result.Schema.Ref
+	if ref != "#/components/schemas/RpcEchoResult" {
+		t.Fatalf("echo result reference mismatch: have %q", ref)
+	}
+	result := doc.Components.Schemas["RpcEchoResult"]
+	if result == nil || result.Type != "object" {
+		t.Fatalf("echo result schema missing: %+v", result)
+	}
+	if want := []string{"Args", "Int", "String"}; !reflect.DeepEqual(result.Required, want) {
+		t.Errorf("echo result required fields mismatch: have %v, want %v", result.Required, want)
+	}
+	if args := result.Properties["Args"]; args == nil || args.Ref != "#/components/schemas/RpcEchoArgs" {
+		t.Errorf("echo args reference mismatch: have %+v", args)
+	}
+	if typ := methods["test_noArgsRets"].Result.Schema.Type; typ != "null" {
+		t.Errorf("void result type mismatch: have %q, want null", typ)
+	}
+}
+
+type schemaTestEmbedded struct {
+	Shadowed string `json:"shadowed"`
+	Promoted uint64 `json:"promoted,omitempty"`
+}
+
+type schemaTestStruct struct {
+	schemaTestEmbedded
+	Shadowed bool              `json:"shadowed"`
+	Address  common.Address    `json:"address"`
+	Quantity *hexutil.Big      `json:"quantity,omitempty"`
+	Blob     []byte            `json:"blob"`
+	Counts   map[string]int    `json:"counts"`
+	Next     *schemaTestStruct `json:"next"`
+	Skipped  int               `json:"-"`
+	hidden   int
+}
+
+// Tests the schemas derived from struct fields.
+func TestSchemaFields(t *testing.T) {
+	b := newSchemaBuilder()
+	if ref := b.schema(reflect.TypeOf(&schemaTestStruct{})).Ref; ref != "#/components/schemas/RpcSchemaTestStruct" {
+		t.Fatalf("reference mismatch: have %q", ref)
+	}
+	schema := b.defs["RpcSchemaTestStruct"]
+
+	if len(schema.Properties) != 7 {
+		t.Errorf("property count mismatch: have %d, want 7", len(schema.Properties))
+	}
+	if typ := schema.Properties["shadowed"].Type; typ != "boolean" {
+		t.Errorf("shadowed field type mismatch: have %q, want boolean", typ)
+	}
+	if typ := schema.Properties["promoted"].Type; typ != "integer" {
+		t.Errorf("promoted field type mismatch: have %q, want integer", typ)
+	}
+	if pattern := schema.Properties["address"].Pattern; pattern != "^0x[0-9a-fA-F]{40}$" {
+		t.Errorf("address pattern mismatch: have %q", pattern)
+	}
+	if pattern := schema.Properties["quantity"].Pattern; pattern != quantityPattern {
+		t.Errorf("quantity pattern mismatch: have %q", pattern)
+	}
+	if blob := schema.Properties["blob"]; blob.Type != "string" || blob.Format != "byte" {
+		t.Errorf("blob schema mismatch: have %+v", blob)
+	}
+	if counts := schema.Properties["counts"]; counts.Type != "object" || counts.AdditionalProperties.Type != "integer" {
+		t.Errorf("map schema mismatch: have %+v", counts)
+	}
+	if ref := schema.Properties["next"].Ref; ref != "#/components/schemas/RpcSchemaTestStruct" {
+		t.Errorf("recursive reference mismatch: have %q", ref)
+	}
+	if want := []string{"address", "blob", "counts", "next", "shadowed"}; !reflect.DeepEqual(schema.Required, want) {
+		t.Errorf("required fields mismatch: have %v, want %v", schema.Required, want)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"time"
+
+	gethmetrics "github.com/ethereum/go-ethereum/metrics"
+)
+
+// quotaMaxWait is the longest a call waits for the quota of its namespace before
+// being rejected.
+const quotaMaxWait = 10 * time.Second
+
+// Quota is the share of the node's resources the calls of a namespace may use.
+// Only the CPU is capped: the Go runtime measures allocations process-wide, so
+// the memory used by the calls of a namespace can't be told apart.
+type Quota struct {
+	CPU float64 // Share of the CPUs the calls may keep busy, 0.2 capping them at 20%
+}
+
+// quotaExceededError is returned for calls rejected after waiting too long for
+// the quota of their namespace.
+type quotaExceededError struct{ namespace string }
+
+func (e *quotaExceededError) ErrorCode() int { return -32005 }
+
+func (e *quotaExceededError) Error() string {
+	return fmt.Sprintf("resource quota of the %s namespace exceeded", e.namespace)
+}
+
+// Quotas caps the resources used by the calls of namespaces. Calls run in a
+// worker pool per namespace sized to its CPU share. Calls of namespaces without
+// a quota are never held back. The same quotas can be shared by several servers
+// to enforce them across endpoints.
+type Quotas struct {
+	pools map[string]*quotaPool
+}
+
+// NewQuotas creates the quotas of the given namespaces.
+func NewQuotas(quotas map[string]Quota) (*Quotas, error) {
+	q := &Quotas{pools: make(map[string]*quotaPool, len(quotas))}
+	for namespace, quota := range quotas {
+		if quota.CPU <= 0 || quota.CPU > 1 {
+			return nil, fmt.Errorf("invalid CPU share %v of the %s namespace", quota.CPU, namespace)
+		}
+		q.pools[namespace] = newQuotaPool(namespace, quota)
+	}
+	return q, nil
+}
+
+// SetQuotas makes the server run the calls of the namespaces under their quotas.
+// Calling it again replaces the quotas.
+func (s *Server) SetQuotas(quotas *Quotas) {
+	s.services.setQuotas(quotas)
+}
+
+// setQuotas sets the quotas the calls are run under.
+func (r *serviceRegistry) setQuotas(quotas *Quotas) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.quotas = quotas
+}
+
+// quotaFor returns the quota of the namespace of the given method, nil if it has
+// none.
+func (r *serviceRegistry) quotaFor(method string) *quotaPool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.quotas == nil {
+		return nil
+	}
+	namespace := strings.SplitN(method, serviceMethodSeparator, 2)[0]
+	return r.quotas.pools[namespace]
+}
+
+// quotaPool is the worker pool of a namespace.
+type quotaPool struct {
+	namespace string
+	slots     chan struct{} // Worker slots, one per CPU of the share
+
+	throttledMeter gethmetrics.Meter // Calls which waited for the quota
+	rejectedMeter  gethmetrics.Meter // Calls rejected after waiting too long
+	waitTimer      gethmetrics.Timer // Time waited for the quota
+}
+
+// newQuotaPool creates the worker pool of a namespace.
+func newQuotaPool(namespace string, quota Quota) *quotaPool {
+	workers := int(math.Round(quota.CPU * float64(runtime.GOMAXPROCS(0))))
+	if workers < 1 {
+		workers = 1
+	}
+	prefix := "rpc/quota/" + namespace + "/"
+	return &quotaPool{
+		namespace:      namespace,
+		slots:          make(chan struct{}, workers),
+		throttledMeter: gethmetrics.GetOrRegisterMeter(prefix+"throttled", nil),
+		rejectedMeter:  gethmetrics.GetOrRegisterMeter(prefix+"rejected", nil),
+		waitTimer:      gethmetrics.GetOrRegisterTimer(prefix+"wait", nil),
+	}
+}
+
+// acquire waits for a worker slot, returning the function to call once the call
+// is done.
+func (p *quotaPool) acquire(ctx context.Context) (func(), error) {
+	select {
+	case p.slots <- struct{}{}:
+		return p.release, nil
+	default:
+	}
+	start := time.Now()
+	timer := time.NewTimer(quotaMaxWait)
+	defer timer.Stop()
+
+	select {
+	case p.slots <- struct{}{}:
+		p.throttledMeter.Mark(1)
+		p.waitTimer.UpdateSince(start)
+		return p.release, nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	p.rejectedMeter.Mark(1)
+	p.waitTimer.UpdateSince(start)
+	return nil, &quotaExceededError{p.namespace}
+}
+
+// release frees the worker slot of a finished call.
+func (p *quotaPool) release() {
+	<-p.slots
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Tests that the calls of a namespace are limited to its worker slots.
+func TestQuotaPool(t *testing.T) {
+	pool := newQuotaPool("test", Quota{CPU: 0.001})
+	if cap(pool.slots) != 1 {
+		t.Fatalf("worker slots mismatch: have %d, want 1", cap(pool.slots))
+	}
+	done, err := pool.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire free slot: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var qerr *quotaExceededError
+	if _, err := pool.acquire(ctx); !errors.As(err, &qerr) {
+		t.Fatalf("busy slot acquired: %v", err)
+	}
+	done()
+	if done, err = pool.acquire(context.Background()); err != nil {
+		t.Fatalf("failed to acquire released slot: %v", err)
+	}
+	done()
+}
+
+// Tests that the server runs the calls of the namespaces under their quotas.
+func TestServerQuotas(t *testing.T) {
+	if _, err := NewQuotas(map[string]Quota{"test": {CPU: 1.5}}); err == nil {
+		t.Fatalf("invalid CPU share accepted")
+	}
+	server := newTestServer()
+	defer server.Stop()
+
+	quotas, err := NewQuotas(map[string]Quota{"test": {CPU: 0.001}})
+	if err != nil {
+		t.Fatalf("failed to create quotas: %v", err)
+	}
+	server.SetQuotas(quotas)
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Calls wait for the only worker slot of the namespace to be released
+	go client.Call(nil, "test_sleep", 500*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	if err := client.Call(nil, "test_echo", "x", 1); err != nil {
+		t.Fatalf("throttled call failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatalf("call served beyond the quota after %v", elapsed)
+	}
+}
+
+// Tests that the calls of namespaces without a quota are not held back, even
+// with the quotas of others exhausted.
+func TestServerQuotasUnlimited(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	quotas, err := NewQuotas(map[string]Quota{"nftest": {CPU: 0.001}})
+	if err != nil {
+		t.Fatalf("failed to create quotas: %v", err)
+	}
+	quotas.pools["nftest"].slots <- struct{}{}
+	server.SetQuotas(quotas)
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	for i := 0; i < 10; i++ {
+		start := time.Now()
+		if err := client.Call(nil, "test_echo", "x", 1); err != nil {
+			t.Fatalf("unlimited call failed: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Fatalf("unlimited call held back for %v", elapsed)
+		}
+	}
+}
@@ -19,6 +19,8 @@ package rpc
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"io"
 	"net"
 	"os"
@@ -26,6 +28,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 func TestServerRegisterName(t *testing.T) {
@@ -152,3 +156,89 @@ func TestServerShortLivedConn(t *testing.T) {
 		}
 	}
 }
+
+// This test checks that the responses of the selected methods carry a signature
+// of their digest, while the others and error responses do not.
+func TestServerSignResponses(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	key, _ := crypto.GenerateKey()
+	server.SignResponses([]string{"test_echo", "test_returnError"}, func(digest []byte) ([]byte, error) {
+		return crypto.Sign(digest, key)
+	})
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go server.ServeCodec(NewCodec(serverConn), 0)
+
+	call := func(request string) *jsonrpcMessage {
+		clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+		if _, err := io.WriteString(clientConn, request+"\n"); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+		var resp jsonrpcMessage
+		if err := json.NewDecoder(clientConn).Decode(&resp); err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		return &resp
+	}
+	params := `["x",3,{"S":"foo"}]`
+	resp := call(`{"jsonrpc":"2.0","id":1,"method":"test_echo","params":` + params + `}`)
+	if len(resp.Signature) != crypto.SignatureLength {
+		t.Fatalf("missing signature: %s", resp.Signature)
+	}
+	digest := ResponseDigest("test_echo", json.RawMessage(params), resp.Result)
+	pub, err := crypto.SigToPub(digest.Bytes(), resp.Signature)
+	if err != nil {
+		t.Fatalf("invalid signature: %v", err)
+	}
+	if crypto.PubkeyToAddress(*pub) != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("signature by wrong key %x", crypto.PubkeyToAddress(*pub))
+	}
+	if resp = call(`{"jsonrpc":"2.0","id":2,"method":"test_returnError"}`); resp.Signature != nil {
+		t.Fatalf("error response signed: %s", resp.Signature)
+	}
+	if resp = call(`{"jsonrpc":"2.0","id":3,"method":"test_rets"}`); resp.Signature != nil {
+		t.Fatalf("unselected method response signed: %s", resp.Signature)
+	}
+}
+
+// This test checks that the failed calls of the selected methods are served by
+// the fallback with its provenance, while the others keep their error.
+func TestServerFallback(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	server.SetFallback([]string{"test_returnError"}, func(ctx context.Context, method string, params json.RawMessage, err error) (json.RawMessage, string, error) {
+		if _, ok := err.(testError); !ok {
+			return nil, "", err
+		}
+		return json.RawMessage(`"remote"`), "archive", nil
+	})
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go server.ServeCodec(NewCodec(serverConn), 0)
+
+	call := func(request string) *jsonrpcMessage {
+		clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+		if _, err := io.WriteString(clientConn, request+"\n"); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+		var resp jsonrpcMessage
+		if err := json.NewDecoder(clientConn).Decode(&resp); err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		return &resp
+	}
+	resp := call(`{"jsonrpc":"2.0","id":1,"method":"test_returnError"}`)
+	if resp.Error != nil || string(resp.Result) != `"remote"` || resp.Provenance != "archive" {
+		t.Fatalf("fallback response mismatch: %+v", resp)
+	}
+	if resp = call(`{"jsonrpc":"2.0","id":2,"method":"test_echo","params":["x",3,{"S":"foo"}]}`); resp.Provenance != "" {
+		t.Fatalf("local response with provenance: %s", resp.Provenance)
+	}
+	server.SetFallback(nil, nil)
+	if resp = call(`{"jsonrpc":"2.0","id":3,"method":"test_returnError"}`); resp.Error == nil || resp.Provenance != "" {
+		t.Fatalf("unselected method served by fallback: %+v", resp)
+	}
+}
@@ -39,6 +39,13 @@ var (
 type serviceRegistry struct {
 	mu       sync.Mutex
 	services map[string]service
+	signed   map[string]bool // methods whose responses are signed
+	sign     ResponseSignFn
+
+	fallbacks map[string]bool // methods whose failed calls are retried by the fallback
+	fallback  FallbackFn
+
+	quotas *Quotas // resource quotas of the namespaces, nil if unlimited
 }
 
 // service represents a registered object.
@@ -94,6 +101,9 @@ func (r *serviceRegistry) registerName(name string, rcvr interface{}) error {
 
 // callback returns the callback corresponding to the given RPC method name.
 func (r *serviceRegistry) callback(method string) *callback {
+	if method == discoverMethod {
+		method = MetadataApi + serviceMethodSeparator + "discover"
+	}
 	elem := strings.SplitN(method, serviceMethodSeparator, 2)
 	if len(elem) != 2 {
 		return nil
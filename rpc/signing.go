@@ -0,0 +1,71 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// responseDigestPrefix domain-separates response digests from any other data
+// signed with the same key.
+var responseDigestPrefix = []byte("aksara-rpc-response")
+
+// ResponseSignFn signs the digest of a response, see ResponseDigest.
+type ResponseSignFn func(digest []byte) ([]byte, error)
+
+// ResponseDigest is the hash signed for the response to a call, binding the
+// result to the method and the parameters it was requested with. Params and
+// result are the exact bytes of the request and of the "result" member of the
+// response, so that consumers can verify them without re-encoding.
+func ResponseDigest(method string, params, result json.RawMessage) common.Hash {
+	return crypto.Keccak256Hash(responseDigestPrefix, []byte{0}, []byte(method), []byte{0}, params, []byte{0}, result)
+}
+
+// SignResponses makes the server attach a signature of the response digest to
+// the successful responses of the given methods, so that clients can detect
+// responses tampered with by intermediaries. The signature is carried in the
+// "signature" member of the response. Calling it again replaces the methods.
+func (s *Server) SignResponses(methods []string, sign ResponseSignFn) {
+	s.services.setSigner(methods, sign)
+}
+
+// setSigner sets the methods whose responses are signed and the signer.
+func (r *serviceRegistry) setSigner(methods []string, sign ResponseSignFn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.signed = make(map[string]bool, len(methods))
+	for _, method := range methods {
+		r.signed[method] = true
+	}
+	r.sign = sign
+}
+
+// signer returns the signer of the responses of the given method, nil if they
+// are not signed.
+func (r *serviceRegistry) signer(method string) ResponseSignFn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.signed[method] {
+		return nil
+	}
+	return r.sign
+}
@@ -131,6 +131,7 @@ type BlockNumberOrHash struct {
 	BlockNumber      *BlockNumber `json:"blockNumber,omitempty"`
 	BlockHash        *common.Hash `json:"blockHash,omitempty"`
 	RequireCanonical bool         `json:"requireCanonical,omitempty"`
+	StateSession     string       `json:"stateSession,omitempty"` // Token of a state session on the block
 }
 
 func (bnh *BlockNumberOrHash) UnmarshalJSON(data []byte) error {
@@ -141,9 +142,13 @@ func (bnh *BlockNumberOrHash) UnmarshalJSON(data []byte) error {
 		if e.BlockNumber != nil && e.BlockHash != nil {
 			return fmt.Errorf("cannot specify both BlockHash and BlockNumber, choose one or the other")
 		}
+		if e.StateSession != "" && (e.BlockNumber != nil || e.BlockHash != nil) {
+			return fmt.Errorf("cannot specify a StateSession along with BlockHash or BlockNumber")
+		}
 		bnh.BlockNumber = e.BlockNumber
 		bnh.BlockHash = e.BlockHash
 		bnh.RequireCanonical = e.RequireCanonical
+		bnh.StateSession = e.StateSession
 		return nil
 	}
 	var input string
@@ -206,6 +211,9 @@ func (bnh *BlockNumberOrHash) String() string {
 	if bnh.BlockHash != nil {
 		return bnh.BlockHash.String()
 	}
+	if bnh.StateSession != "" {
+		return "session " + bnh.StateSession
+	}
 	return "nil"
 }
 
@@ -216,6 +224,11 @@ func (bnh *BlockNumberOrHash) Hash() (common.Hash, bool) {
 	return common.Hash{}, false
 }
 
+// Session returns the token of the state session selecting the block, if any.
+func (bnh *BlockNumberOrHash) Session() (string, bool) {
+	return bnh.StateSession, bnh.StateSession != ""
+}
+
 func BlockNumberOrHashWithNumber(blockNr BlockNumber) BlockNumberOrHash {
 	return BlockNumberOrHash{
 		BlockNumber:      &blockNr,
@@ -99,6 +99,8 @@ func TestBlockNumberOrHash_UnmarshalJSON(t *testing.T) {
 		23: {`{"blockNumber":"latest"}`, false, BlockNumberOrHashWithNumber(LatestBlockNumber)},
 		24: {`{"blockNumber":"earliest"}`, false, BlockNumberOrHashWithNumber(EarliestBlockNumber)},
 		25: {`{"blockNumber":"0x1", "blockHash":"0x0000000000000000000000000000000000000000000000000000000000000000"}`, true, BlockNumberOrHash{}},
+		26: {`{"stateSession":"0x01"}`, false, BlockNumberOrHash{StateSession: "0x01"}},
+		27: {`{"blockNumber":"0x1", "stateSession":"0x01"}`, true, BlockNumberOrHash{}},
 	}
 
 	for i, test := range tests {
@@ -116,7 +118,7 @@ func TestBlockNumberOrHash_UnmarshalJSON(t *testing.T) {
 		expectedHash, expectedHashOk := test.expected.Hash()
 		num, numOk := bnh.Number()
 		expectedNum, expectedNumOk := test.expected.Number()
-		if bnh.RequireCanonical != test.expected.RequireCanonical ||
+		if bnh.RequireCanonical != test.expected.RequireCanonical || bnh.StateSession != test.expected.StateSession ||
 			hash != expectedHash || hashOk != expectedHashOk ||
 			num != expectedNum || numOk != expectedNumOk {
 			t.Errorf("Test %d got unexpected value, want %v, got %v", i, test.expected, bnh)
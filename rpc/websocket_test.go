@@ -117,6 +117,38 @@ func TestWebsocketLargeCall(t *testing.T) {
 	}
 }
 
+// This checks that compressed calls work for messages on either side of the
+// compression threshold.
+func TestWebsocketCompression(t *testing.T) {
+	t.Parallel()
+
+	var (
+		srv     = newTestServer()
+		httpsrv = httptest.NewServer(srv.WebsocketHandlerWithCompression([]string{"*"}, 1024))
+		wsURL   = "ws:" + strings.TrimPrefix(httpsrv.URL, "http:")
+	)
+	defer srv.Stop()
+	defer httpsrv.Close()
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	client, err := DialWebsocketWithDialer(context.Background(), wsURL, "", dialer)
+	if err != nil {
+		t.Fatalf("can't dial: %v", err)
+	}
+	defer client.Close()
+
+	for _, size := range []int{10, 1024, 64 * 1024} {
+		var result echoResult
+		arg := strings.Repeat("x", size)
+		if err := client.Call(&result, "test_echo", arg, 1); err != nil {
+			t.Fatalf("call with %d bytes failed: %v", size, err)
+		}
+		if result.String != arg {
+			t.Fatalf("wrong string echoed for %d bytes", size)
+		}
+	}
+}
+
 func TestWebsocketPeerInfo(t *testing.T) {
 	var (
 		s     = newTestServer()
@@ -0,0 +1,365 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package clique contains the fuzzers of the clique engine: header verification
+// on top of a synthetic chain, the snapshot encodings and the block references
+// accepted by the clique RPC API.
+package clique
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	extraVanity = 32                     // Fixed number of extra-data prefix bytes reserved for signer vanity
+	extraSeal   = crypto.SignatureLength // Fixed number of extra-data suffix bytes reserved for signer seal
+
+	maxHeaders   = 16            // Maximum number of headers verified per input
+	genesisTime  = 1_600_000_000 // Genesis timestamp, far enough in the past for any fuzzed header
+	genesisLimit = 8_000_000     // Gas limit of the synthetic chain
+)
+
+var (
+	// keys are the signers of the synthetic chains. The last one is never
+	// authorized, so no header it seals may pass verification.
+	keys = func() []*ecdsa.PrivateKey {
+		keys := make([]*ecdsa.PrivateKey, 4)
+		for i := range keys {
+			keys[i], _ = crypto.ToECDSA(crypto.Keccak256([]byte(fmt.Sprintf("clique-fuzzer-%d", i))))
+		}
+		return keys
+	}()
+	addrs = func() []common.Address {
+		addrs := make([]common.Address, len(keys))
+		for i, key := range keys {
+			addrs[i] = crypto.PubkeyToAddress(key.PublicKey)
+		}
+		return addrs
+	}()
+	outsider = len(keys) - 1
+
+	// api is a clique RPC API on top of a short sealed chain, serving the block
+	// reference fuzzer.
+	api *rpc.Client
+)
+
+func init() {
+	engine, chain := newEngine(nil)
+	for i := 0; i < 3; i++ {
+		header := chain.child(uint64(i) + 1)
+		signer, err := engine.PrepareSealer(chain, header, common.Address{})
+		if err != nil {
+			panic(err)
+		}
+		seal(header, keys[indexOf(signer)])
+		if err := engine.VerifyHeader(chain, header, true); err != nil {
+			panic(err)
+		}
+		chain.insert(header)
+	}
+	server := rpc.NewServer()
+	for _, service := range engine.APIs(chain) {
+		if err := server.RegisterName(service.Namespace, service.Service); err != nil {
+			panic(err)
+		}
+	}
+	api = rpc.DialInProc(server)
+}
+
+type fuzzer struct {
+	input     io.Reader
+	exhausted bool
+}
+
+func (f *fuzzer) read(size int) []byte {
+	out := make([]byte, size)
+	if _, err := f.input.Read(out); err != nil {
+		f.exhausted = true
+	}
+	return out
+}
+
+func (f *fuzzer) readUint64(min, max uint64) uint64 {
+	if min == max {
+		return min
+	}
+	var a uint64
+	if err := binary.Read(f.input, binary.LittleEndian, &a); err != nil {
+		f.exhausted = true
+	}
+	return min + a%(max-min)
+}
+
+func (f *fuzzer) readBool() bool {
+	return f.read(1)[0]&0x1 == 0
+}
+
+// testChain is a minimal header chain for the engine to verify against.
+type testChain struct {
+	config  *params.ChainConfig
+	headers map[common.Hash]*types.Header
+	canon   []*types.Header
+}
+
+func (c *testChain) Config() *params.ChainConfig  { return c.config }
+func (c *testChain) CurrentHeader() *types.Header { return c.canon[len(c.canon)-1] }
+
+func (c *testChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.headers[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(c.canon)) {
+		return c.canon[number]
+	}
+	return nil
+}
+
+func (c *testChain) GetHeaderByHash(hash common.Hash) *types.Header { return c.headers[hash] }
+func (c *testChain) GetTd(hash common.Hash, number uint64) *big.Int { return nil }
+
+// insert appends a verified header to the canonical chain.
+func (c *testChain) insert(header *types.Header) {
+	c.headers[header.Hash()] = header
+	c.canon = append(c.canon, header)
+}
+
+// child returns an unsealed header on top of the chain head, the given number
+// of seconds past the minimum period.
+func (c *testChain) child(delay uint64) *types.Header {
+	parent := c.CurrentHeader()
+	return &types.Header{
+		ParentHash: parent.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + c.config.Clique.Period + delay,
+		BaseFee:    misc.CalcBaseFee(c.config, parent),
+	}
+}
+
+// newEngine creates a clique engine with a static validator set made of all
+// but the outsider key, along with a chain holding just the genesis.
+func newEngine(commit *big.Int) (*clique.Clique, *testChain) {
+	config := *params.AllCliqueProtocolChanges
+	config.Clique = &params.CliqueConfig{
+		Period:              1,
+		InitialValidators:   addrs[:outsider],
+		SnapshotCommitBlock: commit,
+	}
+	genesis := &types.Header{
+		UncleHash: types.EmptyUncleHash,
+		Number:    new(big.Int),
+		GasLimit:  genesisLimit,
+		Time:      genesisTime,
+		Extra:     make([]byte, extraVanity+extraSeal),
+		BaseFee:   big.NewInt(params.InitialBaseFee),
+	}
+	chain := &testChain{config: &config, headers: make(map[common.Hash]*types.Header)}
+	chain.insert(genesis)
+
+	return clique.New(config.Clique, rawdb.NewMemoryDatabase()), chain
+}
+
+// seal signs a header, which must have room for the seal, with the given key.
+func seal(header *types.Header, key *ecdsa.PrivateKey) {
+	sig, err := crypto.Sign(clique.SealHash(header).Bytes(), key)
+	if err != nil {
+		panic(err)
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+}
+
+// indexOf returns the index of the key of a signer.
+func indexOf(signer common.Address) int {
+	for i, addr := range addrs {
+		if addr == signer {
+			return i
+		}
+	}
+	panic(fmt.Sprintf("unknown signer %x", signer))
+}
+
+// mutate alters one of the consensus fields of a header prepared by the engine.
+func (f *fuzzer) mutate(header *types.Header) {
+	switch f.readUint64(0, 9) {
+	case 1: // Corrupt the extra-data (vanity, signers or commitment)
+		if len(header.Extra) > 0 {
+			header.Extra[f.readUint64(0, uint64(len(header.Extra)))] ^= f.read(1)[0] | 1
+		}
+	case 2: // Propose another epoch, or drop the proposal
+		copy(header.Nonce[:], f.read(len(header.Nonce)))
+	case 3: // Replace the extra-data altogether
+		header.Extra = f.read(int(f.readUint64(0, extraVanity+4*common.AddressLength+common.HashLength+extraSeal+8)))
+	case 4: // Change the turn-ness
+		header.Difficulty = new(big.Int).SetUint64(f.readUint64(0, 4))
+	case 5: // Move the timestamp around the parent's
+		header.Time = header.Time - 2 + f.readUint64(0, 4)
+	case 6: // Set the unused mix digest
+		copy(header.MixDigest[:], f.read(common.HashLength))
+	case 7: // Detach from the parent
+		copy(header.ParentHash[:], f.read(common.HashLength))
+	case 8: // Misnumber the header, the genesis being accepted as is
+		header.Number = new(big.Int).SetUint64(f.readUint64(1, maxHeaders+2))
+	}
+}
+
+// FuzzVerifyHeader builds headers on top of a synthetic chain, sealed by the
+// authorized signers, the outsider or not at all, with fuzzed consensus fields
+// and validator set rotations in between, and verifies them. Accepted headers
+// extend the chain so later ones exercise the snapshots, epoch transitions and
+// their commitments.
+func FuzzVerifyHeader(data []byte) int {
+	f := &fuzzer{input: bytes.NewReader(data)}
+
+	var commit *big.Int
+	if f.readBool() {
+		commit = new(big.Int).SetUint64(f.readUint64(0, maxHeaders))
+	}
+	engine, chain := newEngine(commit)
+
+	accepted := 0
+	for i := 0; i < maxHeaders && !f.exhausted; i++ {
+		// Occasionally move the registry to a new validator set for the next
+		// header to propose
+		if f.readUint64(0, 4) == 0 {
+			var set []common.Address
+			for j, mask := 0, f.read(1)[0]; j < outsider; j++ {
+				if mask&(1<<j) != 0 {
+					set = append(set, addrs[j])
+				}
+			}
+			if len(set) > 0 {
+				if _, err := engine.RotateValidators(set); err != nil {
+					panic(err)
+				}
+			} else if _, err := engine.AdvanceEpoch(); err != nil {
+				panic(err)
+			}
+		}
+		var (
+			header = chain.child(f.readUint64(0, 3))
+			signer = int(f.readUint64(0, uint64(len(keys))))
+		)
+		if _, err := engine.PrepareSealer(chain, header, addrs[signer]); err != nil {
+			// Unauthorized signers seal what the in-turn one would have
+			if _, err := engine.PrepareSealer(chain, header, common.Address{}); err != nil {
+				panic(err)
+			}
+		}
+		f.mutate(header)
+		if len(header.Extra) >= extraSeal {
+			if f.readUint64(0, 8) != 0 {
+				seal(header, keys[signer])
+			} else {
+				copy(header.Extra[len(header.Extra)-extraSeal:], f.read(extraSeal))
+			}
+		}
+		if err := engine.VerifyHeader(chain, header, true); err != nil {
+			continue
+		}
+		if header.Number.Uint64() != uint64(len(chain.canon)) {
+			panic(fmt.Sprintf("header %d accepted on top of %d", header.Number, len(chain.canon)-1))
+		}
+		author, err := engine.Author(header)
+		if err != nil {
+			panic(fmt.Sprintf("header %d accepted without author: %v", header.Number, err))
+		}
+		if author == addrs[outsider] {
+			panic(fmt.Sprintf("header %d accepted from unauthorized signer", header.Number))
+		}
+		chain.insert(header)
+		accepted++
+	}
+	if accepted > 0 {
+		return 1
+	}
+	return 0
+}
+
+// FuzzSnapshot decodes snapshots from their JSON database form and their
+// canonical RLP form, checking that both round trip.
+func FuzzSnapshot(data []byte) int {
+	score := 0
+
+	snap := new(clique.Snapshot)
+	if err := json.Unmarshal(data, snap); err == nil {
+		want, err := snap.RLPHash()
+		if err != nil {
+			panic(fmt.Sprintf("failed to hash decoded snapshot: %v", err))
+		}
+		blob, err := json.Marshal(snap)
+		if err != nil {
+			panic(fmt.Sprintf("failed to encode decoded snapshot: %v", err))
+		}
+		cpy := new(clique.Snapshot)
+		if err := json.Unmarshal(blob, cpy); err != nil {
+			panic(fmt.Sprintf("failed to decode encoded snapshot: %v", err))
+		}
+		if have, _ := cpy.RLPHash(); have != want {
+			panic(fmt.Sprintf("json round trip changed the snapshot: have %x, want %x", have, want))
+		}
+		score = 1
+	}
+	snap = new(clique.Snapshot)
+	if err := rlp.DecodeBytes(data, snap); err == nil {
+		want, err := rlp.EncodeToBytes(snap)
+		if err != nil {
+			panic(fmt.Sprintf("failed to encode decoded snapshot: %v", err))
+		}
+		cpy := new(clique.Snapshot)
+		if err := rlp.DecodeBytes(want, cpy); err != nil {
+			panic(fmt.Sprintf("failed to decode canonical snapshot: %v", err))
+		}
+		if have, _ := rlp.EncodeToBytes(cpy); !bytes.Equal(have, want) {
+			panic(fmt.Sprintf("canonical encoding not stable: have %x, want %x", have, want))
+		}
+		score = 1
+	}
+	return score
+}
+
+// FuzzBlockReference passes the input as the block number, hash or RLP encoded
+// header or block parameter of clique_getSigner.
+func FuzzBlockReference(data []byte) int {
+	if !json.Valid(data) {
+		return -1
+	}
+	var signer common.Address
+	if err := api.Call(&signer, "clique_getSigner", json.RawMessage(data)); err != nil {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestFuzzers runs the fuzzers over random inputs, making sure the harnesses
+// themselves work and that well formed inputs are recognized.
+func TestFuzzers(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	accepted := 0
+	for i := 0; i < 200; i++ {
+		data := make([]byte, rng.Intn(512))
+		rng.Read(data)
+		accepted += FuzzVerifyHeader(data)
+		FuzzSnapshot(data)
+		FuzzBlockReference(data)
+	}
+	if accepted == 0 {
+		t.Errorf("no header accepted")
+	}
+	// Seeds of the decoding paths
+	snapshots := []string{
+		`{"number":5,"hash":"0x0000000000000000000000000000000000000000000000000000000000000005","epoch":2,"signers":{"0x0000000000000000000000000000000000000001":true},"recents":{"4":"0x0000000000000000000000000000000000000001"}}`,
+		`{"number":1,"previousSnapNumber":0,"previousSnapHash":null,"signers":null,"recents":null}`,
+	}
+	for _, seed := range snapshots {
+		if FuzzSnapshot([]byte(seed)) != 1 {
+			t.Errorf("snapshot seed rejected: %s", seed)
+		}
+	}
+	references := []string{`"0x1"`, `"0x3"`, `{"blockNumber":"0x2"}`}
+	for _, seed := range references {
+		if FuzzBlockReference([]byte(seed)) != 1 {
+			t.Errorf("block reference seed rejected: %s", seed)
+		}
+	}
+}